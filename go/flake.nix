@@ -45,6 +45,7 @@
             doCheck = true;
             nativeBuildInputs = with pkgs; [
               go
+              sqlite
               writableTmpDirAsHomeHook
             ];
             checkPhase = ''
@@ -79,6 +80,18 @@
             src = ./statement-extractor;
             # NOTE: Need to run gomod2nix next to go.mod to generate this.
             modules = ./statement-extractor/gomod2nix.toml;
+            nativeBuildInputs = [ pkgs.makeWrapper ];
+            # The transaction database is driven through the sqlite3 shell,
+            # method "local" extracts text with poppler's pdftotext, and 7z
+            # archives are expanded with 7-Zip
+            postFixup = ''
+              wrapProgram "$out/bin/statement-extractor" \
+                --suffix PATH : ${pkgs.lib.makeBinPath [
+                  pkgs.sqlite
+                  pkgs.poppler_utils
+                  pkgs.p7zip
+                ]}
+            '';
           };
         in
         {
@@ -100,6 +113,9 @@
                 gopls
                 gotools
                 go-tools
+                sqlite
+                poppler_utils
+                p7zip
                 gomod2nix.packages.${system}.default
               ];
             };
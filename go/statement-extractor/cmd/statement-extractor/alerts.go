@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/alert"
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/jobs"
+	"github.com/example/statement-extractor/internal/store"
+	"github.com/example/statement-extractor/internal/table"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+var alertsCmd = &cobra.Command{
+	Use:   "alerts",
+	Short: "List alert rule triggers",
+	Long: `Evaluate the [[alerts]] rules from the config file against categorized
+transactions and list every trigger, e.g. single transactions above a threshold
+or too many matching transactions within a day, week or month. Rules with a
+monthly_budget list the transaction that took each month over the budget and
+the month's spending at that point.
+
+import and watch check the rules as well, reporting the triggers the new
+transactions set off on stderr, and to notify.webhook when it is set.`,
+	Example: `  statement-extractor alerts --input 2024-06.json`,
+	RunE:    runAlerts,
+}
+
+func init() {
+	alertsCmd.Flags().StringSlice("input", nil, "TransactionList JSON file(s) to check")
+	alertsCmd.Flags().String("period", "", "Only check transactions in this period (YYYY, YYYY-MM or YYYY-MM:YYYY-MM)")
+	_ = alertsCmd.MarkFlagRequired("input")
+	addTableFlags(alertsCmd)
+	rootCmd.AddCommand(alertsCmd)
+}
+
+func runAlerts(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	out, err := newDisplay(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	rules, err := alert.Compile(cfg.Alerts)
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		fmt.Println("No alert rules configured")
+		return nil
+	}
+
+	txns, period, err := reportInput(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	var inPeriod []transaction.Transaction
+	for _, t := range txns {
+		if period.Contains(t.Date) {
+			inPeriod = append(inPeriod, t)
+		}
+	}
+
+	triggers := alert.Evaluate(rules, inPeriod)
+	if len(triggers) == 0 {
+		fmt.Println("No alerts triggered")
+		return nil
+	}
+
+	tbl := table.New(
+		table.Column{Name: "rule"},
+		table.Column{Name: "date", Kind: table.Date},
+		table.Column{Name: "window"},
+		table.Column{Name: "count", Kind: table.Number},
+		table.Column{Name: "total", Kind: table.Money},
+		table.Column{Name: "spent", Kind: table.Money},
+		table.Column{Name: "description"},
+	)
+	for _, trigger := range triggers {
+		// Frequency triggers span a window rather than a single transaction
+		var date, description, spent any
+		if trigger.Window == "" || trigger.Budget > 0 {
+			date, description = trigger.Transactions[0].Date, trigger.Transactions[0].Description
+		}
+		if trigger.Budget > 0 {
+			spent = trigger.Spent
+		}
+		tbl.Append(trigger.Rule, date, trigger.Window, len(trigger.Transactions), trigger.Total(), spent, description)
+	}
+	return out.renderTable(cmd, tbl)
+}
+
+// alerter reports the alert triggers that new transactions set off, for
+// import and watch
+type alerter struct {
+	rules   []*alert.Rule
+	webhook string
+	secret  string
+}
+
+// newAlerter compiles the alert rules, returning nil when there are none
+func newAlerter(cfg *config.Config) (*alerter, error) {
+	rules, err := alert.Compile(cfg.Alerts)
+	if err != nil || len(rules) == 0 {
+		return nil, err
+	}
+	a := &alerter{rules: rules, webhook: cfg.Notify.Webhook}
+	if cfg.Notify.SecretEnv != "" {
+		if a.secret = os.Getenv(cfg.Notify.SecretEnv); a.secret == "" {
+			return nil, fmt.Errorf("notify.secret_env: %s is not set", cfg.Notify.SecretEnv)
+		}
+	}
+	return a, nil
+}
+
+// alertMonths selects the whole of the months of txns, against which alerts
+// are judged as budgets are monthly
+func alertMonths(txns []transaction.Transaction) store.Filter {
+	if len(txns) == 0 {
+		return store.Filter{}
+	}
+	from, to := txns[0].Date, txns[0].Date
+	for _, t := range txns {
+		if t.Date.Before(from) {
+			from = t.Date
+		}
+		if t.Date.After(to) {
+			to = t.Date
+		}
+	}
+	return store.Filter{
+		From: time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location()),
+		To:   time.Date(to.Year(), to.Month()+1, 1, 0, 0, 0, 0, to.Location()),
+	}
+}
+
+// notify reports the triggers in after that are not in before. Alerts are
+// advisory, so one that cannot be delivered is a warning.
+func (a *alerter) notify(ctx context.Context, before, after []transaction.Transaction) {
+	if a == nil {
+		return
+	}
+	for _, trigger := range alert.Added(alert.Evaluate(a.rules, before), alert.Evaluate(a.rules, after)) {
+		fmt.Fprintf(os.Stderr, "alert: %s\n", trigger.Message())
+		if a.webhook == "" {
+			continue
+		}
+		if err := jobs.Deliver(ctx, nil, a.webhook, a.secret, trigger.Notification()); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: alert %q: %v\n", trigger.Rule, err)
+		}
+	}
+}
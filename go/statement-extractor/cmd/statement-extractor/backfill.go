@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/archive"
+	"github.com/example/statement-extractor/internal/eval"
+	"github.com/example/statement-extractor/internal/parser"
+	"github.com/example/statement-extractor/internal/report"
+	"github.com/example/statement-extractor/internal/store"
+	"github.com/example/statement-extractor/internal/table"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+var backfillCmd = &cobra.Command{
+	Use:   "backfill [dir]...",
+	Short: "Extract archived statements again with an improved parser",
+	Long: `Extract the archived statements that --parser reads again and compare the
+transactions with those stored from them, so that a parser improvement reaches
+the statements already in the transaction database. Statements are read from
+the directories given, by default the watch archive (watch.archive, or
+"processed" in watch.dir), and subdirectories with --recursive. Statements
+with nothing stored are skipped; store them with import --db.
+
+Rows are matched as eval run matches them, on date and description ignoring
+case and spacing, so that a corrected amount shows as a change rather than as
+a row removed and another added. A changed or added row keeps what was set on
+the stored row after extraction: its category, splits, tags, owner, report
+exclusion, amortization and recurrence, so edits made to stored transactions
+survive. It also keeps the statement file name it was stored under, so
+reading the statements from a moved archive changes nothing.
+
+The changes are listed for review; --apply stores them, after confirmation
+unless --yes is given. Statements are sent to PDF services again even if the
+processing ledger has them, and the response cache is not used.`,
+	Example: `  statement-extractor backfill --db txns.db --parser cba --since 2022
+  statement-extractor backfill --db txns.db --parser cba --since 2022-07-01 ~/statements/processed --apply`,
+	RunE: runBackfill,
+}
+
+func init() {
+	addDBFlag(backfillCmd)
+	backfillCmd.Flags().String("parser", "", "Parser whose statements to extract again")
+	backfillCmd.Flags().String("since", "", "Only transactions dated from this year, month or day (YYYY, YYYY-MM or YYYY-MM-DD)")
+	backfillCmd.Flags().BoolP("recursive", "r", false, "Also read statements in subdirectories")
+	backfillCmd.Flags().Bool("apply", false, "Store the changes")
+	backfillCmd.Flags().Bool("yes", false, "Store the changes without asking for confirmation")
+	_ = backfillCmd.MarkFlagRequired("parser")
+	addTableFlags(backfillCmd)
+	rootCmd.AddCommand(backfillCmd)
+}
+
+// backfillChange is a difference between the stored and the extracted
+// transactions of a statement
+type backfillChange struct {
+	statement string
+	stored    *transaction.Transaction // nil when added
+	extracted *transaction.Transaction // nil when removed
+}
+
+func runBackfill(cmd *cobra.Command, args []string) error {
+	parserName, _ := cmd.Flags().GetString("parser")
+	sinceFlag, _ := cmd.Flags().GetString("since")
+	recursive, _ := cmd.Flags().GetBool("recursive")
+	apply, _ := cmd.Flags().GetBool("apply")
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	since, err := parseSince(sinceFlag)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.Parsers[strings.ToLower(parserName)]; !ok {
+		return fmt.Errorf("unknown parser %q", parserName)
+	}
+	dirs := args
+	if len(dirs) == 0 {
+		switch {
+		case cfg.Watch.Archive != "":
+			dirs = []string{cfg.Watch.Archive}
+		case cfg.Watch.Dir != "":
+			dirs = []string{filepath.Join(cfg.Watch.Dir, "processed")}
+		default:
+			return errors.New("no archive; give the directories of the statements or set watch.archive")
+		}
+	}
+	out, err := newDisplay(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	enrich, err := newEnrichment(cfg)
+	if err != nil {
+		return err
+	}
+	db, err := openStore(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+	ctx := cmd.Context()
+
+	tmp, err := os.MkdirTemp("", "statement-extractor-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	var files []archive.File
+	for _, dir := range dirs {
+		found, err := archive.Dir(dir, recursive, tmp)
+		if err != nil {
+			return err
+		}
+		files = append(files, found...)
+	}
+
+	extractor := parser.New(cfg, nil)
+	extractor.Ledger, extractor.Reprocess = db, true
+	extractor.Log = db
+	extractor.Budget = parser.NewBudget(cfg)
+	var changes []backfillChange
+	extracted := 0
+	for _, file := range files {
+		document, err := os.ReadFile(file.Path)
+		if err != nil {
+			return fmt.Errorf("failed to read statement: %w", err)
+		}
+		if detected, err := extractor.Detect(file.Path, document); err != nil || !strings.EqualFold(detected, parserName) {
+			continue
+		}
+		stored, err := db.List(ctx, store.Filter{Statement: parser.Digest(document), From: since})
+		if err != nil {
+			return err
+		}
+		if len(stored) == 0 {
+			fmt.Fprintf(os.Stderr, "%s: skipped, nothing stored from it\n", file.Source)
+			continue
+		}
+
+		txns, _, _, err := extractStatement(ctx, cfg, extractor, parserName, file, nil)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file.Source, err)
+		}
+		if err := enrich.apply(ctx, cfg, txns); err != nil {
+			return err
+		}
+		txns = slices.DeleteFunc(txns, func(t transaction.Transaction) bool { return t.Date.Before(since) })
+		for _, c := range backfillDiff(stored, txns) {
+			c.statement = file.Source
+			changes = append(changes, c)
+		}
+		extracted++
+	}
+	if extracted == 0 {
+		return fmt.Errorf("no stored statements read by parser %q in %s", parserName, strings.Join(dirs, ", "))
+	}
+
+	tbl := table.New(
+		table.Column{Name: "change"},
+		table.Column{Name: "statement"},
+		table.Column{Name: "date", Kind: table.Date},
+		table.Column{Name: "description"},
+		table.Column{Name: "amount", Kind: table.Money},
+		table.Column{Name: "fields"},
+	)
+	var remove, add []transaction.Transaction
+	for _, c := range changes {
+		switch {
+		case c.stored == nil:
+			tbl.Append("added", c.statement, c.extracted.Date, c.extracted.Description, c.extracted.Amount, "")
+			add = append(add, *c.extracted)
+		case c.extracted == nil:
+			tbl.Append("removed", c.statement, c.stored.Date, c.stored.Description, c.stored.Amount, "")
+			remove = append(remove, *c.stored)
+		default:
+			tbl.Append("changed", c.statement, c.extracted.Date, c.extracted.Description, c.extracted.Amount, strings.Join(backfillFields(*c.stored, *c.extracted), ", "))
+			remove, add = append(remove, *c.stored), append(add, *c.extracted)
+		}
+	}
+	if len(changes) == 0 {
+		fmt.Printf("%d statements extracted again; no changes\n", extracted)
+		return nil
+	}
+	if err := out.renderTable(cmd, tbl); err != nil {
+		return err
+	}
+	summary := fmt.Sprintf("%d changes in %d statements", len(changes), extracted)
+	if !apply {
+		fmt.Printf("%s; run again with --apply to store them\n", summary)
+		return nil
+	}
+
+	if !yes {
+		if !interactive(os.Stdin) {
+			return errors.New("not storing the changes without confirmation; pass --yes to store them non-interactively")
+		}
+		fmt.Fprintf(os.Stderr, "Store %s? [y/N] ", summary)
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer := strings.ToLower(strings.TrimSpace(line)); answer != "y" && answer != "yes" {
+			fmt.Println("Nothing stored")
+			return nil
+		}
+	}
+	removed, added, err := db.Update(ctx, remove, add)
+	if err != nil {
+		return closedHint(err)
+	}
+	fmt.Printf("Stored %s: %d transactions removed, %d added\n", summary, removed, added)
+	return nil
+}
+
+// parseSince reads --since as a year, a month or a day, returning zero when
+// it is empty
+func parseSince(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if day, err := time.Parse(time.DateOnly, s); err == nil {
+		return day, nil
+	}
+	period, err := report.ParsePeriod(s)
+	if err != nil || strings.Contains(s, ":") {
+		return time.Time{}, fmt.Errorf("invalid --since %q: expected YYYY, YYYY-MM or YYYY-MM-DD", s)
+	}
+	return period.Start, nil
+}
+
+// backfillDiff compares the transactions stored from a statement with those
+// extracted from it again. Rows are matched on date and description, and a
+// matched or added row keeps what was set on the stored row after
+// extraction.
+func backfillDiff(stored, extracted []transaction.Transaction) []backfillChange {
+	var changes []backfillChange
+	matches := eval.Match(stored, extracted)
+	matched := make([]bool, len(extracted))
+	for i, j := range matches {
+		if j < 0 {
+			changes = append(changes, backfillChange{stored: &stored[i]})
+			continue
+		}
+		matched[j] = true
+		if t := keepEdits(stored[i], extracted[j]); len(backfillFields(stored[i], t)) > 0 {
+			changes = append(changes, backfillChange{stored: &stored[i], extracted: &t})
+		}
+	}
+	for j := range extracted {
+		if !matched[j] {
+			changes = append(changes, backfillChange{extracted: &extracted[j]})
+		}
+	}
+	return changes
+}
+
+// keepEdits returns the extracted transaction with what is set on the
+// stored transaction after extraction carried over, and the statement file
+// it was stored under, which differs when the archive has moved since. Splits
+// are dropped when the amount changed, as they would no longer add up to it.
+func keepEdits(stored, extracted transaction.Transaction) transaction.Transaction {
+	t := extracted
+	t.Category, t.CategoryConfidence, t.CategorizedBy = stored.Category, stored.CategoryConfidence, stored.CategorizedBy
+	t.Splits, t.Tags, t.Owner = stored.Splits, stored.Tags, stored.Owner
+	t.ExcludeFromReports, t.AmortizeMonths, t.Recurrence = stored.ExcludeFromReports, stored.AmortizeMonths, stored.Recurrence
+	if t.Amount != stored.Amount {
+		t.Splits = nil
+	}
+	if stored.Statement != nil {
+		ref := *stored.Statement
+		if t.Statement != nil {
+			ref.Page = t.Statement.Page
+		}
+		t.Statement = &ref
+	}
+	return t
+}
+
+// backfillFields returns the fields a backfill changes, leaving out the
+// transaction ID, which follows the extracted row
+func backfillFields(stored, extracted transaction.Transaction) []string {
+	return slices.DeleteFunc(changedFields(stored, extracted), func(f string) bool { return f == "id" })
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestBackfillDiff(t *testing.T) {
+	day := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	stored := []transaction.Transaction{
+		{ID: "a", Date: day, Description: "COLES 0123", Amount: -4550, Category: "Groceries", CategorizedBy: "manual", Tags: []string{"shared"},
+			Splits: []transaction.Split{{Category: "Groceries", Amount: -3000}, {Category: "Household", Amount: -1550}}},
+		{ID: "b", Date: day, Description: "NETFLIX", Amount: -1799, Category: "Entertainment"},
+		{ID: "c", Date: day, Description: "OPENING BALANCE", Amount: 0},
+	}
+	extracted := []transaction.Transaction{
+		{ID: "x", Date: day, Description: "Coles  0123", Amount: -4505, Category: "Shopping"},
+		{ID: "y", Date: day, Description: "NETFLIX", Amount: -1799, Category: "Other"},
+		{ID: "z", Date: day.AddDate(0, 0, 1), Description: "ATM", Amount: -2000},
+	}
+
+	changes := backfillDiff(stored, extracted)
+	require.Len(t, changes, 3, "NETFLIX is unchanged once its category is kept")
+
+	changed := changes[0]
+	assert.Equal(t, "a", changed.stored.ID)
+	assert.Equal(t, transaction.Amount(-4505), changed.extracted.Amount)
+	assert.Equal(t, "Groceries", changed.extracted.Category, "the stored category is kept")
+	assert.Equal(t, []string{"shared"}, changed.extracted.Tags)
+	assert.Nil(t, changed.extracted.Splits, "splits no longer add up to a corrected amount")
+	assert.Equal(t, []string{"amount", "description", "splits"}, backfillFields(*changed.stored, *changed.extracted))
+
+	assert.Equal(t, "c", changes[1].stored.ID)
+	assert.Nil(t, changes[1].extracted)
+	assert.Nil(t, changes[2].stored)
+	assert.Equal(t, "ATM", changes[2].extracted.Description)
+}
+
+func TestBackfillDiff_MovedArchive(t *testing.T) {
+	day := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	stored := []transaction.Transaction{
+		{Date: day, Description: "NETFLIX", Amount: -1799, Statement: &transaction.StatementRef{File: "inbox/jun.pdf", SHA256: "abc", Page: 1}},
+		{Date: day, Description: "COLES", Amount: -4550, Statement: &transaction.StatementRef{File: "inbox/jun.pdf", SHA256: "abc", Page: 1}},
+	}
+	extracted := []transaction.Transaction{
+		{Date: day, Description: "NETFLIX", Amount: -1799, Statement: &transaction.StatementRef{File: "processed/2024/jun.pdf", SHA256: "abc", Page: 1}},
+		{Date: day, Description: "COLES", Amount: -4550, Statement: &transaction.StatementRef{File: "processed/2024/jun.pdf", SHA256: "abc", Page: 2}},
+	}
+
+	changes := backfillDiff(stored, extracted)
+	require.Len(t, changes, 1, "a statement read from another path is unchanged")
+	assert.Equal(t, "COLES", changes[0].extracted.Description)
+	assert.Equal(t, &transaction.StatementRef{File: "inbox/jun.pdf", SHA256: "abc", Page: 2}, changes[0].extracted.Statement, "a corrected page is taken")
+	assert.Equal(t, []string{"statement"}, backfillFields(*changes[0].stored, *changes[0].extracted))
+}
+
+func TestParseSince(t *testing.T) {
+	for in, want := range map[string]time.Time{
+		"":           {},
+		"2022":       time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		"2022-07":    time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC),
+		"2022-07-15": time.Date(2022, 7, 15, 0, 0, 0, 0, time.UTC),
+	} {
+		got, err := parseSince(in)
+		require.NoError(t, err, in)
+		assert.True(t, want.Equal(got), "%s: %s", in, got)
+	}
+	_, err := parseSince("2022-01:2022-03")
+	assert.Error(t, err)
+}
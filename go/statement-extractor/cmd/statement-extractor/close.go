@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/store"
+	"github.com/example/statement-extractor/internal/table"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+var closeCmd = &cobra.Command{
+	Use:   "close",
+	Short: "Close a month in the transaction database so its transactions stop changing",
+	Long: `Close --month in the transaction database, as an accounting period is closed:
+the month's transactions are snapshotted with their totals by category, and
+locked, so reports of the month never silently change. Importing a statement
+of the month again still works, as its transactions are already stored, but
+new transactions in the month, and "patch" changes to it, are refused until
+the month is reopened with --reopen.
+
+--diff compares a month's transactions with its snapshot, to review what
+changed while it was reopened before closing it again. --list shows the
+months closed.
+
+The lock covers the transaction database; JSON files written by extract are
+not affected.`,
+	Example: `  statement-extractor close --db txns.db --month 2024-06
+  statement-extractor close --db txns.db --month 2024-06 --reopen
+  statement-extractor close --db txns.db --month 2024-06 --diff`,
+	Args: cobra.NoArgs,
+	RunE: runClose,
+}
+
+func init() {
+	addDBFlag(closeCmd)
+	closeCmd.Flags().String("month", "", "Month to close (YYYY-MM)")
+	closeCmd.Flags().Bool("reopen", false, "Reopen the month so its transactions can change")
+	closeCmd.Flags().Bool("diff", false, "Compare the month's transactions with its snapshot")
+	closeCmd.Flags().Bool("list", false, "List the closed months")
+	closeCmd.MarkFlagsMutuallyExclusive("reopen", "diff", "list")
+	addTableFlags(closeCmd)
+	rootCmd.AddCommand(closeCmd)
+}
+
+func runClose(cmd *cobra.Command, args []string) error {
+	monthFlag, _ := cmd.Flags().GetString("month")
+	reopen, _ := cmd.Flags().GetBool("reopen")
+	diff, _ := cmd.Flags().GetBool("diff")
+	list, _ := cmd.Flags().GetBool("list")
+
+	var month time.Time
+	if !list {
+		if monthFlag == "" {
+			return errors.New("no month; pass --month YYYY-MM")
+		}
+		var err error
+		if month, err = time.Parse("2006-01", monthFlag); err != nil {
+			return fmt.Errorf("invalid --month %q: expected YYYY-MM", monthFlag)
+		}
+	}
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	out, err := newDisplay(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	db, err := openStore(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+	ctx := cmd.Context()
+
+	switch {
+	case list:
+		closings, err := db.Closings(ctx)
+		if err != nil {
+			return err
+		}
+		tbl := table.New(
+			table.Column{Name: "month"},
+			table.Column{Name: "closed"},
+			table.Column{Name: "reopened"},
+			table.Column{Name: "count", Kind: table.Number},
+			table.Column{Name: "total", Kind: table.Money},
+		)
+		for _, c := range closings {
+			reopened := ""
+			if c.Open() {
+				reopened = c.ReopenedAt.Local().Format(time.DateTime)
+			}
+			tbl.Append(c.Month, c.ClosedAt.Local().Format(time.DateTime), reopened, c.Count, c.Total)
+		}
+		return out.renderTable(cmd, tbl)
+
+	case reopen:
+		if err := db.Reopen(ctx, month); err != nil {
+			return err
+		}
+		fmt.Printf("Reopened %s; close it again once it is corrected\n", monthFlag)
+		return nil
+
+	case diff:
+		snapshot, err := db.Snapshot(ctx, month)
+		if err != nil {
+			return err
+		}
+		now, err := db.List(ctx, store.Filter{From: month, To: month.AddDate(0, 1, 0)})
+		if err != nil {
+			return err
+		}
+		changes := store.Diff(snapshot, now)
+		tbl := table.New(
+			table.Column{Name: "change"},
+			table.Column{Name: "date", Kind: table.Date},
+			table.Column{Name: "description"},
+			table.Column{Name: "amount", Kind: table.Money},
+			table.Column{Name: "category"},
+			table.Column{Name: "fields"},
+		)
+		for _, c := range changes {
+			switch {
+			case c.Before == nil:
+				tbl.Append("added", c.After.Date, c.After.Description, c.After.Amount, c.After.Category, "")
+			case c.After == nil:
+				tbl.Append("removed", c.Before.Date, c.Before.Description, c.Before.Amount, c.Before.Category, "")
+			default:
+				tbl.Append("changed", c.After.Date, c.After.Description, c.After.Amount, c.After.Category,
+					strings.Join(changedFields(*c.Before, *c.After), ", "))
+			}
+		}
+		if err := out.renderTable(cmd, tbl); err != nil {
+			return err
+		}
+		fmt.Printf("%d changes since %s was closed\n", len(changes), monthFlag)
+		return nil
+	}
+
+	c, err := db.Close(ctx, month)
+	if err != nil {
+		return err
+	}
+	tbl := table.New(
+		table.Column{Name: "category"},
+		table.Column{Name: "count", Kind: table.Number},
+		table.Column{Name: "total", Kind: table.Money},
+	)
+	for _, t := range c.Summary {
+		tbl.Append(t.Group, t.Count, t.Amount)
+	}
+	if err := out.renderTable(cmd, tbl); err != nil {
+		return err
+	}
+	fmt.Printf("Closed %s: %d transactions, total %s\n", c.Month, c.Count, out.amount(c.Total.Float()))
+	return nil
+}
+
+// changedFields returns the JSON names of the fields that differ between two
+// versions of a transaction
+func changedFields(before, after transaction.Transaction) []string {
+	fields := func(t transaction.Transaction) map[string]any {
+		var m map[string]any
+		b, _ := json.Marshal(t)
+		_ = json.Unmarshal(b, &m)
+		return m
+	}
+	a, b := fields(before), fields(after)
+	var changed []string
+	for name, value := range a {
+		if !reflect.DeepEqual(value, b[name]) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+// closedHint explains how to change a closed month when err is
+// store.ErrClosed
+func closedHint(err error) error {
+	if errors.Is(err, store.ErrClosed) {
+		return fmt.Errorf("%w; reopen it with \"close --reopen --month YYYY-MM\" first", err)
+	}
+	return err
+}
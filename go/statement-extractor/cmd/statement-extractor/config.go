@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/cache"
+	"github.com/example/statement-extractor/internal/clock"
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/health"
+	"github.com/example/statement-extractor/internal/quarantine"
+)
+
+// loadConfig loads the file named by --config, falling back to the standard
+// search paths and finally the built-in defaults
+func loadConfig(cmd *cobra.Command) (*config.Config, error) {
+	path, _ := cmd.Flags().GetString("config")
+	overlays, _ := cmd.Flags().GetStringArray("config-overlay")
+	strict, _ := cmd.Flags().GetBool("strict-config")
+
+	if path == "" {
+		path = config.Discover()
+	}
+	if path == "" {
+		return config.Default(), nil
+	}
+	if strict {
+		return config.LoadConfigStrict(path, overlays...)
+	}
+	return config.LoadConfig(path, overlays...)
+}
+
+// loadHealth reads the provider health file from the state directory
+func loadHealth(ctx context.Context, cfg *config.Config) (*health.Tracker, error) {
+	dir, err := cfg.StateDirectory()
+	if err != nil {
+		return nil, err
+	}
+	return health.Load(filepath.Join(dir, health.FileName), clock.From(ctx))
+}
+
+// responseCache returns the configured PDF service response cache, or nil
+// when it is not enabled
+func responseCache(cfg *config.Config) (cache.Cache, error) {
+	if !cfg.Cache.Enabled {
+		return nil, nil
+	}
+	dir := cfg.Cache.Dir
+	if dir == "" {
+		state, err := cfg.StateDirectory()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(state, "responses")
+	}
+	return cache.New(cfg.Cache.Backend, dir)
+}
+
+// loadQuarantine reads the failed attempts file from the state directory for
+// the configured quarantine directory
+func loadQuarantine(ctx context.Context, cfg *config.Config) (*quarantine.Quarantine, error) {
+	dir, err := cfg.StateDirectory()
+	if err != nil {
+		return nil, err
+	}
+	return quarantine.Load(filepath.Join(dir, quarantine.FileName), quarantineDir(cfg, dir), cfg.Quarantine.MaxAttempts, clock.From(ctx))
+}
+
+// quarantineDir returns the configured quarantine directory, defaulting to
+// one in the state directory
+func quarantineDir(cfg *config.Config, state string) string {
+	if cfg.Quarantine.Dir != "" {
+		return cfg.Quarantine.Dir
+	}
+	return filepath.Join(state, "quarantine")
+}
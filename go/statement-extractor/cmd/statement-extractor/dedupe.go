@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/dedup"
+	"github.com/example/statement-extractor/internal/table"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+var dedupeCmd = &cobra.Command{
+	Use:   "dedupe <file>...",
+	Short: "Remove transactions repeated by overlapping statements",
+	Long: `Find transactions that appear more than once because statements overlap,
+such as a purchase on both the January and the February statement, and keep
+one copy of each.
+
+Copies have the same ID, or are from the same merchant within --days and
+--amount of each other. Transactions from the same statement file are never
+copies, so two identical purchases on one statement are both kept.
+
+--strategy picks the copy to keep: keep-first (the earliest in the input),
+keep-latest (the last, from the most recent statement when the files are
+given in order) or interactive, which asks for each group. Use --dry-run to
+list the copies without writing anything.
+
+A single file is rewritten in place unless --output is given; several files
+are combined into --output.`,
+	Example: `  statement-extractor dedupe 2024.json --dry-run
+  statement-extractor dedupe jan.json feb.json mar.json --strategy keep-latest -o q1.json
+  statement-extractor dedupe 2024.json --strategy interactive`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runDedupe,
+}
+
+func init() {
+	dedupeCmd.Flags().String("strategy", "keep-first", "Copy to keep: keep-first, keep-latest or interactive")
+	dedupeCmd.Flags().Int("days", dedup.DefaultTolerance.Days, "Maximum date difference between copies")
+	dedupeCmd.Flags().Float64("amount", dedup.DefaultTolerance.Amount, "Maximum amount difference between copies")
+	dedupeCmd.Flags().Bool("dry-run", false, "List the copies and exit")
+	dedupeCmd.Flags().StringP("output", "o", "", "Write JSON to this file (default: the input file)")
+	addTableFlags(dedupeCmd)
+	rootCmd.AddCommand(dedupeCmd)
+}
+
+func runDedupe(cmd *cobra.Command, args []string) error {
+	strategy, _ := cmd.Flags().GetString("strategy")
+	days, _ := cmd.Flags().GetInt("days")
+	amount, _ := cmd.Flags().GetFloat64("amount")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	output, _ := cmd.Flags().GetString("output")
+
+	var keep func([]transaction.Transaction) (int, error)
+	switch strategy {
+	case "keep-first":
+		keep = transaction.KeepFirst
+	case "keep-latest":
+		keep = transaction.KeepLatest
+	case "interactive":
+		keep = askKeep(bufio.NewReader(os.Stdin))
+	default:
+		return fmt.Errorf("invalid --strategy %q: expected keep-first, keep-latest or interactive", strategy)
+	}
+	if output == "" && !dryRun {
+		if len(args) > 1 {
+			return errors.New("--output is required with several input files")
+		}
+		output = args[0]
+	}
+
+	txns, err := loadTransactions(args)
+	if err != nil {
+		return err
+	}
+	list := transaction.TransactionList{Transactions: txns, Total: len(txns)}
+	tol := transaction.Tolerance{Days: days, Amount: amount}
+
+	if dryRun {
+		return previewDedupe(cmd, &list, tol)
+	}
+	removed, err := list.Deduplicate(tol, keep)
+	if err != nil {
+		return err
+	}
+	if err := writeTransactions(cmd.Context(), output, strings.Join(args, ", "), list.Transactions); err != nil {
+		return err
+	}
+	if output != "-" {
+		fmt.Fprintf(os.Stderr, "Removed %d duplicates, %d transactions left in %s\n", len(removed), list.Total, output)
+	}
+	return nil
+}
+
+// previewDedupe lists each group of copies
+func previewDedupe(cmd *cobra.Command, list *transaction.TransactionList, tol transaction.Tolerance) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	out, err := newDisplay(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	groups := list.FindDuplicates(tol)
+	if len(groups) == 0 {
+		fmt.Println("No duplicates")
+		return nil
+	}
+	tbl := table.New(
+		table.Column{Name: "group", Kind: table.Number},
+		table.Column{Name: "date", Kind: table.Date},
+		table.Column{Name: "amount", Kind: table.Money},
+		table.Column{Name: "description"},
+		table.Column{Name: "statement"},
+	)
+	copies := 0
+	for g, group := range groups {
+		for _, i := range group {
+			t := list.Transactions[i]
+			tbl.Append(g+1, t.Date, t.Amount, t.Description, statementName(t))
+		}
+		copies += len(group) - 1
+	}
+	if err := out.renderTable(cmd, tbl); err != nil {
+		return err
+	}
+	fmt.Printf("%d groups, %d duplicates\n", len(groups), copies)
+	return nil
+}
+
+// statementName returns the statement file a transaction came from, if known
+func statementName(t transaction.Transaction) string {
+	if t.Statement == nil {
+		return ""
+	}
+	return t.Statement.File
+}
+
+// askKeep prompts on stderr for the copy to keep from each group
+func askKeep(in *bufio.Reader) func([]transaction.Transaction) (int, error) {
+	return func(group []transaction.Transaction) (int, error) {
+		for i, t := range group {
+			fmt.Fprintf(os.Stderr, "  %d) %s %s %s", i+1, t.Date.Format("2006-01-02"), t.Amount, t.Description)
+			if name := statementName(t); name != "" {
+				fmt.Fprintf(os.Stderr, " (%s)", name)
+			}
+			fmt.Fprintln(os.Stderr)
+		}
+		for {
+			fmt.Fprintf(os.Stderr, "  keep [1-%d] or [a]ll? ", len(group))
+			line, err := in.ReadString('\n')
+			answer := strings.ToLower(strings.TrimSpace(line))
+			if answer == "a" || answer == "all" {
+				return -1, nil
+			}
+			if n, convErr := strconv.Atoi(answer); convErr == nil && n >= 1 && n <= len(group) {
+				return n - 1, nil
+			}
+			if err != nil {
+				return 0, fmt.Errorf("no answer for duplicate: %w", err)
+			}
+		}
+	}
+}
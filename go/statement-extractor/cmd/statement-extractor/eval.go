@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/eval"
+	"github.com/example/statement-extractor/internal/table"
+)
+
+var evalCmd = &cobra.Command{
+	Use:   "eval",
+	Short: "Measure extraction quality",
+}
+
+var evalRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Score extraction outputs against labeled fixtures",
+	Long: `Score extraction outputs against labeled expected transactions.
+
+Each fixture is a directory holding expected.json plus one TransactionList
+JSON output per provider run, named <provider>.json or <provider>@<prompt>.json.
+Rows match on date and description. Precision is the share of extracted rows
+that match a labeled row, recall the share of labeled rows that were
+extracted, and amounts the share of matched rows with the correct amount.
+Scores are combined per provider and prompt.`,
+	Example: `  statement-extractor eval run --fixtures testdata/eval
+  statement-extractor eval run --detail --sort recall`,
+	RunE: runEval,
+}
+
+func init() {
+	evalRunCmd.Flags().String("fixtures", "testdata/eval", "Directory of fixture directories")
+	evalRunCmd.Flags().Bool("detail", false, "Show a row per fixture instead of per provider and prompt")
+	addTableFlags(evalRunCmd)
+	evalCmd.AddCommand(evalRunCmd)
+	rootCmd.AddCommand(evalCmd)
+}
+
+func runEval(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	out, err := newDisplay(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	dir, _ := cmd.Flags().GetString("fixtures")
+	detail, _ := cmd.Flags().GetBool("detail")
+
+	results, err := eval.Run(dir)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		fmt.Printf("No fixture outputs found in %s\n", dir)
+		return nil
+	}
+
+	columns := []table.Column{
+		{Name: "provider"},
+		{Name: "prompt"},
+		{Name: "precision", Header: "PRECISION %", Kind: table.Number},
+		{Name: "recall", Header: "RECALL %", Kind: table.Number},
+		{Name: "amounts", Header: "AMOUNTS %", Kind: table.Number},
+		{Name: "rows", Kind: table.Number},
+	}
+	if detail {
+		tbl := table.New(append([]table.Column{{Name: "fixture"}}, columns...)...)
+		for _, r := range results {
+			tbl.Append(r.Fixture, r.Provider, r.Prompt, percent(r.Score.Precision()), percent(r.Score.Recall()), percent(r.Score.AmountAccuracy()), r.Score.Actual)
+		}
+		return out.renderTable(cmd, tbl)
+	}
+
+	tbl := table.New(append(columns, table.Column{Name: "fixtures", Kind: table.Number})...)
+	for _, s := range eval.Summarize(results) {
+		tbl.Append(s.Provider, s.Prompt, percent(s.Score.Precision()), percent(s.Score.Recall()), percent(s.Score.AmountAccuracy()), s.Score.Actual, s.Fixtures)
+	}
+	return out.renderTable(cmd, tbl)
+}
+
+// percent converts a ratio to a percentage with one decimal place
+func percent(ratio float64) float64 {
+	return math.Round(ratio*1000) / 10
+}
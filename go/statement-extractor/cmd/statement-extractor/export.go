@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/export"
+	"github.com/example/statement-extractor/internal/money"
+	"github.com/example/statement-extractor/internal/report"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export transactions for other people and tools",
+}
+
+var exportAnnualCmd = &cobra.Command{
+	Use:   "annual",
+	Short: "Consolidated year of transactions across accounts",
+	Long: `Assemble one calendar year of transactions from every account into a set of
+CSV files for an accountant: one file per account, ordered by date and naming
+the statement each transaction came from, plus a summary of the net amount per
+category for each account. With --format xlsx the same is written as a single
+workbook with a sheet per account.
+
+Accounts are the account names set on parsers, falling back to the bank.`,
+	Example: `  statement-extractor export annual --year 2024 --input cba.json --input anz.json -o tax-2024
+  statement-extractor export annual --year 2024 --input 2024.json --format xlsx`,
+	RunE: runExportAnnual,
+}
+
+var exportXLSXCmd = &cobra.Command{
+	Use:   "xlsx",
+	Short: "Spreadsheet with a sheet per month or account",
+	Long: `Write transactions to an .xlsx workbook with a sheet per month or per account
+and a Categories sheet pivoting the net amount per category across them.
+Sheets have a frozen header row and filters, and amounts use the configured
+display currency's format.`,
+	Example: `  statement-extractor export xlsx --input 2024.json --period 2024 -o 2024.xlsx
+  statement-extractor export xlsx --input 2024.json --by account -o accounts.xlsx`,
+	RunE: runExportXLSX,
+}
+
+func init() {
+	exportAnnualCmd.Flags().Int("year", 0, "Calendar year to export")
+	exportAnnualCmd.Flags().StringSlice("input", nil, "TransactionList JSON file(s) to export")
+	exportAnnualCmd.Flags().StringP("output-dir", "o", ".", "Directory to write the files to")
+	exportAnnualCmd.Flags().String("format", "csv", "Output format (csv or xlsx)")
+	_ = exportAnnualCmd.MarkFlagRequired("year")
+	_ = exportAnnualCmd.MarkFlagRequired("input")
+
+	exportXLSXCmd.Flags().StringSlice("input", nil, "TransactionList JSON file(s) to export")
+	exportXLSXCmd.Flags().String("period", "", "Only export this period (YYYY, YYYY-MM or YYYY-MM:YYYY-MM)")
+	addTagFlag(exportXLSXCmd.Flags())
+	exportXLSXCmd.Flags().String("by", export.ByMonth, "Sheet per month or per account")
+	exportXLSXCmd.Flags().StringP("output", "o", "", "Workbook file to write")
+	_ = exportXLSXCmd.MarkFlagRequired("input")
+	_ = exportXLSXCmd.MarkFlagRequired("output")
+
+	exportCmd.AddCommand(exportAnnualCmd)
+	exportCmd.AddCommand(exportXLSXCmd)
+	for _, format := range export.Formats() {
+		exportCmd.AddCommand(newExportFormatCmd(format))
+	}
+	rootCmd.AddCommand(exportCmd)
+}
+
+func runExportAnnual(cmd *cobra.Command, args []string) error {
+	year, _ := cmd.Flags().GetInt("year")
+	inputs, _ := cmd.Flags().GetStringSlice("input")
+	dir, _ := cmd.Flags().GetString("output-dir")
+	format, _ := cmd.Flags().GetString("format")
+	if format != "csv" && format != "xlsx" {
+		return fmt.Errorf("invalid --format %q: expected csv or xlsx", format)
+	}
+
+	txns, err := loadTransactions(inputs)
+	if err != nil {
+		return err
+	}
+	annual := export.NewAnnual(txns, year)
+	if annual.Len() == 0 {
+		return fmt.Errorf("no transactions dated in %d", year)
+	}
+
+	var paths []string
+	if format == "xlsx" {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, fmt.Sprintf("%d.xlsx", year))
+		err = writeWorkbook(cfg, path, func(w io.Writer, currencyFormat string) error {
+			return annual.WriteXLSX(w, currencyFormat)
+		})
+		if err != nil {
+			return err
+		}
+		paths = []string{path}
+	} else if paths, err = annual.WriteDir(dir); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d transactions from %d accounts for %d\n", annual.Len(), len(annual.Accounts), year)
+	for _, path := range paths {
+		fmt.Println(path)
+	}
+	return nil
+}
+
+func runExportXLSX(cmd *cobra.Command, args []string) error {
+	inputs, _ := cmd.Flags().GetStringSlice("input")
+	periodFlag, _ := cmd.Flags().GetString("period")
+	by, _ := cmd.Flags().GetString("by")
+	output, _ := cmd.Flags().GetString("output")
+	tags, _ := cmd.Flags().GetStringSlice("tag")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	txns, err := loadTransactions(inputs)
+	if err != nil {
+		return err
+	}
+	if txns, err = inPeriod(withTags(txns, tags), periodFlag); err != nil {
+		return err
+	}
+
+	err = writeWorkbook(cfg, output, func(w io.Writer, currencyFormat string) error {
+		return export.WriteXLSX(w, txns, by, currencyFormat)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d transactions to %s\n", len(txns), output)
+	return nil
+}
+
+// newExportFormatCmd returns the export subcommand for a file format
+func newExportFormatCmd(format export.Format) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   format.Name,
+		Short: "Write a " + format.Description,
+		Long: `Write transactions as a ` + format.Description + `, oldest first. Where the
+format has a memo or notes field it names the statement each transaction came
+from.`,
+		Example: fmt.Sprintf("  statement-extractor export %s --input 2024.json --period 2024-01 -o 2024-01%s", format.Name, format.Extension),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inputs, _ := cmd.Flags().GetStringSlice("input")
+			periodFlag, _ := cmd.Flags().GetString("period")
+			output, _ := cmd.Flags().GetString("output")
+			tags, _ := cmd.Flags().GetStringSlice("tag")
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			txns, err := loadTransactions(inputs)
+			if err != nil {
+				return err
+			}
+			if txns, err = inPeriod(withTags(txns, tags), periodFlag); err != nil {
+				return err
+			}
+			transaction.SortByDate(txns)
+			return writeOutput(output, func(w io.Writer) error { return format.Write(w, txns, cfg.Export) })
+		},
+	}
+	cmd.Flags().StringSlice("input", nil, "TransactionList JSON file(s) to export")
+	cmd.Flags().String("period", "", "Only export this period (YYYY, YYYY-MM or YYYY-MM:YYYY-MM)")
+	addTagFlag(cmd.Flags())
+	cmd.Flags().StringP("output", "o", "", "Write to this file instead of stdout")
+	_ = cmd.MarkFlagRequired("input")
+	return cmd
+}
+
+// inPeriod keeps the transactions in a --period, if one is given
+func inPeriod(txns []transaction.Transaction, periodFlag string) ([]transaction.Transaction, error) {
+	if periodFlag == "" {
+		return txns, nil
+	}
+	period, err := report.ParsePeriod(periodFlag)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(txns, func(t transaction.Transaction) bool { return !period.Contains(t.Date) }), nil
+}
+
+// writeOutput runs write against the file at path, or stdout when path is
+// empty or "-"
+func writeOutput(path string, write func(io.Writer) error) error {
+	if path == "" || path == "-" {
+		return write(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// writeWorkbook creates path and writes a workbook to it using the
+// configured display currency's number format
+func writeWorkbook(cfg *config.Config, path string, write func(io.Writer, string) error) error {
+	formatter, err := money.New(cfg.Money)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create workbook: %w", err)
+	}
+	if err := write(f, formatter.ExcelFormat("")); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write workbook: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,608 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/archive"
+	"github.com/example/statement-extractor/internal/batch"
+	"github.com/example/statement-extractor/internal/categorizer"
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/export"
+	"github.com/example/statement-extractor/internal/merchant"
+	"github.com/example/statement-extractor/internal/parser"
+	"github.com/example/statement-extractor/internal/postprocess"
+	"github.com/example/statement-extractor/internal/quarantine"
+	"github.com/example/statement-extractor/internal/script"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+var extractCmd = &cobra.Command{
+	Use:   "extract [<statement>...]",
+	Short: "Extract and categorize transactions from bank statements",
+	Long: `Parse bank statements into a categorized TransactionList JSON document.
+
+Each statement is handled by the parser configured under [parsers] for its
+bank, given with --bank or detected from the statement text, the file name or
+the only configured parser. Parsers with method = "pdf" send the PDF to their
+[pdf_services] provider, or to its fallback providers in turn when it fails;
+method = "content" parsers read text statements directly and have their
+provider extract the text of PDFs, while method = "local" extracts it offline
+with pdftotext from poppler-utils.
+Parsers with method = "csv" read the bank's CSV export using the configured
+column mapping, and method = "qif" reads Quicken QIF exports, keeping any
+categories they carry.
+
+A provider that failed three times in a row is skipped for five minutes; see
+"providers status".
+
+With a transaction database, from --db or database in the config file, every
+statement sent to a provider is entered in its processing ledger by content
+hash, and a statement already in it is skipped rather than paid for again,
+whatever its file name; --reprocess sends it anyway. With [cache] enabled the
+provider's earlier response is reused instead, at no cost.
+
+With max_tokens_per_run or max_cost_per_run set, a provider call that could go
+over the limit is not made: extraction stops there, asking first when run in
+a terminal, and the statements done so far are still written.
+
+--concurrency extracts several statements at once, which mostly helps with
+slow PDF services; set requests_per_minute on a service to keep the calls to
+it under its rate limit. Calls already under way when the run budget runs out
+still finish, so a concurrent run can go a little over it. Output and
+summaries keep the order of the statements given.
+
+A statement that fails does not stop the others: the transactions of those
+that succeeded are written, a summary of every statement that failed or was
+skipped follows on stderr, and the command exits with status 3 to tell a
+partial failure from a complete one (status 1). --fail-fast stops at the
+first failure instead. A statement from --input-dir that fails
+quarantine.max_attempts runs in a row is moved to the quarantine directory
+with an error report, so the inbox is not retried forever; see
+"retry-quarantine".
+
+ZIP and 7z archives and saved emails are expanded and every statement inside
+is processed. --input-dir processes every PDF and CSV statement and archive in
+a directory, and with --recursive in its subdirectories too, detecting the
+bank of each unless --bank is given.
+
+The transactions of all statements are merged into one output. With
+--per-file each statement is written to its own file under the --output
+directory instead, named after the statement with the extension of the
+output format, e.g. 2024/jan.pdf becomes 2024/jan.json; statements that differ
+only in their extension keep it, as in jan.pdf.json and jan.csv.json.
+
+The statement's masked account number, period and opening and closing
+balances are kept under "statements" when the parser can read them, and a
+warning is printed when the transactions do not add up to the difference
+between the balances; see also "validate".
+
+Transactions without an ID from the statement get one hashed from their
+source, date, amount and description, so extracting a statement again yields
+the same IDs.
+
+The result is written as JSON, or with --format in one of the export file
+formats: ` + exportFormatNames() + `.
+
+To fix part of a statement without extracting all of it again, give --pages
+and/or --date-range: the transactions from those pages or dates replace the
+ones from the same pages or dates of the same statement in the --output file
+and the --db database, and everything else there is kept. PDF services are
+only sent the pages asked for; other methods read the whole statement, as
+does --date-range on its own, but still patch only that part.
+
+With --llm-categorize, the "llm" stage is added to the end of the categorizer
+chain, unless it is configured in it already, with categorizer.llm.threshold
+as its threshold. Transactions no earlier stage categorizes are sent in
+batches to the model configured under [categorizer.llm], which picks one of
+the configured categories with a confidence score kept in
+category_confidence. A cost cap is required, from categorizer.llm.max_cost or
+--llm-max-cost; once the next batch could go over it the remaining
+transactions get the default category.`,
+	Example: `  statement-extractor extract cba-2024-01.pdf --output cba-2024-01.json
+  statement-extractor extract --bank anz statements.zip > anz.json
+  statement-extractor extract --input-dir ./statements --recursive -o all.json
+  statement-extractor extract --input-dir ./statements --per-file -o ./extracted
+  statement-extractor extract --input-dir ./statements --concurrency 4 -o all.json
+  statement-extractor extract --format ledger cba-2024-01.pdf >> 2024.journal
+  statement-extractor extract cba-2024-03.pdf --pages 7-9 -o cba-2024-03.json
+  statement-extractor extract cba-2024-03.pdf --date-range 2024-03-10:2024-03-20 --db txns.db`,
+	RunE: runExtract,
+}
+
+func init() {
+	extractCmd.Flags().String("bank", "", "Parser to use (default: detected per statement)")
+	extractCmd.Flags().StringP("output", "o", "", "Write to this file instead of stdout")
+	extractCmd.Flags().String("format", "json", "Output format: json or "+exportFormatNames())
+	extractCmd.Flags().String("pages", "", "Extract only these pages (e.g. 7-9) and patch them into the output or database")
+	extractCmd.Flags().String("date-range", "", "Extract only these dates (YYYY-MM-DD:YYYY-MM-DD) and patch them into the output or database")
+	extractCmd.Flags().String("db", "", "SQLite transaction database to patch with --pages or --date-range, and whose processing ledger is checked")
+	extractCmd.Flags().Bool("reprocess", false, "Send statements to providers even when the processing ledger shows they were sent before")
+	addWaitFlag(extractCmd)
+	extractCmd.Flags().Bool("llm-categorize", false, "Ask the [categorizer.llm] model to categorize what the rules leave uncategorized")
+	extractCmd.Flags().Float64("llm-max-cost", 0, "Cost cap for --llm-categorize (default: categorizer.llm.max_cost)")
+	extractCmd.Flags().Bool("fail-fast", false, "Stop at the first statement that fails instead of carrying on with the rest")
+	extractCmd.Flags().String("input-dir", "", "Also process the statements in this directory")
+	extractCmd.Flags().Bool("recursive", false, "With --input-dir, also process the statements in its subdirectories")
+	extractCmd.Flags().Bool("per-file", false, "Write each statement to its own file under the --output directory")
+	extractCmd.Flags().IntP("concurrency", "j", 1, "Extract this many statements at once")
+	rootCmd.AddCommand(extractCmd)
+}
+
+func runExtract(cmd *cobra.Command, args []string) error {
+	bank, _ := cmd.Flags().GetString("bank")
+	output, _ := cmd.Flags().GetString("output")
+	formatName, _ := cmd.Flags().GetString("format")
+	llmCategorize, _ := cmd.Flags().GetBool("llm-categorize")
+	llmMaxCost, _ := cmd.Flags().GetFloat64("llm-max-cost")
+	failFast, _ := cmd.Flags().GetBool("fail-fast")
+	inputDir, _ := cmd.Flags().GetString("input-dir")
+	recursive, _ := cmd.Flags().GetBool("recursive")
+	perFile, _ := cmd.Flags().GetBool("per-file")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+
+	switch {
+	case len(args) == 0 && inputDir == "":
+		return errors.New("give the statements to extract or --input-dir")
+	case recursive && inputDir == "":
+		return errors.New("--recursive needs --input-dir")
+	case perFile && (output == "" || output == "-"):
+		return errors.New("--per-file needs an --output directory")
+	case concurrency < 1:
+		return fmt.Errorf("invalid --concurrency %d: expected at least 1", concurrency)
+	}
+	format, ok := export.Lookup(formatName)
+	if !ok && formatName != "json" {
+		return fmt.Errorf("invalid --format %q: expected json or %s", formatName, exportFormatNames())
+	}
+	patch, err := newExtractPatch(cmd)
+	if err != nil {
+		return err
+	}
+	if patch != nil {
+		if perFile || concurrency > 1 {
+			return errors.New("--per-file and --concurrency cannot be used with --pages or --date-range")
+		}
+		if err := patch.check(cmd, output, formatName); err != nil {
+			return err
+		}
+	}
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	if llmCategorize && !slices.ContainsFunc(cfg.Categorizer.Stages, func(s config.CategorizerStage) bool { return strings.EqualFold(s.Name, "llm") }) {
+		stages := cfg.Categorizer.Stages
+		if len(stages) == 0 {
+			stages = categorizer.DefaultStages
+		}
+		cfg.Categorizer.Stages = append(slices.Clip(stages), config.CategorizerStage{Name: "llm", Threshold: cfg.Categorizer.LLM.Threshold})
+	}
+	if llmMaxCost > 0 {
+		cfg.Categorizer.LLM.MaxCost = llmMaxCost
+	}
+	enrich, err := newEnrichment(cfg)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.MkdirTemp("", "statement-extractor-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	files, err := archive.Inputs(args, tmp)
+	if err != nil {
+		return err
+	}
+	if inputDir != "" {
+		found, err := archive.Dir(inputDir, recursive, tmp)
+		if err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			return fmt.Errorf("no statements found in %s", inputDir)
+		}
+		files = append(files, found...)
+	}
+
+	extractor := parser.New(cfg, nil)
+	// Provider health is advisory; extraction works without it
+	if extractor.Health, err = loadHealth(cmd.Context(), cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	} else {
+		defer func() {
+			if err := extractor.Health.Save(cmd.Context()); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+			}
+		}()
+	}
+	if extractor.Cache, err = responseCache(cfg); err != nil {
+		return err
+	}
+	// A patch extracts part of a statement again on purpose
+	if dbPath, _ := cmd.Flags().GetString("db"); patch == nil && (dbPath != "" || cfg.Database != "") {
+		db, err := openStore(cmd, cfg)
+		if err != nil {
+			return err
+		}
+		extractor.Ledger, extractor.Log = db, db
+		extractor.Reprocess, _ = cmd.Flags().GetBool("reprocess")
+	}
+	if extractor.Budget = parser.NewBudget(cfg); extractor.Budget != nil && interactive(os.Stdin) {
+		extractor.Budget.Confirm = confirmOverBudget
+	}
+	if patch != nil {
+		extractor.Pages = patch.pages
+	}
+	// Only whole statements from the input directory are quarantined
+	var q *quarantine.Quarantine
+	if inputDir != "" && patch == nil && cfg.Quarantine.MaxAttempts > 0 {
+		if q, err = loadQuarantine(cmd.Context(), cfg); err != nil {
+			return err
+		}
+		defer func() {
+			if err := q.Save(cmd.Context()); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+			}
+		}()
+	}
+
+	// Failures from here on are about statements, not the command line
+	cmd.SilenceUsage = true
+
+	// Statements are extracted concurrently but reported and merged in
+	// input order
+	results := make([]statementResult, len(files))
+	starting, stop := context.WithCancel(cmd.Context())
+	defer stop()
+	var skipped, failed error // why statements were not started
+	batch.Run(starting, len(files), concurrency, func(i int) {
+		r := &results[i]
+		r.txns, r.info, r.name, r.err = extractStatement(cmd.Context(), cfg, extractor, bank, files[i], patch)
+		r.done = true
+	}, func(i int) {
+		file, r := files[i], results[i]
+		switch {
+		case errors.Is(r.err, parser.ErrBudgetExceeded):
+			skipped = cmp.Or(skipped, r.err)
+			stop()
+		case errors.Is(r.err, parser.ErrProcessed):
+			if len(files) > 1 {
+				fmt.Fprintf(os.Stderr, "%s: skipped: %v\n", file.Source, r.err)
+			}
+		case r.err != nil:
+			if len(files) > 1 && !failFast {
+				fmt.Fprintf(os.Stderr, "%s: failed: %v\n", file.Source, r.err)
+			}
+			if q != nil && within(inputDir, file.Path) {
+				quarantineStatement(q, file, r.name, r.err)
+			}
+			if failFast && failed == nil {
+				failed = fmt.Errorf("%s: %w", file.Source, r.err)
+				stop()
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "%s: %d transactions (%s)\n", file.Source, len(r.txns), r.name)
+			if q != nil {
+				q.Succeed(file.Path)
+			}
+		}
+	})
+	if failed != nil {
+		return failed
+	}
+	skipped = cmp.Or(skipped, cmd.Context().Err())
+
+	var txns []transaction.Transaction
+	var sources []string
+	var statements []transaction.StatementInfo
+	var report batch.Report
+	for i, file := range files {
+		r := results[i]
+		switch {
+		case !r.done:
+			report.Skip(file.Source, skipped)
+		case errors.Is(r.err, parser.ErrBudgetExceeded), errors.Is(r.err, parser.ErrProcessed):
+			report.Skip(file.Source, r.err)
+		case r.err != nil:
+			report.Fail(file.Source, r.name, r.err)
+		default:
+			report.Succeed(file.Source, r.name, len(r.txns))
+			txns = append(txns, r.txns...)
+			sources = append(sources, file.Source)
+			if r.info.Found() {
+				statements = append(statements, r.info)
+			}
+		}
+	}
+
+	if spent := extractor.Budget.Spent(); spent != (parser.Usage{}) {
+		fmt.Fprintf(os.Stderr, "Provider usage: %s\n", spent)
+	}
+	if len(files) > 1 {
+		if err := report.WriteSummary(os.Stderr); err != nil {
+			return err
+		}
+	}
+	// Nothing to write when every statement failed
+	if report.Count(batch.Succeeded) == 0 {
+		return report.Err()
+	}
+
+	if err := enrich.apply(cmd.Context(), cfg, txns); err != nil {
+		return err
+	}
+	if patch != nil {
+		if err := patch.apply(cmd, cfg, output, strings.Join(sources, ", "), txns); err != nil {
+			return err
+		}
+		return report.Err()
+	}
+	if perFile {
+		err = writePerFile(cmd, cfg, output, format, sources, txns, statements, false)
+	} else {
+		err = writeExtracted(cmd, cfg, output, format, strings.Join(sources, ", "), txns, statements)
+	}
+	if err != nil {
+		return err
+	}
+	return report.Err()
+}
+
+// enrichment is what is done to extracted transactions before they are
+// written: merchant clean-up, categorization, splits, tags, owners and
+// transforms
+type enrichment struct {
+	merchants  *merchant.Normalizer
+	chain      *categorizer.Chain
+	splitter   *categorizer.Splitter
+	tagger     *categorizer.Tagger
+	owners     *categorizer.Owners
+	transforms *script.Transforms
+}
+
+// newEnrichment compiles the rules configured in cfg
+func newEnrichment(cfg *config.Config) (*enrichment, error) {
+	var e enrichment
+	var err error
+	if e.chain, err = categorizer.New(cfg); err != nil {
+		return nil, err
+	}
+	if e.tagger, err = categorizer.NewTagger(cfg.TagRules); err != nil {
+		return nil, err
+	}
+	if e.splitter, err = categorizer.NewSplitter(cfg.SplitRules); err != nil {
+		return nil, err
+	}
+	if e.merchants, err = merchant.New(cfg.Merchants); err != nil {
+		return nil, err
+	}
+	if e.owners, err = categorizer.NewOwners(cfg.Owners); err != nil {
+		return nil, err
+	}
+	if e.transforms, err = script.New(cfg.Transforms); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (e *enrichment) apply(ctx context.Context, cfg *config.Config, txns []transaction.Transaction) error {
+	e.merchants.Apply(txns)
+	if err := e.chain.Apply(ctx, txns); err != nil {
+		return err
+	}
+	if llm, ok := e.chain.Lookup(transaction.CategorizedByLLM).(*categorizer.LLM); ok {
+		if llm.Stopped != nil {
+			fmt.Fprintf(os.Stderr, "warning: llm categorizer stopped: %v\n", llm.Stopped)
+		}
+		n := 0
+		for _, t := range txns {
+			if t.CategorizedBy == transaction.CategorizedByLLM {
+				n++
+			}
+		}
+		fmt.Fprintf(os.Stderr, "LLM categorized %d transactions (%s)\n", n, llm.Spent())
+	}
+	e.splitter.Apply(txns)
+	e.tagger.Apply(txns)
+	e.owners.Apply(txns)
+	return e.transforms.Apply(txns)
+}
+
+// writeExtracted writes txns to output in format, or as a TransactionList
+// with their statements when format is json
+func writeExtracted(cmd *cobra.Command, cfg *config.Config, output string, format export.Format, source string, txns []transaction.Transaction, statements []transaction.StatementInfo) error {
+	if format.Write != nil {
+		return writeOutput(output, func(w io.Writer) error { return format.Write(w, txns, cfg.Export) })
+	}
+	return writeStatements(cmd.Context(), output, source, txns, statements)
+}
+
+// writePerFile writes the transactions of each source statement to its own
+// file under dir, mirroring the statement's path. With exclusive, files
+// already in dir are not written over.
+func writePerFile(cmd *cobra.Command, cfg *config.Config, dir string, format export.Format, sources []string, txns []transaction.Transaction, statements []transaction.StatementInfo, exclusive bool) error {
+	var taken func(string) bool
+	if exclusive {
+		taken = func(path string) bool {
+			_, err := os.Lstat(path)
+			return err == nil
+		}
+	}
+	paths, err := perFilePaths(dir, cmp.Or(format.Extension, ".json"), sources, taken)
+	if err != nil {
+		return err
+	}
+	for _, source := range sources {
+		var own []transaction.Transaction
+		for _, t := range txns {
+			if t.Statement != nil && t.Statement.File == source {
+				own = append(own, t)
+			}
+		}
+		var info []transaction.StatementInfo
+		for _, s := range statements {
+			if s.File == source {
+				info = append(info, s)
+			}
+		}
+
+		path := paths[source]
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		if err := writeExtracted(cmd, cfg, path, format, source, own, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// perFilePaths names the output of each source under dir after the source,
+// with the extension ext: 2024/jan.pdf becomes 2024/jan.json. Where that is
+// taken, by another source such as jan.csv or as taken reports, the
+// source's own extension is kept, as in jan.pdf.json. Sources come from
+// file and archive entry names, so one that would leave dir is refused.
+func perFilePaths(dir, ext string, sources []string, taken func(string) bool) (map[string]string, error) {
+	name := func(source string) (string, error) {
+		rel := filepath.FromSlash(strings.TrimSuffix(source, filepath.Ext(source)) + ext)
+		if !filepath.IsLocal(rel) {
+			return "", fmt.Errorf("statement %q would be written outside %s", source, dir)
+		}
+		return rel, nil
+	}
+
+	claims := make(map[string]int)
+	for _, source := range sources {
+		rel, err := name(source)
+		if err != nil {
+			return nil, err
+		}
+		claims[rel]++
+	}
+	paths := make(map[string]string, len(sources))
+	for _, source := range sources {
+		rel, _ := name(source)
+		path := filepath.Join(dir, rel)
+		if claims[rel] > 1 || (taken != nil && taken(path)) {
+			path = filepath.Join(dir, filepath.FromSlash(source)+ext)
+		}
+		paths[source] = path
+	}
+	return paths, nil
+}
+
+// statementResult is the outcome of extractStatement for one file
+type statementResult struct {
+	done bool // extraction was attempted
+	txns []transaction.Transaction
+	info transaction.StatementInfo
+	name string // of the parser
+	err  error
+}
+
+// extractStatement extracts, post-processes and identifies the transactions
+// of one statement, returning them with what the statement says about itself
+// and the name of the parser used
+func extractStatement(ctx context.Context, cfg *config.Config, extractor *parser.Extractor, bank string, file archive.File, patch *extractPatch) ([]transaction.Transaction, transaction.StatementInfo, string, error) {
+	var info transaction.StatementInfo
+	document, err := os.ReadFile(file.Path)
+	if err != nil {
+		return nil, info, "", fmt.Errorf("failed to read statement: %w", err)
+	}
+
+	name := bank
+	if name == "" {
+		if name, err = extractor.Detect(file.Path, document); err != nil {
+			return nil, info, "", err
+		}
+	}
+	extracted, info, err := extractor.ExtractStatement(ctx, name, document)
+	if err != nil {
+		return nil, info, name, err
+	}
+	parser.Link(extracted, file.Source, document)
+	info.File, info.SHA256 = file.Source, parser.Digest(document)
+	// Part of a statement does not add up to its balances
+	if patch == nil {
+		if err := info.Verify(extracted); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+	}
+	for i := range extracted {
+		extracted[i].RawDescription = extracted[i].Description
+	}
+
+	pipeline, err := postprocess.Compile(cfg.Parsers[strings.ToLower(name)].PostProcess)
+	if err != nil {
+		return nil, info, name, fmt.Errorf("parser %q: %w", name, err)
+	}
+	if err := pipeline.ApplyAll(extracted); err != nil {
+		return nil, info, name, err
+	}
+	transaction.AssignIDs(extracted)
+	transaction.AssignSequences(extracted)
+	if patch != nil {
+		extracted = patch.add(document, extracted)
+	}
+	return extracted, info, name, nil
+}
+
+// quarantineStatement records a failed attempt at file, reporting on stderr
+// when it was moved to the quarantine directory, and returns where it was
+// moved to, if it was
+func quarantineStatement(q *quarantine.Quarantine, file archive.File, name string, failure error) string {
+	document, err := os.ReadFile(file.Path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %s: %v\n", file.Source, err)
+		return ""
+	}
+	moved, err := q.Fail(file.Path, file.Source, parser.Digest(document), name, failure)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	} else if moved != "" {
+		fmt.Fprintf(os.Stderr, "%s: quarantined after %d failed runs: %s\n", file.Source, q.MaxAttempts, moved)
+	}
+	return moved
+}
+
+// within reports whether path is in dir or one of its subdirectories
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// interactive reports whether f is a terminal a prompt can be answered on
+func interactive(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// confirmOverBudget asks on stderr whether to go past the run budget
+func confirmOverBudget(spent parser.Usage) bool {
+	fmt.Fprintf(os.Stderr, "Spent %s; the next provider call could go over the run budget. Continue? [y/N] ", spent)
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes"
+}
+
+// exportFormatNames lists the export file formats for help text
+func exportFormatNames() string {
+	var names []string
+	for _, f := range export.Formats() {
+		names = append(names, f.Name)
+	}
+	return strings.Join(names, ", ")
+}
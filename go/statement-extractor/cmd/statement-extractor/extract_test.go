@@ -0,0 +1,82 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/archive"
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/export"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// fromStatements returns a transaction from each source statement
+func fromStatements(sources ...string) []transaction.Transaction {
+	var txns []transaction.Transaction
+	for _, source := range sources {
+		txns = append(txns, transaction.Transaction{Description: source, Amount: -100, Statement: &transaction.StatementRef{File: source}})
+	}
+	return txns
+}
+
+func testCommand() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+	return cmd
+}
+
+func TestWritePerFileStaysInOutput(t *testing.T) {
+	dir := t.TempDir()
+	bundle := filepath.Join(dir, "bank.zip")
+	f, err := os.Create(bundle)
+	require.NoError(t, err)
+	zw := zip.NewWriter(f)
+	fw, err := zw.Create("../../escaped.csv")
+	require.NoError(t, err)
+	_, _ = fw.Write([]byte("Date,Amount"))
+	require.NoError(t, zw.Close())
+	require.NoError(t, f.Close())
+
+	files, err := archive.Inputs([]string{bundle}, t.TempDir())
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	output := filepath.Join(dir, "a", "b", "out")
+	err = writePerFile(testCommand(), config.Default(), output, export.Format{}, []string{files[0].Source}, fromStatements(files[0].Source), nil, false)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(output, "bank.zip", "escaped.json"))
+	assert.NoFileExists(t, filepath.Join(dir, "a", "b", "escaped.json"))
+
+	// However the source was named
+	err = writePerFile(testCommand(), config.Default(), output, export.Format{}, []string{"../escaped.csv"}, fromStatements("../escaped.csv"), nil, false)
+	assert.ErrorContains(t, err, "would be written outside")
+	assert.NoFileExists(t, filepath.Join(dir, "a", "b", "escaped.json"))
+}
+
+func TestWritePerFileSharedStem(t *testing.T) {
+	output := t.TempDir()
+	sources := []string{"jan.pdf", "jan.csv", "2024/feb.pdf"}
+	require.NoError(t, writePerFile(testCommand(), config.Default(), output, export.Format{}, sources, fromStatements(sources...), nil, false))
+	assert.FileExists(t, filepath.Join(output, "jan.pdf.json"))
+	assert.FileExists(t, filepath.Join(output, "jan.csv.json"))
+	assert.NoFileExists(t, filepath.Join(output, "jan.json"))
+	assert.FileExists(t, filepath.Join(output, "2024", "feb.json"))
+
+	// Rerunning writes over the same files
+	require.NoError(t, writePerFile(testCommand(), config.Default(), output, export.Format{}, sources, fromStatements(sources...), nil, false))
+	assert.FileExists(t, filepath.Join(output, "2024", "feb.json"))
+	assert.NoFileExists(t, filepath.Join(output, "2024", "feb.pdf.json"))
+
+	// As watch does, one statement at a time
+	require.NoError(t, writePerFile(testCommand(), config.Default(), output, export.Format{}, []string{"mar.pdf"}, fromStatements("mar.pdf"), nil, true))
+	require.NoError(t, writePerFile(testCommand(), config.Default(), output, export.Format{}, []string{"mar.csv"}, fromStatements("mar.csv"), nil, true))
+	assert.FileExists(t, filepath.Join(output, "mar.json"))
+	assert.FileExists(t, filepath.Join(output, "mar.csv.json"))
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/dedup"
+	"github.com/example/statement-extractor/internal/importer"
+	"github.com/example/statement-extractor/internal/store"
+	"github.com/example/statement-extractor/internal/table"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import transaction history exported from another app",
+	Long: `Convert a CSV history export from a personal finance app into a
+TransactionList JSON document. Categories assigned in the app are kept, so the
+imported history can seed reports and rule training straight away.
+
+OFX and QFX downloads are also accepted, keeping each transaction's FITID as
+its ID, as are Quicken QIF files with day-first dates; for .ofx, .qfx and .qif
+files --format may be omitted. A TransactionList JSON file written by extract
+is read as format json.
+
+With --into the import is merged into an existing TransactionList, which is
+rewritten in place unless --output is given. Transactions already in it,
+matched by ID or else by account, date, amount and description, are
+duplicates. When there are any, --on-duplicate must say what to do with them:
+skip, replace, keep-both (keeping the import with a "-2" suffix) or ask for
+each one. Use --preview to list the duplicates without writing anything.
+
+With --db the transactions are stored in a SQLite database instead, for the
+list, sum and search commands. Transactions already stored, matched as for
+--into, are duplicates too; --on-duplicate and --preview apply the same way,
+except that duplicates are skipped by default, so re-importing a file is
+harmless. While another process, such as watch, is writing to the database the
+import fails as busy; --wait gives it time to finish.
+
+With --fuzzy, transactions from the same merchant a few days and cents apart
+are also duplicates, for merging a CSV download with PDF-extracted data for
+the same account where posting dates differ.
+
+The [[alerts]] rules are checked on import: each trigger the new transactions
+set off, judged with the rest of the history or the stored transactions of
+the months imported, is reported as by "alerts", and sent to notify.webhook.
+
+Supported formats: ` + strings.Join(importer.HistoryFormats(), ", ") + `.`,
+	Example: `  statement-extractor import --format mint transactions.csv --output mint.json
+  statement-extractor import --format pocketbook pocketbook-export.csv > history.json
+  statement-extractor import 2024-01.qfx -o 2024-01.json
+  statement-extractor import 2024-02.qfx --into history.json --preview
+  statement-extractor import 2024-02.qfx --into history.json --on-duplicate ask
+  statement-extractor import --format pocketbook cba.csv --into cba.json --fuzzy --preview
+  statement-extractor import cba-2024-01.json --db txns.db
+  statement-extractor import cba.csv --format pocketbook --db txns.db --fuzzy --on-duplicate replace`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImport,
+}
+
+func init() {
+	importCmd.Flags().String("format", "", "Export format ("+strings.Join(importer.HistoryFormats(), ", ")+" or json; default: from the .ofx/.qfx/.qif/.json extension)")
+	importCmd.Flags().StringP("output", "o", "", "Write JSON to this file instead of stdout")
+	importCmd.Flags().String("into", "", "Merge into this TransactionList JSON file")
+	importCmd.Flags().String("on-duplicate", "", "What to do with duplicates: skip, replace, keep-both or ask (default skip with --db)")
+	importCmd.Flags().Bool("preview", false, "List the duplicates an --into merge or --db import would find and exit")
+	importCmd.Flags().Bool("fuzzy", false, "Also treat near matches from the same merchant as duplicates")
+	importCmd.Flags().Int("fuzzy-days", dedup.DefaultTolerance.Days, "Maximum date difference for --fuzzy matches")
+	importCmd.Flags().Float64("fuzzy-amount", dedup.DefaultTolerance.Amount, "Maximum amount difference for --fuzzy matches")
+	importCmd.Flags().String("db", "", "Store in this SQLite transaction database instead of writing JSON")
+	addWaitFlag(importCmd)
+	rootCmd.AddCommand(importCmd)
+}
+
+func runImport(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+	into, _ := cmd.Flags().GetString("into")
+	onDuplicate, _ := cmd.Flags().GetString("on-duplicate")
+	preview, _ := cmd.Flags().GetBool("preview")
+	db, _ := cmd.Flags().GetString("db")
+	if into == "" && db == "" && (onDuplicate != "" || preview) {
+		return errors.New("--on-duplicate and --preview need --into or --db")
+	}
+	if db != "" && (into != "" || output != "") {
+		return errors.New("--db cannot be combined with --into or --output")
+	}
+	find := duplicateFinder(cmd)
+	if format == "" {
+		switch ext := strings.ToLower(filepath.Ext(args[0])); ext {
+		case ".ofx", ".qfx", ".qif", ".json":
+			format = ext[1:]
+		default:
+			return fmt.Errorf("--format is required for %s", filepath.Base(args[0]))
+		}
+	}
+
+	txns, err := readImport(format, args[0])
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	alerts, err := newAlerter(cfg)
+	if err != nil {
+		return err
+	}
+
+	if db != "" {
+		return storeImport(cmd, cfg, alerts, txns, onDuplicate, preview, find)
+	}
+	source := filepath.Base(args[0])
+	if preview {
+		return previewMerge(into, txns, find)
+	}
+	var existing []transaction.Transaction
+	if into != "" {
+		if existing, err = loadTransactions([]string{into}); err != nil {
+			return err
+		}
+		if txns, err = mergeInto(into, existing, txns, onDuplicate, find); err != nil {
+			return err
+		}
+		if output == "" {
+			output = into
+		}
+		source = into
+	}
+
+	if err := writeTransactions(cmd.Context(), output, source, txns); err != nil {
+		return err
+	}
+	if output != "" && output != "-" {
+		fmt.Fprintf(os.Stderr, "Imported %d transactions to %s\n", len(txns), output)
+	}
+	alerts.notify(cmd.Context(), existing, txns)
+	return nil
+}
+
+// readImport reads the transactions of an export or TransactionList file
+func readImport(format, path string) ([]transaction.Transaction, error) {
+	if format == "json" {
+		return loadTransactions([]string{path})
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open export: %w", err)
+	}
+	defer f.Close()
+
+	txns, err := importer.ReadHistory(format, f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	transaction.AssignSequences(txns)
+	return txns, nil
+}
+
+// storeImport adds txns to the --db transaction database, resolving their
+// duplicates among the stored transactions with the named strategy, skip
+// when none is named
+func storeImport(cmd *cobra.Command, cfg *config.Config, alerts *alerter, txns []transaction.Transaction, onDuplicate string, preview bool, find duplicateFunc) error {
+	db, err := openStore(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	if len(txns) == 0 {
+		fmt.Fprintln(os.Stderr, "Stored 0 new transactions, 0 already stored")
+		return nil
+	}
+	ctx := cmd.Context()
+
+	// Only the stored transactions that could match are compared
+	from, to := txns[0].Date, txns[0].Date
+	for _, t := range txns {
+		if t.Date.Before(from) {
+			from = t.Date
+		}
+		if t.Date.After(to) {
+			to = t.Date
+		}
+	}
+	days := 0
+	if fuzzy, _ := cmd.Flags().GetBool("fuzzy"); fuzzy {
+		days, _ = cmd.Flags().GetInt("fuzzy-days")
+	}
+	existing, err := db.List(ctx, store.Filter{From: from.AddDate(0, 0, -days), To: to.AddDate(0, 0, days+1)})
+	if err != nil {
+		return err
+	}
+	dups := find(existing, txns)
+	if preview {
+		return previewDuplicates(existing, txns, dups)
+	}
+
+	decide, err := duplicateStrategy(cmp.Or(onDuplicate, string(dedup.Skip)), existing, txns)
+	if err != nil {
+		return err
+	}
+	replaced := make(map[int]bool)
+	merged, result, err := dedup.Merge(existing, txns, dups, func(d dedup.Duplicate) (dedup.Strategy, error) {
+		strategy, err := decide(d)
+		if strategy == dedup.Replace {
+			replaced[d.Existing] = true
+		}
+		return strategy, err
+	})
+	if err != nil {
+		return err
+	}
+	var remove, add []transaction.Transaction
+	for j := range existing {
+		if replaced[j] {
+			remove = append(remove, existing[j])
+			add = append(add, merged[j])
+		}
+	}
+	add = append(add, merged[len(existing):]...)
+
+	months := alertMonths(txns)
+	var before []transaction.Transaction
+	if alerts != nil {
+		if before, err = db.List(ctx, months); err != nil {
+			return err
+		}
+	}
+
+	_, added, err := db.Update(ctx, remove, add)
+	if err != nil {
+		return closedHint(err)
+	}
+	// The store itself skips transactions stored outside the dates compared,
+	// so what was new is told by what it added
+	fmt.Fprintf(os.Stderr, "Stored %d new transactions, %d already stored, %d replaced, %d kept both\n",
+		added-result.Replaced-result.KeptBoth, len(txns)-added, result.Replaced, result.KeptBoth)
+	if alerts != nil {
+		after, err := db.List(ctx, months)
+		if err != nil {
+			return err
+		}
+		alerts.notify(ctx, before, after)
+	}
+	return nil
+}
+
+// duplicateFunc finds the incoming transactions that duplicate existing ones
+type duplicateFunc func(existing, incoming []transaction.Transaction) []dedup.Duplicate
+
+// duplicateFinder returns the duplicate detection selected by --fuzzy
+func duplicateFinder(cmd *cobra.Command) duplicateFunc {
+	fuzzy, _ := cmd.Flags().GetBool("fuzzy")
+	if !fuzzy {
+		return dedup.Find
+	}
+	days, _ := cmd.Flags().GetInt("fuzzy-days")
+	amount, _ := cmd.Flags().GetFloat64("fuzzy-amount")
+	return func(existing, incoming []transaction.Transaction) []dedup.Duplicate {
+		return dedup.FindNear(existing, incoming, dedup.Tolerance{Days: days, Amount: amount})
+	}
+}
+
+// previewMerge lists the transactions that an --into merge would find
+// already in the history at path
+func previewMerge(path string, txns []transaction.Transaction, find duplicateFunc) error {
+	existing, err := loadTransactions([]string{path})
+	if err != nil {
+		return err
+	}
+	return previewDuplicates(existing, txns, find(existing, txns))
+}
+
+// previewDuplicates lists the duplicates of existing transactions found
+// among txns
+func previewDuplicates(existing, txns []transaction.Transaction, dups []dedup.Duplicate) error {
+	if len(dups) == 0 {
+		fmt.Printf("No duplicates; %d new transactions\n", len(txns))
+		return nil
+	}
+
+	tbl := table.New(
+		table.Column{Name: "date", Kind: table.Date},
+		table.Column{Name: "amount", Kind: table.Money},
+		table.Column{Name: "description"},
+		table.Column{Name: "match"},
+		table.Column{Name: "existing"},
+		table.Column{Name: "category", Header: "EXISTING CATEGORY"},
+	)
+	for _, d := range dups {
+		t, e := txns[d.Incoming], existing[d.Existing]
+		match, matched := "exact", ""
+		if d.Near {
+			match = "near"
+			matched = fmt.Sprintf("%s %s %s", e.Date.Format("2006-01-02"), e.Amount, e.Description)
+		}
+		tbl.Append(t.Date, t.Amount, t.Description, match, matched, e.Category)
+	}
+	if err := tbl.Render(os.Stdout, table.Options{}); err != nil {
+		return err
+	}
+	fmt.Printf("%d duplicates, %d new transactions\n", len(dups), len(txns)-len(dups))
+	return nil
+}
+
+// mergeInto merges txns into existing, the history at path, resolving
+// duplicates with the named strategy
+func mergeInto(path string, existing, txns []transaction.Transaction, onDuplicate string, find duplicateFunc) ([]transaction.Transaction, error) {
+	dups := find(existing, txns)
+	if len(dups) > 0 && onDuplicate == "" {
+		return nil, fmt.Errorf("%d transactions are already in %s; choose --on-duplicate skip, replace, keep-both or ask (see --preview)", len(dups), path)
+	}
+	decide, err := duplicateStrategy(cmp.Or(onDuplicate, string(dedup.Skip)), existing, txns)
+	if err != nil {
+		return nil, err
+	}
+
+	merged, result, err := dedup.Merge(existing, txns, dups, decide)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "%d added, %d skipped, %d replaced, %d kept both\n", result.Added, result.Skipped, result.Replaced, result.KeptBoth)
+	return merged, nil
+}
+
+// duplicateStrategy returns the decision for each duplicate named by
+// --on-duplicate
+func duplicateStrategy(onDuplicate string, existing, incoming []transaction.Transaction) (func(dedup.Duplicate) (dedup.Strategy, error), error) {
+	if onDuplicate == "ask" {
+		return askDuplicate(bufio.NewReader(os.Stdin), existing, incoming), nil
+	}
+	strategy, err := dedup.ParseStrategy(onDuplicate)
+	if err != nil {
+		return nil, err
+	}
+	return func(dedup.Duplicate) (dedup.Strategy, error) { return strategy, nil }, nil
+}
+
+// askDuplicate prompts on stderr for the strategy for each duplicate
+func askDuplicate(in *bufio.Reader, existing, incoming []transaction.Transaction) func(dedup.Duplicate) (dedup.Strategy, error) {
+	return func(d dedup.Duplicate) (dedup.Strategy, error) {
+		t, e := incoming[d.Incoming], existing[d.Existing]
+		fmt.Fprintf(os.Stderr, "%s %s %s\n  already imported as %q (%s)\n",
+			t.Date.Format("2006-01-02"), t.Amount, t.Description, e.Description, cmp.Or(e.Category, "uncategorized"))
+		for {
+			fmt.Fprint(os.Stderr, "  [s]kip, [r]eplace or [k]eep both? ")
+			line, err := in.ReadString('\n')
+			switch strings.ToLower(strings.TrimSpace(line)) {
+			case "s", "skip":
+				return dedup.Skip, nil
+			case "r", "replace":
+				return dedup.Replace, nil
+			case "k", "keep", "keep-both":
+				return dedup.KeepBoth, nil
+			}
+			if err != nil {
+				return "", fmt.Errorf("no answer for duplicate: %w", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/example/statement-extractor/internal/clock"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// loadTransactions reads and concatenates TransactionList JSON files
+func loadTransactions(paths []string) ([]transaction.Transaction, error) {
+	var txns []transaction.Transaction
+	for _, path := range paths {
+		list, err := loadList(path)
+		if err != nil {
+			return nil, err
+		}
+		txns = append(txns, list.Transactions...)
+	}
+	return txns, nil
+}
+
+// loadList reads a TransactionList JSON file
+func loadList(path string) (transaction.TransactionList, error) {
+	var list transaction.TransactionList
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return list, fmt.Errorf("failed to read transactions: %w", err)
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return list, fmt.Errorf("failed to parse transactions in %s: %w", path, err)
+	}
+	return list, nil
+}
+
+// writeTransactions writes txns as a TransactionList JSON document to path,
+// or to stdout when path is empty or "-", processed at the time of ctx's
+// clock
+func writeTransactions(ctx context.Context, path, source string, txns []transaction.Transaction) error {
+	return writeStatements(ctx, path, source, txns, nil)
+}
+
+// writeStatements is writeTransactions that also records the statements the
+// transactions were extracted from
+func writeStatements(ctx context.Context, path, source string, txns []transaction.Transaction, statements []transaction.StatementInfo) error {
+	return writeJSON(path, statementList(ctx, source, txns, statements))
+}
+
+// statementList returns txns as a TransactionList extracted from statements,
+// processed at the time of ctx's clock
+func statementList(ctx context.Context, source string, txns []transaction.Transaction, statements []transaction.StatementInfo) transaction.TransactionList {
+	list := transaction.TransactionList{
+		Transactions: []transaction.Transaction{},
+		Source:       source,
+		ProcessedAt:  clock.From(ctx).Now().UTC(),
+		Statements:   statements,
+	}
+	for _, t := range txns {
+		list.AddTransaction(t)
+	}
+	return list
+}
+
+// writeJSON writes v as an indented JSON document to path, or to stdout when
+// path is empty or "-"
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode transactions: %w", err)
+	}
+	data = append(data, '\n')
+
+	if path == "" || path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write transactions: %w", err)
+	}
+	return nil
+}
@@ -1,15 +1,26 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/batch"
+	"github.com/example/statement-extractor/internal/clock"
 )
 
+// exitPartial is the exit status of a batch run in which some statements
+// succeeded and others did not
+const exitPartial = 3
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, batch.ErrPartial) {
+			os.Exit(exitPartial)
+		}
 		os.Exit(1)
 	}
 }
@@ -18,9 +29,29 @@ var rootCmd = &cobra.Command{
 	Use:   "statement-extractor",
 	Short: "Extract and categorize transactions from bank statements",
 	Long: `Statement Extractor is a tool for processing PDF bank statements,
-extracting transaction data, and categorizing transactions based on configurable rules.`,
+extracting transaction data, and categorizing transactions based on configurable rules.
+
+Set SOURCE_DATE_EPOCH to a Unix time to fix the clock, so that timestamps
+such as processed_at are reproducible.`,
+	// Every command reads the time from the context, so SOURCE_DATE_EPOCH
+	// can fix ProcessedAt and other timestamps for reproducible output
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		c, err := clock.FromEnv()
+		if err != nil {
+			return err
+		}
+		cmd.SetContext(clock.With(cmd.Context(), c))
+		return nil
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("Statement Extractor v1.0.0")
 		fmt.Println("Use --help for available commands")
 	},
-}
\ No newline at end of file
+}
+
+func init() {
+	rootCmd.PersistentFlags().String("config", "", "Path to configuration file (default: auto-discovered)")
+	rootCmd.PersistentFlags().StringArray("config-overlay", nil, "Additional config file merged on top of --config (repeatable)")
+	rootCmd.PersistentFlags().Bool("strict-config", false, "Fail on unrecognized config keys (also strict_config = true in the file)")
+	rootCmd.PersistentFlags().Bool("no-color", false, "Disable colored output (also honours NO_COLOR)")
+}
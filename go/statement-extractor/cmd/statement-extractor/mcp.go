@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/categorizer"
+	"github.com/example/statement-extractor/internal/mcp"
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Serve the transaction database to LLM assistants over the Model Context Protocol",
+	Long: `Run a Model Context Protocol server on stdin and stdout, for a desktop LLM
+assistant to start as a local server. It offers three tools, none of which
+change the database:
+
+  query_transactions      list stored transactions, filtered by period,
+                          category, account, description text, tag or card
+  spending_summary        count and total them by category, account, owner,
+                          card, month or source
+  categorize_description  categorize a description with the configured rules
+
+Register it with the assistant as the command
+"statement-extractor mcp --db /path/to/txns.db", adding --config when the
+config file is not found on its own. Diagnostics go to stderr.`,
+	Example: `  statement-extractor mcp --db txns.db`,
+	Args:    cobra.NoArgs,
+	RunE:    runMCP,
+}
+
+func init() {
+	addDBFlag(mcpCmd)
+	rootCmd.AddCommand(mcpCmd)
+}
+
+func runMCP(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	chain, err := categorizer.New(cfg)
+	if err != nil {
+		return err
+	}
+	db, err := openStore(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+	return mcp.New(db, chain).Serve(cmd.Context(), os.Stdin, os.Stdout)
+}
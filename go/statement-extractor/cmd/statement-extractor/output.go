@@ -0,0 +1,72 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/locale"
+	"github.com/example/statement-extractor/internal/money"
+	"github.com/example/statement-extractor/internal/table"
+	"github.com/example/statement-extractor/internal/theme"
+)
+
+// display bundles the output settings shared by listing and report commands
+type display struct {
+	theme  *theme.Theme
+	money  *money.Formatter
+	locale locale.Locale
+}
+
+// newDisplay builds the display settings from config and --no-color
+func newDisplay(cmd *cobra.Command, cfg *config.Config) (*display, error) {
+	noColor, _ := cmd.Flags().GetBool("no-color")
+	th, err := theme.New(cfg.Theme, theme.Enabled(noColor, os.Stdout))
+	if err != nil {
+		return nil, err
+	}
+	formatter, err := money.New(cfg.Money)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := locale.New(cfg.Locale)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Locale.Name != "" {
+		formatter.Localize(loc.Decimal, loc.Thousands, loc.SymbolAfter)
+	}
+	return &display{theme: th, money: formatter, locale: loc}, nil
+}
+
+// amount formats and colors a monetary value
+func (d *display) amount(v float64) string {
+	return d.theme.Amount(v, d.money.Format(v))
+}
+
+// addTableFlags registers the shared --columns, --sort and --limit flags
+func addTableFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("columns", nil, "Columns to show, in order (default: all)")
+	cmd.Flags().StringSlice("sort", nil, `Columns to sort by; prefix with "-" for descending`)
+	cmd.Flags().Int("limit", 0, "Maximum rows to show (default: all)")
+}
+
+// renderTable writes tbl to stdout using the command's table flags
+func (d *display) renderTable(cmd *cobra.Command, tbl *table.Table) error {
+	columns, _ := cmd.Flags().GetStringSlice("columns")
+	sort, _ := cmd.Flags().GetStringSlice("sort")
+	limit, _ := cmd.Flags().GetInt("limit")
+
+	return d.render(os.Stdout, tbl, table.Options{Columns: columns, Sort: sort, Limit: limit})
+}
+
+// render writes tbl to w in the display's style and formats
+func (d *display) render(w io.Writer, tbl *table.Table, opts table.Options) error {
+	opts.Style = d.theme
+	opts.FormatMoney = d.money.Format
+	opts.FormatDate = d.locale.Date
+	opts.FormatNumber = d.locale.Number
+	return tbl.Render(w, opts)
+}
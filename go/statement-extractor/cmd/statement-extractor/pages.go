@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/pages"
+	"github.com/example/statement-extractor/internal/table"
+)
+
+var pagesCmd = &cobra.Command{
+	Use:   "pages <file>...",
+	Short: "Show extraction statistics per statement page",
+	Long: `Summarise extracted transactions by the statement page they came from, so
+that when a long statement comes out wrong you know which page to inspect or
+extract again with extract --pages.
+
+Each page shows its row count, the dates of its first and last rows and how
+its running balances reconcile: a row's balance should be the balance before
+it plus or minus its amount. The status is "ok" when every checked balance
+follows, "breaks" when some do not, and "no balances" when the statement has
+none to check. A page with no rows between two pages that have some is listed
+as "missing". Transactions without a page, such as those from CSV exports,
+are skipped.`,
+	Example: `  statement-extractor pages jan.json
+  statement-extractor pages 2024.json --sort -breaks --limit 10`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runPages,
+}
+
+func init() {
+	addTableFlags(pagesCmd)
+	rootCmd.AddCommand(pagesCmd)
+}
+
+func runPages(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	out, err := newDisplay(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	txns, err := loadTransactions(args)
+	if err != nil {
+		return err
+	}
+
+	stats := pages.Summarize(txns)
+	if len(stats) == 0 {
+		fmt.Println("No transactions with statement pages")
+		return nil
+	}
+	tbl := table.New(
+		table.Column{Name: "statement"},
+		table.Column{Name: "page", Kind: table.Number},
+		table.Column{Name: "rows", Kind: table.Number},
+		table.Column{Name: "from", Kind: table.Date},
+		table.Column{Name: "to", Kind: table.Date},
+		table.Column{Name: "checked", Kind: table.Number},
+		table.Column{Name: "breaks", Kind: table.Number},
+		table.Column{Name: "status"},
+	)
+	var suspect []string
+	for _, s := range stats {
+		tbl.Append(s.File, s.Page, s.Rows, s.First.Date, s.Last.Date, s.Checked, s.Breaks, string(s.Status))
+		if s.Status == pages.Breaks || s.Status == pages.Missing {
+			suspect = append(suspect, fmt.Sprintf("%s p.%d", s.File, s.Page))
+		}
+	}
+	if err := out.renderTable(cmd, tbl); err != nil {
+		return err
+	}
+	if len(suspect) > 0 {
+		fmt.Printf("%d of %d pages to check: %s\n", len(suspect), len(stats), strings.Join(suspect, ", "))
+	}
+	return nil
+}
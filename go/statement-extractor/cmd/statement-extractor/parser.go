@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/scaffold"
+)
+
+var parserCmd = &cobra.Command{
+	Use:   "parser",
+	Short: "Develop built-in statement parsers",
+}
+
+var parserScaffoldCmd = &cobra.Command{
+	Use:   "scaffold <name>",
+	Short: "Generate the skeleton of a parser for a new bank",
+	Long: `Generate a content parser for a new bank under internal/parser: the parser,
+registered under <name>, a test comparing its output on a sample statement
+with a golden file, and the sample statement and golden file themselves under
+testdata/<name>. The [parsers] stanza to add to the config file is printed,
+with a commented-out prompt template for sending the bank's statements to a
+PDF service with method = "pdf" in the meantime.
+
+The skeleton parses a simple one-line-per-transaction layout and its test
+passes as generated. Replace the sample with an anonymized extract of a real
+statement, adapt the patterns, and rewrite the golden file with
+
+  go test ./internal/parser -run <Name> -update
+
+Run it from the module root, or point --dir at it.`,
+	Example: `  statement-extractor parser scaffold mybank
+  statement-extractor parser scaffold bendigo --bank "Bendigo Bank"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runParserScaffold,
+}
+
+func init() {
+	parserScaffoldCmd.Flags().String("bank", "", "Bank name as it appears on statements, used to detect them (default: the name upper-cased)")
+	parserScaffoldCmd.Flags().String("dir", ".", "Module root to write the files under")
+	parserScaffoldCmd.Flags().Bool("force", false, "Replace existing files")
+	parserCmd.AddCommand(parserScaffoldCmd)
+	rootCmd.AddCommand(parserCmd)
+}
+
+func runParserScaffold(cmd *cobra.Command, args []string) error {
+	bank, _ := cmd.Flags().GetString("bank")
+	dir, _ := cmd.Flags().GetString("dir")
+	force, _ := cmd.Flags().GetBool("force")
+
+	p := scaffold.Parser{Name: args[0], Bank: bank}
+	files, err := scaffold.Files(p)
+	if err != nil {
+		return err
+	}
+	stanza, err := scaffold.Config(p)
+	if err != nil {
+		return err
+	}
+	if err := scaffold.Write(dir, files, force); err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		fmt.Printf("Wrote %s\n", f.Path)
+	}
+	fmt.Printf("\nAdd to the [parsers] section of the config file:\n\n%s", stanza)
+	return nil
+}
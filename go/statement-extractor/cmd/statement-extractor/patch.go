@@ -0,0 +1,162 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/parser"
+	"github.com/example/statement-extractor/internal/store"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// extractPatch is the part of each statement that extract --pages and
+// --date-range extract again, replacing the same part in the existing
+// output or database
+type extractPatch struct {
+	pages    *parser.PageRange
+	from, to time.Time // inclusive; zero when not limited by date
+
+	statements map[string]bool // SHA-256 of the statements extracted again
+}
+
+// newExtractPatch reads --pages and --date-range, returning nil when
+// neither is given
+func newExtractPatch(cmd *cobra.Command) (*extractPatch, error) {
+	pagesFlag, _ := cmd.Flags().GetString("pages")
+	dateRange, _ := cmd.Flags().GetString("date-range")
+	if pagesFlag == "" && dateRange == "" {
+		return nil, nil
+	}
+
+	p := &extractPatch{statements: make(map[string]bool)}
+	if pagesFlag != "" {
+		pages, err := parser.ParsePageRange(pagesFlag)
+		if err != nil {
+			return nil, err
+		}
+		p.pages = &pages
+	}
+	if dateRange != "" {
+		from, to, ok := strings.Cut(dateRange, ":")
+		var err1, err2 error
+		p.from, err1 = time.Parse("2006-01-02", from)
+		p.to, err2 = time.Parse("2006-01-02", to)
+		if !ok || err1 != nil || err2 != nil || p.to.Before(p.from) {
+			return nil, fmt.Errorf("invalid --date-range %q (expected YYYY-MM-DD:YYYY-MM-DD)", dateRange)
+		}
+	}
+	return p, nil
+}
+
+// add records a statement extracted again and keeps the rows in the date
+// range
+func (p *extractPatch) add(document []byte, txns []transaction.Transaction) []transaction.Transaction {
+	sum := sha256.Sum256(document)
+	p.statements[hex.EncodeToString(sum[:])] = true
+
+	kept := txns[:0]
+	for _, t := range txns {
+		if p.inDates(t) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+func (p *extractPatch) inDates(t transaction.Transaction) bool {
+	return p.from.IsZero() || !t.Date.Before(p.from) && !t.Date.After(p.to)
+}
+
+// covers reports whether an existing transaction is in the part extracted
+// again
+func (p *extractPatch) covers(t transaction.Transaction) bool {
+	if t.Statement == nil || !p.statements[t.Statement.SHA256] {
+		return false
+	}
+	return (p.pages == nil || p.pages.Contains(t)) && p.inDates(t)
+}
+
+// describe names the part extracted again, e.g. "pages 7-9"
+func (p *extractPatch) describe() string {
+	var parts []string
+	if p.pages != nil {
+		parts = append(parts, p.pages.String())
+	}
+	if !p.from.IsZero() {
+		parts = append(parts, p.from.Format("2006-01-02")+" to "+p.to.Format("2006-01-02"))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// check fails before anything is extracted when there is nothing to patch
+func (p *extractPatch) check(cmd *cobra.Command, output, format string) error {
+	db, _ := cmd.Flags().GetString("db")
+	if db != "" {
+		return nil
+	}
+	if output == "" || output == "-" {
+		return errors.New("--pages and --date-range patch an existing file given with --output or a database given with --db")
+	}
+	if format != "json" {
+		return errors.New("--pages and --date-range patch JSON output only")
+	}
+	if _, err := os.Stat(output); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("nothing to patch: %s does not exist; extract the whole statement first", output)
+	} else if err != nil {
+		return fmt.Errorf("failed to read %s: %w", output, err)
+	}
+	return nil
+}
+
+// apply replaces the part extracted again in the --output file and the
+// --db database
+func (p *extractPatch) apply(cmd *cobra.Command, cfg *config.Config, output, source string, txns []transaction.Transaction) error {
+	if output != "" && output != "-" {
+		list, err := loadList(output)
+		if err != nil {
+			return err
+		}
+		removed := list.Replace(p.covers, txns)
+		if err := writeStatements(cmd.Context(), output, source, list.Transactions, list.Statements); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Patched %s (%s): replaced %d transactions with %d\n", output, p.describe(), removed, len(txns))
+	}
+
+	if db, _ := cmd.Flags().GetString("db"); db == "" {
+		return nil
+	}
+	s, err := openStore(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	for sha := range p.statements {
+		f := store.Filter{Statement: sha}
+		if p.pages != nil {
+			f.FirstPage, f.LastPage = p.pages.First, p.pages.Last
+		}
+		if !p.from.IsZero() {
+			f.From, f.To = p.from, p.to.AddDate(0, 0, 1)
+		}
+		var replacement []transaction.Transaction
+		for _, t := range txns {
+			if t.Statement != nil && t.Statement.SHA256 == sha {
+				replacement = append(replacement, t)
+			}
+		}
+		removed, added, err := s.Replace(cmd.Context(), f, replacement)
+		if err != nil {
+			return closedHint(err)
+		}
+		fmt.Fprintf(os.Stderr, "Patched database (%s): replaced %d transactions with %d\n", p.describe(), removed, added)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/clock"
+	"github.com/example/statement-extractor/internal/health"
+	"github.com/example/statement-extractor/internal/table"
+)
+
+var providersCmd = &cobra.Command{
+	Use:   "providers",
+	Short: "Inspect the configured PDF service providers",
+}
+
+var providersStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show recent provider latency, error rates and circuit state",
+	Long: `Summarise the PDF service calls made by recent extract runs: call count,
+error rate, mean and 95th percentile latency and the last error per provider,
+with the model of its latest call and the share of successful calls whose
+output was malformed JSON that had to be repaired. A provider that often needs
+repair is a candidate for structured_output or another model.
+
+A provider whose last ` + fmt.Sprint(health.BreakerFailures) + ` calls failed has an open circuit and is skipped,
+in favour of the parser's fallback providers, for ` + health.BreakerCooldown.String() + ` after the last
+failure. The next call after that is a trial ("half-open"): a success closes
+the circuit again.`,
+	Example: `  statement-extractor providers status
+  statement-extractor providers status --since 1h`,
+	RunE: runProvidersStatus,
+}
+
+func init() {
+	providersStatusCmd.Flags().Duration("since", 24*time.Hour, "Only count calls made within this duration")
+	addTableFlags(providersStatusCmd)
+	providersCmd.AddCommand(providersStatusCmd)
+	rootCmd.AddCommand(providersCmd)
+}
+
+func runProvidersStatus(cmd *cobra.Command, args []string) error {
+	since, _ := cmd.Flags().GetDuration("since")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	out, err := newDisplay(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	tracker, err := loadHealth(cmd.Context(), cfg)
+	if err != nil {
+		return err
+	}
+
+	stats := tracker.Stats(clock.From(cmd.Context()).Now().Add(-since))
+	seen := make(map[string]bool, len(stats))
+	for _, s := range stats {
+		seen[s.Provider] = true
+	}
+	// Configured providers without calls are listed too, so the table
+	// always covers the whole config
+	var idle []string
+	for name := range cfg.PDFServices {
+		if !seen[name] {
+			idle = append(idle, name)
+		}
+	}
+	sort.Strings(idle)
+	for _, name := range idle {
+		stats = append(stats, health.Stats{Provider: name, State: health.Closed})
+	}
+	if len(stats) == 0 {
+		fmt.Println("No providers configured")
+		return nil
+	}
+
+	tbl := table.New(
+		table.Column{Name: "provider"},
+		table.Column{Name: "model"},
+		table.Column{Name: "state"},
+		table.Column{Name: "calls", Kind: table.Number},
+		table.Column{Name: "errors", Kind: table.Number},
+		table.Column{Name: "error_rate", Header: "ERROR %", Kind: table.Number},
+		table.Column{Name: "mean", Header: "MEAN LATENCY"},
+		table.Column{Name: "p95", Header: "P95 LATENCY"},
+		table.Column{Name: "repair_rate", Header: "REPAIRED %", Kind: table.Number},
+		table.Column{Name: "last_error", Header: "LAST ERROR"},
+	)
+	for _, s := range stats {
+		var lastError string
+		if s.LastError != "" {
+			lastError = s.LastErrorAt.Local().Format("2006-01-02 15:04") + " " + s.LastError
+		}
+		model := s.Model
+		if model == "" {
+			model = cfg.PDFServices[s.Provider].Model
+		}
+		tbl.Append(s.Provider, model, string(s.State), s.Calls, s.Errors, s.ErrorRate()*100,
+			latency(s.MeanLatency), latency(s.P95Latency), s.RepairRate()*100, lastError)
+	}
+	return out.renderTable(cmd, tbl)
+}
+
+// latency formats a duration to the millisecond, or blank when no calls
+// were made
+func latency(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/homeassistant"
+	"github.com/example/statement-extractor/internal/mqtt"
+	"github.com/example/statement-extractor/internal/report"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// node groups the published sensors under one Home Assistant device
+const node = "statement_extractor"
+
+var publishCmd = &cobra.Command{
+	Use:   "publish",
+	Short: "Publish monthly category totals and balances to Home Assistant over MQTT",
+	Long: `Publish a sensor for each category's net amount in --month, by default the
+month of the latest transaction, and for the latest balance of each account to
+the [mqtt] broker. Each sensor is announced with Home Assistant's MQTT
+discovery, so it appears under a "Statement Extractor" device without any
+Home Assistant configuration, and its state is published to
+<topic_prefix>/<sensor>/state. Messages are retained, so Home Assistant keeps
+the values across restarts; run publish after each extract or import to
+update them.
+
+Amounts are converted to the [fx] base currency when conversion is configured,
+and transactions excluded from reports are left out of the category totals.
+--dry-run prints the messages instead of sending them.`,
+	Example: `  statement-extractor publish --input 2024.json
+  statement-extractor publish --input 2024.json --month 2024-06 --dry-run`,
+	RunE: runPublish,
+}
+
+func init() {
+	publishCmd.Flags().StringSlice("input", nil, "TransactionList JSON file(s) to publish from")
+	publishCmd.Flags().String("month", "", "Month of the category totals (YYYY-MM; default: the latest month in the input)")
+	publishCmd.Flags().Bool("dry-run", false, "Print the MQTT messages instead of publishing them")
+	_ = publishCmd.MarkFlagRequired("input")
+	rootCmd.AddCommand(publishCmd)
+}
+
+func runPublish(cmd *cobra.Command, args []string) error {
+	inputs, _ := cmd.Flags().GetStringSlice("input")
+	month, _ := cmd.Flags().GetString("month")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	if cfg.MQTT.Broker == "" && !dryRun {
+		return errors.New("no MQTT broker configured; set mqtt.broker")
+	}
+	if month != "" {
+		if _, err := time.Parse("2006-01", month); err != nil {
+			return fmt.Errorf("invalid --month %q: expected YYYY-MM", month)
+		}
+	}
+
+	txns, err := loadTransactions(inputs)
+	if err != nil {
+		return err
+	}
+	if len(txns) == 0 {
+		return errors.New("no transactions to publish")
+	}
+	if err := convertCurrency(cmd, cfg, txns); err != nil {
+		return err
+	}
+	if month == "" {
+		month = report.SpanOf(txns).End.AddDate(0, -1, 0).Format("2006-01")
+	}
+
+	sensors := homeassistant.Sensors(transaction.ExpandSplits(txns), month, cfg.Money.Currency)
+	messages, err := homeassistant.Messages(sensors, cfg.MQTT.DiscoveryPrefix, cfg.MQTT.TopicPrefix, node)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		for _, m := range messages {
+			fmt.Printf("%s %s\n", m.Topic, m.Payload)
+		}
+		return nil
+	}
+
+	opts := mqtt.Options{ClientID: cfg.MQTT.ClientID, Username: cfg.MQTT.Username}
+	if cfg.MQTT.PasswordEnv != "" {
+		if opts.Password = os.Getenv(cfg.MQTT.PasswordEnv); opts.Password == "" {
+			return fmt.Errorf("mqtt: %s is not set", cfg.MQTT.PasswordEnv)
+		}
+	}
+	client, err := mqtt.Dial(cmd.Context(), cfg.MQTT.Broker, opts)
+	if err != nil {
+		return err
+	}
+	for _, m := range messages {
+		if err := client.Publish(m.Topic, m.Payload, m.Retain); err != nil {
+			client.Close()
+			return err
+		}
+	}
+	if err := client.Close(); err != nil {
+		return fmt.Errorf("failed to disconnect from the MQTT broker: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Published %d sensors for %s to %s\n", len(sensors), month, cfg.MQTT.Broker)
+	return nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/clock"
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/store"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+var storePurgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Delete old transactions from the transaction database",
+	Long: `Delete the transactions dated before --before, optionally only those of
+--account. Without --before the [[retention]] rules of the config file decide:
+each account's transactions dated more than its rule's years ago are deleted,
+and accounts no rule covers are kept.
+
+--export first writes the transactions to be deleted to a TransactionList
+JSON file, which import --db can store again. --dry-run only reports what
+would be deleted. The deletion is confirmed on the terminal unless --yes is
+given. Transactions in closed months are never deleted; reopen the months
+first.`,
+	Example: `  statement-extractor store purge --db txns.db --before 2015-01-01 --dry-run
+  statement-extractor store purge --db txns.db --before 2015-01-01 --account "Old Card" --export old-card.json
+  statement-extractor store purge --db txns.db --yes`,
+	Args: cobra.NoArgs,
+	RunE: runStorePurge,
+}
+
+func init() {
+	addDBFlag(storePurgeCmd)
+	storePurgeCmd.Flags().String("before", "", "Delete transactions dated before this day (YYYY-MM-DD; default: from the retention rules)")
+	storePurgeCmd.Flags().String("account", "", "Only this account")
+	storePurgeCmd.Flags().String("export", "", "Write the transactions to this TransactionList JSON file before deleting them")
+	storePurgeCmd.Flags().Bool("dry-run", false, "Report what would be deleted without deleting it")
+	storePurgeCmd.Flags().Bool("yes", false, "Delete without asking for confirmation")
+	storeCmd.AddCommand(storePurgeCmd)
+}
+
+func runStorePurge(cmd *cobra.Command, args []string) error {
+	beforeFlag, _ := cmd.Flags().GetString("before")
+	account, _ := cmd.Flags().GetString("account")
+	exportPath, _ := cmd.Flags().GetString("export")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	var before time.Time
+	if beforeFlag != "" {
+		var err error
+		if before, err = time.Parse(time.DateOnly, beforeFlag); err != nil {
+			return fmt.Errorf("invalid --before %q: expected YYYY-MM-DD", beforeFlag)
+		}
+	}
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	if before.IsZero() && len(cfg.Retention) == 0 {
+		return errors.New("nothing to purge; pass --before or add [[retention]] rules to the config file")
+	}
+	for _, r := range cfg.Retention {
+		if r.Years <= 0 {
+			return fmt.Errorf("invalid retention rule for %q: years must be positive", r.Account)
+		}
+	}
+	out, err := newDisplay(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	db, err := openStore(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+	ctx := cmd.Context()
+
+	// Without --before only transactions older than the longest-kept
+	// account's cutoff can be listed by date; the rest is sorted out per
+	// account
+	f := store.Filter{Account: account, To: before}
+	cutoff := func(transaction.Transaction) time.Time { return before }
+	if before.IsZero() {
+		now := clock.From(ctx).Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		cutoff = func(t transaction.Transaction) time.Time { return retentionCutoff(cfg.Retention, t.Account, today) }
+		for _, r := range cfg.Retention {
+			f.To = later(f.To, today.AddDate(-r.Years, 0, 0))
+		}
+	}
+	stored, err := db.List(ctx, f)
+	if err != nil {
+		return err
+	}
+	var purged []transaction.Transaction
+	for _, t := range stored {
+		if t.Date.Before(cutoff(t)) {
+			purged = append(purged, t)
+		}
+	}
+	if len(purged) == 0 {
+		fmt.Println("No transactions to purge")
+		return nil
+	}
+
+	closed, err := closedMonths(cmd, db, purged)
+	if err != nil {
+		return err
+	}
+	var total transaction.Amount
+	for _, t := range purged {
+		total += t.Amount
+	}
+	summary := fmt.Sprintf("%d transactions from %s to %s, total %s", len(purged),
+		purged[0].Date.Format(time.DateOnly), purged[len(purged)-1].Date.Format(time.DateOnly), out.money.Format(total.Float()))
+	if len(closed) > 0 {
+		return fmt.Errorf("%s include transactions in closed months %s; reopen them with \"close --reopen --month YYYY-MM\" first",
+			summary, strings.Join(closed, ", "))
+	}
+	if dryRun {
+		fmt.Printf("Would purge %s\n", summary)
+		return nil
+	}
+
+	if !yes {
+		if !interactive(os.Stdin) {
+			return errors.New("not purging without confirmation; pass --yes to purge non-interactively")
+		}
+		fmt.Fprintf(os.Stderr, "Purge %s? [y/N] ", summary)
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer := strings.ToLower(strings.TrimSpace(line)); answer != "y" && answer != "yes" {
+			fmt.Println("Nothing purged")
+			return nil
+		}
+	}
+
+	if exportPath != "" {
+		if err := writeTransactions(ctx, exportPath, "store purge", purged); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d transactions to %s\n", len(purged), exportPath)
+	}
+	removed, _, err := db.Update(ctx, purged, nil)
+	if err != nil {
+		return closedHint(err)
+	}
+	fmt.Printf("Purged %d transactions\n", removed)
+	return nil
+}
+
+// retentionCutoff returns the date before which the retention rules purge
+// the account's transactions, zero when no rule covers the account
+func retentionCutoff(rules []config.RetentionRule, account string, today time.Time) time.Time {
+	years := 0
+	for _, r := range rules {
+		switch {
+		case strings.EqualFold(r.Account, account) && account != "":
+			return today.AddDate(-r.Years, 0, 0)
+		case r.Account == "":
+			years = r.Years
+		}
+	}
+	if years == 0 {
+		return time.Time{}
+	}
+	return today.AddDate(-years, 0, 0)
+}
+
+// later returns the later of two times, zero being the earliest
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// closedMonths returns the closed months, YYYY-MM, the transactions are in
+func closedMonths(cmd *cobra.Command, db *store.Store, txns []transaction.Transaction) ([]string, error) {
+	closings, err := db.Closings(cmd.Context())
+	if err != nil {
+		return nil, err
+	}
+	var months []string
+	for _, c := range closings {
+		if c.Open() {
+			continue
+		}
+		for _, t := range txns {
+			if t.Date.Format("2006-01") == c.Month {
+				months = append(months, c.Month)
+				break
+			}
+		}
+	}
+	return months, nil
+}
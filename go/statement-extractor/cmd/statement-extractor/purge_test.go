@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/example/statement-extractor/internal/config"
+)
+
+func TestRetentionCutoff(t *testing.T) {
+	today := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	rules := []config.RetentionRule{
+		{Account: "Old Card", Years: 2},
+		{Years: 7},
+	}
+
+	assert.Equal(t, time.Date(2022, 6, 30, 0, 0, 0, 0, time.UTC), retentionCutoff(rules, "old card", today))
+	assert.Equal(t, time.Date(2017, 6, 30, 0, 0, 0, 0, time.UTC), retentionCutoff(rules, "Everyday", today))
+	assert.Equal(t, time.Date(2017, 6, 30, 0, 0, 0, 0, time.UTC), retentionCutoff(rules, "", today))
+
+	// Accounts no rule covers are kept
+	assert.True(t, retentionCutoff(rules[:1], "Everyday", today).IsZero())
+}
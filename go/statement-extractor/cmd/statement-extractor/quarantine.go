@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/quarantine"
+	"github.com/example/statement-extractor/internal/table"
+)
+
+var retryQuarantineCmd = &cobra.Command{
+	Use:   "retry-quarantine [<statement>...]",
+	Short: "List quarantined statements or put them back to be extracted again",
+	Long: `Statements that failed quarantine.max_attempts "extract --input-dir" runs in a
+row are moved to the quarantine directory (quarantine.dir, by default
+"quarantine" in state_dir), each with a <name>.error.json report of the
+source, parser and last error.
+
+Once the cause is fixed, for example a parser configured or a provider back,
+this command moves the named statements, or all of them, back to the
+directory they were taken from, or into --to, where the next run picks them
+up with a fresh count of attempts. Statements are named by their file name in
+the quarantine directory. --list shows what is quarantined instead.`,
+	Example: `  statement-extractor retry-quarantine --list
+  statement-extractor retry-quarantine jan.pdf
+  statement-extractor retry-quarantine --to ./statements/inbox`,
+	RunE: runRetryQuarantine,
+}
+
+func init() {
+	retryQuarantineCmd.Flags().Bool("list", false, "List the quarantined statements instead of restoring them")
+	retryQuarantineCmd.Flags().String("to", "", "Restore into this directory instead of where each statement came from")
+	addTableFlags(retryQuarantineCmd)
+	rootCmd.AddCommand(retryQuarantineCmd)
+}
+
+func runRetryQuarantine(cmd *cobra.Command, args []string) error {
+	list, _ := cmd.Flags().GetBool("list")
+	to, _ := cmd.Flags().GetString("to")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	state, err := cfg.StateDirectory()
+	if err != nil {
+		return err
+	}
+	entries, err := quarantine.List(quarantineDir(cfg, state))
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		var named []quarantine.Entry
+		for _, name := range args {
+			i := slices.IndexFunc(entries, func(e quarantine.Entry) bool { return filepath.Base(e.Path) == name })
+			if i < 0 {
+				return fmt.Errorf("%s is not quarantined", name)
+			}
+			named = append(named, entries[i])
+		}
+		entries = named
+	}
+	if len(entries) == 0 {
+		fmt.Println("No quarantined statements")
+		return nil
+	}
+
+	if list {
+		out, err := newDisplay(cmd, cfg)
+		if err != nil {
+			return err
+		}
+		tbl := table.New(
+			table.Column{Name: "statement"},
+			table.Column{Name: "source"},
+			table.Column{Name: "parser"},
+			table.Column{Name: "attempts", Kind: table.Number},
+			table.Column{Name: "quarantined"},
+			table.Column{Name: "error"},
+		)
+		for _, e := range entries {
+			tbl.Append(filepath.Base(e.Path), e.Source, e.Parser, e.Attempts, e.QuarantinedAt.Local().Format("2006-01-02 15:04"), e.Error)
+		}
+		return out.renderTable(cmd, tbl)
+	}
+
+	for _, e := range entries {
+		restored, err := quarantine.Restore(e, to)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s: restored to %s\n", filepath.Base(e.Path), restored)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/store"
+	"github.com/example/statement-extractor/internal/table"
+)
+
+var queryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "Query the transaction database",
+}
+
+var querySQLCmd = &cobra.Command{
+	Use:   "sql <query>",
+	Short: "Run an SQL query over the transaction database",
+	Long: `Run an SQL query over a read-only connection to the transaction database
+for ad-hoc analysis. The database cannot be changed, other files cannot be
+attached or read and sqlite3 dot commands are refused. Amounts are stored in
+cents; run "query sql 'SELECT sql FROM sqlite_schema'" for the schema.
+
+The rows are printed as a table, where --columns, --sort and --limit apply,
+or with --format as CSV with a header or a JSON array of objects.`,
+	Example: `  statement-extractor query sql --db txns.db "SELECT category, SUM(amount) / 100.0 AS total FROM transactions GROUP BY category"
+  statement-extractor query sql --db txns.db --format csv "SELECT * FROM transactions WHERE date >= '2024-06-01'" > june.csv`,
+	Args: cobra.ExactArgs(1),
+	RunE: runQuerySQL,
+}
+
+func init() {
+	addDBFlag(querySQLCmd)
+	querySQLCmd.Flags().String("format", "table", "Output format (table, csv or json)")
+	addTableFlags(querySQLCmd)
+	queryCmd.AddCommand(querySQLCmd)
+	rootCmd.AddCommand(queryCmd)
+}
+
+func runQuerySQL(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	if format != "table" && format != "csv" && format != "json" {
+		return fmt.Errorf("invalid format %q: expected table, csv or json", format)
+	}
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	out, err := newDisplay(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	db, err := openStore(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	cmd.SilenceUsage = true
+	rows, err := db.SQL(cmd.Context(), args[0])
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "csv":
+		return writeRowsCSV(os.Stdout, rows)
+	case "json":
+		return writeRowsJSON(os.Stdout, rows)
+	}
+	if rows.Columns == nil {
+		fmt.Fprintln(os.Stderr, "No rows")
+		return nil
+	}
+	return out.renderTable(cmd, rowsTable(rows))
+}
+
+// rowsTable returns the rows as a table, with columns holding only numbers
+// aligned and sorted as numbers
+func rowsTable(rows store.Rows) *table.Table {
+	columns := make([]table.Column, len(rows.Columns))
+	for i, name := range rows.Columns {
+		columns[i] = table.Column{Name: name, Kind: table.Number}
+		for _, row := range rows.Values {
+			switch row[i].(type) {
+			case nil, int, float64:
+			default:
+				columns[i].Kind = table.Text
+			}
+		}
+	}
+	tbl := table.New(columns...)
+	for _, row := range rows.Values {
+		tbl.Append(row...)
+	}
+	return tbl
+}
+
+func writeRowsCSV(w io.Writer, rows store.Rows) error {
+	if rows.Columns == nil {
+		return nil
+	}
+	cw := csv.NewWriter(w)
+	_ = cw.Write(rows.Columns)
+	for _, row := range rows.Values {
+		record := make([]string, len(row))
+		for i, v := range row {
+			if v != nil {
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		_ = cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeRowsJSON writes the rows as an array of objects whose keys are in
+// the order of the columns
+func writeRowsJSON(w io.Writer, rows store.Rows) error {
+	var b bytes.Buffer
+	b.WriteString("[")
+	for r, row := range rows.Values {
+		if r > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n  {")
+		for i, v := range row {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			key, _ := json.Marshal(rows.Columns[i])
+			value, err := json.Marshal(v)
+			if err != nil {
+				return fmt.Errorf("failed to encode rows: %w", err)
+			}
+			b.Write(key)
+			b.WriteString(": ")
+			b.Write(value)
+		}
+		b.WriteString("}")
+	}
+	if len(rows.Values) > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString("]\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/dedup"
+	"github.com/example/statement-extractor/internal/reconcile"
+	"github.com/example/statement-extractor/internal/table"
+)
+
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Check two sources for the same account against each other",
+	Long: `Match the transactions of two TransactionList files covering the same
+account and period, such as a month extracted from PDF statements and the CSV
+download of that month, and list the transactions only one side has.
+
+Transactions match exactly, or from the same merchant within --days and
+--amount of each other. Each transaction matches at most once, so a purchase
+repeated on the left must be repeated on the right too. The command fails
+when anything is left unmatched.`,
+	Example: `  statement-extractor reconcile --left cba.pdf.json --right cba.csv.json
+  statement-extractor reconcile --left all.json --right cba.csv.json --account Everyday --days 0`,
+	RunE: runReconcile,
+}
+
+func init() {
+	reconcileCmd.Flags().String("left", "", "First TransactionList JSON file")
+	reconcileCmd.Flags().String("right", "", "Second TransactionList JSON file")
+	reconcileCmd.Flags().String("account", "", "Only reconcile transactions of this account")
+	reconcileCmd.Flags().String("period", "", "Only reconcile this period (YYYY, YYYY-MM or YYYY-MM:YYYY-MM)")
+	reconcileCmd.Flags().Int("days", dedup.DefaultTolerance.Days, "Maximum date difference of a match")
+	reconcileCmd.Flags().Float64("amount", dedup.DefaultTolerance.Amount, "Maximum amount difference of a match")
+	_ = reconcileCmd.MarkFlagRequired("left")
+	_ = reconcileCmd.MarkFlagRequired("right")
+	addTableFlags(reconcileCmd)
+	rootCmd.AddCommand(reconcileCmd)
+}
+
+func runReconcile(cmd *cobra.Command, args []string) error {
+	leftPath, _ := cmd.Flags().GetString("left")
+	rightPath, _ := cmd.Flags().GetString("right")
+	account, _ := cmd.Flags().GetString("account")
+	periodFlag, _ := cmd.Flags().GetString("period")
+	days, _ := cmd.Flags().GetInt("days")
+	amount, _ := cmd.Flags().GetFloat64("amount")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	out, err := newDisplay(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	left, err := loadTransactions([]string{leftPath})
+	if err != nil {
+		return err
+	}
+	right, err := loadTransactions([]string{rightPath})
+	if err != nil {
+		return err
+	}
+	if left, err = inPeriod(reconcile.ForAccount(left, account), periodFlag); err != nil {
+		return err
+	}
+	if right, err = inPeriod(reconcile.ForAccount(right, account), periodFlag); err != nil {
+		return err
+	}
+
+	result := reconcile.Reconcile(left, right, dedup.Tolerance{Days: days, Amount: amount})
+	fmt.Printf("%d matched (%d near), %d only in %s, %d only in %s\n",
+		len(result.Matched), result.Near(), len(result.LeftOnly), filepath.Base(leftPath), len(result.RightOnly), filepath.Base(rightPath))
+	if result.Reconciled() {
+		return nil
+	}
+
+	tbl := table.New(
+		table.Column{Name: "only_in", Header: "ONLY IN"},
+		table.Column{Name: "date", Kind: table.Date},
+		table.Column{Name: "amount", Kind: table.Money},
+		table.Column{Name: "description"},
+		table.Column{Name: "account"},
+	)
+	for _, i := range result.LeftOnly {
+		t := left[i]
+		tbl.Append(filepath.Base(leftPath), t.Date, t.Amount, t.Description, t.Account)
+	}
+	for _, i := range result.RightOnly {
+		t := right[i]
+		tbl.Append(filepath.Base(rightPath), t.Date, t.Amount, t.Description, t.Account)
+	}
+	fmt.Println()
+	if err := out.renderTable(cmd, tbl); err != nil {
+		return err
+	}
+	// The table is the report; usage text would only bury it
+	cmd.SilenceUsage = true
+	return fmt.Errorf("%d transactions are unmatched", len(result.LeftOnly)+len(result.RightOnly))
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/lineedit"
+	"github.com/example/statement-extractor/internal/report"
+	"github.com/example/statement-extractor/internal/store"
+	"github.com/example/statement-extractor/internal/table"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+var replCmd = &cobra.Command{
+	Use:   "repl",
+	Short: "Explore the transaction database at an interactive prompt",
+	Long: `Read commands from a prompt and answer them from the transaction database,
+which is only read. Filters set at the prompt stay in effect for the commands
+after them, so a month can be reviewed without repeating the flags of list and
+sum:
+
+  period 2024-06        only this period (YYYY, YYYY-MM or YYYY-MM:YYYY-MM)
+  category Groceries    only this category; a unique prefix is enough
+  account Everyday      only this account; a unique prefix is enough
+  tag work              only transactions with this tag
+  card 4521             only transactions paid with this card
+  filters               show the filters; "clear" removes them all, and a
+                        filter command without a value removes that filter
+  list                  list the transactions
+  search <text>         list the transactions whose description contains text
+  sum [grouping]        total by category (default), account, owner, card,
+                        month or source
+  top [n]               the n largest amounts spent, 10 by default
+  categories, accounts, merchants
+                        what the filters and search can be given, with
+                        counts and totals
+  help, quit
+
+At a terminal, Tab completes the command being typed, and the category,
+account, merchant searched for or grouping after it; pressed again where the
+choices differ, it lists them. Ctrl-C abandons the line and Ctrl-D quits.
+Commands are also read from a pipe, one per line.`,
+	Example: `  statement-extractor repl --db txns.db
+  printf 'period 2024-06\nsum\ntop 5\n' | statement-extractor repl --db txns.db`,
+	Args: cobra.NoArgs,
+	RunE: runREPL,
+}
+
+func init() {
+	addDBFlag(replCmd)
+	rootCmd.AddCommand(replCmd)
+}
+
+func runREPL(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	out, err := newDisplay(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	db, err := openStore(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	r := &repl{cmd: cmd, db: db, out: out, w: os.Stdout}
+	lines := lineedit.New(os.Stdin, os.Stdout, "> ", r.completions)
+	for {
+		line, err := lines.ReadLine()
+		switch {
+		case errors.Is(err, io.EOF):
+			return nil
+		case errors.Is(err, lineedit.ErrInterrupted):
+			continue
+		case err != nil:
+			return err
+		}
+		err = r.run(line)
+		if errors.Is(err, errQuit) {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+		}
+	}
+}
+
+var errQuit = errors.New("quit")
+
+// replCommands are the commands completed at the prompt
+var replCommands = []string{
+	"period", "category", "account", "tag", "card", "filters", "clear", "list", "search", "sum", "top",
+	"categories", "accounts", "merchants", "help", "quit",
+}
+
+// repl answers the commands of a repl session
+type repl struct {
+	cmd    *cobra.Command
+	db     *store.Store
+	out    *display
+	w      io.Writer
+	filter store.Filter
+	period string // as given, for filters
+
+	// names are the categories, accounts and merchants completed at the
+	// prompt, read once as the database is not written
+	names map[string][]string
+}
+
+// run runs one command line; the first word is the command and the rest of
+// the line its argument
+func (r *repl) run(line string) error {
+	name, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	arg = strings.Trim(strings.TrimSpace(arg), `"'`)
+	ctx := r.cmd.Context()
+
+	if name == "" || strings.HasPrefix(name, "#") {
+		return nil
+	}
+	switch name = strings.ToLower(name); name {
+	case "quit", "exit", "q":
+		return errQuit
+	case "help", "?":
+		fmt.Fprintln(r.w, "Commands: period, category, account, tag, card, filters, clear, list, search, sum, top, categories, accounts, merchants, quit")
+		return nil
+
+	case "period":
+		if arg == "" {
+			r.filter.From, r.filter.To, r.period = time.Time{}, time.Time{}, ""
+			return nil
+		}
+		period, err := report.ParsePeriod(arg)
+		if err != nil {
+			return err
+		}
+		r.filter.From, r.filter.To, r.period = period.Start, period.End, arg
+		return nil
+	case "category":
+		category, err := r.complete(arg, "category")
+		if err != nil {
+			return err
+		}
+		r.filter.Category = category
+		return nil
+	case "account":
+		account, err := r.complete(arg, "account")
+		if err != nil {
+			return err
+		}
+		r.filter.Account = account
+		return nil
+	case "tag":
+		r.filter.Tag = arg
+		return nil
+	case "card":
+		if strings.Trim(arg, "0123456789") != "" {
+			return fmt.Errorf("invalid card %q: expected the last digits of the card number", arg)
+		}
+		r.filter.Card = arg
+		return nil
+	case "clear":
+		r.filter, r.period = store.Filter{}, ""
+		return nil
+	case "filters":
+		fmt.Fprintln(r.w, r.describe())
+		return nil
+
+	case "list", "search":
+		if name == "search" && arg == "" {
+			return errors.New("search for what? e.g. search netflix")
+		}
+		f := r.filter
+		f.Text = arg
+		txns, err := r.db.List(ctx, f)
+		if err != nil {
+			return err
+		}
+		return r.list(txns)
+	case "top":
+		n := 10
+		if arg != "" {
+			var err error
+			if n, err = strconv.Atoi(arg); err != nil || n < 1 {
+				return fmt.Errorf("invalid count %q", arg)
+			}
+		}
+		txns, err := r.db.List(ctx, r.filter)
+		if err != nil {
+			return err
+		}
+		txns = slices.DeleteFunc(txns, func(t transaction.Transaction) bool { return t.Amount >= 0 })
+		slices.SortStableFunc(txns, func(a, b transaction.Transaction) int { return cmp.Compare(a.Amount, b.Amount) })
+		return r.list(txns[:min(n, len(txns))])
+	case "sum":
+		by := cmp.Or(arg, "category")
+		if !slices.Contains(store.Groupings, by) {
+			return fmt.Errorf("invalid grouping %q: expected %s", by, strings.Join(store.Groupings, ", "))
+		}
+		totals, err := r.db.Sum(ctx, r.filter, by)
+		if err != nil {
+			return err
+		}
+		return r.totals(by, totals)
+	case "categories", "accounts":
+		by := map[string]string{"categories": "category", "accounts": "account"}[name]
+		totals, err := r.db.Sum(ctx, r.filter, by)
+		if err != nil {
+			return err
+		}
+		return r.totals(by, totals)
+	case "merchants":
+		txns, err := r.db.List(ctx, r.filter)
+		if err != nil {
+			return err
+		}
+		var totals []store.Total
+		index := map[string]int{}
+		for _, t := range txns {
+			merchant := cmp.Or(t.Merchant, t.Description)
+			i, ok := index[merchant]
+			if !ok {
+				i, index[merchant] = len(totals), len(totals)
+				totals = append(totals, store.Total{Group: merchant})
+			}
+			totals[i].Count++
+			totals[i].Amount += t.Amount
+		}
+		slices.SortFunc(totals, func(a, b store.Total) int { return cmp.Compare(a.Group, b.Group) })
+		return r.totals("merchant", totals)
+	}
+	return fmt.Errorf("unknown command %q; try help", name)
+}
+
+// complete resolves prefix to the one stored category or account it
+// starts, case-insensitively, so names need not be typed out in full
+func (r *repl) complete(prefix, by string) (string, error) {
+	if prefix == "" {
+		return "", nil
+	}
+	totals, err := r.db.Sum(r.cmd.Context(), store.Filter{}, by)
+	if err != nil {
+		return "", err
+	}
+	var matches []string
+	for _, t := range totals {
+		switch {
+		case strings.EqualFold(t.Group, prefix):
+			return t.Group, nil
+		case len(t.Group) >= len(prefix) && strings.EqualFold(t.Group[:len(prefix)], prefix):
+			matches = append(matches, t.Group)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no %s starts with %q", by, prefix)
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("%q could be %s", prefix, strings.Join(matches, ", "))
+}
+
+// completions completes the command name being typed, or the category,
+// account, merchant searched for or grouping after it
+func (r *repl) completions(line string) (string, []string) {
+	name, word, found := strings.Cut(line, " ")
+	if !found {
+		var commands []string
+		for _, command := range replCommands {
+			commands = append(commands, command+" ")
+		}
+		return "", startingWith(commands, name)
+	}
+	var names []string
+	switch strings.ToLower(name) {
+	case "category", "account", "search":
+		names = r.namesOf(map[string]string{"category": "category", "account": "account", "search": "merchant"}[strings.ToLower(name)])
+	case "sum":
+		names = store.Groupings
+	}
+	return name + " ", startingWith(names, word)
+}
+
+// namesOf returns the stored categories, accounts or merchants, as by
+func (r *repl) namesOf(by string) []string {
+	if names, ok := r.names[by]; ok {
+		return names
+	}
+	var names []string
+	ctx := r.cmd.Context()
+	if by == "merchant" {
+		// Searches match descriptions, so unnamed merchants are offered by
+		// their descriptions
+		txns, err := r.db.List(ctx, store.Filter{})
+		if err != nil {
+			return nil
+		}
+		for _, t := range txns {
+			names = append(names, cmp.Or(t.Merchant, t.Description))
+		}
+		slices.Sort(names)
+		names = slices.Compact(names)
+	} else {
+		totals, err := r.db.Sum(ctx, store.Filter{}, by)
+		if err != nil {
+			return nil
+		}
+		for _, t := range totals {
+			names = append(names, t.Group)
+		}
+	}
+	if r.names == nil {
+		r.names = map[string][]string{}
+	}
+	r.names[by] = names
+	return names
+}
+
+// startingWith returns the names starting with prefix, ignoring case
+func startingWith(names []string, prefix string) []string {
+	var matches []string
+	for _, name := range names {
+		if len(name) >= len(prefix) && strings.EqualFold(name[:len(prefix)], prefix) {
+			matches = append(matches, name)
+		}
+	}
+	return matches
+}
+
+// describe summarizes the filters in effect
+func (r *repl) describe() string {
+	var filters []string
+	for _, f := range []struct{ name, value string }{
+		{"period", r.period}, {"category", r.filter.Category}, {"account", r.filter.Account},
+		{"tag", r.filter.Tag}, {"card", r.filter.Card},
+	} {
+		if f.value != "" {
+			filters = append(filters, fmt.Sprintf("%s %q", f.name, f.value))
+		}
+	}
+	if len(filters) == 0 {
+		return "No filters; every transaction is included"
+	}
+	return strings.Join(filters, ", ")
+}
+
+func (r *repl) list(txns []transaction.Transaction) error {
+	tbl := table.New(
+		table.Column{Name: "date", Kind: table.Date},
+		table.Column{Name: "description"},
+		table.Column{Name: "amount", Kind: table.Money},
+		table.Column{Name: "category"},
+		table.Column{Name: "account"},
+	)
+	var total transaction.Amount
+	for _, t := range txns {
+		tbl.Append(t.Date, t.Description, t.Amount, t.Category, t.Account)
+		total += t.Amount
+	}
+	if err := r.out.render(r.w, tbl, table.Options{}); err != nil {
+		return err
+	}
+	fmt.Fprintf(r.w, "%d transactions, total %s\n", len(txns), r.out.amount(total.Float()))
+	return nil
+}
+
+func (r *repl) totals(by string, totals []store.Total) error {
+	tbl := table.New(
+		table.Column{Name: by},
+		table.Column{Name: "count", Kind: table.Number},
+		table.Column{Name: "total", Kind: table.Money},
+	)
+	for _, t := range totals {
+		tbl.Append(t.Group, t.Count, t.Amount)
+	}
+	return r.out.render(r.w, tbl, table.Options{})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/store"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestREPL(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	cmd := testCommand()
+	db, err := store.Open(cmd.Context(), filepath.Join(t.TempDir(), "txns.db"))
+	require.NoError(t, err)
+	june := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	_, err = db.Add(cmd.Context(), []transaction.Transaction{
+		{Date: june, Description: "COLES 0123", Merchant: "COLES", Amount: -4550, Category: "Groceries", Account: "Everyday"},
+		{Date: june, Description: "NETFLIX", Amount: -1799, Category: "Entertainment", Account: "Everyday"},
+		{Date: june.AddDate(0, 1, 0), Description: "COLES 0456", Merchant: "COLES", Amount: -2000, Category: "Groceries", Account: "Credit Card"},
+		{Date: june.AddDate(0, 1, 0), Description: "SALARY", Amount: 300000, Category: "Income", Account: "Everyday"},
+	})
+	require.NoError(t, err)
+	out, err := newDisplay(cmd, config.Default())
+	require.NoError(t, err)
+
+	var w bytes.Buffer
+	r := &repl{cmd: cmd, db: db, out: out, w: &w}
+	run := func(line string) string {
+		t.Helper()
+		w.Reset()
+		require.NoError(t, r.run(line))
+		return w.String()
+	}
+
+	// Filters stay in effect, and a unique prefix names a category
+	run("category gro")
+	assert.Equal(t, "category \"Groceries\"\n", run("filters"))
+	assert.Contains(t, run("list"), "2 transactions, total -$65.50")
+	run("period 2024-06")
+	assert.Contains(t, run("list"), "1 transactions, total -$45.50")
+	assert.Contains(t, run("merchants"), "COLES")
+	run("clear")
+
+	assert.Regexp(t, `Entertainment\s+1\s+-\$17\.99`, run("sum"))
+	assert.Contains(t, run("search netflix"), "1 transactions")
+	top := run("top 2")
+	assert.Contains(t, top, "COLES 0123")
+	assert.Contains(t, top, "COLES 0456")
+	assert.NotContains(t, top, "NETFLIX")
+
+	assert.ErrorContains(t, r.run("account savings"), "no account starts with")
+	assert.ErrorContains(t, r.run("category zzz"), `no category starts with "zzz"`)
+	assert.ErrorContains(t, r.run("bogus"), "unknown command")
+	assert.ErrorIs(t, r.run("quit"), errQuit)
+	require.NoError(t, r.run("account c"))
+	assert.Equal(t, "Credit Card", r.filter.Account)
+
+	// Tab completes commands, and the names after them
+	completions := func(line string) []string {
+		head, options := r.completions(line)
+		var lines []string
+		for _, option := range options {
+			lines = append(lines, head+option)
+		}
+		return lines
+	}
+	assert.Equal(t, []string{"category ", "card ", "categories "}, completions("ca"))
+	assert.Equal(t, []string{"category Groceries"}, completions("category gr"))
+	assert.Equal(t, []string{"account Credit Card", "account Everyday"}, completions("account "))
+	assert.Equal(t, []string{"search COLES"}, completions("search co"), "merchants are named once")
+	assert.Equal(t, []string{"sum month"}, completions("sum mo"))
+	assert.Empty(t, completions("top 1"))
+}
@@ -0,0 +1,520 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/example/statement-extractor/internal/alert"
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/fx"
+	"github.com/example/statement-extractor/internal/report"
+	"github.com/example/statement-extractor/internal/store"
+	"github.com/example/statement-extractor/internal/table"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Generate reports from categorized transactions",
+}
+
+var reportFICmd = &cobra.Command{
+	Use:   "fi",
+	Short: "Savings rate and financial-independence projections",
+	Long: `Report savings rate, annual expense run rate and the "FI number" (the invested
+assets needed to cover annual expenses at the configured withdrawal rate),
+with a projection of years to reach it at the current savings rate.
+
+Income and excluded categories, the withdrawal rate and the expected return
+are configured in the [fi] section of the config file.`,
+	Example: `  statement-extractor report fi --input 2024.json --period 2024 --net-worth 150000`,
+	RunE:    runReportFI,
+}
+
+var reportCategoriesCmd = &cobra.Command{
+	Use:   "categories",
+	Short: "Net amount per category or group",
+	Long: `Show the net amount and monthly average for each category over the period.
+With --group-by group, categories are combined into the [[groups]] defined in
+the config file; categories in no group are reported as "Ungrouped". With
+--group-by owner, amounts are broken down by the household member of the
+[[owners]] entries instead, with "Unassigned" for the rest.`,
+	Example: `  statement-extractor report categories --input 2024.json --period 2024 --sort total
+  statement-extractor report categories --input 2024.json --group-by group`,
+	RunE: runReportCategories,
+}
+
+var reportTrendCmd = &cobra.Command{
+	Use:   "trend",
+	Short: "Month-by-month net amount per category or group",
+	Long: `Show each category's net amount for every month of the period alongside the
+monthly average. Use --amortize to spread lumpy expenses, such as annual
+insurance matched by a rule with amortize_months, so renewals do not dominate
+the months they fall in.
+
+With --interval week the columns are weeks instead, headed by their first
+day and starting on the [locale] first day of the week.`,
+	Example: `  statement-extractor report trend --input 2024.json --period 2024-01:2024-06
+  statement-extractor report trend --input 2024.json --group-by group --amortize
+  statement-extractor report trend --input 2024.json --period 2024-03 --interval week`,
+	RunE: runReportTrend,
+}
+
+var reportRecurringCmd = &cobra.Command{
+	Use:   "recurring",
+	Short: "Subscriptions and other recurring payments",
+	Long: `Find recurring payments: at least --min-count debits to the same merchant,
+each within --tolerance of their typical amount, at a regular weekly,
+fortnightly, monthly, quarterly or yearly interval. Each series is listed with
+its frequency, typical amount, monthly cost and the date the next payment is
+expected.
+
+With --output the input transactions are written back with the payments of
+each series tagged "recurring" and their frequency and next expected date
+recorded, so they can be filtered with --tag recurring.`,
+	Example: `  statement-extractor report recurring --input 2024.json
+  statement-extractor report recurring --input 2023.json --input 2024.json --min-count 2
+  statement-extractor report recurring --input 2024.json -o 2024.json`,
+	RunE: runReportRecurring,
+}
+
+var reportBudgetCmd = &cobra.Command{
+	Use:   "budget",
+	Short: "Envelope balances of the monthly budgets",
+	Long: `Show each [[alerts]] rule with a monthly_budget as a budget envelope: for
+every month of the period, the budget, the balance carried in from the month
+before, the net spending on the transactions the rule matches and what is
+available. Rules with rollover = true carry what is left, or the overspending,
+into the next month; the rest start each month afresh.
+
+With the transaction database (--db or database in the config file) the
+envelopes are saved there, and the balance carried into the first month is the
+one saved for the month before it, so reporting month by month keeps the
+balances running. Without it rollover starts from nothing.`,
+	Example: `  statement-extractor report budget --input 2024.json --period 2024
+  statement-extractor report budget --input 2024-07.json --period 2024-07 --db transactions.db`,
+	RunE: runReportBudget,
+}
+
+func init() {
+	reportCmd.PersistentFlags().StringSlice("input", nil, "TransactionList JSON file(s) to report on")
+	reportCmd.PersistentFlags().String("period", "", "Report period (YYYY, YYYY-MM or YYYY-MM:YYYY-MM; default: span of the data)")
+	addTagFlag(reportCmd.PersistentFlags())
+	_ = reportCmd.MarkPersistentFlagRequired("input")
+
+	addGroupByFlag(reportCategoriesCmd)
+	addAmortizeFlag(reportCategoriesCmd)
+	addTableFlags(reportCategoriesCmd)
+	reportCmd.AddCommand(reportCategoriesCmd)
+
+	addGroupByFlag(reportTrendCmd)
+	addAmortizeFlag(reportTrendCmd)
+	addTableFlags(reportTrendCmd)
+	reportTrendCmd.Flags().String("interval", "month", "Column interval: month or week")
+	reportCmd.AddCommand(reportTrendCmd)
+
+	reportRecurringCmd.Flags().Int("min-count", report.DefaultRecurringOptions.MinCount, "Payments needed to count as recurring")
+	reportRecurringCmd.Flags().Float64("tolerance", report.DefaultRecurringOptions.Tolerance, "How far each amount may be from the typical one, as a fraction of it")
+	reportRecurringCmd.Flags().StringP("output", "o", "", "Also write the transactions, with recurring payments tagged, to this file")
+	addTableFlags(reportRecurringCmd)
+	reportCmd.AddCommand(reportRecurringCmd)
+
+	addDBFlag(reportBudgetCmd)
+	addTableFlags(reportBudgetCmd)
+	reportCmd.AddCommand(reportBudgetCmd)
+
+	reportFICmd.Flags().Float64("net-worth", 0, "Current invested assets to project from")
+	reportFICmd.Flags().Float64("withdrawal-rate", 0, "Override the configured safe withdrawal rate (e.g. 0.035)")
+
+	reportCmd.AddCommand(reportFICmd)
+	rootCmd.AddCommand(reportCmd)
+}
+
+// reportInput loads the --input transactions, converts them to the [fx]
+// base currency, keeps those with a --tag, resolves --period and applies
+// --amortize on commands that have them
+func reportInput(cmd *cobra.Command, cfg *config.Config) ([]transaction.Transaction, report.Period, error) {
+	inputs, _ := cmd.Flags().GetStringSlice("input")
+	periodFlag, _ := cmd.Flags().GetString("period")
+	amortize, _ := cmd.Flags().GetBool("amortize")
+	tags, _ := cmd.Flags().GetStringSlice("tag")
+
+	txns, err := loadTransactions(inputs)
+	if err != nil {
+		return nil, report.Period{}, err
+	}
+	if err := convertCurrency(cmd, cfg, txns); err != nil {
+		return nil, report.Period{}, err
+	}
+	txns = transaction.ExpandSplits(withTags(txns, tags))
+
+	// The default period is the span of the real transaction dates, not of
+	// instalments projected into later months
+	period := report.SpanOf(txns)
+	if periodFlag != "" {
+		if period, err = report.ParsePeriod(periodFlag); err != nil {
+			return nil, report.Period{}, err
+		}
+	}
+	if amortize {
+		txns = report.Amortize(txns)
+	}
+	return txns, period, nil
+}
+
+// convertCurrency converts the transactions to the [fx] base currency when
+// conversion is configured
+func convertCurrency(cmd *cobra.Command, cfg *config.Config, txns []transaction.Transaction) error {
+	rates, err := fx.Load(cmd.Context(), cfg.FX, cfg.Money.Currency)
+	if err != nil || rates == nil {
+		return err
+	}
+	return rates.Apply(txns)
+}
+
+// addTagFlag registers --tag for commands that can be limited to tagged
+// transactions
+func addTagFlag(flags *pflag.FlagSet) {
+	flags.StringSlice("tag", nil, "Only transactions with any of these tags")
+}
+
+// withTags keeps the transactions with any of the tags, or all of them when
+// no tags are given
+func withTags(txns []transaction.Transaction, tags []string) []transaction.Transaction {
+	if len(tags) == 0 {
+		return txns
+	}
+	return slices.DeleteFunc(txns, func(t transaction.Transaction) bool {
+		return !slices.ContainsFunc(tags, t.HasTag)
+	})
+}
+
+// addAmortizeFlag registers --amortize for reports of monthly amounts
+func addAmortizeFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("amortize", false, "Spread transactions with amortize_months evenly over those months")
+}
+
+// addGroupByFlag registers --group-by for reports that break amounts down
+// by category
+func addGroupByFlag(cmd *cobra.Command) {
+	cmd.Flags().String("group-by", "category", "Aggregate by category, configured group or owner")
+}
+
+// groupBy applies --group-by, returning the transactions to aggregate and
+// the column name for the breakdown
+func groupBy(cmd *cobra.Command, cfg *config.Config, txns []transaction.Transaction) ([]transaction.Transaction, string, error) {
+	by, _ := cmd.Flags().GetString("group-by")
+	switch by {
+	case "", "category":
+		return txns, "category", nil
+	case "group":
+		if len(cfg.Groups) == 0 {
+			return nil, "", errors.New("--group-by group needs [[groups]] in the config file")
+		}
+		groups, err := report.NewGroups(cfg.Groups)
+		if err != nil {
+			return nil, "", err
+		}
+		return groups.Relabel(txns), "group", nil
+	case "owner":
+		return report.ByOwner(txns), "owner", nil
+	default:
+		return nil, "", fmt.Errorf("invalid --group-by %q: expected category, group or owner", by)
+	}
+}
+
+func runReportCategories(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	out, err := newDisplay(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	txns, period, err := reportInput(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	txns, by, err := groupBy(cmd, cfg, txns)
+	if err != nil {
+		return err
+	}
+
+	agg := report.Aggregate(txns, period)
+	tbl := table.New(
+		table.Column{Name: by},
+		table.Column{Name: "total", Kind: table.Money},
+		table.Column{Name: "monthly", Kind: table.Money},
+	)
+	var total transaction.Amount
+	for _, name := range agg.Categories() {
+		tbl.Append(name, agg.Total(name), agg.MonthlyAverage(name))
+		total += agg.Total(name)
+	}
+
+	fmt.Printf("Net amounts for %s\n\n", period)
+	if err := out.renderTable(cmd, tbl); err != nil {
+		return err
+	}
+	fmt.Printf("\nNet total: %s\n", out.amount(total.Float()))
+	return nil
+}
+
+func runReportTrend(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	out, err := newDisplay(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	txns, period, err := reportInput(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	txns, by, err := groupBy(cmd, cfg, txns)
+	if err != nil {
+		return err
+	}
+	switch interval, _ := cmd.Flags().GetString("interval"); interval {
+	case "week":
+		return weeklyTrend(cmd, out, txns, period, by)
+	case "month":
+	default:
+		return fmt.Errorf("invalid --interval %q: expected month or week", interval)
+	}
+
+	agg := report.Aggregate(txns, period)
+	months := period.MonthLabels()
+	columns := []table.Column{{Name: by}}
+	for _, month := range months {
+		columns = append(columns, table.Column{Name: month, Kind: table.Money})
+	}
+	columns = append(columns, table.Column{Name: "average", Kind: table.Money})
+
+	tbl := table.New(columns...)
+	for _, name := range agg.Categories() {
+		row := []any{name}
+		for _, month := range months {
+			row = append(row, agg.MonthTotal(name, month))
+		}
+		tbl.Append(append(row, agg.MonthlyAverage(name))...)
+	}
+
+	fmt.Printf("Monthly net amounts for %s\n\n", period)
+	return out.renderTable(cmd, tbl)
+}
+
+// weeklyTrend is report trend --interval week
+func weeklyTrend(cmd *cobra.Command, out *display, txns []transaction.Transaction, period report.Period, by string) error {
+	agg := report.AggregateWeeks(txns, period, out.locale.FirstDay)
+	weeks := period.Weeks(out.locale.FirstDay)
+	columns := []table.Column{{Name: by}}
+	for _, week := range weeks {
+		columns = append(columns, table.Column{Name: out.locale.Date(week), Kind: table.Money})
+	}
+	columns = append(columns, table.Column{Name: "average", Kind: table.Money})
+
+	tbl := table.New(columns...)
+	for _, name := range agg.Categories() {
+		row := []any{name}
+		for _, week := range weeks {
+			row = append(row, agg.WeekTotal(name, week))
+		}
+		tbl.Append(append(row, agg.WeeklyAverage(name))...)
+	}
+
+	fmt.Printf("Weekly net amounts for %s, weeks starting %s\n\n", period, out.locale.FirstDay)
+	return out.renderTable(cmd, tbl)
+}
+
+func runReportRecurring(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	out, err := newDisplay(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	inputs, _ := cmd.Flags().GetStringSlice("input")
+	periodFlag, _ := cmd.Flags().GetString("period")
+	tags, _ := cmd.Flags().GetStringSlice("tag")
+	minCount, _ := cmd.Flags().GetInt("min-count")
+	tolerance, _ := cmd.Flags().GetFloat64("tolerance")
+	output, _ := cmd.Flags().GetString("output")
+
+	// Split transactions are kept whole, as payments are matched on their
+	// full amount, and the input is kept as it is for --output
+	txns, err := loadTransactions(inputs)
+	if err != nil {
+		return err
+	}
+	period := report.SpanOf(txns)
+	if periodFlag != "" {
+		if period, err = report.ParsePeriod(periodFlag); err != nil {
+			return err
+		}
+	}
+	var selected []int
+	var candidates []transaction.Transaction
+	for i, t := range txns {
+		if period.Contains(t.Date) && (len(tags) == 0 || slices.ContainsFunc(tags, t.HasTag)) {
+			selected = append(selected, i)
+			candidates = append(candidates, t)
+		}
+	}
+	if err := convertCurrency(cmd, cfg, candidates); err != nil {
+		return err
+	}
+
+	series := report.FindRecurring(candidates, report.RecurringOptions{MinCount: minCount, Tolerance: tolerance})
+	for i := range series {
+		for j, k := range series[i].Indexes {
+			series[i].Indexes[j] = selected[k]
+		}
+	}
+
+	tbl := table.New(
+		table.Column{Name: "merchant"},
+		table.Column{Name: "frequency"},
+		table.Column{Name: "count", Kind: table.Number},
+		table.Column{Name: "amount", Kind: table.Money},
+		table.Column{Name: "monthly", Kind: table.Money},
+		table.Column{Name: "last", Kind: table.Date},
+		table.Column{Name: "next", Kind: table.Date},
+	)
+	var monthly transaction.Amount
+	for _, r := range series {
+		tbl.Append(r.Merchant, r.Frequency.Name, r.Count, r.Amount, r.Monthly(), r.Last, r.Next)
+		monthly += r.Monthly()
+	}
+
+	fmt.Printf("Recurring payments for %s\n\n", period)
+	if err := out.renderTable(cmd, tbl); err != nil {
+		return err
+	}
+	fmt.Printf("\n%d recurring, %s a month\n", len(series), out.amount(monthly.Float()))
+
+	if output == "" {
+		return nil
+	}
+	report.MarkRecurring(txns, series)
+	return writeTransactions(cmd.Context(), output, strings.Join(inputs, ", "), txns)
+}
+
+func runReportFI(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	out, err := newDisplay(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	txns, period, err := reportInput(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	netWorth, _ := cmd.Flags().GetFloat64("net-worth")
+	if rate, _ := cmd.Flags().GetFloat64("withdrawal-rate"); rate > 0 {
+		cfg.FI.WithdrawalRate = rate
+	}
+
+	r := report.FI(txns, period, cfg.FI, netWorth)
+
+	fmt.Printf("FI report for %s\n\n", r.Period)
+	line := func(label, value string) {
+		fmt.Printf("%-21s%s\n", label+":", value)
+	}
+	line("Income", out.amount(r.Income))
+	line("Expenses", out.money.Format(r.Expenses))
+	line("Savings rate", out.theme.Amount(r.SavingsRate(), fmt.Sprintf("%.1f%%", r.SavingsRate()*100)))
+	line("Annual expenses", out.money.Format(r.AnnualExpenses()))
+	line("Annual savings", out.amount(r.AnnualSavings()))
+	line(fmt.Sprintf("FI number (%.1f%%)", r.WithdrawalRate*100), out.money.Format(r.FINumber()))
+
+	if years, ok := r.YearsToFI(); ok {
+		line("Years to FI", fmt.Sprintf("%d (from %s at %.1f%% return)", years, out.money.Format(r.NetWorth), r.ReturnRate*100))
+	} else {
+		line("Years to FI", out.theme.Warning("not reachable at current savings rate"))
+	}
+	return nil
+}
+
+func runReportBudget(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	out, err := newDisplay(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	rules, err := alert.Compile(cfg.Alerts)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(rules, func(r *alert.Rule) bool { return r.MonthlyBudget > 0 }) {
+		fmt.Println("No budgets; add monthly_budget to [[alerts]] rules in the config file")
+		return nil
+	}
+	txns, period, err := reportInput(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	var db *store.Store
+	if dbPath, _ := cmd.Flags().GetString("db"); dbPath != "" || cfg.Database != "" {
+		if db, err = openStore(cmd, cfg); err != nil {
+			return err
+		}
+	}
+	cmd.SilenceUsage = true
+
+	opening := make(map[string]transaction.Amount)
+	if db != nil {
+		before, err := db.Envelopes(cmd.Context(), period.Start.AddDate(0, -1, 0).Format("2006-01"))
+		if err != nil {
+			return err
+		}
+		for name, e := range before {
+			opening[name] = e.Balance
+		}
+	}
+	envelopes := report.Budget(rules, txns, period, opening)
+
+	if db != nil {
+		saved := make([]store.Envelope, len(envelopes))
+		for i, e := range envelopes {
+			saved[i] = store.Envelope{
+				Name: e.Name, Month: e.Month, Budget: e.Budget, Carried: e.Carried, Spent: e.Spent, Balance: e.Available(),
+			}
+		}
+		if err := db.SaveEnvelopes(cmd.Context(), saved); err != nil {
+			return err
+		}
+	}
+
+	tbl := table.New(
+		table.Column{Name: "envelope"},
+		table.Column{Name: "month"},
+		table.Column{Name: "budget", Kind: table.Money},
+		table.Column{Name: "carried", Kind: table.Money},
+		table.Column{Name: "spent", Kind: table.Money},
+		table.Column{Name: "available", Kind: table.Money},
+	)
+	for _, e := range envelopes {
+		tbl.Append(e.Name, e.Month, e.Budget, e.Carried, e.Spent, e.Available())
+	}
+	fmt.Printf("Budgets for %s\n\n", period)
+	return out.renderTable(cmd, tbl)
+}
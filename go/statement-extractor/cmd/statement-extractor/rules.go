@@ -0,0 +1,345 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/categorizer"
+	"github.com/example/statement-extractor/internal/table"
+	"github.com/example/statement-extractor/internal/training"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Work with categorization rules",
+}
+
+var rulesExportTrainingCmd = &cobra.Command{
+	Use:   "export-training",
+	Short: "Export categorized descriptions as classifier training data",
+	Long: `Emit (normalized description, category) pairs from categorized
+transactions for experimenting with other classifiers. Descriptions are
+lower-cased with punctuation and tokens containing digits removed, and
+identical pairs are merged with a count. Transactions in the default category
+are skipped since they carry no label.`,
+	Example: `  statement-extractor rules export-training --input 2024.json --format jsonl -o training.jsonl`,
+	RunE:    runRulesExportTraining,
+}
+
+var rulesTestCmd = &cobra.Command{
+	Use:   "test <description>...",
+	Short: "Show how descriptions would be categorized",
+	Long: `Run each description through the configured categorizer chain and show
+the category assigned, the stage that assigned it, its confidence and the tags
+[[tag_rules]] add. Other rules that also match with a different category are
+listed as conflicts.
+Rules with min_amount, max_amount or direction conditions are tested against
+--amount, negative for money out.`,
+	Example: `  statement-extractor rules test "WOOLWORTHS 1234 SYDNEY" "SALARY ACME PTY"
+  statement-extractor rules test "TRANSFER TO SAVINGS" --amount -5000`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runRulesTest,
+}
+
+var rulesConflictsCmd = &cobra.Command{
+	Use:   "conflicts",
+	Short: "List descriptions matched by rules for different categories",
+	Long: `Run the descriptions of the input transactions through the category rules
+and list those that more than one rule matches with different categories,
+with the rule that wins under the configured priority and rule_match and the
+rules it overrides. Raise a rule's priority or narrow its pattern to settle a
+conflict.`,
+	Example: `  statement-extractor rules conflicts --input 2024.json`,
+	RunE:    runRulesConflicts,
+}
+
+var rulesSuggestCmd = &cobra.Command{
+	Use:   "suggest",
+	Short: "Propose category rules from manual corrections",
+	Long: `Propose [[categories]] entries from the corrections made by hand. A
+correction is an input transaction whose category is not the default and
+differs from what the configured categorizer chain would assign, or a
+description,category line in a --corrections CSV such as one written by
+rules export-training.
+
+Corrections to the same category that share a merchant word are generalized
+into one pattern, and the pattern is run over the input to show how many
+transactions it would match and how many of those are already in the
+category. Only merchants corrected at least --min times are proposed; use
+--toml to print the proposals ready to paste into the config.`,
+	Example: `  statement-extractor rules suggest --input 2024.json
+  statement-extractor rules suggest --input 2024.json --corrections fixes.csv --min 1 --toml`,
+	RunE: runRulesSuggest,
+}
+
+func init() {
+	rulesTestCmd.Flags().Float64("amount", 0, "Amount of the transactions to test")
+	rulesCmd.AddCommand(rulesTestCmd)
+
+	rulesConflictsCmd.Flags().StringSlice("input", nil, "TransactionList JSON file(s) whose descriptions to check")
+	_ = rulesConflictsCmd.MarkFlagRequired("input")
+	addTableFlags(rulesConflictsCmd)
+	rulesCmd.AddCommand(rulesConflictsCmd)
+
+	rulesSuggestCmd.Flags().StringSlice("input", nil, "TransactionList JSON file(s) with the corrected transactions")
+	rulesSuggestCmd.Flags().String("corrections", "", "CSV of description,category corrections to add")
+	rulesSuggestCmd.Flags().Int("min", 2, "Minimum corrections for a merchant to propose a rule")
+	rulesSuggestCmd.Flags().Bool("toml", false, "Print the proposals as [[categories]] entries")
+	_ = rulesSuggestCmd.MarkFlagRequired("input")
+	addTableFlags(rulesSuggestCmd)
+	rulesCmd.AddCommand(rulesSuggestCmd)
+
+	rulesExportTrainingCmd.Flags().StringSlice("input", nil, "TransactionList JSON file(s) to export")
+	rulesExportTrainingCmd.Flags().String("format", "csv", "Output format (csv or jsonl)")
+	rulesExportTrainingCmd.Flags().StringP("output", "o", "", "Write to this file instead of stdout")
+	_ = rulesExportTrainingCmd.MarkFlagRequired("input")
+	rulesCmd.AddCommand(rulesExportTrainingCmd)
+	rootCmd.AddCommand(rulesCmd)
+}
+
+func runRulesTest(cmd *cobra.Command, args []string) error {
+	amount, _ := cmd.Flags().GetFloat64("amount")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	out, err := newDisplay(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	chain, err := categorizer.New(cfg)
+	if err != nil {
+		return err
+	}
+	rules, err := categorizer.ConfiguredRules(cfg)
+	if err != nil {
+		return err
+	}
+	tagger, err := categorizer.NewTagger(cfg.TagRules)
+	if err != nil {
+		return err
+	}
+
+	tbl := table.New(
+		table.Column{Name: "description"},
+		table.Column{Name: "category"},
+		table.Column{Name: "stage"},
+		table.Column{Name: "confidence", Kind: table.Number},
+		table.Column{Name: "tags"},
+		table.Column{Name: "conflicts"},
+	)
+	for _, description := range args {
+		t := transaction.Transaction{Description: description, Amount: transaction.FromFloat(amount)}
+		result, err := chain.Categorize(cmd.Context(), t)
+		if err != nil {
+			return err
+		}
+		conflicts := formatMatches(rules.Conflicts(t))
+		t.Category = result.Category
+		tbl.Append(description, result.Category, result.Stage, result.Confidence, strings.Join(tagger.Tags(t), ", "), conflicts)
+	}
+	return out.renderTable(cmd, tbl)
+}
+
+func runRulesConflicts(cmd *cobra.Command, args []string) error {
+	inputs, _ := cmd.Flags().GetStringSlice("input")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	out, err := newDisplay(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	rules, err := categorizer.ConfiguredRules(cfg)
+	if err != nil {
+		return err
+	}
+	txns, err := loadTransactions(inputs)
+	if err != nil {
+		return err
+	}
+
+	// Transactions with one description may meet different amount
+	// conditions, so they are grouped by the rules they match
+	type conflict struct {
+		description string
+		winner      categorizer.Match
+		conflicts   string
+	}
+	counts := make(map[conflict]int)
+	var found []conflict
+	for _, t := range txns {
+		conflicts := rules.Conflicts(t)
+		if len(conflicts) == 0 {
+			continue
+		}
+		c := conflict{t.Description, rules.Matches(t)[0], formatMatches(conflicts)}
+		if counts[c] == 0 {
+			found = append(found, c)
+		}
+		counts[c]++
+	}
+	if len(found) == 0 {
+		fmt.Println("No conflicting rules")
+		return nil
+	}
+
+	tbl := table.New(
+		table.Column{Name: "description"},
+		table.Column{Name: "count", Kind: table.Number},
+		table.Column{Name: "category"},
+		table.Column{Name: "rule"},
+		table.Column{Name: "conflicts"},
+	)
+	for _, c := range found {
+		tbl.Append(c.description, counts[c], c.winner.Category, c.winner.Pattern, c.conflicts)
+	}
+	if err := out.renderTable(cmd, tbl); err != nil {
+		return err
+	}
+	fmt.Printf("%d descriptions matched by rules for different categories\n", len(found))
+	return nil
+}
+
+// formatMatches lists rule matches as "Category (pattern)"
+func formatMatches(matches []categorizer.Match) string {
+	parts := make([]string, len(matches))
+	for i, m := range matches {
+		parts[i] = fmt.Sprintf("%s (%s)", m.Category, m.Pattern)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func runRulesSuggest(cmd *cobra.Command, args []string) error {
+	inputs, _ := cmd.Flags().GetStringSlice("input")
+	correctionsPath, _ := cmd.Flags().GetString("corrections")
+	minCount, _ := cmd.Flags().GetInt("min")
+	asTOML, _ := cmd.Flags().GetBool("toml")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	out, err := newDisplay(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	chain, err := categorizer.New(cfg)
+	if err != nil {
+		return err
+	}
+	txns, err := loadTransactions(inputs)
+	if err != nil {
+		return err
+	}
+
+	var corrections []transaction.Transaction
+	for _, t := range txns {
+		if t.Category == "" || strings.EqualFold(t.Category, cfg.DefaultCategory) {
+			continue
+		}
+		result, err := chain.Categorize(cmd.Context(), t)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(result.Category, t.Category) {
+			corrections = append(corrections, t)
+		}
+	}
+	if correctionsPath != "" {
+		f, err := os.Open(correctionsPath)
+		if err != nil {
+			return fmt.Errorf("failed to open corrections: %w", err)
+		}
+		examples, err := training.ReadCSV(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read corrections %s: %w", correctionsPath, err)
+		}
+		for _, e := range examples {
+			for range e.Count {
+				corrections = append(corrections, transaction.Transaction{Description: e.Description, Category: e.Category})
+			}
+		}
+	}
+
+	suggestions := training.Suggest(corrections, txns, minCount)
+	if len(suggestions) == 0 {
+		fmt.Printf("No rules to suggest from %d corrections\n", len(corrections))
+		return nil
+	}
+	if asTOML {
+		for _, s := range suggestions {
+			fmt.Printf("# %d corrections; matches %d transactions, %d already in %s\n", s.Corrections, s.Matches, s.Agrees, s.Category)
+			fmt.Printf("[[categories]]\npattern = %q\ncategory = %q\n\n", s.Pattern, s.Category)
+		}
+		return nil
+	}
+
+	tbl := table.New(
+		table.Column{Name: "pattern"},
+		table.Column{Name: "category"},
+		table.Column{Name: "corrections", Kind: table.Number},
+		table.Column{Name: "matches", Kind: table.Number},
+		table.Column{Name: "agrees", Kind: table.Number},
+	)
+	for _, s := range suggestions {
+		tbl.Append(s.Pattern, s.Category, s.Corrections, s.Matches, s.Agrees)
+	}
+	if err := out.renderTable(cmd, tbl); err != nil {
+		return err
+	}
+	fmt.Printf("%d rules suggested from %d corrections\n", len(suggestions), len(corrections))
+	return nil
+}
+
+func runRulesExportTraining(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	inputs, _ := cmd.Flags().GetStringSlice("input")
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+
+	var write func(io.Writer, []training.Example) error
+	switch format {
+	case "csv":
+		write = training.WriteCSV
+	case "jsonl":
+		write = training.WriteJSONL
+	default:
+		return fmt.Errorf("invalid format %q: expected csv or jsonl", format)
+	}
+
+	txns, err := loadTransactions(inputs)
+	if err != nil {
+		return err
+	}
+	examples := training.Collect(txns, cfg.DefaultCategory)
+
+	if output == "" || output == "-" {
+		return write(os.Stdout, examples)
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create output: %w", err)
+	}
+	if err := write(f, examples); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write training data: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write training data: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d examples to %s\n", len(examples), output)
+	return nil
+}
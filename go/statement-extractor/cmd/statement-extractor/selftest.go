@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/selftest"
+	"github.com/example/statement-extractor/internal/theme"
+)
+
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Check the installation and config against a built-in sample",
+	Long: `Run the processing pipeline against an embedded sample statement in a
+temporary directory and report pass or fail for each stage: config
+validation, parsing, categorization, reporting and output. Nothing outside
+the temporary directory is read or written apart from the config file.`,
+	Args: cobra.NoArgs,
+	RunE: runSelftest,
+}
+
+func init() {
+	rootCmd.AddCommand(selftestCmd)
+}
+
+func runSelftest(cmd *cobra.Command, args []string) error {
+	// Failures are reported per stage; usage text would only bury them
+	cmd.SilenceUsage = true
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		fmt.Printf("FAIL  %-10s  %v\n", "config", err)
+		return errors.New("selftest failed")
+	}
+
+	// The config stage reports an invalid theme, so fall back to plain output
+	noColor, _ := cmd.Flags().GetBool("no-color")
+	th, err := theme.New(cfg.Theme, theme.Enabled(noColor, os.Stdout))
+	if err != nil {
+		th = theme.Plain()
+	}
+
+	results, err := selftest.Run(cmd.Context(), cfg, selftest.Stages())
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		switch {
+		case r.Skipped:
+			fmt.Printf("SKIP  %s\n", r.Stage)
+		case r.Err != nil:
+			fmt.Printf("%s  %-10s  %v\n", th.Warning("FAIL"), r.Stage, r.Err)
+		default:
+			fmt.Printf("PASS  %-10s  %s (%s)\n", r.Stage, r.Detail, r.Duration.Round(time.Microsecond))
+		}
+	}
+
+	if !selftest.Passed(results) {
+		return errors.New("selftest failed")
+	}
+	return nil
+}
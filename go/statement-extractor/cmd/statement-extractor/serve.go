@@ -0,0 +1,376 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/api"
+	"github.com/example/statement-extractor/internal/archive"
+	"github.com/example/statement-extractor/internal/clock"
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/graphql"
+	"github.com/example/statement-extractor/internal/jobs"
+	"github.com/example/statement-extractor/internal/parser"
+	"github.com/example/statement-extractor/internal/store"
+	"github.com/example/statement-extractor/internal/systemd"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// shutdownTimeout is how long requests in flight get to finish after
+// SIGINT or SIGTERM
+const shutdownTimeout = 30 * time.Second
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve extraction and the transaction database over HTTP",
+	Long: `Serve the extractor over HTTP, as a small self-hosted service:
+
+  POST /extract      a statement, as the request body or the "file" field of
+                     a form; answers 202 Accepted with the job extracting it
+  GET  /jobs/{id}    the job, with the statement's categorized
+                     TransactionList as its result once it has succeeded
+  GET  /categories   the categories transactions can be assigned
+  POST /categorize   a TransactionList, or an array of transactions; answers
+                     with them categorized
+  GET  /openapi.json the OpenAPI document of these routes; Go programs can
+                     call them with the client in pkg/client
+
+/extract detects the parser unless given one with ?bank=, and names the
+statement after the form's file name or ?name=. Transactions are enriched and
+categorized by the configured categorizer chain, as by extract without
+--llm-categorize. Statements are extracted one at a time, each with the run
+budget of max_tokens_per_run and max_cost_per_run; up to server.queue_size
+more wait their turn, and further uploads get 429 Too Many Requests. A job is
+"queued", "running", "succeeded" or "failed", with the error of a statement
+that could not be extracted, and can be polled for server.job_retention
+after it finishes. With server.webhook set, each finished job is also POSTed
+there.
+
+With server.remote_workers set, uploads are not extracted here but left to
+"statement-extractor worker" processes, on this machine or a bigger one, that
+take them from the queue:
+
+  POST /worker/lease?wait=30s        long-polls for the next upload; 204 No
+                                     Content when none came in time
+  POST /worker/jobs/{id}/complete    reports its transactions, or its error
+
+A worker that does not report an upload within server.lease_timeout, by
+default 10m, loses it to the next worker asking. Uploads are then extracted
+by the workers' configuration, and are not entered in the processing ledger.
+
+With a transaction database, from --db or database, a read-only GraphQL API
+is served for custom frontends too. POST queries to /graphql, or GET
+/graphql?query=..., for transactions with filtering and cursor pagination,
+accounts, categories and report totals. The schema is at
+/graphql/schema.graphql; the API has no introspection and no mutations.
+Statements sent to a PDF service are entered in the database's processing
+ledger, as by extract, and the job of one already there fails rather than
+paying for it again, unless --reprocess is given.
+
+The server listens on server.listen, by default 127.0.0.1:8080, unless
+systemd passes it a socket: with a socket unit, the service is started on the
+first connection and serves the socket instead. Under a Type=notify service
+it reports READY=1 once it is serving. With server.token_env set, every
+request must carry the token in that environment variable as
+"Authorization: Bearer <token>"; set one before listening on anything but
+localhost. The server stops gracefully on SIGINT or SIGTERM, letting requests
+in flight and queued statements finish.`,
+	Example: `  statement-extractor serve --db txns.db
+  curl -s localhost:8080/extract?bank=cba --data-binary @statement.pdf
+  curl -s localhost:8080/graphql -d '{"query": "{ categories(filter: {period: \"2024-06\"}) { name total } }"}'`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+func init() {
+	addDBFlag(serveCmd)
+	serveCmd.Flags().String("listen", "", "Address to listen on (default: server.listen)")
+	serveCmd.Flags().Bool("reprocess", false, "Send statements to providers even when the processing ledger shows they were sent before")
+	rootCmd.AddCommand(serveCmd)
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	listen, _ := cmd.Flags().GetString("listen")
+	reprocess, _ := cmd.Flags().GetBool("reprocess")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	if listen == "" {
+		listen = cfg.Server.Listen
+	}
+	var token string
+	if cfg.Server.TokenEnv != "" {
+		if token = os.Getenv(cfg.Server.TokenEnv); token == "" {
+			return fmt.Errorf("server.token_env: %s is not set", cfg.Server.TokenEnv)
+		}
+	}
+	enrich, err := newEnrichment(cfg)
+	if err != nil {
+		return err
+	}
+	webhook := jobs.WebhookConfig{URL: cfg.Server.Webhook}
+	if cfg.Server.WebhookSecretEnv != "" {
+		if webhook.Secret = os.Getenv(cfg.Server.WebhookSecretEnv); webhook.Secret == "" {
+			return fmt.Errorf("server.webhook_secret_env: %s is not set", cfg.Server.WebhookSecretEnv)
+		}
+	}
+
+	var query http.Handler
+	var db *store.Store
+	if dbPath, _ := cmd.Flags().GetString("db"); dbPath != "" || cfg.Database != "" {
+		if db, err = openStore(cmd, cfg); err != nil {
+			return err
+		}
+		query = graphql.Handler(db)
+	}
+
+	tmp, err := os.MkdirTemp("", "statement-extractor-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	svc, err := newExtractService(cmd.Context(), cfg, enrich, tmp)
+	if err != nil {
+		return err
+	}
+	if db != nil {
+		svc.extractor.Ledger, svc.extractor.Reprocess = db, reprocess
+		svc.extractor.Log = db
+	}
+	// Statements are extracted one at a time, as they share the run budget,
+	// unless remote workers take them
+	queue := jobs.NewQueue(jobs.Options{
+		Workers:      1,
+		Capacity:     cfg.Server.QueueSize,
+		Retention:    cfg.Server.JobRetention,
+		Webhook:      webhook,
+		Remote:       cfg.Server.RemoteWorkers,
+		LeaseTimeout: cfg.Server.LeaseTimeout,
+	})
+	var worker http.Handler
+	if cfg.Server.RemoteWorkers {
+		worker = queue.WorkerHandler()
+	}
+	ln, err := listener(listen)
+	if err != nil {
+		return err
+	}
+	handler := serveHandler(api.Handler(svc, queue), worker, query, token)
+	if host, _, _ := net.SplitHostPort(ln.Addr().String()); token == "" && !isLoopback(host) {
+		fmt.Fprintf(os.Stderr, "warning: serving on %s without a token; set server.token_env\n", ln.Addr())
+	}
+	cmd.SilenceUsage = true
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return serve(ctx, ln, handler, queue)
+}
+
+// serveHandler routes the REST API, and the remote workers' and GraphQL
+// APIs when worker and query are not nil, requiring token when it is not
+// empty
+func serveHandler(rest, worker, query http.Handler, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/extract", rest)
+	mux.Handle("/jobs/", rest)
+	mux.Handle("/categories", rest)
+	mux.Handle("/categorize", rest)
+	mux.Handle("/openapi.json", rest)
+	if worker != nil {
+		mux.Handle("/worker/", worker)
+	}
+	if query != nil {
+		mux.Handle("/graphql", query)
+		mux.Handle("/graphql/", query)
+	}
+	if token == "" {
+		return mux
+	}
+	return api.RequireToken(token, mux)
+}
+
+// listener returns the socket passed by systemd socket activation, or else
+// listens on address
+func listener(address string) (net.Listener, error) {
+	listeners, err := systemd.Listeners()
+	if err != nil {
+		return nil, fmt.Errorf("failed to take the socket from systemd: %w", err)
+	}
+	switch len(listeners) {
+	case 0:
+		ln, err := net.Listen("tcp", address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", address, err)
+		}
+		return ln, nil
+	case 1:
+		return listeners[0], nil
+	default:
+		for _, ln := range listeners {
+			ln.Close()
+		}
+		return nil, fmt.Errorf("systemd passed %d sockets; expected one", len(listeners))
+	}
+}
+
+// isLoopback reports whether host only accepts local connections
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// serve handles requests on ln until ctx is done, then shuts down
+// gracefully: the queue stops taking uploads and finishes those queued,
+// while jobs can still be polled, and then the server stops
+func serve(ctx context.Context, ln net.Listener, handler http.Handler, queue *jobs.Queue) error {
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return context.WithoutCancel(ctx) },
+	}
+	fmt.Fprintf(os.Stderr, "Listening on http://%s\n", ln.Addr())
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+	if err := systemd.Ready(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+	select {
+	case err := <-errc:
+		return fmt.Errorf("server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	fmt.Fprintln(os.Stderr, "Shutting down")
+	if err := systemd.Stopping(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+	if err := queue.Shutdown(shutdownCtx); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: statements still being extracted were cancelled: %v\n", err)
+	}
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down: %w", err)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("server failed: %w", err)
+	}
+	return nil
+}
+
+// extractService runs the extract pipeline behind the REST API. Jobs and
+// requests take turns, as they share the extractor and the enrichment.
+type extractService struct {
+	mu        sync.Mutex
+	cfg       *config.Config
+	extractor *parser.Extractor
+	enrich    *enrichment
+	tmp       string
+}
+
+// newExtractService returns the pipeline extracting uploads saved under tmp
+func newExtractService(ctx context.Context, cfg *config.Config, enrich *enrichment, tmp string) (*extractService, error) {
+	svc := &extractService{cfg: cfg, extractor: parser.New(cfg, nil), enrich: enrich, tmp: tmp}
+	var err error
+	// Provider health is advisory; extraction works without it
+	if svc.extractor.Health, err = loadHealth(ctx, cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+	if svc.extractor.Cache, err = responseCache(cfg); err != nil {
+		return nil, err
+	}
+	return svc, nil
+}
+
+func (s *extractService) Extract(ctx context.Context, name string, document []byte, bank string) (transaction.TransactionList, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Parsers are detected from the file, so the upload is saved under its
+	// own name
+	dir, err := os.MkdirTemp(s.tmp, "upload-")
+	if err != nil {
+		return transaction.TransactionList{}, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, document, 0o600); err != nil {
+		return transaction.TransactionList{}, fmt.Errorf("failed to save statement: %w", err)
+	}
+
+	s.extractor.Budget = parser.NewBudget(s.cfg)
+	txns, info, _, err := extractStatement(ctx, s.cfg, s.extractor, bank, archive.File{Path: path, Source: name}, nil)
+	if s.extractor.Health != nil {
+		if err := s.extractor.Health.Save(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+	}
+	if err != nil {
+		return transaction.TransactionList{}, err
+	}
+	if err := s.enrich.apply(ctx, s.cfg, txns); err != nil {
+		return transaction.TransactionList{}, err
+	}
+
+	list := transaction.TransactionList{
+		Transactions: []transaction.Transaction{},
+		Source:       name,
+		ProcessedAt:  clock.From(ctx).Now().UTC(),
+	}
+	if info.Found() {
+		list.Statements = []transaction.StatementInfo{info}
+	}
+	for _, t := range txns {
+		list.AddTransaction(t)
+	}
+	return list, nil
+}
+
+func (s *extractService) Categorize(ctx context.Context, txns []transaction.Transaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range txns {
+		if txns[i].RawDescription == "" {
+			txns[i].RawDescription = txns[i].Description
+		}
+	}
+	transaction.AssignIDs(txns)
+	return s.enrich.apply(ctx, s.cfg, txns)
+}
+
+// Categories returns the categories of the category rules, those offered to
+// the LLM categorizer, and the default category
+func (s *extractService) Categories() []string {
+	categories := []string{}
+	add := func(category string) {
+		if category != "" && !slices.ContainsFunc(categories, func(c string) bool { return strings.EqualFold(c, category) }) {
+			categories = append(categories, category)
+		}
+	}
+	for _, rule := range s.cfg.Categories {
+		add(rule.Category)
+	}
+	for _, category := range s.cfg.Categorizer.LLM.Categories {
+		add(category)
+	}
+	add(s.cfg.DefaultCategory)
+	return categories
+}
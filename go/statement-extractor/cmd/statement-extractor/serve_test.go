@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/api"
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/jobs"
+	"github.com/example/statement-extractor/internal/parser"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// testServer serves the REST API over a CSV parser "bank" and a rule
+// categorizing COLES as Groceries
+func testServer(t *testing.T, token string) (http.Handler, *jobs.Queue) {
+	cfg := config.Default()
+	cfg.Parsers = map[string]config.ParserConfig{
+		"bank": {Method: "csv", CSV: config.CSVConfig{Date: "Date", Description: "Description", Amount: "Amount", DateFormat: "02/01/2006"}},
+	}
+	cfg.Categories = []config.CategoryRule{{Pattern: "COLES", Category: "Groceries"}}
+	enrich, err := newEnrichment(cfg)
+	require.NoError(t, err)
+
+	svc := &extractService{cfg: cfg, extractor: parser.New(cfg, nil), enrich: enrich, tmp: t.TempDir()}
+	queue := jobs.NewQueue(jobs.Options{Workers: 1, Capacity: 1})
+	t.Cleanup(func() { _ = queue.Shutdown(context.Background()) })
+	return serveHandler(api.Handler(svc, queue), nil, nil, token), queue
+}
+
+func request(t *testing.T, h http.Handler, method, target, body string) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	var decoded map[string]any
+	_ = json.Unmarshal(rec.Body.Bytes(), &decoded)
+	return rec, decoded
+}
+
+func TestServeExtract(t *testing.T) {
+	h, _ := testServer(t, "")
+
+	rec, job := request(t, h, http.MethodPost, "/extract?bank=bank&name=jun.csv", "Date,Description,Amount\n02/06/2024,COLES 0123,-45.50\n")
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	location := rec.Header().Get("Location")
+	require.Eventually(t, func() bool {
+		_, job = request(t, h, http.MethodGet, location, "")
+		return job["status"] == "succeeded" || job["status"] == "failed"
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, "succeeded", job["status"], job["error"])
+
+	b, _ := json.Marshal(job["result"])
+	var list transaction.TransactionList
+	require.NoError(t, json.Unmarshal(b, &list))
+	assert.Equal(t, "jun.csv", list.Source)
+	require.Len(t, list.Transactions, 1)
+	assert.Equal(t, "Groceries", list.Transactions[0].Category)
+	assert.Equal(t, transaction.Amount(-4550), list.Transactions[0].Amount)
+
+	// A statement no parser can read fails its job rather than the request
+	rec, _ = request(t, h, http.MethodPost, "/extract?bank=missing&name=jul.csv", "Date,Description,Amount\n")
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	location = rec.Header().Get("Location")
+	require.Eventually(t, func() bool {
+		_, job = request(t, h, http.MethodGet, location, "")
+		return job["status"] == "failed"
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.NotEmpty(t, job["error"])
+}
+
+func TestServeRoutes(t *testing.T) {
+	h, _ := testServer(t, "s3cret")
+
+	rec, _ := request(t, h, http.MethodGet, "/openapi.json", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	authorized := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Set("Authorization", "Bearer s3cret")
+		h.ServeHTTP(w, r)
+	})
+	rec, spec := request(t, authorized, http.MethodGet, "/openapi.json", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, spec, "openapi")
+	rec, body := request(t, authorized, http.MethodGet, "/categories", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []any{"Groceries", "Uncategorized"}, body["categories"])
+	rec, _ = request(t, authorized, http.MethodPost, "/categorize", `[{"date": "2024-06-02T00:00:00Z", "description": "COLES", "amount": -45.5}]`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec, _ = request(t, authorized, http.MethodGet, "/jobs/missing", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Without a database there is no GraphQL API
+	rec, _ = request(t, authorized, http.MethodGet, "/graphql?query={categories{name}}", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServeRemoteWorkers(t *testing.T) {
+	h, _ := testServer(t, "")
+	rec, _ := request(t, h, http.MethodPost, "/worker/lease?wait=0s", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code, "without server.remote_workers")
+
+	queue := jobs.NewQueue(jobs.Options{Capacity: 1, Remote: true})
+	t.Cleanup(func() { _ = queue.Shutdown(context.Background()) })
+	h = serveHandler(api.Handler(nil, queue), queue.WorkerHandler(), nil, "s3cret")
+	rec, _ = request(t, h, http.MethodPost, "/worker/lease?wait=0s", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "workers need the token")
+
+	authorized := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Set("Authorization", "Bearer s3cret")
+		h.ServeHTTP(w, r)
+	})
+	rec, _ = request(t, authorized, http.MethodPost, "/extract?name=jun.csv", "Date,Description,Amount\n")
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	rec, lease := request(t, authorized, http.MethodPost, "/worker/lease?wait=1s", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "jun.csv", lease["payload"].(map[string]any)["name"])
+	rec, _ = request(t, authorized, http.MethodPost, "/worker/jobs/"+lease["job"].(string)+"/complete", `{"token": "`+lease["token"].(string)+`", "error": "no parser"}`)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+func TestServeDrainsQueue(t *testing.T) {
+	h, queue := testServer(t, "")
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serve(ctx, ln, h, queue) }()
+
+	resp, err := http.Post("http://"+ln.Addr().String()+"/extract?bank=bank", "text/csv", strings.NewReader("Date,Description,Amount\n02/06/2024,COLES,-1\n"))
+	require.NoError(t, err)
+	var job jobs.Job
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&job))
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	cancel()
+	require.NoError(t, <-done)
+	job, ok := queue.Get(job.ID)
+	require.True(t, ok)
+	assert.Equal(t, jobs.StatusSucceeded, job.Status, "the statement queued before shutdown is extracted")
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/report"
+	"github.com/example/statement-extractor/internal/table"
+)
+
+var simulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Project savings from hypothetical spending cuts",
+	Long: `Simulate re-aggregates categorized transactions with hypothetical changes
+applied, such as cutting a category by a percentage or cancelling subscriptions,
+and shows the projected monthly savings per category.`,
+	Example: `  statement-extractor simulate --input 2024.json --period 2024 --cut "Food & dining=20%"
+  statement-extractor simulate --input 2024.json --remove "NETFLIX|SPOTIFY"
+  statement-extractor simulate --input 2024.json --group-by group --cut "Discretionary=30%"`,
+	RunE: runSimulate,
+}
+
+func init() {
+	simulateCmd.Flags().StringSlice("input", nil, "TransactionList JSON file(s) to analyze")
+	simulateCmd.Flags().String("period", "", "Period to simulate (YYYY, YYYY-MM or YYYY-MM:YYYY-MM; default: span of the data)")
+	simulateCmd.Flags().StringArray("cut", nil, `Reduce a category's spending, e.g. "Food & dining=20%" (repeatable)`)
+	simulateCmd.Flags().StringArray("remove", nil, "Drop transactions whose description matches this regex, e.g. a cancelled subscription (repeatable)")
+	_ = simulateCmd.MarkFlagRequired("input")
+	addGroupByFlag(simulateCmd)
+	addAmortizeFlag(simulateCmd)
+	addTableFlags(simulateCmd)
+	rootCmd.AddCommand(simulateCmd)
+}
+
+func runSimulate(cmd *cobra.Command, args []string) error {
+	cutFlags, _ := cmd.Flags().GetStringArray("cut")
+	removeFlags, _ := cmd.Flags().GetStringArray("remove")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	out, err := newDisplay(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	txns, period, err := reportInput(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	txns, by, err := groupBy(cmd, cfg, txns)
+	if err != nil {
+		return err
+	}
+
+	var scenario report.Scenario
+	for _, flag := range cutFlags {
+		cut, err := report.ParseCut(flag)
+		if err != nil {
+			return err
+		}
+		scenario.Cuts = append(scenario.Cuts, cut)
+	}
+	for _, flag := range removeFlags {
+		re, err := regexp.Compile("(?i)" + flag)
+		if err != nil {
+			return fmt.Errorf("invalid --remove pattern %q: %w", flag, err)
+		}
+		scenario.Removed = append(scenario.Removed, re)
+	}
+
+	sim := report.Simulate(txns, period, scenario)
+
+	tbl := table.New(
+		table.Column{Name: by},
+		table.Column{Name: "actual", Kind: table.Money},
+		table.Column{Name: "projected", Kind: table.Money},
+		table.Column{Name: "savings", Kind: table.Money},
+	)
+	for _, line := range sim.Lines {
+		tbl.Append(line.Category, line.Actual, line.Projected, line.Savings())
+	}
+
+	fmt.Printf("Simulation for %s (monthly averages)\n\n", sim.Period)
+	if err := out.renderTable(cmd, tbl); err != nil {
+		return err
+	}
+	fmt.Printf("\nProjected monthly savings: %s\n", out.amount(sim.MonthlySavings()))
+	return nil
+}
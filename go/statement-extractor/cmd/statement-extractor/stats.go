@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/clock"
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/store"
+	"github.com/example/statement-extractor/internal/table"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Summarize local usage: statements, provider calls and cache hits",
+	Long: `Summarize how statements have been extracted, from local records only:
+nothing is sent anywhere. It helps judge which parsers are worth writing or
+improving, and what the PDF services cost in calls.
+
+  --by month      statements first sent to a PDF service, from the
+                  processing ledger, and statements extracted, with the share
+                  the response cache answered
+  --by provider   calls, errors and mean latency from the provider health
+                  file, with the statements each provider extracted and its
+                  cache hit rate
+  --by bank       statements extracted per parser, their cache hit rate and
+                  the mean time an extraction took
+
+Extractions are recorded by extract, watch, serve and backfill when they use
+the transaction database, so the month and bank views need it; the provider
+view shows the health file alone without it. --since limits the provider and
+bank views; the health file keeps recent calls only.`,
+	Example: `  statement-extractor stats
+  statement-extractor stats --by bank --since 2160h
+  statement-extractor stats --by provider --sort -calls`,
+	Args: cobra.NoArgs,
+	RunE: runStats,
+}
+
+func init() {
+	statsCmd.Flags().String("by", "month", "View: month, provider or bank")
+	statsCmd.Flags().Duration("since", 0, "Only count provider calls and extractions within this duration (default: all recorded)")
+	addDBFlag(statsCmd)
+	addTableFlags(statsCmd)
+	rootCmd.AddCommand(statsCmd)
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	by, _ := cmd.Flags().GetString("by")
+	since, _ := cmd.Flags().GetDuration("since")
+	if !slices.Contains([]string{"month", "provider", "bank"}, by) {
+		return fmt.Errorf("invalid --by %q: expected month, provider or bank", by)
+	}
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	out, err := newDisplay(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	var from time.Time
+	if since > 0 {
+		from = clock.From(cmd.Context()).Now().Add(-since)
+	}
+
+	// The provider view makes do with the health file
+	var db *store.Store
+	if dbPath, _ := cmd.Flags().GetString("db"); by != "provider" || dbPath != "" || cfg.Database != "" {
+		if db, err = openStore(cmd, cfg); err != nil {
+			return err
+		}
+	}
+
+	cmd.SilenceUsage = true
+	var tbl *table.Table
+	switch by {
+	case "month":
+		tbl, err = monthStats(cmd, db)
+	case "provider":
+		tbl, err = providerStats(cmd, cfg, db, from)
+	case "bank":
+		tbl, err = bankStats(cmd, db, from)
+	}
+	if err != nil || tbl == nil {
+		return err
+	}
+	return out.renderTable(cmd, tbl)
+}
+
+// noExtractions is printed when the database has recorded no extractions
+const noExtractions = "No statements recorded yet; extract with --db or a configured database to record them"
+
+func monthStats(cmd *cobra.Command, db *store.Store) (*table.Table, error) {
+	months, err := db.UsageByMonth(cmd.Context())
+	if err != nil {
+		return nil, err
+	}
+	if len(months) == 0 {
+		fmt.Println(noExtractions)
+		return nil, nil
+	}
+	tbl := table.New(
+		table.Column{Name: "month"},
+		table.Column{Name: "sent", Header: "SENT TO SERVICE", Kind: table.Number},
+		table.Column{Name: "extracted", Kind: table.Number},
+		table.Column{Name: "cached", Kind: table.Number},
+		table.Column{Name: "cache_rate", Header: "CACHE HIT %", Kind: table.Number},
+	)
+	for _, m := range months {
+		tbl.Append(m.Month, m.Sent, m.Extracted, m.Cached, rate(m.Cached, m.Extracted))
+	}
+	return tbl, nil
+}
+
+func providerStats(cmd *cobra.Command, cfg *config.Config, db *store.Store, from time.Time) (*table.Table, error) {
+	type row struct {
+		calls, errors, extracted, cached int
+		mean                             time.Duration
+	}
+	rows := make(map[string]*row)
+	get := func(provider string) *row {
+		if rows[provider] == nil {
+			rows[provider] = &row{}
+		}
+		return rows[provider]
+	}
+
+	tracker, err := loadHealth(cmd.Context(), cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range tracker.Stats(from) {
+		r := get(s.Provider)
+		r.calls, r.errors, r.mean = s.Calls, s.Errors, s.MeanLatency
+	}
+	if db != nil {
+		usage, err := db.UsageByParser(cmd.Context(), from)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range usage {
+			if u.Provider != "" {
+				r := get(u.Provider)
+				r.extracted += u.Extractions
+				r.cached += u.Cached
+			}
+		}
+	}
+	if len(rows) == 0 {
+		fmt.Println("No provider calls recorded")
+		return nil, nil
+	}
+
+	tbl := table.New(
+		table.Column{Name: "provider"},
+		table.Column{Name: "calls", Kind: table.Number},
+		table.Column{Name: "errors", Kind: table.Number},
+		table.Column{Name: "mean", Header: "MEAN LATENCY"},
+		table.Column{Name: "extracted", Kind: table.Number},
+		table.Column{Name: "cached", Kind: table.Number},
+		table.Column{Name: "cache_rate", Header: "CACHE HIT %", Kind: table.Number},
+	)
+	providers := make([]string, 0, len(rows))
+	for provider := range rows {
+		providers = append(providers, provider)
+	}
+	slices.Sort(providers)
+	for _, provider := range providers {
+		r := rows[provider]
+		tbl.Append(provider, r.calls, r.errors, latency(r.mean), r.extracted, r.cached, rate(r.cached, r.extracted))
+	}
+	return tbl, nil
+}
+
+func bankStats(cmd *cobra.Command, db *store.Store, from time.Time) (*table.Table, error) {
+	usage, err := db.UsageByParser(cmd.Context(), from)
+	if err != nil {
+		return nil, err
+	}
+	if len(usage) == 0 {
+		fmt.Println(noExtractions)
+		return nil, nil
+	}
+
+	tbl := table.New(
+		table.Column{Name: "bank"},
+		table.Column{Name: "extracted", Kind: table.Number},
+		table.Column{Name: "cached", Kind: table.Number},
+		table.Column{Name: "cache_rate", Header: "CACHE HIT %", Kind: table.Number},
+		table.Column{Name: "mean", Header: "MEAN TIME"},
+	)
+	// Rows come by parser, then provider; a bank's are combined
+	for i := 0; i < len(usage); {
+		bank := usage[i].Parser
+		var extracted, cached int
+		var total time.Duration
+		for ; i < len(usage) && usage[i].Parser == bank; i++ {
+			extracted += usage[i].Extractions
+			cached += usage[i].Cached
+			total += usage[i].Mean * time.Duration(usage[i].Extractions)
+		}
+		tbl.Append(bank, extracted, cached, rate(cached, extracted), (total / time.Duration(extracted)).Round(time.Millisecond).String())
+	}
+	return tbl, nil
+}
+
+// rate returns n as a percentage of total, 0 when total is
+func rate(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
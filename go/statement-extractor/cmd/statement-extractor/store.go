@@ -0,0 +1,212 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/report"
+	"github.com/example/statement-extractor/internal/store"
+	"github.com/example/statement-extractor/internal/table"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List transactions in the transaction database",
+	Long: `List the transactions stored with import --db, oldest first, optionally
+limited to a period, category, account or tag.`,
+	Example: `  statement-extractor list --db txns.db --period 2024-06
+  statement-extractor list --db txns.db --category Groceries --sort -amount --limit 10`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runStoreList(cmd, "")
+	},
+}
+
+var searchCmd = &cobra.Command{
+	Use:   "search <text>",
+	Short: "Find transactions in the transaction database by description",
+	Long: `List the stored transactions whose description contains the text, ignoring
+case. The --period, --category, --account and --tag filters of list also
+apply.`,
+	Example: `  statement-extractor search --db txns.db netflix
+  statement-extractor search --db txns.db "bunnings" --period 2024`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runStoreList(cmd, args[0])
+	},
+}
+
+var sumCmd = &cobra.Command{
+	Use:   "sum",
+	Short: "Total transactions in the transaction database",
+	Long: `Count and total the stored transactions by category, account, month or
+source, optionally limited to a period, category, account or tag. Split
+transactions count in each category they are split into, and with --category
+only their parts in that category are totalled.`,
+	Example: `  statement-extractor sum --db txns.db --period 2024
+  statement-extractor sum --db txns.db --by month --category Groceries`,
+	Args: cobra.NoArgs,
+	RunE: runSum,
+}
+
+var storeCmd = &cobra.Command{
+	Use:   "store",
+	Short: "Manage the transaction database",
+}
+
+func init() {
+	rootCmd.AddCommand(storeCmd)
+	for _, cmd := range []*cobra.Command{listCmd, searchCmd, sumCmd} {
+		addDBFlag(cmd)
+		cmd.Flags().String("period", "", "Only this period (YYYY, YYYY-MM or YYYY-MM:YYYY-MM)")
+		cmd.Flags().String("category", "", "Only this category")
+		cmd.Flags().String("account", "", "Only this account")
+		cmd.Flags().String("tag", "", "Only transactions with this tag")
+		cmd.Flags().String("card", "", "Only transactions paid with the card ending in these digits")
+		addTableFlags(cmd)
+		rootCmd.AddCommand(cmd)
+	}
+	sumCmd.Flags().String("by", "category", "Group totals by "+strings.Join(store.Groupings, ", "))
+}
+
+// addDBFlag registers --db for commands using the transaction database
+func addDBFlag(cmd *cobra.Command) {
+	cmd.Flags().String("db", "", "SQLite transaction database (default: database in the config file)")
+	addWaitFlag(cmd)
+}
+
+// addWaitFlag registers --wait for commands that write to the transaction
+// database
+func addWaitFlag(cmd *cobra.Command) {
+	cmd.Flags().Duration("wait", 0, "Wait this long for another process writing to the transaction database, instead of failing as busy")
+}
+
+// openStore opens the database named by --db or the config file
+func openStore(cmd *cobra.Command, cfg *config.Config) (*store.Store, error) {
+	path, _ := cmd.Flags().GetString("db")
+	if path == "" {
+		path = cfg.Database
+	}
+	if path == "" {
+		return nil, errors.New("no transaction database; pass --db or set database in the config file")
+	}
+	wait, _ := cmd.Flags().GetDuration("wait")
+	return store.OpenWait(cmd.Context(), path, wait)
+}
+
+// storeFilter builds the filter from --period, --category, --account, --tag
+// and --card
+func storeFilter(cmd *cobra.Command, text string) (store.Filter, error) {
+	periodFlag, _ := cmd.Flags().GetString("period")
+	category, _ := cmd.Flags().GetString("category")
+	account, _ := cmd.Flags().GetString("account")
+	tag, _ := cmd.Flags().GetString("tag")
+	card, _ := cmd.Flags().GetString("card")
+	if strings.Trim(card, "0123456789") != "" {
+		return store.Filter{}, fmt.Errorf("invalid --card %q: expected the last digits of the card number", card)
+	}
+
+	f := store.Filter{Category: category, Account: account, Text: text, Tag: tag, Card: card}
+	if periodFlag != "" {
+		period, err := report.ParsePeriod(periodFlag)
+		if err != nil {
+			return store.Filter{}, err
+		}
+		f.From, f.To = period.Start, period.End
+	}
+	return f, nil
+}
+
+func runStoreList(cmd *cobra.Command, text string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	out, err := newDisplay(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	f, err := storeFilter(cmd, text)
+	if err != nil {
+		return err
+	}
+	db, err := openStore(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	txns, err := db.List(cmd.Context(), f)
+	if err != nil {
+		return err
+	}
+	tbl := table.New(
+		table.Column{Name: "date", Kind: table.Date},
+		table.Column{Name: "description"},
+		table.Column{Name: "amount", Kind: table.Money},
+		table.Column{Name: "category"},
+		table.Column{Name: "account"},
+		table.Column{Name: "source"},
+		table.Column{Name: "card"},
+		table.Column{Name: "tags"},
+	)
+	for _, t := range txns {
+		tbl.Append(t.Date, t.Description, t.Amount, t.Category, t.Account, t.Source, t.Card, strings.Join(t.Tags, ", "))
+	}
+	return out.renderTable(cmd, tbl)
+}
+
+func runSum(cmd *cobra.Command, args []string) error {
+	by, _ := cmd.Flags().GetString("by")
+	if !slices.Contains(store.Groupings, by) {
+		return fmt.Errorf("invalid --by %q: expected %s", by, strings.Join(store.Groupings, ", "))
+	}
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	out, err := newDisplay(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	f, err := storeFilter(cmd, "")
+	if err != nil {
+		return err
+	}
+	db, err := openStore(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	totals, err := db.Sum(cmd.Context(), f, by)
+	if err != nil {
+		return err
+	}
+	tbl := table.New(
+		table.Column{Name: by},
+		table.Column{Name: "count", Kind: table.Number},
+		table.Column{Name: "total", Kind: table.Money},
+	)
+	var sum transaction.Amount
+	for _, t := range totals {
+		tbl.Append(t.Group, t.Count, t.Amount)
+		sum += t.Amount
+	}
+	if err := out.renderTable(cmd, tbl); err != nil {
+		return err
+	}
+	// A split transaction is in several categories, so the groups' counts
+	// can add up to more than the transactions
+	count, err := db.Count(cmd.Context(), f)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d transactions, total %s\n", count, out.amount(sum.Float()))
+	return nil
+}
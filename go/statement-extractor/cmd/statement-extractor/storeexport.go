@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/export"
+	"github.com/example/statement-extractor/internal/store"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+var storeExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export one account's transactions, notes and history from the transaction database",
+	Long: `Write every stored transaction of --account, oldest first, to one file, e.g.
+to hand an account's history over or to keep it before "store purge".
+
+The JSON format is a TransactionList whose transactions import --db can store
+again, listing the statements they came from, with the account's notes, the
+metadata of its attachments (added with "store note" and "store attach") and
+its audit history: every transaction added to or removed from the account,
+and every note and attachment, with when and by which write. The attached
+files themselves are not copied.
+
+The CSV format has a row per transaction naming its statement, as export
+annual writes, and nothing else.`,
+	Example: `  statement-extractor store export --db txns.db --account Everyday -o everyday.json
+  statement-extractor store export --db txns.db --account "Old Card" --format csv -o old-card.csv`,
+	Args: cobra.NoArgs,
+	RunE: runStoreExport,
+}
+
+func init() {
+	addDBFlag(storeExportCmd)
+	storeExportCmd.Flags().String("account", "", "Account to export")
+	storeExportCmd.Flags().String("format", "json", "Output format (json or csv)")
+	storeExportCmd.Flags().StringP("output", "o", "", "Write to this file instead of stdout")
+	_ = storeExportCmd.MarkFlagRequired("account")
+	storeCmd.AddCommand(storeExportCmd)
+}
+
+func runStoreExport(cmd *cobra.Command, args []string) error {
+	account, _ := cmd.Flags().GetString("account")
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+	if format != "json" && format != "csv" {
+		return fmt.Errorf("invalid format %q: expected json or csv", format)
+	}
+	if account == "" {
+		return errors.New("no account; pass --account")
+	}
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	db, err := openStore(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	txns, err := db.List(cmd.Context(), store.Filter{Account: account})
+	if err != nil {
+		return err
+	}
+	if len(txns) == 0 {
+		return fmt.Errorf("no transactions of account %q in the transaction database", account)
+	}
+	if format == "csv" {
+		err = writeOutput(output, func(w io.Writer) error { return export.WriteCSV(w, txns) })
+	} else {
+		err = writeAccount(cmd.Context(), db, output, account, txns)
+	}
+	if err != nil {
+		return err
+	}
+	if output != "" && output != "-" {
+		fmt.Fprintf(os.Stderr, "Exported %d transactions of %s to %s\n", len(txns), account, output)
+	}
+	return nil
+}
+
+// accountExport is the JSON export of an account
+type accountExport struct {
+	transaction.TransactionList
+	Notes       []store.Note       `json:"notes"`
+	Attachments []store.Attachment `json:"attachments"`
+	Audit       []store.AuditEntry `json:"audit"`
+}
+
+// writeAccount writes account's transactions txns, with its notes,
+// attachments and audit history, as JSON to path
+func writeAccount(ctx context.Context, db *store.Store, path, account string, txns []transaction.Transaction) error {
+	doc := accountExport{TransactionList: statementList(ctx, "store export "+account, txns, statementsOf(txns))}
+	var err error
+	if doc.Notes, err = db.Notes(ctx, store.Filter{Account: account}); err != nil {
+		return err
+	}
+	if doc.Attachments, err = db.Attachments(ctx, store.Filter{Account: account}); err != nil {
+		return err
+	}
+	if doc.Audit, err = db.Audit(ctx, account); err != nil {
+		return err
+	}
+	return writeJSON(path, doc)
+}
+
+// statementsOf lists the statements the transactions came from, in the
+// order they first appear
+func statementsOf(txns []transaction.Transaction) []transaction.StatementInfo {
+	var statements []transaction.StatementInfo
+	seen := map[string]bool{}
+	for _, t := range txns {
+		if t.Statement == nil || seen[t.Statement.SHA256] {
+			continue
+		}
+		seen[t.Statement.SHA256] = true
+		statements = append(statements, transaction.StatementInfo{File: t.Statement.File, SHA256: t.Statement.SHA256})
+	}
+	return statements
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/store"
+)
+
+var storeNoteCmd = &cobra.Command{
+	Use:   "note <id> <text>",
+	Short: "Add a note to a stored transaction",
+	Long: `Add a note to the stored transaction with the ID id: the "id" of the
+transaction in the JSON that extract and "store export" write. Notes stay
+with the transaction while it is stored, and "store export" writes them.`,
+	Example: `  statement-extractor store note --db txns.db 3f9c2a1b "Refunded in March"`,
+	Args:    cobra.MinimumNArgs(2),
+	RunE:    runStoreNote,
+}
+
+var storeAttachCmd = &cobra.Command{
+	Use:   "attach <id> <file>",
+	Short: "Attach a file, such as a receipt, to a stored transaction",
+	Long: `Record file as attached to the stored transaction with the ID id, as for
+"store note". The database keeps the file's path, size, SHA-256 and media
+type, not the file itself, so keep it where it is; "store export" lists the
+attachments.`,
+	Example: `  statement-extractor store attach --db txns.db 3f9c2a1b receipts/laptop.pdf`,
+	Args:    cobra.ExactArgs(2),
+	RunE:    runStoreAttach,
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{storeNoteCmd, storeAttachCmd} {
+		addDBFlag(cmd)
+		storeCmd.AddCommand(cmd)
+	}
+}
+
+func runStoreNote(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	db, err := openStore(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+	return db.AddNote(cmd.Context(), args[0], strings.Join(args[1:], " "))
+}
+
+func runStoreAttach(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	db, err := openStore(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+	attachment, err := describeAttachment(args[1])
+	if err != nil {
+		return err
+	}
+	return db.Attach(cmd.Context(), args[0], attachment)
+}
+
+// describeAttachment reads the file at path for the metadata stored of an
+// attachment
+func describeAttachment(path string) (store.Attachment, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return store.Attachment{}, fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	f, err := os.Open(abs)
+	if err != nil {
+		return store.Attachment{}, fmt.Errorf("failed to open attachment: %w", err)
+	}
+	defer f.Close()
+
+	// The first 512 bytes decide the media type of a file without a known
+	// extension
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return store.Attachment{}, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	head = head[:n]
+	h := sha256.New()
+	h.Write(head)
+	rest, err := io.Copy(h, f)
+	if err != nil {
+		return store.Attachment{}, fmt.Errorf("failed to read attachment: %w", err)
+	}
+
+	mediaType := mime.TypeByExtension(filepath.Ext(abs))
+	if mediaType == "" {
+		mediaType = http.DetectContentType(head)
+	}
+	return store.Attachment{
+		Name:      filepath.Base(abs),
+		Path:      abs,
+		MediaType: mediaType,
+		Size:      int64(n) + rest,
+		SHA256:    hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
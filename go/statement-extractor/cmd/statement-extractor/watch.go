@@ -0,0 +1,538 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/internal/archive"
+	"github.com/example/statement-extractor/internal/clock"
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/export"
+	"github.com/example/statement-extractor/internal/lockfile"
+	"github.com/example/statement-extractor/internal/parser"
+	"github.com/example/statement-extractor/internal/quarantine"
+	"github.com/example/statement-extractor/internal/store"
+	"github.com/example/statement-extractor/internal/systemd"
+	"github.com/example/statement-extractor/internal/watch"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [<dir>]",
+	Short: "Extract statements as they are saved into a drop folder",
+	Long: `Watch a drop folder, by default watch.dir, and extract each statement or
+archive saved into it: its categorized transactions are written under the
+--output directory as by "extract --per-file", and the statement is moved to
+the --archive directory, by default "processed" in the drop folder. Only
+files directly in the drop folder are picked up.
+
+With a transaction database, from --db or database, each statement's
+transactions are stored in it too, as by "import --db": transactions already
+stored are skipped, and while another process is writing to the database the
+statement waits for the next poll, or --wait. Statements sent to a PDF
+service are entered in its processing ledger, as by extract, and one already
+there is not sent again, whatever its file name: it is moved to the archive
+directory without writing anything, unless --reprocess is given. Other
+statements in an archive are still extracted.
+
+The folder is looked at as soon as a file in it changes, where its file
+system reports changes, and every watch.interval, and a file is only taken
+once it has not changed between two looks, a few seconds apart after a change,
+so statements still being copied in are left alone. On a network share or
+synced folder that does not report changes it is only polled. A statement whose name is already in the archive directory is
+archived, and written, with a -2, -3... suffix rather than replacing the
+earlier one, and nothing in the --output directory is written over: jan.csv
+after jan.pdf is written to jan.csv.json.
+
+A statement that fails stays where it is and is tried again after
+watch.retry_after, or as soon as it changes; after quarantine.max_attempts
+failures it is moved to the quarantine directory, see "retry-quarantine".
+The run budget of max_tokens_per_run and max_cost_per_run applies to each
+statement. The [[alerts]] rules are checked as each statement is ingested,
+and the triggers its transactions set off, such as taking a month's spending
+over a monthly_budget, are reported as by "alerts" and sent to
+notify.webhook straight away. With a transaction database they are judged
+with the stored transactions of the statement's months, otherwise with the
+statement's alone.
+
+With watch.source the statements are pulled from a remote folder into the
+drop folder, by default "inbox" in state_dir, and extracted from there:
+dav:// or davs:// (or http:// and https://) for a WebDAV share, such as a
+scanner's, authenticating as the user in the URL with the password in the
+environment variable watch.password_env; gdrive://<folder ID> for a Google
+Drive folder and dropbox:///<path> for a Dropbox one, with an access token in
+the environment variable watch.token_env, or a refresh token configured under
+[watch.oauth] that is exchanged for access tokens as they expire. It is
+listed every watch.source_interval, by default watch.interval, and a file is
+pulled once it has not changed between two listings, then moved into the
+folder's "processed" subfolder. SMB shares are not supported; mount them and
+watch the mount point instead.
+
+--once processes what is in the folder and exits, for running from cron or a
+systemd timer; it takes every statement there, so run it when nothing is
+being copied in. Otherwise watch runs until SIGINT or SIGTERM; a statement
+interrupted part way is left in the folder for next time. Under a Type=notify
+systemd service it reports READY=1 once it is watching, and the statement it
+last processed as its status.`,
+	Example: `  statement-extractor watch ~/Statements/inbox --output ~/Statements/extracted
+  statement-extractor watch --once --format csv`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runWatch,
+}
+
+func init() {
+	watchCmd.Flags().StringP("output", "o", "", "Directory to write the transactions to (default: watch.output)")
+	watchCmd.Flags().String("archive", "", "Directory processed statements are moved to (default: watch.archive)")
+	watchCmd.Flags().String("format", "", "Output format: json or "+exportFormatNames()+" (default: watch.format)")
+	watchCmd.Flags().String("bank", "", "Parser to use (default: detected per statement)")
+	watchCmd.Flags().Duration("interval", 0, "Time between polls of the folder (default: watch.interval)")
+	watchCmd.Flags().Bool("once", false, "Process the statements in the folder and exit")
+	watchCmd.Flags().Bool("reprocess", false, "Send statements to providers even when the processing ledger shows they were sent before")
+	addDBFlag(watchCmd)
+	rootCmd.AddCommand(watchCmd)
+}
+
+// watcher extracts the statements dropped into a folder
+type watcher struct {
+	cmd       *cobra.Command
+	cfg       *config.Config
+	extractor *parser.Extractor
+	enrich    *enrichment
+	alerts    *alerter
+	db        *store.Store
+	bank      string
+	format    export.Format
+	output    string
+	archive   string
+	tmp       string
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	output, _ := cmd.Flags().GetString("output")
+	archiveDir, _ := cmd.Flags().GetString("archive")
+	formatName, _ := cmd.Flags().GetString("format")
+	bank, _ := cmd.Flags().GetString("bank")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	once, _ := cmd.Flags().GetBool("once")
+	reprocess, _ := cmd.Flags().GetBool("reprocess")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	dir := cfg.Watch.Dir
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	remote, err := watchSource(cfg)
+	if err != nil {
+		return err
+	}
+	if remote != nil {
+		if dir == "" {
+			state, err := cfg.StateDirectory()
+			if err != nil {
+				return err
+			}
+			dir = filepath.Join(state, "inbox")
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create drop folder: %w", err)
+		}
+	}
+	output = cmp.Or(output, cfg.Watch.Output)
+	archiveDir = cmp.Or(archiveDir, cfg.Watch.Archive, filepath.Join(dir, "processed"))
+	formatName = cmp.Or(formatName, cfg.Watch.Format, "json")
+	interval = cmp.Or(interval, cfg.Watch.Interval)
+
+	switch {
+	case dir == "":
+		return errors.New("no drop folder; give one or set watch.dir")
+	case output == "":
+		return errors.New("no output directory; pass --output or set watch.output")
+	case interval <= 0 && !once:
+		return fmt.Errorf("invalid interval %s: expected more than 0", interval)
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", dir, err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("failed to watch %s: not a directory", dir)
+	}
+	format, ok := export.Lookup(formatName)
+	if !ok && formatName != "json" {
+		return fmt.Errorf("invalid format %q: expected json or %s", formatName, exportFormatNames())
+	}
+	enrich, err := newEnrichment(cfg)
+	if err != nil {
+		return err
+	}
+	alerts, err := newAlerter(cfg)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.MkdirTemp("", "statement-extractor-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	w := &watcher{
+		cmd:       cmd,
+		cfg:       cfg,
+		extractor: parser.New(cfg, nil),
+		enrich:    enrich,
+		alerts:    alerts,
+		bank:      bank,
+		format:    format,
+		output:    output,
+		archive:   archiveDir,
+		tmp:       tmp,
+	}
+	// Provider health is advisory; extraction works without it
+	if w.extractor.Health, err = loadHealth(cmd.Context(), cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+	if w.extractor.Cache, err = responseCache(cfg); err != nil {
+		return err
+	}
+	if dbPath, _ := cmd.Flags().GetString("db"); dbPath != "" || cfg.Database != "" {
+		if w.db, err = openStore(cmd, cfg); err != nil {
+			return err
+		}
+		w.extractor.Ledger, w.extractor.Reprocess = w.db, reprocess
+		w.extractor.Log = w.db
+	}
+	var q *quarantine.Quarantine
+	if cfg.Quarantine.MaxAttempts > 0 {
+		if q, err = loadQuarantine(cmd.Context(), cfg); err != nil {
+			return err
+		}
+	}
+
+	cmd.SilenceUsage = true
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	cmd.SetContext(ctx)
+
+	folder := watch.New(watch.Local(dir), cfg.Watch.RetryAfter)
+	sourceInterval := cmp.Or(cfg.Watch.SourceInterval, interval)
+	var pulledAt time.Time
+	if once {
+		// Everything in the folders is taken as it is
+		now := clock.From(ctx).Now()
+		if remote != nil {
+			if _, err := remote.Poll(ctx, now); err != nil {
+				return err
+			}
+			pull(ctx, remote, dir, now)
+		}
+		if _, err := folder.Poll(ctx, now); err != nil {
+			return err
+		}
+	} else {
+		if remote != nil {
+			fmt.Fprintf(os.Stderr, "Pulling from %s every %s\n", remote.Source, sourceInterval)
+		}
+		fmt.Fprintf(os.Stderr, "Watching %s\n", dir)
+		if err := systemd.Ready(); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+	}
+	// Changes wake the loop as statements arrive; polling every interval
+	// still finds them where there are none
+	var changes <-chan struct{}
+	if !once {
+		if notifier, err := folder.Notify(); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v; polling every %s\n", err, interval)
+		} else {
+			defer notifier.Close()
+			changes = notifier.C
+		}
+	}
+	for {
+		now := clock.From(ctx).Now()
+		if remote != nil && !once && now.Sub(pulledAt) >= sourceInterval {
+			pull(ctx, remote, dir, now)
+			pulledAt = now
+		}
+		ready, err := folder.Poll(ctx, now)
+		if err != nil {
+			return err
+		}
+		for _, path := range ready {
+			if ctx.Err() != nil {
+				break
+			}
+			w.handle(folder, q, path, now)
+		}
+
+		if w.extractor.Health != nil {
+			if err := w.extractor.Health.Save(ctx); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+			}
+		}
+		if q != nil {
+			if err := q.Save(ctx); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+			}
+		}
+		if once {
+			return nil
+		}
+		wait := interval
+		if changes != nil && folder.Settling() {
+			wait = watch.Settle
+		}
+		if remote != nil {
+			wait = min(wait, max(sourceInterval-clock.From(ctx).Now().Sub(pulledAt), 0))
+		}
+		if sleep(ctx, changes, wait) != nil {
+			if err := systemd.Stopping(); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+			}
+			fmt.Fprintln(os.Stderr, "Stopped watching")
+			return nil
+		}
+	}
+}
+
+// watchSource returns the remote folder of watch.source, or nil without one
+func watchSource(cfg *config.Config) (*watch.Folder, error) {
+	if cfg.Watch.Source == "" {
+		return nil, nil
+	}
+	opts := watch.Options{}
+	env := func(name string) (string, error) {
+		value := os.Getenv(name)
+		if value == "" {
+			return "", fmt.Errorf("watch.source: %s is not set", name)
+		}
+		return value, nil
+	}
+	var err error
+	if cfg.Watch.PasswordEnv != "" {
+		if opts.Password, err = env(cfg.Watch.PasswordEnv); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Watch.TokenEnv != "" {
+		if opts.Token, err = env(cfg.Watch.TokenEnv); err != nil {
+			return nil, err
+		}
+	}
+	if oc := cfg.Watch.OAuth; oc.RefreshTokenEnv != "" {
+		opts.OAuth = &watch.OAuth{ClientID: oc.ClientID}
+		if opts.OAuth.RefreshToken, err = env(oc.RefreshTokenEnv); err != nil {
+			return nil, err
+		}
+		if oc.ClientSecretEnv != "" {
+			if opts.OAuth.ClientSecret, err = env(oc.ClientSecretEnv); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if opts.Client, err = parser.NewHTTPClient(cfg.Watch.HTTP); err != nil {
+		return nil, fmt.Errorf("watch.source: %w", err)
+	}
+	source, err := watch.Open(cfg.Watch.Source, opts)
+	if err != nil {
+		return nil, err
+	}
+	return watch.New(source, cfg.Watch.RetryAfter), nil
+}
+
+// pull fetches the statements ready in the remote folder into the drop
+// folder dir. A remote folder that cannot be reached is tried again at the
+// next listing.
+func pull(ctx context.Context, remote *watch.Folder, dir string, now time.Time) {
+	pulled, err := watch.Pull(ctx, remote, dir, now)
+	for _, path := range pulled {
+		fmt.Fprintf(os.Stderr, "%s: pulled from %s\n", filepath.Base(path), remote.Source)
+	}
+	if err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+}
+
+// sleep waits until wait has passed, or until the folder has been quiet for
+// watch.Settle after a change, or ctx is done
+func sleep(ctx context.Context, changes <-chan struct{}, wait time.Duration) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changes:
+			timer.Reset(watch.Settle)
+		case <-timer.C:
+			return nil
+		}
+	}
+}
+
+// handle processes the statement at path and records the outcome
+func (w *watcher) handle(folder *watch.Folder, q *quarantine.Quarantine, path string, now time.Time) {
+	ctx := w.cmd.Context()
+	name, err := w.process(path)
+	switch {
+	case err == nil:
+		folder.Done(path)
+		if q != nil {
+			q.Succeed(path)
+		}
+	case ctx.Err() != nil:
+		// Interrupted, which says nothing about the statement
+	case errors.Is(err, parser.ErrProcessed):
+		// Archived without being extracted again
+		fmt.Fprintf(os.Stderr, "%s: skipped: %v\n", filepath.Base(path), err)
+		folder.Done(path)
+		if q != nil {
+			q.Succeed(path)
+		}
+	case errors.Is(err, parser.ErrBudgetExceeded):
+		fmt.Fprintf(os.Stderr, "%s: skipped: %v\n", filepath.Base(path), err)
+		folder.Fail(path, now)
+	case errors.Is(err, lockfile.ErrBusy):
+		// Says nothing about the statement either; it is tried at the next
+		// poll
+		fmt.Fprintf(os.Stderr, "%s: skipped: %v\n", filepath.Base(path), err)
+	default:
+		fmt.Fprintf(os.Stderr, "%s: failed: %v\n", filepath.Base(path), err)
+		if q != nil && quarantineStatement(q, archive.File{Path: path, Source: filepath.Base(path)}, name, err) != "" {
+			folder.Done(path)
+			return
+		}
+		folder.Fail(path, now)
+	}
+}
+
+// process extracts the statements in the file at path, writes their
+// transactions and moves the file to the archive directory. It returns the
+// name of the parser, of the last statement for an archive. Nothing is
+// written unless every statement in an archive succeeds. Statements the
+// processing ledger refuses are passed over; when it refuses all of them the
+// file is archived and the refusal, parser.ErrProcessed, returned.
+func (w *watcher) process(path string) (string, error) {
+	ctx := w.cmd.Context()
+	// Outputs are named after the archived statement, so that a later
+	// statement of the same name gets new ones
+	archived := archive.FreeName(w.archive, filepath.Base(path))
+	name := filepath.Base(archived)
+
+	files := []archive.File{{Path: path, Source: name}}
+	if archive.IsArchive(path) {
+		tmp, err := os.MkdirTemp(w.tmp, "drop-")
+		if err != nil {
+			return "", fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+		if files, err = archive.Expand(path, tmp); err != nil {
+			return "", err
+		}
+		if len(files) == 0 {
+			return "", errors.New("no statements in the archive")
+		}
+		for i := range files {
+			files[i].Source = name + strings.TrimPrefix(files[i].Source, filepath.Base(path))
+		}
+	}
+
+	w.extractor.Budget = parser.NewBudget(w.cfg)
+	var txns []transaction.Transaction
+	var sources []string
+	var statements []transaction.StatementInfo
+	var parserName string
+	var processed error
+	for _, file := range files {
+		extracted, info, used, err := extractStatement(ctx, w.cfg, w.extractor, w.bank, file, nil)
+		parserName = used
+		if errors.Is(err, parser.ErrProcessed) {
+			if len(files) > 1 {
+				fmt.Fprintf(os.Stderr, "%s: skipped: %v\n", file.Source, err)
+			}
+			processed = err
+			continue
+		}
+		if err != nil {
+			if len(files) > 1 {
+				err = fmt.Errorf("%s: %w", file.Source, err)
+			}
+			return parserName, err
+		}
+		txns = append(txns, extracted...)
+		sources = append(sources, file.Source)
+		if info.Found() {
+			statements = append(statements, info)
+		}
+	}
+	if len(sources) == 0 && processed != nil {
+		if err := w.moveToArchive(path, archived); err != nil {
+			return parserName, err
+		}
+		return parserName, fmt.Errorf("%w; archived to %s", processed, archived)
+	}
+
+	if err := w.enrich.apply(ctx, w.cfg, txns); err != nil {
+		return parserName, err
+	}
+	// Stored before the outputs are written, so that a statement the store
+	// refuses is tried again without having written any; the store skips
+	// what it already has. Alerts are judged with the rest of the months
+	// stored.
+	stored := ""
+	var before []transaction.Transaction
+	after := txns
+	if w.db != nil {
+		months := alertMonths(txns)
+		var err error
+		if w.alerts != nil {
+			if before, err = w.db.List(ctx, months); err != nil {
+				return parserName, err
+			}
+		}
+		added, err := w.db.Add(ctx, txns)
+		if err != nil {
+			return parserName, closedHint(err)
+		}
+		stored = fmt.Sprintf(", %d new in the database", added)
+		if w.alerts != nil {
+			if after, err = w.db.List(ctx, months); err != nil {
+				return parserName, err
+			}
+		}
+	}
+	if err := writePerFile(w.cmd, w.cfg, w.output, w.format, sources, txns, statements, true); err != nil {
+		return parserName, err
+	}
+	if err := w.moveToArchive(path, archived); err != nil {
+		return parserName, err
+	}
+	fmt.Fprintf(os.Stderr, "%s: %d transactions (%s)%s, archived to %s\n", filepath.Base(path), len(txns), parserName, stored, archived)
+	_ = systemd.Status("%s: %d transactions", filepath.Base(path), len(txns))
+	w.alerts.notify(ctx, before, after)
+	return parserName, nil
+}
+
+// moveToArchive moves the statement at path to archived in the archive
+// directory
+func (w *watcher) moveToArchive(path, archived string) error {
+	if err := os.MkdirAll(w.archive, 0o755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	if err := archive.Move(path, archived); err != nil {
+		return fmt.Errorf("failed to archive %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
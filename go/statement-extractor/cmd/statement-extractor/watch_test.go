@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/parser"
+	"github.com/example/statement-extractor/internal/store"
+)
+
+func TestWatchSkipsProcessed(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(`{"transactions": [{"date": "2024-02-01", "description": "RENT", "amount": -500}]}`))
+	}))
+	defer srv.Close()
+
+	cfg := config.Default()
+	cfg.Parsers = map[string]config.ParserConfig{"westpac": {Method: "pdf", Provider: "svc"}}
+	cfg.PDFServices = map[string]config.ServiceConfig{"svc": {BaseURL: srv.URL}}
+	enrich, err := newEnrichment(cfg)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	db, err := store.Open(testCommand().Context(), filepath.Join(dir, "txns.db"))
+	require.NoError(t, err)
+
+	w := &watcher{
+		cmd:       testCommand(),
+		cfg:       cfg,
+		extractor: parser.New(cfg, srv.Client()),
+		enrich:    enrich,
+		db:        db,
+		output:    filepath.Join(dir, "out"),
+		archive:   filepath.Join(dir, "processed"),
+		tmp:       t.TempDir(),
+	}
+	w.extractor.Ledger = db
+	drop := func(name string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte("%PDF-1.7 westpac"), 0o600))
+		return path
+	}
+
+	_, err = w.process(drop("jan.pdf"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+
+	// The same statement dropped again under another name
+	again := drop("jan (1).pdf")
+	_, err = w.process(again)
+	assert.ErrorIs(t, err, parser.ErrProcessed)
+	assert.Equal(t, 1, calls, "the statement is not sent again")
+	assert.NoFileExists(t, again)
+	assert.FileExists(t, filepath.Join(w.archive, "jan (1).pdf"))
+	assert.NoFileExists(t, filepath.Join(w.output, "jan (1).json"))
+
+	w.extractor.Reprocess = true
+	_, err = w.process(drop("jan (2).pdf"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls, "--reprocess sends it anyway")
+}
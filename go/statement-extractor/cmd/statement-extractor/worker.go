@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/statement-extractor/pkg/client"
+)
+
+// Worker timings: the long poll for an upload, and the pause before asking
+// again after the coordinator could not be reached
+const (
+	leaseWait   = 30 * time.Second
+	workerRetry = 10 * time.Second
+)
+
+var workerCmd = &cobra.Command{
+	Use:   "worker",
+	Short: "Extract statements uploaded to a serve coordinator on this machine",
+	Long: `Extract the statements uploaded to a "statement-extractor serve" running with
+server.remote_workers set, so that OCR and LLM extraction run on this machine
+instead of the one hosting the database. The worker takes one upload at a
+time from the coordinator at --coordinator, extracts, enriches and
+categorizes it with this machine's configuration, as serve would, and sends
+the transactions back as the result of the upload's job.
+
+Workers keep no state: run as many as the providers' rate limits allow, and
+stop them at any time. The processing ledger, extraction log and database
+stay with the coordinator, so uploads extracted by workers are not checked
+against the ledger. An upload a worker takes and does not report within the
+coordinator's server.lease_timeout is given to another worker.
+
+With server.token_env set, the token in that environment variable is sent
+to the coordinator, which must have the same token. The worker stops on
+SIGINT or SIGTERM once the statement in hand is reported.`,
+	Example: `  statement-extractor worker --coordinator http://store.local:8080`,
+	Args:    cobra.NoArgs,
+	RunE:    runWorker,
+}
+
+func init() {
+	workerCmd.Flags().String("coordinator", "", "URL of the serve coordinator")
+	_ = workerCmd.MarkFlagRequired("coordinator")
+	rootCmd.AddCommand(workerCmd)
+}
+
+func runWorker(cmd *cobra.Command, args []string) error {
+	coordinator, _ := cmd.Flags().GetString("coordinator")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	var token string
+	if cfg.Server.TokenEnv != "" {
+		if token = os.Getenv(cfg.Server.TokenEnv); token == "" {
+			return fmt.Errorf("server.token_env: %s is not set", cfg.Server.TokenEnv)
+		}
+	}
+	enrich, err := newEnrichment(cfg)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp("", "statement-extractor-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	svc, err := newExtractService(cmd.Context(), cfg, enrich, tmp)
+	if err != nil {
+		return err
+	}
+	// The coordinator answers a lease within leaseWait, unless it is stuck
+	c := client.New(coordinator, token)
+	c.HTTP = &http.Client{Timeout: leaseWait + time.Minute}
+
+	cmd.SilenceUsage = true
+	// The statement in hand is finished and reported after a signal
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	work := context.WithoutCancel(ctx)
+	fmt.Fprintf(os.Stderr, "Taking statements from %s\n", coordinator)
+	for ctx.Err() == nil {
+		lease, ok, err := c.Lease(ctx, leaseWait)
+		var apiErr *client.Error
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized:
+			return fmt.Errorf("%s refused the token: %w", coordinator, err)
+		case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+			return fmt.Errorf("%s does not take remote workers; set server.remote_workers", coordinator)
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "warning: %v; retrying in %s\n", err, workerRetry)
+			select {
+			case <-ctx.Done():
+			case <-time.After(workerRetry):
+			}
+			continue
+		case !ok:
+			continue
+		}
+
+		name := lease.Statement.Name
+		list, failed := svc.Extract(work, name, lease.Statement.Document, lease.Statement.Bank)
+		if err := c.Complete(work, lease, list, failed); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to report %s: %v\n", name, err)
+			continue
+		}
+		if failed != nil {
+			fmt.Fprintf(os.Stderr, "Failed %s: %v\n", name, failed)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "Extracted %d transactions from %s\n", len(list.Transactions), name)
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+// Package alert evaluates configured alert rules against transactions, such as
+// "any transaction over $500 in Entertainment" or "more than 3 ATM
+// withdrawals per week".
+package alert
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Rule is a compiled alert rule
+type Rule struct {
+	config.AlertRule
+	pattern *regexp.Regexp
+}
+
+// Trigger records a rule firing for one or more transactions
+type Trigger struct {
+	Rule         string
+	Window       string // window label for frequency and budget rules, e.g. "2024-W07"
+	Transactions []transaction.Transaction
+
+	// Budget rules trigger on one transaction, recording the month's budget
+	// and the net spending up to and including it
+	Budget transaction.Amount
+	Spent  transaction.Amount
+}
+
+// Total returns the net amount of the triggering transactions
+func (t Trigger) Total() transaction.Amount {
+	var total transaction.Amount
+	for _, tx := range t.Transactions {
+		total += tx.Amount
+	}
+	return total
+}
+
+// Message describes the trigger for notifications and listings
+func (t Trigger) Message() string {
+	if t.Budget > 0 {
+		tx := t.Transactions[0]
+		return fmt.Sprintf("%s: %s %s %s took %s spending to %s of %s", t.Rule,
+			tx.Date.Format("2006-01-02"), tx.Description, tx.Amount, t.Window, t.Spent, t.Budget)
+	}
+	if t.Window != "" {
+		return fmt.Sprintf("%s: %d transactions in %s totalling %s", t.Rule, len(t.Transactions), t.Window, t.Total())
+	}
+	tx := t.Transactions[0]
+	return fmt.Sprintf("%s: %s %s %s", t.Rule, tx.Date.Format("2006-01-02"), tx.Description, tx.Amount)
+}
+
+// Notification is the JSON body a trigger is sent as
+type Notification struct {
+	Rule         string                    `json:"rule"`
+	Window       string                    `json:"window,omitempty"`
+	Message      string                    `json:"message"`
+	Transactions []transaction.Transaction `json:"transactions"`
+	Budget       *transaction.Amount       `json:"budget,omitempty"`
+	Spent        *transaction.Amount       `json:"spent,omitempty"`
+}
+
+// Notification returns the trigger as it is sent
+func (t Trigger) Notification() Notification {
+	n := Notification{Rule: t.Rule, Window: t.Window, Message: t.Message(), Transactions: t.Transactions}
+	if t.Budget > 0 {
+		n.Budget, n.Spent = &t.Budget, &t.Spent
+	}
+	return n
+}
+
+// Added returns the triggers in after that are not in before, as when
+// after is evaluated once transactions were added to those before was
+// evaluated on. A trigger that changed, such as a window with one more
+// transaction, is added.
+func Added(before, after []Trigger) []Trigger {
+	seen := make(map[string]bool, len(before))
+	for _, t := range before {
+		seen[t.Message()] = true
+	}
+	var added []Trigger
+	for _, t := range after {
+		if !seen[t.Message()] {
+			added = append(added, t)
+		}
+	}
+	return added
+}
+
+// Compile validates the configured rules
+func Compile(rules []config.AlertRule) ([]*Rule, error) {
+	compiled := make([]*Rule, 0, len(rules))
+	for i, r := range rules {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("alert %d", i+1)
+		}
+
+		rule := &Rule{AlertRule: r}
+		if r.Pattern != "" {
+			re, err := regexp.Compile("(?i)" + r.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("alert %q: invalid pattern: %w", r.Name, err)
+			}
+			rule.pattern = re
+		}
+
+		if r.MonthlyBudget < 0 {
+			return nil, fmt.Errorf("alert %q: monthly_budget must not be negative", r.Name)
+		}
+		if r.MonthlyBudget > 0 && r.Count > 0 {
+			return nil, fmt.Errorf("alert %q: count and monthly_budget cannot be combined", r.Name)
+		}
+		if r.Rollover && r.MonthlyBudget == 0 {
+			return nil, fmt.Errorf("alert %q: rollover needs monthly_budget", r.Name)
+		}
+		if r.Count > 0 {
+			if _, err := windowLabel(r.Window, transaction.Transaction{}); err != nil {
+				return nil, fmt.Errorf("alert %q: %w", r.Name, err)
+			}
+		}
+		compiled = append(compiled, rule)
+	}
+	return compiled, nil
+}
+
+// Matches reports whether a single transaction satisfies the rule's filters
+func (r *Rule) Matches(t transaction.Transaction) bool {
+	if r.Category != "" && !strings.EqualFold(r.Category, t.Category) {
+		return false
+	}
+	if r.pattern != nil && !r.pattern.MatchString(t.Description) {
+		return false
+	}
+	return t.Amount.Abs() >= transaction.FromFloat(r.MinAmount)
+}
+
+// Evaluate returns the triggers for every rule, in rule order
+func Evaluate(rules []*Rule, txns []transaction.Transaction) []Trigger {
+	var triggers []Trigger
+	for _, rule := range rules {
+		triggers = append(triggers, rule.evaluate(txns)...)
+	}
+	return triggers
+}
+
+func (r *Rule) evaluate(txns []transaction.Transaction) []Trigger {
+	var matched []transaction.Transaction
+	for _, t := range txns {
+		if r.Matches(t) {
+			matched = append(matched, t)
+		}
+	}
+
+	if r.MonthlyBudget > 0 {
+		return r.overBudget(matched)
+	}
+	if r.Count <= 0 {
+		triggers := make([]Trigger, 0, len(matched))
+		for _, t := range matched {
+			triggers = append(triggers, Trigger{Rule: r.Name, Transactions: []transaction.Transaction{t}})
+		}
+		return triggers
+	}
+
+	windows := make(map[string][]transaction.Transaction)
+	for _, t := range matched {
+		label, _ := windowLabel(r.Window, t)
+		windows[label] = append(windows[label], t)
+	}
+
+	labels := make([]string, 0, len(windows))
+	for label, txns := range windows {
+		if len(txns) > r.Count {
+			labels = append(labels, label)
+		}
+	}
+	sort.Strings(labels)
+
+	triggers := make([]Trigger, 0, len(labels))
+	for _, label := range labels {
+		triggers = append(triggers, Trigger{Rule: r.Name, Window: label, Transactions: windows[label]})
+	}
+	return triggers
+}
+
+// overBudget returns a trigger for each month whose net spending on the
+// matched transactions goes over the budget, on the transaction that takes
+// it over
+func (r *Rule) overBudget(matched []transaction.Transaction) []Trigger {
+	matched = slices.Clone(matched)
+	transaction.SortByDate(matched)
+
+	budget := transaction.FromFloat(r.MonthlyBudget)
+	spent := make(map[string]transaction.Amount)
+	var triggers []Trigger
+	for _, t := range matched {
+		month := t.Date.Format("2006-01")
+		before := spent[month]
+		spent[month] -= t.Amount
+		if before <= budget && spent[month] > budget {
+			triggers = append(triggers, Trigger{
+				Rule: r.Name, Window: month, Transactions: []transaction.Transaction{t},
+				Budget: budget, Spent: spent[month],
+			})
+		}
+	}
+	return triggers
+}
+
+// windowLabel buckets a transaction into its day, ISO week or month
+func windowLabel(window string, t transaction.Transaction) (string, error) {
+	switch strings.ToLower(window) {
+	case "day":
+		return t.Date.Format("2006-01-02"), nil
+	case "week", "":
+		year, week := t.Date.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week), nil
+	case "month":
+		return t.Date.Format("2006-01"), nil
+	default:
+		return "", fmt.Errorf("invalid window %q: expected day, week or month", window)
+	}
+}
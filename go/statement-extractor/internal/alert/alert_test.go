@@ -0,0 +1,125 @@
+package alert
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func tx(date, description string, amount float64, category string) transaction.Transaction {
+	d, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		panic(err)
+	}
+	return transaction.Transaction{Date: d, Description: description, Amount: transaction.FromFloat(amount), Category: category}
+}
+
+func TestEvaluate_Threshold(t *testing.T) {
+	rules, err := Compile([]config.AlertRule{
+		{Name: "Big night out", Category: "entertainment", MinAmount: 500},
+	})
+	require.NoError(t, err)
+
+	triggers := Evaluate(rules, []transaction.Transaction{
+		tx("2024-03-01", "TICKETMASTER", -650, "Entertainment"),
+		tx("2024-03-02", "NETFLIX", -20, "Entertainment"),
+		tx("2024-03-03", "HARVEY NORMAN", -900, "Retail shopping"),
+	})
+
+	require.Len(t, triggers, 1)
+	assert.Equal(t, "Big night out: 2024-03-01 TICKETMASTER -650.00", triggers[0].Message())
+}
+
+func TestEvaluate_Frequency(t *testing.T) {
+	rules, err := Compile([]config.AlertRule{
+		{Name: "ATM habit", Pattern: "atm withdrawal", Count: 3, Window: "week"},
+	})
+	require.NoError(t, err)
+
+	triggers := Evaluate(rules, []transaction.Transaction{
+		tx("2024-02-12", "ATM WITHDRAWAL", -50, ""),
+		tx("2024-02-13", "ATM WITHDRAWAL", -50, ""),
+		tx("2024-02-15", "ATM WITHDRAWAL", -20, ""),
+		tx("2024-02-18", "ATM WITHDRAWAL", -100, ""),
+		tx("2024-02-19", "ATM WITHDRAWAL", -50, ""),
+		tx("2024-02-20", "WOOLWORTHS", -80, ""),
+	})
+
+	require.Len(t, triggers, 1)
+	assert.Equal(t, "2024-W07", triggers[0].Window)
+	assert.Len(t, triggers[0].Transactions, 4)
+	assert.Equal(t, transaction.Amount(-22000), triggers[0].Total())
+	assert.Equal(t, "ATM habit: 4 transactions in 2024-W07 totalling -220.00", triggers[0].Message())
+}
+
+func TestEvaluate_MonthlyBudget(t *testing.T) {
+	rules, err := Compile([]config.AlertRule{
+		{Name: "Dining budget", Category: "Dining", MonthlyBudget: 200},
+	})
+	require.NoError(t, err)
+
+	triggers := Evaluate(rules, []transaction.Transaction{
+		tx("2024-03-20", "NANDOS", -90, "Dining"),
+		tx("2024-03-02", "GRILLD", -80, "Dining"),
+		tx("2024-03-10", "GRILLD REFUND", 30, "Dining"),
+		tx("2024-03-15", "SUSHI TRAIN", -70, "Dining"),
+		tx("2024-03-28", "PIZZA", -40, "Dining"),
+		tx("2024-04-01", "WOOLWORTHS", -500, "Groceries"),
+		tx("2024-04-03", "BANQUET", -250, "Dining"),
+	})
+
+	require.Len(t, triggers, 2, "each month triggers once")
+	assert.Equal(t, "2024-03", triggers[0].Window)
+	assert.Equal(t, transaction.Amount(21000), triggers[0].Spent, "refunds reduce the spending")
+	assert.Equal(t, "Dining budget: 2024-03-20 NANDOS -90.00 took 2024-03 spending to 210.00 of 200.00", triggers[0].Message())
+	assert.Equal(t, "BANQUET", triggers[1].Transactions[0].Description)
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	_, err := Compile([]config.AlertRule{{Name: "bad", Pattern: "("}})
+	assert.ErrorContains(t, err, `alert "bad": invalid pattern`)
+
+	_, err = Compile([]config.AlertRule{{Name: "bad", Count: 1, Window: "fortnight"}})
+	assert.ErrorContains(t, err, "invalid window")
+
+	_, err = Compile([]config.AlertRule{{Name: "bad", Count: 1, MonthlyBudget: 100}})
+	assert.ErrorContains(t, err, "cannot be combined")
+
+	_, err = Compile([]config.AlertRule{{Name: "bad", Category: "Dining", Rollover: true}})
+	assert.ErrorContains(t, err, "rollover needs monthly_budget")
+
+	rules, err := Compile([]config.AlertRule{{MinAmount: 1}})
+	require.NoError(t, err)
+	assert.Equal(t, "alert 1", rules[0].Name)
+}
+
+func TestAdded(t *testing.T) {
+	rules, err := Compile([]config.AlertRule{
+		{Name: "ATM habit", Pattern: "atm withdrawal", Count: 1, Window: "week"},
+		{Name: "Big night out", Category: "entertainment", MinAmount: 500},
+	})
+	require.NoError(t, err)
+
+	stored := []transaction.Transaction{
+		tx("2024-02-12", "ATM WITHDRAWAL", -50, ""),
+		tx("2024-02-13", "ATM WITHDRAWAL", -50, ""),
+		tx("2024-02-14", "TICKETMASTER", -650, "Entertainment"),
+	}
+	imported := append(slices.Clone(stored), tx("2024-02-15", "ATM WITHDRAWAL", -20, ""), tx("2024-02-16", "WOOLWORTHS", -80, ""))
+
+	added := Added(Evaluate(rules, stored), Evaluate(rules, imported))
+	require.Len(t, added, 1, "the ticket was already stored")
+	assert.Equal(t, "ATM habit: 3 transactions in 2024-W07 totalling -120.00", added[0].Message())
+	assert.Empty(t, Added(Evaluate(rules, imported), Evaluate(rules, imported)))
+
+	n := added[0].Notification()
+	assert.Equal(t, "2024-W07", n.Window)
+	assert.Len(t, n.Transactions, 3)
+	assert.Nil(t, n.Budget)
+}
@@ -0,0 +1,215 @@
+// Package api serves the extraction pipeline over a small REST API, for
+// running the extractor as a self-hosted service: statements are POSTed to
+// /extract, queued as jobs, and come back as categorized transactions from
+// /jobs/{id}.
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/example/statement-extractor/internal/clock"
+	"github.com/example/statement-extractor/internal/jobs"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+const (
+	// maxStatementSize bounds an uploaded statement
+	maxStatementSize = 32 << 20
+
+	// maxRequestSize bounds the JSON body of a request
+	maxRequestSize = 8 << 20
+
+	// retryAfter is the Retry-After, in seconds, of an upload refused as the
+	// queue is full
+	retryAfter = "30"
+)
+
+// OpenAPI is the OpenAPI document describing the routes
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
+// Service does the work behind the routes
+type Service interface {
+	// Extract extracts and categorizes the statement document, uploaded as
+	// name, with the parser bank, or the one detected when bank is empty
+	Extract(ctx context.Context, name string, document []byte, bank string) (transaction.TransactionList, error)
+
+	// Categorize categorizes txns in place
+	Categorize(ctx context.Context, txns []transaction.Transaction) error
+
+	// Categories returns the categories transactions can be assigned
+	Categories() []string
+}
+
+// Statement is an uploaded statement, the payload of the job extracting it
+// that remote workers lease
+type Statement struct {
+	Name     string `json:"name"`
+	Bank     string `json:"bank,omitempty"`
+	Document []byte `json:"document"`
+}
+
+// Error is the body of a failed request
+type Error struct {
+	Error string `json:"error"`
+}
+
+// Handler serves svc, extracting statements on queue:
+//
+//	POST /extract      a statement, as the body or the "file" field of a form
+//	GET  /jobs/{id}    the job extracting a statement
+//	GET  /categories   the categories transactions can be assigned
+//	POST /categorize   a TransactionList, or an array of transactions
+//	GET  /openapi.json the OpenAPI document
+//
+// /extract takes the parser from the bank parameter, and the statement's
+// name from name or the form's file name. It answers 202 Accepted with the
+// queued job, whose result is a TransactionList once it has succeeded, or 429
+// Too Many Requests when the queue is full. /categorize answers with a
+// TransactionList.
+func Handler(svc Service, queue *jobs.Queue) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /extract", func(w http.ResponseWriter, r *http.Request) {
+		name, document, err := readStatement(w, r)
+		if err != nil {
+			writeError(w, requestStatus(err), err)
+			return
+		}
+		bank := r.URL.Query().Get("bank")
+		job, err := queue.Submit(func(ctx context.Context) (any, error) {
+			return svc.Extract(ctx, name, document, bank)
+		}, jobs.WithPayload(Statement{Name: name, Bank: bank, Document: document}))
+		switch {
+		case errors.Is(err, jobs.ErrQueueFull):
+			w.Header().Set("Retry-After", retryAfter)
+			writeError(w, http.StatusTooManyRequests, err)
+			return
+		case err != nil:
+			writeError(w, http.StatusServiceUnavailable, err)
+			return
+		}
+		w.Header().Set("Location", "/jobs/"+job.ID)
+		writeJSON(w, http.StatusAccepted, job)
+	})
+	mux.Handle("GET /jobs/{id}", queue.Handler())
+	mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(OpenAPI)
+	})
+	mux.HandleFunc("GET /categories", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string][]string{"categories": svc.Categories()})
+	})
+	mux.HandleFunc("POST /categorize", func(w http.ResponseWriter, r *http.Request) {
+		list, err := readTransactions(w, r)
+		if err != nil {
+			writeError(w, requestStatus(err), err)
+			return
+		}
+		if err := svc.Categorize(r.Context(), list.Transactions); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		list.ProcessedAt = clock.From(r.Context()).Now().UTC()
+		writeJSON(w, http.StatusOK, list)
+	})
+	return mux
+}
+
+// RequireToken refuses requests to next without the bearer token in their
+// Authorization header
+func RequireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="statement-extractor"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// readStatement reads the uploaded statement and its name
+func readStatement(w http.ResponseWriter, r *http.Request) (string, []byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxStatementSize)
+	name := r.URL.Query().Get("name")
+	var body io.Reader = r.Body
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid form: %w", err)
+		}
+		defer file.Close()
+		if name == "" {
+			name = header.Filename
+		}
+		body = file
+	}
+	document, err := io.ReadAll(body)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read statement: %w", err)
+	}
+	if len(document) == 0 {
+		return "", nil, errors.New("no statement in the request")
+	}
+	// Only the base name is kept, as it ends up in the transactions
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || name == "/" {
+		name = "statement.pdf"
+	}
+	return name, document, nil
+}
+
+// readTransactions reads a TransactionList, or an array of transactions
+func readTransactions(w http.ResponseWriter, r *http.Request) (transaction.TransactionList, error) {
+	var list transaction.TransactionList
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err != nil {
+		return list, fmt.Errorf("failed to read request: %w", err)
+	}
+	body = bytes.TrimSpace(body)
+	if bytes.HasPrefix(body, []byte("[")) {
+		err = json.Unmarshal(body, &list.Transactions)
+	} else {
+		err = json.Unmarshal(body, &list)
+	}
+	if err != nil {
+		return list, fmt.Errorf("invalid transactions: %w", err)
+	}
+	if list.Transactions == nil {
+		list.Transactions = []transaction.Transaction{}
+	}
+	list.Total = len(list.Transactions)
+	return list, nil
+}
+
+// requestStatus is the status of a request that could not be read
+func requestStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, Error{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
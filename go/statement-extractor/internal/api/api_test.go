@@ -0,0 +1,225 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/jobs"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// fakeService extracts one transaction described by the document and
+// categorizes everything as Groceries. Extraction waits for release when it
+// is set.
+type fakeService struct {
+	mu         sync.Mutex
+	name, bank string
+	release    chan struct{}
+}
+
+func (s *fakeService) Extract(_ context.Context, name string, document []byte, bank string) (transaction.TransactionList, error) {
+	if s.release != nil {
+		<-s.release
+	}
+	s.mu.Lock()
+	s.name, s.bank = name, bank
+	s.mu.Unlock()
+	if string(document) == "broken" {
+		return transaction.TransactionList{}, errors.New("no parser recognized the statement")
+	}
+	list := transaction.TransactionList{Source: name}
+	list.AddTransaction(transaction.Transaction{Description: string(document), Amount: -450, Category: "Groceries"})
+	return list, nil
+}
+
+func (s *fakeService) Categorize(_ context.Context, txns []transaction.Transaction) error {
+	for i := range txns {
+		txns[i].Category = "Groceries"
+	}
+	return nil
+}
+
+func (s *fakeService) Categories() []string {
+	return []string{"Groceries", "Uncategorized"}
+}
+
+func do(t *testing.T, h http.Handler, method, target, contentType string, body io.Reader) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, body)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &decoded), rec.Body.String())
+	return rec, decoded
+}
+
+// newQueue returns a queue of one worker that is shut down with the test
+func newQueue(t *testing.T, capacity int) *jobs.Queue {
+	q := jobs.NewQueue(jobs.Options{Workers: 1, Capacity: capacity})
+	t.Cleanup(func() { _ = q.Shutdown(context.Background()) })
+	return q
+}
+
+// finished polls the job named by an /extract response until it finishes
+func finished(t *testing.T, h http.Handler, rec *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	var job map[string]any
+	require.Eventually(t, func() bool {
+		_, job = do(t, h, http.MethodGet, rec.Header().Get("Location"), "", nil)
+		return job["status"] == "succeeded" || job["status"] == "failed"
+	}, 2*time.Second, 5*time.Millisecond)
+	return job
+}
+
+func TestExtract(t *testing.T) {
+	svc := &fakeService{}
+	h := Handler(svc, newQueue(t, 4))
+
+	rec, body := do(t, h, http.MethodPost, "/extract?bank=cba&name=jun.pdf", "application/pdf", strings.NewReader("COLES"))
+	assert.Equal(t, "queued", body["status"])
+	assert.Equal(t, "/jobs/"+body["id"].(string), rec.Header().Get("Location"))
+	job := finished(t, h, rec)
+	assert.Equal(t, "succeeded", job["status"])
+	assert.Equal(t, float64(1), job["result"].(map[string]any)["total"])
+	assert.Equal(t, "jun.pdf", svc.name)
+	assert.Equal(t, "cba", svc.bank)
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	fw, err := mw.CreateFormFile("file", `C:\Statements\jul.pdf`)
+	require.NoError(t, err)
+	_, _ = fw.Write([]byte("WOOLWORTHS"))
+	require.NoError(t, mw.Close())
+	rec, _ = do(t, h, http.MethodPost, "/extract", mw.FormDataContentType(), &form)
+	finished(t, h, rec)
+	assert.Equal(t, "jul.pdf", svc.name, "only the base name of an upload is kept")
+
+	rec, _ = do(t, h, http.MethodPost, "/extract", "", strings.NewReader("broken"))
+	job = finished(t, h, rec)
+	assert.Equal(t, "failed", job["status"])
+	assert.Equal(t, "no parser recognized the statement", job["error"])
+
+	rec, _ = do(t, h, http.MethodGet, "/jobs/missing", "", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec, _ = do(t, h, http.MethodPost, "/extract", "", strings.NewReader(""))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec, _ = do(t, h, http.MethodPost, "/extract", "", bytes.NewReader(make([]byte, maxStatementSize+1)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
+
+func TestExtractQueueFull(t *testing.T) {
+	svc := &fakeService{release: make(chan struct{})}
+	h := Handler(svc, newQueue(t, 1))
+
+	running, _ := do(t, h, http.MethodPost, "/extract", "", strings.NewReader("COLES"))
+	require.Equal(t, http.StatusAccepted, running.Code)
+	require.Eventually(t, func() bool {
+		_, job := do(t, h, http.MethodGet, running.Header().Get("Location"), "", nil)
+		return job["status"] == "running"
+	}, 2*time.Second, 5*time.Millisecond)
+	queued, body := do(t, h, http.MethodPost, "/extract", "", strings.NewReader("ALDI"))
+	require.Equal(t, http.StatusAccepted, queued.Code)
+	assert.Equal(t, float64(1), body["position"])
+
+	rec, body := do(t, h, http.MethodPost, "/extract", "", strings.NewReader("IGA"))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "job queue is full", body["error"])
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+
+	close(svc.release)
+	finished(t, h, running)
+	finished(t, h, queued)
+}
+
+func TestCategorize(t *testing.T) {
+	h := Handler(&fakeService{}, newQueue(t, 1))
+	for _, body := range []string{
+		`[{"date": "2024-06-02T00:00:00Z", "description": "COLES", "amount": -45.5}]`,
+		`{"transactions": [{"date": "2024-06-02T00:00:00Z", "description": "COLES", "amount": -45.5}]}`,
+	} {
+		rec, decoded := do(t, h, http.MethodPost, "/categorize", "application/json", strings.NewReader(body))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, float64(1), decoded["total"])
+		txn := decoded["transactions"].([]any)[0].(map[string]any)
+		assert.Equal(t, "Groceries", txn["category"])
+		assert.Equal(t, -45.5, txn["amount"])
+	}
+
+	rec, _ := do(t, h, http.MethodPost, "/categorize", "application/json", strings.NewReader(`{"transactions": 1}`))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec, decoded := do(t, h, http.MethodGet, "/categories", "", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []any{"Groceries", "Uncategorized"}, decoded["categories"])
+}
+
+func TestRequireToken(t *testing.T) {
+	h := RequireToken("s3cret", Handler(&fakeService{}, newQueue(t, 1)))
+	for _, header := range []string{"", "Bearer wrong", "s3cret", "Basic s3cret"} {
+		req := httptest.NewRequest(http.MethodGet, "/categories", nil)
+		req.Header.Set("Authorization", header)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, header)
+		assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/categories", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestOpenAPI(t *testing.T) {
+	h := Handler(&fakeService{}, newQueue(t, 1))
+	rec, spec := do(t, h, http.MethodGet, "/openapi.json", "", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Every route is described
+	paths := spec["paths"].(map[string]any)
+	for _, route := range []string{"POST /extract", "GET /jobs/{id}", "GET /categories", "POST /categorize", "GET /openapi.json"} {
+		method, path, _ := strings.Cut(route, " ")
+		require.Contains(t, paths, path)
+		assert.Contains(t, paths[path], strings.ToLower(method), route)
+	}
+
+	// and every field of the transactions
+	schemas := spec["components"].(map[string]any)["schemas"].(map[string]any)
+	for name, v := range map[string]any{
+		"Transaction":     transaction.Transaction{},
+		"TransactionList": transaction.TransactionList{},
+		"StatementInfo":   transaction.StatementInfo{},
+		"StatementRef":    transaction.StatementRef{},
+		"Split":           transaction.Split{},
+		"Recurrence":      transaction.Recurrence{},
+		"Job":             jobs.Job{},
+	} {
+		properties := schemas[name].(map[string]any)["properties"].(map[string]any)
+		typ := reflect.TypeOf(v)
+		for i := range typ.NumField() {
+			field, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			if field != "" && field != "-" {
+				assert.Contains(t, properties, field, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Package archive expands statement bundles, such as the ZIP files banks
+// deliver multiple statements in or exported email with statement
+// attachments, keeping track of where each statement came from.
+package archive
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Extensions are the statement file types taken from archives
+var Extensions = []string{".pdf", ".csv"}
+
+// maxEntrySize guards against decompression bombs
+var maxEntrySize int64 = 256 << 20
+
+// File is a statement to process
+type File struct {
+	Path   string // location on disk
+	Source string // for attribution, e.g. "2024.zip/jan.pdf"; the base name for plain files
+}
+
+// IsArchive reports whether path is an archive or email file to expand
+func IsArchive(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".zip", ".7z", ".eml", ".mbox":
+		return true
+	}
+	return false
+}
+
+// Inputs resolves input paths to statement files. Archives are expanded
+// into dir, which the caller removes when done; other files are returned
+// unchanged.
+func Inputs(paths []string, dir string) ([]File, error) {
+	var files []File
+	for _, path := range paths {
+		if !IsArchive(path) {
+			files = append(files, File{Path: path, Source: filepath.Base(path)})
+			continue
+		}
+		expanded, err := Expand(path, dir)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, expanded...)
+	}
+	return files, nil
+}
+
+// Dir finds the statements in root, and in its subdirectories when
+// recursive, in name order. Archives found there are expanded into dir as
+// by Inputs. Sources are relative to root, so that statements of the same
+// name in different folders stay apart; hidden files and directories are
+// skipped.
+func Dir(root string, recursive bool, dir string) ([]File, error) {
+	var files []File
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		hidden := strings.HasPrefix(d.Name(), ".")
+		if d.IsDir() {
+			if hidden || !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if hidden || !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		switch {
+		case IsArchive(path):
+			expanded, err := Expand(path, dir)
+			if err != nil {
+				return err
+			}
+			// Expand names the archive by its base name
+			prefix := strings.TrimSuffix(rel, d.Name())
+			for _, f := range expanded {
+				files = append(files, File{Path: f.Path, Source: prefix + f.Source})
+			}
+		case wanted(rel):
+			files = append(files, File{Path: path, Source: rel})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read statements in %s: %w", root, err)
+	}
+	return files, nil
+}
+
+// Expand extracts the statements in an archive, or the statement
+// attachments of an email file, into a new directory under dir. Entries with
+// other extensions, including nested archives, are skipped.
+func Expand(path, dir string) ([]File, error) {
+	target, err := os.MkdirTemp(dir, filepath.Base(path)+"-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create extraction directory: %w", err)
+	}
+
+	var files []File
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".zip":
+		files, err = expandZip(path, target)
+	case ".7z":
+		files, err = expand7z(path, target)
+	case ".eml":
+		files, err = expandEML(path, target)
+	case ".mbox":
+		files, err = expandMbox(path, target)
+	default:
+		err = fmt.Errorf("unsupported archive type %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return files, nil
+}
+
+// cleanName turns an untrusted entry or attachment name into a relative
+// slash-separated path that stays inside the archive, as outputs are named
+// after it
+func cleanName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, `\`, "/")), "/")
+}
+
+func wanted(name string) bool {
+	base := filepath.Base(name)
+	if strings.HasPrefix(base, ".") || strings.HasPrefix(name, "__MACOSX/") {
+		return false
+	}
+	return slices.Contains(Extensions, strings.ToLower(filepath.Ext(name)))
+}
+
+func expandZip(path, target string) ([]File, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var files []File
+	for i, entry := range r.File {
+		if entry.FileInfo().IsDir() || !wanted(entry.Name) {
+			continue
+		}
+		// Entry names are untrusted: write under a generated name rather than
+		// joining the archive path onto target
+		out := filepath.Join(target, fmt.Sprintf("%03d-%s", i, filepath.Base(filepath.FromSlash(entry.Name))))
+		if err := extractEntry(entry, out); err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name, err)
+		}
+		files = append(files, File{Path: out, Source: filepath.Base(path) + "/" + cleanName(entry.Name)})
+	}
+	return files, nil
+}
+
+func extractEntry(entry *zip.File, out string) error {
+	in, err := entry.Open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f, io.LimitReader(in, maxEntrySize+1))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if n > maxEntrySize {
+		return fmt.Errorf("larger than %d MiB", maxEntrySize>>20)
+	}
+	return nil
+}
+
+// sevenZip are the 7-Zip executables tried in order; Go has no 7z reader
+var sevenZip = []string{"7zz", "7z", "7za"}
+
+func expand7z(path, target string) ([]File, error) {
+	var bin string
+	for _, name := range sevenZip {
+		if p, err := exec.LookPath(name); err == nil {
+			bin = p
+			break
+		}
+	}
+	if bin == "" {
+		return nil, errors.New("7z archives need the 7-Zip command (7zz, 7z or 7za) on PATH")
+	}
+
+	cmd := exec.Command(bin, "x", "-y", "-bd", "-o"+target, path)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("7-Zip failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	var files []File
+	err := filepath.WalkDir(target, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(target, p)
+		if err != nil {
+			return err
+		}
+		// Symlinks could point outside target
+		if d.Type().IsRegular() && wanted(filepath.ToSlash(rel)) {
+			files = append(files, File{Path: p, Source: filepath.Base(path) + "/" + filepath.ToSlash(rel)})
+		}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,131 @@
+package archive
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeZip(t *testing.T, path string, entries map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	w := zip.NewWriter(f)
+	for name, content := range entries {
+		fw, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = fw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+}
+
+func TestInputs(t *testing.T) {
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "2024.zip")
+	writeZip(t, zipPath, map[string]string{
+		"jan/statement.pdf":     "%PDF-jan",
+		"feb/statement.PDF":     "%PDF-feb",
+		"export.csv":            "Date,Amount",
+		"readme.txt":            "skipped",
+		"__MACOSX/._export.csv": "skipped",
+		"../../escape.pdf":      "%PDF-escape",
+	})
+
+	work := filepath.Join(dir, "work")
+	require.NoError(t, os.Mkdir(work, 0o755))
+	files, err := Inputs([]string{filepath.Join(dir, "plain.pdf"), zipPath}, work)
+	require.NoError(t, err)
+
+	sources := make(map[string]string)
+	for _, f := range files {
+		if f.Source == "plain.pdf" {
+			assert.Equal(t, filepath.Join(dir, "plain.pdf"), f.Path, "plain files are used in place")
+			continue
+		}
+		assert.True(t, strings.HasPrefix(f.Path, work), "%s extracted outside the work directory", f.Path)
+		data, err := os.ReadFile(f.Path)
+		require.NoError(t, err)
+		sources[f.Source] = string(data)
+	}
+	assert.Equal(t, map[string]string{
+		"2024.zip/jan/statement.pdf": "%PDF-jan",
+		"2024.zip/feb/statement.PDF": "%PDF-feb",
+		"2024.zip/export.csv":        "Date,Amount",
+		"2024.zip/escape.pdf":        "%PDF-escape",
+	}, sources)
+	_, err = os.Stat(filepath.Join(dir, "escape.pdf"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDir(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"jan.pdf", "notes.txt", ".hidden.pdf", "2023/jan.pdf", "2023/export.CSV", ".git/x.pdf"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte("%PDF-"+name), 0o644))
+	}
+	writeZip(t, filepath.Join(root, "2023", "q4.zip"), map[string]string{"dec.pdf": "%PDF-dec"})
+
+	sources := func(files []File) []string {
+		var names []string
+		for _, f := range files {
+			names = append(names, f.Source)
+		}
+		return names
+	}
+	work := t.TempDir()
+	files, err := Dir(root, false, work)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"jan.pdf"}, sources(files))
+	assert.Equal(t, filepath.Join(root, "jan.pdf"), files[0].Path)
+
+	files, err = Dir(root, true, work)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2023/export.CSV", "2023/jan.pdf", "2023/q4.zip/dec.pdf", "jan.pdf"}, sources(files))
+
+	_, err = Dir(filepath.Join(root, "missing"), true, work)
+	assert.ErrorContains(t, err, "failed to read statements")
+}
+
+func TestExpand_TooLarge(t *testing.T) {
+	defer func(size int64) { maxEntrySize = size }(maxEntrySize)
+	maxEntrySize = 4
+
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "big.zip")
+	writeZip(t, zipPath, map[string]string{"big.pdf": "0123456789"})
+
+	_, err := Expand(zipPath, dir)
+	assert.ErrorContains(t, err, "big.pdf: larger than")
+}
+
+func TestExpand_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "broken.zip")
+	require.NoError(t, os.WriteFile(path, []byte("not a zip"), 0o644))
+
+	_, err := Expand(path, dir)
+	assert.ErrorContains(t, err, "broken.zip")
+	assert.True(t, IsArchive("A.ZIP"))
+	assert.True(t, IsArchive("a.7z"))
+	assert.False(t, IsArchive("a.pdf"))
+}
+
+func TestCleanName(t *testing.T) {
+	for name, want := range map[string]string{
+		"jan/statement.pdf":       "jan/statement.pdf",
+		"../../escaped.csv":       "escaped.csv",
+		"/etc/statement.pdf":      "etc/statement.pdf",
+		`..\..\windows.pdf`:       "windows.pdf",
+		"jan/../../feb/./may.pdf": "feb/may.pdf",
+	} {
+		assert.Equal(t, want, cleanName(name), name)
+	}
+}
@@ -0,0 +1,197 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// expandEML saves the statement attachments of a single email
+func expandEML(path, target string) ([]File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return saveAttachments(data, filepath.Base(path), target, 0)
+}
+
+// expandMbox saves the statement attachments of every message in an mbox
+func expandMbox(path, target string) ([]File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var files []File
+	n := 0
+	err = splitMbox(f, func(message []byte) error {
+		n++
+		saved, err := saveAttachments(message, fmt.Sprintf("%s/%d", filepath.Base(path), n), target, n)
+		if err != nil {
+			return fmt.Errorf("message %d: %w", n, err)
+		}
+		files = append(files, saved...)
+		return nil
+	})
+	return files, err
+}
+
+// splitMbox calls fn with each message in r. Messages start with a "From "
+// line at the beginning of the file or after a blank line.
+func splitMbox(r io.Reader, fn func(message []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 64<<20)
+
+	var message bytes.Buffer
+	started, blank := false, true
+	flush := func() error {
+		if !started {
+			return nil
+		}
+		return fn(bytes.Clone(message.Bytes()))
+	}
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if blank && bytes.HasPrefix(line, []byte("From ")) {
+			if err := flush(); err != nil {
+				return err
+			}
+			message.Reset()
+			started, blank = true, false
+			continue
+		}
+		blank = len(bytes.TrimRight(line, "\r")) == 0
+		if !started {
+			continue
+		}
+		// mboxrd escapes body lines starting with "From " as ">From "
+		if bytes.HasPrefix(line, []byte(">")) && bytes.HasPrefix(bytes.TrimLeft(line, ">"), []byte("From ")) {
+			line = line[1:]
+		}
+		message.Write(line)
+		message.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// saveAttachments writes a message's statement attachments into target
+func saveAttachments(data []byte, source, target string, message int) ([]File, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("reading message: %w", err)
+	}
+
+	var files []File
+	var walk func(header textproto.MIMEHeader, body io.Reader) error
+	walk = func(header textproto.MIMEHeader, body io.Reader) error {
+		mediaType, params, _ := mime.ParseMediaType(header.Get("Content-Type"))
+		if strings.HasPrefix(mediaType, "multipart/") {
+			mr := multipart.NewReader(body, params["boundary"])
+			for {
+				part, err := mr.NextRawPart()
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				if err := walk(part.Header, part); err != nil {
+					return err
+				}
+			}
+		}
+
+		name := attachmentName(header, mediaType, params, len(files)+1)
+		if name == "" || !wanted(name) {
+			return nil
+		}
+		out := filepath.Join(target, fmt.Sprintf("%03d-%03d-%s", message, len(files)+1, filepath.Base(name)))
+		if err := saveBody(header.Get("Content-Transfer-Encoding"), body, out); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		files = append(files, File{Path: out, Source: source + "/" + cleanName(name)})
+		return nil
+	}
+
+	if err := walk(textproto.MIMEHeader(msg.Header), msg.Body); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// attachmentName returns the part's file name, or a generated one for an
+// unnamed PDF. Non-attachments return "".
+func attachmentName(header textproto.MIMEHeader, mediaType string, params map[string]string, n int) string {
+	decoder := new(mime.WordDecoder)
+	if _, dparams, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil && dparams["filename"] != "" {
+		if name, err := decoder.DecodeHeader(dparams["filename"]); err == nil {
+			return name
+		}
+		return dparams["filename"]
+	}
+	if params["name"] != "" {
+		if name, err := decoder.DecodeHeader(params["name"]); err == nil {
+			return name
+		}
+		return params["name"]
+	}
+	if mediaType == "application/pdf" {
+		return fmt.Sprintf("attachment-%d.pdf", n)
+	}
+	return ""
+}
+
+func saveBody(encoding string, body io.Reader, out string) error {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, newlineStripper{body})
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f, io.LimitReader(body, maxEntrySize+1))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if n > maxEntrySize {
+		return fmt.Errorf("larger than %d MiB", maxEntrySize>>20)
+	}
+	return nil
+}
+
+// newlineStripper drops line breaks, which base64.NewDecoder only tolerates
+// between complete quanta
+type newlineStripper struct{ r io.Reader }
+
+func (s newlineStripper) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	kept := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' && b != ' ' && b != '\t' {
+			p[kept] = b
+			kept++
+		}
+	}
+	return kept, err
+}
@@ -0,0 +1,100 @@
+package archive
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// message builds a multipart email with a text body and the given PDF
+// attachments, base64-encoded with line wrapping as mail clients do
+func message(subject string, attachments map[string]string) string {
+	var b strings.Builder
+	b.WriteString("From: bank@example.com\r\nSubject: " + subject + "\r\nMIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: multipart/mixed; boundary=\"outer\"\r\n\r\n")
+	b.WriteString("--outer\r\nContent-Type: multipart/alternative; boundary=\"inner\"\r\n\r\n")
+	b.WriteString("--inner\r\nContent-Type: text/plain\r\n\r\nYour statement is attached.\r\n--inner--\r\n")
+	for name, content := range attachments {
+		encoded := base64.StdEncoding.EncodeToString([]byte(content))
+		var wrapped []string
+		for len(encoded) > 8 {
+			wrapped, encoded = append(wrapped, encoded[:8]), encoded[8:]
+		}
+		wrapped = append(wrapped, encoded)
+
+		b.WriteString("--outer\r\nContent-Type: application/pdf\r\nContent-Transfer-Encoding: base64\r\n")
+		if name != "" {
+			b.WriteString("Content-Disposition: attachment; filename=\"" + name + "\"\r\n")
+		}
+		b.WriteString("\r\n" + strings.Join(wrapped, "\r\n") + "\r\n")
+	}
+	b.WriteString("--outer--\r\n")
+	return b.String()
+}
+
+func contents(t *testing.T, files []File) map[string]string {
+	t.Helper()
+	got := make(map[string]string)
+	for _, f := range files {
+		data, err := os.ReadFile(f.Path)
+		require.NoError(t, err)
+		got[f.Source] = string(data)
+	}
+	return got
+}
+
+func TestExpand_EML(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "statement.eml")
+	content := message("March statement", map[string]string{
+		"=?utf-8?q?M=C3=A4rz.pdf?=": "%PDF-1.7 march",
+		"":                          "%PDF-1.7 unnamed",
+	})
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	files, err := Expand(path, dir)
+	require.NoError(t, err)
+	got := contents(t, files)
+	assert.Equal(t, "%PDF-1.7 march", got["statement.eml/März.pdf"])
+	assert.Len(t, got, 2)
+	for source := range got {
+		assert.True(t, strings.HasPrefix(source, "statement.eml/"), source)
+	}
+}
+
+func TestExpand_Mbox(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Bank.mbox")
+	mbox := "From bank@example.com Mon Jan  1 00:00:00 2024\n" +
+		strings.ReplaceAll(message("Jan", map[string]string{"jan.pdf": "%PDF jan"}), "\r\n", "\n") +
+		"\nFrom bank@example.com Thu Feb  1 00:00:00 2024\n" +
+		"From: bank@example.com\nSubject: no attachment\n\n>From the bank: nothing this month\n" +
+		"\nFrom bank@example.com Fri Mar  1 00:00:00 2024\n" +
+		message("Mar", map[string]string{"mar.pdf": "%PDF mar"})
+	require.NoError(t, os.WriteFile(path, []byte(mbox), 0o644))
+
+	files, err := Expand(path, dir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"Bank.mbox/1/jan.pdf": "%PDF jan",
+		"Bank.mbox/3/mar.pdf": "%PDF mar",
+	}, contents(t, files))
+}
+
+func TestSplitMbox(t *testing.T) {
+	input := "From a\nSubject: 1\n\nbody\nFrom here on, not a separator\n>From escaped\n\nFrom b\nSubject: 2\n\nbody\n"
+	var messages []string
+	require.NoError(t, splitMbox(strings.NewReader(input), func(m []byte) error {
+		messages = append(messages, string(m))
+		return nil
+	}))
+	assert.Equal(t, []string{
+		"Subject: 1\n\nbody\nFrom here on, not a separator\nFrom escaped\n\n",
+		"Subject: 2\n\nbody\n",
+	}, messages)
+}
@@ -0,0 +1,55 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FreeName returns the path of name in dir, with a -2, -3... suffix before
+// the extension when a file of that name is already there
+func FreeName(dir, name string) string {
+	ext := filepath.Ext(name)
+	target := filepath.Join(dir, name)
+	for n := 2; exists(target); n++ {
+		target = filepath.Join(dir, fmt.Sprintf("%s-%d%s", strings.TrimSuffix(name, ext), n, ext))
+	}
+	return target
+}
+
+// Move renames from to to, copying when they are on different file
+// systems. An existing file at to is not replaced.
+func Move(from, to string) error {
+	if exists(to) {
+		return fmt.Errorf("%s already exists", to)
+	}
+	if err := os.Rename(from, to); err == nil {
+		return nil
+	}
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(to)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(to)
+		return err
+	}
+	return os.Remove(from)
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
@@ -0,0 +1,134 @@
+// Package batch runs over many statement files, a few at a time, and tracks
+// the outcome of each, so that a few bad statements are reported together at
+// the end instead of aborting the whole run.
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ErrPartial is returned when some statements of a run failed or were
+// skipped while others succeeded
+var ErrPartial = errors.New("some statements were not processed")
+
+// Status is the outcome of one statement
+type Status string
+
+const (
+	Succeeded Status = "succeeded"
+	Skipped   Status = "skipped" // not attempted, e.g. once the run budget ran out
+	Failed    Status = "failed"
+)
+
+// Result is the outcome of one statement
+type Result struct {
+	Source       string // as in archive.File
+	Status       Status
+	Parser       string // the parser used, when one was chosen
+	Transactions int    // extracted, for successes
+	Err          error  // the reason, for failures and skips
+}
+
+// Report collects the results of a run in processing order
+type Report struct {
+	Results []Result
+}
+
+// Succeed records a statement that was processed
+func (r *Report) Succeed(source, parser string, transactions int) {
+	r.Results = append(r.Results, Result{Source: source, Status: Succeeded, Parser: parser, Transactions: transactions})
+}
+
+// Fail records a statement that could not be processed
+func (r *Report) Fail(source, parser string, err error) {
+	r.Results = append(r.Results, Result{Source: source, Status: Failed, Parser: parser, Err: err})
+}
+
+// Skip records a statement that was not attempted
+func (r *Report) Skip(source string, reason error) {
+	r.Results = append(r.Results, Result{Source: source, Status: Skipped, Err: reason})
+}
+
+// Count returns the number of results with status
+func (r *Report) Count(status Status) int {
+	n := 0
+	for _, result := range r.Results {
+		if result.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Err returns nil when every statement succeeded, ErrPartial when some
+// did, and an error naming the first failure when none did
+func (r *Report) Err() error {
+	succeeded := r.Count(Succeeded)
+	switch {
+	case succeeded == len(r.Results):
+		return nil
+	case succeeded > 0:
+		return fmt.Errorf("%w: %d failed, %d skipped", ErrPartial, r.Count(Failed), r.Count(Skipped))
+	}
+	for _, result := range r.Results {
+		if result.Status == Failed {
+			return fmt.Errorf("%s: %w", result.Source, result.Err)
+		}
+	}
+	return fmt.Errorf("%s: %w", r.Results[0].Source, r.Results[0].Err)
+}
+
+// WriteSummary writes a count of each outcome and a line for each statement
+// that did not succeed
+func (r *Report) WriteSummary(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "%d statements: %d succeeded, %d skipped, %d failed\n",
+		len(r.Results), r.Count(Succeeded), r.Count(Skipped), r.Count(Failed)); err != nil {
+		return err
+	}
+	for _, result := range r.Results {
+		if result.Status == Succeeded {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "  %-7s %s: %v\n", result.Status, result.Source, result.Err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run calls fn with the index of each of n statements on up to workers
+// goroutines, starting them in order, then calls done with the index as it
+// finishes. Calls to done are serialized, so done can update shared state
+// such as a Report without locking. Once ctx is done no more statements are
+// started, while those already running finish.
+func Run(ctx context.Context, n, workers int, fn func(i int), done func(i int)) {
+	var mu sync.Mutex // guards next and done
+	next := 0
+	var wg sync.WaitGroup
+	for range max(1, min(workers, n)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				if next == n || ctx.Err() != nil {
+					mu.Unlock()
+					return
+				}
+				i := next
+				next++
+				mu.Unlock()
+
+				fn(i)
+				mu.Lock()
+				done(i)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
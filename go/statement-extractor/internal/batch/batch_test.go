@@ -0,0 +1,80 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport(t *testing.T) {
+	var r Report
+	assert.NoError(t, r.Err(), "an empty run did not fail")
+
+	r.Succeed("jan.pdf", "cba", 42)
+	assert.NoError(t, r.Err())
+
+	r.Fail("feb.pdf", "cba", errors.New("server error: 503"))
+	r.Skip("mar.pdf", errors.New("run budget exceeded"))
+	assert.Equal(t, 1, r.Count(Succeeded))
+	assert.Equal(t, 1, r.Count(Failed))
+	assert.Equal(t, 1, r.Count(Skipped))
+	err := r.Err()
+	assert.ErrorIs(t, err, ErrPartial)
+	assert.EqualError(t, err, "some statements were not processed: 1 failed, 1 skipped")
+
+	var b strings.Builder
+	require.NoError(t, r.WriteSummary(&b))
+	assert.Equal(t, "3 statements: 1 succeeded, 1 skipped, 1 failed\n"+
+		"  failed  feb.pdf: server error: 503\n"+
+		"  skipped mar.pdf: run budget exceeded\n", b.String())
+}
+
+func TestReportAllFailed(t *testing.T) {
+	var r Report
+	r.Skip("jan.pdf", errors.New("run budget exceeded"))
+	assert.EqualError(t, r.Err(), "jan.pdf: run budget exceeded")
+
+	r.Fail("feb.pdf", "", errors.New("no parser detected"))
+	err := r.Err()
+	assert.NotErrorIs(t, err, ErrPartial)
+	assert.EqualError(t, err, "feb.pdf: no parser detected", "failures are reported before skips")
+}
+
+func TestRun(t *testing.T) {
+	var running, peak atomic.Int32
+	var finished []int
+	Run(context.Background(), 20, 4, func(i int) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		running.Add(-1)
+	}, func(i int) {
+		finished = append(finished, i)
+	})
+	assert.Len(t, finished, 20)
+	assert.LessOrEqual(t, peak.Load(), int32(4))
+
+	// With one worker, stopping in done starts nothing more
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var started []int
+	Run(ctx, 5, 1, func(i int) {
+		started = append(started, i)
+	}, func(i int) {
+		if i == 1 {
+			cancel()
+		}
+	})
+	assert.Equal(t, []int{0, 1}, started)
+}
@@ -0,0 +1,111 @@
+// Package cache keeps PDF service responses, keyed by the request that
+// produced them, so a statement extracted once is not paid for again by
+// another run, worker or server instance sharing the cache.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Cache stores responses by key. Implementations must be safe for
+// concurrent use, including by other processes sharing the same store.
+type Cache interface {
+	// Get returns the response stored under key, and false if there is none
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+
+	// Put stores the response under key, replacing any stored before
+	Put(ctx context.Context, key string, value []byte) error
+}
+
+// Key returns the key for a request made of parts, such as the statement's
+// content hash and the provider, model and output asked for
+func Key(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Backends are the backends New can open
+var Backends = []string{"dir"}
+
+// New opens the named backend: "dir", also the default, is a Dir at dir.
+// Redis is refused with an error saying so, as no Redis client is built
+// in; a directory shared between machines serves the same purpose.
+func New(backend, dir string) (Cache, error) {
+	switch strings.ToLower(backend) {
+	case "", "dir":
+		return NewDir(dir), nil
+	case "redis":
+		return nil, errors.New(`cache backend "redis" is not supported: no Redis client is built in; use backend = "dir" with a dir shared between the machines`)
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q (available: %s)", backend, strings.Join(Backends, ", "))
+	}
+}
+
+// Dir is a Cache of one file per key in a directory, which can be shared
+// between processes on a machine or over a network filesystem
+type Dir struct {
+	path string
+}
+
+// NewDir returns a Cache in the directory at path, created on the first Put
+func NewDir(path string) *Dir {
+	return &Dir{path: path}
+}
+
+// Get returns the response stored under key, and false if there is none
+func (d *Dir) Get(_ context.Context, key string) ([]byte, bool, error) {
+	value, err := os.ReadFile(d.file(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read cached response: %w", err)
+	}
+	return value, true, nil
+}
+
+// Put stores the response under key, replacing any stored before
+func (d *Dir) Put(_ context.Context, key string, value []byte) error {
+	path := d.file(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	// Written aside and renamed, so a reader never sees half a response
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to cache response: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to cache response: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to cache response: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to cache response: %w", err)
+	}
+	return nil
+}
+
+// file returns the path of key's file. Keys are spread over subdirectories
+// by their first two characters, so no one directory grows too large.
+func (d *Dir) file(key string) string {
+	name := filepath.Base(filepath.Clean("/" + key))
+	if len(name) > 2 {
+		return filepath.Join(d.path, name[:2], name)
+	}
+	return filepath.Join(d.path, name)
+}
@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDir(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	c := NewDir(filepath.Join(root, "responses"))
+	key := Key("abc123", "gateway", "transactions")
+
+	_, ok, err := c.Get(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, c.Put(ctx, key, []byte(`{"text": "one"}`)))
+	require.NoError(t, c.Put(ctx, key, []byte(`{"text": "two"}`)))
+	value, ok, err := NewDir(filepath.Join(root, "responses")).Get(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, ok, "another instance sharing the directory sees the response")
+	assert.Equal(t, `{"text": "two"}`, string(value))
+
+	leftovers, _ := filepath.Glob(filepath.Join(root, "responses", "*", ".tmp-*"))
+	assert.Empty(t, leftovers)
+
+	require.NoError(t, c.Put(ctx, "../../escaped", []byte("x")))
+	_, err = os.Stat(filepath.Join(root, "escaped"))
+	assert.True(t, os.IsNotExist(err), "keys stay in the directory")
+}
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, Key("a", "b"), Key("a", "b"))
+	assert.NotEqual(t, Key("ab", ""), Key("a", "b"), "parts are delimited")
+	assert.Len(t, Key("a"), 64)
+}
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	for _, backend := range []string{"", "dir", "Dir"} {
+		c, err := New(backend, dir)
+		require.NoError(t, err, backend)
+		assert.Equal(t, NewDir(dir), c)
+	}
+
+	_, err := New("redis", dir)
+	assert.ErrorContains(t, err, `cache backend "redis" is not supported`)
+	_, err = New("memcached", dir)
+	assert.EqualError(t, err, `unknown cache backend "memcached" (available: dir)`)
+}
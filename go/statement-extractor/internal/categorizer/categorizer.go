@@ -0,0 +1,226 @@
+// Package categorizer assigns categories to transactions. Strategies such as
+// regex rules implement Categorizer and are combined into a Chain, so each
+// can be tested on its own and ordered by configuration.
+package categorizer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Result is a proposed category
+type Result struct {
+	Category   string
+	Confidence float64 // 0 to 1
+	Stage      string  // name of the categorizer that produced it
+	Rule       string  // ID of the category rule that matched, if any
+
+	ExcludeFromReports bool // mark the transaction as a report outlier
+	AmortizeMonths     int  // spread the amount over this many months in reports
+}
+
+// Categorizer proposes a category for a transaction. It returns false when
+// it has no opinion.
+type Categorizer interface {
+	Name() string
+	Categorize(ctx context.Context, t transaction.Transaction) (Result, bool, error)
+}
+
+// Batcher is a Categorizer that works best on many transactions at once,
+// such as one asking a model. The chain passes Prepare the transactions
+// reaching the stage before categorizing them one at a time.
+type Batcher interface {
+	Categorizer
+	Prepare(ctx context.Context, txns []transaction.Transaction) error
+}
+
+// Stage is a categorizer with the minimum confidence the chain accepts from it
+type Stage struct {
+	Categorizer Categorizer
+	Threshold   float64
+}
+
+// Chain tries stages in order and falls back to a default category
+type Chain struct {
+	stages   []Stage
+	fallback string
+}
+
+// DefaultStage is the Result stage for transactions no categorizer accepted
+const DefaultStage = transaction.CategorizedByDefault
+
+// NewChain creates a chain; fallback is assigned when no stage matches
+func NewChain(fallback string, stages ...Stage) *Chain {
+	return &Chain{stages: stages, fallback: fallback}
+}
+
+// Categorize returns the first result meeting its stage's threshold
+func (c *Chain) Categorize(ctx context.Context, t transaction.Transaction) (Result, error) {
+	for _, stage := range c.stages {
+		result, ok, err := stage.categorize(ctx, t)
+		if err != nil {
+			return Result{}, err
+		}
+		if ok {
+			return result, nil
+		}
+	}
+	return Result{Category: c.fallback, Stage: DefaultStage}, nil
+}
+
+// categorize returns the stage's result when it meets the threshold
+func (s Stage) categorize(ctx context.Context, t transaction.Transaction) (Result, bool, error) {
+	result, ok, err := s.Categorizer.Categorize(ctx, t)
+	if err != nil {
+		return Result{}, false, fmt.Errorf("%s categorizer: %w", s.Categorizer.Name(), err)
+	}
+	if !ok || result.Confidence < s.Threshold {
+		return Result{}, false, nil
+	}
+	result.Stage = s.Categorizer.Name()
+	return result, true, nil
+}
+
+// Apply categorizes every transaction that has no category yet, recording
+// the confidence and what categorized it. A category already set is marked
+// manual. Report flags from the result are added but never clear existing
+// ones. The transactions go through the stages together, so that a Batcher
+// is prepared once with all that reach it.
+func (c *Chain) Apply(ctx context.Context, txns []transaction.Transaction) error {
+	var pending []int
+	for i := range txns {
+		if txns[i].Category != "" {
+			if txns[i].CategorizedBy == "" {
+				txns[i].CategorizedBy = transaction.CategorizedByManual
+			}
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	for _, stage := range c.stages {
+		if len(pending) == 0 {
+			break
+		}
+		if b, ok := stage.Categorizer.(Batcher); ok {
+			batch := make([]transaction.Transaction, len(pending))
+			for k, i := range pending {
+				batch[k] = txns[i]
+			}
+			if err := b.Prepare(ctx, batch); err != nil {
+				return fmt.Errorf("%s categorizer: %w", b.Name(), err)
+			}
+		}
+		rest := pending[:0]
+		for _, i := range pending {
+			result, ok, err := stage.categorize(ctx, txns[i])
+			if err != nil {
+				return err
+			}
+			if !ok {
+				rest = append(rest, i)
+				continue
+			}
+			assign(&txns[i], result)
+		}
+		pending = rest
+	}
+	for _, i := range pending {
+		assign(&txns[i], Result{Category: c.fallback, Stage: DefaultStage})
+	}
+	return nil
+}
+
+// assign records result on t
+func assign(t *transaction.Transaction, result Result) {
+	t.Category = result.Category
+	t.CategoryConfidence = result.Confidence
+	t.CategorizedBy = result.Stage
+	if result.Rule != "" {
+		t.CategorizedBy = transaction.CategorizedByRule(result.Rule)
+	}
+	t.ExcludeFromReports = t.ExcludeFromReports || result.ExcludeFromReports
+	if t.AmortizeMonths == 0 {
+		t.AmortizeMonths = result.AmortizeMonths
+	}
+}
+
+// Lookup returns the categorizer of the stage named name, or nil
+func (c *Chain) Lookup(name string) Categorizer {
+	for _, stage := range c.stages {
+		if strings.EqualFold(stage.Categorizer.Name(), name) {
+			return stage.Categorizer
+		}
+	}
+	return nil
+}
+
+// builders constructs the named strategies available to the chain
+var builders = map[string]func(cfg *config.Config) (Categorizer, error){
+	"rules": func(cfg *config.Config) (Categorizer, error) {
+		return ConfiguredRules(cfg)
+	},
+	"merchants": func(cfg *config.Config) (Categorizer, error) {
+		return NewMerchants(cfg.Categorizer.Merchants)
+	},
+	"llm": func(cfg *config.Config) (Categorizer, error) {
+		return NewLLM(cfg, 0)
+	},
+	"embedding": func(cfg *config.Config) (Categorizer, error) {
+		return NewEmbedding(cfg)
+	},
+}
+
+// DefaultStages are the stages of a chain configured with none: the rules,
+// then the merchant table
+var DefaultStages = []config.CategorizerStage{{Name: "rules"}, {Name: "merchants"}}
+
+// ConfiguredRules compiles the [[categories]] rules with the configured
+// rule_match strategy
+func ConfiguredRules(cfg *config.Config) (*Rules, error) {
+	match, err := ParseMatchStrategy(cfg.Categorizer.RuleMatch)
+	if err != nil {
+		return nil, err
+	}
+	return NewRules(cfg.Categories, match)
+}
+
+// New builds the chain configured under [categorizer]. Without configured
+// stages the rules are used, then the merchant table.
+func New(cfg *config.Config) (*Chain, error) {
+	stages := cfg.Categorizer.Stages
+	if len(stages) == 0 {
+		stages = DefaultStages
+	}
+
+	chain := NewChain(cfg.DefaultCategory)
+	for _, s := range stages {
+		if s.Threshold < 0 || s.Threshold > 1 {
+			return nil, fmt.Errorf("categorizer %q: threshold must be between 0 and 1", s.Name)
+		}
+		build, ok := builders[strings.ToLower(s.Name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown categorizer %q (available: %s)", s.Name, strings.Join(available(), ", "))
+		}
+		c, err := build(cfg)
+		if err != nil {
+			return nil, err
+		}
+		chain.stages = append(chain.stages, Stage{Categorizer: c, Threshold: s.Threshold})
+	}
+	return chain, nil
+}
+
+func available() []string {
+	names := make([]string, 0, len(builders))
+	for name := range builders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
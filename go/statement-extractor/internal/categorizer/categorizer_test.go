@@ -0,0 +1,193 @@
+package categorizer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// fixed proposes the same category for everything
+type fixed struct {
+	name       string
+	category   string
+	confidence float64
+	err        error
+}
+
+func (f fixed) Name() string { return f.name }
+
+func (f fixed) Categorize(context.Context, transaction.Transaction) (Result, bool, error) {
+	if f.err != nil {
+		return Result{}, false, f.err
+	}
+	return Result{Category: f.category, Confidence: f.confidence}, f.category != "", nil
+}
+
+func TestChain(t *testing.T) {
+	ctx := context.Background()
+	txn := transaction.Transaction{Description: "ACME"}
+
+	chain := NewChain("Uncategorized",
+		Stage{Categorizer: fixed{name: "none"}},
+		Stage{Categorizer: fixed{name: "unsure", category: "Shopping", confidence: 0.4}, Threshold: 0.5},
+		Stage{Categorizer: fixed{name: "sure", category: "Groceries", confidence: 0.9}, Threshold: 0.5},
+	)
+	result, err := chain.Categorize(ctx, txn)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Category: "Groceries", Confidence: 0.9, Stage: "sure"}, result)
+
+	result, err = NewChain("Uncategorized").Categorize(ctx, txn)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Category: "Uncategorized", Stage: DefaultStage}, result)
+
+	_, err = NewChain("", Stage{Categorizer: fixed{name: "broken", err: errors.New("boom")}}).Categorize(ctx, txn)
+	assert.EqualError(t, err, "broken categorizer: boom")
+}
+
+func TestChainApply(t *testing.T) {
+	chain := NewChain("Uncategorized", Stage{Categorizer: fixed{name: "f", category: "Groceries", confidence: 1}})
+	txns := []transaction.Transaction{{Description: "A"}, {Description: "B", Category: "Manual"}}
+
+	require.NoError(t, chain.Apply(context.Background(), txns))
+	assert.Equal(t, "Groceries", txns[0].Category)
+	assert.Equal(t, "Manual", txns[1].Category, "existing categories are kept")
+}
+
+func TestNew(t *testing.T) {
+	cfg := &config.Config{
+		DefaultCategory: "Uncategorized",
+		Categories: []config.CategoryRule{
+			{Pattern: "woolworths|coles", Category: "Groceries"},
+			{Pattern: "SALARY", Category: "Income"},
+		},
+	}
+
+	chain, err := New(cfg)
+	require.NoError(t, err)
+	result, err := chain.Categorize(context.Background(), transaction.Transaction{Description: "COLES 0456 SYDNEY"})
+	require.NoError(t, err)
+	assert.Equal(t, Result{Category: "Groceries", Confidence: 1, Stage: "rules", Rule: "woolworths|coles"}, result)
+
+	cfg.Categories = append(cfg.Categories,
+		config.CategoryRule{Pattern: "DEPOSIT", Category: "Housing", ExcludeFromReports: true},
+		config.CategoryRule{Pattern: "NRMA", Category: "Insurance", AmortizeMonths: 12},
+	)
+	chain, err = New(cfg)
+	require.NoError(t, err)
+	txns := []transaction.Transaction{{Description: "HOUSE DEPOSIT"}, {Description: "SALARY"}, {Description: "NRMA CAR"}}
+	require.NoError(t, chain.Apply(context.Background(), txns))
+	assert.True(t, txns[0].ExcludeFromReports)
+	assert.False(t, txns[1].ExcludeFromReports)
+	assert.Equal(t, 12, txns[2].AmortizeMonths)
+	assert.Equal(t, "rule:SALARY", txns[1].CategorizedBy)
+	assert.Equal(t, 1.0, txns[1].CategoryConfidence)
+
+	txns = []transaction.Transaction{{Description: "ACME", Category: "Shopping"}, {Description: "UNKNOWN"}}
+	require.NoError(t, chain.Apply(context.Background(), txns))
+	assert.Equal(t, transaction.CategorizedByManual, txns[0].CategorizedBy)
+	assert.Equal(t, transaction.CategorizedByDefault, txns[1].CategorizedBy)
+
+	cfg.Categorizer.Stages = []config.CategorizerStage{{Name: "learned"}}
+	_, err = New(cfg)
+	assert.EqualError(t, err, `unknown categorizer "learned" (available: embedding, llm, merchants, rules)`)
+
+	cfg.Categorizer.Stages = []config.CategorizerStage{{Name: "rules", Threshold: 1.5}}
+	_, err = New(cfg)
+	assert.ErrorContains(t, err, "threshold must be between 0 and 1")
+
+	cfg.Categorizer.Stages = nil
+	cfg.Categories = []config.CategoryRule{{Pattern: "(", Category: "Broken"}}
+	_, err = New(cfg)
+	assert.ErrorContains(t, err, `invalid pattern for category "Broken"`)
+}
+
+func TestRulesPriority(t *testing.T) {
+	rules := []config.CategoryRule{
+		{Pattern: "TRANSFER", Category: "Transfer"},
+		{Pattern: "TRANSFER TO SAVINGS", Category: "Savings"},
+		{Pattern: "SAVINGS", Category: "Savings"},
+		{Pattern: "PAYPAL", Category: "Shopping", Priority: 10},
+	}
+	ctx := context.Background()
+
+	r, err := NewRules(rules, FirstMatch)
+	require.NoError(t, err)
+	result, _, err := r.Categorize(ctx, transaction.Transaction{Description: "TRANSFER TO SAVINGS"})
+	require.NoError(t, err)
+	assert.Equal(t, "Transfer", result.Category, "first match in file order")
+	result, _, _ = r.Categorize(ctx, transaction.Transaction{Description: "PAYPAL TRANSFER"})
+	assert.Equal(t, "Shopping", result.Category, "higher priority first")
+
+	r, err = NewRules(rules, MostSpecific)
+	require.NoError(t, err)
+	result, _, _ = r.Categorize(ctx, transaction.Transaction{Description: "TRANSFER TO SAVINGS"})
+	assert.Equal(t, "Savings", result.Category, "longest match")
+	result, _, _ = r.Categorize(ctx, transaction.Transaction{Description: "PAYPAL TRANSFER TO SAVINGS"})
+	assert.Equal(t, "Shopping", result.Category, "priority before length")
+
+	matches := r.Matches(transaction.Transaction{Description: "TRANSFER TO SAVINGS"})
+	require.Len(t, matches, 3)
+	assert.Equal(t, Match{Pattern: "TRANSFER TO SAVINGS", Category: "Savings", Text: "TRANSFER TO SAVINGS"}, matches[0])
+	assert.Equal(t, []Match{{Pattern: "TRANSFER", Category: "Transfer", Text: "TRANSFER"}}, r.Conflicts(transaction.Transaction{Description: "TRANSFER TO SAVINGS"}))
+	assert.Empty(t, r.Conflicts(transaction.Transaction{Description: "SAVINGS"}))
+	assert.Nil(t, r.Matches(transaction.Transaction{Description: "WOOLWORTHS"}))
+}
+
+func TestParseMatchStrategy(t *testing.T) {
+	match, err := ParseMatchStrategy("")
+	require.NoError(t, err)
+	assert.Equal(t, FirstMatch, match)
+
+	match, err = ParseMatchStrategy("Most-Specific")
+	require.NoError(t, err)
+	assert.Equal(t, MostSpecific, match)
+
+	_, err = New(&config.Config{Categorizer: config.CategorizerConfig{RuleMatch: "best"}})
+	assert.EqualError(t, err, `unknown rule_match "best" (expected first or most-specific)`)
+}
+
+func TestRulesAmountConditions(t *testing.T) {
+	r, err := NewRules([]config.CategoryRule{
+		{Pattern: "TRANSFER", Category: "Savings", MinAmount: 1000, Direction: "Debit"},
+		{Pattern: "TRANSFER", Category: "Refund", Direction: "credit", MaxAmount: 50},
+		{Pattern: "TRANSFER", Category: "Transfer"},
+	}, FirstMatch)
+	require.NoError(t, err)
+
+	for amount, want := range map[float64]string{-5000: "Savings", -1000: "Savings", -999: "Transfer", 5000: "Transfer", 20: "Refund", 0: "Transfer"} {
+		result, ok, err := r.Categorize(context.Background(), transaction.Transaction{Description: "TRANSFER", Amount: transaction.FromFloat(amount)})
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, want, result.Category, "amount %v", amount)
+	}
+	assert.Len(t, r.Conflicts(transaction.Transaction{Description: "TRANSFER", Amount: -500000}), 1)
+
+	_, err = NewRules([]config.CategoryRule{{Pattern: "X", Category: "Y", Direction: "out"}}, FirstMatch)
+	assert.EqualError(t, err, `invalid direction "out" for category "Y" (expected debit or credit)`)
+	_, err = NewRules([]config.CategoryRule{{Pattern: "X", Category: "Y", MinAmount: 100, MaxAmount: 10}}, FirstMatch)
+	assert.EqualError(t, err, `invalid amount range for category "Y": min_amount 100, max_amount 10`)
+}
+
+func TestRulesCardCondition(t *testing.T) {
+	r, err := NewRules([]config.CategoryRule{
+		{Pattern: "UBER", Category: "Work travel", Card: "4521"},
+		{Pattern: "UBER", Category: "Transport"},
+	}, FirstMatch)
+	require.NoError(t, err)
+
+	result, _, err := r.Categorize(context.Background(), transaction.Transaction{Description: "UBER TRIP", Card: "4521"})
+	require.NoError(t, err)
+	assert.Equal(t, "Work travel", result.Category)
+	result, _, err = r.Categorize(context.Background(), transaction.Transaction{Description: "UBER TRIP XX9912"})
+	require.NoError(t, err)
+	assert.Equal(t, "Transport", result.Category)
+
+	_, err = NewRules([]config.CategoryRule{{Pattern: "X", Category: "Y", Card: "xx4521"}}, FirstMatch)
+	assert.ErrorContains(t, err, `invalid card "xx4521" for category "Y"`)
+}
@@ -0,0 +1,345 @@
+package categorizer
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/example/statement-extractor/internal/cache"
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/parser"
+	"github.com/example/statement-extractor/internal/store"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Defaults for the embedding stage's batch_size and neighbours
+const (
+	DefaultEmbeddingBatchSize  = 100
+	DefaultEmbeddingNeighbours = 5
+)
+
+// Lister lists stored transactions, as the store does
+type Lister interface {
+	List(ctx context.Context, f store.Filter) ([]transaction.Transaction, error)
+}
+
+// Embedding is the "embedding" categorizer stage, for merchants no pattern
+// covers. It embeds descriptions through an OpenAI-compatible embeddings
+// endpoint and finds the Neighbours labeled transactions in the database
+// most similar to each: categorized by hand or by a rule, not by a model or
+// the default. Each neighbour votes for its category with its cosine
+// similarity, and the winning category's votes over Neighbours is the
+// confidence, so only close and agreeing neighbours pass a threshold.
+// Vectors are kept in Cache, so each description is embedded once.
+type Embedding struct {
+	BaseURL string
+	Model   string
+	APIKey  string // sent as a bearer token when set
+	Client  *http.Client
+
+	BatchSize  int
+	Neighbours int
+
+	// Store lists the labeled transactions; Fallback is the default
+	// category, which is not a label
+	Store    Lister
+	Fallback string
+
+	// Cache keeps vectors by endpoint, model and description. It is only an
+	// optimisation: a cache that cannot be read or written is passed over.
+	Cache cache.Cache
+
+	examples []example            // loaded on first use
+	vectors  map[string][]float64 // by normalized description
+}
+
+// example is a distinct labeled description
+type example struct {
+	description string
+	category    string
+}
+
+// NewEmbedding returns the stage configured under [categorizer.embedding],
+// searching the transactions in the configured database
+func NewEmbedding(cfg *config.Config) (*Embedding, error) {
+	ec := cfg.Categorizer.Embedding
+	if ec.BaseURL == "" {
+		return nil, errors.New("categorizer.embedding.base_url is not set")
+	}
+	if cfg.Database == "" {
+		return nil, errors.New("the embedding categorizer searches the transaction database; set database")
+	}
+
+	e := &Embedding{
+		BaseURL:    strings.TrimSuffix(ec.BaseURL, "/"),
+		Model:      ec.Model,
+		BatchSize:  ec.BatchSize,
+		Neighbours: ec.Neighbours,
+		Fallback:   cfg.DefaultCategory,
+	}
+	if e.BatchSize <= 0 {
+		e.BatchSize = DefaultEmbeddingBatchSize
+	}
+	if e.Neighbours <= 0 {
+		e.Neighbours = DefaultEmbeddingNeighbours
+	}
+	if ec.APIKeyEnv != "" {
+		if e.APIKey = os.Getenv(ec.APIKeyEnv); e.APIKey == "" {
+			return nil, fmt.Errorf("categorizer.embedding: %s is not set", ec.APIKeyEnv)
+		}
+	}
+	client, err := parser.NewHTTPClient(ec.HTTP)
+	if err != nil {
+		return nil, fmt.Errorf("categorizer.embedding: %w", err)
+	}
+	e.Client = client
+
+	dir := ec.CacheDir
+	if dir == "" {
+		state, err := cfg.StateDirectory()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(state, "embeddings")
+	}
+	e.Cache = cache.NewDir(dir)
+	e.Store = &lazyStore{path: cfg.Database}
+	return e, nil
+}
+
+// lazyStore opens the database when the stage is first used, so building a
+// chain that never reaches the stage does not need sqlite3
+type lazyStore struct {
+	path string
+}
+
+func (l *lazyStore) List(ctx context.Context, f store.Filter) ([]transaction.Transaction, error) {
+	s, err := store.Open(ctx, l.path)
+	if err != nil {
+		return nil, err
+	}
+	return s.List(ctx, f)
+}
+
+// Name returns "embedding"
+func (e *Embedding) Name() string {
+	return transaction.CategorizedByEmbedding
+}
+
+// Prepare embeds the labeled descriptions and those of txns, BatchSize at a
+// time, skipping any embedded before
+func (e *Embedding) Prepare(ctx context.Context, txns []transaction.Transaction) error {
+	if err := e.load(ctx); err != nil || len(e.examples) == 0 {
+		return err
+	}
+	descriptions := make([]string, 0, len(txns))
+	for _, t := range txns {
+		descriptions = append(descriptions, t.Description)
+	}
+	return e.embed(ctx, descriptions)
+}
+
+// Categorize returns the category the nearest labeled transactions vote for
+func (e *Embedding) Categorize(ctx context.Context, t transaction.Transaction) (Result, bool, error) {
+	if err := e.load(ctx); err != nil || len(e.examples) == 0 {
+		return Result{}, false, err
+	}
+	if err := e.embed(ctx, []string{t.Description}); err != nil {
+		return Result{}, false, err
+	}
+	vector := e.vectors[normalize(t.Description)]
+
+	type neighbour struct {
+		category   string
+		similarity float64
+	}
+	neighbours := make([]neighbour, 0, len(e.examples))
+	for _, ex := range e.examples {
+		neighbours = append(neighbours, neighbour{ex.category, cosine(vector, e.vectors[normalize(ex.description)])})
+	}
+	slices.SortStableFunc(neighbours, func(a, b neighbour) int { return cmp.Compare(b.similarity, a.similarity) })
+
+	votes := make(map[string]float64)
+	var best string
+	for _, n := range neighbours[:min(e.Neighbours, len(neighbours))] {
+		votes[n.category] += max(n.similarity, 0)
+		if best == "" || votes[n.category] > votes[best] {
+			best = n.category
+		}
+	}
+	if best == "" || votes[best] == 0 {
+		return Result{}, false, nil
+	}
+	return Result{Category: best, Confidence: min(votes[best]/float64(e.Neighbours), 1)}, true, nil
+}
+
+// load lists the distinct labeled descriptions and embeds them
+func (e *Embedding) load(ctx context.Context) error {
+	if e.examples != nil {
+		return nil
+	}
+	txns, err := e.Store.List(ctx, store.Filter{})
+	if err != nil {
+		return fmt.Errorf("listing labeled transactions: %w", err)
+	}
+	examples := []example{} // not nil once loaded
+	seen := make(map[example]bool)
+	for _, t := range txns {
+		switch {
+		case t.Category == "" || strings.EqualFold(t.Category, e.Fallback):
+			continue
+		case t.CategorizedBy == transaction.CategorizedByDefault, t.CategorizedBy == transaction.CategorizedByLLM, t.CategorizedBy == transaction.CategorizedByEmbedding:
+			continue
+		}
+		ex := example{description: normalize(t.Description), category: t.Category}
+		if !seen[ex] {
+			seen[ex] = true
+			examples = append(examples, ex)
+		}
+	}
+
+	descriptions := make([]string, len(examples))
+	for i, ex := range examples {
+		descriptions[i] = ex.description
+	}
+	if err := e.embed(ctx, descriptions); err != nil {
+		return err
+	}
+	e.examples = examples
+	return nil
+}
+
+// embed records the vectors of the descriptions not embedded yet, from the
+// cache or the endpoint
+func (e *Embedding) embed(ctx context.Context, descriptions []string) error {
+	if e.vectors == nil {
+		e.vectors = make(map[string][]float64)
+	}
+	var missing []string
+	asking := make(map[string]bool)
+	for _, d := range descriptions {
+		d = normalize(d)
+		if _, ok := e.vectors[d]; ok || asking[d] {
+			continue
+		}
+		asking[d] = true
+		if e.Cache != nil {
+			if value, ok, err := e.Cache.Get(ctx, e.key(d)); err == nil && ok {
+				var vector []float64
+				if json.Unmarshal(value, &vector) == nil {
+					e.vectors[d] = vector
+					continue
+				}
+			}
+		}
+		missing = append(missing, d)
+	}
+
+	for batch := range slices.Chunk(missing, e.BatchSize) {
+		vectors, err := e.request(ctx, batch)
+		if err != nil {
+			return err
+		}
+		for i, d := range batch {
+			e.vectors[d] = vectors[i]
+			if e.Cache != nil {
+				if value, err := json.Marshal(vectors[i]); err == nil {
+					_ = e.Cache.Put(ctx, e.key(d), value)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (e *Embedding) key(description string) string {
+	return cache.Key("embedding", e.BaseURL, e.Model, description)
+}
+
+type embeddingRequest struct {
+	Model string   `json:"model,omitempty"`
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+}
+
+// request embeds one batch, returning a vector per input in order
+func (e *Embedding) request(ctx context.Context, inputs []string) ([][]float64, error) {
+	body, err := json.Marshal(embeddingRequest{Model: e.Model, Input: inputs})
+	if err != nil {
+		return nil, fmt.Errorf("encoding request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.BaseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.APIKey)
+	}
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var embeddings embeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embeddings); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	vectors := make([][]float64, len(inputs))
+	for _, d := range embeddings.Data {
+		if d.Index >= 0 && d.Index < len(inputs) {
+			vectors[d.Index] = d.Embedding
+		}
+	}
+	for i, v := range vectors {
+		if len(v) == 0 {
+			return nil, fmt.Errorf("response has no embedding for %q", inputs[i])
+		}
+	}
+	return vectors, nil
+}
+
+// normalize collapses the whitespace of a description, as the llm stage
+// sends it
+func normalize(description string) string {
+	return strings.Join(strings.Fields(description), " ")
+}
+
+// cosine returns the cosine similarity of two vectors, 0 when either is
+// empty or their lengths differ
+func cosine(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
@@ -0,0 +1,93 @@
+package categorizer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/cache"
+	"github.com/example/statement-extractor/internal/store"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// fakeEmbeddings embeds descriptions as the vectors given, counting the
+// descriptions it is asked about
+func fakeEmbeddings(t *testing.T, vectors map[string][]float64, asked *[]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/embeddings", r.URL.Path)
+		var req embeddingRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		var resp embeddingResponse
+		resp.Data = make([]struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		}, len(req.Input))
+		for i, input := range req.Input {
+			*asked = append(*asked, input)
+			resp.Data[i].Index = i
+			resp.Data[i].Embedding = vectors[input]
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(resp))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+type fakeLister []transaction.Transaction
+
+func (l fakeLister) List(context.Context, store.Filter) ([]transaction.Transaction, error) {
+	return l, nil
+}
+
+func TestEmbeddingStage(t *testing.T) {
+	var asked []string
+	server := fakeEmbeddings(t, map[string][]float64{
+		"UBER TRIP":       {1, 0, 0},
+		"DIDI RIDE":       {0.9, 0.1, 0},
+		"OLA CABS":        {0.95, 0, 0.05},
+		"HARRIS FARM":     {0, 1, 0},
+		"CHEMIST WAREHSE": {0, 0, 1},
+		"LYFT":            {0.92, 0.05, 0},
+		"IGA 42":          {0.1, 0.9, 0},
+	}, &asked)
+
+	dir := t.TempDir()
+	stage := func() *Embedding {
+		return &Embedding{
+			BaseURL: server.URL, Client: server.Client(), BatchSize: 3, Neighbours: 3, Fallback: "Uncategorized",
+			Cache: cache.NewDir(dir),
+			Store: fakeLister{
+				{Description: "UBER  TRIP", Category: "Transport", CategorizedBy: "manual"},
+				{Description: "UBER TRIP", Category: "Transport", CategorizedBy: "rule:UBER"},
+				{Description: "DIDI RIDE", Category: "Transport", CategorizedBy: "manual"},
+				{Description: "OLA CABS", Category: "Transport"},
+				{Description: "HARRIS FARM", Category: "Groceries", CategorizedBy: "manual"},
+				{Description: "CHEMIST WAREHSE", Category: "Health", CategorizedBy: transaction.CategorizedByLLM},
+				{Description: "IGA 42", Category: "Uncategorized", CategorizedBy: "manual"},
+			},
+		}
+	}
+
+	e := stage()
+	txns := []transaction.Transaction{{Description: "LYFT"}, {Description: "IGA 42"}}
+	require.NoError(t, NewChain("Uncategorized", Stage{Categorizer: e, Threshold: 0.8}).Apply(context.Background(), txns))
+	assert.Equal(t, "Transport", txns[0].Category, "the nearest labeled transactions agree")
+	assert.Equal(t, transaction.CategorizedByEmbedding, txns[0].CategorizedBy)
+	assert.Greater(t, txns[0].CategoryConfidence, 0.9)
+	assert.Equal(t, "Uncategorized", txns[1].Category, "one close neighbour among three is not enough")
+	assert.ElementsMatch(t, []string{"UBER TRIP", "DIDI RIDE", "OLA CABS", "HARRIS FARM", "LYFT", "IGA 42"}, asked,
+		"each description is embedded once, and model guesses and the default are not labels")
+
+	// Another run finds the vectors in the cache
+	asked = nil
+	result, ok, err := stage().Categorize(context.Background(), transaction.Transaction{Description: "LYFT"})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "Transport", result.Category)
+	assert.Empty(t, asked)
+}
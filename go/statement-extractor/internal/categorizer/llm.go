@@ -0,0 +1,303 @@
+package categorizer
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/parser"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// DefaultLLMBatchSize is the number of descriptions sent per request when
+// batch_size is not set
+const DefaultLLMBatchSize = 25
+
+// LLM is the "llm" categorizer stage, which --llm-categorize adds to the end
+// of the chain. It sends the distinct descriptions of the transactions
+// reaching it in batches to an OpenAI-compatible chat completions endpoint,
+// asking the model to pick one of the known categories for each with a
+// confidence, which the chain holds to the stage's threshold. Calls are
+// charged to Budget, which must cap the cost of the run; once the next call
+// could go over it the stage has no opinion on the rest, and Stopped says
+// why.
+type LLM struct {
+	BaseURL string
+	Model   string
+	APIKey  string // sent as a bearer token when set
+	Client  *http.Client
+
+	Categories []string
+	BatchSize  int
+	Prompt     string // the system prompt
+
+	Budget               *parser.Budget
+	CostPerMillionTokens float64
+
+	// Stopped wraps parser.ErrBudgetExceeded once the budget has stopped
+	// the calls
+	Stopped error
+
+	answers map[string]llmAnswer // by description
+}
+
+// NewLLM returns the stage configured under [categorizer.llm], offering
+// the [[categories]] categories and the extra ones configured. maxCost,
+// when positive, overrides max_cost.
+func NewLLM(cfg *config.Config, maxCost float64) (*LLM, error) {
+	lc := cfg.Categorizer.LLM
+	if lc.BaseURL == "" {
+		return nil, errors.New("categorizer.llm.base_url is not set")
+	}
+	if maxCost <= 0 {
+		maxCost = lc.MaxCost
+	}
+	if maxCost <= 0 {
+		return nil, errors.New("the llm categorizer needs a cost cap: set categorizer.llm.max_cost, or --llm-max-cost with extract")
+	}
+	if lc.CostPerMillionTokens <= 0 {
+		return nil, errors.New("categorizer.llm.cost_per_million_tokens is not set; it prices calls for the cost cap")
+	}
+
+	l := &LLM{
+		BaseURL:   strings.TrimSuffix(lc.BaseURL, "/"),
+		Model:     lc.Model,
+		BatchSize: lc.BatchSize,
+
+		Budget:               &parser.Budget{MaxCost: maxCost},
+		CostPerMillionTokens: lc.CostPerMillionTokens,
+	}
+	if l.BatchSize <= 0 {
+		l.BatchSize = DefaultLLMBatchSize
+	}
+	for _, rule := range cfg.Categories {
+		l.addCategory(rule.Category)
+	}
+	for _, category := range lc.Categories {
+		l.addCategory(category)
+	}
+	if len(l.Categories) == 0 {
+		return nil, errors.New("categorizer.llm: no categories to choose from; add [[categories]] rules or categorizer.llm.categories")
+	}
+	prompt, err := renderPrompt(cmp.Or(lc.Prompt, llmPrompt), l.Categories)
+	if err != nil {
+		return nil, err
+	}
+	l.Prompt = prompt
+	if lc.APIKeyEnv != "" {
+		if l.APIKey = os.Getenv(lc.APIKeyEnv); l.APIKey == "" {
+			return nil, fmt.Errorf("categorizer.llm: %s is not set", lc.APIKeyEnv)
+		}
+	}
+	client, err := parser.NewHTTPClient(lc.HTTP)
+	if err != nil {
+		return nil, fmt.Errorf("categorizer.llm: %w", err)
+	}
+	l.Client = client
+	return l, nil
+}
+
+// renderPrompt renders the system prompt template, given the categories
+func renderPrompt(text string, categories []string) (string, error) {
+	tmpl, err := template.New("prompt").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("categorizer.llm.prompt: %w", err)
+	}
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, map[string]any{"Categories": categories}); err != nil {
+		return "", fmt.Errorf("categorizer.llm.prompt: %w", err)
+	}
+	return buf.String(), nil
+}
+
+func (l *LLM) addCategory(category string) {
+	if category != "" && !slices.ContainsFunc(l.Categories, func(c string) bool { return strings.EqualFold(c, category) }) {
+		l.Categories = append(l.Categories, category)
+	}
+}
+
+// Name returns "llm"
+func (l *LLM) Name() string {
+	return transaction.CategorizedByLLM
+}
+
+// Prepare asks the model about the distinct descriptions of txns it has not
+// answered yet, BatchSize at a time
+func (l *LLM) Prepare(ctx context.Context, txns []transaction.Transaction) error {
+	var descriptions []string
+	asking := make(map[string]bool)
+	for _, t := range txns {
+		if _, ok := l.answers[t.Description]; !ok && !asking[t.Description] {
+			descriptions = append(descriptions, t.Description)
+			asking[t.Description] = true
+		}
+	}
+	for batch := range slices.Chunk(descriptions, l.BatchSize) {
+		if err := l.ask(ctx, batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Categorize returns the model's category for the description, asking about
+// it alone when Prepare has not
+func (l *LLM) Categorize(ctx context.Context, t transaction.Transaction) (Result, bool, error) {
+	if _, ok := l.answers[t.Description]; !ok {
+		if err := l.ask(ctx, []string{t.Description}); err != nil {
+			return Result{}, false, err
+		}
+	}
+	a := l.answers[t.Description]
+	if a.Category == "" {
+		return Result{}, false, nil
+	}
+	return Result{Category: a.Category, Confidence: a.Confidence}, true, nil
+}
+
+// ask records the model's answers about descriptions. Once the budget stops
+// the calls nothing more is asked, and the descriptions stay unanswered.
+func (l *LLM) ask(ctx context.Context, descriptions []string) error {
+	if l.Stopped != nil {
+		return nil
+	}
+	answers, err := l.categorize(ctx, descriptions)
+	if errors.Is(err, parser.ErrBudgetExceeded) {
+		l.Stopped = err
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if l.answers == nil {
+		l.answers = make(map[string]llmAnswer)
+	}
+	for i, a := range answers {
+		l.answers[descriptions[i]] = a
+	}
+	return nil
+}
+
+// llmAnswer is the model's category for one description; an empty
+// Category means none fits
+type llmAnswer struct {
+	ID         int     `json:"id"`
+	Category   string  `json:"category"`
+	Confidence float64 `json:"confidence"`
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model          string         `json:"model,omitempty"`
+	Messages       []chatMessage  `json:"messages"`
+	Temperature    float64        `json:"temperature"`
+	ResponseFormat map[string]any `json:"response_format"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// llmPrompt is the default system prompt template
+const llmPrompt = `You categorize bank transactions. For each numbered description choose the
+one category from the list that fits it best, or "" if none does, and say how
+sure you are from 0 to 1. Answer with JSON only:
+{"results": [{"id": 1, "category": "...", "confidence": 0.9}]}`
+
+// categorize asks the model about one batch, returning an answer per
+// description in order. Categories not in the list count as no answer.
+func (l *LLM) categorize(ctx context.Context, descriptions []string) ([]llmAnswer, error) {
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Categories: %s\n\n", strings.Join(l.Categories, "; "))
+	for i, d := range descriptions {
+		fmt.Fprintf(&prompt, "%d. %s\n", i+1, normalize(d))
+	}
+	body, err := json.Marshal(chatRequest{
+		Model:          l.Model,
+		Messages:       []chatMessage{{Role: "system", Content: l.Prompt}, {Role: "user", Content: prompt.String()}},
+		ResponseFormat: map[string]any{"type": "json_object"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encoding request: %w", err)
+	}
+	if err := l.Budget.Reserve(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.BaseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if l.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+l.APIKey)
+	}
+	resp, err := l.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var chat chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chat); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	usage := parser.Usage{InputTokens: chat.Usage.PromptTokens, OutputTokens: chat.Usage.CompletionTokens}
+	usage.Cost = float64(usage.Tokens()) * l.CostPerMillionTokens / 1e6
+	l.Budget.Add(usage)
+	if len(chat.Choices) == 0 {
+		return nil, errors.New("response has no choices")
+	}
+
+	content := chat.Choices[0].Message.Content
+	if start, end := strings.Index(content, "{"), strings.LastIndex(content, "}"); start >= 0 && end > start {
+		content = content[start : end+1]
+	}
+	var results struct {
+		Results []llmAnswer `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(content), &results); err != nil {
+		return nil, fmt.Errorf("decoding answer: %w", err)
+	}
+
+	answers := make([]llmAnswer, len(descriptions))
+	for _, a := range results.Results {
+		i := slices.IndexFunc(l.Categories, func(c string) bool { return strings.EqualFold(c, strings.TrimSpace(a.Category)) })
+		if a.ID < 1 || a.ID > len(descriptions) || i < 0 {
+			continue
+		}
+		a.Category = l.Categories[i]
+		a.Confidence = min(max(a.Confidence, 0), 1)
+		answers[a.ID-1] = a
+	}
+	return answers, nil
+}
+
+// Spent returns what the calls so far have cost
+func (l *LLM) Spent() parser.Usage {
+	return l.Budget.Spent()
+}
@@ -0,0 +1,127 @@
+package categorizer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/parser"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// fakeChat answers every request with content, reporting 1000 tokens
+func fakeChat(t *testing.T, content string, requests *[]chatRequest) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat/completions", r.URL.Path)
+		var req chatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		*requests = append(*requests, req)
+		answer, _ := json.Marshal(content)
+		fmt.Fprintf(w, `{"choices": [{"message": {"role": "assistant", "content": %s}}], "usage": {"prompt_tokens": 800, "completion_tokens": 200}}`, answer)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func llmConfig(baseURL string) *config.Config {
+	return &config.Config{
+		Categories: []config.CategoryRule{{Pattern: "WOOLWORTHS", Category: "Groceries"}},
+		Categorizer: config.CategorizerConfig{LLM: config.LLMConfig{
+			BaseURL: baseURL, Model: "small", Threshold: 0.6, Categories: []string{"Transport"},
+			MaxCost: 1, CostPerMillionTokens: 100,
+		}},
+	}
+}
+
+func TestLLMStage(t *testing.T) {
+	var requests []chatRequest
+	server := fakeChat(t, "```json\n"+`{"results": [
+		{"id": 1, "category": "transport", "confidence": 0.9},
+		{"id": 2, "category": "Groceries", "confidence": 0.4},
+		{"id": 3, "category": "Holidays", "confidence": 1}]}`+"\n```", &requests)
+
+	cfg := llmConfig(server.URL)
+	cfg.DefaultCategory = "Uncategorized"
+	cfg.Categorizer.Stages = []config.CategorizerStage{{Name: "rules"}, {Name: "llm", Threshold: 0.6}}
+	chain, err := New(cfg)
+	require.NoError(t, err)
+	llm := chain.Lookup("llm").(*LLM)
+	assert.Equal(t, []string{"Groceries", "Transport"}, llm.Categories)
+
+	txns := []transaction.Transaction{
+		{Description: "UBER TRIP"},
+		{Description: "IGA 42"},
+		{Description: "QANTAS"},
+		{Description: "UBER TRIP"},
+		{Description: "WOOLWORTHS"},
+	}
+	require.NoError(t, chain.Apply(context.Background(), txns))
+	assert.Equal(t, "Transport", txns[0].Category, "categories are matched to the configured names")
+	assert.Equal(t, transaction.CategorizedByLLM, txns[3].CategorizedBy)
+	assert.Equal(t, 0.9, txns[3].CategoryConfidence)
+	assert.Equal(t, "Uncategorized", txns[1].Category, "answers below the stage threshold are ignored")
+	assert.Equal(t, "Uncategorized", txns[2].Category, "categories that were not offered are ignored")
+	assert.Equal(t, "rule:WOOLWORTHS", txns[4].CategorizedBy, "earlier stages come first")
+
+	require.Len(t, requests, 1, "the transactions reaching the stage are sent together")
+	prompt := requests[0].Messages[1].Content
+	assert.Contains(t, prompt, "1. UBER TRIP\n2. IGA 42\n3. QANTAS\n", "each description is sent once")
+	assert.NotContains(t, prompt, "WOOLWORTHS\n")
+	assert.InDelta(t, 0.1, llm.Spent().Cost, 1e-9)
+
+	// Categorizing one transaction asks about it alone
+	result, err := chain.Categorize(context.Background(), transaction.Transaction{Description: "UBER TRIP"})
+	require.NoError(t, err)
+	assert.Equal(t, "Transport", result.Category)
+	assert.Len(t, requests, 1, "answers are kept")
+}
+
+func TestLLMCostCap(t *testing.T) {
+	var requests []chatRequest
+	server := fakeChat(t, `{"results": [{"id": 1, "category": "Transport", "confidence": 1}]}`, &requests)
+
+	cfg := llmConfig(server.URL)
+	cfg.Categorizer.LLM.BatchSize = 1
+	llm, err := NewLLM(cfg, 0.15)
+	require.NoError(t, err)
+
+	txns := []transaction.Transaction{{Description: "A"}, {Description: "B"}, {Description: "C"}}
+	require.NoError(t, NewChain("Uncategorized", Stage{Categorizer: llm}).Apply(context.Background(), txns))
+	assert.ErrorIs(t, llm.Stopped, parser.ErrBudgetExceeded)
+	assert.Equal(t, "Transport", txns[0].Category, "batches categorized before the cap are kept")
+	assert.Equal(t, "Uncategorized", txns[1].Category)
+	assert.Len(t, requests, 1)
+
+	cfg.Categorizer.LLM.MaxCost = 0
+	_, err = NewLLM(cfg, 0)
+	assert.ErrorContains(t, err, "cost cap")
+
+	cfg.Categorizer.LLM.MaxCost, cfg.Categorizer.LLM.CostPerMillionTokens = 1, 0
+	_, err = NewLLM(cfg, 0)
+	assert.ErrorContains(t, err, "cost_per_million_tokens")
+}
+
+func TestLLMPrompt(t *testing.T) {
+	var requests []chatRequest
+	server := fakeChat(t, `{"results": []}`, &requests)
+
+	cfg := llmConfig(server.URL)
+	cfg.Categorizer.LLM.Prompt = `Categorize Australian card payments into{{range .Categories}} {{.}}{{end}}.`
+	llm, err := NewLLM(cfg, 0)
+	require.NoError(t, err)
+	_, _, err = llm.Categorize(context.Background(), transaction.Transaction{Description: "UBER TRIP"})
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	assert.Equal(t, "Categorize Australian card payments into Groceries Transport.", requests[0].Messages[0].Content)
+
+	cfg.Categorizer.LLM.Prompt = `{{.Descriptions}}`
+	_, err = NewLLM(cfg, 0)
+	assert.ErrorContains(t, err, "categorizer.llm.prompt: ")
+}
@@ -0,0 +1,90 @@
+package categorizer
+
+import (
+	"cmp"
+	"context"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// MerchantConfidence is the confidence of a merchant table match: below a
+// rule's, as the table only knows a merchant's usual category
+const MerchantConfidence = 0.7
+
+//go:embed data/merchants.csv
+var merchantTable string
+
+// Merchants categorizes well-known merchants from a table shipped with the
+// program, mapping common Australian and US merchant names to the default
+// categories of the example config. It runs after the rules, so new users
+// start with far fewer uncategorized transactions.
+type Merchants struct {
+	entries []merchantEntry // longest name first
+}
+
+type merchantEntry struct {
+	name     string // normalized
+	category string
+}
+
+// NewMerchants loads the merchant table, renaming its categories as
+// configured under [categorizer.merchants]; a category renamed to "" is
+// dropped
+func NewMerchants(cfg config.MerchantTableConfig) (*Merchants, error) {
+	renames := make(map[string]string, len(cfg.Categories))
+	for from, to := range cfg.Categories {
+		renames[strings.ToLower(from)] = to
+	}
+
+	records, err := csv.NewReader(strings.NewReader(merchantTable)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("merchant table: %w", err)
+	}
+	m := &Merchants{}
+	for _, record := range records[1:] {
+		category := record[1]
+		if to, ok := renames[strings.ToLower(category)]; ok {
+			category = to
+		}
+		if category != "" {
+			m.entries = append(m.entries, merchantEntry{name: merchantKey(record[0]), category: category})
+		}
+	}
+	slices.SortStableFunc(m.entries, func(a, b merchantEntry) int { return cmp.Compare(len(b.name), len(a.name)) })
+	return m, nil
+}
+
+// merchantKey normalizes a name or description for matching: upper case,
+// without apostrophes and with other punctuation as spaces, so "McDonald's"
+// and "BOOKING.COM" match MCDONALDS and BOOKING COM
+func merchantKey(s string) string {
+	s = strings.NewReplacer("'", "", "’", "").Replace(strings.ToUpper(s))
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// Name implements Categorizer
+func (m *Merchants) Name() string {
+	return "merchants"
+}
+
+// Categorize implements Categorizer. The merchant name, when known, or else
+// the description must contain a table name as whole words; the longest
+// name wins.
+func (m *Merchants) Categorize(_ context.Context, t transaction.Transaction) (Result, bool, error) {
+	text := " " + merchantKey(cmp.Or(t.Merchant, t.Description)) + " "
+	for _, e := range m.entries {
+		if strings.Contains(text, " "+e.name+" ") {
+			return Result{Category: e.category, Confidence: MerchantConfidence}, true, nil
+		}
+	}
+	return Result{}, false, nil
+}
@@ -0,0 +1,57 @@
+package categorizer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestMerchants(t *testing.T) {
+	m, err := NewMerchants(config.MerchantTableConfig{Categories: map[string]string{
+		"food & dining": "Eating out",
+		"Gambling":      "",
+	}})
+	require.NoError(t, err)
+
+	for description, want := range map[string]string{
+		"WOOLWORTHS 1234 SYDNEY":     "Groceries & household",
+		"UBER *TRIP HELP.UBER.COM":   "Auto & transport",
+		"UBER EATS SYDNEY":           "Eating out",
+		"McDonald's Parramatta":      "Eating out",
+		"COLES EXPRESS 1234":         "Auto & transport",
+		"BOOKING.COM HOTEL":          "Travel",
+		"SPORTSBET MELBOURNE":        "",
+		"TRANSFER TO SHELLEY SAVING": "",
+	} {
+		result, ok, err := m.Categorize(context.Background(), transaction.Transaction{Description: description})
+		require.NoError(t, err)
+		assert.Equal(t, want, result.Category, description)
+		if ok {
+			assert.Equal(t, MerchantConfidence, result.Confidence)
+		}
+	}
+
+	result, _, err := m.Categorize(context.Background(), transaction.Transaction{Description: "SQ *4521 XYZ", Merchant: "Netflix"})
+	require.NoError(t, err)
+	assert.Equal(t, "Entertainment", result.Category, "the merchant name is matched when known")
+}
+
+func TestChainMerchantFallback(t *testing.T) {
+	chain, err := New(&config.Config{
+		DefaultCategory: "Uncategorized",
+		Categories:      []config.CategoryRule{{Pattern: "NETFLIX", Category: "Subscriptions"}},
+	})
+	require.NoError(t, err)
+
+	txns := []transaction.Transaction{{Description: "NETFLIX.COM"}, {Description: "ALDI 42"}, {Description: "ACME PTY"}}
+	require.NoError(t, chain.Apply(context.Background(), txns))
+	assert.Equal(t, "Subscriptions", txns[0].Category, "rules come first")
+	assert.Equal(t, "Groceries & household", txns[1].Category)
+	assert.Equal(t, "merchants", txns[1].CategorizedBy)
+	assert.Equal(t, "Uncategorized", txns[2].Category)
+}
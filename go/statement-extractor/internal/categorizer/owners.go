@@ -0,0 +1,79 @@
+package categorizer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Owners attributes transactions to the household members of the [[owners]]
+// entries. A card number ending in one of a member's card suffixes wins over
+// the accounts, so purchases on a shared account can be told apart by card.
+type Owners struct {
+	cards    []ownerCard
+	accounts map[string]string // lower-cased account -> owner
+}
+
+type ownerCard struct {
+	suffix string
+	owner  string
+}
+
+// NewOwners indexes the owner rules. An account or card suffix may belong to
+// only one owner.
+func NewOwners(rules []config.OwnerRule) (*Owners, error) {
+	o := &Owners{accounts: make(map[string]string)}
+	suffixes := make(map[string]string)
+	for i, r := range rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("owner %d has no name", i+1)
+		}
+		if len(r.Accounts) == 0 && len(r.CardSuffixes) == 0 {
+			return nil, fmt.Errorf("owner %q needs accounts or card_suffixes", r.Name)
+		}
+		for _, account := range r.Accounts {
+			key := strings.ToLower(account)
+			if existing, ok := o.accounts[key]; ok && existing != r.Name {
+				return nil, fmt.Errorf("account %q belongs to both owners %q and %q", account, existing, r.Name)
+			}
+			o.accounts[key] = r.Name
+		}
+		for _, suffix := range r.CardSuffixes {
+			if suffix == "" || strings.Trim(suffix, "0123456789") != "" {
+				return nil, fmt.Errorf("owner %q: card suffix %q is not digits", r.Name, suffix)
+			}
+			if existing, ok := suffixes[suffix]; ok && existing != r.Name {
+				return nil, fmt.Errorf("card suffix %q belongs to both owners %q and %q", suffix, existing, r.Name)
+			}
+			suffixes[suffix] = r.Name
+			o.cards = append(o.cards, ownerCard{suffix: suffix, owner: r.Name})
+		}
+	}
+	// Longer suffixes are more specific, so they are tried first
+	slices.SortStableFunc(o.cards, func(a, b ownerCard) int {
+		return len(b.suffix) - len(a.suffix)
+	})
+	return o, nil
+}
+
+// Of returns the owner of t, or "" when no rule attributes it
+func (o *Owners) Of(t transaction.Transaction) string {
+	for _, card := range o.cards {
+		if t.PaidWith(card.suffix) {
+			return card.owner
+		}
+	}
+	return o.accounts[strings.ToLower(t.Account)]
+}
+
+// Apply sets Owner on the transactions that do not already have one
+func (o *Owners) Apply(txns []transaction.Transaction) {
+	for i := range txns {
+		if txns[i].Owner == "" {
+			txns[i].Owner = o.Of(txns[i])
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package categorizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestOwners(t *testing.T) {
+	owners, err := NewOwners([]config.OwnerRule{
+		{Name: "Sam", Accounts: []string{"Joint"}, CardSuffixes: []string{"4521"}},
+		{Name: "Alex", CardSuffixes: []string{"9912", "14521"}},
+	})
+	require.NoError(t, err)
+
+	txns := []transaction.Transaction{
+		{Description: "WOOLWORTHS CARD XX4521", Account: "joint"},
+		{Description: "COLES ****9912", Account: "Joint"},
+		{Description: "BP 4521 SYDNEY", Account: "Joint"},
+		{Description: "QANTAS", RawDescription: "QANTAS CARD 9912"},
+		{Description: "SHELL XX0014521"},
+		{Description: "NETFLIX", Account: "Everyday"},
+		{Description: "ALDI XX4521", Owner: "Kim"},
+	}
+	owners.Apply(txns)
+
+	assert.Equal(t, "Sam", txns[0].Owner)
+	assert.Equal(t, "Alex", txns[1].Owner, "the card wins over the account")
+	assert.Equal(t, "Sam", txns[2].Owner, "store numbers are not cards")
+	assert.Equal(t, "Alex", txns[3].Owner, "the raw description is checked too")
+	assert.Equal(t, "Alex", txns[4].Owner, "longer suffixes are tried first")
+	assert.Empty(t, txns[5].Owner)
+	assert.Equal(t, "Kim", txns[6].Owner, "owners already set are kept")
+}
+
+func TestNewOwners_Errors(t *testing.T) {
+	_, err := NewOwners([]config.OwnerRule{{Name: "Sam"}})
+	assert.ErrorContains(t, err, "needs accounts or card_suffixes")
+
+	_, err = NewOwners([]config.OwnerRule{{Name: "Sam", CardSuffixes: []string{"45x1"}}})
+	assert.ErrorContains(t, err, "is not digits")
+
+	_, err = NewOwners([]config.OwnerRule{
+		{Name: "Sam", Accounts: []string{"Joint"}},
+		{Name: "Alex", Accounts: []string{"joint"}},
+	})
+	assert.EqualError(t, err, `account "joint" belongs to both owners "Sam" and "Alex"`)
+}
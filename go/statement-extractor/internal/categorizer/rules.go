@@ -0,0 +1,226 @@
+package categorizer
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// MatchStrategy decides which rule wins when several match a description
+type MatchStrategy string
+
+const (
+	FirstMatch   MatchStrategy = "first"         // the first rule in priority order
+	MostSpecific MatchStrategy = "most-specific" // the highest priority, then the longest matched text
+)
+
+// ParseMatchStrategy parses the rule_match setting; empty is FirstMatch
+func ParseMatchStrategy(s string) (MatchStrategy, error) {
+	switch MatchStrategy(strings.ToLower(s)) {
+	case "", FirstMatch:
+		return FirstMatch, nil
+	case MostSpecific:
+		return MostSpecific, nil
+	}
+	return "", fmt.Errorf("unknown rule_match %q (expected first or most-specific)", s)
+}
+
+// Rules matches descriptions against the configured [[categories]] patterns
+// in priority order, highest first, keeping the file order between equal
+// priorities. A rule with amount or direction conditions only matches
+// transactions that meet them. The rule chosen by the match strategy wins,
+// with full confidence.
+type Rules struct {
+	rules []rule
+	match MatchStrategy
+}
+
+type rule struct {
+	id       string
+	source   string // the pattern as configured
+	pattern  *regexp.Regexp
+	category string
+	priority int
+	exclude  bool
+	amortize int
+	amount   amountCondition
+	card     string // last digits of the card; "" for any
+}
+
+// amountCondition limits a rule to amounts of a size and direction
+type amountCondition struct {
+	min, max  transaction.Amount // on the size of the amount; a zero max is unbounded
+	direction string             // "debit", "credit" or "" for either
+}
+
+// newAmountCondition validates the min_amount, max_amount and direction of
+// the rule named by what
+func newAmountCondition(minAmount, maxAmount float64, direction, what string) (amountCondition, error) {
+	direction = strings.ToLower(direction)
+	if direction != "" && direction != "debit" && direction != "credit" {
+		return amountCondition{}, fmt.Errorf("invalid direction %q for %s (expected debit or credit)", direction, what)
+	}
+	if minAmount < 0 || maxAmount < 0 || maxAmount != 0 && maxAmount < minAmount {
+		return amountCondition{}, fmt.Errorf("invalid amount range for %s: min_amount %g, max_amount %g", what, minAmount, maxAmount)
+	}
+	return amountCondition{
+		min:       transaction.FromFloat(minAmount),
+		max:       transaction.FromFloat(maxAmount),
+		direction: direction,
+	}, nil
+}
+
+// applies reports whether t meets the condition
+func (c amountCondition) applies(t transaction.Transaction) bool {
+	switch c.direction {
+	case "debit":
+		if t.Amount >= 0 {
+			return false
+		}
+	case "credit":
+		if t.Amount <= 0 {
+			return false
+		}
+	}
+	size := t.Amount.Abs()
+	return size >= c.min && (c.max == 0 || size <= c.max)
+}
+
+// checkCard validates the card condition of the rule named by what
+func checkCard(card, what string) error {
+	if strings.Trim(card, "0123456789") != "" {
+		return fmt.Errorf("invalid card %q for %s (expected the last digits of the card number)", card, what)
+	}
+	return nil
+}
+
+// paidWith reports whether t meets a card condition; "" is any card
+func paidWith(card string, t transaction.Transaction) bool {
+	return card == "" || t.PaidWith(card)
+}
+
+// Match is a rule that matched a description
+type Match struct {
+	Pattern  string
+	Category string
+	Priority int
+	Text     string // the part of the description the pattern matched
+}
+
+// NewRules compiles category rules; patterns are case-insensitive
+func NewRules(rules []config.CategoryRule, match MatchStrategy) (*Rules, error) {
+	r := &Rules{rules: make([]rule, 0, len(rules)), match: match}
+	for _, cr := range rules {
+		pattern, err := regexp.Compile("(?i)" + cr.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for category %q: %w", cr.Category, err)
+		}
+		amount, err := newAmountCondition(cr.MinAmount, cr.MaxAmount, cr.Direction, fmt.Sprintf("category %q", cr.Category))
+		if err != nil {
+			return nil, err
+		}
+		if err := checkCard(cr.Card, fmt.Sprintf("category %q", cr.Category)); err != nil {
+			return nil, err
+		}
+		r.rules = append(r.rules, rule{
+			id:       cmp.Or(cr.ID, cr.Pattern),
+			source:   cr.Pattern,
+			pattern:  pattern,
+			category: cr.Category,
+			priority: cr.Priority,
+			exclude:  cr.ExcludeFromReports,
+			amortize: cr.AmortizeMonths,
+			amount:   amount,
+			card:     cr.Card,
+		})
+	}
+	slices.SortStableFunc(r.rules, func(a, b rule) int { return b.priority - a.priority })
+	return r, nil
+}
+
+// Name implements Categorizer
+func (r *Rules) Name() string {
+	return "rules"
+}
+
+// Categorize implements Categorizer
+func (r *Rules) Categorize(_ context.Context, t transaction.Transaction) (Result, bool, error) {
+	i := r.winner(t)
+	if i < 0 {
+		return Result{}, false, nil
+	}
+	rule := r.rules[i]
+	return Result{Category: rule.category, Confidence: 1, Rule: rule.id, ExcludeFromReports: rule.exclude, AmortizeMonths: rule.amortize}, true, nil
+}
+
+// winner returns the index of the winning rule for t, or -1
+func (r *Rules) winner(t transaction.Transaction) int {
+	best, bestLen := -1, 0
+	for i, rule := range r.rules {
+		if !rule.amount.applies(t) || !paidWith(rule.card, t) {
+			continue
+		}
+		loc := rule.pattern.FindStringIndex(t.Description)
+		if loc == nil {
+			continue
+		}
+		if r.match != MostSpecific {
+			return i
+		}
+		if best >= 0 && rule.priority < r.rules[best].priority {
+			break
+		}
+		if length := loc[1] - loc[0]; best < 0 || length > bestLen {
+			best, bestLen = i, length
+		}
+	}
+	return best
+}
+
+// Matches returns every rule matching the transaction, the winner first and
+// the rest in priority order
+func (r *Rules) Matches(t transaction.Transaction) []Match {
+	win := r.winner(t)
+	if win < 0 {
+		return nil
+	}
+	var matches []Match
+	for i, rule := range r.rules {
+		if !rule.amount.applies(t) || !paidWith(rule.card, t) {
+			continue
+		}
+		loc := rule.pattern.FindStringIndex(t.Description)
+		if loc == nil {
+			continue
+		}
+		m := Match{Pattern: rule.source, Category: rule.category, Priority: rule.priority, Text: t.Description[loc[0]:loc[1]]}
+		if i == win {
+			matches = slices.Insert(matches, 0, m)
+		} else {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// Conflicts returns the rules matching the transaction that assign a
+// different category from the winner
+func (r *Rules) Conflicts(t transaction.Transaction) []Match {
+	matches := r.Matches(t)
+	if len(matches) == 0 {
+		return nil
+	}
+	var conflicts []Match
+	for _, m := range matches[1:] {
+		if !strings.EqualFold(m.Category, matches[0].Category) {
+			conflicts = append(conflicts, m)
+		}
+	}
+	return conflicts
+}
@@ -0,0 +1,94 @@
+package categorizer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Splitter splits transactions between categories with the first
+// [[split_rules]] entry they match. Splits are applied after categorization
+// so rules can select by category.
+type Splitter struct {
+	rules []splitRule
+}
+
+type splitRule struct {
+	pattern  *regexp.Regexp // nil matches every description
+	category string
+	splits   []config.SplitAllocation
+}
+
+// NewSplitter compiles split rules; patterns are case-insensitive
+func NewSplitter(rules []config.SplitRule) (*Splitter, error) {
+	s := &Splitter{rules: make([]splitRule, 0, len(rules))}
+	for i, sr := range rules {
+		what := fmt.Sprintf("split rule %d", i+1)
+		if sr.Pattern == "" && sr.Category == "" {
+			return nil, errors.New(what + " needs a pattern or a category")
+		}
+		if len(sr.Splits) < 2 {
+			return nil, fmt.Errorf("%s needs at least two splits", what)
+		}
+		var total float64
+		for _, split := range sr.Splits {
+			if split.Category == "" || split.Percent <= 0 {
+				return nil, fmt.Errorf("%s: each split needs a category and a positive percent", what)
+			}
+			total += split.Percent
+		}
+		if math.Abs(total-100) > 1e-9 {
+			return nil, fmt.Errorf("%s: percentages add up to %g, not 100", what, total)
+		}
+		rule := splitRule{category: sr.Category, splits: sr.Splits}
+		if sr.Pattern != "" {
+			pattern, err := regexp.Compile("(?i)" + sr.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern for %s: %w", what, err)
+			}
+			rule.pattern = pattern
+		}
+		s.rules = append(s.rules, rule)
+	}
+	return s, nil
+}
+
+// Splits returns the splits of the first rule t matches, or nil. Amounts are
+// rounded to cents, with the rounding left on the last split so they add up
+// to the transaction's amount.
+func (s *Splitter) Splits(t transaction.Transaction) []transaction.Split {
+	for _, rule := range s.rules {
+		if rule.category != "" && !strings.EqualFold(rule.category, t.Category) {
+			continue
+		}
+		if rule.pattern != nil && !rule.pattern.MatchString(t.Description) {
+			continue
+		}
+		splits := make([]transaction.Split, len(rule.splits))
+		remaining := t.Amount
+		for i, split := range rule.splits {
+			amount := t.Amount.Mul(split.Percent / 100)
+			if i == len(rule.splits)-1 {
+				amount = remaining
+			}
+			splits[i] = transaction.Split{Category: split.Category, Amount: amount}
+			remaining -= amount
+		}
+		return splits
+	}
+	return nil
+}
+
+// Apply splits the transactions that match a rule and are not split already
+func (s *Splitter) Apply(txns []transaction.Transaction) {
+	for i := range txns {
+		if len(txns[i].Splits) == 0 {
+			txns[i].Splits = s.Splits(txns[i])
+		}
+	}
+}
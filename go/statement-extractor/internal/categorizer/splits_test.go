@@ -0,0 +1,50 @@
+package categorizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestSplitter(t *testing.T) {
+	s, err := NewSplitter([]config.SplitRule{
+		{Pattern: "WOOLWORTHS|COLES", Splits: []config.SplitAllocation{
+			{Category: "Groceries", Percent: 70}, {Category: "Household", Percent: 30},
+		}},
+		{Category: "Utilities", Splits: []config.SplitAllocation{
+			{Category: "Utilities", Percent: 100.0 / 3}, {Category: "Home office", Percent: 200.0 / 3},
+		}},
+	})
+	require.NoError(t, err)
+
+	txns := []transaction.Transaction{
+		{Description: "WOOLWORTHS 1234", Amount: -8215, Category: "Groceries"},
+		{Description: "AGL ENERGY", Amount: -10000, Category: "Utilities"},
+		{Description: "NETFLIX", Amount: -2000, Category: "Entertainment"},
+		{Description: "COLES", Amount: -1000, Splits: []transaction.Split{{Category: "Gifts", Amount: -1000}}},
+	}
+	s.Apply(txns)
+
+	assert.Equal(t, []transaction.Split{{Category: "Groceries", Amount: -5751}, {Category: "Household", Amount: -2464}}, txns[0].Splits)
+	assert.Equal(t, []transaction.Split{{Category: "Utilities", Amount: -3333}, {Category: "Home office", Amount: -6667}}, txns[1].Splits,
+		"the last split takes the rounding")
+	assert.Nil(t, txns[2].Splits)
+	assert.Equal(t, "Gifts", txns[3].Splits[0].Category, "splits already set are kept")
+}
+
+func TestNewSplitter_Errors(t *testing.T) {
+	halves := []config.SplitAllocation{{Category: "A", Percent: 50}, {Category: "B", Percent: 50}}
+
+	_, err := NewSplitter([]config.SplitRule{{Splits: halves}})
+	assert.ErrorContains(t, err, "needs a pattern or a category")
+
+	_, err = NewSplitter([]config.SplitRule{{Pattern: "X", Splits: halves[:1]}})
+	assert.ErrorContains(t, err, "at least two splits")
+
+	_, err = NewSplitter([]config.SplitRule{{Pattern: "X", Splits: []config.SplitAllocation{{Category: "A", Percent: 50}, {Category: "B", Percent: 40}}}})
+	assert.EqualError(t, err, "split rule 1: percentages add up to 90, not 100")
+}
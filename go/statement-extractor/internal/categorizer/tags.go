@@ -0,0 +1,90 @@
+package categorizer
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Tagger adds the tags of every [[tag_rules]] entry a transaction matches.
+// Tags are applied after categorization so rules can select by category.
+type Tagger struct {
+	rules []tagRule
+}
+
+type tagRule struct {
+	pattern  *regexp.Regexp // nil matches every description
+	category string
+	tags     []string
+	amount   amountCondition
+	card     string
+}
+
+// NewTagger compiles tag rules; patterns are case-insensitive
+func NewTagger(rules []config.TagRule) (*Tagger, error) {
+	t := &Tagger{rules: make([]tagRule, 0, len(rules))}
+	for i, tr := range rules {
+		what := fmt.Sprintf("tag rule %d", i+1)
+		if len(tr.Tags) == 0 {
+			return nil, fmt.Errorf("%s has no tags", what)
+		}
+		if tr.Pattern == "" && tr.Category == "" && tr.Card == "" {
+			return nil, errors.New(what + " needs a pattern, a category or a card")
+		}
+		if err := checkCard(tr.Card, what); err != nil {
+			return nil, err
+		}
+		rule := tagRule{category: tr.Category, tags: tr.Tags, card: tr.Card}
+		if tr.Pattern != "" {
+			pattern, err := regexp.Compile("(?i)" + tr.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern for %s: %w", what, err)
+			}
+			rule.pattern = pattern
+		}
+		amount, err := newAmountCondition(tr.MinAmount, tr.MaxAmount, tr.Direction, what)
+		if err != nil {
+			return nil, err
+		}
+		rule.amount = amount
+		t.rules = append(t.rules, rule)
+	}
+	return t, nil
+}
+
+// Tags returns the tags the rules give t, in rule order without repeats
+func (t *Tagger) Tags(txn transaction.Transaction) []string {
+	var tagged transaction.Transaction
+	for _, rule := range t.rules {
+		if rule.matches(txn) {
+			for _, tag := range rule.tags {
+				tagged.AddTag(tag)
+			}
+		}
+	}
+	return tagged.Tags
+}
+
+// Apply adds the matching rules' tags to each transaction, keeping the tags
+// it already has
+func (t *Tagger) Apply(txns []transaction.Transaction) {
+	for i := range txns {
+		for _, tag := range t.Tags(txns[i]) {
+			txns[i].AddTag(tag)
+		}
+	}
+}
+
+func (r tagRule) matches(t transaction.Transaction) bool {
+	if r.category != "" && !strings.EqualFold(r.category, t.Category) {
+		return false
+	}
+	if r.pattern != nil && !r.pattern.MatchString(t.Description) {
+		return false
+	}
+	return r.amount.applies(t) && paidWith(r.card, t)
+}
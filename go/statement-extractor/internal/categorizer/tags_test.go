@@ -0,0 +1,41 @@
+package categorizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestTagger(t *testing.T) {
+	tagger, err := NewTagger([]config.TagRule{
+		{Pattern: "NETFLIX|SPOTIFY", Tags: []string{"subscription"}},
+		{Category: "Office", Tags: []string{"work", "tax-deductible"}},
+		{Pattern: "OFFICEWORKS", Category: "office", MinAmount: 300, Tags: []string{"depreciable", "work"}},
+	})
+	require.NoError(t, err)
+
+	txns := []transaction.Transaction{
+		{Description: "NETFLIX.COM", Amount: -1699, Category: "Entertainment"},
+		{Description: "OFFICEWORKS 0421", Amount: -4500, Category: "Office"},
+		{Description: "OFFICEWORKS 0421", Amount: -89900, Category: "Office", Tags: []string{"Work"}},
+		{Description: "WOOLWORTHS", Amount: -8000, Category: "Groceries"},
+	}
+	tagger.Apply(txns)
+	assert.Equal(t, []string{"subscription"}, txns[0].Tags)
+	assert.Equal(t, []string{"work", "tax-deductible"}, txns[1].Tags)
+	assert.Equal(t, []string{"Work", "tax-deductible", "depreciable"}, txns[2].Tags, "existing tags are kept and not repeated")
+	assert.Nil(t, txns[3].Tags)
+
+	_, err = NewTagger([]config.TagRule{{Pattern: "X"}})
+	assert.EqualError(t, err, "tag rule 1 has no tags")
+	_, err = NewTagger([]config.TagRule{{Tags: []string{"x"}}})
+	assert.EqualError(t, err, "tag rule 1 needs a pattern, a category or a card")
+	_, err = NewTagger([]config.TagRule{{Pattern: "(", Tags: []string{"x"}}})
+	assert.ErrorContains(t, err, "invalid pattern for tag rule 1")
+	_, err = NewTagger([]config.TagRule{{Pattern: "X", Tags: []string{"x"}, Direction: "sideways"}})
+	assert.ErrorContains(t, err, `invalid direction "sideways" for tag rule 1`)
+}
@@ -0,0 +1,67 @@
+// Package clock is the source of the current time for timestamps such as
+// ProcessedAt and for time-based expiry, so that tests and reproducible runs
+// can fix it instead of reading the wall clock.
+package clock
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// EpochEnv is the environment variable that fixes the clock of a run, in
+// seconds since the Unix epoch, following the reproducible builds
+// convention
+const EpochEnv = "SOURCE_DATE_EPOCH"
+
+// Clock tells the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// Func adapts a function, such as time.Now, to a Clock
+type Func func() time.Time
+
+// Now implements Clock
+func (f Func) Now() time.Time {
+	return f()
+}
+
+// System is the wall clock
+var System Clock = Func(time.Now)
+
+// Fixed returns a clock stopped at t
+func Fixed(t time.Time) Clock {
+	return Func(func() time.Time { return t })
+}
+
+// FromEnv returns a clock fixed at EpochEnv when it is set, and System
+// otherwise
+func FromEnv() (Clock, error) {
+	epoch := os.Getenv(EpochEnv)
+	if epoch == "" {
+		return System, nil
+	}
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: expected seconds since the Unix epoch", EpochEnv, epoch)
+	}
+	return Fixed(time.Unix(seconds, 0).UTC()), nil
+}
+
+type contextKey struct{}
+
+// With returns a copy of ctx carrying c
+func With(ctx context.Context, c Clock) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// From returns the clock carried by ctx, or System when it carries none
+func From(ctx context.Context) Clock {
+	if c, ok := ctx.Value(contextKey{}).(Clock); ok {
+		return c
+	}
+	return System
+}
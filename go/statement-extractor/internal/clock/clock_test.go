@@ -0,0 +1,35 @@
+package clock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv(EpochEnv, "")
+	c, err := FromEnv()
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), c.Now(), time.Minute)
+
+	t.Setenv(EpochEnv, "1704067200")
+	c, err = FromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), c.Now())
+
+	t.Setenv(EpochEnv, "2024-01-01")
+	_, err = FromEnv()
+	assert.ErrorContains(t, err, "invalid SOURCE_DATE_EPOCH")
+}
+
+func TestContext(t *testing.T) {
+	assert.WithinDuration(t, time.Now(), From(context.Background()).Now(), time.Minute, "the system clock by default")
+
+	fixed := time.Date(2024, 3, 15, 9, 30, 0, 0, time.UTC)
+	ctx := With(context.Background(), Fixed(fixed))
+	assert.Equal(t, fixed, From(ctx).Now())
+	assert.Equal(t, fixed, From(ctx).Now(), "a fixed clock does not move")
+}
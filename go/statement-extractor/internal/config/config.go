@@ -2,53 +2,715 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 // Config represents the application configuration
 type Config struct {
-	DefaultCategory string                    `mapstructure:"default_category"`
-	Parsers         map[string]ParserConfig   `mapstructure:"parsers"`
-	PDFServices     map[string]ServiceConfig  `mapstructure:"pdf_services"`
-	Categories      []CategoryRule            `mapstructure:"categories"`
+	ConfigVersion   int                      `mapstructure:"config_version"`
+	StrictConfig    bool                     `mapstructure:"strict_config"` // reject unknown keys
+	DefaultCategory string                   `mapstructure:"default_category"`
+	Overlays        []string                 `mapstructure:"overlays"`
+	StateDir        string                   `mapstructure:"state_dir"`          // local state such as provider health; default: the user cache directory
+	Database        string                   `mapstructure:"database"`           // SQLite transaction store for list, sum and search when --db is not given
+	Retention       []RetentionRule          `mapstructure:"retention"`          // how long store purge keeps transactions in the database
+	MaxTokensPerRun int                      `mapstructure:"max_tokens_per_run"` // PDF service tokens one run may spend; 0 is unlimited
+	MaxCostPerRun   float64                  `mapstructure:"max_cost_per_run"`   // PDF service cost one run may spend; 0 is unlimited
+	Parsers         map[string]ParserConfig  `mapstructure:"parsers"`
+	PDFServices     map[string]ServiceConfig `mapstructure:"pdf_services"`
+	Categories      []CategoryRule           `mapstructure:"categories"`
+	Categorizer     CategorizerConfig        `mapstructure:"categorizer"`
+	TagRules        []TagRule                `mapstructure:"tag_rules"`
+	SplitRules      []SplitRule              `mapstructure:"split_rules"`
+	Merchants       MerchantConfig           `mapstructure:"merchants"`
+	Transforms      []TransformRule          `mapstructure:"transforms"`
+	Owners          []OwnerRule              `mapstructure:"owners"`
+	Groups          []CategoryGroup          `mapstructure:"groups"`
+	FI              FIConfig                 `mapstructure:"fi"`
+	Alerts          []AlertRule              `mapstructure:"alerts"`
+	Notify          NotifyConfig             `mapstructure:"notify"`
+	Theme           ThemeConfig              `mapstructure:"theme"`
+	Money           MoneyConfig              `mapstructure:"money"`
+	FX              FXConfig                 `mapstructure:"fx"`
+	Locale          LocaleConfig             `mapstructure:"locale"`
+	Export          ExportConfig             `mapstructure:"export"`
+	Quarantine      QuarantineConfig         `mapstructure:"quarantine"`
+	Cache           CacheConfig              `mapstructure:"cache"`
+	MQTT            MQTTConfig               `mapstructure:"mqtt"`
+	Server          ServerConfig             `mapstructure:"server"`
+	Watch           WatchConfig              `mapstructure:"watch"`
 }
 
 // ParserConfig defines how to parse different bank statements
 type ParserConfig struct {
-	Method   string `mapstructure:"method"`   // "content" or "pdf"
-	Provider string `mapstructure:"provider"` // PDF service provider name
+	Method     string    `mapstructure:"method"`      // "content", "local", "pdf", "csv" or "qif"
+	Provider   string    `mapstructure:"provider"`    // PDF service provider name
+	Fallback   []string  `mapstructure:"fallback"`    // providers tried in order when the provider fails or is skipped
+	CSV        CSVConfig `mapstructure:"csv"`         // column mapping for method "csv"
+	DateFormat string    `mapstructure:"date_format"` // Go layout for method "qif", e.g. "01/02/2006"; default day-first
+
+	// Defaults stamped on every transaction from this parser that does not
+	// already carry a value
+	Currency    string `mapstructure:"currency"`     // ISO 4217 code, e.g. "AUD"
+	Account     string `mapstructure:"account"`      // account name, e.g. "Everyday"
+	AccountType string `mapstructure:"account_type"` // e.g. "transaction", "savings" or "credit"
+
+	// Prompt is sent with the document to the provider, as the instructions
+	// for its model. It is a Go text/template given .Name, .Currency,
+	// .Account and .AccountType; default: the service's own prompt.
+	Prompt string `mapstructure:"prompt"`
+
+	// PostProcess steps are applied in order to every extracted row
+	PostProcess []PostProcessStep `mapstructure:"post_process"`
+}
+
+// CSVConfig maps the columns of a bank's CSV export onto transaction fields.
+// Columns are header names, matched case-insensitively, or 1-based column
+// numbers when the export has no header row.
+type CSVConfig struct {
+	Date        string `mapstructure:"date"`
+	Description string `mapstructure:"description"`
+	Amount      string `mapstructure:"amount"` // signed amount
+	Debit       string `mapstructure:"debit"`  // money out, when amounts are split
+	Credit      string `mapstructure:"credit"` // money in, when amounts are split
+	Balance     string `mapstructure:"balance"`
+	DateFormat  string `mapstructure:"date_format"` // Go layout, e.g. "02/01/2006"
+	NoHeader    bool   `mapstructure:"no_header"`
+}
+
+// PostProcessStep rewrites one field of an extracted row using Go
+// text/template syntax, e.g. {{ trimPrefix "VISA " .Description }}
+type PostProcessStep struct {
+	When     string `mapstructure:"when"`     // optional template; the step runs only when it renders "true"
+	Field    string `mapstructure:"field"`    // description, amount, balance, category or date
+	Template string `mapstructure:"template"` // the field's new value
 }
 
 // ServiceConfig defines PDF service provider settings
 type ServiceConfig struct {
-	APIKeyEnv string `mapstructure:"api_key_env"`
-	BaseURL   string `mapstructure:"base_url"`
-	Model     string `mapstructure:"model"`
+	APIKeyEnv string     `mapstructure:"api_key_env"`
+	BaseURL   string     `mapstructure:"base_url"`
+	Model     string     `mapstructure:"model"`
+	HTTP      HTTPConfig `mapstructure:"http"`
+
+	// CostPerMillionTokens prices calls for max_cost_per_run when the
+	// service reports tokens but no cost
+	CostPerMillionTokens float64 `mapstructure:"cost_per_million_tokens"`
+
+	// StructuredOutput sends the service the JSON schema of the transactions
+	// for it to enforce, where its model supports constrained output
+	StructuredOutput bool `mapstructure:"structured_output"`
+
+	// RequestsPerMinute spaces out calls to the service, shared by every
+	// statement of an extract run; 0 is unlimited
+	RequestsPerMinute float64 `mapstructure:"requests_per_minute"`
+}
+
+// HTTPConfig tunes the HTTP client for a service, for use behind a proxy or
+// with a gateway that has a private CA or requires client certificates
+type HTTPConfig struct {
+	Proxy          string        `mapstructure:"proxy"`           // proxy URL; default: HTTPS_PROXY, HTTP_PROXY and NO_PROXY
+	CABundle       string        `mapstructure:"ca_bundle"`       // PEM file of CAs trusted in addition to the system ones
+	ClientCert     string        `mapstructure:"client_cert"`     // PEM certificate for mutual TLS
+	ClientKey      string        `mapstructure:"client_key"`      // PEM private key of client_cert
+	Timeout        time.Duration `mapstructure:"timeout"`         // whole request, e.g. "2m"; default 30s
+	ConnectTimeout time.Duration `mapstructure:"connect_timeout"` // connecting and the TLS handshake, e.g. "10s"
 }
 
 // CategoryRule defines a transaction categorization rule
 type CategoryRule struct {
-	Pattern  string `mapstructure:"pattern"`
-	Category string `mapstructure:"category"`
-}
-
-// LoadConfig loads configuration from file and environment variables
-func LoadConfig(configPath string) (*Config, error) {
-	viper.SetConfigFile(configPath)
-	viper.SetConfigType("toml")
-	
-	// Set defaults
-	viper.SetDefault("default_category", "Uncategorized")
-	
-	if err := viper.ReadInConfig(); err != nil {
+	ID                 string `mapstructure:"id"` // names the rule in categorized_by; default the pattern
+	Pattern            string `mapstructure:"pattern"`
+	Category           string `mapstructure:"category"`
+	Priority           int    `mapstructure:"priority"`             // higher is tried first; equal priorities keep file order
+	ExcludeFromReports bool   `mapstructure:"exclude_from_reports"` // mark matches as report outliers
+	AmortizeMonths     int    `mapstructure:"amortize_months"`      // spread matches over this many months with --amortize
+
+	// Optional conditions on the amount. The bounds apply to its size, so a
+	// rule with min_amount = 1000 matches both -1500 and 1500; direction
+	// narrows it to money out ("debit") or in ("credit").
+	MinAmount float64 `mapstructure:"min_amount"`
+	MaxAmount float64 `mapstructure:"max_amount"` // 0 is unbounded
+	Direction string  `mapstructure:"direction"`
+
+	// Card limits the rule to transactions paid with the card whose number
+	// ends in these digits
+	Card string `mapstructure:"card"`
+}
+
+// TagRule adds tags to the transactions it matches. Every matching rule
+// applies, so a transaction can collect tags from several rules. A rule
+// needs a pattern, a category, a card or a combination; the amount and card
+// conditions are those of CategoryRule.
+type TagRule struct {
+	Pattern   string   `mapstructure:"pattern"`  // case-insensitive regular expression on the description
+	Category  string   `mapstructure:"category"` // only transactions in this category, case-insensitive
+	Tags      []string `mapstructure:"tags"`
+	MinAmount float64  `mapstructure:"min_amount"`
+	MaxAmount float64  `mapstructure:"max_amount"`
+	Direction string   `mapstructure:"direction"`
+	Card      string   `mapstructure:"card"`
+}
+
+// SplitRule splits the transactions it matches between categories by
+// percentage, e.g. a supermarket purchase 70% Groceries and 30% Household.
+// Like a TagRule it needs a pattern, a category or both; the first matching
+// rule applies.
+type SplitRule struct {
+	Pattern  string            `mapstructure:"pattern"`  // case-insensitive regular expression on the description
+	Category string            `mapstructure:"category"` // only transactions in this category, case-insensitive
+	Splits   []SplitAllocation `mapstructure:"splits"`
+}
+
+// SplitAllocation is one category's share of a split; the percentages of a
+// rule add up to 100
+type SplitAllocation struct {
+	Category string  `mapstructure:"category"`
+	Percent  float64 `mapstructure:"percent"`
+}
+
+// TransformRule is a script run against every extracted transaction after
+// categorization, for rules the declarative schema cannot express
+type TransformRule struct {
+	Name      string `mapstructure:"name"`      // for errors and categorized_by; default the rule's position
+	Transform string `mapstructure:"transform"` // e.g. "amount < 0 && contains(desc, 'FEE') ? set_category('Bank fees') : skip()"
+}
+
+// MerchantConfig sets how merchant names are cleaned from descriptions.
+// Rules are tried in order first; descriptions no rule matches go through
+// the built-in cleaners.
+type MerchantConfig struct {
+	Cleaners []string       `mapstructure:"cleaners"` // "processor", "location" and "card"; default all, "none" for none
+	Rules    []MerchantRule `mapstructure:"rules"`
+}
+
+// MerchantRule names the merchant of the descriptions it matches
+type MerchantRule struct {
+	Pattern  string `mapstructure:"pattern"`  // case-insensitive regular expression on the description
+	Merchant string `mapstructure:"merchant"` // may refer to submatches as $1
+}
+
+// OwnerRule attributes transactions to a household member: those of the
+// accounts, and those paid with a card ending in one of the card suffixes.
+// Card suffixes win over accounts, so a shared account can be split by card.
+type OwnerRule struct {
+	Name         string   `mapstructure:"name"`
+	Accounts     []string `mapstructure:"accounts"`      // matched case-insensitively
+	CardSuffixes []string `mapstructure:"card_suffixes"` // last digits of the card number, e.g. "4521"
+}
+
+// CategoryGroup collects categories into a reporting group such as
+// "Essentials", used by reports run with --group-by group
+type CategoryGroup struct {
+	Name       string   `mapstructure:"name"`
+	Categories []string `mapstructure:"categories"` // matched case-insensitively
+}
+
+// CategorizerConfig orders the categorization strategies. Each stage is
+// tried in turn and the first result at or above its threshold wins;
+// transactions no stage accepts get the default category.
+type CategorizerConfig struct {
+	Stages    []CategorizerStage  `mapstructure:"stages"`
+	RuleMatch string              `mapstructure:"rule_match"` // "first" (default) or "most-specific"
+	Merchants MerchantTableConfig `mapstructure:"merchants"`
+	LLM       LLMConfig           `mapstructure:"llm"`
+	Embedding EmbeddingConfig     `mapstructure:"embedding"`
+}
+
+// MerchantTableConfig adapts the built-in merchant table of the "merchants"
+// stage to your categories
+type MerchantTableConfig struct {
+	// Categories renames the table's categories, e.g. "Food & dining" =
+	// "Eating out"; renaming one to "" stops the table assigning it
+	Categories map[string]string `mapstructure:"categories"`
+}
+
+// LLMConfig configures the "llm" categorizer stage, which asks a model behind
+// an OpenAI-compatible chat completions endpoint to categorize what earlier
+// stages leave, and which extract --llm-categorize adds to the chain
+type LLMConfig struct {
+	BaseURL   string     `mapstructure:"base_url"` // e.g. "https://api.openai.com/v1"
+	Model     string     `mapstructure:"model"`
+	APIKeyEnv string     `mapstructure:"api_key_env"`
+	HTTP      HTTPConfig `mapstructure:"http"`
+
+	BatchSize  int      `mapstructure:"batch_size"` // descriptions per request; default 25
+	Threshold  float64  `mapstructure:"threshold"`  // stage threshold when added by --llm-categorize
+	Categories []string `mapstructure:"categories"` // offered besides the [[categories]] ones
+
+	// Prompt replaces the built-in system prompt. It is a Go text/template
+	// given .Categories, the category names offered; the categories and the
+	// numbered descriptions follow it in the user message, and the answer
+	// must keep the built-in prompt's JSON shape.
+	Prompt string `mapstructure:"prompt"`
+
+	// MaxCost caps the spend of one run; a run is refused without a cap.
+	// Calls are priced from the tokens the endpoint reports.
+	MaxCost              float64 `mapstructure:"max_cost"`
+	CostPerMillionTokens float64 `mapstructure:"cost_per_million_tokens"`
+}
+
+// EmbeddingConfig configures the "embedding" categorizer stage, which
+// embeds descriptions through an OpenAI-compatible embeddings endpoint and
+// takes the category of the nearest labeled transactions in the database
+type EmbeddingConfig struct {
+	BaseURL   string     `mapstructure:"base_url"` // e.g. "https://api.openai.com/v1"
+	Model     string     `mapstructure:"model"`
+	APIKeyEnv string     `mapstructure:"api_key_env"`
+	HTTP      HTTPConfig `mapstructure:"http"`
+
+	BatchSize  int    `mapstructure:"batch_size"` // descriptions per request; default 100
+	Neighbours int    `mapstructure:"neighbours"` // labeled transactions that vote; default 5
+	CacheDir   string `mapstructure:"cache_dir"`  // default: embeddings in the state directory
+}
+
+// CategorizerStage configures one categorization strategy in the chain
+type CategorizerStage struct {
+	Name      string  `mapstructure:"name"`      // strategy, e.g. "rules"
+	Threshold float64 `mapstructure:"threshold"` // minimum confidence from 0 to 1
+}
+
+// FIConfig tunes the financial-independence report
+type FIConfig struct {
+	WithdrawalRate     float64  `mapstructure:"withdrawal_rate"`     // safe withdrawal rate, e.g. 0.04
+	ReturnRate         float64  `mapstructure:"return_rate"`         // expected annual real return for projections
+	IncomeCategories   []string `mapstructure:"income_categories"`   // categories counted as income
+	ExcludedCategories []string `mapstructure:"excluded_categories"` // neither income nor expense, e.g. transfers
+}
+
+// AlertRule flags unusual transactions. Without Count every matching
+// transaction triggers; with Count the rule triggers when more than Count
+// transactions match within the same Window. With MonthlyBudget the rule
+// triggers on the transaction that takes a month's net spending on matching
+// transactions over the budget, and the rule is an envelope in the budget
+// report; Rollover carries what is left of each month's budget, or its
+// overspending, into the next month there.
+type AlertRule struct {
+	Name          string  `mapstructure:"name"`
+	Category      string  `mapstructure:"category"`   // optional, case-insensitive
+	Pattern       string  `mapstructure:"pattern"`    // optional description regex, case-insensitive
+	MinAmount     float64 `mapstructure:"min_amount"` // minimum absolute amount
+	Count         int     `mapstructure:"count"`
+	Window        string  `mapstructure:"window"` // "day", "week" (default) or "month"
+	MonthlyBudget float64 `mapstructure:"monthly_budget"`
+	Rollover      bool    `mapstructure:"rollover"`
+}
+
+// NotifyConfig is where import and watch send the alert triggers new
+// transactions set off
+type NotifyConfig struct {
+	Webhook   string `mapstructure:"webhook"`    // URL each trigger is POSTed to as JSON
+	SecretEnv string `mapstructure:"secret_env"` // environment variable holding the key the body is signed with
+}
+
+// RetentionRule sets how long "store purge" keeps an account's transactions
+// in the database. A rule without an account covers every account that has
+// no rule of its own.
+type RetentionRule struct {
+	Account string `mapstructure:"account"` // case-insensitive; empty for the other accounts
+	Years   int    `mapstructure:"years"`   // transactions dated more than this many years ago are purged
+}
+
+// ThemeConfig sets terminal output colors. Styles are space-separated names
+// such as "red", "bold green" or "dim"; an empty style leaves text plain.
+type ThemeConfig struct {
+	Header     string            `mapstructure:"header"`
+	Negative   string            `mapstructure:"negative"`
+	Positive   string            `mapstructure:"positive"`
+	Warning    string            `mapstructure:"warning"`
+	Categories map[string]string `mapstructure:"categories"` // category name -> style
+}
+
+// MoneyConfig controls how amounts are rounded and displayed
+type MoneyConfig struct {
+	Currency string                 `mapstructure:"currency"` // display currency, e.g. "AUD"
+	Formats  map[string]MoneyFormat `mapstructure:"formats"`  // per-currency overrides of the built-in formats
+}
+
+// FXConfig converts amounts in other currencies to a base currency so
+// reports present one currency. Conversion is on when rates are configured
+// or the source is "ecb".
+type FXConfig struct {
+	Base   string             `mapstructure:"base"`    // default: money.currency
+	Source string             `mapstructure:"source"`  // "static" (default) or "ecb" for the ECB daily reference rates
+	Rates  map[string]float64 `mapstructure:"rates"`   // base units per unit of each currency; with ecb, overrides
+	ECBURL string             `mapstructure:"ecb_url"` // default: the ECB daily reference rates feed
+	HTTP   HTTPConfig         `mapstructure:"http"`
+}
+
+// ExportConfig configures the export file formats
+type ExportConfig struct {
+	CSV       CSVOutputConfig `mapstructure:"csv"`
+	Ledger    LedgerConfig    `mapstructure:"ledger"`
+	Beancount BeancountConfig `mapstructure:"beancount"`
+	HomeBank  HomeBankConfig  `mapstructure:"homebank"`
+	MMEX      MMEXConfig      `mapstructure:"mmex"`
+}
+
+// DescriptionLimit shortens descriptions for targets that limit their
+// length. Where the format has a memo or notes field, a shortened
+// description is kept there in full.
+type DescriptionLimit struct {
+	MaxLength int    `mapstructure:"max_length"` // in characters; 0 is unlimited
+	Policy    string `mapstructure:"policy"`     // "truncate" (default), "ellipsis" or "abbreviate"
+}
+
+// CSVOutputConfig lays out CSV output: a preset ("default" or "ynab") or an
+// explicit list of columns, which takes precedence
+type CSVOutputConfig struct {
+	Preset     string            `mapstructure:"preset"`
+	Columns    []CSVOutputColumn `mapstructure:"columns"`
+	DateFormat string            `mapstructure:"date_format"` // Go layout; default from the preset
+
+	Description DescriptionLimit `mapstructure:"description"`
+}
+
+// CSVOutputColumn is one column of CSV output
+type CSVOutputColumn struct {
+	Field  string `mapstructure:"field"`  // e.g. "date", "description", "outflow"
+	Header string `mapstructure:"header"` // defaults to the field name
+}
+
+// LedgerConfig names the accounts of ledger journal postings. Each name is a
+// Go text/template over the transaction's .Category, .Source, .Account,
+// .AccountType and .Currency.
+type LedgerConfig struct {
+	AssetAccount   string `mapstructure:"asset_account"`   // the statement's account, e.g. "Assets:{{ .Source }}"
+	ExpenseAccount string `mapstructure:"expense_account"` // for money out, e.g. "Expenses:{{ .Category }}"
+	IncomeAccount  string `mapstructure:"income_account"`  // for money in, e.g. "Income:{{ .Category }}"
+
+	Description DescriptionLimit `mapstructure:"description"`
+}
+
+// BeancountConfig names the accounts of Beancount postings, as templates
+// like LedgerConfig's. Rendered names are adjusted to Beancount's account
+// syntax, e.g. "Expenses:eating out" becomes "Expenses:Eating-out".
+type BeancountConfig struct {
+	AssetAccount   string `mapstructure:"asset_account"`
+	ExpenseAccount string `mapstructure:"expense_account"`
+	IncomeAccount  string `mapstructure:"income_account"`
+	Currency       string `mapstructure:"currency"` // for transactions without one
+
+	Description DescriptionLimit `mapstructure:"description"`
+}
+
+// HomeBankConfig configures the HomeBank CSV import file
+type HomeBankConfig struct {
+	Description DescriptionLimit `mapstructure:"description"`
+}
+
+// MMEXConfig configures the Money Manager EX CSV import file
+type MMEXConfig struct {
+	Description DescriptionLimit `mapstructure:"description"`
+}
+
+// MoneyFormat overrides the display format for one currency. Unset fields
+// keep the built-in value for that currency.
+type MoneyFormat struct {
+	Symbol      string  `mapstructure:"symbol"`
+	SymbolAfter *bool   `mapstructure:"symbol_after"` // "12,50 €" rather than "€12,50"
+	Decimals    *int    `mapstructure:"decimals"`
+	Decimal     string  `mapstructure:"decimal"`   // decimal separator
+	Thousands   *string `mapstructure:"thousands"` // grouping separator; "" disables grouping
+	Rounding    string  `mapstructure:"rounding"`  // "half-even", "half-up" or "down"
+	Negative    string  `mapstructure:"negative"`  // "minus" or "parens"
+}
+
+// LocaleConfig sets how dates and numbers are written in tables and reports
+// and the day weekly reports start on
+type LocaleConfig struct {
+	Name           string `mapstructure:"name"`              // e.g. "en-AU", "en-US" or "de-DE"; default ISO dates
+	DateFormat     string `mapstructure:"date_format"`       // Go layout overriding the locale's
+	FirstDayOfWeek string `mapstructure:"first_day_of_week"` // e.g. "sunday", overriding the locale's
+}
+
+// CacheConfig keeps PDF service responses, so extracting a statement again
+// with the same service, model and pages does not call the service
+type CacheConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Backend string `mapstructure:"backend"` // "dir", the default; "redis" is refused
+	Dir     string `mapstructure:"dir"`     // default: responses in the state directory; may be shared
+}
+
+// QuarantineConfig sets aside statements that keep failing in directory
+// runs, so that they stop being retried on every run
+type QuarantineConfig struct {
+	Dir         string `mapstructure:"dir"`          // default: quarantine in the state directory
+	MaxAttempts int    `mapstructure:"max_attempts"` // failed runs before a statement is moved there; 0 never moves it
+}
+
+// MQTTConfig is the broker the publish command sends Home Assistant sensors
+// to
+type MQTTConfig struct {
+	Broker          string `mapstructure:"broker"`           // e.g. "mqtt://homeassistant.local:1883", or mqtts:// for TLS
+	Username        string `mapstructure:"username"`         // optional
+	PasswordEnv     string `mapstructure:"password_env"`     // environment variable holding the password
+	ClientID        string `mapstructure:"client_id"`        // default "statement-extractor"
+	TopicPrefix     string `mapstructure:"topic_prefix"`     // of the sensor state topics; default "statement-extractor"
+	DiscoveryPrefix string `mapstructure:"discovery_prefix"` // Home Assistant's MQTT discovery prefix; default "homeassistant"
+}
+
+// ServerConfig configures the serve command
+type ServerConfig struct {
+	Listen           string        `mapstructure:"listen"`             // host:port; default "127.0.0.1:8080"
+	TokenEnv         string        `mapstructure:"token_env"`          // environment variable holding the bearer token requests must carry
+	QueueSize        int           `mapstructure:"queue_size"`         // uploads waiting to be extracted before more are refused; default 4
+	JobRetention     time.Duration `mapstructure:"job_retention"`      // how long a finished job can be polled; default 1h
+	Webhook          string        `mapstructure:"webhook"`            // URL each finished job is POSTed to
+	WebhookSecretEnv string        `mapstructure:"webhook_secret_env"` // environment variable holding the key the webhook body is signed with
+
+	// RemoteWorkers leaves uploads to "statement-extractor worker"
+	// processes, which lease them from /worker/; a lease not completed
+	// within LeaseTimeout lapses and the upload is offered again
+	RemoteWorkers bool          `mapstructure:"remote_workers"`
+	LeaseTimeout  time.Duration `mapstructure:"lease_timeout"` // default 10m
+}
+
+// WatchConfig configures the watch command's drop folder
+type WatchConfig struct {
+	Dir        string        `mapstructure:"dir"`         // drop folder statements are saved into
+	Output     string        `mapstructure:"output"`      // directory each statement's transactions are written to
+	Archive    string        `mapstructure:"archive"`     // processed statements are moved here; default "processed" in dir
+	Format     string        `mapstructure:"format"`      // output format; default "json"
+	Interval   time.Duration `mapstructure:"interval"`    // between polls of the folder; default 30s
+	RetryAfter time.Duration `mapstructure:"retry_after"` // before a failed statement is tried again unless it changes; default 1h
+
+	// Source is a remote folder statements are pulled from into Dir, chosen
+	// by URL scheme: dav:// or davs:// (also http:// and https://) for
+	// WebDAV, gdrive://<folder ID> for Google Drive and dropbox:///<path>
+	// for Dropbox. Pulled files are moved into its "processed" subfolder.
+	Source         string        `mapstructure:"source"`
+	SourceInterval time.Duration `mapstructure:"source_interval"` // between listings of source; default interval
+	PasswordEnv    string        `mapstructure:"password_env"`    // environment variable holding the password of the user in a WebDAV source
+	TokenEnv       string        `mapstructure:"token_env"`       // environment variable holding an access token for a Google Drive or Dropbox source
+	OAuth          OAuthConfig   `mapstructure:"oauth"`           // refresh token for a Google Drive or Dropbox source, instead of token_env
+	HTTP           HTTPConfig    `mapstructure:"http"`            // proxy, TLS and timeout settings for source
+}
+
+// OAuthConfig is an OAuth client's refresh token, exchanged for access
+// tokens as they expire
+type OAuthConfig struct {
+	ClientID        string `mapstructure:"client_id"`
+	ClientSecretEnv string `mapstructure:"client_secret_env"` // environment variable holding the client secret; unset for a PKCE client
+	RefreshTokenEnv string `mapstructure:"refresh_token_env"` // environment variable holding the refresh token
+}
+
+// searchPaths are checked in order when no config file is given
+var searchPaths = []string{
+	"statement-extractor.toml",
+	"~/.config/statement-extractor/statement-extractor.toml",
+	"/etc/statement-extractor/statement-extractor.toml",
+}
+
+// Discover returns the first config file found in the standard locations,
+// or an empty string if there is none
+func Discover() string {
+	for _, path := range searchPaths {
+		path = resolvePath(".", path)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// Default returns the configuration used when no config file is present
+func Default() *Config {
+	var config Config
+	_ = newViper().Unmarshal(&config)
+	return &config
+}
+
+// newViper returns a viper instance with the built-in defaults applied
+func newViper() *viper.Viper {
+	v := viper.New()
+	v.SetConfigType("toml")
+
+	v.SetDefault("config_version", CurrentVersion)
+	v.SetDefault("default_category", "Uncategorized")
+	v.SetDefault("fi.withdrawal_rate", 0.04)
+	v.SetDefault("fi.return_rate", 0.05)
+	v.SetDefault("fi.income_categories", []string{"Income"})
+	v.SetDefault("fi.excluded_categories", []string{"Transfer"})
+	v.SetDefault("theme.header", "bold")
+	v.SetDefault("theme.negative", "red")
+	v.SetDefault("theme.warning", "yellow")
+	v.SetDefault("money.currency", "AUD")
+	v.SetDefault("export.ledger.asset_account", "Assets:{{ .Source }}")
+	v.SetDefault("export.ledger.expense_account", "Expenses:{{ .Category }}")
+	v.SetDefault("export.ledger.income_account", "Income:{{ .Category }}")
+	v.SetDefault("export.beancount.asset_account", "Assets:{{ .Source }}")
+	v.SetDefault("export.beancount.expense_account", "Expenses:{{ .Category }}")
+	v.SetDefault("export.beancount.income_account", "Income:{{ .Category }}")
+	v.SetDefault("export.beancount.currency", "AUD")
+	v.SetDefault("quarantine.max_attempts", 3)
+	v.SetDefault("mqtt.client_id", "statement-extractor")
+	v.SetDefault("mqtt.topic_prefix", "statement-extractor")
+	v.SetDefault("mqtt.discovery_prefix", "homeassistant")
+	v.SetDefault("server.listen", "127.0.0.1:8080")
+	v.SetDefault("server.queue_size", 4)
+	v.SetDefault("server.job_retention", "1h")
+	v.SetDefault("server.lease_timeout", "10m")
+	v.SetDefault("watch.format", "json")
+	v.SetDefault("watch.interval", "30s")
+	v.SetDefault("watch.retry_after", "1h")
+
+	return v
+}
+
+// LoadConfig loads configuration from file and environment variables, then
+// applies overlays in order: those listed under `overlays` in the base file
+// followed by any passed explicitly.
+func LoadConfig(configPath string, overlays ...string) (*Config, error) {
+	return load(configPath, false, overlays)
+}
+
+// LoadConfigStrict is LoadConfig but fails on keys that do not correspond to
+// any setting, such as a misspelt `patern`. Setting strict_config = true in
+// the file has the same effect with LoadConfig.
+func LoadConfigStrict(configPath string, overlays ...string) (*Config, error) {
+	return load(configPath, true, overlays)
+}
+
+func load(configPath string, strict bool, overlays []string) (*Config, error) {
+	v := newViper()
+	v.SetConfigFile(configPath)
+
+	if err := v.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
-	
+	v, err := migrate(v, configPath, newViper)
+	if err != nil {
+		return nil, err
+	}
+
+	// Overlays named in the base file are optional so a shared config can
+	// reference a per-user file that not every household member has
+	baseDir := filepath.Dir(configPath)
+	for _, overlay := range v.GetStringSlice("overlays") {
+		path := resolvePath(baseDir, overlay)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		if v, err = applyOverlay(v, path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, overlay := range overlays {
+		if v, err = applyOverlay(v, overlay); err != nil {
+			return nil, err
+		}
+	}
+
 	var config Config
-	if err := viper.Unmarshal(&config); err != nil {
+	if strict || v.GetBool("strict_config") {
+		if err := v.UnmarshalExact(&config); err != nil {
+			return nil, fmt.Errorf("unknown config keys: %w", err)
+		}
+		return &config, nil
+	}
+	if err := v.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
-	
+
 	return &config, nil
-}
\ No newline at end of file
+}
+
+// applyOverlay returns v with the overlay file at path merged in. Tables are
+// merged key by key with overlay values winning, except that parser and
+// service entries replace the base entry of the same name. Overlay category
+// rules are evaluated before the base rules, replacing any base rule with an
+// identical pattern.
+func applyOverlay(v *viper.Viper, path string) (*viper.Viper, error) {
+	ov := viper.New()
+	ov.SetConfigFile(path)
+	ov.SetConfigType("toml")
+
+	if err := ov.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config overlay %s: %w", path, err)
+	}
+	ov, err := migrate(ov, path, viper.New)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := v.AllSettings()
+	overlay := ov.AllSettings()
+	for _, key := range []string{"parsers", "pdf_services"} {
+		entries, _ := settings[key].(map[string]any)
+		for name := range ov.GetStringMap(key) {
+			delete(entries, name)
+		}
+	}
+	if categories := mergeRules(overlay["categories"], settings["categories"]); categories != nil {
+		settings["categories"] = categories
+		delete(overlay, "categories")
+	}
+
+	merged := newViper()
+	if err := merged.MergeConfigMap(settings); err != nil {
+		return nil, fmt.Errorf("failed to merge config overlay %s: %w", path, err)
+	}
+	if err := merged.MergeConfigMap(overlay); err != nil {
+		return nil, fmt.Errorf("failed to merge config overlay %s: %w", path, err)
+	}
+	return merged, nil
+}
+
+// mergeRules returns the overlay rules followed by the base rules whose
+// pattern the overlay does not redefine
+func mergeRules(overlay, base any) []any {
+	overlayRules, _ := overlay.([]any)
+	baseRules, _ := base.([]any)
+	if len(overlayRules) == 0 {
+		return nil
+	}
+
+	overridden := make(map[any]bool, len(overlayRules))
+	for _, rule := range overlayRules {
+		if m, ok := rule.(map[string]any); ok {
+			overridden[m["pattern"]] = true
+		}
+	}
+
+	merged := append([]any{}, overlayRules...)
+	for _, rule := range baseRules {
+		if m, ok := rule.(map[string]any); ok && overridden[m["pattern"]] {
+			continue
+		}
+		merged = append(merged, rule)
+	}
+	return merged
+}
+
+// StateDirectory returns the directory for local state such as provider
+// health: state_dir, or statement-extractor in the user cache directory
+func (c *Config) StateDirectory() (string, error) {
+	if c.StateDir != "" {
+		return c.StateDir, nil
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find a state directory; set state_dir: %w", err)
+	}
+	return filepath.Join(cache, "statement-extractor"), nil
+}
+
+// resolvePath expands a leading ~ and makes relative paths relative to dir
+func resolvePath(dir, path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
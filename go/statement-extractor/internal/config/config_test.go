@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -32,30 +33,30 @@ category = "Test"
 
 	tmpDir := t.TempDir()
 	configPath := filepath.Join(tmpDir, "test-config.toml")
-	
+
 	err := os.WriteFile(configPath, []byte(configContent), 0644)
 	require.NoError(t, err)
-	
+
 	// Load the config
 	config, err := LoadConfig(configPath)
 	require.NoError(t, err)
-	
+
 	// Verify config values
 	assert.Equal(t, "Test Category", config.DefaultCategory)
-	
+
 	// Check parser config
 	assert.Contains(t, config.Parsers, "test_bank")
 	parser := config.Parsers["test_bank"]
 	assert.Equal(t, "pdf", parser.Method)
 	assert.Equal(t, "test-service", parser.Provider)
-	
+
 	// Check PDF service config
 	assert.Contains(t, config.PDFServices, "test-service")
 	service := config.PDFServices["test-service"]
 	assert.Equal(t, "TEST_API_KEY", service.APIKeyEnv)
 	assert.Equal(t, "https://test.example.com", service.BaseURL)
 	assert.Equal(t, "test-model", service.Model)
-	
+
 	// Check categories
 	assert.Len(t, config.Categories, 1)
 	assert.Equal(t, "TEST.*PATTERN", config.Categories[0].Pattern)
@@ -67,4 +68,134 @@ func TestLoadConfig_InvalidFile(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, config)
 	assert.Contains(t, err.Error(), "failed to read config file")
-}
\ No newline at end of file
+}
+
+func TestLoadConfig_Overlays(t *testing.T) {
+	base := `
+default_category = "Uncategorized"
+overlays = ["household.local.toml", "missing.local.toml"]
+
+[parsers]
+  [parsers.cba]
+  method = "pdf"
+  provider = "shared-service"
+
+[[categories]]
+pattern = "NETFLIX"
+category = "Entertainment"
+
+[[categories]]
+pattern = "WOOLWORTHS"
+category = "Groceries & household"
+`
+	local := `
+[parsers]
+  [parsers.cba]
+  method = "content"
+
+[[categories]]
+pattern = "NETFLIX"
+category = "Shared subscriptions"
+`
+	explicit := `
+default_category = "Review"
+
+[[categories]]
+pattern = "BUNNINGS"
+category = "Home & renovation"
+`
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "statement-extractor.toml")
+	require.NoError(t, os.WriteFile(configPath, []byte(base), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "household.local.toml"), []byte(local), 0644))
+	explicitPath := filepath.Join(tmpDir, "me.toml")
+	require.NoError(t, os.WriteFile(explicitPath, []byte(explicit), 0644))
+
+	config, err := LoadConfig(configPath, explicitPath)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Review", config.DefaultCategory)
+	assert.Equal(t, ParserConfig{Method: "content"}, config.Parsers["cba"])
+	assert.Equal(t, []CategoryRule{
+		{Pattern: "BUNNINGS", Category: "Home & renovation"},
+		{Pattern: "NETFLIX", Category: "Shared subscriptions"},
+		{Pattern: "WOOLWORTHS", Category: "Groceries & household"},
+	}, config.Categories)
+}
+
+func TestLoadConfig_OverlayKeepsBaseDefault(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "base.toml")
+	overlayPath := filepath.Join(tmpDir, "overlay.toml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`default_category = "Misc"`), 0644))
+	require.NoError(t, os.WriteFile(overlayPath, []byte("[[categories]]\npattern = \"X\"\ncategory = \"Y\"\n"), 0644))
+
+	config, err := LoadConfig(configPath, overlayPath)
+	require.NoError(t, err)
+	assert.Equal(t, "Misc", config.DefaultCategory)
+	assert.Len(t, config.Categories, 1)
+}
+
+func TestLoadConfig_MissingExplicitOverlay(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "base.toml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`default_category = "Misc"`), 0644))
+
+	_, err := LoadConfig(configPath, filepath.Join(tmpDir, "nope.toml"))
+	assert.ErrorContains(t, err, "failed to read config overlay")
+}
+
+func TestLoadConfig_Strict(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "typo.toml")
+	require.NoError(t, os.WriteFile(configPath, []byte("[[categories]]\npatern = \"X\"\ncategory = \"Y\"\n"), 0644))
+
+	config, err := LoadConfig(configPath)
+	require.NoError(t, err, "unknown keys are ignored by default")
+	assert.Empty(t, config.Categories[0].Pattern)
+
+	_, err = LoadConfigStrict(configPath)
+	assert.ErrorContains(t, err, "patern")
+
+	strictPath := filepath.Join(tmpDir, "strict.toml")
+	require.NoError(t, os.WriteFile(strictPath, []byte("strict_config = true\ndefualt_category = \"Misc\"\n"), 0644))
+	_, err = LoadConfig(strictPath)
+	assert.ErrorContains(t, err, "defualt_category")
+
+	_, err = LoadConfigStrict(filepath.Join("..", "..", "configs", "statement-extractor.toml"))
+	assert.NoError(t, err, "the example config only uses known keys")
+}
+
+func TestLoadConfig_ServiceHTTP(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "http.toml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+[pdf_services.gateway]
+base_url = "https://llm.corp.example"
+[pdf_services.gateway.http]
+proxy = "http://proxy:3128"
+ca_bundle = "/etc/ssl/private-ca.pem"
+timeout = "2m"
+connect_timeout = "10s"
+`), 0644))
+
+	config, err := LoadConfigStrict(configPath)
+	require.NoError(t, err)
+	http := config.PDFServices["gateway"].HTTP
+	assert.Equal(t, "http://proxy:3128", http.Proxy)
+	assert.Equal(t, "/etc/ssl/private-ca.pem", http.CABundle)
+	assert.Equal(t, 2*time.Minute, http.Timeout)
+	assert.Equal(t, 10*time.Second, http.ConnectTimeout)
+}
+
+func TestDefault(t *testing.T) {
+	config := Default()
+	assert.Equal(t, "Uncategorized", config.DefaultCategory)
+	assert.Equal(t, 0.04, config.FI.WithdrawalRate)
+	assert.Equal(t, []string{"Income"}, config.FI.IncomeCategories)
+	assert.Equal(t, []string{"Transfer"}, config.FI.ExcludedCategories)
+	assert.Equal(t, 3, config.Quarantine.MaxAttempts)
+	assert.Equal(t, "127.0.0.1:8080", config.Server.Listen)
+	assert.Equal(t, 30*time.Second, config.Watch.Interval)
+	assert.Equal(t, time.Hour, config.Watch.RetryAfter)
+}
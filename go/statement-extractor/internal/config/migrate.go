@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// CurrentVersion is the config layout this build understands. Files without
+// config_version are read as version 1, the layout that predates versioning.
+// No layout has changed since, so this is only the framework: version 1
+// files need no migration, and the first change to the layout bumps
+// CurrentVersion and adds its step to migrations.
+const CurrentVersion = 1
+
+// migrations[n] rewrites raw version n settings into the version n+1 layout;
+// empty while there is only version 1
+var migrations = map[int]func(settings map[string]any) error{}
+
+// migrate checks the config_version of the file loaded into v and upgrades
+// older layouts to CurrentVersion, returning a viper instance holding the
+// upgraded settings
+func migrate(v *viper.Viper, path string, fresh func() *viper.Viper) (*viper.Viper, error) {
+	version := 1
+	if v.InConfig("config_version") {
+		version = v.GetInt("config_version")
+	}
+	switch {
+	case version > CurrentVersion:
+		return nil, fmt.Errorf("%s: config_version %d is newer than this build supports (%d); upgrade statement-extractor", path, version, CurrentVersion)
+	case version < 1:
+		return nil, fmt.Errorf("%s: invalid config_version %d", path, version)
+	case version == CurrentVersion:
+		return v, nil
+	}
+
+	settings := v.AllSettings()
+	if err := upgrade(settings, version, CurrentVersion); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	settings["config_version"] = CurrentVersion
+
+	upgraded := fresh()
+	if err := upgraded.MergeConfigMap(settings); err != nil {
+		return nil, fmt.Errorf("%s: failed to apply migrated config: %w", path, err)
+	}
+	return upgraded, nil
+}
+
+// upgrade applies the migrations from version from up to version to
+func upgrade(settings map[string]any, from, to int) error {
+	for version := from; version < to; version++ {
+		step, ok := migrations[version]
+		if !ok {
+			return fmt.Errorf("no migration from config_version %d", version)
+		}
+		if err := step(settings); err != nil {
+			return fmt.Errorf("migrating config_version %d to %d: %w", version, version+1, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig_Version(t *testing.T) {
+	tmpDir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(tmpDir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	config, err := LoadConfig(write("unversioned.toml", `default_category = "Misc"`))
+	require.NoError(t, err)
+	assert.Equal(t, CurrentVersion, config.ConfigVersion)
+
+	_, err = LoadConfig(write("future.toml", fmt.Sprintf("config_version = %d\n", CurrentVersion+1)))
+	assert.ErrorContains(t, err, "is newer than this build supports")
+
+	_, err = LoadConfig(write("zero.toml", "config_version = 0\n"))
+	assert.ErrorContains(t, err, "invalid config_version 0")
+
+	base := write("base.toml", "config_version = 1\n")
+	_, err = LoadConfig(base, write("overlay.toml", fmt.Sprintf("config_version = %d\n", CurrentVersion+1)))
+	assert.ErrorContains(t, err, "overlay.toml: config_version")
+}
+
+func TestUpgrade(t *testing.T) {
+	defer func(saved map[int]func(map[string]any) error) { migrations = saved }(migrations)
+	migrations = map[int]func(map[string]any) error{
+		1: func(s map[string]any) error {
+			s["default_category"] = s["fallback_category"]
+			delete(s, "fallback_category")
+			return nil
+		},
+		2: func(s map[string]any) error {
+			s["upgraded"] = true
+			return nil
+		},
+	}
+
+	settings := map[string]any{"fallback_category": "Misc"}
+	require.NoError(t, upgrade(settings, 1, 3))
+	assert.Equal(t, map[string]any{"default_category": "Misc", "upgraded": true}, settings)
+
+	assert.EqualError(t, upgrade(settings, 3, 4), "no migration from config_version 3")
+}
@@ -0,0 +1,215 @@
+// Package dedup finds incoming transactions that are already in a history
+// and merges the two using an explicit strategy for each duplicate.
+package dedup
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Strategy decides what happens to an incoming transaction that duplicates
+// one already in the history
+type Strategy string
+
+const (
+	Skip     Strategy = "skip"      // keep the existing transaction and drop the incoming one
+	Replace  Strategy = "replace"   // overwrite the existing transaction with the incoming one
+	KeepBoth Strategy = "keep-both" // keep both, marking the incoming one with a suffix
+)
+
+// Strategies lists the strategies in the order they are offered
+var Strategies = []Strategy{Skip, Replace, KeepBoth}
+
+// ParseStrategy parses a strategy name
+func ParseStrategy(s string) (Strategy, error) {
+	for _, strategy := range Strategies {
+		if strings.EqualFold(s, string(strategy)) {
+			return strategy, nil
+		}
+	}
+	return "", fmt.Errorf("unknown duplicate strategy %q (expected skip, replace or keep-both)", s)
+}
+
+// Duplicate pairs an incoming transaction with the existing one it matches
+type Duplicate struct {
+	Incoming int  // index into the incoming transactions
+	Existing int  // index into the existing transactions
+	Near     bool // matched within a Tolerance rather than exactly
+}
+
+// Tolerance bounds how far a near duplicate may drift from the transaction
+// it matches, as when a CSV download and a PDF statement post the same
+// purchase on different days
+type Tolerance = transaction.Tolerance
+
+// DefaultTolerance allows for posting dates two days apart and rounding in
+// the cents
+var DefaultTolerance = Tolerance{Days: 2, Amount: 0.05}
+
+// Find returns the incoming transactions that match an existing one: by ID
+// when both have one, otherwise by transaction.FieldKey, so a history without
+// IDs still matches statements extracted with them. Each existing
+// transaction matches at most once, so two identical coffees imported
+// against a history holding one are reported as one duplicate and one new
+// transaction.
+func Find(existing, incoming []transaction.Transaction) []Duplicate {
+	byID := make(map[string][]int)
+	byFields := make(map[string][]int)
+	for i, t := range existing {
+		if t.ID != "" {
+			byID[t.ID] = append(byID[t.ID], i)
+		}
+		key := transaction.FieldKey(t)
+		byFields[key] = append(byFields[key], i)
+	}
+
+	// IDs are matched first, so that a field match cannot claim an existing
+	// transaction whose ID a later incoming one carries
+	matched := make(map[int]bool)
+	found := make(map[int]int)
+	for i, t := range incoming {
+		if t.ID == "" {
+			continue
+		}
+		for _, j := range byID[t.ID] {
+			if !matched[j] {
+				matched[j], found[i] = true, j
+				break
+			}
+		}
+	}
+	for i, t := range incoming {
+		if _, ok := found[i]; ok {
+			continue
+		}
+		for _, j := range byFields[transaction.FieldKey(t)] {
+			if !matched[j] && (t.ID == "" || existing[j].ID == "") {
+				matched[j], found[i] = true, j
+				break
+			}
+		}
+	}
+
+	var dups []Duplicate
+	for i := range incoming {
+		if j, ok := found[i]; ok {
+			dups = append(dups, Duplicate{Incoming: i, Existing: j})
+		}
+	}
+	return dups
+}
+
+// FindNear is Find followed by a fuzzy pass: each incoming transaction
+// without an exact match is paired with the closest unmatched existing one
+// that is Near it within tol, as transaction.FindDuplicates pairs copies.
+func FindNear(existing, incoming []transaction.Transaction, tol Tolerance) []Duplicate {
+	dups := Find(existing, incoming)
+	matchedIncoming := make(map[int]bool, len(dups))
+	matchedExisting := make(map[int]bool, len(dups))
+	for _, d := range dups {
+		matchedIncoming[d.Incoming] = true
+		matchedExisting[d.Existing] = true
+	}
+
+	merchants := make([]string, len(existing))
+	for i, t := range existing {
+		merchants[i] = transaction.Merchant(t.Description)
+	}
+	for i, t := range incoming {
+		if matchedIncoming[i] {
+			continue
+		}
+		merchant := transaction.Merchant(t.Description)
+		best, bestDays, bestAmount := -1, 0, transaction.Amount(0)
+		for j, e := range existing {
+			if matchedExisting[j] {
+				continue
+			}
+			days, amount, ok := tol.Near(e, t, merchants[j], merchant)
+			if !ok {
+				continue
+			}
+			if best < 0 || days < bestDays || days == bestDays && amount < bestAmount {
+				best, bestDays, bestAmount = j, days, amount
+			}
+		}
+		if best >= 0 {
+			dups = append(dups, Duplicate{Incoming: i, Existing: best, Near: true})
+			matchedExisting[best] = true
+		}
+	}
+	return dups
+}
+
+// Result counts what a merge did with the incoming transactions
+type Result struct {
+	Added    int // not duplicates
+	Skipped  int
+	Replaced int
+	KeptBoth int
+}
+
+// Merge appends incoming to existing, applying decide to each duplicate.
+// Transactions kept with KeepBoth get a "-2" suffix (or "-3" and so on) on
+// their ID, or " (2)" on their description when they have no ID, so that
+// the copy is no longer a duplicate when imported again.
+func Merge(existing, incoming []transaction.Transaction, dups []Duplicate, decide func(Duplicate) (Strategy, error)) ([]transaction.Transaction, Result, error) {
+	merged := append([]transaction.Transaction(nil), existing...)
+	taken := make(map[string]bool, len(existing))
+	for _, t := range existing {
+		taken[transaction.Key(t)] = true
+	}
+
+	byIncoming := make(map[int]Duplicate, len(dups))
+	for _, d := range dups {
+		byIncoming[d.Incoming] = d
+	}
+
+	var result Result
+	for i, t := range incoming {
+		d, ok := byIncoming[i]
+		if !ok {
+			merged = append(merged, t)
+			taken[transaction.Key(t)] = true
+			result.Added++
+			continue
+		}
+
+		strategy, err := decide(d)
+		if err != nil {
+			return nil, Result{}, err
+		}
+		switch strategy {
+		case Skip:
+			result.Skipped++
+		case Replace:
+			merged[d.Existing] = t
+			result.Replaced++
+		case KeepBoth:
+			t = suffixed(t, taken)
+			taken[transaction.Key(t)] = true
+			merged = append(merged, t)
+			result.KeptBoth++
+		default:
+			return nil, Result{}, fmt.Errorf("unknown duplicate strategy %q", strategy)
+		}
+	}
+	return merged, result, nil
+}
+
+// suffixed returns t with the first numeric suffix that makes its key unique
+func suffixed(t transaction.Transaction, taken map[string]bool) transaction.Transaction {
+	id, description := t.ID, t.Description
+	for n := 2; ; n++ {
+		if id != "" {
+			t.ID = fmt.Sprintf("%s-%d", id, n)
+		} else {
+			t.Description = fmt.Sprintf("%s (%d)", description, n)
+		}
+		if !taken[transaction.Key(t)] {
+			return t
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func coffee(day int) transaction.Transaction {
+	return transaction.Transaction{
+		Date:        time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC),
+		Description: "CAFE  NERO",
+		Amount:      -450,
+		Account:     "Everyday",
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	s, err := ParseStrategy("Keep-Both")
+	require.NoError(t, err)
+	assert.Equal(t, KeepBoth, s)
+
+	_, err = ParseStrategy("newest")
+	assert.EqualError(t, err, `unknown duplicate strategy "newest" (expected skip, replace or keep-both)`)
+}
+
+func TestFind(t *testing.T) {
+	existing := []transaction.Transaction{coffee(3), coffee(4)}
+	incoming := []transaction.Transaction{coffee(3), coffee(3), coffee(5)}
+
+	dups := Find(existing, incoming)
+	assert.Equal(t, []Duplicate{{Incoming: 0, Existing: 0}}, dups, "the second same-day coffee is new")
+}
+
+func TestFindIDs(t *testing.T) {
+	withID := func(tx transaction.Transaction, id string) transaction.Transaction {
+		tx.ID = id
+		return tx
+	}
+	existing := []transaction.Transaction{coffee(3), withID(coffee(4), "a"), withID(coffee(5), "b")}
+	incoming := []transaction.Transaction{withID(coffee(3), "x"), withID(coffee(4), "c"), withID(coffee(9), "b")}
+
+	dups := Find(existing, incoming)
+	assert.Equal(t, []Duplicate{
+		{Incoming: 0, Existing: 0}, // an existing transaction without an ID matches on its fields
+		{Incoming: 2, Existing: 2}, // equal IDs match whatever the fields
+	}, dups, "different IDs never match")
+}
+
+func TestMerge(t *testing.T) {
+	existing := []transaction.Transaction{coffee(3), {ID: "FIT1", Description: "SALARY", Amount: 250000}}
+	replacement := transaction.Transaction{ID: "FIT1", Description: "SALARY ACME", Amount: 250000, Category: "Income"}
+	incoming := []transaction.Transaction{coffee(3), replacement, coffee(5)}
+
+	dups := Find(existing, incoming)
+	require.Len(t, dups, 2)
+
+	strategies := map[int]Strategy{0: KeepBoth, 1: Replace}
+	merged, result, err := Merge(existing, incoming, dups, func(d Duplicate) (Strategy, error) {
+		return strategies[d.Incoming], nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Result{Added: 1, Replaced: 1, KeptBoth: 1}, result)
+	require.Len(t, merged, 4)
+	assert.Equal(t, replacement, merged[1])
+	assert.Equal(t, "CAFE  NERO (2)", merged[2].Description)
+	assert.Empty(t, Find(existing, merged[2:3]), "the kept copy no longer matches the original")
+
+	merged, result, err = Merge(existing, incoming, dups, func(Duplicate) (Strategy, error) { return Skip, nil })
+	require.NoError(t, err)
+	assert.Equal(t, Result{Added: 1, Skipped: 2}, result)
+	assert.Len(t, merged, 3)
+}
+
+func TestMergeSuffixesIDs(t *testing.T) {
+	existing := []transaction.Transaction{{ID: "FIT1"}, {ID: "FIT1-2"}}
+	incoming := []transaction.Transaction{{ID: "FIT1"}}
+
+	merged, _, err := Merge(existing, incoming, Find(existing, incoming), func(Duplicate) (Strategy, error) { return KeepBoth, nil })
+	require.NoError(t, err)
+	assert.Equal(t, "FIT1-3", merged[2].ID)
+}
+
+func TestFindNear(t *testing.T) {
+	pdf := []transaction.Transaction{
+		{Date: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), Description: "VISA PURCHASE WOOLWORTHS 1234 SYDNEY", Amount: -5410},
+		{Date: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), Description: "CAFE NERO", Amount: -450},
+		{Date: time.Date(2024, 1, 9, 0, 0, 0, 0, time.UTC), Description: "CAFE NERO", Amount: -450},
+	}
+	csv := []transaction.Transaction{
+		{Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Description: "WOOLWORTHS SYDNEY", Amount: -5410},
+		{Date: time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC), Description: "Cafe Nero", Amount: -449},
+		{Date: time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC), Description: "CAFE NERO", Amount: -450},
+		{Date: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), Description: "CAFE NERO", Amount: -450},
+	}
+
+	dups := FindNear(pdf, csv, DefaultTolerance)
+	assert.Equal(t, []Duplicate{
+		{Incoming: 3, Existing: 1},
+		{Incoming: 0, Existing: 0, Near: true},
+		{Incoming: 1, Existing: 2, Near: true},
+	}, dups, "exact matches are taken first and each existing row matches once")
+
+	assert.Len(t, FindNear(pdf, csv, Tolerance{}), 1, "a zero tolerance only finds exact duplicates")
+	assert.Len(t, FindNear(pdf, csv[:1], Tolerance{Days: 1}), 0)
+}
+
+func TestFindNearAccounts(t *testing.T) {
+	existing := []transaction.Transaction{{Date: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), Description: "NETFLIX", Amount: -1699, Account: "Credit"}}
+	incoming := []transaction.Transaction{existing[0], existing[0]}
+	incoming[0].Account, incoming[0].Description = "Everyday", "NETFLIX.COM"
+	incoming[1].Account, incoming[1].Description = "", "NETFLIX.COM"
+
+	assert.Equal(t, []Duplicate{{Incoming: 1, Existing: 0, Near: true}}, FindNear(existing, incoming, DefaultTolerance))
+}
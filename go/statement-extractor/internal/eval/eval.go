@@ -0,0 +1,210 @@
+// Package eval scores extracted transactions against labeled expectations so
+// that parser and prompt changes can be compared with numbers.
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// ExpectedFile is the labeled result inside each fixture directory
+const ExpectedFile = "expected.json"
+
+// Score counts how well extracted rows match the expected rows. Rows match
+// on date and description (case and whitespace insensitive).
+type Score struct {
+	Expected      int // labeled rows
+	Actual        int // extracted rows
+	Matched       int // extracted rows matching a labeled row
+	AmountCorrect int // matched rows whose amount is also correct
+}
+
+// Precision is the fraction of extracted rows that are real
+func (s Score) Precision() float64 {
+	return ratio(s.Matched, s.Actual)
+}
+
+// Recall is the fraction of labeled rows that were extracted
+func (s Score) Recall() float64 {
+	return ratio(s.Matched, s.Expected)
+}
+
+// AmountAccuracy is the fraction of matched rows with the correct amount
+func (s Score) AmountAccuracy() float64 {
+	return ratio(s.AmountCorrect, s.Matched)
+}
+
+// Add sums two scores, micro-averaging across fixtures
+func (s Score) Add(o Score) Score {
+	return Score{
+		Expected:      s.Expected + o.Expected,
+		Actual:        s.Actual + o.Actual,
+		Matched:       s.Matched + o.Matched,
+		AmountCorrect: s.AmountCorrect + o.AmountCorrect,
+	}
+}
+
+// ratio treats 0/0 as perfect: nothing expected and nothing produced
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 1
+	}
+	return float64(n) / float64(d)
+}
+
+// Compare scores actual against expected
+func Compare(expected, actual []transaction.Transaction) Score {
+	score := Score{Expected: len(expected), Actual: len(actual)}
+	for i, j := range Match(expected, actual) {
+		if j < 0 {
+			continue
+		}
+		score.Matched++
+		if actual[j].Amount == expected[i].Amount {
+			score.AmountCorrect++
+		}
+	}
+	return score
+}
+
+// Match pairs the rows of expected with rows of actual on date and
+// description (case and whitespace insensitive), returning the index in
+// actual of each expected row's match, or -1. Each row is matched at most
+// once, preferring a candidate with the same amount when a row repeats.
+func Match(expected, actual []transaction.Transaction) []int {
+	matches := make([]int, len(expected))
+	used := make([]bool, len(actual))
+
+	find := func(want transaction.Transaction, sameAmount bool) int {
+		for i, got := range actual {
+			if used[i] || !got.Date.Equal(want.Date) || normalize(got.Description) != normalize(want.Description) {
+				continue
+			}
+			if sameAmount && got.Amount != want.Amount {
+				continue
+			}
+			return i
+		}
+		return -1
+	}
+
+	for k, want := range expected {
+		i := find(want, true)
+		if i < 0 {
+			i = find(want, false)
+		}
+		if i >= 0 {
+			used[i] = true
+		}
+		matches[k] = i
+	}
+	return matches
+}
+
+func normalize(description string) string {
+	return strings.ToLower(strings.Join(strings.Fields(description), " "))
+}
+
+// Result is the score of one output file within a fixture
+type Result struct {
+	Fixture  string
+	Provider string
+	Prompt   string // empty for the provider's default prompt
+	Score    Score
+}
+
+// Run scores every fixture under dir. Each fixture is a directory holding
+// expected.json and one TransactionList JSON output per run, named
+// "<provider>.json" or "<provider>@<prompt>.json".
+func Run(dir string) ([]Result, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+
+	var results []Result
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		fixture := filepath.Join(dir, entry.Name())
+		expected, err := load(filepath.Join(fixture, ExpectedFile))
+		if err != nil {
+			return nil, err
+		}
+
+		outputs, err := filepath.Glob(filepath.Join(fixture, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(outputs)
+		for _, output := range outputs {
+			name := strings.TrimSuffix(filepath.Base(output), ".json")
+			if name+".json" == ExpectedFile {
+				continue
+			}
+			actual, err := load(output)
+			if err != nil {
+				return nil, err
+			}
+			provider, prompt, _ := strings.Cut(name, "@")
+			results = append(results, Result{
+				Fixture:  entry.Name(),
+				Provider: provider,
+				Prompt:   prompt,
+				Score:    Compare(expected, actual),
+			})
+		}
+	}
+	return results, nil
+}
+
+// Summary is the combined score of one provider and prompt
+type Summary struct {
+	Provider string
+	Prompt   string
+	Fixtures int
+	Score    Score
+}
+
+// Summarize combines results per provider and prompt, sorted by name
+func Summarize(results []Result) []Summary {
+	index := make(map[[2]string]int)
+	var summaries []Summary
+	for _, r := range results {
+		key := [2]string{r.Provider, r.Prompt}
+		i, ok := index[key]
+		if !ok {
+			i = len(summaries)
+			index[key] = i
+			summaries = append(summaries, Summary{Provider: r.Provider, Prompt: r.Prompt})
+		}
+		summaries[i].Fixtures++
+		summaries[i].Score = summaries[i].Score.Add(r.Score)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Provider != summaries[j].Provider {
+			return summaries[i].Provider < summaries[j].Provider
+		}
+		return summaries[i].Prompt < summaries[j].Prompt
+	})
+	return summaries
+}
+
+func load(path string) ([]transaction.Transaction, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+	var list transaction.TransactionList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return list.Transactions, nil
+}
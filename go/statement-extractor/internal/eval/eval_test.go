@@ -0,0 +1,85 @@
+package eval
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func txn(day int, description string, amount float64) transaction.Transaction {
+	return transaction.Transaction{
+		Date:        time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC),
+		Description: description,
+		Amount:      transaction.FromFloat(amount),
+	}
+}
+
+func TestCompare(t *testing.T) {
+	expected := []transaction.Transaction{
+		txn(1, "COFFEE", -4.5),
+		txn(1, "COFFEE", -5.0),
+		txn(2, "SALARY ACME", 3000),
+		txn(3, "RENT", -500),
+	}
+	actual := []transaction.Transaction{
+		txn(1, "coffee", -5.0),
+		txn(1, "COFFEE ", -4.5),
+		txn(2, "SALARY  ACME", -3000),
+		txn(4, "OPENING BALANCE", 0),
+	}
+
+	assert.Equal(t, []int{1, 0, 2, -1}, Match(expected, actual))
+	score := Compare(expected, actual)
+	assert.Equal(t, Score{Expected: 4, Actual: 4, Matched: 3, AmountCorrect: 2}, score)
+	assert.Equal(t, 0.75, score.Precision())
+	assert.Equal(t, 0.75, score.Recall())
+	assert.InDelta(t, 2.0/3, score.AmountAccuracy(), 1e-9)
+
+	empty := Compare(nil, nil)
+	assert.Equal(t, 1.0, empty.Precision())
+	assert.Equal(t, 1.0, empty.Recall())
+}
+
+func writeList(t *testing.T, path string, txns ...transaction.Transaction) {
+	t.Helper()
+	data, err := json.Marshal(transaction.TransactionList{Transactions: txns})
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+}
+
+func TestRunAndSummarize(t *testing.T) {
+	dir := t.TempDir()
+	writeList(t, filepath.Join(dir, "jan", "expected.json"), txn(1, "A", -1), txn(2, "B", -2))
+	writeList(t, filepath.Join(dir, "jan", "mistral.json"), txn(1, "A", -1), txn(2, "B", -2))
+	writeList(t, filepath.Join(dir, "jan", "mistral@terse.json"), txn(1, "A", -1))
+	writeList(t, filepath.Join(dir, "feb", "expected.json"), txn(5, "C", -3))
+	writeList(t, filepath.Join(dir, "feb", "mistral.json"), txn(5, "C", 3))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("ignored"), 0o644))
+
+	results, err := Run(dir)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, Result{Fixture: "feb", Provider: "mistral", Score: Score{1, 1, 1, 0}}, results[0])
+	assert.Equal(t, "terse", results[2].Prompt)
+
+	summaries := Summarize(results)
+	require.Len(t, summaries, 2)
+	assert.Equal(t, Summary{Provider: "mistral", Fixtures: 2, Score: Score{3, 3, 3, 2}}, summaries[0])
+	assert.Equal(t, Summary{Provider: "mistral", Prompt: "terse", Fixtures: 1, Score: Score{2, 1, 1, 1}}, summaries[1])
+}
+
+func TestRun_MissingExpected(t *testing.T) {
+	dir := t.TempDir()
+	writeList(t, filepath.Join(dir, "jan", "mistral.json"))
+
+	_, err := Run(dir)
+	assert.ErrorContains(t, err, "failed to read fixture")
+}
@@ -0,0 +1,118 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// uncategorized is the category used in account names for transactions
+// without one
+const uncategorized = "Uncategorized"
+
+// accountTemplates are the account name templates of a double-entry export:
+// the statement's asset account and the expense or income account that
+// balances it
+type accountTemplates struct {
+	section                string // config section, for errors
+	asset, expense, income *template.Template
+}
+
+// accountData is the template data for an account name
+type accountData struct {
+	Category    string
+	Source      string
+	Account     string
+	AccountType string
+	Currency    string
+}
+
+// compileAccounts parses the account name templates configured in section
+func compileAccounts(section, asset, expense, income string) (*accountTemplates, error) {
+	a := &accountTemplates{section: section}
+	for _, name := range []struct {
+		key  string
+		text string
+		tmpl **template.Template
+	}{
+		{"asset_account", asset, &a.asset},
+		{"expense_account", expense, &a.expense},
+		{"income_account", income, &a.income},
+	} {
+		if name.text == "" {
+			return nil, fmt.Errorf("%s.%s is not set", section, name.key)
+		}
+		tmpl, err := template.New(name.key).Option("missingkey=error").Parse(name.text)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", section, name.key, err)
+		}
+		*name.tmpl = tmpl
+	}
+	return a, nil
+}
+
+// render returns the category and asset account names for t, cleaned up
+// for the target format by clean
+func (a *accountTemplates) render(t transaction.Transaction, clean func(string) (string, error)) (category, asset string, err error) {
+	data := accountData{
+		Category:    t.Category,
+		Source:      t.Source,
+		Account:     t.Account,
+		AccountType: t.AccountType,
+		Currency:    t.Currency,
+	}
+	if data.Category == "" {
+		data.Category = uncategorized
+	}
+
+	counter := a.expense
+	if t.Amount > 0 {
+		counter = a.income
+	}
+	if category, err = a.name(counter, data, clean); err != nil {
+		return "", "", fmt.Errorf("%s %s: %w", t.Date.Format("2006-01-02"), t.Description, err)
+	}
+	if asset, err = a.name(a.asset, data, clean); err != nil {
+		return "", "", fmt.Errorf("%s %s: %w", t.Date.Format("2006-01-02"), t.Description, err)
+	}
+	return category, asset, nil
+}
+
+func (a *accountTemplates) name(tmpl *template.Template, data accountData, clean func(string) (string, error)) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("%s.%s: %w", a.section, tmpl.Name(), err)
+	}
+	name, err := clean(b.String())
+	if err != nil {
+		return "", fmt.Errorf("%s.%s: %w", a.section, tmpl.Name(), err)
+	}
+	if name == "" {
+		return "", fmt.Errorf("%s.%s renders an empty account name", a.section, tmpl.Name())
+	}
+	return name, nil
+}
+
+// posting is a category posting of a double-entry transaction
+type posting struct {
+	account string
+	amount  transaction.Amount
+}
+
+// postings returns the category postings of t, one per split when it is
+// split, and the asset account balancing them
+func (a *accountTemplates) postings(t transaction.Transaction, clean func(string) (string, error)) ([]posting, string, error) {
+	var postings []posting
+	var asset string
+	for _, part := range t.Allocations() {
+		category, partAsset, err := a.render(part, clean)
+		if err != nil {
+			return nil, "", err
+		}
+		postings = append(postings, posting{account: category, amount: part.Amount})
+		asset = partAsset
+	}
+	return postings, asset, nil
+}
@@ -0,0 +1,244 @@
+// Package export writes transactions in formats meant for people and other
+// tools, such as the consolidated annual files handed to an accountant.
+package export
+
+import (
+	"cmp"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// AccountOf names the account a transaction belongs to: its account name,
+// falling back to its source
+func AccountOf(t transaction.Transaction) string {
+	if t.Account != "" {
+		return t.Account
+	}
+	if t.Source != "" {
+		return t.Source
+	}
+	return "Unknown"
+}
+
+// Annual is one calendar year of transactions split by account
+type Annual struct {
+	Year     int
+	Accounts []string // sorted
+	accounts map[string][]transaction.Transaction
+}
+
+// NewAnnual collects the transactions dated in year, ordered by date within
+// each account
+func NewAnnual(txns []transaction.Transaction, year int) *Annual {
+	a := &Annual{Year: year, accounts: make(map[string][]transaction.Transaction)}
+	for _, t := range txns {
+		if t.Date.Year() != year {
+			continue
+		}
+		account := AccountOf(t)
+		if _, ok := a.accounts[account]; !ok {
+			a.Accounts = append(a.Accounts, account)
+		}
+		a.accounts[account] = append(a.accounts[account], t)
+	}
+	slices.Sort(a.Accounts)
+	for _, txns := range a.accounts {
+		transaction.SortByDate(txns)
+	}
+	return a
+}
+
+// Transactions returns an account's transactions
+func (a *Annual) Transactions(account string) []transaction.Transaction {
+	return a.accounts[account]
+}
+
+// Len returns the number of transactions across all accounts
+func (a *Annual) Len() int {
+	n := 0
+	for _, txns := range a.accounts {
+		n += len(txns)
+	}
+	return n
+}
+
+// WriteDir writes one CSV per account and a summary CSV into dir, returning
+// the paths written
+func (a *Annual) WriteDir(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	var paths []string
+	write := func(name string, fn func(io.Writer) error) error {
+		path := filepath.Join(dir, name)
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", name, err)
+		}
+		if err := fn(f); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		paths = append(paths, path)
+		return nil
+	}
+
+	used := make(map[string]bool)
+	for _, account := range a.Accounts {
+		txns := a.accounts[account]
+		name := fmt.Sprintf("%d-%s.csv", a.Year, slug(account))
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%d-%s-%d.csv", a.Year, slug(account), n)
+		}
+		used[name] = true
+		if err := write(name, func(w io.Writer) error { return WriteCSV(w, txns) }); err != nil {
+			return paths, err
+		}
+	}
+	if err := write(fmt.Sprintf("%d-summary.csv", a.Year), a.WriteSummary); err != nil {
+		return paths, err
+	}
+	return paths, nil
+}
+
+// WriteCSV writes transactions one per row, including the statement each
+// came from so figures can be traced back to the source document
+func WriteCSV(w io.Writer, txns []transaction.Transaction) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"date", "description", "category", "amount", "balance", "currency", "statement"})
+	for _, t := range txns {
+		var statement string
+		if t.Statement != nil {
+			statement = t.Statement.String()
+		}
+		_ = cw.Write([]string{
+			t.Date.Format("2006-01-02"),
+			t.Description,
+			t.Category,
+			formatAmount(t.Amount),
+			formatBalance(t.Balance),
+			t.Currency,
+			statement,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteSummary writes the net amount per category for each account, with
+// totals
+func (a *Annual) WriteSummary(w io.Writer) error {
+	p := newPivot(a.Accounts, a.accounts)
+
+	cw := csv.NewWriter(w)
+	_ = cw.Write(append(append([]string{"category"}, a.Accounts...), "total"))
+	for _, row := range p.rows() {
+		record := []string{row.label}
+		for _, amount := range row.amounts {
+			record = append(record, formatAmount(amount))
+		}
+		_ = cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// pivot holds net amounts per category for each of a set of columns, such
+// as accounts or months
+type pivot struct {
+	columns    []string
+	categories []string                                 // sorted case-insensitively
+	net        map[string]map[string]transaction.Amount // category -> column -> net
+	totals     map[string]transaction.Amount            // column -> net
+}
+
+func newPivot(columns []string, groups map[string][]transaction.Transaction) *pivot {
+	p := &pivot{columns: columns, net: make(map[string]map[string]transaction.Amount), totals: make(map[string]transaction.Amount)}
+	for column, txns := range groups {
+		for _, t := range transaction.ExpandSplits(txns) {
+			if p.net[t.Category] == nil {
+				p.net[t.Category] = make(map[string]transaction.Amount)
+				p.categories = append(p.categories, t.Category)
+			}
+			p.net[t.Category][column] += t.Amount
+			p.totals[column] += t.Amount
+		}
+	}
+	slices.SortFunc(p.categories, func(x, y string) int {
+		return cmp.Compare(strings.ToLower(x), strings.ToLower(y))
+	})
+	return p
+}
+
+type pivotRow struct {
+	label   string
+	amounts []transaction.Amount // one per column, then the row total
+}
+
+// rows returns a row per category followed by a "Total" row
+func (p *pivot) rows() []pivotRow {
+	row := func(label string, amounts map[string]transaction.Amount) pivotRow {
+		r := pivotRow{label: label}
+		var total transaction.Amount
+		for _, column := range p.columns {
+			r.amounts = append(r.amounts, amounts[column])
+			total += amounts[column]
+		}
+		r.amounts = append(r.amounts, total)
+		return r
+	}
+
+	rows := make([]pivotRow, 0, len(p.categories)+1)
+	for _, category := range p.categories {
+		label := category
+		if label == "" {
+			label = "Uncategorized"
+		}
+		rows = append(rows, row(label, p.net[category]))
+	}
+	return append(rows, row("Total", p.totals))
+}
+
+func formatAmount(v transaction.Amount) string {
+	return v.String()
+}
+
+// formatBalance formats a balance, empty when the statement shows none
+func formatBalance(v *transaction.Amount) string {
+	if v == nil {
+		return ""
+	}
+	return v.String()
+}
+
+// slug makes an account name safe for a file name: "Joint Savings" becomes
+// "joint-savings"
+func slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	if s := strings.TrimSuffix(b.String(), "-"); s != "" {
+		return s
+	}
+	return "account"
+}
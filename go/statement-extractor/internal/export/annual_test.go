@@ -0,0 +1,78 @@
+package export
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func date(s string) time.Time {
+	d, _ := time.Parse("2006-01-02", s)
+	return d
+}
+
+var annualTxns = []transaction.Transaction{
+	{Date: date("2024-03-01"), Description: "WOOLWORTHS", Amount: -5000, Category: "Groceries", Source: "CBA", Account: "Everyday"},
+	{Date: date("2024-01-15"), Description: "SALARY", Amount: 300000, Category: "Income", Source: "CBA", Account: "Everyday",
+		Statement: &transaction.StatementRef{File: "jan.pdf", SHA256: "9f86d081884c7d659a2feaa0c55ad015", Page: 2}},
+	{Date: date("2024-02-01"), Description: "INTEREST", Amount: 1250, Category: "Income", Source: "ANZ"},
+	{Date: date("2023-12-31"), Description: "LAST YEAR", Amount: -100, Category: "Groceries", Source: "ANZ"},
+}
+
+func TestNewAnnual(t *testing.T) {
+	a := NewAnnual(annualTxns, 2024)
+	assert.Equal(t, []string{"ANZ", "Everyday"}, a.Accounts, "account name, else source")
+	assert.Equal(t, 3, a.Len())
+
+	everyday := a.Transactions("Everyday")
+	require.Len(t, everyday, 2)
+	assert.Equal(t, "SALARY", everyday[0].Description, "sorted by date")
+}
+
+func TestWriteCSV(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, WriteCSV(&b, NewAnnual(annualTxns, 2024).Transactions("Everyday")))
+	assert.Equal(t, "date,description,category,amount,balance,currency,statement\n"+
+		"2024-01-15,SALARY,Income,3000.00,,,jan.pdf p.2 sha256:9f86d081884c\n"+
+		"2024-03-01,WOOLWORTHS,Groceries,-50.00,,,\n", b.String())
+}
+
+func TestWriteSummary(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, NewAnnual(annualTxns, 2024).WriteSummary(&b))
+	assert.Equal(t, "category,ANZ,Everyday,total\n"+
+		"Groceries,0.00,-50.00,-50.00\n"+
+		"Income,12.50,3000.00,3012.50\n"+
+		"Total,12.50,2950.00,2962.50\n", b.String())
+}
+
+func TestWriteDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "2024")
+	txns := append(annualTxns, transaction.Transaction{Date: date("2024-05-01"), Amount: -500, Account: "everyday"})
+
+	paths, err := NewAnnual(txns, 2024).WriteDir(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "2024-anz.csv"),
+		filepath.Join(dir, "2024-everyday.csv"),
+		filepath.Join(dir, "2024-everyday-2.csv"),
+		filepath.Join(dir, "2024-summary.csv"),
+	}, paths)
+
+	data, err := os.ReadFile(paths[3])
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "Uncategorized,0.00,0.00,-5.00,-5.00\n")
+}
+
+func TestSlug(t *testing.T) {
+	assert.Equal(t, "joint-savings", slug("Joint Savings"))
+	assert.Equal(t, "cba-06-2000", slug(" CBA (06-2000) "))
+	assert.Equal(t, "account", slug("***"))
+}
@@ -0,0 +1,161 @@
+package export
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// beancountRoots are the account types a Beancount account name must start
+// with
+var beancountRoots = []string{"Assets", "Liabilities", "Equity", "Income", "Expenses"}
+
+// Beancount writes Beancount ledgers that Fava can open directly: an open
+// directive for every account used, dated at its first transaction, then one
+// transaction per row with the statement it came from as metadata.
+type Beancount struct {
+	accounts     *accountTemplates
+	currency     string
+	descriptions *shortener
+}
+
+// NewBeancount compiles the account name templates in cfg
+func NewBeancount(cfg config.BeancountConfig) (*Beancount, error) {
+	accounts, err := compileAccounts("export.beancount", cfg.AssetAccount, cfg.ExpenseAccount, cfg.IncomeAccount)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Currency == "" {
+		return nil, errors.New("export.beancount.currency is not set")
+	}
+	descriptions, err := newShortener("export.beancount.description", cfg.Description)
+	if err != nil {
+		return nil, err
+	}
+	return &Beancount{accounts: accounts, currency: cfg.Currency, descriptions: descriptions}, nil
+}
+
+func writeBeancount(w io.Writer, txns []transaction.Transaction, cfg config.ExportConfig) error {
+	b, err := NewBeancount(cfg.Beancount)
+	if err != nil {
+		return err
+	}
+	return b.Write(w, txns)
+}
+
+// beancountEntry is a transaction with its account names resolved
+type beancountEntry struct {
+	transaction.Transaction
+	postings []posting
+	asset    string
+}
+
+// Write writes the open directives followed by txns in the order given.
+// The description is the payee; the bank's transaction ID and the
+// statement file, page and hash are kept as metadata, as is the full
+// description when the payee is cut. A split transaction has a category
+// posting per split.
+func (b *Beancount) Write(w io.Writer, txns []transaction.Transaction) error {
+	entries := make([]beancountEntry, 0, len(txns))
+	opened := make(map[string]time.Time)
+	open := func(account string, date time.Time) {
+		if first, ok := opened[account]; !ok || date.Before(first) {
+			opened[account] = date
+		}
+	}
+	for _, t := range txns {
+		postings, asset, err := b.accounts.postings(t, beancountAccountName)
+		if err != nil {
+			return err
+		}
+		for _, p := range postings {
+			open(p.account, t.Date)
+		}
+		open(asset, t.Date)
+		entries = append(entries, beancountEntry{t, postings, asset})
+	}
+
+	bw := bufio.NewWriter(w)
+	accounts := make([]string, 0, len(opened))
+	for account := range opened {
+		accounts = append(accounts, account)
+	}
+	slices.Sort(accounts)
+	for _, account := range accounts {
+		fmt.Fprintf(bw, "%s open %s\n", opened[account].Format("2006-01-02"), account)
+	}
+
+	for _, e := range entries {
+		currency := e.Currency
+		if currency == "" {
+			currency = b.currency
+		}
+		payee, cut := b.descriptions.shorten(e.Description)
+		fmt.Fprintf(bw, "\n%s * %s \"\"\n", e.Date.Format("2006-01-02"), beancountString(payee))
+		if cut {
+			fmt.Fprintf(bw, "  description: %s\n", beancountString(e.Description))
+		}
+		if e.ID != "" {
+			fmt.Fprintf(bw, "  id: %s\n", beancountString(e.ID))
+		}
+		if ref := e.Statement; ref != nil {
+			fmt.Fprintf(bw, "  source_file: %s\n", beancountString(ref.File))
+			if ref.Page > 0 {
+				fmt.Fprintf(bw, "  source_page: %d\n", ref.Page)
+			}
+			if ref.SHA256 != "" {
+				fmt.Fprintf(bw, "  source_sha256: %s\n", beancountString(ref.SHA256))
+			}
+		}
+		for _, p := range e.postings {
+			fmt.Fprintf(bw, "  %s  %s %s\n", p.account, formatAmount(-p.amount), currency)
+		}
+		fmt.Fprintf(bw, "  %s\n", e.asset)
+	}
+	return bw.Flush()
+}
+
+// beancountAccountName adjusts a rendered account name to Beancount's
+// syntax: each component starts with a capital letter or digit and holds only
+// letters, digits and dashes, and the first is one of the five account types
+func beancountAccountName(name string) (string, error) {
+	var components []string
+	for _, component := range strings.Split(name, ":") {
+		component = strings.Join(strings.FieldsFunc(component, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}), "-")
+		if component == "" {
+			continue
+		}
+		runes := []rune(component)
+		runes[0] = unicode.ToUpper(runes[0])
+		components = append(components, string(runes))
+	}
+	if len(components) == 0 {
+		return "", nil
+	}
+	if !slices.Contains(beancountRoots, components[0]) {
+		return "", fmt.Errorf("account %q must start with one of %s", name, strings.Join(beancountRoots, ", "))
+	}
+	if len(components) == 1 {
+		return "", fmt.Errorf("account %q has no name after its type", name)
+	}
+	return strings.Join(components, ":"), nil
+}
+
+// beancountEscaper escapes text for a Beancount string, which cannot span
+// lines
+var beancountEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ")
+
+// beancountString quotes s as a Beancount string
+func beancountString(s string) string {
+	return `"` + beancountEscaper.Replace(s) + `"`
+}
@@ -0,0 +1,77 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+var beancountConfig = config.BeancountConfig{
+	AssetAccount:   "Assets:{{ .Source }}",
+	ExpenseAccount: "Expenses:{{ .Category }}",
+	IncomeAccount:  "Income:{{ .Category }}",
+	Currency:       "AUD",
+}
+
+func TestBeancountWrite(t *testing.T) {
+	b, err := NewBeancount(beancountConfig)
+	require.NoError(t, err)
+
+	txns := append([]transaction.Transaction{}, formatTxns...)
+	txns[0].Source = "ANZ"
+	txns = append(txns, transaction.Transaction{Date: date("2024-01-02"), Description: `CAFE "NERO"`, Amount: -450, Source: "CBA", Currency: "NZD", Category: "eating out"})
+
+	var out bytes.Buffer
+	require.NoError(t, b.Write(&out, txns))
+	assert.Equal(t, `2024-01-03 open Assets:ANZ
+2024-01-02 open Assets:CBA
+2024-01-02 open Expenses:Eating-out
+2024-01-03 open Expenses:Groceries:Supermarket
+2024-01-15 open Income:Income
+
+2024-01-03 * "WOOLWORTHS 1234" ""
+  id: "T1"
+  source_file: "jan.pdf"
+  source_page: 1
+  source_sha256: "9f86d081884c7d659a2feaa0c55ad015"
+  Expenses:Groceries:Supermarket  54.10 AUD
+  Assets:ANZ
+
+2024-01-15 * "SALARY; ACME" ""
+  Income:Income  -2500.00 AUD
+  Assets:CBA
+
+2024-01-02 * "CAFE \"NERO\"" ""
+  Expenses:Eating-out  4.50 NZD
+  Assets:CBA
+`, out.String())
+}
+
+func TestBeancountAccountName(t *testing.T) {
+	for name, want := range map[string]string{
+		"Expenses:Groceries & household": "Expenses:Groceries-household",
+		"Assets:joint everyday:":         "Assets:Joint-everyday",
+		"Income:2024 bonus":              "Income:2024-bonus",
+	} {
+		got, err := beancountAccountName(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, got, name)
+	}
+
+	_, err := beancountAccountName("Bank:CBA")
+	assert.EqualError(t, err, `account "Bank:CBA" must start with one of Assets, Liabilities, Equity, Income, Expenses`)
+	_, err = beancountAccountName("Assets")
+	assert.EqualError(t, err, `account "Assets" has no name after its type`)
+}
+
+func TestNewBeancount(t *testing.T) {
+	cfg := beancountConfig
+	cfg.Currency = ""
+	_, err := NewBeancount(cfg)
+	assert.EqualError(t, err, "export.beancount.currency is not set")
+}
@@ -0,0 +1,174 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// csvFields extract the values of the fields available as CSV columns
+var csvFields = map[string]func(t transaction.Transaction) string{
+	"id":               func(t transaction.Transaction) string { return t.ID },
+	"description":      func(t transaction.Transaction) string { return t.Description },
+	"full_description": func(t transaction.Transaction) string { return t.Description },
+	"raw_description":  func(t transaction.Transaction) string { return t.RawDescription },
+	"merchant":         func(t transaction.Transaction) string { return t.Merchant },
+	"amount":           func(t transaction.Transaction) string { return formatAmount(t.Amount) },
+	"balance":          func(t transaction.Transaction) string { return formatBalance(t.Balance) },
+	"category":         func(t transaction.Transaction) string { return t.Category },
+	"source":           func(t transaction.Transaction) string { return t.Source },
+	"account":          func(t transaction.Transaction) string { return t.Account },
+	"account_type":     func(t transaction.Transaction) string { return t.AccountType },
+	"owner":            func(t transaction.Transaction) string { return t.Owner },
+	"card":             func(t transaction.Transaction) string { return t.Card },
+	"currency":         func(t transaction.Transaction) string { return t.Currency },
+	"statement":        memo,
+	"sequence":         func(t transaction.Transaction) string { return strconv.Itoa(t.Sequence) },
+	"outflow": func(t transaction.Transaction) string {
+		if t.Amount >= 0 {
+			return ""
+		}
+		return formatAmount(t.Amount.Abs())
+	},
+	"inflow": func(t transaction.Transaction) string {
+		if t.Amount <= 0 {
+			return ""
+		}
+		return formatAmount(t.Amount)
+	},
+	"confidence":     func(t transaction.Transaction) string { return strconv.FormatFloat(t.CategoryConfidence, 'f', -1, 64) },
+	"categorized_by": func(t transaction.Transaction) string { return t.CategorizedBy },
+	"excluded":       func(t transaction.Transaction) string { return strconv.FormatBool(t.ExcludeFromReports) },
+}
+
+// csvPreset is a built-in CSV layout
+type csvPreset struct {
+	columns    []config.CSVOutputColumn
+	dateFormat string
+}
+
+// csvPresets are the built-in layouts, keyed by name
+var csvPresets = map[string]csvPreset{
+	"default": {
+		columns: []config.CSVOutputColumn{
+			{Field: "date"}, {Field: "description"}, {Field: "amount"}, {Field: "balance"},
+			{Field: "category"}, {Field: "source"}, {Field: "account"}, {Field: "currency"}, {Field: "id"},
+		},
+		dateFormat: "2006-01-02",
+	},
+	// YNAB's file import: the statement reference goes in the memo
+	"ynab": {
+		columns: []config.CSVOutputColumn{
+			{Field: "date", Header: "Date"}, {Field: "description", Header: "Payee"}, {Field: "statement", Header: "Memo"},
+			{Field: "outflow", Header: "Outflow"}, {Field: "inflow", Header: "Inflow"},
+		},
+		dateFormat: "2006-01-02",
+	},
+}
+
+// CSVLayout writes transactions as CSV with configured columns
+type CSVLayout struct {
+	headers      []string
+	fields       []string
+	dateFormat   string
+	descriptions *shortener
+}
+
+// NewCSVLayout resolves the preset or columns in cfg
+func NewCSVLayout(cfg config.CSVOutputConfig) (*CSVLayout, error) {
+	name := strings.ToLower(cfg.Preset)
+	if name == "" {
+		name = "default"
+	}
+	preset, ok := csvPresets[name]
+	if !ok {
+		return nil, fmt.Errorf("export.csv.preset: unknown preset %q (expected %s)", cfg.Preset, strings.Join(csvPresetNames(), ", "))
+	}
+
+	columns := preset.columns
+	if len(cfg.Columns) > 0 {
+		columns = cfg.Columns
+	}
+	descriptions, err := newShortener("export.csv.description", cfg.Description)
+	if err != nil {
+		return nil, err
+	}
+	l := &CSVLayout{dateFormat: preset.dateFormat, descriptions: descriptions}
+	if cfg.DateFormat != "" {
+		l.dateFormat = cfg.DateFormat
+	}
+	for i, c := range columns {
+		field := strings.ToLower(c.Field)
+		if _, ok := csvFields[field]; !ok && field != "date" {
+			return nil, fmt.Errorf("export.csv.columns[%d]: unknown field %q (expected date, %s)", i, c.Field, strings.Join(csvFieldNames(), ", "))
+		}
+		header := c.Header
+		if header == "" {
+			header = field
+		}
+		l.fields = append(l.fields, field)
+		l.headers = append(l.headers, header)
+	}
+	return l, nil
+}
+
+func writeCSVLayout(w io.Writer, txns []transaction.Transaction, cfg config.ExportConfig) error {
+	l, err := NewCSVLayout(cfg.CSV)
+	if err != nil {
+		return err
+	}
+	return l.Write(w, txns)
+}
+
+// Write writes a header row and a row per transaction, or per split of a
+// split transaction with the split's category and amount. The description
+// column is cut to the configured limit, and a cut description is given in
+// full in the statement column, which presets use as the memo;
+// full_description is never cut.
+func (l *CSVLayout) Write(w io.Writer, txns []transaction.Transaction) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write(l.headers)
+	row := make([]string, len(l.fields))
+	for _, t := range transaction.ExpandSplits(txns) {
+		description, cut := l.descriptions.shorten(t.Description)
+		for i, field := range l.fields {
+			switch field {
+			case "date":
+				row[i] = t.Date.Format(l.dateFormat)
+			case "description":
+				row[i] = description
+			case "statement":
+				row[i] = fullDescription(t.Description, cut, memo(t))
+			default:
+				row[i] = csvFields[field](t)
+			}
+		}
+		_ = cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvPresetNames() []string {
+	names := make([]string, 0, len(csvPresets))
+	for name := range csvPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func csvFieldNames() []string {
+	names := make([]string, 0, len(csvFields))
+	for name := range csvFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,52 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestCSVLayoutPresets(t *testing.T) {
+	l, err := NewCSVLayout(config.CSVOutputConfig{})
+	require.NoError(t, err)
+	var b bytes.Buffer
+	require.NoError(t, l.Write(&b, formatTxns))
+	assert.Equal(t, "date,description,amount,balance,category,source,account,currency,id\n"+
+		"2024-01-03,WOOLWORTHS 1234,-54.10,,Groceries:Supermarket,,Joint Everyday,,T1\n"+
+		"2024-01-15,SALARY; ACME,2500.00,,Income,CBA,,,\n", b.String())
+
+	l, err = NewCSVLayout(config.CSVOutputConfig{Preset: "YNAB", DateFormat: "02/01/2006"})
+	require.NoError(t, err)
+	b.Reset()
+	require.NoError(t, l.Write(&b, formatTxns))
+	assert.Equal(t, "Date,Payee,Memo,Outflow,Inflow\n"+
+		"03/01/2024,WOOLWORTHS 1234,jan.pdf p.1 sha256:9f86d081884c,54.10,\n"+
+		"15/01/2024,SALARY; ACME,,,2500.00\n", b.String())
+}
+
+func TestCSVLayoutColumns(t *testing.T) {
+	l, err := NewCSVLayout(config.CSVOutputConfig{Columns: []config.CSVOutputColumn{
+		{Field: "Category", Header: "Kategorie"}, {Field: "amount"}, {Field: "date", Header: "Datum"},
+	}})
+	require.NoError(t, err)
+	var b bytes.Buffer
+	require.NoError(t, l.Write(&b, formatTxns[:1]))
+	assert.Equal(t, "Kategorie,amount,Datum\nGroceries:Supermarket,-54.10,2024-01-03\n", b.String())
+
+	split := formatTxns[0]
+	split.Splits = []transaction.Split{{Category: "Groceries", Amount: -4000}, {Category: "Household", Amount: -1410}}
+	b.Reset()
+	require.NoError(t, l.Write(&b, []transaction.Transaction{split}))
+	assert.Equal(t, "Kategorie,amount,Datum\nGroceries,-40.00,2024-01-03\nHousehold,-14.10,2024-01-03\n", b.String(), "a row per split")
+
+	_, err = NewCSVLayout(config.CSVOutputConfig{Preset: "quicken"})
+	assert.EqualError(t, err, `export.csv.preset: unknown preset "quicken" (expected default, ynab)`)
+
+	_, err = NewCSVLayout(config.CSVOutputConfig{Columns: []config.CSVOutputColumn{{Field: "payee"}}})
+	assert.ErrorContains(t, err, `export.csv.columns[0]: unknown field "payee" (expected date, account, `)
+}
@@ -0,0 +1,113 @@
+package export
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/example/statement-extractor/internal/config"
+)
+
+// shortener fits descriptions into the length a target allows. A nil
+// shortener leaves descriptions as they are.
+type shortener struct {
+	max    int // in characters
+	policy string
+}
+
+// newShortener validates the description limit of the export section named
+// by section, returning nil when there is no limit
+func newShortener(section string, cfg config.DescriptionLimit) (*shortener, error) {
+	if cfg.MaxLength < 0 {
+		return nil, fmt.Errorf("%s.max_length: must not be negative", section)
+	}
+	policy := strings.ToLower(cfg.Policy)
+	switch policy {
+	case "":
+		policy = "truncate"
+	case "truncate", "ellipsis", "abbreviate":
+	default:
+		return nil, fmt.Errorf("%s.policy: unknown policy %q (expected truncate, ellipsis or abbreviate)", section, cfg.Policy)
+	}
+	if cfg.MaxLength == 0 {
+		return nil, nil
+	}
+	return &shortener{max: cfg.MaxLength, policy: policy}, nil
+}
+
+// shorten returns description cut to the limit, and whether it was cut.
+// truncate keeps the first characters; ellipsis ends the cut description
+// with "..."; abbreviate first collapses spaces, drops words with digits
+// after the first, such as card numbers and references, from the end and
+// takes the vowels out of long words, truncating only if that is not enough.
+func (s *shortener) shorten(description string) (string, bool) {
+	if s == nil || len([]rune(description)) <= s.max {
+		return description, false
+	}
+	switch s.policy {
+	case "ellipsis":
+		if s.max > 3 {
+			return truncate(description, s.max-3) + "...", true
+		}
+	case "abbreviate":
+		return truncate(abbreviate(description, s.max), s.max), true
+	}
+	return truncate(description, s.max), true
+}
+
+// truncate returns the first n characters of s without trailing spaces
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		s = string(r[:n])
+	}
+	return strings.TrimRightFunc(s, unicode.IsSpace)
+}
+
+// abbreviate shortens the words of s towards n characters
+func abbreviate(s string, n int) string {
+	words := strings.Fields(s)
+	length := func() int { return len([]rune(strings.Join(words, " "))) }
+
+	for i := len(words) - 1; i > 0 && length() > n; i-- {
+		if strings.ContainsFunc(words[i], unicode.IsDigit) {
+			words = slices.Delete(words, i, i+1)
+		}
+	}
+	for length() > n {
+		longest := -1
+		for i, word := range words {
+			if len([]rune(word)) > 3 && stripVowels(word) != word && (longest < 0 || len([]rune(word)) > len([]rune(words[longest]))) {
+				longest = i
+			}
+		}
+		if longest < 0 {
+			break
+		}
+		words[longest] = stripVowels(words[longest])
+	}
+	return strings.Join(words, " ")
+}
+
+// stripVowels removes the vowels after the first letter of word
+func stripVowels(word string) string {
+	r := []rune(word)
+	kept := []rune{r[0]}
+	for _, c := range r[1:] {
+		if !strings.ContainsRune("aeiouAEIOU", c) {
+			kept = append(kept, c)
+		}
+	}
+	return string(kept)
+}
+
+// fullDescription joins the full description, when it was cut, to a memo
+func fullDescription(description string, cut bool, memo string) string {
+	if !cut {
+		return memo
+	}
+	if memo == "" {
+		return description
+	}
+	return description + "; " + memo
+}
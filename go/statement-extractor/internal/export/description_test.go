@@ -0,0 +1,37 @@
+package export
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+)
+
+func TestShorten(t *testing.T) {
+	shorten := func(policy string, max int, description string) string {
+		s, err := newShortener("export.csv.description", config.DescriptionLimit{MaxLength: max, Policy: policy})
+		require.NoError(t, err)
+		short, _ := s.shorten(description)
+		return short
+	}
+
+	assert.Equal(t, "WOOLWORTHS", shorten("truncate", 11, "WOOLWORTHS 1234 SYDNEY"), "trailing spaces are trimmed")
+	assert.Equal(t, "WOOLWORT...", shorten("ellipsis", 11, "WOOLWORTHS 1234 SYDNEY"))
+	assert.Equal(t, "WOOLWORTHS SYDNEY 1234", shorten("ellipsis", 30, "WOOLWORTHS SYDNEY 1234"), "short enough already")
+	assert.Equal(t, "WOOLWORTHS SYDNEY", shorten("abbreviate", 17, "WOOLWORTHS 7-ELEVEN SYDNEY 1234"))
+	assert.Equal(t, "WLWRTHS SYDNEY", shorten("abbreviate", 14, "WOOLWORTHS SYDNEY 1234"))
+	assert.Equal(t, "WLWRTHS SYD", shorten("abbreviate", 11, "WOOLWORTHS SYDNEY 1234"), "truncated when abbreviating is not enough")
+	assert.Equal(t, "Café", shorten("truncate", 4, "Café Nero"), "lengths are in characters")
+
+	s, err := newShortener("export.csv.description", config.DescriptionLimit{})
+	require.NoError(t, err)
+	assert.Nil(t, s, "no limit")
+	short, cut := s.shorten("WOOLWORTHS")
+	assert.Equal(t, "WOOLWORTHS", short)
+	assert.False(t, cut)
+
+	_, err = newShortener("export.csv.description", config.DescriptionLimit{MaxLength: 10, Policy: "squash"})
+	assert.ErrorContains(t, err, "export.csv.description.policy")
+}
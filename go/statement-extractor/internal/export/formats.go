@@ -0,0 +1,52 @@
+package export
+
+import (
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Format is a file format read by another finance tool
+type Format struct {
+	Name        string
+	Description string
+	Extension   string // e.g. ".csv"
+	Write       func(w io.Writer, txns []transaction.Transaction, cfg config.ExportConfig) error
+}
+
+// formats are the supported file formats, keyed by name
+var formats = map[string]Format{
+	"beancount": {Name: "beancount", Description: "Beancount ledger", Extension: ".beancount", Write: writeBeancount},
+	"csv":       {Name: "csv", Description: "CSV file with configurable columns", Extension: ".csv", Write: writeCSVLayout},
+	"homebank":  {Name: "homebank", Description: "HomeBank CSV import file", Extension: ".csv", Write: writeHomeBank},
+	"ledger":    {Name: "ledger", Description: "ledger/hledger journal", Extension: ".journal", Write: writeLedger},
+	"mmex":      {Name: "mmex", Description: "Money Manager EX CSV import file", Extension: ".csv", Write: writeMMEX},
+}
+
+// Lookup returns the named file format
+func Lookup(name string) (Format, bool) {
+	f, ok := formats[strings.ToLower(name)]
+	return f, ok
+}
+
+// Formats returns the supported file formats sorted by name
+func Formats() []Format {
+	list := make([]Format, 0, len(formats))
+	for _, f := range formats {
+		list = append(list, f)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// memo is the note exported with a transaction: a reference to the
+// statement it came from, if known
+func memo(t transaction.Transaction) string {
+	if t.Statement == nil {
+		return ""
+	}
+	return t.Statement.String()
+}
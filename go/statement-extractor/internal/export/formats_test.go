@@ -0,0 +1,54 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+var formatTxns = []transaction.Transaction{
+	{ID: "T1", Date: date("2024-01-03"), Description: "WOOLWORTHS 1234", Amount: -5410, Category: "Groceries:Supermarket", Account: "Joint Everyday",
+		Statement: &transaction.StatementRef{File: "jan.pdf", SHA256: "9f86d081884c7d659a2feaa0c55ad015", Page: 1}},
+	{Date: date("2024-01-15"), Description: "SALARY; ACME", Amount: 250000, Category: "Income", Source: "CBA"},
+}
+
+func TestFormats(t *testing.T) {
+	var names []string
+	for _, f := range Formats() {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"beancount", "csv", "homebank", "ledger", "mmex"}, names)
+}
+
+func TestWriteHomeBank(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, WriteHomeBank(&b, formatTxns))
+	assert.Equal(t, "date;payment;info;payee;memo;amount;category;tags\n"+
+		"03-01-24;0;;WOOLWORTHS 1234;jan.pdf p.1 sha256:9f86d081884c;-54.10;Groceries:Supermarket;Joint_Everyday\n"+
+		"15-01-24;0;;\"SALARY; ACME\";;2500.00;Income;CBA\n", b.String())
+}
+
+func TestWriteHomeBankDescriptionLimit(t *testing.T) {
+	format, ok := Lookup("homebank")
+	require.True(t, ok)
+	cfg := config.ExportConfig{HomeBank: config.HomeBankConfig{Description: config.DescriptionLimit{MaxLength: 10}}}
+
+	var b bytes.Buffer
+	require.NoError(t, format.Write(&b, formatTxns, cfg))
+	assert.Equal(t, "date;payment;info;payee;memo;amount;category;tags\n"+
+		"03-01-24;0;;WOOLWORTHS;\"WOOLWORTHS 1234; jan.pdf p.1 sha256:9f86d081884c\";-54.10;Groceries:Supermarket;Joint_Everyday\n"+
+		"15-01-24;0;;\"SALARY; AC\";\"SALARY; ACME\";2500.00;Income;CBA\n", b.String(), "the memo keeps the full description")
+}
+
+func TestWriteMMEX(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, WriteMMEX(&b, formatTxns))
+	assert.Equal(t, "Date,Payee,Amount,Category,SubCategory,Number,Notes\n"+
+		"2024-01-03,WOOLWORTHS 1234,-54.10,Groceries,Supermarket,T1,jan.pdf p.1 sha256:9f86d081884c\n"+
+		"2024-01-15,SALARY; ACME,2500.00,Income,,,\n", b.String())
+}
@@ -0,0 +1,60 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// WriteHomeBank writes transactions in HomeBank's CSV import layout:
+// semicolon-separated date;payment;info;payee;memo;amount;category;tags with
+// DD-MM-YY dates. Categories keep a "Parent:Child" form as HomeBank
+// subcategories.
+func WriteHomeBank(w io.Writer, txns []transaction.Transaction) error {
+	return homeBank(w, txns, nil)
+}
+
+// writeHomeBank writes descriptions cut to export.homebank.description, with
+// the full description in the memo
+func writeHomeBank(w io.Writer, txns []transaction.Transaction, cfg config.ExportConfig) error {
+	s, err := newShortener("export.homebank.description", cfg.HomeBank.Description)
+	if err != nil {
+		return err
+	}
+	return homeBank(w, txns, s)
+}
+
+func homeBank(w io.Writer, txns []transaction.Transaction, s *shortener) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = ';'
+	_ = cw.Write([]string{"date", "payment", "info", "payee", "memo", "amount", "category", "tags"})
+	for _, t := range txns {
+		payee, cut := s.shorten(t.Description)
+		_ = cw.Write([]string{
+			t.Date.Format("02-01-06"),
+			"0", // payment type: none
+			"",
+			payee,
+			fullDescription(t.Description, cut, memo(t)),
+			formatAmount(t.Amount),
+			t.Category,
+			homeBankTags(t),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// homeBankTags tags transactions with their account, as HomeBank imports
+// into one account at a time, followed by their own tags. HomeBank tags are
+// space-separated, so spaces within a tag become underscores.
+func homeBankTags(t transaction.Transaction) string {
+	tags := []string{strings.Join(strings.Fields(AccountOf(t)), "_")}
+	for _, tag := range t.Tags {
+		tags = append(tags, strings.Join(strings.Fields(tag), "_"))
+	}
+	return strings.Join(tags, " ")
+}
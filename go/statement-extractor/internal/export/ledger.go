@@ -0,0 +1,93 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Ledger writes ledger-cli journals, which hledger also reads. Each
+// transaction becomes two postings: the amount against its category's
+// expense or income account, balanced by the statement's asset account. A
+// split transaction has a category posting per split.
+type Ledger struct {
+	accounts     *accountTemplates
+	descriptions *shortener
+}
+
+// NewLedger compiles the account name templates in cfg
+func NewLedger(cfg config.LedgerConfig) (*Ledger, error) {
+	accounts, err := compileAccounts("export.ledger", cfg.AssetAccount, cfg.ExpenseAccount, cfg.IncomeAccount)
+	if err != nil {
+		return nil, err
+	}
+	descriptions, err := newShortener("export.ledger.description", cfg.Description)
+	if err != nil {
+		return nil, err
+	}
+	return &Ledger{accounts: accounts, descriptions: descriptions}, nil
+}
+
+func writeLedger(w io.Writer, txns []transaction.Transaction, cfg config.ExportConfig) error {
+	l, err := NewLedger(cfg.Ledger)
+	if err != nil {
+		return err
+	}
+	return l.Write(w, txns)
+}
+
+// Write writes txns as journal entries in the order given. The transaction
+// ID, when set, becomes the entry's code and the statement reference a
+// comment, as does the full description when the payee is cut.
+func (l *Ledger) Write(w io.Writer, txns []transaction.Transaction) error {
+	bw := bufio.NewWriter(w)
+	for i, t := range txns {
+		postings, asset, err := l.accounts.postings(t, ledgerAccountName)
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			fmt.Fprintln(bw)
+		}
+		fmt.Fprint(bw, t.Date.Format("2006-01-02"))
+		if t.ID != "" {
+			fmt.Fprintf(bw, " (%s)", strings.ReplaceAll(t.ID, ")", ""))
+		}
+		payee, cut := l.descriptions.shorten(t.Description)
+		fmt.Fprintf(bw, " %s\n", ledgerPayee(payee))
+		if cut {
+			fmt.Fprintf(bw, "    ; description: %s\n", strings.Join(strings.Fields(t.Description), " "))
+		}
+		if ref := memo(t); ref != "" {
+			fmt.Fprintf(bw, "    ; statement: %s\n", ref)
+		}
+		for _, p := range postings {
+			amount := formatAmount(-p.amount)
+			if t.Currency != "" {
+				amount += " " + t.Currency
+			}
+			fmt.Fprintf(bw, "    %s  %s\n", p.account, amount)
+		}
+		fmt.Fprintf(bw, "    %s\n", asset)
+	}
+	return bw.Flush()
+}
+
+// ledgerAccountName folds runs of spaces, which would end the name in a
+// posting, into one
+func ledgerAccountName(name string) (string, error) {
+	return strings.Trim(strings.Join(strings.Fields(name), " "), ":"), nil
+}
+
+// ledgerPayee keeps a description from being read as a status mark, a code
+// or the start of a comment
+func ledgerPayee(description string) string {
+	payee := strings.TrimLeft(description, "*!( ")
+	payee = strings.ReplaceAll(payee, ";", ",")
+	return strings.Join(strings.Fields(payee), " ")
+}
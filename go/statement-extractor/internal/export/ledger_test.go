@@ -0,0 +1,85 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+var ledgerConfig = config.LedgerConfig{
+	AssetAccount:   "Assets:{{ .Source }}",
+	ExpenseAccount: "Expenses:{{ .Category }}",
+	IncomeAccount:  "Income:{{ .Category }}",
+}
+
+func TestLedgerWrite(t *testing.T) {
+	l, err := NewLedger(ledgerConfig)
+	require.NoError(t, err)
+
+	txns := append([]transaction.Transaction{}, formatTxns...)
+	txns[0].Source, txns[0].Currency = "ANZ", "AUD"
+	txns = append(txns, transaction.Transaction{Date: date("2024-01-20"), Description: "* CAFE  NERO", Amount: -450, Source: "CBA"})
+
+	var b bytes.Buffer
+	require.NoError(t, l.Write(&b, txns))
+	assert.Equal(t, `2024-01-03 (T1) WOOLWORTHS 1234
+    ; statement: jan.pdf p.1 sha256:9f86d081884c
+    Expenses:Groceries:Supermarket  54.10 AUD
+    Assets:ANZ
+
+2024-01-15 SALARY, ACME
+    Income:Income  -2500.00
+    Assets:CBA
+
+2024-01-20 CAFE NERO
+    Expenses:Uncategorized  4.50
+    Assets:CBA
+`, b.String())
+}
+
+func TestLedgerWriteSplits(t *testing.T) {
+	l, err := NewLedger(ledgerConfig)
+	require.NoError(t, err)
+
+	txns := []transaction.Transaction{{Date: date("2024-01-03"), Description: "COLES", Amount: -10000, Category: "Groceries", Source: "CBA",
+		Splits: []transaction.Split{{Category: "Groceries", Amount: -7000}, {Category: "Household", Amount: -3000}}}}
+	var b bytes.Buffer
+	require.NoError(t, l.Write(&b, txns))
+	assert.Equal(t, `2024-01-03 COLES
+    Expenses:Groceries  70.00
+    Expenses:Household  30.00
+    Assets:CBA
+`, b.String())
+}
+
+func TestLedgerAccountTemplates(t *testing.T) {
+	cfg := ledgerConfig
+	cfg.AssetAccount = `Assets:Bank:{{ or .Account .Source }}`
+	l, err := NewLedger(cfg)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	require.NoError(t, l.Write(&b, formatTxns[:1]))
+	assert.Contains(t, b.String(), "    Assets:Bank:Joint Everyday\n")
+
+	cfg.IncomeAccount = ""
+	_, err = NewLedger(cfg)
+	assert.EqualError(t, err, "export.ledger.income_account is not set")
+
+	cfg.IncomeAccount = "Income:{{ .Merchant }}"
+	l, err = NewLedger(cfg)
+	require.NoError(t, err)
+	err = l.Write(&b, formatTxns[1:])
+	assert.ErrorContains(t, err, "export.ledger.income_account")
+
+	cfg.AssetAccount = "{{ .Source }}"
+	l, err = NewLedger(cfg)
+	require.NoError(t, err)
+	err = l.Write(&b, []transaction.Transaction{{Description: "X", Amount: -100}})
+	assert.ErrorContains(t, err, "export.ledger.asset_account renders an empty account name")
+}
@@ -0,0 +1,48 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// WriteMMEX writes transactions as CSV for Money Manager EX's CSV import,
+// with the columns Date,Payee,Amount,Category,SubCategory,Number,Notes and
+// ISO dates. A "Parent:Child" category is split into category and
+// subcategory.
+func WriteMMEX(w io.Writer, txns []transaction.Transaction) error {
+	return mmex(w, txns, nil)
+}
+
+// writeMMEX writes descriptions cut to export.mmex.description, with the
+// full description in the notes
+func writeMMEX(w io.Writer, txns []transaction.Transaction, cfg config.ExportConfig) error {
+	s, err := newShortener("export.mmex.description", cfg.MMEX.Description)
+	if err != nil {
+		return err
+	}
+	return mmex(w, txns, s)
+}
+
+func mmex(w io.Writer, txns []transaction.Transaction, s *shortener) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"Date", "Payee", "Amount", "Category", "SubCategory", "Number", "Notes"})
+	for _, t := range txns {
+		category, subcategory, _ := strings.Cut(t.Category, ":")
+		payee, cut := s.shorten(t.Description)
+		_ = cw.Write([]string{
+			t.Date.Format("2006-01-02"),
+			payee,
+			formatAmount(t.Amount),
+			strings.TrimSpace(category),
+			strings.TrimSpace(subcategory),
+			t.ID,
+			fullDescription(t.Description, cut, memo(t)),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,84 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/example/statement-extractor/internal/xlsx"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Ways WriteXLSX can split transactions into sheets
+const (
+	ByMonth   = "month"
+	ByAccount = "account"
+)
+
+// transactionHeader is the column layout of transaction sheets
+var transactionHeader = []string{"date", "description", "category", "amount", "balance", "currency", "account", "statement"}
+
+// WriteXLSX writes a workbook with a sheet of transactions per month or per
+// account, ordered by date, followed by a Categories sheet of the net amount
+// per category on each of those sheets. Money cells use currencyFormat, an
+// Excel number format such as money.Formatter.ExcelFormat returns.
+func WriteXLSX(w io.Writer, txns []transaction.Transaction, by, currencyFormat string) error {
+	var key func(transaction.Transaction) string
+	switch by {
+	case ByMonth:
+		key = func(t transaction.Transaction) string { return t.Date.Format("2006-01") }
+	case ByAccount:
+		key = AccountOf
+	default:
+		return fmt.Errorf("invalid split %q: expected month or account", by)
+	}
+
+	groups := make(map[string][]transaction.Transaction)
+	for _, t := range txns {
+		groups[key(t)] = append(groups[key(t)], t)
+	}
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	book := xlsx.New(currencyFormat)
+	for _, name := range names {
+		sheet := book.AddSheet(name, transactionHeader...)
+		rows := slices.Clone(groups[name])
+		transaction.SortByDate(rows)
+		for _, t := range rows {
+			var statement string
+			if t.Statement != nil {
+				statement = t.Statement.String()
+			}
+			var balance any
+			if t.Balance != nil {
+				balance = t.Balance.Float()
+			}
+			sheet.Append(t.Date, t.Description, t.Category, t.Amount.Float(), balance, t.Currency, AccountOf(t), statement)
+		}
+	}
+
+	p := newPivot(names, groups)
+	summary := book.AddSheet("Categories", append(append([]string{"category"}, names...), "total")...)
+	for _, row := range p.rows() {
+		values := []any{row.label}
+		for _, amount := range row.amounts {
+			values = append(values, amount.Float())
+		}
+		summary.Append(values...)
+	}
+	return book.Write(w)
+}
+
+// WriteXLSX writes the year as a single workbook: a sheet per account and
+// the category summary
+func (a *Annual) WriteXLSX(w io.Writer, currencyFormat string) error {
+	var txns []transaction.Transaction
+	for _, account := range a.Accounts {
+		txns = append(txns, a.accounts[account]...)
+	}
+	return WriteXLSX(w, txns, ByAccount, currencyFormat)
+}
@@ -0,0 +1,63 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sheetXML returns the workbook listing and each sheet's XML
+func sheetXML(t *testing.T, data []byte) (string, []string) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	read := func(name string) string {
+		f, err := r.Open(name)
+		require.NoError(t, err)
+		defer f.Close()
+		content, err := io.ReadAll(f)
+		require.NoError(t, err)
+		return string(content)
+	}
+	var sheets []string
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("xl/worksheets/sheet%d.xml", i)
+		if _, err := r.Open(name); err != nil {
+			break
+		}
+		sheets = append(sheets, read(name))
+	}
+	return read("xl/workbook.xml"), sheets
+}
+
+func TestWriteXLSX(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, WriteXLSX(&b, annualTxns, ByMonth, `"$"#,##0.00`))
+
+	workbook, sheets := sheetXML(t, b.Bytes())
+	assert.Contains(t, workbook, `name="2023-12"`)
+	assert.Contains(t, workbook, `name="2024-03"`)
+	assert.Contains(t, workbook, `name="Categories"`)
+	require.Len(t, sheets, 5)
+	assert.Contains(t, sheets[1], `jan.pdf p.2 sha256:9f86d081884c`, "2024-01 links the statement")
+	assert.Contains(t, sheets[4], `<t xml:space="preserve">Income</t>`)
+
+	err := WriteXLSX(&b, annualTxns, "week", "")
+	assert.EqualError(t, err, `invalid split "week": expected month or account`)
+}
+
+func TestAnnualWriteXLSX(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, NewAnnual(annualTxns, 2024).WriteXLSX(&b, ""))
+
+	workbook, sheets := sheetXML(t, b.Bytes())
+	assert.Contains(t, workbook, `name="ANZ"`)
+	assert.Contains(t, workbook, `name="Everyday"`)
+	require.Len(t, sheets, 3)
+	assert.NotContains(t, sheets[0], "LAST YEAR")
+}
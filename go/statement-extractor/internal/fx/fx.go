@@ -0,0 +1,184 @@
+// Package fx converts transaction amounts between currencies so reports on
+// accounts in several currencies can present one base currency.
+package fx
+
+import (
+	"cmp"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/parser"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// ECBDailyURL is the European Central Bank's feed of daily reference rates
+const ECBDailyURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+
+// Rates converts amounts to Base
+type Rates struct {
+	Base  string
+	rates map[string]float64 // units of Base per unit of each currency
+}
+
+// New returns rates converting to base; rates are in base units per unit of
+// each currency, e.g. USD 1.52 when one US dollar buys 1.52 of base
+func New(base string, rates map[string]float64) (*Rates, error) {
+	if base == "" {
+		return nil, errors.New("fx: no base currency; set fx.base or money.currency")
+	}
+	r := &Rates{Base: strings.ToUpper(base), rates: make(map[string]float64, len(rates))}
+	for code, rate := range rates {
+		if rate <= 0 {
+			return nil, fmt.Errorf("fx.rates.%s: rate must be positive", strings.ToUpper(code))
+		}
+		r.rates[strings.ToUpper(code)] = rate
+	}
+	return r, nil
+}
+
+// Load returns the rates configured in [fx], converting to cfg.Base or else
+// to base, or nil when conversion is not configured. The ECB source is
+// fetched, with the configured rates overriding it.
+func Load(ctx context.Context, cfg config.FXConfig, base string) (*Rates, error) {
+	base = cmp.Or(cfg.Base, base)
+	switch strings.ToLower(cfg.Source) {
+	case "", "static":
+		if len(cfg.Rates) == 0 {
+			return nil, nil
+		}
+		return New(base, cfg.Rates)
+	case "ecb":
+	default:
+		return nil, fmt.Errorf("unknown fx.source %q (expected static or ecb)", cfg.Source)
+	}
+
+	client, err := parser.NewHTTPClient(cfg.HTTP)
+	if err != nil {
+		return nil, fmt.Errorf("fx: %w", err)
+	}
+	euro, err := FetchECB(ctx, client, cmp.Or(cfg.ECBURL, ECBDailyURL))
+	if err != nil {
+		return nil, err
+	}
+	rates, err := CrossRates(euro, base)
+	if err != nil {
+		return nil, err
+	}
+	for code, rate := range cfg.Rates {
+		rates[strings.ToUpper(code)] = rate
+	}
+	return New(base, rates)
+}
+
+// Rate returns the base units per unit of currency. The base currency, and
+// no currency at all, are 1.
+func (r *Rates) Rate(currency string) (float64, bool) {
+	currency = strings.ToUpper(currency)
+	if currency == "" || currency == r.Base {
+		return 1, true
+	}
+	rate, ok := r.rates[currency]
+	return rate, ok
+}
+
+// Convert returns t with its amount, balance and splits in the base
+// currency, rounded to cents
+func (r *Rates) Convert(t transaction.Transaction) (transaction.Transaction, error) {
+	rate, ok := r.Rate(t.Currency)
+	if !ok {
+		return t, fmt.Errorf("no exchange rate from %s to %s; add it to [fx.rates]", strings.ToUpper(t.Currency), r.Base)
+	}
+	if rate == 1 {
+		return t, nil
+	}
+	t.Amount = t.Amount.Mul(rate)
+	if t.Balance != nil {
+		t.Balance = t.Balance.Mul(rate).Ptr()
+	}
+	if len(t.Splits) > 0 {
+		splits := make([]transaction.Split, len(t.Splits))
+		for i, s := range t.Splits {
+			splits[i] = transaction.Split{Category: s.Category, Amount: s.Amount.Mul(rate)}
+		}
+		t.Splits = splits
+	}
+	t.Currency = r.Base
+	return t, nil
+}
+
+// Apply converts each transaction to the base currency in place
+func (r *Rates) Apply(txns []transaction.Transaction) error {
+	for i, t := range txns {
+		converted, err := r.Convert(t)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", t.Date.Format("2006-01-02"), t.Description, err)
+		}
+		txns[i] = converted
+	}
+	return nil
+}
+
+// ecbEnvelope is the document at ECBDailyURL
+type ecbEnvelope struct {
+	Cube struct {
+		Cube struct {
+			Rates []struct {
+				Currency string  `xml:"currency,attr"`
+				Rate     float64 `xml:"rate,attr"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	} `xml:"Cube"`
+}
+
+// FetchECB fetches the ECB daily reference rates at url, in units of each
+// currency per euro
+func FetchECB(ctx context.Context, client *http.Client, url string) (map[string]float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching ECB rates: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("fetching ECB rates: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var doc ecbEnvelope
+	if err := xml.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decoding ECB rates: %w", err)
+	}
+	rates := map[string]float64{"EUR": 1}
+	for _, r := range doc.Cube.Cube.Rates {
+		if r.Rate > 0 {
+			rates[strings.ToUpper(r.Currency)] = r.Rate
+		}
+	}
+	if len(rates) == 1 {
+		return nil, errors.New("decoding ECB rates: no rates in the response")
+	}
+	return rates, nil
+}
+
+// CrossRates turns rates per euro into base units per unit of each currency
+func CrossRates(euro map[string]float64, base string) (map[string]float64, error) {
+	base = strings.ToUpper(base)
+	perEuro, ok := euro[base]
+	if !ok {
+		return nil, fmt.Errorf("fx: the ECB has no rate for the base currency %s", base)
+	}
+	rates := make(map[string]float64, len(euro))
+	for code, rate := range euro {
+		rates[code] = perEuro / rate
+	}
+	return rates, nil
+}
@@ -0,0 +1,107 @@
+package fx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+const ecbDaily = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<Cube>
+		<Cube time="2024-06-03">
+			<Cube currency="USD" rate="1.0842"/>
+			<Cube currency="AUD" rate="1.6290"/>
+			<Cube currency="NZD" rate="1.7605"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+func TestConvert(t *testing.T) {
+	rates, err := New("aud", map[string]float64{"usd": 1.5})
+	require.NoError(t, err)
+
+	txns := []transaction.Transaction{
+		{Description: "AMAZON.COM", Amount: -1001, Balance: transaction.Amount(10000).Ptr(), Currency: "USD",
+			Splits: []transaction.Split{{Category: "Books", Amount: -600}, {Category: "Music", Amount: -401}}},
+		{Description: "WOOLWORTHS", Amount: -4520, Currency: "AUD"},
+		{Description: "COLES", Amount: -1200},
+	}
+	require.NoError(t, rates.Apply(txns))
+	assert.Equal(t, transaction.Amount(-1502), txns[0].Amount, "amounts are rounded to cents")
+	assert.Equal(t, transaction.Amount(15000).Ptr(), txns[0].Balance)
+	assert.Equal(t, []transaction.Split{{Category: "Books", Amount: -900}, {Category: "Music", Amount: -602}}, txns[0].Splits)
+	assert.Equal(t, "AUD", txns[0].Currency)
+	assert.Equal(t, transaction.Amount(-4520), txns[1].Amount)
+	assert.Equal(t, transaction.Amount(-1200), txns[2].Amount, "no currency is the base currency")
+	assert.Empty(t, txns[2].Currency)
+
+	err = rates.Apply([]transaction.Transaction{{Description: "HARRODS", Amount: -100, Currency: "GBP"}})
+	assert.ErrorContains(t, err, "no exchange rate from GBP to AUD")
+
+	_, err = New("AUD", map[string]float64{"USD": 0})
+	assert.ErrorContains(t, err, "fx.rates.USD")
+}
+
+func TestLoad(t *testing.T) {
+	rates, err := Load(context.Background(), config.FXConfig{}, "AUD")
+	require.NoError(t, err)
+	assert.Nil(t, rates, "conversion is off without rates")
+
+	rates, err = Load(context.Background(), config.FXConfig{Base: "NZD", Rates: map[string]float64{"aud": 1.08}}, "AUD")
+	require.NoError(t, err)
+	assert.Equal(t, "NZD", rates.Base)
+	rate, ok := rates.Rate("AUD")
+	assert.True(t, ok)
+	assert.Equal(t, 1.08, rate)
+
+	_, err = Load(context.Background(), config.FXConfig{Source: "oanda"}, "AUD")
+	assert.ErrorContains(t, err, "unknown fx.source")
+}
+
+func TestLoadECB(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, ecbDaily)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.FXConfig{Source: "ecb", ECBURL: server.URL, Rates: map[string]float64{"nzd": 0.9}}
+	rates, err := Load(context.Background(), cfg, "AUD")
+	require.NoError(t, err)
+
+	usd, _ := rates.Rate("USD")
+	assert.InDelta(t, 1.6290/1.0842, usd, 1e-9, "rates are crossed through the euro")
+	eur, _ := rates.Rate("EUR")
+	assert.InDelta(t, 1.6290, eur, 1e-9)
+	nzd, _ := rates.Rate("NZD")
+	assert.Equal(t, 0.9, nzd, "configured rates override the ECB")
+
+	cfg.Base = "JPY"
+	_, err = Load(context.Background(), cfg, "AUD")
+	assert.ErrorContains(t, err, "no rate for the base currency JPY")
+}
+
+func TestFetchECBErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/empty" {
+			fmt.Fprint(w, `<Envelope><Cube/></Envelope>`)
+			return
+		}
+		http.Error(w, "maintenance", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	_, err := FetchECB(context.Background(), server.Client(), server.URL)
+	assert.ErrorContains(t, err, "503")
+	_, err = FetchECB(context.Background(), server.Client(), server.URL+"/empty")
+	assert.ErrorContains(t, err, "no rates")
+}
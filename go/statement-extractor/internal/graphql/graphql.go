@@ -0,0 +1,397 @@
+// Package graphql serves a read-only GraphQL API over the transaction store,
+// for frontends that want transactions, accounts, categories and report
+// totals in one round trip with only the fields they show.
+//
+// The executor is deliberately small: it runs queries (not mutations or
+// subscriptions) with variables, aliases, fragments and @skip/@include
+// against object types whose resolvers are plain functions. Argument and
+// variable types are checked by the resolvers rather than by a schema, and
+// there is no introspection; the schema is published as SDL instead.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Type is an object type, its name and how each of its fields is resolved
+type Type struct {
+	Name   string
+	Fields map[string]Resolver
+}
+
+// Resolver returns the value of a field of source. Values are scalars
+// (string, int, float64, bool or nil), Objects, or slices of either.
+type Resolver func(ctx context.Context, source any, args map[string]any) (any, error)
+
+// Object is a value of an object type
+type Object struct {
+	Type  *Type
+	Value any
+}
+
+// Request is a GraphQL request as sent over HTTP
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Response is the result of a request. Data is absent when the request
+// could not be executed at all, and null fields are listed in Errors.
+type Response struct {
+	Data   any     `json:"data,omitempty"`
+	Errors []Error `json:"errors,omitempty"`
+}
+
+// Error is a request or field error
+type Error struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// Execute runs the query in req with root as the Query object
+func Execute(ctx context.Context, root Object, req Request) Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	e := &executor{ctx: ctx, doc: doc, variables: make(map[string]any)}
+	for _, def := range op.variables {
+		if v, ok := req.Variables[def.name]; ok {
+			e.variables[def.name] = v
+		} else if def.defaultVal != nil {
+			e.variables[def.name] = e.value(def.defaultVal)
+		}
+		e.declared = append(e.declared, def.name)
+	}
+
+	data, err := e.selectionSet(root, op.selection, nil)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	return Response{Data: data, Errors: e.errors}
+}
+
+// queryError is a mistake in the query itself, such as an unknown field,
+// which fails the whole request rather than nulling one field
+type queryError struct{ msg string }
+
+func (e queryError) Error() string { return e.msg }
+
+func queryErrorf(format string, args ...any) error {
+	return queryError{fmt.Sprintf(format, args...)}
+}
+
+// operation picks the operation to run: the one named, or the only one
+func (d *document) operation(name string) (*operation, error) {
+	var op *operation
+	for _, o := range d.operations {
+		if name == "" || o.name == name {
+			if op != nil {
+				return nil, fmt.Errorf("the document has several operations; set operationName")
+			}
+			op = o
+		}
+	}
+	if op == nil {
+		return nil, fmt.Errorf("unknown operation %q", name)
+	}
+	if op.kind != "query" {
+		return nil, fmt.Errorf("%s operations are not supported; the API is read-only", op.kind)
+	}
+	return op, nil
+}
+
+type executor struct {
+	ctx       context.Context
+	doc       *document
+	variables map[string]any
+	declared  []string
+	errors    []Error
+}
+
+// field is one entry of a result object; fields keep their order in the
+// query, as the spec requires of the response
+type field struct {
+	key   string
+	value any
+}
+
+type fields []field
+
+// MarshalJSON encodes the fields as an object in query order
+func (f fields) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, fd := range f {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(fd.key)
+		b.Write(key)
+		b.WriteByte(':')
+		value, err := json.Marshal(fd.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// fieldGroup is the fields of a selection set that share a response key,
+// merged from fragments
+type fieldGroup struct {
+	key    string
+	fields []selection
+}
+
+// selectionSet resolves the fields selected from obj. Only errors in the
+// query itself fail the set; resolver errors null the field.
+func (e *executor) selectionSet(obj Object, set []selection, path []any) (fields, error) {
+	groups, err := e.collect(obj.Type, set, nil, make(map[string]bool))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(fields, 0, len(groups))
+	for _, g := range groups {
+		name := g.fields[0].name
+		fieldPath := append(path[:len(path):len(path)], g.key)
+		if name == "__typename" {
+			result = append(result, field{g.key, obj.Type.Name})
+			continue
+		}
+		resolve, ok := obj.Type.Fields[name]
+		if !ok {
+			return nil, queryErrorf("cannot query field %q on type %q", name, obj.Type.Name)
+		}
+		args, err := e.arguments(g.fields[0].arguments)
+		if err != nil {
+			return nil, err
+		}
+
+		var sub []selection
+		for _, f := range g.fields {
+			sub = append(sub, f.selection...)
+		}
+		value, err := resolve(e.ctx, obj.Value, args)
+		if err == nil {
+			value, err = e.complete(name, value, sub, fieldPath)
+		}
+		var qe queryError
+		if errors.As(err, &qe) {
+			return nil, err
+		}
+		if err != nil {
+			e.errors = append(e.errors, Error{Message: err.Error(), Path: fieldPath})
+			value = nil
+		}
+		result = append(result, field{g.key, value})
+	}
+	return result, nil
+}
+
+// complete resolves the selection of an object value, or of each element
+// of a list
+func (e *executor) complete(name string, value any, set []selection, path []any) (any, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case Object:
+		if len(set) == 0 {
+			return nil, fmt.Errorf("field %q of type %q must have a selection of subfields", name, v.Type.Name)
+		}
+		return e.selectionSet(v, set, path)
+	case []Object:
+		if len(set) == 0 && len(v) > 0 {
+			return nil, fmt.Errorf("field %q of type %q must have a selection of subfields", name, v[0].Type.Name)
+		}
+		list := make([]any, len(v))
+		for i, o := range v {
+			elem, err := e.selectionSet(o, set, append(path[:len(path):len(path)], i))
+			if err != nil {
+				return nil, err
+			}
+			list[i] = elem
+		}
+		return list, nil
+	}
+	if len(set) > 0 {
+		return nil, fmt.Errorf("field %q is a scalar and has no subfields", name)
+	}
+	return value, nil
+}
+
+// collect groups the fields of a selection set by response key, expanding
+// the fragments that apply to typ and leaving out skipped fields
+func (e *executor) collect(typ *Type, set []selection, groups []fieldGroup, visited map[string]bool) ([]fieldGroup, error) {
+	for _, sel := range set {
+		include, err := e.included(sel.directives)
+		if err != nil {
+			return nil, err
+		}
+		if !include {
+			continue
+		}
+
+		switch {
+		case sel.spread != "":
+			frag, ok := e.doc.fragments[sel.spread]
+			if !ok {
+				return nil, queryErrorf("unknown fragment %q", sel.spread)
+			}
+			if visited[sel.spread] || frag.typeCondition != typ.Name {
+				continue
+			}
+			visited[sel.spread] = true
+			if groups, err = e.collect(typ, frag.selection, groups, visited); err != nil {
+				return nil, err
+			}
+		case sel.inline:
+			if sel.typeCondition != "" && sel.typeCondition != typ.Name {
+				continue
+			}
+			if groups, err = e.collect(typ, sel.selection, groups, visited); err != nil {
+				return nil, err
+			}
+		default:
+			key := sel.alias
+			if key == "" {
+				key = sel.name
+			}
+			i := 0
+			for i < len(groups) && groups[i].key != key {
+				i++
+			}
+			if i == len(groups) {
+				groups = append(groups, fieldGroup{key: key})
+			} else if groups[i].fields[0].name != sel.name {
+				return nil, queryErrorf("fields %q and %q conflict because they are both returned as %q", groups[i].fields[0].name, sel.name, key)
+			}
+			groups[i].fields = append(groups[i].fields, sel)
+		}
+	}
+	return groups, nil
+}
+
+// included evaluates @skip and @include
+func (e *executor) included(dirs []directive) (bool, error) {
+	for _, d := range dirs {
+		if d.name != "skip" && d.name != "include" {
+			return false, queryErrorf("unknown directive @%s", d.name)
+		}
+		args, err := e.arguments(d.arguments)
+		if err != nil {
+			return false, err
+		}
+		cond, ok := args["if"].(bool)
+		if !ok {
+			return false, queryErrorf("@%s needs a Boolean if argument", d.name)
+		}
+		if cond == (d.name == "skip") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// arguments substitutes variables into argument literals
+func (e *executor) arguments(literals map[string]any) (map[string]any, error) {
+	args := make(map[string]any, len(literals))
+	for name, lit := range literals {
+		if err := e.checkVariables(lit); err != nil {
+			return nil, err
+		}
+		args[name] = e.value(lit)
+	}
+	return args, nil
+}
+
+func (e *executor) checkVariables(lit any) error {
+	switch v := lit.(type) {
+	case variable:
+		for _, name := range e.declared {
+			if name == string(v) {
+				return nil
+			}
+		}
+		return queryErrorf("variable $%s is not defined by the operation", v)
+	case []any:
+		for _, elem := range v {
+			if err := e.checkVariables(elem); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		for _, elem := range v {
+			if err := e.checkVariables(elem); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// value turns a literal into the plain value a resolver sees: variables
+// substituted, enums as strings
+func (e *executor) value(lit any) any {
+	switch v := lit.(type) {
+	case variable:
+		return e.variables[string(v)]
+	case enum:
+		return string(v)
+	case []any:
+		list := make([]any, len(v))
+		for i, elem := range v {
+			list[i] = e.value(elem)
+		}
+		return list
+	case map[string]any:
+		obj := make(map[string]any, len(v))
+		for k, elem := range v {
+			obj[k] = e.value(elem)
+		}
+		return obj
+	}
+	return lit
+}
+
+// IntArg returns the Int argument name, or def when it is absent or null.
+// Numbers from JSON variables arrive as float64 and must be whole.
+func IntArg(args map[string]any, name string, def int) (int, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return def, nil
+	case int:
+		return v, nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) <= math.MaxInt32 {
+			return int(v), nil
+		}
+	}
+	return 0, fmt.Errorf("argument %q must be an Int", name)
+}
+
+// StringArg returns the String argument name, or "" when it is absent or
+// null
+func StringArg(args map[string]any, name string) (string, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	}
+	return "", fmt.Errorf("argument %q must be a String", name)
+}
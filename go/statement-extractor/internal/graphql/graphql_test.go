@@ -0,0 +1,250 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/store"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// fakeStore filters by date, category and account, and sums by category
+// or account, counting split transactions in each of their categories,
+// like the SQLite store
+type fakeStore struct {
+	txns    []transaction.Transaction
+	filters []store.Filter
+}
+
+func (s *fakeStore) List(_ context.Context, f store.Filter) ([]transaction.Transaction, error) {
+	s.filters = append(s.filters, f)
+	var out []transaction.Transaction
+	for _, t := range s.txns {
+		inCategory := f.Category == ""
+		for _, part := range t.Allocations() {
+			inCategory = inCategory || part.Category == f.Category
+		}
+		if (f.From.IsZero() || !t.Date.Before(f.From)) && (f.To.IsZero() || t.Date.Before(f.To)) &&
+			inCategory && (f.Account == "" || t.Account == f.Account) {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func (s *fakeStore) Sum(ctx context.Context, f store.Filter, by string) ([]store.Total, error) {
+	txns, _ := s.List(ctx, f)
+	var totals []store.Total
+	for _, t := range transaction.ExpandSplits(txns) {
+		if f.Category != "" && t.Category != f.Category {
+			continue
+		}
+		group := t.Category
+		if by == "account" {
+			group = t.Account
+		}
+		i := 0
+		for i < len(totals) && totals[i].Group != group {
+			i++
+		}
+		if i == len(totals) {
+			totals = append(totals, store.Total{Group: group})
+		}
+		totals[i].Count++
+		totals[i].Amount += t.Amount
+	}
+	return totals, nil
+}
+
+func date(s string) time.Time {
+	d, _ := time.Parse(time.DateOnly, s)
+	return d
+}
+
+func sample() *fakeStore {
+	return &fakeStore{txns: []transaction.Transaction{
+		{Date: date("2024-05-30"), Description: "WOOLWORTHS", Amount: -8215, Category: "Groceries", Account: "Everyday", Tags: []string{"home"}},
+		{Date: date("2024-06-01"), Description: "SALARY", Amount: 300000, Category: "Income", Account: "Everyday",
+			Statement: &transaction.StatementRef{File: "jun.pdf", Page: 1}},
+		{Date: date("2024-06-02"), Description: "COLES", Amount: -4550, Category: "Groceries", Account: "Credit"},
+		{Date: date("2024-06-03"), Description: "NETFLIX", Amount: -2299, Category: "Subscriptions", Account: "Credit"},
+	}}
+}
+
+func run(t *testing.T, db Store, query string, vars map[string]any) Response {
+	t.Helper()
+	return Execute(context.Background(), Query(db), Request{Query: query, Variables: vars})
+}
+
+func toJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestTransactionsPagination(t *testing.T) {
+	db := sample()
+	query := `query Page($after: String) {
+		transactions(first: 2, after: $after) {
+			totalCount
+			nodes { date description amount }
+			pageInfo { hasNextPage endCursor }
+		}
+	}`
+
+	resp := run(t, db, query, nil)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"transactions": {
+		"totalCount": 4,
+		"nodes": [
+			{"date": "2024-05-30", "description": "WOOLWORTHS", "amount": -82.15},
+			{"date": "2024-06-01", "description": "SALARY", "amount": 3000}
+		],
+		"pageInfo": {"hasNextPage": true, "endCursor": "b2Zmc2V0OjE="}
+	}}`, toJSON(t, resp.Data))
+
+	resp = run(t, db, query, map[string]any{"after": "b2Zmc2V0OjE="})
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"transactions": {
+		"totalCount": 4,
+		"nodes": [
+			{"date": "2024-06-02", "description": "COLES", "amount": -45.5},
+			{"date": "2024-06-03", "description": "NETFLIX", "amount": -22.99}
+		],
+		"pageInfo": {"hasNextPage": false, "endCursor": "b2Zmc2V0OjM="}
+	}}`, toJSON(t, resp.Data))
+}
+
+func TestFilterAndFragments(t *testing.T) {
+	db := sample()
+	resp := run(t, db, `
+		query ($period: String!, $withTags: Boolean = false) {
+			june: transactions(filter: {period: $period, category: "Groceries"}) {
+				total
+				edges { node { ...row } }
+			}
+			categories(filter: {period: $period}) {
+				__typename name total
+				transactions(first: 1) { nodes { ... on Transaction { description } } }
+			}
+		}
+		fragment row on Transaction { description tags @include(if: $withTags) statement { file } }`,
+		map[string]any{"period": "2024-06"})
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{
+		"june": {"total": -45.5, "edges": [{"node": {"description": "COLES", "statement": null}}]},
+		"categories": [
+			{"__typename": "Category", "name": "Income", "total": 3000, "transactions": {"nodes": [{"description": "SALARY"}]}},
+			{"__typename": "Category", "name": "Groceries", "total": -45.5, "transactions": {"nodes": [{"description": "COLES"}]}},
+			{"__typename": "Category", "name": "Subscriptions", "total": -22.99, "transactions": {"nodes": [{"description": "NETFLIX"}]}}
+		]
+	}`, toJSON(t, resp.Data))
+	assert.Equal(t, date("2024-06-01"), db.filters[0].From)
+	assert.Equal(t, date("2024-07-01"), db.filters[0].To)
+}
+
+func TestCategorySplits(t *testing.T) {
+	db := sample()
+	db.txns = append(db.txns, transaction.Transaction{Date: date("2024-06-04"), Description: "BIG W", Amount: -10000, Category: "Groceries", Account: "Credit",
+		Splits: []transaction.Split{{Category: "Groceries", Amount: -3000}, {Category: "Household", Amount: -7000}}})
+	resp := run(t, db, `{
+		categories(filter: {period: "2024-06"}) {
+			name transactionCount total
+			transactions { totalCount total nodes { description } }
+		}
+	}`, nil)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"categories": [
+		{"name": "Income", "transactionCount": 1, "total": 3000, "transactions": {"totalCount": 1, "total": 3000, "nodes": [{"description": "SALARY"}]}},
+		{"name": "Groceries", "transactionCount": 2, "total": -75.5, "transactions": {"totalCount": 2, "total": -75.5, "nodes": [{"description": "COLES"}, {"description": "BIG W"}]}},
+		{"name": "Subscriptions", "transactionCount": 1, "total": -22.99, "transactions": {"totalCount": 1, "total": -22.99, "nodes": [{"description": "NETFLIX"}]}},
+		{"name": "Household", "transactionCount": 1, "total": -70, "transactions": {"totalCount": 1, "total": -70, "nodes": [{"description": "BIG W"}]}}
+	]}`, toJSON(t, resp.Data))
+	last := db.filters[len(db.filters)-1]
+	assert.Equal(t, "Household", last.Category, "the category is selected by the store")
+}
+
+func TestReport(t *testing.T) {
+	resp := run(t, sample(), `{ report(by: account) { group count amount } }`, nil)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"report": [
+		{"group": "Everyday", "count": 2, "amount": 2917.85},
+		{"group": "Credit", "count": 2, "amount": -68.49}
+	]}`, toJSON(t, resp.Data))
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name, query string
+		data        bool // whether the query still returns data
+		want        string
+	}{
+		{"syntax", `{ transactions { nodes { date }`, false, "unexpected end of document"},
+		{"unknown field", `{ transactions { nodes { payee } } }`, false, `cannot query field "payee" on type "Transaction"`},
+		{"mutation", `mutation { transactions { totalCount } }`, false, "the API is read-only"},
+		{"undefined variable", `{ transactions(first: $n) { totalCount } }`, false, "variable $n is not defined"},
+		{"no subfields", `{ transactions }`, true, "must have a selection of subfields"},
+		{"bad argument", `{ transactions(first: 1000) { totalCount } }`, true, "first must be between 0 and 500"},
+		{"bad cursor", `{ transactions(after: "nope") { totalCount } }`, true, `invalid cursor "nope"`},
+		{"bad filter", `{ accounts(filter: {payee: "x"}) { name } }`, true, `unknown filter field "payee"`},
+		{"bad grouping", `{ report(by: "day") { group } }`, true, `invalid by "day"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := run(t, sample(), tt.query, nil)
+			require.Len(t, resp.Errors, 1)
+			assert.Contains(t, resp.Errors[0].Message, tt.want)
+			assert.Equal(t, tt.data, resp.Data != nil)
+		})
+	}
+
+	resp := run(t, sample(), `{ ok: accounts { name } bad: transactions(first: -1) { totalCount } }`, nil)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, []any{"bad"}, resp.Errors[0].Path)
+	assert.JSONEq(t, `{"ok": [{"name": "Everyday"}, {"name": "Credit"}], "bad": null}`, toJSON(t, resp.Data))
+}
+
+func TestHandler(t *testing.T) {
+	srv := httptest.NewServer(Handler(sample()))
+	defer srv.Close()
+
+	res, err := http.Post(srv.URL+"/graphql", "application/json",
+		strings.NewReader(`{"query": "query Count($n: Int) { transactions(first: $n) { nodes { description } } }", "variables": {"n": 1}}`))
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	var resp struct {
+		Data json.RawMessage `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&resp))
+	assert.JSONEq(t, `{"transactions": {"nodes": [{"description": "WOOLWORTHS"}]}}`, string(resp.Data))
+
+	res, err = http.Get(srv.URL + "/graphql?query=" + "%7B%20nope%20%7D")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res, err = http.Get(srv.URL + "/graphql/schema.graphql")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestSchemaCoversResolvers(t *testing.T) {
+	types := []*Type{Query(nil).Type, connectionType, edgeType, pageInfoType, transactionType, splitType, statementRefType, totalType,
+		accountType, categoryType}
+	for _, typ := range types {
+		for name := range typ.Fields {
+			assert.Regexp(t, `(?s)type `+typ.Name+` \{[^}]*\n  `+name+`[(:]`, Schema, "%s.%s is missing from the schema", typ.Name, name)
+		}
+	}
+}
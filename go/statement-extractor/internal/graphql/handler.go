@@ -0,0 +1,52 @@
+package graphql
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// maxRequestSize bounds the JSON body of a request
+const maxRequestSize = 1 << 20
+
+// Handler serves the API over db at /graphql, as POSTed JSON requests or
+// GET with query, operationName and variables parameters, and the schema at
+// /graphql/schema.graphql
+func Handler(db Store) http.Handler {
+	root := Query(db)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /graphql", func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestSize)).Decode(&req); err != nil {
+			writeResponse(w, Response{Errors: []Error{{Message: "invalid request body: " + err.Error()}}})
+			return
+		}
+		writeResponse(w, Execute(r.Context(), root, req))
+	})
+	mux.HandleFunc("GET /graphql", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		req := Request{Query: q.Get("query"), OperationName: q.Get("operationName")}
+		if vars := q.Get("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				writeResponse(w, Response{Errors: []Error{{Message: "invalid variables: " + err.Error()}}})
+				return
+			}
+		}
+		writeResponse(w, Execute(r.Context(), root, req))
+	})
+	mux.HandleFunc("GET /graphql/schema.graphql", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = io.WriteString(w, Schema)
+	})
+	return mux
+}
+
+// writeResponse writes resp, with 400 Bad Request when the request could
+// not be executed at all
+func writeResponse(w http.ResponseWriter, resp Response) {
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Data == nil {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
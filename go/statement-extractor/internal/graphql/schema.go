@@ -0,0 +1,473 @@
+package graphql
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/statement-extractor/internal/report"
+	"github.com/example/statement-extractor/internal/store"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Schema describes the API in GraphQL SDL, for client code generators and
+// for reading; it is served next to the endpoint as there is no
+// introspection
+const Schema = `type Query {
+  "Transactions oldest first, a page at a time"
+  transactions(filter: TransactionFilter, first: Int = 50, after: String): TransactionConnection!
+  "Accounts with their transaction counts and totals"
+  accounts(filter: TransactionFilter): [Account!]!
+  "Categories with their transaction counts and totals; split transactions count in each split's category"
+  categories(filter: TransactionFilter): [Category!]!
+  "Totals grouped by category, account, owner, card, month or source"
+  report(by: String! = "category", filter: TransactionFilter): [Total!]!
+}
+
+"All fields are optional and combine with AND"
+input TransactionFilter {
+  "YYYY, YYYY-MM or YYYY-MM:YYYY-MM"
+  period: String
+  "Dates from, inclusive, and to, exclusive, as YYYY-MM-DD"
+  from: String
+  to: String
+  category: String
+  account: String
+  "Substring of the description, ignoring case"
+  text: String
+  tag: String
+  "Last digits of the card number"
+  card: String
+}
+
+type TransactionConnection {
+  totalCount: Int!
+  "Net amount of all matching transactions, not only this page; with a category, of their parts in it"
+  total: Float!
+  nodes: [Transaction!]!
+  edges: [TransactionEdge!]!
+  pageInfo: PageInfo!
+}
+
+type TransactionEdge {
+  cursor: String!
+  node: Transaction!
+}
+
+type PageInfo {
+  hasNextPage: Boolean!
+  endCursor: String
+}
+
+type Transaction {
+  id: String!
+  "YYYY-MM-DD"
+  date: String!
+  description: String!
+  rawDescription: String!
+  merchant: String!
+  amount: Float!
+  balance: Float
+  currency: String!
+  category: String!
+  categoryConfidence: Float!
+  categorizedBy: String!
+  account: String!
+  accountType: String!
+  source: String!
+  owner: String!
+  card: String!
+  tags: [String!]!
+  excludeFromReports: Boolean!
+  splits: [Split!]!
+  statement: StatementRef
+}
+
+type Split {
+  category: String!
+  amount: Float!
+}
+
+type StatementRef {
+  file: String!
+  sha256: String!
+  page: Int!
+}
+
+type Account {
+  name: String!
+  transactionCount: Int!
+  total: Float!
+  transactions(filter: TransactionFilter, first: Int = 50, after: String): TransactionConnection!
+}
+
+type Category {
+  name: String!
+  transactionCount: Int!
+  total: Float!
+  transactions(filter: TransactionFilter, first: Int = 50, after: String): TransactionConnection!
+}
+
+type Total {
+  group: String!
+  count: Int!
+  amount: Float!
+}
+`
+
+// maxPageSize bounds first, so one request cannot return the whole store
+const maxPageSize = 500
+
+// Store is the part of the transaction store the API reads
+type Store interface {
+	List(ctx context.Context, f store.Filter) ([]transaction.Transaction, error)
+	Sum(ctx context.Context, f store.Filter, by string) ([]store.Total, error)
+}
+
+// Query returns the root object of the API over db
+func Query(db Store) Object {
+	connection := func(ctx context.Context, f store.Filter, keep func(transaction.Transaction) bool, args map[string]any) (any, error) {
+		return transactions(ctx, db, f, keep, args)
+	}
+	group := func(typ *Type, by string) Resolver {
+		return func(ctx context.Context, _ any, args map[string]any) (any, error) {
+			f, err := filterArg(args)
+			if err != nil {
+				return nil, err
+			}
+			totals, err := db.Sum(ctx, f, by)
+			if err != nil {
+				return nil, err
+			}
+			objects := make([]Object, len(totals))
+			for i, t := range totals {
+				objects[i] = Object{typ, grouped{Total: t, filter: f, connection: connection}}
+			}
+			return objects, nil
+		}
+	}
+
+	query := &Type{Name: "Query", Fields: map[string]Resolver{
+		"transactions": func(ctx context.Context, _ any, args map[string]any) (any, error) {
+			f, err := filterArg(args)
+			if err != nil {
+				return nil, err
+			}
+			return connection(ctx, f, nil, args)
+		},
+		"accounts":   group(accountType, "account"),
+		"categories": group(categoryType, "category"),
+		"report": func(ctx context.Context, _ any, args map[string]any) (any, error) {
+			by, err := StringArg(args, "by")
+			if err != nil {
+				return nil, err
+			}
+			if by == "" {
+				by = "category"
+			}
+			if !slices.Contains(store.Groupings, by) {
+				return nil, fmt.Errorf("invalid by %q: expected %s", by, strings.Join(store.Groupings, ", "))
+			}
+			return group(totalType, by)(ctx, nil, args)
+		},
+	}}
+	return Object{Type: query}
+}
+
+// grouped is an account, category or report total, with the filter it was
+// totalled under so its transactions can be listed with the same one
+type grouped struct {
+	store.Total
+	filter     store.Filter
+	connection func(context.Context, store.Filter, func(transaction.Transaction) bool, map[string]any) (any, error)
+}
+
+var totalType = &Type{Name: "Total", Fields: map[string]Resolver{
+	"group":  func(_ context.Context, v any, _ map[string]any) (any, error) { return v.(grouped).Group, nil },
+	"count":  func(_ context.Context, v any, _ map[string]any) (any, error) { return v.(grouped).Count, nil },
+	"amount": func(_ context.Context, v any, _ map[string]any) (any, error) { return v.(grouped).Amount.Float(), nil },
+}}
+
+var (
+	accountType = groupType("Account", func(f *store.Filter, name string) { f.Account = name },
+		func(t transaction.Transaction) bool { return t.Account == "" })
+	categoryType = groupType("Category", func(f *store.Filter, name string) { f.Category = name },
+		func(t transaction.Transaction) bool { return t.Category == "" && len(t.Splits) == 0 })
+)
+
+// groupType returns the Account or Category type, whose transactions are
+// those the store selects with scope applied to the group's filter. The
+// store cannot select an empty account or category, so for the group of
+// transactions without one, unnamed picks them out instead.
+func groupType(name string, scope func(f *store.Filter, name string), unnamed func(t transaction.Transaction) bool) *Type {
+	return &Type{Name: name, Fields: map[string]Resolver{
+		"name":             func(_ context.Context, v any, _ map[string]any) (any, error) { return v.(grouped).Group, nil },
+		"transactionCount": func(_ context.Context, v any, _ map[string]any) (any, error) { return v.(grouped).Count, nil },
+		"total":            func(_ context.Context, v any, _ map[string]any) (any, error) { return v.(grouped).Amount.Float(), nil },
+		"transactions": func(ctx context.Context, v any, args map[string]any) (any, error) {
+			g := v.(grouped)
+			f := g.filter
+			if _, ok := args["filter"]; ok {
+				var err error
+				if f, err = filterArg(args); err != nil {
+					return nil, err
+				}
+			}
+			if g.Group == "" {
+				return g.connection(ctx, f, unnamed, args)
+			}
+			scope(&f, g.Group)
+			return g.connection(ctx, f, nil, args)
+		},
+	}}
+}
+
+// page is one page of a transaction connection
+type page struct {
+	all    []transaction.Transaction
+	total  transaction.Amount
+	offset int // of the first transaction on the page
+	size   int
+}
+
+type edge struct {
+	offset int
+	txn    transaction.Transaction
+}
+
+// transactions lists the transactions matching f, and keep unless it is
+// nil, and returns the page after the cursor. The matching transactions are
+// read whole and paged here: a household's transactions fit comfortably in
+// memory, and totalCount comes for free. As for the store's totals, with a
+// category in f only the parts of split transactions in it are totalled.
+func transactions(ctx context.Context, db Store, f store.Filter, keep func(transaction.Transaction) bool, args map[string]any) (any, error) {
+	first, err := IntArg(args, "first", 50)
+	if err != nil {
+		return nil, err
+	}
+	if first < 0 || first > maxPageSize {
+		return nil, fmt.Errorf("first must be between 0 and %d", maxPageSize)
+	}
+	after, err := StringArg(args, "after")
+	if err != nil {
+		return nil, err
+	}
+	offset := 0
+	if after != "" {
+		n, err := decodeCursor(after)
+		if err != nil {
+			return nil, err
+		}
+		offset = n + 1
+	}
+
+	txns, err := db.List(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	if keep != nil {
+		txns = slices.DeleteFunc(txns, func(t transaction.Transaction) bool { return !keep(t) })
+	}
+	var total transaction.Amount
+	for _, t := range txns {
+		for _, part := range t.Allocations() {
+			if f.Category == "" || strings.EqualFold(part.Category, f.Category) {
+				total += part.Amount
+			}
+		}
+	}
+	offset = min(offset, len(txns))
+	return Object{connectionType, page{all: txns, total: total, offset: offset, size: min(first, len(txns)-offset)}}, nil
+}
+
+var connectionType = &Type{Name: "TransactionConnection", Fields: map[string]Resolver{
+	"totalCount": func(_ context.Context, v any, _ map[string]any) (any, error) { return len(v.(page).all), nil },
+	"total":      func(_ context.Context, v any, _ map[string]any) (any, error) { return v.(page).total.Float(), nil },
+	"nodes": func(_ context.Context, v any, _ map[string]any) (any, error) {
+		p := v.(page)
+		nodes := make([]Object, p.size)
+		for i := range nodes {
+			nodes[i] = Object{transactionType, p.all[p.offset+i]}
+		}
+		return nodes, nil
+	},
+	"edges": func(_ context.Context, v any, _ map[string]any) (any, error) {
+		p := v.(page)
+		edges := make([]Object, p.size)
+		for i := range edges {
+			edges[i] = Object{edgeType, edge{p.offset + i, p.all[p.offset+i]}}
+		}
+		return edges, nil
+	},
+	"pageInfo": func(_ context.Context, v any, _ map[string]any) (any, error) { return Object{pageInfoType, v}, nil },
+}}
+
+var edgeType = &Type{Name: "TransactionEdge", Fields: map[string]Resolver{
+	"cursor": func(_ context.Context, v any, _ map[string]any) (any, error) {
+		return encodeCursor(v.(edge).offset), nil
+	},
+	"node": func(_ context.Context, v any, _ map[string]any) (any, error) {
+		return Object{transactionType, v.(edge).txn}, nil
+	},
+}}
+
+var pageInfoType = &Type{Name: "PageInfo", Fields: map[string]Resolver{
+	"hasNextPage": func(_ context.Context, v any, _ map[string]any) (any, error) {
+		p := v.(page)
+		return p.offset+p.size < len(p.all), nil
+	},
+	"endCursor": func(_ context.Context, v any, _ map[string]any) (any, error) {
+		p := v.(page)
+		if p.size == 0 {
+			return nil, nil
+		}
+		return encodeCursor(p.offset + p.size - 1), nil
+	},
+}}
+
+// txnField is a Transaction field that needs no arguments
+func txnField(get func(t transaction.Transaction) any) Resolver {
+	return func(_ context.Context, v any, _ map[string]any) (any, error) {
+		return get(v.(transaction.Transaction)), nil
+	}
+}
+
+// balance is a transaction's balance, null when the statement shows none
+func balance(t transaction.Transaction) any {
+	if t.Balance == nil {
+		return nil
+	}
+	return t.Balance.Float()
+}
+
+var transactionType = &Type{Name: "Transaction", Fields: map[string]Resolver{
+	"id":                 txnField(func(t transaction.Transaction) any { return t.ID }),
+	"date":               txnField(func(t transaction.Transaction) any { return t.Date.Format(time.DateOnly) }),
+	"description":        txnField(func(t transaction.Transaction) any { return t.Description }),
+	"rawDescription":     txnField(func(t transaction.Transaction) any { return t.RawDescription }),
+	"merchant":           txnField(func(t transaction.Transaction) any { return t.Merchant }),
+	"amount":             txnField(func(t transaction.Transaction) any { return t.Amount.Float() }),
+	"balance":            txnField(func(t transaction.Transaction) any { return balance(t) }),
+	"currency":           txnField(func(t transaction.Transaction) any { return t.Currency }),
+	"category":           txnField(func(t transaction.Transaction) any { return t.Category }),
+	"categoryConfidence": txnField(func(t transaction.Transaction) any { return t.CategoryConfidence }),
+	"categorizedBy":      txnField(func(t transaction.Transaction) any { return t.CategorizedBy }),
+	"account":            txnField(func(t transaction.Transaction) any { return t.Account }),
+	"accountType":        txnField(func(t transaction.Transaction) any { return t.AccountType }),
+	"source":             txnField(func(t transaction.Transaction) any { return t.Source }),
+	"owner":              txnField(func(t transaction.Transaction) any { return t.Owner }),
+	"card":               txnField(func(t transaction.Transaction) any { return t.Card }),
+	"tags":               txnField(func(t transaction.Transaction) any { return append([]string{}, t.Tags...) }),
+	"excludeFromReports": txnField(func(t transaction.Transaction) any { return t.ExcludeFromReports }),
+	"splits": txnField(func(t transaction.Transaction) any {
+		splits := make([]Object, len(t.Splits))
+		for i, s := range t.Splits {
+			splits[i] = Object{splitType, s}
+		}
+		return splits
+	}),
+	"statement": txnField(func(t transaction.Transaction) any {
+		if t.Statement == nil {
+			return nil
+		}
+		return Object{statementRefType, *t.Statement}
+	}),
+}}
+
+var splitType = &Type{Name: "Split", Fields: map[string]Resolver{
+	"category": func(_ context.Context, v any, _ map[string]any) (any, error) {
+		return v.(transaction.Split).Category, nil
+	},
+	"amount": func(_ context.Context, v any, _ map[string]any) (any, error) {
+		return v.(transaction.Split).Amount.Float(), nil
+	},
+}}
+
+var statementRefType = &Type{Name: "StatementRef", Fields: map[string]Resolver{
+	"file": func(_ context.Context, v any, _ map[string]any) (any, error) {
+		return v.(transaction.StatementRef).File, nil
+	},
+	"sha256": func(_ context.Context, v any, _ map[string]any) (any, error) {
+		return v.(transaction.StatementRef).SHA256, nil
+	},
+	"page": func(_ context.Context, v any, _ map[string]any) (any, error) {
+		return v.(transaction.StatementRef).Page, nil
+	},
+}}
+
+// filterArg converts the filter argument, a TransactionFilter, to a store
+// filter
+func filterArg(args map[string]any) (store.Filter, error) {
+	var f store.Filter
+	if args["filter"] == nil {
+		return f, nil
+	}
+	input, ok := args["filter"].(map[string]any)
+	if !ok {
+		return f, fmt.Errorf("argument \"filter\" must be a TransactionFilter")
+	}
+	for name := range input {
+		var err error
+		switch name {
+		case "period":
+			var s string
+			if s, err = StringArg(input, name); err == nil && s != "" {
+				var period report.Period
+				if period, err = report.ParsePeriod(s); err == nil {
+					f.From, f.To = period.Start, period.End
+				}
+			}
+		case "from", "to":
+			var s string
+			if s, err = StringArg(input, name); err == nil && s != "" {
+				var d time.Time
+				if d, err = time.Parse(time.DateOnly, s); err != nil {
+					err = fmt.Errorf("invalid %s %q: expected YYYY-MM-DD", name, s)
+				} else if name == "from" {
+					f.From = d
+				} else {
+					f.To = d
+				}
+			}
+		case "category":
+			f.Category, err = StringArg(input, name)
+		case "account":
+			f.Account, err = StringArg(input, name)
+		case "text":
+			f.Text, err = StringArg(input, name)
+		case "tag":
+			f.Tag, err = StringArg(input, name)
+		case "card":
+			if f.Card, err = StringArg(input, name); err == nil && strings.Trim(f.Card, "0123456789") != "" {
+				err = fmt.Errorf("invalid card %q: expected the last digits of the card number", f.Card)
+			}
+		default:
+			err = fmt.Errorf("unknown filter field %q", name)
+		}
+		if err != nil {
+			return store.Filter{}, err
+		}
+	}
+	return f, nil
+}
+
+// encodeCursor returns the opaque cursor of the transaction at offset
+func encodeCursor(offset int) string {
+	return base64.StdEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	b, err := base64.StdEncoding.DecodeString(cursor)
+	if err == nil {
+		if s, ok := strings.CutPrefix(string(b), "offset:"); ok {
+			if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+				return n, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("invalid cursor %q", cursor)
+}
@@ -0,0 +1,275 @@
+// Package health records the outcome and latency of provider calls and runs
+// a circuit breaker over them, so that a provider that keeps failing is
+// skipped for a while instead of being retried on every statement.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/example/statement-extractor/internal/clock"
+	"github.com/example/statement-extractor/internal/lockfile"
+)
+
+const (
+	// BreakerFailures is the number of consecutive failures that opens a
+	// provider's circuit
+	BreakerFailures = 3
+	// BreakerCooldown is how long an open circuit skips the provider before
+	// a single trial call is let through
+	BreakerCooldown = 5 * time.Minute
+	// MaxCalls is the number of recent calls kept per provider
+	MaxCalls = 200
+)
+
+// FileName is the name of the health file in the state directory
+const FileName = "providers.json"
+
+// Call is the outcome of one provider call
+type Call struct {
+	At      time.Time     `json:"at"`
+	Latency time.Duration `json:"latency"`
+	Error   string        `json:"error,omitempty"`
+
+	Model    string `json:"model,omitempty"`    // the model the provider was asked to use
+	Repaired bool   `json:"repaired,omitempty"` // the model's output was malformed JSON that had to be repaired
+}
+
+// Failed reports whether the call failed
+func (c Call) Failed() bool { return c.Error != "" }
+
+// State is the circuit breaker state of a provider
+type State string
+
+const (
+	Closed   State = "closed"    // calls go through
+	Open     State = "open"      // calls are skipped until the cooldown ends
+	HalfOpen State = "half-open" // the cooldown ended; the next call is a trial
+)
+
+// Tracker keeps the recent calls of each provider in a JSON file shared
+// between runs
+type Tracker struct {
+	path  string
+	clock clock.Clock
+
+	mu      sync.Mutex
+	calls   map[string][]Call
+	pending map[string][]Call // recorded since the last Save
+}
+
+// Load reads the health file at path; a missing file starts empty. Calls
+// are stamped, and cooldowns timed, by clk.
+func Load(path string, clk clock.Clock) (*Tracker, error) {
+	t := &Tracker{path: path, clock: clk, pending: make(map[string][]Call)}
+	calls, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+	t.calls = calls
+	return t, nil
+}
+
+func read(path string) (map[string][]Call, error) {
+	calls := make(map[string][]Call)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return calls, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read provider health: %w", err)
+	}
+	if err := json.Unmarshal(data, &calls); err != nil {
+		return nil, fmt.Errorf("failed to parse provider health in %s: %w", path, err)
+	}
+	return calls, nil
+}
+
+// Record adds the outcome of a call to provider
+func (t *Tracker) Record(provider string, latency time.Duration, err error) {
+	call := Call{Latency: latency}
+	if err != nil {
+		call.Error = err.Error()
+	}
+	t.RecordCall(provider, call)
+}
+
+// RecordCall adds a call to provider, stamping it with the current time
+func (t *Tracker) RecordCall(provider string, call Call) {
+	call.At = t.clock.Now().UTC()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.calls[provider] = trim(append(t.calls[provider], call))
+	t.pending[provider] = append(t.pending[provider], call)
+}
+
+// State returns the circuit state of provider and, when open, the time the
+// cooldown ends
+func (t *Tracker) State(provider string) (State, time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	calls := t.calls[provider]
+	if len(calls) < BreakerFailures {
+		return Closed, time.Time{}
+	}
+	for _, c := range calls[len(calls)-BreakerFailures:] {
+		if !c.Failed() {
+			return Closed, time.Time{}
+		}
+	}
+	until := calls[len(calls)-1].At.Add(BreakerCooldown)
+	if t.clock.Now().Before(until) {
+		return Open, until
+	}
+	return HalfOpen, time.Time{}
+}
+
+// Allow reports whether a call to provider should be made
+func (t *Tracker) Allow(provider string) bool {
+	state, _ := t.State(provider)
+	return state != Open
+}
+
+// Stats summarises a provider's calls
+type Stats struct {
+	Provider    string
+	State       State
+	Calls       int
+	Errors      int
+	MeanLatency time.Duration
+	P95Latency  time.Duration
+	LastError   string
+	LastErrorAt time.Time
+
+	Model   string // of the most recent call
+	Repairs int    // successful calls whose output had to be repaired
+}
+
+// RepairRate returns the share of successful calls whose output had to be
+// repaired, from 0 to 1
+func (s Stats) RepairRate() float64 {
+	if s.Calls == s.Errors {
+		return 0
+	}
+	return float64(s.Repairs) / float64(s.Calls-s.Errors)
+}
+
+// ErrorRate returns the share of failed calls from 0 to 1
+func (s Stats) ErrorRate() float64 {
+	if s.Calls == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Calls)
+}
+
+// Stats summarises the calls made since the given time for every provider
+// with recorded calls, sorted by provider
+func (t *Tracker) Stats(since time.Time) []Stats {
+	t.mu.Lock()
+	providers := make([]string, 0, len(t.calls))
+	for provider := range t.calls {
+		providers = append(providers, provider)
+	}
+	t.mu.Unlock()
+	sort.Strings(providers)
+
+	stats := make([]Stats, 0, len(providers))
+	for _, provider := range providers {
+		s := Stats{Provider: provider}
+		s.State, _ = t.State(provider)
+
+		t.mu.Lock()
+		var latencies []time.Duration
+		for _, c := range t.calls[provider] {
+			if c.At.Before(since) {
+				continue
+			}
+			s.Calls++
+			latencies = append(latencies, c.Latency)
+			if c.Model != "" {
+				s.Model = c.Model
+			}
+			if c.Failed() {
+				s.Errors++
+				s.LastError, s.LastErrorAt = c.Error, c.At
+			} else if c.Repaired {
+				s.Repairs++
+			}
+		}
+		t.mu.Unlock()
+
+		if len(latencies) > 0 {
+			var total time.Duration
+			for _, l := range latencies {
+				total += l
+			}
+			s.MeanLatency = total / time.Duration(len(latencies))
+			slices.Sort(latencies)
+			s.P95Latency = latencies[(len(latencies)*95+99)/100-1]
+		}
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// Save merges the calls recorded since the last save into the health file,
+// keeping calls saved meanwhile by other runs
+func (t *Tracker) Save(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.pending) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	lock, err := lockfile.Acquire(ctx, t.path, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	calls, err := read(t.path)
+	if err != nil {
+		return err
+	}
+	for provider, pending := range t.pending {
+		merged := append(calls[provider], pending...)
+		slices.SortStableFunc(merged, func(a, b Call) int { return a.At.Compare(b.At) })
+		calls[provider] = trim(merged)
+	}
+
+	data, err := json.MarshalIndent(calls, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode provider health: %w", err)
+	}
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write provider health: %w", err)
+	}
+	if err := os.Rename(tmp, t.path); err != nil {
+		return fmt.Errorf("failed to write provider health: %w", err)
+	}
+	t.calls = calls
+	t.pending = make(map[string][]Call)
+	return nil
+}
+
+// trim keeps the most recent MaxCalls calls
+func trim(calls []Call) []Call {
+	if len(calls) > MaxCalls {
+		return calls[len(calls)-MaxCalls:]
+	}
+	return calls
+}
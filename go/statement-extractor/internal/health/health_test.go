@@ -0,0 +1,121 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/clock"
+)
+
+// testClock is a settable time source for the breaker
+type testClock struct{ t time.Time }
+
+func (c *testClock) Now() time.Time { return c.t }
+
+func openTracker(t *testing.T, path string, c *testClock) *Tracker {
+	t.Helper()
+	tracker, err := Load(path, c)
+	require.NoError(t, err)
+	return tracker
+}
+
+func TestBreaker(t *testing.T) {
+	c := &testClock{t: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}
+	tracker := openTracker(t, filepath.Join(t.TempDir(), FileName), c)
+	failure := errors.New("server error: 503 Service Unavailable")
+
+	for range BreakerFailures - 1 {
+		tracker.Record("ocr", time.Second, failure)
+	}
+	assert.True(t, tracker.Allow("ocr"))
+
+	tracker.Record("ocr", time.Second, failure)
+	state, until := tracker.State("ocr")
+	assert.Equal(t, Open, state)
+	assert.Equal(t, c.t.Add(BreakerCooldown), until)
+	assert.False(t, tracker.Allow("ocr"))
+	assert.True(t, tracker.Allow("other"))
+
+	c.t = c.t.Add(BreakerCooldown)
+	state, _ = tracker.State("ocr")
+	assert.Equal(t, HalfOpen, state)
+	assert.True(t, tracker.Allow("ocr"))
+
+	tracker.Record("ocr", time.Second, nil)
+	state, _ = tracker.State("ocr")
+	assert.Equal(t, Closed, state)
+}
+
+func TestStats(t *testing.T) {
+	c := &testClock{t: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}
+	tracker := openTracker(t, filepath.Join(t.TempDir(), FileName), c)
+
+	tracker.Record("ocr", 10*time.Second, errors.New("old"))
+	c.t = c.t.Add(time.Hour)
+	for i := 1; i <= 20; i++ {
+		var err error
+		if i%5 == 0 {
+			err = errors.New("timeout")
+		}
+		tracker.Record("ocr", time.Duration(i)*100*time.Millisecond, err)
+	}
+
+	stats := tracker.Stats(c.t.Add(-time.Minute))
+	require.Len(t, stats, 1)
+	s := stats[0]
+	assert.Equal(t, "ocr", s.Provider)
+	assert.Equal(t, Closed, s.State)
+	assert.Equal(t, 20, s.Calls)
+	assert.Equal(t, 4, s.Errors)
+	assert.InDelta(t, 0.2, s.ErrorRate(), 1e-9)
+	assert.Equal(t, 1050*time.Millisecond, s.MeanLatency)
+	assert.Equal(t, 1900*time.Millisecond, s.P95Latency)
+	assert.Equal(t, "timeout", s.LastError)
+}
+
+func TestStatsRepairs(t *testing.T) {
+	tracker := openTracker(t, filepath.Join(t.TempDir(), FileName), &testClock{t: time.Now()})
+	tracker.RecordCall("llm", Call{Model: "v1", Repaired: true})
+	tracker.RecordCall("llm", Call{Model: "v2", Repaired: true})
+	tracker.RecordCall("llm", Call{Model: "v2"})
+	tracker.RecordCall("llm", Call{Model: "v2", Error: "bad gateway"})
+
+	stats := tracker.Stats(time.Time{})
+	require.Len(t, stats, 1)
+	assert.Equal(t, "v2", stats[0].Model)
+	assert.Equal(t, 2, stats[0].Repairs)
+	assert.InDelta(t, 2.0/3, stats[0].RepairRate(), 1e-9, "failed calls are not counted")
+}
+
+func TestSaveMerges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", FileName)
+	c := &testClock{t: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}
+	a := openTracker(t, path, c)
+	b := openTracker(t, path, c)
+
+	a.Record("ocr", time.Second, nil)
+	c.t = c.t.Add(time.Minute)
+	b.Record("ocr", 2*time.Second, errors.New("boom"))
+	require.NoError(t, b.Save(context.Background()))
+	require.NoError(t, a.Save(context.Background()))
+
+	reloaded := openTracker(t, path, c)
+	stats := reloaded.Stats(time.Time{})
+	require.Len(t, stats, 1)
+	assert.Equal(t, 2, stats[0].Calls, "both runs' calls are kept")
+	assert.Equal(t, "boom", stats[0].LastError)
+}
+
+func TestOpenInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o644))
+	_, err := Load(path, clock.System)
+	assert.ErrorContains(t, err, "failed to parse provider health")
+}
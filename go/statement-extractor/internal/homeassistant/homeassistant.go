@@ -0,0 +1,136 @@
+// Package homeassistant turns transactions into Home Assistant sensors,
+// monthly category totals and the latest balance of each account, published
+// over MQTT with discovery so they appear on a dashboard without any
+// configuration on the Home Assistant side.
+package homeassistant
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Sensor is one Home Assistant sensor and its current state
+type Sensor struct {
+	ID         string // object ID, unique within the node
+	Name       string
+	State      string
+	Unit       string // currency code
+	StateClass string // "total" for running totals, "measurement" for balances
+}
+
+// Message is an MQTT message to publish
+type Message struct {
+	Topic   string
+	Payload []byte
+	Retain  bool
+}
+
+// Sensors returns a sensor with the net amount of each category in month
+// (YYYY-MM) and one with the latest balance of each account, in currency
+// unless the transaction has its own. Transactions excluded from reports are
+// left out of the category totals.
+func Sensors(txns []transaction.Transaction, month, currency string) []Sensor {
+	totals := make(map[string]transaction.Amount)
+	for _, t := range txns {
+		if t.Date.Format("2006-01") == month && !t.ExcludeFromReports {
+			totals[t.Category] += t.Amount
+		}
+	}
+	var sensors []Sensor
+	for _, category := range sortedKeys(totals) {
+		sensors = append(sensors, Sensor{
+			ID:         "spending_" + slug(category),
+			Name:       category + " spending",
+			State:      totals[category].String(),
+			Unit:       currency,
+			StateClass: "total",
+		})
+	}
+
+	// The latest balance by date and statement order, per account
+	latest := make(map[string]transaction.Transaction)
+	for _, t := range txns {
+		if t.Balance == nil {
+			continue
+		}
+		account := cmp.Or(t.Account, t.Source)
+		if prev, ok := latest[account]; !ok || transaction.Compare(prev, t) <= 0 {
+			latest[account] = t
+		}
+	}
+	for _, account := range sortedKeys(latest) {
+		t := latest[account]
+		sensors = append(sensors, Sensor{
+			ID:         "balance_" + slug(account),
+			Name:       account + " balance",
+			State:      t.Balance.String(),
+			Unit:       cmp.Or(t.Currency, currency),
+			StateClass: "measurement",
+		})
+	}
+	return sensors
+}
+
+// Messages returns the retained discovery config and state messages of the
+// sensors. Discovery configs go under discoveryPrefix (usually
+// "homeassistant") and states under topicPrefix, with node naming the device
+// the sensors are grouped under.
+func Messages(sensors []Sensor, discoveryPrefix, topicPrefix, node string) ([]Message, error) {
+	var messages []Message
+	for _, s := range sensors {
+		stateTopic := fmt.Sprintf("%s/%s/state", topicPrefix, s.ID)
+		config := map[string]any{
+			"name":                s.Name,
+			"unique_id":           node + "_" + s.ID,
+			"object_id":           node + "_" + s.ID,
+			"state_topic":         stateTopic,
+			"unit_of_measurement": s.Unit,
+			"device_class":        "monetary",
+			"state_class":         s.StateClass,
+			"device": map[string]any{
+				"identifiers": []string{node},
+				"name":        "Statement Extractor",
+			},
+		}
+		payload, err := json.Marshal(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode sensor %s: %w", s.ID, err)
+		}
+		messages = append(messages,
+			Message{Topic: fmt.Sprintf("%s/sensor/%s/%s/config", discoveryPrefix, node, s.ID), Payload: payload, Retain: true},
+			Message{Topic: stateTopic, Payload: []byte(s.State), Retain: true},
+		)
+	}
+	return messages, nil
+}
+
+// slug makes a name safe for MQTT topics and Home Assistant object IDs,
+// e.g. "Eating Out" becomes "eating_out"
+func slug(name string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(name) {
+		if 'a' <= r && r <= 'z' || '0' <= r && r <= '9' {
+			b.WriteRune(r)
+			underscore = false
+		} else if !underscore && b.Len() > 0 {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	return cmp.Or(strings.TrimSuffix(b.String(), "_"), "none")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,48 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestSensors(t *testing.T) {
+	day := func(month time.Month, d int) time.Time { return time.Date(2024, month, d, 0, 0, 0, 0, time.UTC) }
+	txns := []transaction.Transaction{
+		{Date: day(6, 3), Category: "Eating Out", Amount: -2550, Balance: transaction.Amount(97450).Ptr(), Source: "CBA"},
+		{Date: day(6, 9), Category: "Eating Out", Amount: -1200, Balance: transaction.Amount(96250).Ptr(), Source: "CBA"},
+		{Date: day(6, 9), Category: "Groceries", Amount: -8000, Account: "Visa", Currency: "USD", Balance: transaction.Amount(-8000).Ptr()},
+		{Date: day(6, 12), Category: "Insurance", Amount: -90000, ExcludeFromReports: true, Source: "CBA"},
+		{Date: day(5, 28), Category: "Groceries", Amount: -5000, Source: "CBA", Balance: transaction.Amount(100000).Ptr()},
+	}
+	assert.Equal(t, []Sensor{
+		{ID: "spending_eating_out", Name: "Eating Out spending", State: "-37.50", Unit: "AUD", StateClass: "total"},
+		{ID: "spending_groceries", Name: "Groceries spending", State: "-80.00", Unit: "AUD", StateClass: "total"},
+		{ID: "balance_cba", Name: "CBA balance", State: "962.50", Unit: "AUD", StateClass: "measurement"},
+		{ID: "balance_visa", Name: "Visa balance", State: "-80.00", Unit: "USD", StateClass: "measurement"},
+	}, Sensors(txns, "2024-06", "AUD"))
+}
+
+func TestMessages(t *testing.T) {
+	messages, err := Messages([]Sensor{
+		{ID: "spending_eating_out", Name: "Eating Out spending", State: "-37.50", Unit: "AUD", StateClass: "total"},
+	}, "homeassistant", "statement-extractor", "statement_extractor")
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+
+	assert.Equal(t, "homeassistant/sensor/statement_extractor/spending_eating_out/config", messages[0].Topic)
+	assert.True(t, messages[0].Retain)
+	var config map[string]any
+	require.NoError(t, json.Unmarshal(messages[0].Payload, &config))
+	assert.Equal(t, "statement-extractor/spending_eating_out/state", config["state_topic"])
+	assert.Equal(t, "statement_extractor_spending_eating_out", config["unique_id"])
+	assert.Equal(t, "monetary", config["device_class"])
+
+	assert.Equal(t, Message{Topic: "statement-extractor/spending_eating_out/state", Payload: []byte("-37.50"), Retain: true}, messages[1])
+	assert.Equal(t, "none", slug("—"))
+}
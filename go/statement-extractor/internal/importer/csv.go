@@ -0,0 +1,306 @@
+// Package importer reads transaction exports from other tools and banks into
+// the Transaction model.
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Mapping describes how CSV columns map onto transaction fields. Each field
+// lists the header names it may appear under, matched case-insensitively.
+type Mapping struct {
+	Date        []string
+	Description []string // the first non-empty matching column wins
+	Amount      []string // signed amount; alternatively use Debit and Credit
+	Debit       []string // money out, as a positive number
+	Credit      []string // money in, as a positive number
+	Direction   []string // column holding a debit/credit marker for unsigned amounts
+	Category    []string
+	Account     []string
+	Balance     []string
+
+	DateLayouts []string // tried in order; defaults to common day-first and ISO layouts
+	DebitValues []string // Direction values meaning money out, e.g. "debit"
+
+	// Headerless exports have no header row; columns are then named by their
+	// 1-based position, e.g. Date: []string{"1"}
+	Headerless bool
+}
+
+// columns holds the resolved header indexes for a Mapping; -1 means absent
+type columns struct {
+	date, amount, debit, credit, direction, category, account, balance int
+	description                                                        []int
+}
+
+// Read parses a CSV export with a header row. Source is recorded on each
+// transaction unless the export names the account.
+func (m Mapping) Read(r io.Reader, source string) ([]transaction.Transaction, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var err error
+	var header []string
+	if m.Headerless {
+		header = m.positions()
+	} else {
+		header, err = reader.Read()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading header: %w", err)
+		}
+	}
+	if len(m.DateLayouts) == 0 {
+		m.DateLayouts = auDateLayouts
+	}
+
+	cols, err := m.resolve(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var txns []transaction.Transaction
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading CSV: %w", err)
+		}
+		if blank(record) {
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		t, err := m.row(record, cols, source)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		txns = append(txns, t)
+	}
+	return txns, nil
+}
+
+// positions names columns by position, up to the last one the mapping uses
+func (m Mapping) positions() []string {
+	last := 0
+	for _, names := range [][]string{m.Date, m.Description, m.Amount, m.Debit, m.Credit, m.Direction, m.Category, m.Account, m.Balance} {
+		for _, name := range names {
+			if n, err := strconv.Atoi(name); err == nil && n > last {
+				last = n
+			}
+		}
+	}
+	header := make([]string, last)
+	for i := range header {
+		header[i] = strconv.Itoa(i + 1)
+	}
+	return header
+}
+
+func (m Mapping) resolve(header []string) (columns, error) {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, seen := index[name]; !seen {
+			index[name] = i
+		}
+	}
+	find := func(names []string) int {
+		for _, name := range names {
+			if i, ok := index[strings.ToLower(name)]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+
+	cols := columns{
+		date:      find(m.Date),
+		amount:    find(m.Amount),
+		debit:     find(m.Debit),
+		credit:    find(m.Credit),
+		direction: find(m.Direction),
+		category:  find(m.Category),
+		account:   find(m.Account),
+		balance:   find(m.Balance),
+	}
+	for _, name := range m.Description {
+		if i := find([]string{name}); i >= 0 {
+			cols.description = append(cols.description, i)
+		}
+	}
+
+	switch {
+	case cols.date < 0:
+		return cols, fmt.Errorf("no date column (expected one of %s)", strings.Join(m.Date, ", "))
+	case len(cols.description) == 0:
+		return cols, fmt.Errorf("no description column (expected one of %s)", strings.Join(m.Description, ", "))
+	case cols.amount < 0 && cols.debit < 0 && cols.credit < 0:
+		return cols, errors.New("no amount, debit or credit column")
+	}
+	return cols, nil
+}
+
+func (m Mapping) row(record []string, cols columns, source string) (transaction.Transaction, error) {
+	field := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	date, err := ParseDate(field(cols.date), m.DateLayouts)
+	if err != nil {
+		return transaction.Transaction{}, err
+	}
+
+	var description string
+	for _, i := range cols.description {
+		if description = field(i); description != "" {
+			break
+		}
+	}
+
+	amount, err := m.amount(field, cols)
+	if err != nil {
+		return transaction.Transaction{}, err
+	}
+
+	t := transaction.Transaction{
+		Date:        date,
+		Description: description,
+		Amount:      amount,
+		Category:    field(cols.category),
+		Source:      source,
+	}
+	if account := field(cols.account); account != "" {
+		t.Source = account
+	}
+	if balance := field(cols.balance); balance != "" {
+		b, err := ParseAmount(balance)
+		if err != nil {
+			return transaction.Transaction{}, fmt.Errorf("invalid balance: %w", err)
+		}
+		t.Balance = &b
+	}
+	return t, nil
+}
+
+func (m Mapping) amount(field func(int) string, cols columns) (transaction.Amount, error) {
+	if value := field(cols.amount); value != "" {
+		amount, err := ParseAmount(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid amount: %w", err)
+		}
+		if cols.direction >= 0 {
+			amount = amount.Abs()
+			if m.isDebit(field(cols.direction)) {
+				amount = -amount
+			}
+		}
+		return amount, nil
+	}
+
+	var amount transaction.Amount
+	if value := field(cols.credit); value != "" {
+		credit, err := ParseAmount(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid credit: %w", err)
+		}
+		amount += credit.Abs()
+	}
+	if value := field(cols.debit); value != "" {
+		debit, err := ParseAmount(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid debit: %w", err)
+		}
+		amount -= debit.Abs()
+	}
+	return amount, nil
+}
+
+func (m Mapping) isDebit(direction string) bool {
+	for _, value := range m.DebitValues {
+		if strings.EqualFold(direction, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseAmount parses amounts as banks and finance tools write them:
+// currency symbols, thousands separators, "(12.50)" or "12.50-" negatives
+// and trailing "DR"/"CR" markers
+func ParseAmount(s string) (transaction.Amount, error) {
+	value := strings.Map(func(r rune) rune {
+		switch r {
+		case '$', '€', '£', '¥', ',', ' ', '\t':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(s))
+
+	negative := false
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		negative = true
+		value = value[1 : len(value)-1]
+	}
+	if rest, ok := strings.CutSuffix(value, "DR"); ok {
+		negative, value = true, rest
+	} else if rest, ok := strings.CutSuffix(value, "CR"); ok {
+		value = rest
+	}
+	if rest, ok := strings.CutSuffix(value, "-"); ok {
+		negative, value = true, rest
+	}
+	if rest, ok := strings.CutPrefix(value, "-"); ok {
+		negative, value = !negative, rest
+	} else {
+		value = strings.TrimPrefix(value, "+")
+	}
+
+	if value == "" || strings.Trim(value, "0123456789.") != "" {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	amount, err := transaction.ParseAmount(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// ParseDate parses s with the first matching layout
+func ParseDate(s string, layouts []string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range layouts {
+		if d, err := time.Parse(layout, s); err == nil {
+			return d, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+func blank(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
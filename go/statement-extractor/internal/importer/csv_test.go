@@ -0,0 +1,139 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestParseAmount(t *testing.T) {
+	valid := map[string]transaction.Amount{
+		"12.50":      1250,
+		"-12.50":     -1250,
+		"$1,234.56":  123456,
+		"-$5":        -500,
+		"$-5":        -500,
+		"(42.10)":    -4210,
+		"42.10-":     -4210,
+		"99.00 DR":   -9900,
+		"99.00 cr":   9900,
+		"+3":         300,
+		" 1 000.00 ": 100000,
+	}
+	for input, want := range valid {
+		got, err := ParseAmount(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	for _, input := range []string{"", "abc", "1e5", "NaN", "Inf", "--5", "0x10", "1.2.3", "-"} {
+		_, err := ParseAmount(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestMappingRead(t *testing.T) {
+	m := Mapping{
+		Date:        []string{"Date"},
+		Description: []string{"Memo", "Payee"},
+		Amount:      []string{"Value"},
+		Direction:   []string{"Type"},
+		Account:     []string{"Account"},
+		DateLayouts: []string{"2006-01-02"},
+		DebitValues: []string{"DR"},
+	}
+	input := "DATE,payee,memo,value,type,account\n" +
+		"2024-02-01,Shop,,10.00,dr,\n" +
+		"2024-02-02,Employer,Pay run,-500.00,CR,Savings\n"
+
+	txns, err := m.Read(strings.NewReader(input), "bank.csv")
+	require.NoError(t, err)
+	require.Len(t, txns, 2)
+
+	assert.Equal(t, "Shop", txns[0].Description)
+	assert.Equal(t, transaction.Amount(-1000), txns[0].Amount)
+	assert.Equal(t, "bank.csv", txns[0].Source)
+
+	assert.Equal(t, "Pay run", txns[1].Description)
+	assert.Equal(t, transaction.Amount(50000), txns[1].Amount, "direction overrides the amount's sign")
+	assert.Equal(t, "Savings", txns[1].Source)
+
+	_, err = m.Read(strings.NewReader("Date,Memo,Value\n2024-02-01,X,ten\n"), "")
+	assert.ErrorContains(t, err, `line 2: invalid amount: invalid amount "ten"`)
+}
+
+func TestMappingReadHeaderless(t *testing.T) {
+	// CommBank's CSV export: date, amount, description, balance
+	m := Mapping{Date: []string{"1"}, Amount: []string{"2"}, Description: []string{"3"}, Balance: []string{"4"}, Headerless: true}
+	input := "03/01/2024,-54.10,WOOLWORTHS 1234,+1945.90\n" +
+		"05/01/2024,+200.00,TRANSFER FROM J SMITH,+2145.90\n"
+
+	txns, err := m.Read(strings.NewReader(input), "CBA")
+	require.NoError(t, err)
+	require.Len(t, txns, 2)
+	assert.Equal(t, time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), txns[0].Date, "day-first dates by default")
+	assert.Equal(t, "WOOLWORTHS 1234", txns[0].Description)
+	assert.Equal(t, transaction.Amount(-5410), txns[0].Amount)
+	assert.Equal(t, transaction.Amount(194590).Ptr(), txns[0].Balance)
+	assert.Equal(t, transaction.Amount(20000), txns[1].Amount)
+}
+
+func FuzzParseAmount(f *testing.F) {
+	for _, seed := range []string{"12.50", "-$1,234.56", "(42.10)", "42.10-", "99.00 DR", "1e5", "", "--", "(", "$", "0x1p-2"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		amount, err := ParseAmount(s)
+		if err != nil {
+			return
+		}
+		again, err := ParseAmount(amount.String())
+		if err != nil || again != amount {
+			t.Fatalf("ParseAmount(%q) = %v does not round-trip: %v, %v", s, amount, again, err)
+		}
+	})
+}
+
+func FuzzParseDate(f *testing.F) {
+	layouts := []string{"2006-01-02", "02/01/2006", "2 Jan 2006"}
+	for _, seed := range []string{"2024-02-29", "31/12/2023", "1 Feb 2024", "2023-02-29", "", "99/99/9999"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		if _, err := ParseDate(s, layouts); err != nil && !strings.Contains(err.Error(), "invalid date") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func FuzzMappingRead(f *testing.F) {
+	f.Add("Date,Description,Amount\n2024-01-01,Coffee,-4.50\n")
+	f.Add("Date,Description,Debit,Credit,Balance\n2024-01-01,\"Quoted, desc\",1,,2\n\n")
+	f.Add("\ufeffdate,description,amount\n2024-01-01\n")
+	f.Add("Date,Description,Amount\n\"unterminated")
+	m := Mapping{
+		Date:        []string{"Date"},
+		Description: []string{"Description"},
+		Amount:      []string{"Amount"},
+		Debit:       []string{"Debit"},
+		Credit:      []string{"Credit"},
+		Balance:     []string{"Balance"},
+		DateLayouts: []string{"2006-01-02"},
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		txns, err := m.Read(strings.NewReader(input), "fuzz")
+		if err != nil {
+			return
+		}
+		for _, txn := range txns {
+			if again, err := transaction.ParseAmount(txn.Amount.String()); err != nil || again != txn.Amount {
+				t.Fatalf("amount %v from %q does not round-trip", txn.Amount, input)
+			}
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// auDateLayouts covers the day-first and ISO dates used by Australian apps
+var auDateLayouts = []string{"2006-01-02", "02/01/2006", "2/1/2006", "02/01/06", "2 Jan 2006", "02 Jan 2006"}
+
+// historyFormats are exports from aggregator apps whose history is already
+// categorized
+var historyFormats = map[string]struct {
+	name    string
+	mapping Mapping
+}{
+	// Mint "Export all transactions": unsigned amounts with a debit/credit
+	// transaction type and US month-first dates
+	"mint": {
+		name: "Mint",
+		mapping: Mapping{
+			Date:        []string{"Date"},
+			Description: []string{"Original Description", "Description"},
+			Amount:      []string{"Amount"},
+			Direction:   []string{"Transaction Type"},
+			Category:    []string{"Category"},
+			Account:     []string{"Account Name"},
+			DateLayouts: []string{"1/02/2006", "01/02/2006", "1/2/2006", "2006-01-02"},
+			DebitValues: []string{"debit"},
+		},
+	},
+	// Pocketbook CSV export: signed amounts, or separate debit/credit columns
+	// in older exports
+	"pocketbook": {
+		name: "Pocketbook",
+		mapping: Mapping{
+			Date:        []string{"Date"},
+			Description: []string{"Description", "Narrative", "Merchant"},
+			Amount:      []string{"Amount"},
+			Debit:       []string{"Debit", "Debit Amount"},
+			Credit:      []string{"Credit", "Credit Amount"},
+			Category:    []string{"Category"},
+			Account:     []string{"Account", "Bank Account"},
+			Balance:     []string{"Balance"},
+			DateLayouts: auDateLayouts,
+		},
+	},
+	// Frollo transaction export: signed amounts with the original bank
+	// description alongside the cleaned merchant name
+	"frollo": {
+		name: "Frollo",
+		mapping: Mapping{
+			Date:        []string{"Transaction Date", "Date"},
+			Description: []string{"Original Description", "Description", "Merchant"},
+			Amount:      []string{"Amount"},
+			Category:    []string{"Category", "Budget Category"},
+			Account:     []string{"Account Name", "Account"},
+			DateLayouts: auDateLayouts,
+		},
+	},
+}
+
+// ofxFormats are read with ReadOFX rather than a CSV mapping
+var ofxFormats = []string{"ofx", "qfx"}
+
+// HistoryFormats returns the supported history export format names
+func HistoryFormats() []string {
+	names := make([]string, 0, len(historyFormats)+len(ofxFormats)+1)
+	for name := range historyFormats {
+		names = append(names, name)
+	}
+	names = append(names, ofxFormats...)
+	names = append(names, "qif")
+	sort.Strings(names)
+	return names
+}
+
+// ReadHistory parses a history export in the named format. Categories from
+// the export are kept as-is.
+func ReadHistory(format string, r io.Reader) ([]transaction.Transaction, error) {
+	if slices.Contains(ofxFormats, strings.ToLower(format)) {
+		return ReadOFX(r)
+	}
+	if strings.EqualFold(format, "qif") {
+		return ReadQIF(r, "QIF", nil)
+	}
+	f, ok := historyFormats[strings.ToLower(format)]
+	if !ok {
+		return nil, fmt.Errorf("unknown import format %q (supported: %s)", format, strings.Join(HistoryFormats(), ", "))
+	}
+
+	txns, err := f.mapping.Read(r, f.name)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s export: %w", f.name, err)
+	}
+	return txns, nil
+}
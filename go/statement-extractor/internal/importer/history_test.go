@@ -0,0 +1,71 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestReadHistory_Mint(t *testing.T) {
+	export := `"Date","Description","Original Description","Amount","Transaction Type","Category","Account Name","Labels","Notes"
+"1/05/2021","Woolworths","WOOLWORTHS 1234 SYDNEY","54.20","debit","Groceries","Everyday","",""
+"1/15/2021","Employer","SALARY ACME PTY","3000.00","credit","Paycheck","Everyday","",""
+`
+	txns, err := ReadHistory("Mint", strings.NewReader(export))
+	require.NoError(t, err)
+	require.Len(t, txns, 2)
+
+	assert.Equal(t, time.Date(2021, 1, 5, 0, 0, 0, 0, time.UTC), txns[0].Date)
+	assert.Equal(t, "WOOLWORTHS 1234 SYDNEY", txns[0].Description)
+	assert.Equal(t, transaction.Amount(-5420), txns[0].Amount)
+	assert.Equal(t, "Groceries", txns[0].Category)
+	assert.Equal(t, "Everyday", txns[0].Source)
+	assert.Equal(t, transaction.Amount(300000), txns[1].Amount)
+}
+
+func TestReadHistory_Pocketbook(t *testing.T) {
+	export := "\ufeffDate,Description,Debit,Credit,Balance,Category\n" +
+		"15/03/2019,COLES 0456,82.15,,1017.85,Groceries\n" +
+		"\n" +
+		"16/03/2019,REFUND,,12.00,1029.85,Shopping\n"
+
+	txns, err := ReadHistory("pocketbook", strings.NewReader(export))
+	require.NoError(t, err)
+	require.Len(t, txns, 2)
+
+	assert.Equal(t, time.Date(2019, 3, 15, 0, 0, 0, 0, time.UTC), txns[0].Date)
+	assert.Equal(t, transaction.Amount(-8215), txns[0].Amount)
+	assert.Equal(t, transaction.Amount(101785).Ptr(), txns[0].Balance)
+	assert.Equal(t, "Pocketbook", txns[0].Source)
+	assert.Equal(t, transaction.Amount(1200), txns[1].Amount)
+}
+
+func TestReadHistory_Frollo(t *testing.T) {
+	export := "Transaction Date,Merchant,Original Description,Amount,Category,Account Name\n" +
+		"2022-07-01,Netflix,NETFLIX.COM MELBOURNE,-16.99,Entertainment,Credit Card\n" +
+		"2022-07-02,Unknown,,-4.50,Coffee,Credit Card\n"
+
+	txns, err := ReadHistory("frollo", strings.NewReader(export))
+	require.NoError(t, err)
+	require.Len(t, txns, 2)
+
+	assert.Equal(t, "NETFLIX.COM MELBOURNE", txns[0].Description)
+	assert.Equal(t, "Unknown", txns[1].Description, "falls back to the merchant when the original is empty")
+	assert.Equal(t, transaction.Amount(-1699), txns[0].Amount)
+}
+
+func TestReadHistory_Errors(t *testing.T) {
+	_, err := ReadHistory("quicken", strings.NewReader(""))
+	assert.ErrorContains(t, err, "supported: frollo, mint, ofx, pocketbook, qfx")
+
+	_, err = ReadHistory("frollo", strings.NewReader("Date,Amount\n2022-01-01,1\n"))
+	assert.ErrorContains(t, err, "no description column")
+
+	_, err = ReadHistory("frollo", strings.NewReader("Date,Description,Amount\n2022-01-01,X,1\n2022-13-01,Y,2\n"))
+	assert.ErrorContains(t, err, `line 3: invalid date "2022-13-01"`)
+}
@@ -0,0 +1,154 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// ReadOFX parses an OFX or QFX download. Both OFX 1.x, which is SGML with
+// unclosed value tags, and the XML of OFX 2.x are accepted. The FITID of each
+// transaction becomes its ID, so repeated downloads of overlapping date
+// ranges can be deduplicated reliably.
+func ReadOFX(r io.Reader) ([]transaction.Transaction, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading OFX: %w", err)
+	}
+	doc := string(data)
+	start := strings.Index(strings.ToUpper(doc), "<OFX>")
+	if start < 0 {
+		return nil, errors.New("not an OFX file: no <OFX> element")
+	}
+
+	var (
+		txns     []transaction.Transaction
+		current  map[string]string // fields of the open <STMTTRN>
+		source   = "OFX"
+		account  string
+		currency string
+		path     []string // open aggregate elements, for telling NAME in <FI> from NAME in <STMTTRN>
+	)
+	for _, tok := range ofxTokens(doc[start:]) {
+		switch {
+		case tok.close:
+			for i := len(path) - 1; i >= 0; i-- {
+				if path[i] == tok.name {
+					path = path[:i]
+					break
+				}
+			}
+			if tok.name == "STMTTRN" && current != nil {
+				t, err := ofxTransaction(current)
+				if err != nil {
+					return nil, err
+				}
+				t.Source, t.Account, t.Currency = source, account, currency
+				txns = append(txns, t)
+				current = nil
+			}
+		case tok.value == "":
+			path = append(path, tok.name)
+			if tok.name == "STMTTRN" {
+				current = make(map[string]string)
+			}
+		default:
+			parent := ""
+			if len(path) > 0 {
+				parent = path[len(path)-1]
+			}
+			switch {
+			case current != nil:
+				current[tok.name] = tok.value
+			case tok.name == "ORG" && parent == "FI":
+				source = tok.value
+			case tok.name == "ACCTID":
+				account = tok.value
+			case tok.name == "CURDEF":
+				currency = tok.value
+			}
+		}
+	}
+	return txns, nil
+}
+
+// ofxToken is a start tag with its value, a start tag opening an aggregate
+// (no value), or a close tag
+type ofxToken struct {
+	name  string
+	value string
+	close bool
+}
+
+// ofxTokens splits an OFX body into tags. A value is the text between a
+// start tag and the next tag, which covers both SGML's unclosed
+// <TRNAMT>-12.50 and XML's <TRNAMT>-12.50</TRNAMT>.
+func ofxTokens(doc string) []ofxToken {
+	var tokens []ofxToken
+	for {
+		open := strings.IndexByte(doc, '<')
+		if open < 0 {
+			return tokens
+		}
+		end := strings.IndexByte(doc[open:], '>')
+		if end < 0 {
+			return tokens
+		}
+		tag := strings.TrimSpace(doc[open+1 : open+end])
+		doc = doc[open+end+1:]
+		if tag == "" || tag[0] == '?' || tag[0] == '!' {
+			continue
+		}
+
+		if name, ok := strings.CutPrefix(tag, "/"); ok {
+			tokens = append(tokens, ofxToken{name: strings.ToUpper(strings.TrimSpace(name)), close: true})
+			continue
+		}
+		text := doc
+		if next := strings.IndexByte(doc, '<'); next >= 0 {
+			text = doc[:next]
+		}
+		name := strings.ToUpper(strings.Fields(tag)[0])
+		tokens = append(tokens, ofxToken{name: strings.TrimSuffix(name, "/"), value: strings.TrimSpace(html.UnescapeString(text))})
+	}
+}
+
+func ofxTransaction(fields map[string]string) (transaction.Transaction, error) {
+	date, err := ParseOFXDate(fields["DTPOSTED"])
+	if err != nil {
+		return transaction.Transaction{}, fmt.Errorf("transaction %s: %w", fields["FITID"], err)
+	}
+	amount, err := ParseAmount(fields["TRNAMT"])
+	if err != nil {
+		return transaction.Transaction{}, fmt.Errorf("transaction %s: %w", fields["FITID"], err)
+	}
+
+	description := fields["NAME"]
+	if memo := fields["MEMO"]; memo != "" && !strings.Contains(description, memo) {
+		description = strings.TrimSpace(description + " " + memo)
+	}
+	return transaction.Transaction{
+		ID:          fields["FITID"],
+		Date:        date,
+		Description: description,
+		Amount:      amount,
+	}, nil
+}
+
+// ParseOFXDate parses the date part of an OFX datetime such as
+// "20240115120000.000[-5:EST]"
+func ParseOFXDate(s string) (time.Time, error) {
+	if len(s) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	d, err := time.Parse("20060102", s[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return d, nil
+}
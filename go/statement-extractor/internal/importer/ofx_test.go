@@ -0,0 +1,127 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+const ofxSGML = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+ENCODING:USASCII
+
+<OFX>
+<SIGNONMSGSRSV1><SONRS>
+<STATUS><CODE>0<SEVERITY>INFO</STATUS>
+<FI><ORG>Commonwealth Bank<FID>1234</FI>
+</SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>AUD
+<BANKACCTFROM><BANKID>062000<ACCTID>12345678<ACCTTYPE>CHECKING</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20240101<DTEND>20240131
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240103120000.000[+10:AEST]
+<TRNAMT>-54.10
+<FITID>2024010300001
+<NAME>WOOLWORTHS 1234
+<MEMO>Card xx1234
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240115
+<TRNAMT>2500.00
+<FITID>2024011500002
+<NAME>SALARY ACME &amp; CO
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+const ofxXML = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE"?>
+<OFX>
+  <CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS>
+    <CURDEF>USD</CURDEF>
+    <CCACCTFROM><ACCTID>4000123412341234</ACCTID></CCACCTFROM>
+    <BANKTRANLIST>
+      <STMTTRN>
+        <TRNTYPE>DEBIT</TRNTYPE>
+        <DTPOSTED>20240210</DTPOSTED>
+        <TRNAMT>-12.99</TRNAMT>
+        <FITID>T-1</FITID>
+        <NAME>NETFLIX.COM</NAME>
+        <MEMO>NETFLIX.COM</MEMO>
+      </STMTTRN>
+    </BANKTRANLIST>
+  </CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func TestReadOFX_SGML(t *testing.T) {
+	txns, err := ReadHistory("qfx", strings.NewReader(ofxSGML))
+	require.NoError(t, err)
+	require.Len(t, txns, 2)
+
+	assert.Equal(t, "2024010300001", txns[0].ID, "FITID is kept as the ID")
+	assert.Equal(t, time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), txns[0].Date)
+	assert.Equal(t, "WOOLWORTHS 1234 Card xx1234", txns[0].Description)
+	assert.Equal(t, transaction.Amount(-5410), txns[0].Amount)
+	assert.Equal(t, "Commonwealth Bank", txns[0].Source)
+	assert.Equal(t, "12345678", txns[0].Account)
+	assert.Equal(t, "AUD", txns[0].Currency)
+
+	assert.Equal(t, "SALARY ACME & CO", txns[1].Description)
+	assert.Equal(t, transaction.Amount(250000), txns[1].Amount)
+}
+
+func TestReadOFX_XML(t *testing.T) {
+	txns, err := ReadOFX(strings.NewReader(ofxXML))
+	require.NoError(t, err)
+	require.Len(t, txns, 1)
+
+	assert.Equal(t, "T-1", txns[0].ID)
+	assert.Equal(t, "NETFLIX.COM", txns[0].Description, "a memo repeating the name is not appended")
+	assert.Equal(t, transaction.Amount(-1299), txns[0].Amount)
+	assert.Equal(t, "OFX", txns[0].Source)
+	assert.Equal(t, "4000123412341234", txns[0].Account)
+	assert.Equal(t, "USD", txns[0].Currency)
+}
+
+func TestReadOFX_Errors(t *testing.T) {
+	_, err := ReadOFX(strings.NewReader("Date,Amount\n"))
+	assert.EqualError(t, err, "not an OFX file: no <OFX> element")
+
+	_, err = ReadOFX(strings.NewReader("<OFX><STMTTRN><DTPOSTED>2024<TRNAMT>1<FITID>X</STMTTRN></OFX>"))
+	assert.EqualError(t, err, `transaction X: invalid date "2024"`)
+}
+
+func FuzzReadOFX(f *testing.F) {
+	f.Add(ofxSGML)
+	f.Add(ofxXML)
+	f.Add("<OFX><STMTTRN><DTPOSTED>20240103<TRNAMT>-1.5<FITID>X</STMTTRN></OFX>")
+	f.Add("<OFX><STMTTRN><DTPOSTED>20240103[+10:AEST]<TRNAMT>1e5</STMTTRN>")
+	f.Add("<OFX><STMTTRN><NAME>&amp;<</OFX>")
+	f.Fuzz(func(t *testing.T, input string) {
+		txns, err := ReadOFX(strings.NewReader(input))
+		if err != nil {
+			return
+		}
+		for _, txn := range txns {
+			if txn.Date.IsZero() {
+				t.Fatalf("transaction %q from %q has no date", txn.ID, input)
+			}
+			if again, err := transaction.ParseAmount(txn.Amount.String()); err != nil || again != txn.Amount {
+				t.Fatalf("amount %v from %q does not round-trip", txn.Amount, input)
+			}
+		}
+	})
+}
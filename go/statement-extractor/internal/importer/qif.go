@@ -0,0 +1,141 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// qifDateLayouts adds the two-digit years Quicken writes after an
+// apostrophe, e.g. "15/01'24"
+var qifDateLayouts = slices.Concat(auDateLayouts, []string{"2/1/06"})
+
+// qifTransactionTypes are the !Type headers of account registers; other
+// sections, such as category and memorized payee lists, are skipped
+var qifTransactionTypes = []string{"bank", "ccard", "cash", "oth a", "oth l"}
+
+// ReadQIF parses a Quicken Interchange Format file. Dates are parsed with
+// layouts, or day-first with either four or two digit years when none are
+// given. A category given with L is kept; transfers, written as
+// "[Account]", are left uncategorized. The account is taken from a preceding
+// !Account record.
+func ReadQIF(r io.Reader, source string, layouts []string) ([]transaction.Transaction, error) {
+	if len(layouts) == 0 {
+		layouts = qifDateLayouts
+	}
+
+	var (
+		txns    []transaction.Transaction
+		section string            // "account", "transactions" or "" to skip
+		record  map[string]string // first value of each field code in the open record
+		account string
+		lineNo  int
+		start   int // line of the open record
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), "\r ")
+		if lineNo == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		switch {
+		case line[0] == '!':
+			header := strings.ToLower(strings.TrimSpace(line[1:]))
+			switch {
+			case header == "account":
+				section = "account"
+			case strings.HasPrefix(header, "type:"):
+				section = ""
+				if slices.Contains(qifTransactionTypes, strings.TrimSpace(header[len("type:"):])) {
+					section = "transactions"
+				}
+			default:
+				continue // !Option:AutoSwitch and friends
+			}
+			record = nil
+
+		case line[0] == '^':
+			switch {
+			case record == nil:
+			case section == "account":
+				account = record["N"]
+			case section == "transactions":
+				t, err := qifTransaction(record, layouts)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", start, err)
+				}
+				t.Source, t.Account = source, account
+				txns = append(txns, t)
+			}
+			record = nil
+
+		default:
+			if record == nil {
+				record = make(map[string]string)
+				start = lineNo
+			}
+			code := string(line[0])
+			if _, seen := record[code]; !seen {
+				record[code] = strings.TrimSpace(line[1:])
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading QIF: %w", err)
+	}
+	if section == "transactions" && record != nil {
+		return nil, fmt.Errorf("line %d: record is not terminated by ^", start)
+	}
+	return txns, nil
+}
+
+func qifTransaction(record map[string]string, layouts []string) (transaction.Transaction, error) {
+	date, err := ParseDate(qifDate(record["D"]), layouts)
+	if err != nil {
+		return transaction.Transaction{}, err
+	}
+	total := record["T"]
+	if total == "" {
+		total = record["U"]
+	}
+	amount, err := ParseAmount(total)
+	if err != nil {
+		return transaction.Transaction{}, err
+	}
+
+	description := record["P"]
+	if memo := record["M"]; memo != "" && !strings.Contains(description, memo) {
+		description = strings.TrimSpace(description + " " + memo)
+	}
+	category := record["L"]
+	if strings.HasPrefix(category, "[") {
+		category = ""
+	}
+	return transaction.Transaction{
+		Date:        date,
+		Description: description,
+		Amount:      amount,
+		Category:    category,
+	}, nil
+}
+
+// qifDate normalises Quicken's date quirks: an apostrophe before years after
+// 1999 and space-padded fields, as in " 1/ 5' 4"
+func qifDate(s string) string {
+	s = strings.ReplaceAll(s, "'", "/")
+	s = strings.ReplaceAll(s, " ", "")
+	parts := strings.Split(s, "/")
+	if len(parts) == 3 && len(parts[2]) == 1 {
+		parts[2] = "0" + parts[2]
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,106 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+const qifExport = "\ufeff!Option:AutoSwitch\n" +
+	"!Account\n" +
+	"NEveryday\n" +
+	"TBank\n" +
+	"^\n" +
+	"!Clear:AutoSwitch\n" +
+	"!Type:Cat\n" +
+	"NGroceries\n" +
+	"E\n" +
+	"^\n" +
+	"!Type:Bank\r\n" +
+	"D03/01/2024\r\n" +
+	"T-54.10\r\n" +
+	"PWOOLWORTHS 1234\r\n" +
+	"MCard xx1234\r\n" +
+	"LGroceries\r\n" +
+	"^\r\n" +
+	"D15/01'24\n" +
+	"U2,500.00\n" +
+	"T2,500.00\n" +
+	"PSALARY ACME\n" +
+	"^\n" +
+	"D 1/ 2' 4\n" +
+	"T-100.00\n" +
+	"PTRANSFER TO SAVINGS\n" +
+	"L[Savings]\n" +
+	"^\n"
+
+func TestReadQIF(t *testing.T) {
+	txns, err := ReadQIF(strings.NewReader(qifExport), "QIF", nil)
+	require.NoError(t, err)
+	require.Len(t, txns, 3)
+
+	assert.Equal(t, time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), txns[0].Date)
+	assert.Equal(t, "WOOLWORTHS 1234 Card xx1234", txns[0].Description)
+	assert.Equal(t, transaction.Amount(-5410), txns[0].Amount)
+	assert.Equal(t, "Groceries", txns[0].Category)
+	assert.Equal(t, "Everyday", txns[0].Account)
+	assert.Equal(t, "QIF", txns[0].Source)
+
+	assert.Equal(t, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), txns[1].Date)
+	assert.Equal(t, transaction.Amount(250000), txns[1].Amount)
+	assert.Empty(t, txns[1].Category)
+
+	assert.Equal(t, time.Date(2004, 2, 1, 0, 0, 0, 0, time.UTC), txns[2].Date)
+	assert.Empty(t, txns[2].Category, "transfers are left uncategorized")
+}
+
+func TestReadQIFDateFormat(t *testing.T) {
+	input := "!Type:CCard\nD01/15/2024\nT-12.50\nPNETFLIX\n^\n"
+
+	_, err := ReadQIF(strings.NewReader(input), "QIF", nil)
+	assert.EqualError(t, err, `line 2: invalid date "01/15/2024"`)
+
+	txns, err := ReadQIF(strings.NewReader(input), "QIF", []string{"01/02/2006"})
+	require.NoError(t, err)
+	require.Len(t, txns, 1)
+	assert.Equal(t, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), txns[0].Date)
+}
+
+func TestReadQIFErrors(t *testing.T) {
+	_, err := ReadQIF(strings.NewReader("!Type:Bank\nD03/01/2024\nTabc\n^\n"), "QIF", nil)
+	assert.EqualError(t, err, `line 2: invalid amount "abc"`)
+
+	_, err = ReadQIF(strings.NewReader("!Type:Bank\nD03/01/2024\nT-1.00\n"), "QIF", nil)
+	assert.EqualError(t, err, "line 2: record is not terminated by ^")
+
+	txns, err := ReadQIF(strings.NewReader("!Type:Invst\nD03/01/2024\nNBuy\n^\n"), "QIF", nil)
+	require.NoError(t, err)
+	assert.Empty(t, txns)
+}
+
+func FuzzReadQIF(f *testing.F) {
+	f.Add(qifExport)
+	f.Add("!Type:Bank\nD03/01/2024\nT-1.00\nSGroceries\n$-0.40\nSHousehold\n$-0.60\n^\n")
+	f.Add("!Type:CCard\nD1/15'24\nU1,234.56\n^\n")
+	f.Add("!Type:Bank\nD03/01/2024\nT-1.00\n")
+	f.Add("^\n^\n!Type:Invst\n")
+	f.Fuzz(func(t *testing.T, input string) {
+		txns, err := ReadQIF(strings.NewReader(input), "QIF", nil)
+		if err != nil {
+			return
+		}
+		for _, txn := range txns {
+			if txn.Date.IsZero() {
+				t.Fatalf("transaction %q from %q has no date", txn.Description, input)
+			}
+			if again, err := transaction.ParseAmount(txn.Amount.String()); err != nil || again != txn.Amount {
+				t.Fatalf("amount %v from %q does not round-trip", txn.Amount, input)
+			}
+		}
+	})
+}
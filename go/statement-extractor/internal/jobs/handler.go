@@ -0,0 +1,95 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Long-poll limits of POST /worker/lease
+const (
+	defaultLeaseWait = 30 * time.Second
+	maxLeaseWait     = 5 * time.Minute
+)
+
+// Handler serves GET /jobs/{id} for status polling. An unknown job, or one
+// past its retention, is 404 Not Found with a JSON {"error": ...} body.
+func (q *Queue) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		job, ok := q.Get(r.PathValue("id"))
+		if !ok {
+			writeError(w, http.StatusNotFound, ErrNotFound.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(job)
+	})
+	return mux
+}
+
+// Completion is what a remote worker reports for a leased job: its result,
+// or an error
+type Completion struct {
+	Token  string          `json:"token"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// WorkerHandler serves the endpoints remote workers take jobs from:
+//
+//	POST /worker/lease?wait=30s          long-polls for a job: 200 with a
+//	                                     Lease, or 204 No Content
+//	POST /worker/jobs/{id}/complete      reports a Completion: 204, 404 for
+//	                                     an unknown job, or 409 Conflict
+//	                                     when the lease lapsed
+func (q *Queue) WorkerHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /worker/lease", func(w http.ResponseWriter, r *http.Request) {
+		wait := defaultLeaseWait
+		if s := r.URL.Query().Get("wait"); s != "" {
+			d, err := time.ParseDuration(s)
+			if err != nil || d < 0 {
+				writeError(w, http.StatusBadRequest, "invalid wait "+s)
+				return
+			}
+			wait = min(d, maxLeaseWait)
+		}
+		lease, ok := q.Lease(r.Context(), wait)
+		if !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(lease)
+	})
+	mux.HandleFunc("POST /worker/jobs/{id}/complete", func(w http.ResponseWriter, r *http.Request) {
+		var c Completion
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid completion: "+err.Error())
+			return
+		}
+		var result any
+		if len(c.Result) > 0 {
+			result = c.Result
+		}
+		switch err := q.Complete(r.PathValue("id"), c.Token, result, c.Error); {
+		case errors.Is(err, ErrNotFound):
+			writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, ErrLeaseLost):
+			writeError(w, http.StatusConflict, err.Error())
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, err.Error())
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	return mux
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
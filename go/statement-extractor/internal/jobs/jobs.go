@@ -0,0 +1,339 @@
+// Package jobs provides a bounded work queue for long-running extractions so
+// that bursts of uploads are queued with job IDs instead of all running at once.
+package jobs
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/example/statement-extractor/internal/clock"
+)
+
+// Status describes where a job is in its lifecycle
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+var (
+	// ErrQueueFull is returned when the pending queue is at capacity
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrClosed is returned when submitting to a queue that is shutting down
+	ErrClosed = errors.New("job queue is closed")
+)
+
+// Func is the unit of work executed by a job
+type Func func(ctx context.Context) (any, error)
+
+// Job is a snapshot of a submitted unit of work
+type Job struct {
+	ID         string    `json:"id"`
+	Status     Status    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	Result     any       `json:"result,omitempty"`
+	Position   int       `json:"position,omitempty"` // 1-based place in the pending queue
+	CreatedAt  time.Time `json:"created_at"`
+	StartedAt  time.Time `json:"started_at,omitzero"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+
+	Webhook      string `json:"webhook,omitempty"`
+	WebhookError string `json:"webhook_error,omitempty"`
+
+	payload any // for remote workers
+}
+
+// Finished reports whether the job has reached a terminal state
+func (j Job) Finished() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed
+}
+
+// Options configures a Queue
+type Options struct {
+	Workers  int // concurrent jobs
+	Capacity int // jobs allowed to wait for a free worker
+
+	// Retention is how long finished jobs and their results are kept for
+	// polling; zero keeps them until shutdown
+	Retention time.Duration
+
+	// Webhook is the default completion callback for jobs submitted without one
+	Webhook WebhookConfig
+
+	// Clock stamps jobs and times their retention; nil is the system clock
+	Clock clock.Clock
+
+	// Remote leaves jobs to workers on other machines, which take them with
+	// Lease and report them with Complete, instead of running them on
+	// Workers here. A lease not completed within LeaseTimeout, by default
+	// DefaultLeaseTimeout, lapses and the job is offered again.
+	Remote       bool
+	LeaseTimeout time.Duration
+}
+
+// SubmitOption customizes a single submitted job
+type SubmitOption func(*Job)
+
+// WithPayload sets what a remote worker is given to run the job, as JSON
+func WithPayload(payload any) SubmitOption {
+	return func(j *Job) {
+		j.payload = payload
+	}
+}
+
+// WithWebhook sets the URL notified when the job finishes
+func WithWebhook(url string) SubmitOption {
+	return func(j *Job) {
+		j.Webhook = url
+	}
+}
+
+type entry struct {
+	job Job
+	fn  Func
+
+	// The lease of a job run remotely
+	token   string
+	expires time.Time
+}
+
+// Queue runs submitted jobs on a fixed number of workers, holding at most
+// capacity jobs waiting for a free worker
+type Queue struct {
+	mu      sync.Mutex
+	jobs    map[string]*entry
+	order   []string // pending job IDs, oldest first
+	pending chan *entry
+	closed  bool
+
+	retention time.Duration
+	webhook   WebhookConfig
+	client    *http.Client
+	clock     clock.Clock
+
+	remote       bool
+	leaseTimeout time.Duration
+	lapsed       []*entry // jobs whose lease lapsed, offered before pending
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewQueue starts a queue with the configured workers and pending capacity
+func NewQueue(opts Options) *Queue {
+	workers := max(opts.Workers, 1)
+	capacity := max(opts.Capacity, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		jobs:      make(map[string]*entry),
+		pending:   make(chan *entry, capacity),
+		retention: opts.Retention,
+		webhook:   opts.Webhook,
+		client:    &http.Client{Timeout: webhookTimeout},
+		clock:     opts.Clock,
+		ctx:       ctx,
+		cancel:    cancel,
+
+		remote:       opts.Remote,
+		leaseTimeout: cmp.Or(opts.LeaseTimeout, DefaultLeaseTimeout),
+	}
+	if q.clock == nil {
+		q.clock = clock.System
+	}
+	if q.remote {
+		return q
+	}
+
+	for range workers {
+		q.wg.Add(1)
+		go q.worker()
+	}
+	return q
+}
+
+// Submit enqueues fn and returns the queued job snapshot. It fails with
+// ErrQueueFull rather than blocking when the backlog is at capacity.
+func (q *Queue) Submit(fn Func, opts ...SubmitOption) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return Job{}, ErrClosed
+	}
+	q.prune()
+
+	e := &entry{
+		job: Job{
+			ID:        newID(),
+			Status:    StatusQueued,
+			CreatedAt: q.clock.Now(),
+			Webhook:   q.webhook.URL,
+		},
+		fn: fn,
+	}
+	for _, opt := range opts {
+		opt(&e.job)
+	}
+
+	select {
+	case q.pending <- e:
+	default:
+		return Job{}, ErrQueueFull
+	}
+
+	q.jobs[e.job.ID] = e
+	q.order = append(q.order, e.job.ID)
+	if q.remote {
+		// Shutdown waits for remote jobs to be completed
+		q.wg.Add(1)
+	}
+	return q.snapshot(e), nil
+}
+
+// Get returns the current state of the job with the given ID
+func (q *Queue) Get(id string) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.prune()
+	q.lapse()
+	e, ok := q.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return q.snapshot(e), true
+}
+
+// Shutdown stops accepting new jobs and waits for queued and running jobs to
+// finish. If ctx expires first, running jobs are cancelled.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.pending)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		// Remote workers are not stopped by cancelling
+		if !q.remote {
+			<-done
+		}
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) worker() {
+	defer q.wg.Done()
+	for e := range q.pending {
+		q.run(e)
+	}
+}
+
+func (q *Queue) run(e *entry) {
+	q.mu.Lock()
+	e.job.Status = StatusRunning
+	e.job.StartedAt = q.clock.Now()
+	q.dequeue(e.job.ID)
+	q.mu.Unlock()
+
+	result, err := e.fn(q.ctx)
+
+	q.mu.Lock()
+	job := q.finish(e, result, err)
+	q.mu.Unlock()
+	q.callback(e, job)
+}
+
+// finish records the outcome of the job, letting go of its work; callers
+// must hold q.mu
+func (q *Queue) finish(e *entry, result any, err error) Job {
+	e.fn, e.job.payload = nil, nil
+	e.job.FinishedAt = q.clock.Now()
+	if err != nil {
+		e.job.Status = StatusFailed
+		e.job.Error = err.Error()
+	} else {
+		e.job.Status = StatusSucceeded
+		e.job.Result = result
+	}
+	return e.job
+}
+
+// callback sends the finished job to its webhook, if it has one
+func (q *Queue) callback(e *entry, job Job) {
+	if job.Webhook == "" {
+		return
+	}
+	if err := q.notify(q.ctx, job); err != nil {
+		q.mu.Lock()
+		e.job.WebhookError = err.Error()
+		q.mu.Unlock()
+	}
+}
+
+// prune drops finished jobs older than the retention period; callers must
+// hold q.mu
+func (q *Queue) prune() {
+	if q.retention <= 0 {
+		return
+	}
+	cutoff := q.clock.Now().Add(-q.retention)
+	for id, e := range q.jobs {
+		if e.job.Finished() && e.job.FinishedAt.Before(cutoff) {
+			delete(q.jobs, id)
+		}
+	}
+}
+
+// dequeue removes id from the pending order; callers must hold q.mu
+func (q *Queue) dequeue(id string) {
+	for i, pending := range q.order {
+		if pending == id {
+			q.order = append(q.order[:i], q.order[i+1:]...)
+			return
+		}
+	}
+}
+
+// snapshot copies the job state; callers must hold q.mu
+func (q *Queue) snapshot(e *entry) Job {
+	job := e.job
+	if job.Status == StatusQueued {
+		for i, id := range q.order {
+			if id == job.ID {
+				job.Position = i + 1
+				break
+			}
+		}
+	}
+	return job
+}
+
+func newID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
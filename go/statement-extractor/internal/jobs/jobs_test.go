@@ -0,0 +1,139 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func waitFor(t *testing.T, q *Queue, id string, status Status) Job {
+	t.Helper()
+	var job Job
+	require.Eventually(t, func() bool {
+		job, _ = q.Get(id)
+		return job.Status == status
+	}, 2*time.Second, 5*time.Millisecond)
+	return job
+}
+
+func TestQueue_Backpressure(t *testing.T) {
+	q := NewQueue(Options{Workers: 1, Capacity: 1})
+	defer q.Shutdown(context.Background())
+
+	release := make(chan struct{})
+	blocking := func(ctx context.Context) (any, error) {
+		<-release
+		return "done", nil
+	}
+
+	first, err := q.Submit(blocking)
+	require.NoError(t, err)
+	waitFor(t, q, first.ID, StatusRunning)
+
+	second, err := q.Submit(blocking)
+	require.NoError(t, err)
+	assert.Equal(t, StatusQueued, second.Status)
+	assert.Equal(t, 1, second.Position)
+
+	_, err = q.Submit(blocking)
+	assert.ErrorIs(t, err, ErrQueueFull)
+
+	close(release)
+	job := waitFor(t, q, second.ID, StatusSucceeded)
+	assert.Equal(t, "done", job.Result)
+	assert.False(t, job.FinishedAt.IsZero())
+}
+
+func TestQueue_FailedJob(t *testing.T) {
+	q := NewQueue(Options{Workers: 2, Capacity: 4})
+	defer q.Shutdown(context.Background())
+
+	job, err := q.Submit(func(ctx context.Context) (any, error) {
+		return nil, errors.New("provider unavailable")
+	})
+	require.NoError(t, err)
+
+	job = waitFor(t, q, job.ID, StatusFailed)
+	assert.Equal(t, "provider unavailable", job.Error)
+}
+
+func TestQueue_ShutdownDrains(t *testing.T) {
+	q := NewQueue(Options{Workers: 1, Capacity: 4})
+
+	job, err := q.Submit(func(ctx context.Context) (any, error) {
+		time.Sleep(50 * time.Millisecond)
+		return 1, nil
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, q.Shutdown(context.Background()))
+	job, _ = q.Get(job.ID)
+	assert.Equal(t, StatusSucceeded, job.Status)
+
+	_, err = q.Submit(func(ctx context.Context) (any, error) { return nil, nil })
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestHandler(t *testing.T) {
+	q := NewQueue(Options{Workers: 1, Capacity: 1})
+	defer q.Shutdown(context.Background())
+
+	job, err := q.Submit(func(ctx context.Context) (any, error) { return "ok", nil })
+	require.NoError(t, err)
+	waitFor(t, q, job.ID, StatusSucceeded)
+
+	rec := httptest.NewRecorder()
+	q.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/"+job.ID, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got Job
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	assert.Equal(t, job.ID, got.ID)
+	assert.Equal(t, StatusSucceeded, got.Status)
+
+	rec = httptest.NewRecorder()
+	q.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/missing", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, `{"error": "job not found"}`, rec.Body.String())
+}
+
+func TestQueue_Retention(t *testing.T) {
+	c := &testClock{t: time.Now()}
+	q := NewQueue(Options{Workers: 1, Capacity: 1, Retention: time.Hour, Clock: c})
+	defer q.Shutdown(context.Background())
+
+	job, err := q.Submit(func(ctx context.Context) (any, error) { return "ok", nil })
+	require.NoError(t, err)
+	waitFor(t, q, job.ID, StatusSucceeded)
+
+	c.advance(2 * time.Hour)
+
+	_, ok := q.Get(job.ID)
+	assert.False(t, ok, "finished job should be pruned after the retention period")
+}
+
+// testClock is a settable time source for retention
+type testClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
@@ -0,0 +1,158 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+// DefaultLeaseTimeout is how long a remote worker has to complete a job
+const DefaultLeaseTimeout = 10 * time.Minute
+
+var (
+	// ErrNotFound is returned when completing a job the queue doesn't hold
+	ErrNotFound = errors.New("job not found")
+	// ErrLeaseLost is returned when completing a job whose lease lapsed or
+	// went to another worker
+	ErrLeaseLost = errors.New("job lease lapsed or is held by another worker")
+)
+
+// Lease is a job taken by a remote worker, which reports it with Complete
+// and Token before Expires
+type Lease struct {
+	Job     string    `json:"job"`
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires_at"`
+	Payload any       `json:"payload,omitempty"`
+}
+
+// Lease takes the next queued job of a remote queue, waiting up to wait for
+// one to be submitted. Jobs whose lease lapsed are offered first. It
+// reports false when no job was queued in time, ctx is done or the queue
+// has shut down.
+func (q *Queue) Lease(ctx context.Context, wait time.Duration) (Lease, bool) {
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+	pending := q.pending
+
+	for {
+		q.mu.Lock()
+		q.lapse()
+		if len(q.lapsed) > 0 {
+			e := q.lapsed[0]
+			q.lapsed = q.lapsed[1:]
+			lease := q.lease(e)
+			q.mu.Unlock()
+			return lease, true
+		}
+		// Wake when a running job's lease lapses, to offer it again
+		var lapses <-chan time.Time
+		if next, ok := q.nextExpiry(); ok {
+			lapses = time.After(max(next.Sub(q.clock.Now()), time.Millisecond))
+		}
+		q.mu.Unlock()
+
+		select {
+		case e, ok := <-pending:
+			if !ok {
+				// Closed: only lapsed leases are left to offer
+				pending = nil
+				continue
+			}
+			q.mu.Lock()
+			lease := q.lease(e)
+			q.mu.Unlock()
+			return lease, true
+		case <-lapses:
+		case <-timeout.C:
+			return Lease{}, false
+		case <-ctx.Done():
+			return Lease{}, false
+		case <-q.ctx.Done():
+			// Shut down
+			return Lease{}, false
+		}
+	}
+}
+
+// Complete records the outcome of the leased job id: its result, or the
+// error failure when not empty. The job's webhook is notified in the
+// background.
+func (q *Queue) Complete(id, token string, result any, failure string) error {
+	q.mu.Lock()
+	q.lapse()
+	e, ok := q.jobs[id]
+	if !ok {
+		q.mu.Unlock()
+		return ErrNotFound
+	}
+	if e.job.Status != StatusRunning || e.token == "" || e.token != token {
+		q.mu.Unlock()
+		return ErrLeaseLost
+	}
+	var err error
+	if failure != "" {
+		err = errors.New(failure)
+	}
+	e.token = ""
+	job := q.finish(e, result, err)
+	q.mu.Unlock()
+
+	// Submit counted the job for Shutdown; it is done once notified
+	go func() {
+		defer q.wg.Done()
+		q.callback(e, job)
+	}()
+	return nil
+}
+
+// lease marks e as running under a new lease; callers must hold q.mu
+func (q *Queue) lease(e *entry) Lease {
+	now := q.clock.Now()
+	e.job.Status = StatusRunning
+	e.job.StartedAt = now
+	e.token = newID()
+	e.expires = now.Add(q.leaseTimeout)
+	q.dequeue(e.job.ID)
+	return Lease{Job: e.job.ID, Token: e.token, Expires: e.expires, Payload: e.job.payload}
+}
+
+// lapse returns jobs whose lease expired to the front of the queue, oldest
+// first; callers must hold q.mu
+func (q *Queue) lapse() {
+	if !q.remote {
+		return
+	}
+	now := q.clock.Now()
+	var lapsed []*entry
+	for _, e := range q.jobs {
+		if e.job.Status == StatusRunning && e.token != "" && !now.Before(e.expires) {
+			lapsed = append(lapsed, e)
+		}
+	}
+	slices.SortFunc(lapsed, func(a, b *entry) int {
+		return a.job.CreatedAt.Compare(b.job.CreatedAt)
+	})
+	ids := make([]string, 0, len(lapsed))
+	for _, e := range lapsed {
+		e.job.Status = StatusQueued
+		e.job.StartedAt = time.Time{}
+		e.token = ""
+		ids = append(ids, e.job.ID)
+	}
+	q.order = append(ids, q.order...)
+	q.lapsed = append(q.lapsed, lapsed...)
+}
+
+// nextExpiry returns when the next running lease expires; callers must
+// hold q.mu
+func (q *Queue) nextExpiry() (time.Time, bool) {
+	var next time.Time
+	for _, e := range q.jobs {
+		if e.job.Status == StatusRunning && e.token != "" && (next.IsZero() || e.expires.Before(next)) {
+			next = e.expires
+		}
+	}
+	return next, !next.IsZero()
+}
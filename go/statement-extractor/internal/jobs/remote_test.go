@@ -0,0 +1,118 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueue_RemoteLease(t *testing.T) {
+	c := &testClock{t: time.Now()}
+	q := NewQueue(Options{Capacity: 2, Clock: c, Remote: true, LeaseTimeout: time.Minute})
+	ctx := context.Background()
+
+	_, ok := q.Lease(ctx, 0)
+	assert.False(t, ok, "nothing is queued")
+
+	first, err := q.Submit(nil, WithPayload("jan.pdf"))
+	require.NoError(t, err)
+	second, err := q.Submit(nil, WithPayload("feb.pdf"))
+	require.NoError(t, err)
+
+	lease, ok := q.Lease(ctx, time.Second)
+	require.True(t, ok)
+	assert.Equal(t, first.ID, lease.Job)
+	assert.Equal(t, "jan.pdf", lease.Payload)
+	assert.Equal(t, c.Now().Add(time.Minute), lease.Expires)
+	job, _ := q.Get(first.ID)
+	assert.Equal(t, StatusRunning, job.Status, "jobs are left to remote workers")
+	job, _ = q.Get(second.ID)
+	assert.Equal(t, 1, job.Position)
+
+	c.advance(2 * time.Minute)
+	assert.ErrorIs(t, q.Complete(first.ID, lease.Token, "late", ""), ErrLeaseLost)
+	job, _ = q.Get(first.ID)
+	assert.Equal(t, StatusQueued, job.Status, "a lapsed lease returns the job to the queue")
+	assert.Equal(t, 1, job.Position, "ahead of jobs not yet leased")
+
+	again, ok := q.Lease(ctx, time.Second)
+	require.True(t, ok)
+	assert.Equal(t, first.ID, again.Job)
+	assert.NotEqual(t, lease.Token, again.Token)
+	require.NoError(t, q.Complete(first.ID, again.Token, "done", ""))
+	job, _ = q.Get(first.ID)
+	assert.Equal(t, StatusSucceeded, job.Status)
+	assert.Equal(t, "done", job.Result)
+	assert.ErrorIs(t, q.Complete(first.ID, again.Token, "twice", ""), ErrLeaseLost)
+	assert.ErrorIs(t, q.Complete("missing", "", nil, ""), ErrNotFound)
+
+	lease, ok = q.Lease(ctx, time.Second)
+	require.True(t, ok)
+	require.NoError(t, q.Complete(second.ID, lease.Token, nil, "unreadable PDF"))
+	job, _ = q.Get(second.ID)
+	assert.Equal(t, StatusFailed, job.Status)
+	assert.Equal(t, "unreadable PDF", job.Error)
+
+	require.NoError(t, q.Shutdown(ctx), "every job was completed")
+}
+
+func TestQueue_LeaseWaits(t *testing.T) {
+	q := NewQueue(Options{Capacity: 1, Remote: true})
+	defer q.Shutdown(context.Background())
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_, _ = q.Submit(nil)
+	}()
+	lease, ok := q.Lease(context.Background(), 2*time.Second)
+	require.True(t, ok, "a job submitted while waiting is leased")
+	require.NoError(t, q.Complete(lease.Job, lease.Token, nil, ""))
+}
+
+func TestWorkerHandler(t *testing.T) {
+	q := NewQueue(Options{Capacity: 1, Remote: true})
+	defer q.Shutdown(context.Background())
+	handler := q.WorkerHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/worker/lease?wait=0s", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	job, err := q.Submit(nil, WithPayload(map[string]string{"name": "jan.pdf"}))
+	require.NoError(t, err)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/worker/lease?wait=1s", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var lease struct {
+		Job     string            `json:"job"`
+		Token   string            `json:"token"`
+		Payload map[string]string `json:"payload"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&lease))
+	assert.Equal(t, job.ID, lease.Job)
+	assert.Equal(t, "jan.pdf", lease.Payload["name"])
+
+	complete := func(id, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/worker/jobs/"+id+"/complete", strings.NewReader(body)))
+		return rec
+	}
+	assert.Equal(t, http.StatusConflict, complete(job.ID, `{"token": "stolen"}`).Code)
+	assert.Equal(t, http.StatusNotFound, complete("missing", `{"token": "x"}`).Code)
+	assert.Equal(t, http.StatusNoContent, complete(job.ID, `{"token": "`+lease.Token+`", "result": {"count": 3}}`).Code)
+
+	rec = httptest.NewRecorder()
+	q.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/"+job.ID, nil))
+	assert.Contains(t, rec.Body.String(), `"result":{"count":3}`, "the worker's result is served as sent")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/worker/lease?wait=soon", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,104 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	webhookTimeout    = 30 * time.Second
+	webhookMaxRetries = 3
+
+	// SignatureHeader carries the hex HMAC-SHA256 of the body when a secret
+	// is configured, so receivers can verify the callback came from us
+	SignatureHeader = "X-Statement-Extractor-Signature"
+)
+
+// webhookBackoff is the initial retry delay, doubled after each attempt
+var webhookBackoff = time.Second
+
+// WebhookConfig configures completion callbacks
+type WebhookConfig struct {
+	URL    string // default callback URL; jobs may override it
+	Secret string // optional HMAC key for SignatureHeader
+}
+
+// Sign returns the signature header value for body under secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// notify POSTs the finished job to its webhook
+func (q *Queue) notify(ctx context.Context, job Job) error {
+	return Deliver(ctx, q.client, job.Webhook, q.webhook.Secret, job)
+}
+
+// Deliver POSTs payload as JSON to url, signed with secret when one is set,
+// retrying transient failures with exponential backoff. A nil client uses
+// one with the webhook timeout.
+func Deliver(ctx context.Context, client *http.Client, url, secret string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshaling webhook payload: %w", err)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: webhookTimeout}
+	}
+
+	var lastErr error
+	backoff := webhookBackoff
+	for attempt := 0; attempt <= webhookMaxRetries; attempt++ {
+		retry, err := post(ctx, client, url, secret, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry || attempt == webhookMaxRetries {
+			break
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return fmt.Errorf("webhook delivery failed: %w", lastErr)
+}
+
+// post delivers one webhook attempt and reports whether a failure is retryable
+func post(ctx context.Context, client *http.Client, url, secret string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set(SignatureHeader, Sign(secret, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("server error: %s", resp.Status)
+	default:
+		return false, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+}
@@ -0,0 +1,85 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_DeliversSignedResult(t *testing.T) {
+	received := make(chan Job, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, Sign("s3cret", body), r.Header.Get(SignatureHeader))
+
+		var job Job
+		assert.NoError(t, json.Unmarshal(body, &job))
+		received <- job
+	}))
+	defer server.Close()
+
+	q := NewQueue(Options{Workers: 1, Capacity: 1, Webhook: WebhookConfig{Secret: "s3cret"}})
+	defer q.Shutdown(context.Background())
+
+	job, err := q.Submit(func(ctx context.Context) (any, error) {
+		return map[string]int{"total": 3}, nil
+	}, WithWebhook(server.URL))
+	require.NoError(t, err)
+
+	select {
+	case got := <-received:
+		assert.Equal(t, job.ID, got.ID)
+		assert.Equal(t, StatusSucceeded, got.Status)
+		assert.Equal(t, map[string]any{"total": float64(3)}, got.Result)
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+}
+
+func TestWebhook_RetriesServerErrors(t *testing.T) {
+	defer func(old time.Duration) { webhookBackoff = old }(webhookBackoff)
+	webhookBackoff = time.Millisecond
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	q := NewQueue(Options{Workers: 1, Capacity: 1, Webhook: WebhookConfig{URL: server.URL}})
+	job, err := q.Submit(func(ctx context.Context) (any, error) { return nil, nil })
+	require.NoError(t, err)
+	require.NoError(t, q.Shutdown(context.Background()))
+
+	job, _ = q.Get(job.ID)
+	assert.Equal(t, int32(3), calls.Load())
+	assert.Empty(t, job.WebhookError)
+}
+
+func TestWebhook_ClientErrorNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	q := NewQueue(Options{Workers: 1, Capacity: 1})
+	job, err := q.Submit(func(ctx context.Context) (any, error) { return nil, nil }, WithWebhook(server.URL))
+	require.NoError(t, err)
+	require.NoError(t, q.Shutdown(context.Background()))
+
+	job, _ = q.Get(job.ID)
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Contains(t, job.WebhookError, "404")
+}
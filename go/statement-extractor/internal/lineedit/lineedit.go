@@ -0,0 +1,195 @@
+// Package lineedit reads command lines at a terminal prompt, completing what
+// is being typed when Tab is pressed. Input that is not a terminal, such as a
+// pipe, is read line by line without a prompt, as is a terminal that cannot
+// be switched to raw mode.
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrInterrupted is returned when the line is abandoned with Ctrl-C
+var ErrInterrupted = errors.New("interrupted")
+
+// Completer returns the ways line can be completed: the start of line they
+// follow, and the possible endings, each of which replaces the rest of line
+type Completer func(line string) (head string, options []string)
+
+// Keys read at the prompt
+const (
+	ctrlC     = 0x03
+	ctrlD     = 0x04
+	ctrlH     = 0x08
+	ctrlU     = 0x15
+	escape    = 0x1b
+	backspace = 0x7f
+)
+
+// Reader reads lines from a terminal, or any other file
+type Reader struct {
+	Prompt   string
+	Complete Completer // nil completes nothing
+
+	in       *os.File
+	out      io.Writer
+	keys     *bufio.Reader
+	terminal bool
+}
+
+// New returns a reader of the lines of in, echoed with the prompt to out
+// when in is a terminal
+func New(in *os.File, out io.Writer, prompt string, complete Completer) *Reader {
+	info, err := in.Stat()
+	return &Reader{
+		Prompt:   prompt,
+		Complete: complete,
+		in:       in,
+		out:      out,
+		keys:     bufio.NewReader(in),
+		terminal: err == nil && info.Mode()&os.ModeCharDevice != 0,
+	}
+}
+
+// ReadLine returns the next line, without its line ending. It fails with
+// io.EOF at the end of the input, or Ctrl-D at an empty prompt, and with
+// ErrInterrupted when Ctrl-C abandons the line.
+func (r *Reader) ReadLine() (string, error) {
+	if !r.terminal {
+		return r.readLine()
+	}
+	restore, err := makeRaw(r.in)
+	if err != nil {
+		fmt.Fprint(r.out, r.Prompt)
+		line, err := r.readLine()
+		if err != nil {
+			fmt.Fprintln(r.out)
+		}
+		return line, err
+	}
+	defer restore()
+	return r.edit(r.keys)
+}
+
+// readLine reads a line as the input gives it
+func (r *Reader) readLine() (string, error) {
+	line, err := r.keys.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// edit reads a line key by key from a terminal in raw mode, echoing it
+func (r *Reader) edit(keys io.RuneReader) (string, error) {
+	var line []rune
+	fmt.Fprint(r.out, r.Prompt)
+	for {
+		key, _, err := keys.ReadRune()
+		if err != nil {
+			fmt.Fprint(r.out, "\r\n")
+			if err == io.EOF && len(line) > 0 {
+				return string(line), nil
+			}
+			return "", err
+		}
+		switch key {
+		case '\r', '\n':
+			fmt.Fprint(r.out, "\r\n")
+			return string(line), nil
+		case ctrlC:
+			fmt.Fprint(r.out, "^C\r\n")
+			return "", ErrInterrupted
+		case ctrlD:
+			if len(line) == 0 {
+				fmt.Fprint(r.out, "\r\n")
+				return "", io.EOF
+			}
+		case backspace, ctrlH:
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+				fmt.Fprint(r.out, "\b \b")
+			}
+		case ctrlU:
+			line = line[:0]
+			r.redraw(line)
+		case '\t':
+			line = r.complete(line)
+		case escape:
+			skipEscape(keys)
+		default:
+			if unicode.IsPrint(key) {
+				line = append(line, key)
+				fmt.Fprint(r.out, string(key))
+			}
+		}
+	}
+}
+
+// complete completes line as far as its options agree, or lists them when
+// they go separate ways
+func (r *Reader) complete(line []rune) []rune {
+	if r.Complete == nil {
+		return line
+	}
+	head, options := r.Complete(string(line))
+	if len(options) == 0 {
+		fmt.Fprint(r.out, "\a")
+		return line
+	}
+	common := options[0]
+	for _, option := range options[1:] {
+		common = commonPrefix(common, option)
+	}
+	if utf8.RuneCountInString(head+common) > len(line) {
+		line = []rune(head + common)
+		r.redraw(line)
+		return line
+	}
+	fmt.Fprint(r.out, "\r\n")
+	for i, option := range options {
+		if i > 0 {
+			fmt.Fprint(r.out, "  ")
+		}
+		fmt.Fprint(r.out, strings.TrimSpace(option))
+	}
+	fmt.Fprint(r.out, "\r\n")
+	r.redraw(line)
+	return line
+}
+
+// redraw writes the prompt and line over the current terminal line
+func (r *Reader) redraw(line []rune) {
+	fmt.Fprintf(r.out, "\r\x1b[K%s%s", r.Prompt, string(line))
+}
+
+// commonPrefix returns the start of a that b shares, ignoring case
+func commonPrefix(a, b string) string {
+	ar, br := []rune(a), []rune(b)
+	n := 0
+	for n < len(ar) && n < len(br) && unicode.ToLower(ar[n]) == unicode.ToLower(br[n]) {
+		n++
+	}
+	return string(ar[:n])
+}
+
+// skipEscape passes over the rest of an escape sequence, such as an arrow
+// key's, as there is no cursor to move
+func skipEscape(keys io.RuneReader) {
+	key, _, err := keys.ReadRune()
+	if err != nil || (key != '[' && key != 'O') {
+		return
+	}
+	for {
+		key, _, err := keys.ReadRune()
+		if err != nil || (key >= 0x40 && key <= 0x7e) {
+			return
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package lineedit
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// complete completes command names, and categories after "category "
+func complete(line string) (string, []string) {
+	head, word := "", line
+	options := []string{"categories", "category ", "clear"}
+	if i := strings.Index(line, " "); i >= 0 {
+		head, word = line[:i+1], line[i+1:]
+		options = []string{"Gifts", "Groceries", "Groceries & household"}
+	}
+	var matches []string
+	for _, option := range options {
+		if strings.HasPrefix(strings.ToLower(option), strings.ToLower(word)) {
+			matches = append(matches, option)
+		}
+	}
+	return head, matches
+}
+
+func TestEdit(t *testing.T) {
+	for _, test := range []struct {
+		name, keys, want string
+		err              error
+		shown            string
+	}{
+		{name: "typed", keys: "sum\r", want: "sum"},
+		{name: "completed to the options' common start", keys: "cat\t\r", want: "categor"},
+		{name: "unique option", keys: "category gi\t\r", want: "category Gifts"},
+		{name: "ignoring case", keys: "category gro\t\r", want: "category Groceries"},
+		{name: "options listed", keys: "c\t\t\r", want: "c", shown: "categories  category  clear"},
+		{name: "no options", keys: "x\t\r", want: "x", shown: "\a"},
+		{name: "backspace", keys: "sumx\x7f\r", want: "sum"},
+		{name: "line erased", keys: "sum\x15top\r", want: "top"},
+		{name: "arrow keys ignored", keys: "su\x1b[Dm\r", want: "sum"},
+		{name: "unicode", keys: "café\x7fe\r", want: "cafe"},
+		{name: "interrupted", keys: "sum\x03", err: ErrInterrupted},
+		{name: "end of input", keys: "\x04", err: io.EOF},
+		{name: "ctrl-D in a line", keys: "s\x04um\r", want: "sum"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			r := &Reader{Prompt: "> ", Complete: complete, out: &out}
+			line, err := r.edit(strings.NewReader(test.keys))
+			assert.ErrorIs(t, err, test.err)
+			assert.Equal(t, test.want, line)
+			assert.Contains(t, out.String(), test.shown)
+		})
+	}
+}
+
+func TestReadLinePiped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "commands")
+	require.NoError(t, os.WriteFile(path, []byte("period 2024-06\r\nsum"), 0o644))
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var out bytes.Buffer
+	r := New(f, &out, "> ", complete)
+	for _, want := range []string{"period 2024-06", "sum"} {
+		line, err := r.ReadLine()
+		require.NoError(t, err)
+		assert.Equal(t, want, line)
+	}
+	_, err = r.ReadLine()
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Empty(t, out.String(), "no prompt is shown without a terminal")
+}
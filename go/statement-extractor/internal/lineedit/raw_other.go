@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package lineedit
+
+import (
+	"errors"
+	"os"
+)
+
+// makeRaw is not supported here, so lines are read as the terminal gives
+// them, without completion
+func makeRaw(f *os.File) (func(), error) {
+	return nil, errors.New("raw terminal input is not supported on this platform")
+}
@@ -0,0 +1,97 @@
+// Package locale describes how dates and numbers are written in a region,
+// so that tables and reports read naturally wherever they are run.
+package locale
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/statement-extractor/internal/config"
+)
+
+// Locale is the way a region writes dates and numbers
+type Locale struct {
+	Name        string
+	DateLayout  string // Go layout, e.g. "02/01/2006"
+	Decimal     string
+	Thousands   string
+	SymbolAfter bool // currency symbols follow the amount, "12,50 €"
+	FirstDay    time.Weekday
+}
+
+// ISO is used without a configured locale: ISO 8601 dates, "." decimals
+// and weeks starting on Monday, as output has always been
+var ISO = Locale{Name: "iso", DateLayout: "2006-01-02", Decimal: ".", Thousands: ",", FirstDay: time.Monday}
+
+// builtin holds the supported locales, keyed by lower-cased name
+var builtin = map[string]Locale{
+	"iso":   ISO,
+	"en-au": {Name: "en-AU", DateLayout: "02/01/2006", Decimal: ".", Thousands: ",", FirstDay: time.Monday},
+	"en-nz": {Name: "en-NZ", DateLayout: "02/01/2006", Decimal: ".", Thousands: ",", FirstDay: time.Monday},
+	"en-gb": {Name: "en-GB", DateLayout: "02/01/2006", Decimal: ".", Thousands: ",", FirstDay: time.Monday},
+	"en-us": {Name: "en-US", DateLayout: "01/02/2006", Decimal: ".", Thousands: ",", FirstDay: time.Sunday},
+	"en-ca": {Name: "en-CA", DateLayout: "2006-01-02", Decimal: ".", Thousands: ",", FirstDay: time.Sunday},
+	"de-de": {Name: "de-DE", DateLayout: "02.01.2006", Decimal: ",", Thousands: ".", SymbolAfter: true, FirstDay: time.Monday},
+	"fr-fr": {Name: "fr-FR", DateLayout: "02/01/2006", Decimal: ",", Thousands: " ", SymbolAfter: true, FirstDay: time.Monday},
+}
+
+// New returns the configured locale with its date format and first day of
+// the week overridden where set; no name is ISO
+func New(cfg config.LocaleConfig) (Locale, error) {
+	l := ISO
+	if cfg.Name != "" {
+		var ok bool
+		if l, ok = builtin[strings.ToLower(strings.ReplaceAll(cfg.Name, "_", "-"))]; !ok {
+			return Locale{}, fmt.Errorf("locale.name: unknown locale %q (expected %s)", cfg.Name, strings.Join(names(), ", "))
+		}
+	}
+	if cfg.DateFormat != "" {
+		l.DateLayout = cfg.DateFormat
+	}
+	if cfg.FirstDayOfWeek != "" {
+		day, err := ParseWeekday(cfg.FirstDayOfWeek)
+		if err != nil {
+			return Locale{}, fmt.Errorf("locale.first_day_of_week: %w", err)
+		}
+		l.FirstDay = day
+	}
+	return l, nil
+}
+
+// ParseWeekday parses an English day name such as "sunday" or "Mon"
+func ParseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if len(s) >= 3 && strings.HasPrefix(name, s) {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", s)
+}
+
+// Date formats t, or "" for the zero time
+func (l Locale) Date(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(l.DateLayout)
+}
+
+// Number formats a plain number with the locale's decimal separator
+func (l Locale) Number(v float64) string {
+	s := strconv.FormatFloat(v+0, 'g', -1, 64) // +0 normalizes negative zero
+	return strings.Replace(s, ".", l.Decimal, 1)
+}
+
+func names() []string {
+	list := make([]string, 0, len(builtin))
+	for _, l := range builtin {
+		list = append(list, l.Name)
+	}
+	sort.Strings(list)
+	return list
+}
@@ -0,0 +1,42 @@
+package locale
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+)
+
+func TestNew(t *testing.T) {
+	date := time.Date(2024, 3, 7, 0, 0, 0, 0, time.UTC)
+
+	l, err := New(config.LocaleConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-07", l.Date(date))
+	assert.Equal(t, "1.5", l.Number(1.5))
+	assert.Equal(t, time.Monday, l.FirstDay)
+
+	l, err = New(config.LocaleConfig{Name: "en_US"})
+	require.NoError(t, err)
+	assert.Equal(t, "03/07/2024", l.Date(date))
+	assert.Equal(t, time.Sunday, l.FirstDay)
+
+	l, err = New(config.LocaleConfig{Name: "de-DE", FirstDayOfWeek: "Sat"})
+	require.NoError(t, err)
+	assert.Equal(t, "07.03.2024", l.Date(date))
+	assert.Equal(t, "1,5", l.Number(1.5))
+	assert.Equal(t, time.Saturday, l.FirstDay)
+	assert.Empty(t, l.Date(time.Time{}))
+
+	l, err = New(config.LocaleConfig{Name: "en-AU", DateFormat: "2 Jan 2006"})
+	require.NoError(t, err)
+	assert.Equal(t, "7 Mar 2024", l.Date(date))
+
+	_, err = New(config.LocaleConfig{Name: "xx-XX"})
+	assert.ErrorContains(t, err, "unknown locale")
+	_, err = New(config.LocaleConfig{FirstDayOfWeek: "someday"})
+	assert.ErrorContains(t, err, "first_day_of_week")
+}
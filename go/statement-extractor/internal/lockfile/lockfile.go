@@ -0,0 +1,101 @@
+// Package lockfile provides advisory locks guarding on-disk state that may be
+// shared between concurrent invocations (watch mode, manual CLI runs).
+package lockfile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrBusy is returned when the lock is held by another process
+var ErrBusy = errors.New("store is busy")
+
+// errLocked is returned by tryLock when another process holds the lock
+var errLocked = errors.New("locked")
+
+// pollInterval is how often a waiting caller retries the lock
+const pollInterval = 100 * time.Millisecond
+
+// Lock is an exclusive advisory lock held on a sidecar ".lock" file
+type Lock struct {
+	file *os.File
+	path string
+}
+
+// Path returns the lock file path for the given resource
+func Path(resource string) string {
+	return resource + ".lock"
+}
+
+// Acquire takes an exclusive lock for resource. With a zero wait it fails
+// immediately with ErrBusy when another process holds the lock; otherwise it
+// retries until wait elapses or ctx is cancelled.
+func Acquire(ctx context.Context, resource string, wait time.Duration) (*Lock, error) {
+	path := Path(resource)
+	deadline := time.Now().Add(wait)
+	var f *os.File
+	for {
+		var err error
+		if f, err = tryLock(path); err == nil {
+			break
+		}
+		if !errors.Is(err, errLocked) {
+			return nil, err
+		}
+		if wait <= 0 || time.Now().After(deadline) {
+			return nil, busyError(path, readHolder(path))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+
+	// Record our PID so a competing process can report who holds the lock
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+
+	return &Lock{file: f, path: path}, nil
+}
+
+// Release drops the lock
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	defer func() { l.file = nil }()
+
+	_ = l.file.Truncate(0)
+	if err := unlock(l.file, l.path); err != nil {
+		return fmt.Errorf("failed to unlock %s: %w", l.path, err)
+	}
+	return nil
+}
+
+// readHolder returns the PID recorded by the current lock holder, if any
+func readHolder(path string) int {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
+
+func busyError(path string, pid int) error {
+	if pid > 0 {
+		return fmt.Errorf("%w: %s is held by process %d (use --wait to block until it is released)", ErrBusy, path, pid)
+	}
+	return fmt.Errorf("%w: %s is held by another process (use --wait to block until it is released)", ErrBusy, path)
+}
@@ -0,0 +1,47 @@
+//go:build !unix
+
+package lockfile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// tryLock creates the lock file exclusively, returning errLocked when it
+// already exists. A lock file left by a process that is no longer running
+// is removed and created again.
+func tryLock(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		if pid := readHolder(path); pid > 0 && !running(pid) && os.Remove(path) == nil {
+			return tryLock(path)
+		}
+		return nil, errLocked
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	return f, nil
+}
+
+// unlock closes and removes the lock file, letting the next process create
+// it
+func unlock(f *os.File, path string) error {
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// running reports whether process pid exists. Where the platform cannot
+// tell, it is assumed to, leaving the lock to be removed by hand.
+func running(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+	return true
+}
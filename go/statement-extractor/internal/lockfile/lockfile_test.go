@@ -0,0 +1,72 @@
+package lockfile
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquire_Busy(t *testing.T) {
+	resource := filepath.Join(t.TempDir(), "txns.db")
+
+	lock, err := Acquire(context.Background(), resource, 0)
+	require.NoError(t, err)
+	defer lock.Release()
+
+	// flock locks are per open file, so a second open in-process conflicts
+	second, err := Acquire(context.Background(), resource, 0)
+	assert.Nil(t, second)
+	assert.ErrorIs(t, err, ErrBusy)
+	assert.Contains(t, err.Error(), "process "+strconv.Itoa(os.Getpid()))
+}
+
+func TestAcquire_WaitForRelease(t *testing.T) {
+	resource := filepath.Join(t.TempDir(), "txns.db")
+
+	lock, err := Acquire(context.Background(), resource, 0)
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		lock.Release()
+	}()
+
+	second, err := Acquire(context.Background(), resource, 2*time.Second)
+	require.NoError(t, err)
+	defer second.Release()
+
+	content, err := os.ReadFile(Path(resource))
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(os.Getpid()), strings.TrimSpace(string(content)))
+}
+
+func TestAcquire_WaitCancelled(t *testing.T) {
+	resource := filepath.Join(t.TempDir(), "txns.db")
+
+	lock, err := Acquire(context.Background(), resource, 0)
+	require.NoError(t, err)
+	defer lock.Release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = Acquire(ctx, resource, time.Minute)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRelease_Idempotent(t *testing.T) {
+	resource := filepath.Join(t.TempDir(), "txns.db")
+
+	lock, err := Acquire(context.Background(), resource, 0)
+	require.NoError(t, err)
+
+	assert.NoError(t, lock.Release())
+	assert.NoError(t, lock.Release())
+}
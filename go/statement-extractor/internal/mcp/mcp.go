@@ -0,0 +1,191 @@
+// Package mcp serves the transaction store to desktop LLM assistants as a
+// Model Context Protocol server, so they can answer questions about
+// spending from the local data.
+//
+// Only the parts of the protocol a tools-only server needs are implemented:
+// JSON-RPC 2.0 messages, one per line, over the stdio transport, with the
+// initialize handshake, ping, tools/list and tools/call. Tools read the
+// store; none of them change it.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// ProtocolVersions are the protocol revisions the server speaks, newest
+// first
+var ProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// maxMessage is the longest message read
+const maxMessage = 4 << 20
+
+// Tool is a function the assistant can call
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema"` // JSON Schema of the arguments
+
+	// Call returns the tool's result, encoded as JSON for the assistant. An
+	// error is reported to the assistant as a failed call rather than a
+	// protocol error, so that it can correct its arguments.
+	Call func(ctx context.Context, args json.RawMessage) (any, error) `json:"-"`
+}
+
+// Server answers the requests of one client
+type Server struct {
+	Name    string
+	Version string
+	Tools   []Tool
+}
+
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// Serve answers the requests read from r on w until r ends or ctx is done
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	lines := bufio.NewScanner(r)
+	lines.Buffer(make([]byte, 64<<10), maxMessage)
+	enc := json.NewEncoder(w)
+	for lines.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line := lines.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if reply := s.handle(ctx, line); reply != nil {
+			if err := enc.Encode(reply); err != nil {
+				return fmt.Errorf("failed to write response: %w", err)
+			}
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return fmt.Errorf("failed to read request: %w", err)
+	}
+	return nil
+}
+
+// handle answers one message, returning nil for notifications and
+// responses, which get no reply
+func (s *Server) handle(ctx context.Context, line []byte) *message {
+	var req message
+	if err := json.Unmarshal(line, &req); err != nil {
+		return &message{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{codeParseError, "invalid JSON: " + err.Error()}}
+	}
+	if req.Method == "" {
+		if req.ID == nil {
+			return nil
+		}
+		return &message{JSONRPC: "2.0", ID: req.ID, Error: &rpcError{codeInvalidRequest, "no method"}}
+	}
+	if req.ID == nil {
+		return nil
+	}
+
+	result, err := s.call(ctx, req.Method, req.Params)
+	reply := &message{JSONRPC: "2.0", ID: req.ID, Result: result}
+	if err != nil {
+		var rpcErr *rpcError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &rpcError{codeInvalidParams, err.Error()}
+		}
+		reply.Result, reply.Error = nil, rpcErr
+	}
+	return reply
+}
+
+func (s *Server) call(ctx context.Context, method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		var p struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		version := ProtocolVersions[0]
+		if slices.Contains(ProtocolVersions, p.ProtocolVersion) {
+			version = p.ProtocolVersion
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]string{"name": s.Name, "version": s.Version},
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		return map[string]any{"tools": s.Tools}, nil
+	case "tools/call":
+		var p struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		i := slices.IndexFunc(s.Tools, func(t Tool) bool { return t.Name == p.Name })
+		if i < 0 {
+			return nil, &rpcError{codeInvalidParams, fmt.Sprintf("unknown tool %q", p.Name)}
+		}
+		if len(p.Arguments) == 0 || string(p.Arguments) == "null" {
+			p.Arguments = json.RawMessage("{}")
+		}
+		return toolResult(s.Tools[i].Call(ctx, p.Arguments)), nil
+	}
+	return nil, &rpcError{codeMethodNotFound, fmt.Sprintf("unknown method %q", method)}
+}
+
+// toolResult is the result of tools/call: the tool's value as JSON text, or
+// its error
+func toolResult(v any, err error) map[string]any {
+	text := func(s string) []map[string]string { return []map[string]string{{"type": "text", "text": s}} }
+	if err != nil {
+		return map[string]any{"content": text(err.Error()), "isError": true}
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return map[string]any{"content": text("failed to encode result: " + err.Error()), "isError": true}
+	}
+	return map[string]any{"content": text(string(data)), "structuredContent": json.RawMessage(data)}
+}
+
+func decodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &rpcError{codeInvalidParams, "invalid params: " + err.Error()}
+	}
+	return nil
+}
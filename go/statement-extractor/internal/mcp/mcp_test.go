@@ -0,0 +1,128 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/categorizer"
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/store"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// exchange sends the requests, one per line, and decodes the replies
+func exchange(t *testing.T, s *Server, requests ...string) []map[string]any {
+	t.Helper()
+	var out bytes.Buffer
+	require.NoError(t, s.Serve(context.Background(), strings.NewReader(strings.Join(requests, "\n")+"\n"), &out))
+	var replies []map[string]any
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var reply map[string]any
+		require.NoError(t, dec.Decode(&reply))
+		replies = append(replies, reply)
+	}
+	return replies
+}
+
+func TestServe(t *testing.T) {
+	s := &Server{Name: "test", Version: "1", Tools: []Tool{{
+		Name:        "echo",
+		InputSchema: json.RawMessage(`{"type": "object"}`),
+		Call: func(_ context.Context, args json.RawMessage) (any, error) {
+			var v map[string]any
+			_ = json.Unmarshal(args, &v)
+			if v["fail"] == true {
+				return nil, errors.New("asked to fail")
+			}
+			return v, nil
+		},
+	}}}
+
+	replies := exchange(t, s,
+		`{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": {"protocolVersion": "2024-11-05", "capabilities": {}}}`,
+		`{"jsonrpc": "2.0", "method": "notifications/initialized"}`,
+		`{"jsonrpc": "2.0", "id": "two", "method": "tools/list"}`,
+		`{"jsonrpc": "2.0", "id": 3, "method": "tools/call", "params": {"name": "echo", "arguments": {"a": 1}}}`,
+		`{"jsonrpc": "2.0", "id": 4, "method": "tools/call", "params": {"name": "echo", "arguments": {"fail": true}}}`,
+		`{"jsonrpc": "2.0", "id": 5, "method": "tools/call", "params": {"name": "missing"}}`,
+		`{"jsonrpc": "2.0", "id": 6, "method": "resources/list"}`,
+		`not json`,
+	)
+	require.Len(t, replies, 7, "the notification is not answered")
+
+	assert.Equal(t, float64(1), replies[0]["id"])
+	result := replies[0]["result"].(map[string]any)
+	assert.Equal(t, "2024-11-05", result["protocolVersion"], "a supported version is kept")
+	assert.Contains(t, result["capabilities"], "tools")
+
+	assert.Equal(t, "two", replies[1]["id"])
+	tools := replies[1]["result"].(map[string]any)["tools"].([]any)
+	require.Len(t, tools, 1)
+	assert.Equal(t, "echo", tools[0].(map[string]any)["name"])
+
+	result = replies[2]["result"].(map[string]any)
+	assert.Equal(t, map[string]any{"a": float64(1)}, result["structuredContent"])
+	assert.Equal(t, `{"a":1}`, result["content"].([]any)[0].(map[string]any)["text"])
+
+	result = replies[3]["result"].(map[string]any)
+	assert.Equal(t, true, result["isError"], "a failed call is a tool error, not a protocol error")
+	assert.Equal(t, "asked to fail", result["content"].([]any)[0].(map[string]any)["text"])
+
+	assert.Equal(t, float64(codeInvalidParams), replies[4]["error"].(map[string]any)["code"])
+	assert.Equal(t, float64(codeMethodNotFound), replies[5]["error"].(map[string]any)["code"])
+	assert.Equal(t, float64(codeParseError), replies[6]["error"].(map[string]any)["code"])
+	assert.Nil(t, replies[6]["id"])
+}
+
+func TestTools(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	ctx := context.Background()
+	db, err := store.Open(ctx, filepath.Join(t.TempDir(), "txns.db"))
+	require.NoError(t, err)
+	june := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	_, err = db.Add(ctx, []transaction.Transaction{
+		{Date: june, Description: "COLES 0123", Amount: -4550, Category: "Groceries"},
+		{Date: june, Description: "COLES 0456", Amount: -2000, Category: "Groceries"},
+		{Date: june.AddDate(0, 1, 0), Description: "NETFLIX", Amount: -1799, Category: "Entertainment"},
+	})
+	require.NoError(t, err)
+	cfg := config.Default()
+	cfg.Categories = []config.CategoryRule{{Pattern: "NETFLIX", Category: "Entertainment"}}
+	chain, err := categorizer.New(cfg)
+	require.NoError(t, err)
+	s := New(db, chain)
+
+	call := func(name, args string) map[string]any {
+		t.Helper()
+		replies := exchange(t, s, `{"jsonrpc": "2.0", "id": 1, "method": "tools/call", "params": {"name": "`+name+`", "arguments": `+args+`}}`)
+		require.Len(t, replies, 1)
+		result := replies[0]["result"].(map[string]any)
+		require.Nil(t, result["isError"], result["content"])
+		return result["structuredContent"].(map[string]any)
+	}
+
+	result := call("query_transactions", `{"period": "2024-06", "limit": 1}`)
+	assert.Equal(t, float64(2), result["count"])
+	assert.Equal(t, -65.5, result["total"])
+	assert.Len(t, result["transactions"], 1)
+	assert.Equal(t, true, result["truncated"])
+
+	result = call("spending_summary", `{"by": "month", "category": "groceries"}`)
+	assert.Equal(t, []any{map[string]any{"group": "2024-06", "count": float64(2), "total": -65.5}}, result["groups"])
+
+	result = call("categorize_description", `{"description": "NETFLIX.COM"}`)
+	assert.Equal(t, "Entertainment", result["category"])
+}
@@ -0,0 +1,192 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/example/statement-extractor/internal/categorizer"
+	"github.com/example/statement-extractor/internal/report"
+	"github.com/example/statement-extractor/internal/store"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// DefaultLimit and MaxLimit bound the transactions query_transactions
+// returns, keeping its result within an assistant's context
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// filterSchema is the JSON Schema properties of filterArgs
+const filterSchema = `
+    "period": {"type": "string", "description": "YYYY, YYYY-MM or YYYY-MM:YYYY-MM"},
+    "category": {"type": "string", "description": "Exact category, ignoring case"},
+    "account": {"type": "string", "description": "Exact account, ignoring case"},
+    "text": {"type": "string", "description": "Substring of the description, ignoring case"},
+    "tag": {"type": "string"},
+    "card": {"type": "string", "description": "Last digits of the card number"}`
+
+// filterArgs are the arguments narrowing the transactions a tool reads
+type filterArgs struct {
+	Period   string `json:"period"`
+	Category string `json:"category"`
+	Account  string `json:"account"`
+	Text     string `json:"text"`
+	Tag      string `json:"tag"`
+	Card     string `json:"card"`
+}
+
+func (a filterArgs) filter() (store.Filter, error) {
+	f := store.Filter{Category: a.Category, Account: a.Account, Text: a.Text, Tag: a.Tag, Card: a.Card}
+	if a.Period != "" {
+		period, err := report.ParsePeriod(a.Period)
+		if err != nil {
+			return store.Filter{}, err
+		}
+		f.From, f.To = period.Start, period.End
+	}
+	return f, nil
+}
+
+// New returns a server whose tools query db and categorize descriptions
+// with chain
+func New(db *store.Store, chain *categorizer.Chain) *Server {
+	return &Server{
+		Name:    "statement-extractor",
+		Version: "1",
+		Tools: []Tool{
+			queryTransactions(db),
+			spendingSummary(db),
+			categorizeDescription(chain),
+		},
+	}
+}
+
+func queryTransactions(db *store.Store) Tool {
+	return Tool{
+		Name: "query_transactions",
+		Description: "List stored bank transactions, oldest first. Amounts are negative for money spent. " +
+			"Returns the number of matching transactions and their net total even when the list is cut at the limit.",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {` + filterSchema + `,
+    "limit": {"type": "integer", "description": "Maximum transactions to return, default 100, at most 1000"}
+  }
+}`),
+		Call: func(ctx context.Context, raw json.RawMessage) (any, error) {
+			var args struct {
+				filterArgs
+				Limit int `json:"limit"`
+			}
+			if err := json.Unmarshal(raw, &args); err != nil {
+				return nil, fmt.Errorf("invalid arguments: %w", err)
+			}
+			f, err := args.filter()
+			if err != nil {
+				return nil, err
+			}
+			limit := min(args.Limit, MaxLimit)
+			if limit <= 0 {
+				limit = DefaultLimit
+			}
+
+			txns, err := db.List(ctx, f)
+			if err != nil {
+				return nil, err
+			}
+			var total transaction.Amount
+			for _, t := range txns {
+				total += t.Amount
+			}
+			result := map[string]any{"count": len(txns), "total": total, "transactions": txns[:min(limit, len(txns))]}
+			if len(txns) > limit {
+				result["truncated"] = true
+			}
+			return result, nil
+		},
+	}
+}
+
+func spendingSummary(db *store.Store) Tool {
+	return Tool{
+		Name: "spending_summary",
+		Description: "Count and total stored bank transactions by " + strings.Join(store.Groupings, ", ") + ". " +
+			"Amounts are negative for money spent. Split transactions count in each category they are split into.",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "by": {"type": "string", "enum": ["` + strings.Join(store.Groupings, `", "`) + `"], "description": "Grouping, default category"},` + filterSchema + `
+  }
+}`),
+		Call: func(ctx context.Context, raw json.RawMessage) (any, error) {
+			var args struct {
+				filterArgs
+				By string `json:"by"`
+			}
+			if err := json.Unmarshal(raw, &args); err != nil {
+				return nil, fmt.Errorf("invalid arguments: %w", err)
+			}
+			if args.By == "" {
+				args.By = "category"
+			}
+			if !slices.Contains(store.Groupings, args.By) {
+				return nil, fmt.Errorf("invalid by %q: expected %s", args.By, strings.Join(store.Groupings, ", "))
+			}
+			f, err := args.filter()
+			if err != nil {
+				return nil, err
+			}
+
+			totals, err := db.Sum(ctx, f, args.By)
+			if err != nil {
+				return nil, err
+			}
+			type group struct {
+				Group string             `json:"group"`
+				Count int                `json:"count"`
+				Total transaction.Amount `json:"total"`
+			}
+			groups := make([]group, len(totals))
+			for i, t := range totals {
+				groups[i] = group{t.Group, t.Count, t.Amount}
+			}
+			return map[string]any{"by": args.By, "groups": groups}, nil
+		},
+	}
+}
+
+func categorizeDescription(chain *categorizer.Chain) Tool {
+	return Tool{
+		Name:        "categorize_description",
+		Description: "Categorize a bank transaction description with the configured rules, as extraction would.",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "description": {"type": "string"},
+    "amount": {"type": "number", "description": "Negative for money spent; rules with amount conditions need it"}
+  },
+  "required": ["description"]
+}`),
+		Call: func(ctx context.Context, raw json.RawMessage) (any, error) {
+			var args struct {
+				Description string  `json:"description"`
+				Amount      float64 `json:"amount"`
+			}
+			if err := json.Unmarshal(raw, &args); err != nil {
+				return nil, fmt.Errorf("invalid arguments: %w", err)
+			}
+			if args.Description == "" {
+				return nil, errors.New("no description")
+			}
+			result, err := chain.Categorize(ctx, transaction.Transaction{Description: args.Description, Amount: transaction.FromFloat(args.Amount)})
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{"category": result.Category, "confidence": result.Confidence, "stage": result.Stage, "rule": result.Rule}, nil
+		},
+	}
+}
@@ -0,0 +1,159 @@
+// Package merchant cleans the noise banks add to transaction descriptions,
+// such as payment processor prefixes, card numbers and locations, down to a
+// merchant name: "SQ *COFFEE SHOP 4521 SYDNEY AU" becomes "COFFEE SHOP".
+package merchant
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Cleaners are the names of the built-in cleaners, in the order they run
+var Cleaners = []string{"processor", "location", "card"}
+
+// Normalizer names the merchant of a description with the first matching
+// [[merchants.rules]] entry, or else with the enabled built-in cleaners
+type Normalizer struct {
+	rules    []rule
+	cleaners map[string]bool
+}
+
+type rule struct {
+	pattern  *regexp.Regexp
+	merchant string
+}
+
+// New compiles the merchant rules and enables the configured cleaners; no
+// cleaners enables them all, and "none" disables them
+func New(cfg config.MerchantConfig) (*Normalizer, error) {
+	n := &Normalizer{cleaners: make(map[string]bool)}
+	for i, r := range cfg.Rules {
+		if r.Merchant == "" {
+			return nil, fmt.Errorf("merchants.rules %d has no merchant", i+1)
+		}
+		pattern, err := regexp.Compile("(?i)" + r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for merchant %q: %w", r.Merchant, err)
+		}
+		n.rules = append(n.rules, rule{pattern: pattern, merchant: r.Merchant})
+	}
+
+	cleaners := cfg.Cleaners
+	if len(cleaners) == 0 {
+		cleaners = Cleaners
+	}
+	for _, name := range cleaners {
+		name = strings.ToLower(name)
+		switch {
+		case name == "none":
+		case slices.Contains(Cleaners, name):
+			n.cleaners[name] = true
+		default:
+			return nil, fmt.Errorf("unknown merchants.cleaners entry %q (expected %s or none)", name, strings.Join(Cleaners, ", "))
+		}
+	}
+	return n, nil
+}
+
+// Merchant returns the merchant name for a description. A rule's merchant
+// may refer to submatches of its pattern as $1. When cleaning leaves nothing
+// the description is returned with its spacing tidied.
+func (n *Normalizer) Merchant(description string) string {
+	for _, r := range n.rules {
+		if m := r.pattern.FindStringSubmatchIndex(description); m != nil {
+			return string(r.pattern.ExpandString(nil, r.merchant, description, m))
+		}
+	}
+
+	s := description
+	if n.cleaners["processor"] {
+		s = processorPrefix.ReplaceAllString(s, "")
+	}
+	words := strings.Fields(s)
+	if n.cleaners["location"] {
+		words = trimLocation(words)
+	}
+	if n.cleaners["card"] {
+		words = dropCardNumbers(words)
+	}
+	if len(words) == 0 {
+		return strings.Join(strings.Fields(description), " ")
+	}
+	return strings.Trim(strings.Join(words, " "), " -*#")
+}
+
+// Apply sets Merchant on each transaction, first keeping the description as
+// extracted in RawDescription when that is not already set
+func (n *Normalizer) Apply(txns []transaction.Transaction) {
+	for i := range txns {
+		if txns[i].RawDescription == "" {
+			txns[i].RawDescription = txns[i].Description
+		}
+		txns[i].Merchant = n.Merchant(txns[i].RawDescription)
+	}
+}
+
+// processorPrefix matches the prefix payment processors such as Square,
+// PayPal, Shopify, Toast, Zeller and Zettle put before the merchant
+var processorPrefix = regexp.MustCompile(`(?i)^\s*(?:SQ|SQUARE|PAYPAL|PP|SP|TST|ZLR|IZ|SMP|SUMUP|STRIPE|CKO|LS)\s?\*\s*`)
+
+// cardNumber matches a masked card number, such as XX1234 or ****1234, or a
+// terminal or store number
+var cardNumber = regexp.MustCompile(`(?i)^(?:[X*]+\d{2,}|\d{3,})$`)
+
+// locationCodes are state and country codes banks append to descriptions
+var locationCodes = map[string]bool{
+	"NSW": true, "NS": true, "VIC": true, "QLD": true, "QL": true, "SA": true, "WA": true,
+	"TAS": true, "NT": true, "ACT": true,
+	"AU": true, "AUS": true, "NZ": true, "NZL": true, "US": true, "USA": true, "GB": true, "GBR": true,
+}
+
+// cities are dropped when they end a description after a location code
+var cities = map[string]bool{
+	"SYDNEY": true, "MELBOURNE": true, "BRISBANE": true, "PERTH": true, "ADELAIDE": true,
+	"HOBART": true, "DARWIN": true, "CANBERRA": true, "AUCKLAND": true, "WELLINGTON": true,
+	"CHRISTCHURCH": true, "LONDON": true,
+}
+
+// trimLocation drops the trailing state and country codes, then the city:
+// the words after a store number, or a well-known city name. A description
+// without a trailing code is left alone, as is its first word.
+func trimLocation(words []string) []string {
+	end := len(words)
+	for end > 1 && locationCodes[strings.ToUpper(words[end-1])] {
+		end--
+	}
+	if end == len(words) {
+		return words
+	}
+	for i := end - 1; i > 0; i-- {
+		if cardNumber.MatchString(words[i]) {
+			return words[:i+1]
+		}
+	}
+	if end > 1 && cities[strings.ToUpper(words[end-1])] {
+		end--
+	}
+	return words[:end]
+}
+
+// dropCardNumbers drops card, terminal and store numbers and the word CARD
+// before them
+func dropCardNumbers(words []string) []string {
+	kept := make([]string, 0, len(words))
+	for _, w := range words {
+		if !cardNumber.MatchString(w) {
+			kept = append(kept, w)
+			continue
+		}
+		if len(kept) > 0 && strings.EqualFold(kept[len(kept)-1], "CARD") {
+			kept = kept[:len(kept)-1]
+		}
+	}
+	return kept
+}
@@ -0,0 +1,68 @@
+package merchant
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestMerchant(t *testing.T) {
+	n, err := New(config.MerchantConfig{})
+	require.NoError(t, err)
+
+	tests := map[string]string{
+		"SQ *COFFEE SHOP 4521 SYDNEY AU":    "COFFEE SHOP",
+		"PAYPAL *NETFLIX.COM":               "NETFLIX.COM",
+		"WOOLWORTHS 1234 SYDNEY NS AUS":     "WOOLWORTHS",
+		"BUNNINGS MELBOURNE VIC":            "BUNNINGS",
+		"COFFEE SHOP SURRY HILLS NSW":       "COFFEE SHOP SURRY HILLS",
+		"UBER TRIP CARD XX4521":             "UBER TRIP",
+		"7-ELEVEN 2345":                     "7-ELEVEN",
+		"TRANSFER TO SAVINGS":               "TRANSFER TO SAVINGS",
+		"  1234  ":                          "1234",
+		"ZLR*Corner Bakery Adelaide SA AUS": "Corner Bakery",
+	}
+	for description, want := range tests {
+		assert.Equal(t, want, n.Merchant(description), description)
+	}
+}
+
+func TestMerchantRules(t *testing.T) {
+	n, err := New(config.MerchantConfig{
+		Cleaners: []string{"processor"},
+		Rules: []config.MerchantRule{
+			{Pattern: `^AMZN MKTP`, Merchant: "Amazon"},
+			{Pattern: `^DD (\w+)`, Merchant: "$1 direct debit"},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Amazon", n.Merchant("amzn mktp au*2K4HX1"))
+	assert.Equal(t, "TELSTRA direct debit", n.Merchant("DD TELSTRA 0042"))
+	assert.Equal(t, "COFFEE SHOP 4521 SYDNEY AU", n.Merchant("SQ *COFFEE SHOP 4521 SYDNEY AU"), "only the listed cleaners run")
+
+	txns := []transaction.Transaction{
+		{Description: "SQ *CAFE"},
+		{Description: "CAFE", RawDescription: "SQ *CAFE 12345"},
+	}
+	n.Apply(txns)
+	assert.Equal(t, "SQ *CAFE", txns[0].RawDescription)
+	assert.Equal(t, "CAFE", txns[0].Merchant)
+	assert.Equal(t, "SQ *CAFE 12345", txns[1].RawDescription, "the extracted description is kept")
+}
+
+func TestNewInvalid(t *testing.T) {
+	_, err := New(config.MerchantConfig{Cleaners: []string{"postcode"}})
+	assert.ErrorContains(t, err, `unknown merchants.cleaners entry "postcode"`)
+
+	_, err = New(config.MerchantConfig{Rules: []config.MerchantRule{{Pattern: "(", Merchant: "X"}}})
+	assert.ErrorContains(t, err, "invalid pattern")
+
+	n, err := New(config.MerchantConfig{Cleaners: []string{"none"}})
+	require.NoError(t, err)
+	assert.Equal(t, "SQ *CAFE", n.Merchant("SQ  *CAFE"))
+}
@@ -0,0 +1,337 @@
+// Package money rounds and formats monetary amounts for display so that
+// reports and tables agree on currency symbols, separators and rounding.
+package money
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/example/statement-extractor/internal/config"
+)
+
+// Rounding selects how amounts are rounded to the display precision
+type Rounding string
+
+const (
+	HalfEven Rounding = "half-even" // banker's rounding
+	HalfUp   Rounding = "half-up"   // ties away from zero
+	Down     Rounding = "down"      // truncate toward zero
+)
+
+// Format describes how one currency is displayed
+type Format struct {
+	Symbol      string
+	SymbolAfter bool
+	Decimals    int
+	Decimal     string
+	Thousands   string
+	Rounding    Rounding
+	Parens      bool // show negatives as (1.00) rather than -1.00
+}
+
+// builtin holds the default formats for common currencies
+var builtin = map[string]Format{
+	"AUD": {Symbol: "$", Decimals: 2, Decimal: ".", Thousands: ",", Rounding: HalfEven},
+	"NZD": {Symbol: "$", Decimals: 2, Decimal: ".", Thousands: ",", Rounding: HalfEven},
+	"USD": {Symbol: "$", Decimals: 2, Decimal: ".", Thousands: ",", Rounding: HalfEven},
+	"CAD": {Symbol: "$", Decimals: 2, Decimal: ".", Thousands: ",", Rounding: HalfEven},
+	"GBP": {Symbol: "£", Decimals: 2, Decimal: ".", Thousands: ",", Rounding: HalfEven},
+	"EUR": {Symbol: "€", Decimals: 2, Decimal: ".", Thousands: ",", Rounding: HalfEven},
+	"JPY": {Symbol: "¥", Decimals: 0, Decimal: ".", Thousands: ",", Rounding: HalfEven},
+}
+
+// fallback is used for currencies without a built-in or configured format
+var fallback = Format{Decimals: 2, Decimal: ".", Thousands: ",", Rounding: HalfEven}
+
+// Formatter formats amounts in a default currency or any known currency
+type Formatter struct {
+	currency   string
+	formats    map[string]Format
+	configured map[string]bool // currencies with a [money.formats] entry
+	fallback   Format
+}
+
+// New builds a formatter from config, layering configured formats over the
+// built-in ones
+func New(cfg config.MoneyConfig) (*Formatter, error) {
+	f := &Formatter{
+		currency:   strings.ToUpper(cfg.Currency),
+		formats:    make(map[string]Format, len(builtin)),
+		configured: make(map[string]bool, len(cfg.Formats)),
+		fallback:   fallback,
+	}
+	for code, format := range builtin {
+		f.formats[code] = format
+	}
+
+	for code, override := range cfg.Formats {
+		code = strings.ToUpper(code)
+		format, ok := f.formats[code]
+		if !ok {
+			format = fallback
+			format.Symbol = code + " "
+		}
+		if err := apply(&format, override); err != nil {
+			return nil, fmt.Errorf("money format %s: %w", code, err)
+		}
+		f.formats[code] = format
+		f.configured[code] = true
+	}
+	return f, nil
+}
+
+// Localize writes every currency without a [money.formats] entry with a
+// locale's separators, and its symbol after the amount where the locale
+// puts it there
+func (f *Formatter) Localize(decimal, thousands string, symbolAfter bool) {
+	localize := func(format Format) Format {
+		format.Decimal, format.Thousands = decimal, thousands
+		if symbolAfter && !format.SymbolAfter {
+			format.SymbolAfter = true
+			if format.Symbol != "" {
+				format.Symbol = " " + strings.TrimSpace(format.Symbol)
+			}
+		}
+		return format
+	}
+	for code, format := range f.formats {
+		if !f.configured[code] {
+			f.formats[code] = localize(format)
+		}
+	}
+	f.fallback = localize(f.fallback)
+}
+
+// Default returns a formatter using the built-in formats and AUD
+func Default() *Formatter {
+	f, _ := New(config.MoneyConfig{Currency: "AUD"})
+	return f
+}
+
+// Currency returns the default display currency
+func (f *Formatter) Currency() string {
+	return f.currency
+}
+
+// Format formats amount in the default currency, e.g. "-$1,234.50"
+func (f *Formatter) Format(amount float64) string {
+	return f.FormatCurrency(amount, f.currency)
+}
+
+// FormatCurrency formats amount in the given currency
+func (f *Formatter) FormatCurrency(amount float64, currency string) string {
+	return f.format(currency).format(amount)
+}
+
+// ExcelFormat returns a spreadsheet number format code showing amounts in
+// currency the way Format does. Spreadsheets apply their own locale's
+// separators, so only the symbol, decimals, grouping and negative style carry
+// over.
+func (f *Formatter) ExcelFormat(currency string) string {
+	if currency == "" {
+		currency = f.currency
+	}
+	format := f.format(currency)
+
+	number := "0"
+	if format.Thousands != "" {
+		number = "#,##0"
+	}
+	if format.Decimals > 0 {
+		number += "." + strings.Repeat("0", format.Decimals)
+	}
+	if format.Symbol != "" {
+		symbol := `"` + strings.ReplaceAll(format.Symbol, `"`, "") + `"`
+		if format.SymbolAfter {
+			number += symbol
+		} else {
+			number = symbol + number
+		}
+	}
+	if format.Parens {
+		return number + ";(" + number + ")"
+	}
+	return number + ";-" + number
+}
+
+// Round rounds amount to the default currency's display precision
+func (f *Formatter) Round(amount float64) float64 {
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return amount
+	}
+	format := f.format(f.currency)
+	digits, neg := round(amount, format.Decimals, format.Rounding)
+	value, _ := strconv.ParseFloat(digits, 64)
+	if neg {
+		value = -value
+	}
+	return value
+}
+
+func (f *Formatter) format(currency string) Format {
+	currency = strings.ToUpper(currency)
+	if format, ok := f.formats[currency]; ok {
+		return format
+	}
+	format := f.fallback
+	if currency != "" {
+		if format.SymbolAfter {
+			format.Symbol = " " + currency
+		} else {
+			format.Symbol = currency + " "
+		}
+	}
+	return format
+}
+
+func (fm Format) format(amount float64) string {
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return strconv.FormatFloat(amount, 'f', -1, 64)
+	}
+	digits, neg := round(amount, fm.Decimals, fm.Rounding)
+
+	intPart, frac, _ := strings.Cut(digits, ".")
+	number := group(intPart, fm.Thousands)
+	if frac != "" {
+		number += fm.Decimal + frac
+	}
+
+	if fm.SymbolAfter {
+		number += fm.Symbol
+	} else {
+		number = fm.Symbol + number
+	}
+
+	switch {
+	case !neg:
+		return number
+	case fm.Parens:
+		return "(" + number + ")"
+	default:
+		return "-" + number
+	}
+}
+
+// round returns |amount| rounded to decimals as a plain decimal string and
+// whether the rounded value is negative. Rounding works on the shortest
+// decimal representation of the float, so 1.005 rounds half-up to 1.01.
+func round(amount float64, decimals int, mode Rounding) (string, bool) {
+	neg := math.Signbit(amount)
+	s := strconv.FormatFloat(math.Abs(amount), 'f', -1, 64)
+	intPart, frac, _ := strings.Cut(s, ".")
+
+	if len(frac) <= decimals {
+		frac += strings.Repeat("0", decimals-len(frac))
+	} else {
+		kept, rest := frac[:decimals], frac[decimals:]
+		digits := intPart + kept
+		if roundsUp(digits, rest, mode) {
+			digits = increment(digits)
+		}
+		intPart, frac = digits[:len(digits)-decimals], digits[len(digits)-decimals:]
+		if intPart == "" {
+			intPart = "0"
+		}
+	}
+
+	if strings.Trim(intPart+frac, "0") == "" {
+		neg = false
+	}
+	if decimals == 0 {
+		return intPart, neg
+	}
+	return intPart + "." + frac, neg
+}
+
+// roundsUp decides whether the discarded digits round the kept digits up
+func roundsUp(kept, rest string, mode Rounding) bool {
+	switch mode {
+	case Down:
+		return false
+	case HalfUp:
+		return rest[0] >= '5'
+	default:
+		if rest[0] != '5' {
+			return rest[0] > '5'
+		}
+		if strings.Trim(rest[1:], "0") != "" {
+			return true
+		}
+		last := kept[len(kept)-1]
+		return (last-'0')%2 == 1
+	}
+}
+
+// increment adds one to a string of decimal digits
+func increment(digits string) string {
+	b := []byte(digits)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < '9' {
+			b[i]++
+			return string(b)
+		}
+		b[i] = '0'
+	}
+	return "1" + string(b)
+}
+
+// group inserts sep between each group of three integer digits
+func group(digits, sep string) string {
+	if sep == "" || len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	lead := len(digits) % 3
+	if lead > 0 {
+		b.WriteString(digits[:lead])
+	}
+	for i := lead; i < len(digits); i += 3 {
+		if b.Len() > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
+
+func apply(format *Format, o config.MoneyFormat) error {
+	if o.Symbol != "" {
+		format.Symbol = o.Symbol
+	}
+	if o.SymbolAfter != nil {
+		format.SymbolAfter = *o.SymbolAfter
+	}
+	if o.Decimals != nil {
+		if *o.Decimals < 0 || *o.Decimals > 8 {
+			return fmt.Errorf("decimals must be between 0 and 8")
+		}
+		format.Decimals = *o.Decimals
+	}
+	if o.Decimal != "" {
+		format.Decimal = o.Decimal
+	}
+	if o.Thousands != nil {
+		format.Thousands = *o.Thousands
+	}
+
+	switch Rounding(o.Rounding) {
+	case "":
+	case HalfEven, HalfUp, Down:
+		format.Rounding = Rounding(o.Rounding)
+	default:
+		return fmt.Errorf("invalid rounding %q: expected half-even, half-up or down", o.Rounding)
+	}
+
+	switch o.Negative {
+	case "":
+	case "minus":
+		format.Parens = false
+	case "parens":
+		format.Parens = true
+	default:
+		return fmt.Errorf("invalid negative style %q: expected minus or parens", o.Negative)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package money
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestFormat_Defaults(t *testing.T) {
+	f := Default()
+
+	tests := []struct {
+		amount float64
+		want   string
+	}{
+		{0, "$0.00"},
+		{-0.001, "$0.00"},
+		{1234.5, "$1,234.50"},
+		{-1234567.891, "-$1,234,567.89"},
+		{999.995, "$1,000.00"},
+		{0.125, "$0.12"}, // half-even
+		{0.135, "$0.14"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, f.Format(tt.amount), "%v", tt.amount)
+	}
+
+	assert.Equal(t, "¥1,234", f.FormatCurrency(1234.5, "jpy"))
+	assert.Equal(t, "¥1,236", f.FormatCurrency(1235.5, "JPY"))
+	assert.Equal(t, "CHF 12.00", f.FormatCurrency(12, "CHF"))
+}
+
+func TestFormat_Configured(t *testing.T) {
+	f, err := New(config.MoneyConfig{
+		Currency: "eur",
+		Formats: map[string]config.MoneyFormat{
+			"eur": {
+				SymbolAfter: ptr(true),
+				Symbol:      " €",
+				Decimal:     ",",
+				Thousands:   ptr("."),
+				Rounding:    "half-up",
+				Negative:    "parens",
+			},
+			"btc": {Symbol: "₿", Decimals: ptr(8), Thousands: ptr("")},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "EUR", f.Currency())
+	assert.Equal(t, "1.234,57 €", f.Format(1234.565))
+	assert.Equal(t, "(0,13 €)", f.Format(-0.125))
+	assert.Equal(t, "₿1234.00000001", f.FormatCurrency(1234.00000001, "BTC"))
+}
+
+func TestLocalize(t *testing.T) {
+	f, err := New(config.MoneyConfig{
+		Currency: "EUR",
+		Formats:  map[string]config.MoneyFormat{"gbp": {Negative: "parens"}},
+	})
+	require.NoError(t, err)
+	f.Localize(",", ".", true)
+
+	assert.Equal(t, "-1.234,50 €", f.Format(-1234.5))
+	assert.Equal(t, "12,00 XYZ", f.FormatCurrency(12, "XYZ"))
+	assert.Equal(t, "(£1,234.50)", f.FormatCurrency(-1234.5, "GBP"), "configured currencies keep their format")
+}
+
+func TestExcelFormat(t *testing.T) {
+	assert.Equal(t, `"$"#,##0.00;-"$"#,##0.00`, Default().ExcelFormat(""))
+	assert.Equal(t, `"¥"#,##0;-"¥"#,##0`, Default().ExcelFormat("JPY"))
+
+	f, err := New(config.MoneyConfig{
+		Currency: "EUR",
+		Formats: map[string]config.MoneyFormat{
+			"EUR": {SymbolAfter: ptr(true), Symbol: " €", Negative: "parens"},
+			"BTC": {Symbol: "₿", Decimals: ptr(8), Thousands: ptr("")},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `#,##0.00" €";(#,##0.00" €")`, f.ExcelFormat(""))
+	assert.Equal(t, `"₿"0.00000000;-"₿"0.00000000`, f.ExcelFormat("BTC"))
+}
+
+func TestRound(t *testing.T) {
+	f, err := New(config.MoneyConfig{
+		Currency: "AUD",
+		Formats:  map[string]config.MoneyFormat{"aud": {Rounding: "down"}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1.99, f.Round(1.999))
+	assert.Equal(t, -1.99, f.Round(-1.999))
+
+	f, err = New(config.MoneyConfig{
+		Currency: "AUD",
+		Formats:  map[string]config.MoneyFormat{"aud": {Rounding: "half-up"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1.01, f.Round(1.005), "rounds the decimal representation, not the binary float")
+}
+
+func TestNew_Invalid(t *testing.T) {
+	_, err := New(config.MoneyConfig{Formats: map[string]config.MoneyFormat{"aud": {Rounding: "ceiling"}}})
+	assert.ErrorContains(t, err, `money format AUD: invalid rounding "ceiling"`)
+
+	_, err = New(config.MoneyConfig{Formats: map[string]config.MoneyFormat{"aud": {Negative: "red"}}})
+	assert.ErrorContains(t, err, "invalid negative style")
+
+	_, err = New(config.MoneyConfig{Formats: map[string]config.MoneyFormat{"aud": {Decimals: ptr(-1)}}})
+	assert.ErrorContains(t, err, "decimals must be between 0 and 8")
+}
+
+func FuzzFormat(f *testing.F) {
+	for _, seed := range []float64{0, -0.005, 1.005, 1234567.891, -1e21, 5e-324, math.MaxFloat64, math.Inf(1), math.NaN()} {
+		f.Add(seed, uint8(2))
+	}
+	f.Fuzz(func(t *testing.T, amount float64, decimals uint8) {
+		format := Format{Symbol: "$", Decimals: int(decimals % 9), Decimal: ".", Thousands: ",", Rounding: HalfEven}
+		text := format.format(amount)
+		if text == "" {
+			t.Fatalf("empty output for %v", amount)
+		}
+		if math.IsNaN(amount) || math.IsInf(amount, 0) {
+			return
+		}
+		digits, _ := round(amount, format.Decimals, format.Rounding)
+		parsed, err := strconv.ParseFloat(digits, 64)
+		if err != nil {
+			t.Fatalf("round(%v) = %q is not a number: %v", amount, digits, err)
+		}
+		if math.Abs(parsed-math.Abs(amount)) > math.Pow10(-format.Decimals)*math.Max(1, math.Abs(amount)*1e-12) {
+			t.Fatalf("round(%v, %d) = %q is too far from the input", amount, format.Decimals, digits)
+		}
+	})
+}
+
+func TestFormat_NonFinite(t *testing.T) {
+	f := Default()
+	assert.Equal(t, "NaN", f.Format(math.NaN()))
+	assert.Equal(t, "-Inf", f.Format(math.Inf(-1)))
+	assert.True(t, math.IsInf(f.Round(math.Inf(1)), 1))
+}
@@ -0,0 +1,189 @@
+// Package mqtt is a minimal MQTT 3.1.1 client: it connects, publishes at
+// QoS 0 and disconnects, which is all that feeding Home Assistant sensors
+// needs, without depending on a broker library.
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"time"
+)
+
+// Packet types, in the high nibble of the first header byte
+const (
+	packetConnect    = 1
+	packetConnAck    = 2
+	packetPublish    = 3
+	packetDisconnect = 14
+)
+
+// keepAlive is the keep alive interval in seconds sent in CONNECT;
+// connections are short-lived, so no pings are sent
+const keepAlive = 60
+
+// connectTimeout bounds connecting and the broker's CONNACK
+const connectTimeout = 10 * time.Second
+
+// connAckErrors are the CONNACK return codes that refuse a connection
+var connAckErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// Options are the credentials of a connection
+type Options struct {
+	ClientID string
+	Username string
+	Password string
+}
+
+// Client is a connection to a broker
+type Client struct {
+	conn net.Conn
+	w    *bufio.Writer
+}
+
+// Dial connects to the broker at a URL such as mqtt://host:1883, or
+// mqtts://host:8883 for TLS, and waits for it to accept the connection
+func Dial(ctx context.Context, broker string, opts Options) (*Client, error) {
+	u, err := url.Parse(broker)
+	if err != nil {
+		return nil, fmt.Errorf("invalid broker %q: %w", broker, err)
+	}
+	var port string
+	var secure bool
+	switch u.Scheme {
+	case "mqtt", "tcp":
+		port = "1883"
+	case "mqtts", "ssl", "tls":
+		port, secure = "8883", true
+	default:
+		return nil, fmt.Errorf("invalid broker %q: expected an mqtt:// or mqtts:// URL", broker)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+	var conn net.Conn
+	if secure {
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: u.Hostname()}}
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+
+	c := &Client{conn: conn, w: bufio.NewWriter(conn)}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if err := c.connect(opts); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("mqtt broker %s: %w", addr, err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return c, nil
+}
+
+func (c *Client) connect(opts Options) error {
+	var flags byte = 0x02 // clean session
+	if opts.Username != "" {
+		flags |= 0x80
+	}
+	if opts.Password != "" {
+		flags |= 0x40
+	}
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags, byte(keepAlive>>8), byte(keepAlive&0xff))
+	body = appendString(body, opts.ClientID)
+	if opts.Username != "" {
+		body = appendString(body, opts.Username)
+	}
+	if opts.Password != "" {
+		body = appendString(body, opts.Password)
+	}
+	if err := c.send(packetConnect<<4, body); err != nil {
+		return err
+	}
+
+	var ack [4]byte
+	if _, err := io.ReadFull(c.conn, ack[:]); err != nil {
+		return fmt.Errorf("reading CONNACK: %w", err)
+	}
+	if ack[0]>>4 != packetConnAck || ack[1] != 2 {
+		return errors.New("unexpected reply to CONNECT")
+	}
+	if ack[3] != 0 {
+		if reason, ok := connAckErrors[ack[3]]; ok {
+			return fmt.Errorf("connection refused: %s", reason)
+		}
+		return fmt.Errorf("connection refused: code %d", ack[3])
+	}
+	return nil
+}
+
+// Publish sends payload to topic at QoS 0. Retained messages are kept by
+// the broker for subscribers that connect later.
+func (c *Client) Publish(topic string, payload []byte, retain bool) error {
+	var header byte = packetPublish << 4
+	if retain {
+		header |= 0x01
+	}
+	body := append(appendString(nil, topic), payload...)
+	if err := c.send(header, body); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", topic, err)
+	}
+	return nil
+}
+
+// Close disconnects from the broker, which delivers what was published
+func (c *Client) Close() error {
+	err := c.send(packetDisconnect<<4, nil)
+	if closeErr := c.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// send writes one packet: the header byte, the remaining length and body
+func (c *Client) send(header byte, body []byte) error {
+	if len(body) > 268435455 {
+		return errors.New("packet too large")
+	}
+	c.w.WriteByte(header)
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		c.w.WriteByte(b)
+		if n == 0 {
+			break
+		}
+	}
+	c.w.Write(body)
+	return c.w.Flush()
+}
+
+// appendString appends s with its two-byte length prefix
+func appendString(b []byte, s string) []byte {
+	b = append(b, byte(len(s)>>8), byte(len(s)))
+	return append(b, s...)
+}
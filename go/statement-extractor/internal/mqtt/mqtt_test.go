@@ -0,0 +1,97 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// packet is one packet received by the test broker
+type packet struct {
+	header byte
+	body   []byte
+}
+
+// broker accepts one connection, answers its CONNECT with code and sends
+// every packet it receives to the returned channel
+func broker(t *testing.T, code byte) (string, <-chan packet) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	packets := make(chan packet, 16)
+	go func() {
+		defer close(packets)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			header, err := r.ReadByte()
+			if err != nil {
+				return
+			}
+			length, shift := 0, 0
+			for {
+				b, err := r.ReadByte()
+				if err != nil {
+					return
+				}
+				length |= int(b&0x7f) << shift
+				shift += 7
+				if b&0x80 == 0 {
+					break
+				}
+			}
+			body := make([]byte, length)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return
+			}
+			packets <- packet{header, body}
+			if header>>4 == packetConnect {
+				conn.Write([]byte{packetConnAck << 4, 2, 0, code})
+			}
+		}
+	}()
+	return "mqtt://" + ln.Addr().String(), packets
+}
+
+func TestPublish(t *testing.T) {
+	url, packets := broker(t, 0)
+	c, err := Dial(context.Background(), url, Options{ClientID: "se", Username: "ha", Password: "secret"})
+	require.NoError(t, err)
+
+	connect := <-packets
+	assert.Equal(t, byte(packetConnect<<4), connect.header)
+	assert.Equal(t, "\x00\x04MQTT\x04\xc2\x00\x3c\x00\x02se\x00\x02ha\x00\x06secret", string(connect.body))
+
+	require.NoError(t, c.Publish("home/spending", []byte("12.50"), true))
+	large := make([]byte, 300)
+	require.NoError(t, c.Publish("big", large, false))
+	require.NoError(t, c.Close())
+
+	publish := <-packets
+	assert.Equal(t, byte(packetPublish<<4|1), publish.header, "retained")
+	assert.Equal(t, "\x00\x0dhome/spending12.50", string(publish.body))
+	publish = <-packets
+	assert.Equal(t, byte(packetPublish<<4), publish.header)
+	assert.Len(t, publish.body, 2+3+300, "lengths over 127 take two bytes")
+	assert.Equal(t, byte(packetDisconnect<<4), (<-packets).header)
+}
+
+func TestDialRefused(t *testing.T) {
+	url, _ := broker(t, 4)
+	_, err := Dial(context.Background(), url, Options{ClientID: "se"})
+	assert.ErrorContains(t, err, "connection refused: bad user name or password")
+
+	_, err = Dial(context.Background(), "http://localhost", Options{})
+	assert.ErrorContains(t, err, "expected an mqtt:// or mqtts:// URL")
+}
@@ -0,0 +1,103 @@
+// Package pages summarises extracted transactions by statement page, so that
+// when a long statement comes out wrong the page to inspect or re-extract
+// can be found without reading every row.
+package pages
+
+import (
+	"slices"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Status summarises how a page's rows reconcile
+type Status string
+
+const (
+	OK         Status = "ok"          // every running balance follows from the row before
+	Breaks     Status = "breaks"      // some running balances do not follow
+	NoBalances Status = "no balances" // no running balances to check
+	Missing    Status = "missing"     // no rows, although pages either side have some
+)
+
+// Stats describes the rows extracted from one page of a statement
+type Stats struct {
+	File    string
+	Page    int
+	Rows    int
+	Checked int // rows whose running balance was checked against the row before
+	Breaks  int // checked rows whose running balance does not follow
+	Status  Status
+
+	First, Last transaction.Transaction // the page's first and last rows
+
+	balances bool // some row has a running balance
+}
+
+// Summarize groups transactions by statement file and page, in the order
+// the files first appear and by page number within a file. Transactions
+// without a page are skipped. Running balances are checked along each
+// file's rows in their extracted order; a row's balance follows when it is
+// the balance before it plus or minus its amount, which covers both bank
+// accounts and credit cards. Pages missing between two pages with rows are
+// included with the Missing status.
+func Summarize(txns []transaction.Transaction) []Stats {
+	var files []string
+	byFile := make(map[string]map[int]*Stats)
+	last := make(map[string]transaction.Transaction)
+
+	for _, t := range txns {
+		if t.Statement == nil || t.Statement.Page == 0 {
+			continue
+		}
+		file, page := t.Statement.File, t.Statement.Page
+		pages, ok := byFile[file]
+		if !ok {
+			pages = make(map[int]*Stats)
+			byFile[file] = pages
+			files = append(files, file)
+		}
+		s, ok := pages[page]
+		if !ok {
+			s = &Stats{File: file, Page: page, First: t}
+			pages[page] = s
+		}
+		s.Rows++
+		s.Last = t
+		s.balances = s.balances || t.Balance != nil
+
+		if prev, ok := last[file]; ok && prev.Balance != nil && t.Balance != nil {
+			s.Checked++
+			if !transaction.BalanceFollows(*prev.Balance, t.Amount, *t.Balance) {
+				s.Breaks++
+			}
+		}
+		last[file] = t
+	}
+
+	var stats []Stats
+	for _, file := range files {
+		pages := byFile[file]
+		numbers := make([]int, 0, len(pages))
+		for page := range pages {
+			numbers = append(numbers, page)
+		}
+		slices.Sort(numbers)
+		for page := numbers[0]; page <= numbers[len(numbers)-1]; page++ {
+			s, ok := pages[page]
+			if !ok {
+				stats = append(stats, Stats{File: file, Page: page, Status: Missing})
+				continue
+			}
+			switch {
+			case s.Breaks > 0:
+				s.Status = Breaks
+			case s.balances:
+				s.Status = OK
+			default:
+				s.Status = NoBalances
+			}
+			stats = append(stats, *s)
+		}
+	}
+	return stats
+}
@@ -0,0 +1,53 @@
+package pages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// row returns a transaction on page of file, without a balance when balance
+// is zero
+func row(file string, page int, amount, balance float64) transaction.Transaction {
+	t := transaction.Transaction{
+		Date:      time.Date(2024, 1, page, 0, 0, 0, 0, time.UTC),
+		Amount:    transaction.FromFloat(amount),
+		Statement: &transaction.StatementRef{File: file, Page: page},
+	}
+	if balance != 0 {
+		t.Balance = transaction.FromFloat(balance).Ptr()
+	}
+	return t
+}
+
+func TestSummarize(t *testing.T) {
+	stats := Summarize([]transaction.Transaction{
+		row("jan.pdf", 1, -10, 90),
+		row("jan.pdf", 1, -5, 85),
+		row("jan.pdf", 2, 100, 185),
+		row("jan.pdf", 2, -20, 150), // should be 165
+		row("jan.pdf", 4, -15, 135),
+		row("card.pdf", 1, 30, 30),
+		row("card.pdf", 1, 20, 50), // a card balance grows with purchases
+		row("csv", 0, -1, 0),
+		row("card.pdf", 2, -4, 0),
+	})
+
+	require.Len(t, stats, 6)
+	got := make([]string, len(stats))
+	for i, s := range stats {
+		got[i] = s.File + ":" + string(s.Status)
+	}
+	assert.Equal(t, []string{"jan.pdf:ok", "jan.pdf:breaks", "jan.pdf:missing", "jan.pdf:ok", "card.pdf:ok", "card.pdf:no balances"}, got)
+
+	assert.Equal(t, 2, stats[0].Rows)
+	assert.Equal(t, 1, stats[0].Checked, "the first row has nothing to follow")
+	assert.Equal(t, 1, stats[1].Breaks)
+	assert.Equal(t, 3, stats[2].Page)
+	assert.Equal(t, transaction.Amount(-1500), stats[3].First.Amount)
+	assert.Empty(t, Summarize(nil))
+}
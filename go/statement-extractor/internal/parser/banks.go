@@ -0,0 +1,256 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/example/statement-extractor/internal/importer"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// builtin are the content parsers, keyed by the [parsers] name they serve
+var builtin = map[string]Parser{
+	"anz": newANZ(),
+	"cba": newCBA(),
+}
+
+// Builtin returns the content parser registered under name, if any
+func Builtin(name string) (Parser, bool) {
+	p, ok := builtin[strings.ToLower(name)]
+	return p, ok
+}
+
+// pages tracks the page of each line in text extracted from a PDF, where
+// pages end with a form feed
+type pages struct {
+	paged bool
+	page  int
+}
+
+func newPages(text string) *pages {
+	return &pages{paged: strings.Contains(text, "\f"), page: 1}
+}
+
+// next advances past the page breaks in line, which pdftotext puts before
+// the first line of each new page
+func (p *pages) next(line string) {
+	p.page += strings.Count(line, "\f")
+}
+
+// ref returns the current page as a statement reference, or nil for text
+// without page breaks
+func (p *pages) ref() *transaction.StatementRef {
+	if !p.paged {
+		return nil
+	}
+	return &transaction.StatementRef{Page: p.page}
+}
+
+const monthPattern = `Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec`
+
+// cba parses Commonwealth Bank statement text. Each transaction starts with
+// a "02 Jan DESCRIPTION" line, optionally followed by card and value date
+// lines, and ends with an amount line: "45.20 ( $1,954.80 CR" for a debit or
+// "2,500.00 $ $4,454.80 CR" for a credit. Text extracted in layout mode has
+// the amounts on the date line, separated from the description by a gap.
+// Credit card statements list each card's transactions under a "Transactions
+// for card xxxx 1234" heading.
+type cba struct {
+	detect  *regexp.Regexp
+	section *regexp.Regexp
+	period  *regexp.Regexp
+	start   *regexp.Regexp
+	inline  *regexp.Regexp
+	debit   *regexp.Regexp
+	credit  *regexp.Regexp
+	balance *regexp.Regexp
+}
+
+func newCBA() *cba {
+	return &cba{
+		detect:  regexp.MustCompile(`(?i)Commonwealth Bank|CommBank`),
+		section: regexp.MustCompile(`(?i)^(?:transactions for card|card number)\b.*?(\d{4})$`),
+		period:  regexp.MustCompile(`Statement Period\s+(\d{1,2}\s+\w+\s+\d{4})\s+-\s+(\d{1,2}\s+\w+\s+\d{4})`),
+		start:   regexp.MustCompile(`^(\d{1,2})\s+(` + monthPattern + `)\s+(.+)$`),
+		inline:  regexp.MustCompile(`^(.*?)\s{2,}([\d,]+\.\d{2}\s+(?:\(|\$\s+\$).*)$`),
+		debit:   regexp.MustCompile(`^([\d,]+\.\d{2})\s+\(`),
+		credit:  regexp.MustCompile(`^([\d,]+\.\d{2})\s+\$\s+\$`),
+		balance: regexp.MustCompile(`\$([\d,]+\.\d{2})\s*(CR|DR)?$`),
+	}
+}
+
+func (p *cba) Name() string { return "CBA" }
+
+func (p *cba) Detect(text string) bool { return p.detect.MatchString(text) }
+
+func (p *cba) Parse(text string) ([]transaction.Transaction, error) {
+	period := p.period.FindStringSubmatch(text)
+	if period == nil {
+		return nil, errors.New("could not find statement period")
+	}
+	startDate, err := time.Parse("2 Jan 2006", period[1])
+	if err != nil {
+		return nil, fmt.Errorf("parsing start date: %w", err)
+	}
+	endDate, err := time.Parse("2 Jan 2006", period[2])
+	if err != nil {
+		return nil, fmt.Errorf("parsing end date: %w", err)
+	}
+
+	var txns []transaction.Transaction
+	var current *transaction.Transaction
+	var card string // of the current card section
+	pages := newPages(text)
+	for _, line := range strings.Split(text, "\n") {
+		pages.next(line)
+		line = strings.TrimSpace(line)
+		if m := p.section.FindStringSubmatch(line); m != nil {
+			card, current = m[1], nil
+			continue
+		}
+		if m := p.start.FindStringSubmatch(line); m != nil {
+			date, err := resolveDate(m[1]+" "+m[2], startDate, endDate)
+			if err != nil {
+				return nil, err
+			}
+			txns = append(txns, transaction.Transaction{Date: date, Description: m[3], Source: p.Name(), Card: card, Statement: pages.ref()})
+			current = &txns[len(txns)-1]
+			if inline := p.inline.FindStringSubmatch(m[3]); inline != nil {
+				current.Description = inline[1]
+				if err := p.amounts(inline[2], current); err != nil {
+					return nil, fmt.Errorf("%s: %w", current.Description, err)
+				}
+				current = nil
+			}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "Card "), strings.HasPrefix(line, "Value Date:"):
+			current.Description += " " + line
+			if suffix := transaction.CardSuffix(line); suffix != "" {
+				current.Card = suffix
+			}
+		case p.debit.MatchString(line), p.credit.MatchString(line):
+			if err := p.amounts(line, current); err != nil {
+				return nil, fmt.Errorf("%s: %w", current.Description, err)
+			}
+			current = nil
+		}
+	}
+	return txns, nil
+}
+
+func (p *cba) amounts(line string, t *transaction.Transaction) error {
+	if m := p.debit.FindStringSubmatch(line); m != nil {
+		amount, err := importer.ParseAmount(m[1])
+		if err != nil {
+			return err
+		}
+		t.Amount = -amount
+	} else if m := p.credit.FindStringSubmatch(line); m != nil {
+		amount, err := importer.ParseAmount(m[1])
+		if err != nil {
+			return err
+		}
+		t.Amount = amount
+	}
+
+	if m := p.balance.FindStringSubmatch(line); m != nil {
+		balance, err := importer.ParseAmount(m[1] + m[2])
+		if err != nil {
+			return err
+		}
+		t.Balance = &balance
+	}
+	return nil
+}
+
+// resolveDate places a "2 Jan" date in the statement period, taking the end
+// year for months before the period starts
+func resolveDate(dayMonth string, startDate, endDate time.Time) (time.Time, error) {
+	date, err := time.Parse("2 Jan", dayMonth)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", dayMonth)
+	}
+	year := startDate.Year()
+	if date.Month() < startDate.Month() {
+		year = endDate.Year()
+	}
+	return time.Date(year, date.Month(), date.Day(), 0, 0, 0, 0, time.UTC), nil
+}
+
+// anz parses ANZ statement text, one transaction per line:
+// "03/01/2024 02/01/2024 1234 COLES 0456 SYDNEY $54.10 $1,945.90CR", with a
+// CR suffix on the amount for credits. The number after the dates is the
+// last digits of the card used.
+type anz struct {
+	detect *regexp.Regexp
+	line   *regexp.Regexp
+}
+
+func newANZ() *anz {
+	return &anz{
+		detect: regexp.MustCompile(`\bANZ\b|Australia and New Zealand Banking`),
+		line:   regexp.MustCompile(`^(\d{2}/\d{2}/\d{4})\s+(\d{2}/\d{2}/\d{4})\s+(\d+)\s+(.+)\s+\$([0-9,]+\.\d{2})(CR)?\s+\$([0-9,]+\.\d{2})(CR|DR)?$`),
+	}
+}
+
+func (p *anz) Name() string { return "ANZ" }
+
+func (p *anz) Detect(text string) bool { return p.detect.MatchString(text) }
+
+func (p *anz) Parse(text string) ([]transaction.Transaction, error) {
+	var txns []transaction.Transaction
+	pages := newPages(text)
+	for i, line := range strings.Split(text, "\n") {
+		pages.next(line)
+		m := p.line.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+
+		processed, err := time.Parse("02/01/2006", m[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid date %q", i+1, m[1])
+		}
+		transacted, err := time.Parse("02/01/2006", m[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid date %q", i+1, m[2])
+		}
+
+		description := strings.TrimSpace(m[4])
+		if !transacted.Equal(processed) {
+			description += fmt.Sprintf(" (Transaction Date: %s)", transacted.Format("2006-01-02"))
+		}
+
+		amount, err := transaction.ParseAmount(strings.ReplaceAll(m[5], ",", ""))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid amount %q", i+1, m[5])
+		}
+		if m[6] != "CR" {
+			amount = -amount
+		}
+		balance, err := importer.ParseAmount(m[7] + m[8])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		txns = append(txns, transaction.Transaction{
+			Date:        processed,
+			Description: description,
+			Amount:      amount,
+			Balance:     &balance,
+			Source:      p.Name(),
+			Card:        m[3],
+			Statement:   pages.ref(),
+		})
+	}
+	return txns, nil
+}
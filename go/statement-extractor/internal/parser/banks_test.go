@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+const cbaStatement = `Commonwealth Bank of Australia
+Account Number 06 2000 12345678
+Statement Period 1 Dec 2023 - 31 Jan 2024
+01 Dec OPENING BALANCE
+28 Dec PURCHASE WOOLWORTHS 1234
+Card xx1234
+Value Date: 27/12/2023
+45.20 ( $1,954.80 CR
+15 Jan SALARY ACME PTY LTD
+2,500.00 $ $4,454.80 CR
+`
+
+const anzStatement = `ANZ ACCESS ADVANTAGE STATEMENT
+ACCOUNT NUMBER: 1234-56789
+03/01/2024 02/01/2024 1234 COLES 0456 SYDNEY $54.10 $1,945.90CR
+05/01/2024 05/01/2024 1235 PAYMENT FROM J SMITH $200.00CR $2,145.90CR
+`
+
+func TestCBAParse(t *testing.T) {
+	p, ok := Builtin("CBA")
+	require.True(t, ok)
+	assert.True(t, p.Detect(cbaStatement))
+	assert.False(t, p.Detect(anzStatement))
+
+	txns, err := p.Parse(cbaStatement)
+	require.NoError(t, err)
+	require.Len(t, txns, 3)
+
+	assert.Equal(t, "OPENING BALANCE", txns[0].Description)
+	assert.Zero(t, txns[0].Amount)
+
+	assert.Equal(t, time.Date(2023, 12, 28, 0, 0, 0, 0, time.UTC), txns[1].Date)
+	assert.Equal(t, "PURCHASE WOOLWORTHS 1234 Card xx1234 Value Date: 27/12/2023", txns[1].Description)
+	assert.Equal(t, transaction.Amount(-4520), txns[1].Amount)
+	assert.Equal(t, transaction.Amount(195480).Ptr(), txns[1].Balance)
+	assert.Equal(t, "CBA", txns[1].Source)
+	assert.Equal(t, "1234", txns[1].Card)
+
+	assert.Equal(t, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), txns[2].Date, "months before the period start fall in the end year")
+	assert.Equal(t, transaction.Amount(250000), txns[2].Amount)
+
+	layout := "Statement Period 1 Jan 2024 - 31 Jan 2024\n  03 Jan   TRANSFER TO SAVINGS      100.00 (    $1,000.00 CR\n"
+	txns, err = p.Parse(layout)
+	require.NoError(t, err)
+	require.Len(t, txns, 1)
+	assert.Equal(t, "TRANSFER TO SAVINGS", txns[0].Description)
+	assert.Equal(t, transaction.Amount(-10000), txns[0].Amount)
+	assert.Equal(t, transaction.Amount(100000).Ptr(), txns[0].Balance)
+
+	sections := "Statement Period 1 Jan 2024 - 31 Jan 2024\nTransactions for card xxxx xxxx xxxx 4521\n" +
+		"03 Jan   QANTAS      100.00 (    $1,000.00 CR\nTransactions for card xxxx xxxx xxxx 9912\n" +
+		"04 Jan   COLES      20.00 (    $1,020.00 CR\n05 Jan   BP      30.00 (    $1,050.00 CR\n"
+	txns, err = p.Parse(sections)
+	require.NoError(t, err)
+	require.Len(t, txns, 3)
+	assert.Equal(t, []string{"4521", "9912", "9912"}, []string{txns[0].Card, txns[1].Card, txns[2].Card}, "rows take the card of their section")
+
+	_, err = p.Parse("Commonwealth Bank\n02 Jan SOMETHING\n")
+	assert.EqualError(t, err, "could not find statement period")
+}
+
+func TestANZParse(t *testing.T) {
+	p, ok := Builtin("anz")
+	require.True(t, ok)
+	assert.True(t, p.Detect(anzStatement))
+	assert.False(t, p.Detect(cbaStatement))
+
+	txns, err := p.Parse(anzStatement)
+	require.NoError(t, err)
+	require.Len(t, txns, 2)
+
+	assert.Equal(t, time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), txns[0].Date)
+	assert.Equal(t, "COLES 0456 SYDNEY (Transaction Date: 2024-01-02)", txns[0].Description)
+	assert.Equal(t, transaction.Amount(-5410), txns[0].Amount)
+	assert.Equal(t, transaction.Amount(194590).Ptr(), txns[0].Balance)
+	assert.Equal(t, "ANZ", txns[0].Source)
+	assert.Equal(t, "1234", txns[0].Card)
+
+	assert.Equal(t, "PAYMENT FROM J SMITH", txns[1].Description)
+	assert.Equal(t, transaction.Amount(20000), txns[1].Amount)
+	assert.Nil(t, txns[1].Statement, "no page numbers without page breaks")
+}
+
+func TestParsePages(t *testing.T) {
+	p, _ := Builtin("anz")
+	lines := strings.Split(strings.TrimSpace(anzStatement), "\n")
+	paged := strings.Join(lines[:3], "\n") + "\n\f" + lines[3] + "\n\f"
+
+	txns, err := p.Parse(paged)
+	require.NoError(t, err)
+	require.Len(t, txns, 2)
+	assert.Equal(t, &transaction.StatementRef{Page: 1}, txns[0].Statement)
+	assert.Equal(t, &transaction.StatementRef{Page: 2}, txns[1].Statement)
+}
+
+func FuzzContentParsers(f *testing.F) {
+	f.Add(cbaStatement)
+	f.Add(anzStatement)
+	f.Add("Commonwealth Bank\nTransactions for card xxxx 1234\n02 Jan COLES    45.20 ( $1,954.80 CR\n\f31 Feb BAD\n")
+	f.Add("ANZ\n99/99/2024 01/01/2024 1 X $1.00 $2.00CR\n")
+	f.Fuzz(func(t *testing.T, text string) {
+		for name, p := range builtin {
+			p.Detect(text)
+			txns, err := p.Parse(text)
+			if err != nil {
+				continue
+			}
+			for _, txn := range txns {
+				if txn.Source != p.Name() {
+					t.Fatalf("%s: transaction %q has source %q", name, txn.Description, txn.Source)
+				}
+				if txn.Statement != nil && txn.Statement.Page < 1 {
+					t.Fatalf("%s: transaction %q is on page %d", name, txn.Description, txn.Statement.Page)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/example/statement-extractor/internal/config"
+)
+
+// ErrBudgetExceeded is returned instead of making a PDF service call that
+// could take the run past max_tokens_per_run or max_cost_per_run
+var ErrBudgetExceeded = errors.New("run budget exceeded")
+
+// Usage is what PDF service calls consumed, as reported by the service
+type Usage struct {
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	Cost         float64 `json:"cost"` // in the service's billing currency
+}
+
+// Tokens returns the input and output tokens together
+func (u Usage) Tokens() int { return u.InputTokens + u.OutputTokens }
+
+func (u Usage) String() string {
+	return fmt.Sprintf("%d tokens, cost %.4f", u.Tokens(), u.Cost)
+}
+
+// Budget caps the tokens and cost of the PDF service calls in one run. As
+// usage is only known after a call, a call is refused when one as large as
+// the largest so far would go over a limit.
+type Budget struct {
+	MaxTokens int     // 0 is unlimited
+	MaxCost   float64 // 0 is unlimited
+
+	// Confirm, when set, is asked whether to go on instead of refusing a
+	// call; a yes lifts the limits for the rest of the run
+	Confirm func(spent Usage) bool
+
+	mu            sync.Mutex
+	spent         Usage
+	largestTokens int
+	largestCost   float64
+	lifted        bool
+}
+
+// NewBudget returns the run budget configured in cfg, or nil when there
+// are no limits
+func NewBudget(cfg *config.Config) *Budget {
+	if cfg.MaxTokensPerRun <= 0 && cfg.MaxCostPerRun <= 0 {
+		return nil
+	}
+	return &Budget{MaxTokens: cfg.MaxTokensPerRun, MaxCost: cfg.MaxCostPerRun}
+}
+
+// Reserve checks the budget before a call. A nil Budget allows every call.
+func (b *Budget) Reserve() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.lifted || !b.over() {
+		return nil
+	}
+	if b.Confirm != nil && b.Confirm(b.spent) {
+		b.lifted = true
+		return nil
+	}
+	var limits []string
+	if b.MaxTokens > 0 {
+		limits = append(limits, fmt.Sprintf("max_tokens_per_run = %d", b.MaxTokens))
+	}
+	if b.MaxCost > 0 {
+		limits = append(limits, fmt.Sprintf("max_cost_per_run = %g", b.MaxCost))
+	}
+	return fmt.Errorf("%w: spent %s; the next call could go over %s", ErrBudgetExceeded, b.spent, strings.Join(limits, " or "))
+}
+
+// over reports whether a call as large as the largest so far could exceed
+// a limit
+func (b *Budget) over() bool {
+	if b.MaxTokens > 0 && b.spent.Tokens()+b.largestTokens > b.MaxTokens {
+		return true
+	}
+	return b.MaxCost > 0 && b.spent.Cost+b.largestCost > b.MaxCost
+}
+
+// Add records the usage of a call
+func (b *Budget) Add(u Usage) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.spent.InputTokens += u.InputTokens
+	b.spent.OutputTokens += u.OutputTokens
+	b.spent.Cost += u.Cost
+	b.largestTokens = max(b.largestTokens, u.Tokens())
+	b.largestCost = max(b.largestCost, u.Cost)
+}
+
+// Spent returns the usage recorded so far
+func (b *Budget) Spent() Usage {
+	if b == nil {
+		return Usage{}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.spent
+}
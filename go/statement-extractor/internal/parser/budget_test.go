@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+)
+
+func TestNewBudget(t *testing.T) {
+	assert.Nil(t, NewBudget(&config.Config{}), "no limits")
+
+	var b *Budget
+	assert.NoError(t, b.Reserve(), "a nil budget allows every call")
+	b.Add(Usage{InputTokens: 10})
+	assert.Equal(t, Usage{}, b.Spent())
+}
+
+func TestBudgetReserve(t *testing.T) {
+	b := NewBudget(&config.Config{MaxTokensPerRun: 2500})
+	require.NoError(t, b.Reserve())
+	b.Add(Usage{InputTokens: 800, OutputTokens: 200})
+	require.NoError(t, b.Reserve(), "1000 spent plus a 1000 call fits")
+	b.Add(Usage{InputTokens: 500, OutputTokens: 100})
+
+	err := b.Reserve()
+	assert.ErrorIs(t, err, ErrBudgetExceeded, "1600 spent plus a 1000 call does not")
+	assert.ErrorContains(t, err, "spent 1600 tokens")
+	assert.ErrorContains(t, err, "max_tokens_per_run = 2500")
+
+	asked := 0
+	b.Confirm = func(Usage) bool { asked++; return true }
+	assert.NoError(t, b.Reserve())
+	assert.NoError(t, b.Reserve())
+	assert.Equal(t, 1, asked, "a yes lifts the limit for the rest of the run")
+}
+
+func TestBudgetCost(t *testing.T) {
+	b := NewBudget(&config.Config{MaxCostPerRun: 1})
+	b.Add(Usage{Cost: 0.6})
+	err := b.Reserve()
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+	assert.ErrorContains(t, err, "max_cost_per_run = 1")
+
+	b.Confirm = func(Usage) bool { return false }
+	assert.ErrorIs(t, b.Reserve(), ErrBudgetExceeded)
+}
+
+func TestServiceChargesBudget(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(`{"text": "statement text", "usage": {"input_tokens": 1500, "output_tokens": 500}}`))
+	}))
+	defer srv.Close()
+
+	s, err := NewService("test", config.ServiceConfig{BaseURL: srv.URL, CostPerMillionTokens: 3}, srv.Client())
+	require.NoError(t, err)
+	s.Budget = &Budget{MaxTokens: 3000}
+
+	_, err = s.Text(context.Background(), []byte("%PDF-1.4"))
+	require.NoError(t, err)
+	spent := s.Budget.Spent()
+	assert.Equal(t, 2000, spent.Tokens())
+	assert.InDelta(t, 0.006, spent.Cost, 1e-9, "cost from the configured price")
+
+	_, err = s.Text(context.Background(), []byte("%PDF-1.4"))
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+	assert.Equal(t, 1, calls, "the refused call is not made")
+}
@@ -0,0 +1,30 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrTruncated is returned when a PDF service's response was cut short and
+// could not be continued
+var ErrTruncated = errors.New("response was truncated")
+
+const (
+	// maxOverlap bounds the text a continuation may repeat from the end of
+	// the output before it
+	maxOverlap = 256
+	// minOverlap is the shortest repeat that is dropped; shorter ones are
+	// more likely to be a coincidence than a repeat
+	minOverlap = 16
+)
+
+// stitch appends a continuation to the output so far, dropping any text the
+// model repeated from the end of it
+func stitch(prev, next string) string {
+	for k := min(len(prev), len(next), maxOverlap); k >= minOverlap; k-- {
+		if strings.HasSuffix(prev, next[:k]) {
+			return prev + next[k:]
+		}
+	}
+	return prev + next
+}
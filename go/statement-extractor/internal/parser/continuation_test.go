@@ -0,0 +1,33 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStitch(t *testing.T) {
+	prev := `[{"date": "2024-01-02", "description": "COLES", "amount": -12.5}, {"date": "2024-01-0`
+	assert.Equal(t, prev+`3"}]`, stitch(prev, `3"}]`))
+	assert.Equal(t, prev+`3"}]`, stitch(prev, `"description": "COLES", "amount": -12.5}, {"date": "2024-01-03"}]`), "a repeated tail is dropped")
+	assert.Equal(t, `{"a": 1}{"a": 1}`, stitch(`{"a": 1}`, `{"a": 1}`), "short repeats are kept")
+	assert.Equal(t, "next", stitch("", "next"))
+}
+
+func TestDecodeRaw(t *testing.T) {
+	result, err := decodeRaw(OutputTransactions, "```json\n[{\"date\": \"2024-01-02\", \"description\": \"COLES\", \"amount\": -12.5}]\n```")
+	require.NoError(t, err)
+	assert.Equal(t, []serviceRow{{Date: "2024-01-02", Description: "COLES", Amount: -1250}}, result.Transactions)
+
+	result, err = decodeRaw(OutputTransactions, `{"transactions": [{"date": "2024-01-03", "amount": 4}]}`)
+	require.NoError(t, err)
+	assert.Equal(t, []serviceRow{{Date: "2024-01-03", Amount: 400}}, result.Transactions)
+
+	result, err = decodeRaw(OutputText, "page 1 ")
+	require.NoError(t, err)
+	assert.Equal(t, "page 1 ", result.Text)
+
+	_, err = decodeRaw(OutputTransactions, `[{"date": "2024-01-03"`)
+	assert.Error(t, err)
+}
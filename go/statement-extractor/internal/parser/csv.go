@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/importer"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// CSVMapping returns the importer mapping for a parser's [csv] columns
+func CSVMapping(cfg config.CSVConfig) (importer.Mapping, error) {
+	switch {
+	case cfg.Date == "":
+		return importer.Mapping{}, errors.New("csv.date is not set")
+	case cfg.Description == "":
+		return importer.Mapping{}, errors.New("csv.description is not set")
+	case cfg.Amount == "" && cfg.Debit == "" && cfg.Credit == "":
+		return importer.Mapping{}, errors.New("csv needs an amount column or debit and credit columns")
+	}
+
+	column := func(name string) []string {
+		if name == "" {
+			return nil
+		}
+		return []string{name}
+	}
+	m := importer.Mapping{
+		Date:        column(cfg.Date),
+		Description: column(cfg.Description),
+		Amount:      column(cfg.Amount),
+		Debit:       column(cfg.Debit),
+		Credit:      column(cfg.Credit),
+		Balance:     column(cfg.Balance),
+		Headerless:  cfg.NoHeader,
+	}
+	if cfg.DateFormat != "" {
+		m.DateLayouts = []string{cfg.DateFormat}
+	}
+	return m, nil
+}
+
+// readCSV parses a CSV export with the parser's column mapping
+func readCSV(name string, cfg config.CSVConfig, document []byte) ([]transaction.Transaction, error) {
+	m, err := CSVMapping(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("parser %q: %w", name, err)
+	}
+	txns, err := m.Read(bytes.NewReader(document), strings.ToUpper(name))
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s CSV: %w", strings.ToUpper(name), err)
+	}
+	return txns, nil
+}
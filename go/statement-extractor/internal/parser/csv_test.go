@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestCSVMapping(t *testing.T) {
+	_, err := CSVMapping(config.CSVConfig{Description: "Narrative", Amount: "Amount"})
+	assert.EqualError(t, err, "csv.date is not set")
+
+	_, err = CSVMapping(config.CSVConfig{Date: "Date", Description: "Narrative"})
+	assert.EqualError(t, err, "csv needs an amount column or debit and credit columns")
+
+	m, err := CSVMapping(config.CSVConfig{Date: "Date", Description: "Narrative", Debit: "Debit Amount", Credit: "Credit Amount", DateFormat: "02/01/2006"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Debit Amount"}, m.Debit)
+	assert.Nil(t, m.Amount)
+	assert.Equal(t, []string{"02/01/2006"}, m.DateLayouts)
+}
+
+func TestExtractCSV(t *testing.T) {
+	cfg := &config.Config{Parsers: map[string]config.ParserConfig{
+		"westpac": {Method: "csv", Currency: "AUD", CSV: config.CSVConfig{
+			Date:        "Date",
+			Description: "Narrative",
+			Debit:       "Debit Amount",
+			Credit:      "Credit Amount",
+			Balance:     "Balance",
+			DateFormat:  "02/01/2006",
+		}},
+	}}
+	input := "Bank Account,Date,Narrative,Debit Amount,Credit Amount,Balance\n" +
+		"032000123456,03/01/2024,WOOLWORTHS 1234,54.10,,1945.90\n" +
+		"032000123456,05/01/2024,SALARY ACME,,2500.00,4445.90\n"
+
+	txns, err := New(cfg, nil).Extract(context.Background(), "westpac", []byte(input))
+	require.NoError(t, err)
+	require.Len(t, txns, 2)
+	assert.Equal(t, "WOOLWORTHS 1234", txns[0].Description)
+	assert.Equal(t, transaction.Amount(-5410), txns[0].Amount)
+	assert.Equal(t, transaction.Amount(194590).Ptr(), txns[0].Balance)
+	assert.Equal(t, "WESTPAC", txns[0].Source)
+	assert.Equal(t, "AUD", txns[0].Currency)
+	assert.Equal(t, transaction.Amount(250000), txns[1].Amount)
+
+	_, err = New(cfg, nil).Extract(context.Background(), "westpac", []byte("Date,Narrative,Debit Amount\n2024-01-03,X,1\n"))
+	assert.EqualError(t, err, `parsing WESTPAC CSV: line 2: invalid date "2024-01-03"`)
+}
@@ -0,0 +1,32 @@
+package parser
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the parser tests")
+
+// golden compares txns with testdata/<name>/transactions.golden.json, or
+// rewrites that file when the tests are run with -update
+func golden(t *testing.T, name string, txns []transaction.Transaction) {
+	t.Helper()
+	got, err := json.MarshalIndent(txns, "", "  ")
+	require.NoError(t, err)
+
+	path := filepath.Join("testdata", name, "transactions.golden.json")
+	if *update {
+		require.NoError(t, os.WriteFile(path, append(got, '\n'), 0o644))
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(want), string(got))
+}
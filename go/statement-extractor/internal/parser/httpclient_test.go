@@ -0,0 +1,134 @@
+package parser
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+)
+
+// writePEM writes a PEM block to a file in dir and returns its path
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+	return path
+}
+
+// clientCertificate writes a self-signed client certificate and key to dir
+func clientCertificate(t *testing.T, dir string) (certPath, keyPath string, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "statement-extractor"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err = x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return writePEM(t, dir, "client.pem", "CERTIFICATE", der), writePEM(t, dir, "client.key", "EC PRIVATE KEY", keyDER), cert
+}
+
+func TestHTTPClientCABundleAndClientCert(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath, clientCert := clientCertificate(t, dir)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Len(t, r.TLS.PeerCertificates, 1)
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	defer srv.Close()
+	caPath := writePEM(t, dir, "ca.pem", "CERTIFICATE", srv.Certificate().Raw)
+
+	client, err := NewHTTPClient(config.HTTPConfig{CABundle: caPath, ClientCert: certPath, ClientKey: keyPath})
+	require.NoError(t, err)
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Without the CA bundle the server's certificate is not trusted
+	plain, err := NewHTTPClient(config.HTTPConfig{ClientCert: certPath, ClientKey: keyPath})
+	require.NoError(t, err)
+	_, err = plain.Get(srv.URL)
+	assert.ErrorContains(t, err, "certificate")
+}
+
+func TestHTTPClientProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	client, err := NewHTTPClient(config.HTTPConfig{Proxy: proxy.URL})
+	require.NoError(t, err)
+	resp, err := client.Get("http://pdf-service.internal/extract")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "http://pdf-service.internal/extract", proxied)
+}
+
+func TestHTTPClientTimeout(t *testing.T) {
+	client, err := NewHTTPClient(config.HTTPConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, APITimeoutSeconds*time.Second, client.Timeout)
+
+	client, err = NewHTTPClient(config.HTTPConfig{Timeout: 2 * time.Minute, ConnectTimeout: 5 * time.Second})
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, client.Timeout)
+	assert.Equal(t, 5*time.Second, client.Transport.(*http.Transport).TLSHandshakeTimeout)
+}
+
+func TestHTTPClientInvalid(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0o600))
+
+	tests := []struct {
+		name string
+		cfg  config.HTTPConfig
+		want string
+	}{
+		{"proxy", config.HTTPConfig{Proxy: "proxy:3128"}, "invalid proxy"},
+		{"missing bundle", config.HTTPConfig{CABundle: filepath.Join(dir, "missing.pem")}, "failed to read CA bundle"},
+		{"empty bundle", config.HTTPConfig{CABundle: notPEM}, "has no PEM certificates"},
+		{"cert without key", config.HTTPConfig{ClientCert: notPEM}, "must be set together"},
+		{"bad key pair", config.HTTPConfig{ClientCert: notPEM, ClientKey: notPEM}, "failed to load client certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHTTPClient(tt.cfg)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
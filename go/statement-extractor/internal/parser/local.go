@@ -0,0 +1,37 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// pdftotext is the poppler-utils command used for local extraction
+var pdftotext = "pdftotext"
+
+// PDFText extracts the text of a PDF document without a PDF service.
+// Layout mode keeps each statement row on one line.
+func PDFText(ctx context.Context, document []byte) (string, error) {
+	bin, err := exec.LookPath(pdftotext)
+	if err != nil {
+		return "", errors.New(`method "local" needs the pdftotext command from poppler-utils on PATH`)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, bin, "-layout", "-enc", "UTF-8", "-", "-")
+	cmd.Stdin = bytes.NewReader(document)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("pdftotext failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	text := stdout.String()
+	if strings.TrimSpace(text) == "" {
+		return "", errors.New("the PDF has no text layer; scanned statements need a PDF service")
+	}
+	return text, nil
+}
@@ -0,0 +1,44 @@
+package parser
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePdftotext installs a pdftotext on PATH that runs script
+func fakePdftotext(t *testing.T, script string) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pdftotext"), []byte("#!/bin/sh\n"+script+"\n"), 0o755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestPDFText(t *testing.T) {
+	fakePdftotext(t, `[ "$1 $2 $3 $4 $5" = "-layout -enc UTF-8 - -" ] || exit 2; cat`)
+
+	text, err := PDFText(context.Background(), []byte("%PDF-1.7 statement"))
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-1.7 statement", text)
+}
+
+func TestPDFTextErrors(t *testing.T) {
+	ctx := context.Background()
+
+	path := os.Getenv("PATH")
+	t.Setenv("PATH", t.TempDir())
+	_, err := PDFText(ctx, []byte("%PDF-1.7"))
+	assert.EqualError(t, err, `method "local" needs the pdftotext command from poppler-utils on PATH`)
+
+	t.Setenv("PATH", path)
+	fakePdftotext(t, `echo "Syntax Error: broken" >&2; exit 1`)
+	_, err = PDFText(ctx, []byte("%PDF-1.7"))
+	assert.EqualError(t, err, "pdftotext failed: exit status 1: Syntax Error: broken")
+
+	fakePdftotext(t, `printf '\f'`)
+	_, err = PDFText(ctx, []byte("%PDF-1.7"))
+	assert.EqualError(t, err, "the PDF has no text layer; scanned statements need a PDF service")
+}
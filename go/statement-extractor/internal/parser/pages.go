@@ -0,0 +1,43 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// PageRange is a span of statement pages, inclusive and 1-based
+type PageRange struct {
+	First int `json:"first"`
+	Last  int `json:"last"`
+}
+
+// ParsePageRange parses "7" or "7-9"
+func ParsePageRange(s string) (PageRange, error) {
+	first, last, ranged := strings.Cut(strings.TrimSpace(s), "-")
+	if !ranged {
+		last = first
+	}
+	var r PageRange
+	var err1, err2 error
+	r.First, err1 = strconv.Atoi(strings.TrimSpace(first))
+	r.Last, err2 = strconv.Atoi(strings.TrimSpace(last))
+	if err1 != nil || err2 != nil || r.First < 1 || r.Last < r.First {
+		return PageRange{}, fmt.Errorf("invalid page range %q (expected e.g. 7 or 7-9)", s)
+	}
+	return r, nil
+}
+
+// Contains reports whether the transaction came from a page in the range
+func (r PageRange) Contains(t transaction.Transaction) bool {
+	return t.Statement != nil && t.Statement.Page >= r.First && t.Statement.Page <= r.Last
+}
+
+func (r PageRange) String() string {
+	if r.First == r.Last {
+		return fmt.Sprintf("page %d", r.First)
+	}
+	return fmt.Sprintf("pages %d-%d", r.First, r.Last)
+}
@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestParsePageRange(t *testing.T) {
+	r, err := ParsePageRange("7-9")
+	require.NoError(t, err)
+	assert.Equal(t, PageRange{First: 7, Last: 9}, r)
+	assert.Equal(t, "pages 7-9", r.String())
+
+	r, err = ParsePageRange("3")
+	require.NoError(t, err)
+	assert.Equal(t, PageRange{First: 3, Last: 3}, r)
+	assert.Equal(t, "page 3", r.String())
+
+	for _, bad := range []string{"", "0", "9-7", "a-b", "1-"} {
+		_, err := ParsePageRange(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestExtractPages(t *testing.T) {
+	var requested *PageRange
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req serviceRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		requested = req.Pages
+		if req.Output == OutputText {
+			// Text from page 2 onwards
+			lines := strings.Split(anzStatement, "\n")
+			_, _ = w.Write([]byte(`{"text": ` + quote(strings.Join(lines[:2], "\n")+"\n"+lines[3]+"\n\f") + `}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"transactions": [{"date": "2024-02-01", "description": "RENT", "amount": -500, "page": 2}, {"date": "2024-02-09", "description": "GYM", "amount": -20, "page": 4}]}`))
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL)
+	cfg.Parsers["anz"] = cfg.Parsers["cba"]
+	e := New(cfg, srv.Client())
+	e.Pages = &PageRange{First: 2, Last: 3}
+	ctx := context.Background()
+
+	txns, err := e.Extract(ctx, "westpac", []byte("%PDF-1.4"))
+	require.NoError(t, err)
+	assert.Equal(t, &PageRange{First: 2, Last: 3}, requested)
+	require.Len(t, txns, 1, "rows outside the range are dropped")
+	assert.Equal(t, "RENT", txns[0].Description)
+
+	txns, err = e.Extract(ctx, "anz", []byte("%PDF-1.4"))
+	require.NoError(t, err)
+	require.Len(t, txns, 1)
+	assert.Equal(t, "PAYMENT FROM J SMITH", txns[0].Description)
+	assert.Equal(t, 2, txns[0].Statement.Page, "pages count from the first requested")
+
+	e.Pages = &PageRange{First: 2, Last: 2}
+	paged := strings.Replace(anzStatement, "05/01/2024 05/01", "\f05/01/2024 05/01", 1)
+	cfg.Parsers["anz"] = testConfig("").Parsers["anz"]
+	txns, err = e.Extract(ctx, "anz", []byte(paged))
+	require.NoError(t, err)
+	require.Len(t, txns, 1)
+	assert.Equal(t, transaction.Amount(20000), txns[0].Amount)
+}
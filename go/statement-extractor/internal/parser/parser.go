@@ -0,0 +1,395 @@
+// Package parser turns bank statements into transactions, either by parsing
+// the statement text with a built-in bank parser (method "content", or
+// "local" to extract PDF text offline), by sending the PDF to a configured
+// extraction service (method "pdf"), by mapping the columns of a CSV
+// export (method "csv") or by reading a Quicken QIF export (method "qif").
+package parser
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/example/statement-extractor/internal/cache"
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/health"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Parser parses the text of one bank's statements
+type Parser interface {
+	Name() string
+	Detect(text string) bool // reports whether text looks like this bank's statement
+	Parse(text string) ([]transaction.Transaction, error)
+}
+
+// Extractor runs the configured parsers. Extract may be called from several
+// goroutines at once.
+type Extractor struct {
+	cfg    *config.Config
+	client *http.Client // for PDF services; nil uses a default client
+
+	// Health, when set, records PDF service calls and skips services whose
+	// circuit breaker is open in favour of the parser's fallbacks
+	Health *health.Tracker
+
+	// Budget, when set, caps the tokens and cost of PDF service calls
+	Budget *Budget
+
+	// Pages, when set, limits extraction to part of each statement. PDF
+	// services are asked for those pages only; other methods parse the
+	// whole statement and keep the rows from those pages.
+	Pages *PageRange
+
+	// Cache, when set, keeps PDF service responses so the same request is
+	// not paid for twice
+	Cache cache.Cache
+
+	// Ledger, when set, refuses to send a statement to a PDF service again
+	// once it has been sent, with ErrProcessed, unless Reprocess is set
+	Ledger    Ledger
+	Reprocess bool
+
+	// Log, when set, records each statement extracted, for stats
+	Log ExtractionLog
+
+	mu       sync.Mutex
+	limiters map[string]*Limiter // by provider, shared by concurrent extractions
+}
+
+// ExtractionLog records extractions for the stats command: the parser, the PDF
+// service that answered, if any, whether its response came from the cache,
+// and how long the extraction took
+type ExtractionLog interface {
+	RecordExtraction(ctx context.Context, parser, provider string, cached bool, elapsed time.Duration) error
+}
+
+// extraction is what the ExtractionLog records of how a statement was extracted
+type extraction struct {
+	provider string
+	cached   bool
+}
+
+// New returns an Extractor for the [parsers] and [pdf_services] in cfg
+func New(cfg *config.Config, client *http.Client) *Extractor {
+	return &Extractor{cfg: cfg, client: client}
+}
+
+// Names returns the configured parser names
+func (e *Extractor) Names() []string {
+	names := make([]string, 0, len(e.cfg.Parsers))
+	for name := range e.cfg.Parsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Detect picks the configured parser for a statement: a built-in parser
+// that recognises a text statement, then a parser named in the file name,
+// then the only configured parser
+func (e *Extractor) Detect(path string, document []byte) (string, error) {
+	names := e.Names()
+	if len(names) == 0 {
+		return "", errors.New("no parsers configured")
+	}
+
+	// PDF content streams are compressed, so only text is searched
+	if !IsPDF(document) {
+		text := string(document)
+		for _, name := range names {
+			if p, ok := Builtin(name); ok && p.Detect(text) {
+				return name, nil
+			}
+		}
+	}
+
+	base := strings.ToLower(filepath.Base(path))
+	words := strings.FieldsFunc(base, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || '0' <= r && r <= '9')
+	})
+	for _, word := range words {
+		for _, name := range names {
+			if word == strings.ToLower(name) {
+				return name, nil
+			}
+		}
+	}
+
+	if len(names) == 1 {
+		return names[0], nil
+	}
+	return "", fmt.Errorf("cannot detect the bank for %s; use --bank (configured: %s)", filepath.Base(path), strings.Join(names, ", "))
+}
+
+// Extract parses document with the named parser and stamps the parser's
+// currency and account defaults on the result. Content parsers accept a text
+// document as-is; a PDF has its text extracted by the parser's provider
+// first.
+func (e *Extractor) Extract(ctx context.Context, name string, document []byte) ([]transaction.Transaction, error) {
+	txns, _, err := e.ExtractStatement(ctx, name, document)
+	return txns, err
+}
+
+// ExtractStatement is Extract that also returns what the statement says about
+// itself. Only content parsers see the statement text, so the other methods
+// return an empty StatementInfo.
+func (e *Extractor) ExtractStatement(ctx context.Context, name string, document []byte) ([]transaction.Transaction, transaction.StatementInfo, error) {
+	pc, ok := e.lookup(name)
+	if !ok {
+		return nil, transaction.StatementInfo{}, fmt.Errorf("unknown parser %q (configured: %s)", name, strings.Join(e.Names(), ", "))
+	}
+
+	start := time.Now()
+	var run extraction
+	txns, info, err := e.extract(ctx, name, pc, document, &run)
+	if err != nil {
+		return nil, transaction.StatementInfo{}, err
+	}
+	if e.Log != nil {
+		// Stats are only an aid: a record that cannot be written is passed over
+		_ = e.Log.RecordExtraction(ctx, strings.ToLower(name), run.provider, run.cached, time.Since(start))
+	}
+	if e.Pages != nil {
+		txns = slices.DeleteFunc(txns, func(t transaction.Transaction) bool { return !e.Pages.Contains(t) })
+	}
+	for i := range txns {
+		stamp(&txns[i], pc)
+	}
+	return txns, info, nil
+}
+
+// stamp fills the parser's defaults into fields t leaves empty, and the card
+// from a card number in the description
+func stamp(t *transaction.Transaction, pc config.ParserConfig) {
+	if t.Card == "" {
+		t.Card = transaction.CardSuffix(t.Description)
+	}
+	if t.Currency == "" {
+		t.Currency = pc.Currency
+	}
+	if t.Account == "" {
+		t.Account = pc.Account
+	}
+	if t.AccountType == "" {
+		t.AccountType = pc.AccountType
+	}
+}
+
+func (e *Extractor) extract(ctx context.Context, name string, pc config.ParserConfig, document []byte, run *extraction) ([]transaction.Transaction, transaction.StatementInfo, error) {
+	switch pc.Method {
+	case "pdf":
+		var txns []transaction.Transaction
+		var info transaction.StatementInfo
+		err := e.withService(name, pc, func(service *Service) (err error) {
+			txns, err = service.Transactions(ctx, document)
+			info = service.Info
+			run.record(service)
+			return err
+		})
+		if err != nil {
+			return nil, transaction.StatementInfo{}, err
+		}
+		for i := range txns {
+			txns[i].Source = strings.ToUpper(name)
+		}
+		return txns, info, nil
+
+	case "csv":
+		txns, err := readCSV(name, pc.CSV, document)
+		return txns, transaction.StatementInfo{}, err
+
+	case "qif":
+		txns, err := readQIF(name, pc.DateFormat, document)
+		return txns, transaction.StatementInfo{}, err
+
+	case "content", "local", "":
+		p, ok := Builtin(name)
+		if !ok {
+			return nil, transaction.StatementInfo{}, fmt.Errorf("parser %q: no built-in content parser for this bank; use method = \"pdf\"", name)
+		}
+		text, err := e.text(ctx, name, pc, document, run)
+		if err != nil {
+			return nil, transaction.StatementInfo{}, err
+		}
+		txns, err := p.Parse(text)
+		if err != nil {
+			return nil, transaction.StatementInfo{}, fmt.Errorf("parsing %s statement: %w", p.Name(), err)
+		}
+		if e.Pages != nil && IsPDF(document) && pc.Method != "local" {
+			// The provider's text starts at the first requested page
+			for _, t := range txns {
+				if t.Statement != nil {
+					t.Statement.Page += e.Pages.First - 1
+				}
+			}
+		}
+		return txns, ParseStatementInfo(text), nil
+
+	default:
+		return nil, transaction.StatementInfo{}, fmt.Errorf("parser %q: unknown method %q (expected content, local, pdf, csv or qif)", name, pc.Method)
+	}
+}
+
+// text returns the statement text for a content parser: text documents
+// as-is, and PDFs converted locally or by the parser's provider
+func (e *Extractor) text(ctx context.Context, name string, pc config.ParserConfig, document []byte, run *extraction) (string, error) {
+	switch {
+	case !IsPDF(document):
+		return string(document), nil
+	case pc.Method == "local":
+		return PDFText(ctx, document)
+	case pc.Provider == "":
+		return "", fmt.Errorf("parser %q: method \"content\" needs a provider to extract text from PDFs; use method = \"local\" to extract it offline", name)
+	}
+	var text string
+	err := e.withService(name, pc, func(service *Service) (err error) {
+		text, err = service.Text(ctx, document)
+		run.record(service)
+		return err
+	})
+	return text, err
+}
+
+// record notes the service as the one that extracted the statement; the
+// last one called is, as fallbacks are only tried after a failure
+func (run *extraction) record(service *Service) {
+	run.provider, run.cached = service.Name, service.CacheHits > 0
+}
+
+// lookup finds a parser's config, matching the name case-insensitively
+func (e *Extractor) lookup(name string) (config.ParserConfig, bool) {
+	for key, pc := range e.cfg.Parsers {
+		if strings.EqualFold(key, name) {
+			return pc, true
+		}
+	}
+	return config.ParserConfig{}, false
+}
+
+// withService calls fn with the parser's provider, then with each fallback
+// in turn until one succeeds. Providers whose circuit breaker is open are
+// skipped.
+func (e *Extractor) withService(name string, pc config.ParserConfig, fn func(*Service) error) error {
+	if pc.Provider == "" {
+		return fmt.Errorf("parser %q has no provider", name)
+	}
+
+	var errs []error
+	for _, provider := range append([]string{pc.Provider}, pc.Fallback...) {
+		sc, ok := e.cfg.PDFServices[provider]
+		if !ok {
+			return fmt.Errorf("parser %q: unknown pdf service %q", name, provider)
+		}
+		if e.Health != nil && !e.Health.Allow(provider) {
+			_, until := e.Health.State(provider)
+			errs = append(errs, fmt.Errorf("pdf service %q skipped after repeated failures until %s", provider, until.Local().Format("15:04:05")))
+			continue
+		}
+		service, err := NewService(provider, sc, e.client)
+		if err != nil {
+			return err
+		}
+		service.Budget = e.Budget
+		service.Pages = e.Pages
+		if service.Prompt, err = prompt(name, pc); err != nil {
+			return err
+		}
+		service.Limiter = e.limiter(provider, sc)
+		service.Cache = e.Cache
+		service.Ledger, service.Reprocess = e.Ledger, e.Reprocess
+
+		start := time.Now()
+		err = fn(service)
+		if errors.Is(err, ErrBudgetExceeded) || errors.Is(err, ErrProcessed) {
+			// A fallback would only spend more
+			return err
+		}
+		// A cached response says nothing about the service's health
+		if e.Health != nil && service.CacheHits == 0 && !errors.Is(err, context.Canceled) {
+			call := health.Call{Latency: time.Since(start), Model: service.Model, Repaired: service.Repairs > 0}
+			if err != nil {
+				call.Error = err.Error()
+			}
+			e.Health.RecordCall(provider, call)
+		}
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// prompt renders the parser's prompt template, or returns "" when it has
+// none
+func prompt(name string, pc config.ParserConfig) (string, error) {
+	if pc.Prompt == "" {
+		return "", nil
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(pc.Prompt)
+	if err != nil {
+		return "", fmt.Errorf("parser %q: prompt: %w", name, err)
+	}
+	var buf strings.Builder
+	err = tmpl.Execute(&buf, map[string]string{
+		"Name":        name,
+		"Currency":    pc.Currency,
+		"Account":     pc.Account,
+		"AccountType": pc.AccountType,
+	})
+	if err != nil {
+		return "", fmt.Errorf("parser %q: prompt: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// limiter returns the rate limiter shared by the calls to provider
+func (e *Extractor) limiter(provider string, sc config.ServiceConfig) *Limiter {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	l, ok := e.limiters[provider]
+	if !ok {
+		if e.limiters == nil {
+			e.limiters = make(map[string]*Limiter)
+		}
+		l = NewLimiter(sc.RequestsPerMinute)
+		e.limiters[provider] = l
+	}
+	return l
+}
+
+// Link records the statement file on each transaction, keeping any page the
+// parser found
+func Link(txns []transaction.Transaction, file string, document []byte) {
+	digest := Digest(document)
+	for i := range txns {
+		ref := transaction.StatementRef{File: file, SHA256: digest}
+		if txns[i].Statement != nil {
+			ref.Page = txns[i].Statement.Page
+		}
+		txns[i].Statement = &ref
+	}
+}
+
+// Digest returns the SHA-256 of a statement file, as recorded by Link
+func Digest(document []byte) string {
+	sum := sha256.Sum256(document)
+	return hex.EncodeToString(sum[:])
+}
+
+// IsPDF reports whether document is a PDF file
+func IsPDF(document []byte) bool {
+	return bytes.HasPrefix(document, []byte("%PDF-"))
+}
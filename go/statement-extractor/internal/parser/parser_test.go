@@ -0,0 +1,218 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/cache"
+	"github.com/example/statement-extractor/internal/clock"
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/health"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func testConfig(url string) *config.Config {
+	return &config.Config{
+		Parsers: map[string]config.ParserConfig{
+			"anz":     {Method: "content", Currency: "AUD", Account: "Everyday", AccountType: "transaction"},
+			"cba":     {Method: "content", Provider: "svc"},
+			"westpac": {Method: "pdf", Provider: "svc"},
+		},
+		PDFServices: map[string]config.ServiceConfig{
+			"svc": {BaseURL: url},
+		},
+	}
+}
+
+func TestDetect(t *testing.T) {
+	e := New(testConfig(""), nil)
+
+	name, err := e.Detect("statement.txt", []byte(anzStatement))
+	require.NoError(t, err)
+	assert.Equal(t, "anz", name, "recognised from the text")
+
+	name, err = e.Detect("/tmp/Westpac-2024-01.pdf", []byte("%PDF-1.7 ANZ"))
+	require.NoError(t, err)
+	assert.Equal(t, "westpac", name, "named in the file, PDF bytes are not searched")
+
+	_, err = e.Detect("statement.pdf", []byte("%PDF-1.7"))
+	assert.EqualError(t, err, "cannot detect the bank for statement.pdf; use --bank (configured: anz, cba, westpac)")
+
+	single := New(&config.Config{Parsers: map[string]config.ParserConfig{"cba": {}}}, nil)
+	name, err = single.Detect("statement.pdf", []byte("%PDF-1.7"))
+	require.NoError(t, err)
+	assert.Equal(t, "cba", name)
+}
+
+func TestExtract(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"text": ` + quote(cbaStatement) + `, "transactions": [{"date": "2024-02-01", "description": "RENT", "amount": -500}]}`))
+	}))
+	defer srv.Close()
+	e := New(testConfig(srv.URL), srv.Client())
+	ctx := context.Background()
+
+	txns, err := e.Extract(ctx, "anz", []byte(anzStatement))
+	require.NoError(t, err)
+	require.Len(t, txns, 2, "text statements are parsed directly")
+	assert.Equal(t, "AUD", txns[0].Currency)
+	assert.Equal(t, "Everyday", txns[0].Account)
+	assert.Equal(t, "transaction", txns[1].AccountType)
+
+	txns, err = e.Extract(ctx, "CBA", []byte("%PDF-1.7"))
+	require.NoError(t, err)
+	assert.Len(t, txns, 3, "PDF text comes from the provider")
+
+	txns, err = e.Extract(ctx, "westpac", []byte("%PDF-1.7"))
+	require.NoError(t, err)
+	require.Len(t, txns, 1)
+	assert.Equal(t, "RENT", txns[0].Description)
+	assert.Equal(t, "WESTPAC", txns[0].Source)
+	assert.Empty(t, txns[0].Currency, "no defaults configured")
+
+	_, err = e.Extract(ctx, "anz", []byte("%PDF-1.7"))
+	assert.EqualError(t, err, `parser "anz": method "content" needs a provider to extract text from PDFs; use method = "local" to extract it offline`)
+
+	_, err = e.Extract(ctx, "nab", nil)
+	assert.EqualError(t, err, `unknown parser "nab" (configured: anz, cba, westpac)`)
+}
+
+func TestExtractFallback(t *testing.T) {
+	var brokenCalls int
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		brokenCalls++
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer broken.Close()
+	working := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"transactions": [{"date": "2024-02-01", "description": "RENT", "amount": -500}]}`))
+	}))
+	defer working.Close()
+
+	cfg := &config.Config{
+		Parsers: map[string]config.ParserConfig{"westpac": {Method: "pdf", Provider: "broken", Fallback: []string{"working"}}},
+		PDFServices: map[string]config.ServiceConfig{
+			"broken":  {BaseURL: broken.URL},
+			"working": {BaseURL: working.URL},
+		},
+	}
+	tracker, err := health.Load(filepath.Join(t.TempDir(), health.FileName), clock.System)
+	require.NoError(t, err)
+	e := New(cfg, nil)
+	e.Health = tracker
+
+	for range health.BreakerFailures + 2 {
+		txns, err := e.Extract(context.Background(), "westpac", []byte("%PDF-1.7"))
+		require.NoError(t, err)
+		assert.Len(t, txns, 1)
+	}
+	assert.Equal(t, health.BreakerFailures, brokenCalls, "the open circuit skips the broken provider")
+	state, _ := tracker.State("broken")
+	assert.Equal(t, health.Open, state)
+
+	cfg.Parsers["westpac"] = config.ParserConfig{Method: "pdf", Provider: "broken"}
+	_, err = e.Extract(context.Background(), "westpac", []byte("%PDF-1.7"))
+	assert.ErrorContains(t, err, `pdf service "broken" skipped after repeated failures until `)
+}
+
+func TestExtractPrompt(t *testing.T) {
+	var prompts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req serviceRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		prompts = append(prompts, req.Prompt)
+		_, _ = w.Write([]byte(`{"transactions": [{"date": "2024-02-01", "description": "RENT", "amount": -500}]}`))
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{
+		Parsers: map[string]config.ParserConfig{"westpac": {
+			Method: "pdf", Provider: "svc", Currency: "AUD",
+			Prompt: `Amounts on this {{.Name}} statement are in {{.Currency}}.`,
+		}},
+		PDFServices: map[string]config.ServiceConfig{"svc": {BaseURL: srv.URL}},
+	}
+	e := New(cfg, srv.Client())
+	_, err := e.Extract(context.Background(), "westpac", []byte("%PDF-1.7"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Amounts on this westpac statement are in AUD."}, prompts)
+
+	cfg.Parsers["westpac"] = config.ParserConfig{Method: "pdf", Provider: "svc", Prompt: "{{.Bank}}"}
+	_, err = e.Extract(context.Background(), "westpac", []byte("%PDF-1.7"))
+	assert.ErrorContains(t, err, `parser "westpac": prompt: `)
+	assert.Len(t, prompts, 1, "a prompt that does not render is not sent")
+}
+
+// extractionLog records extractions in memory
+type extractionLog []string
+
+func (l *extractionLog) RecordExtraction(_ context.Context, parser, provider string, cached bool, elapsed time.Duration) error {
+	*l = append(*l, fmt.Sprintf("%s %s %t", parser, provider, cached))
+	return nil
+}
+
+func TestExtractLog(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"text": ` + quote(cbaStatement) + `, "transactions": [{"date": "2024-02-01", "description": "RENT", "amount": -500}]}`))
+	}))
+	defer srv.Close()
+	e := New(testConfig(srv.URL), srv.Client())
+	e.Cache = cache.NewDir(t.TempDir())
+	var log extractionLog
+	e.Log = &log
+	ctx := context.Background()
+
+	for _, name := range []string{"westpac", "westpac", "CBA", "anz"} {
+		document := []byte("%PDF-1.7")
+		if name == "anz" {
+			document = []byte(anzStatement)
+		}
+		_, err := e.Extract(ctx, name, document)
+		require.NoError(t, err)
+	}
+	_, err := e.Extract(ctx, "nab", nil)
+	require.Error(t, err)
+	assert.Equal(t, extractionLog{"westpac svc false", "westpac svc true", "cba svc false", "anz  false"}, log,
+		"parsers are recorded by their config key, and failures not at all")
+}
+
+func TestExtractLocal(t *testing.T) {
+	statement := filepath.Join(t.TempDir(), "statement.txt")
+	require.NoError(t, os.WriteFile(statement, []byte(anzStatement), 0o644))
+	fakePdftotext(t, "cat "+statement)
+
+	e := New(&config.Config{Parsers: map[string]config.ParserConfig{"anz": {Method: "local"}}}, nil)
+	txns, err := e.Extract(context.Background(), "anz", []byte("%PDF-1.7"))
+	require.NoError(t, err)
+	assert.Len(t, txns, 2)
+}
+
+func TestLink(t *testing.T) {
+	txns := []transaction.Transaction{{Statement: &transaction.StatementRef{Page: 3}}, {}}
+	Link(txns, "2024.zip/jan.pdf", []byte("test"))
+
+	digest := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	assert.Equal(t, &transaction.StatementRef{File: "2024.zip/jan.pdf", SHA256: digest, Page: 3}, txns[0].Statement)
+	assert.Equal(t, &transaction.StatementRef{File: "2024.zip/jan.pdf", SHA256: digest}, txns[1].Statement)
+}
+
+func TestStamp(t *testing.T) {
+	txn := transaction.Transaction{Currency: "USD"}
+	stamp(&txn, config.ParserConfig{Currency: "AUD", Account: "Travel card", AccountType: "credit"})
+	assert.Equal(t, transaction.Transaction{Currency: "USD", Account: "Travel card", AccountType: "credit"}, txn, "existing values are kept")
+}
+
+func quote(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
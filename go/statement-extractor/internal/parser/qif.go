@@ -0,0 +1,23 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/example/statement-extractor/internal/importer"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// readQIF parses a QIF export, with dates in dateFormat when it is set
+func readQIF(name, dateFormat string, document []byte) ([]transaction.Transaction, error) {
+	var layouts []string
+	if dateFormat != "" {
+		layouts = []string{dateFormat}
+	}
+	txns, err := importer.ReadQIF(bytes.NewReader(document), strings.ToUpper(name), layouts)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s QIF: %w", strings.ToUpper(name), err)
+	}
+	return txns, nil
+}
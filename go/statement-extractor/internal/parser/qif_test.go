@@ -0,0 +1,29 @@
+package parser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+)
+
+func TestExtractQIF(t *testing.T) {
+	cfg := &config.Config{Parsers: map[string]config.ParserConfig{
+		"legacy": {Method: "qif", DateFormat: "01/02/2006", Account: "Cheque"},
+	}}
+	input := "!Type:Bank\nD01/15/2024\nT-12.50\nPNETFLIX\nLEntertainment\n^\n"
+
+	txns, err := New(cfg, nil).Extract(context.Background(), "legacy", []byte(input))
+	require.NoError(t, err)
+	require.Len(t, txns, 1)
+	assert.Equal(t, "NETFLIX", txns[0].Description)
+	assert.Equal(t, "Entertainment", txns[0].Category)
+	assert.Equal(t, "LEGACY", txns[0].Source)
+	assert.Equal(t, "Cheque", txns[0].Account)
+
+	_, err = New(cfg, nil).Extract(context.Background(), "legacy", []byte("!Type:Bank\nD15/01/2024\nT-1\n^\n"))
+	assert.EqualError(t, err, `parsing LEGACY QIF: line 2: invalid date "15/01/2024"`)
+}
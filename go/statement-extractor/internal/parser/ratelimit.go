@@ -0,0 +1,53 @@
+package parser
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter spaces out the calls to one PDF service so that statements
+// extracted concurrently stay under its rate limit together
+type Limiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time // the earliest the next call may start
+}
+
+// NewLimiter returns a limiter allowing perMinute calls a minute, or nil,
+// which allows every call at once, when perMinute is not positive
+func NewLimiter(perMinute float64) *Limiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &Limiter{interval: time.Duration(float64(time.Minute) / perMinute)}
+}
+
+// Wait blocks until the next call may start, or ctx is done
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(start)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,35 @@
+package parser
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter(t *testing.T) {
+	assert.Nil(t, NewLimiter(0))
+	assert.NoError(t, NewLimiter(0).Wait(context.Background()), "a nil limiter allows every call")
+
+	l := NewLimiter(60 * 50) // one call every 20ms
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, l.Wait(context.Background()))
+		}()
+	}
+	wg.Wait()
+	assert.GreaterOrEqual(t, time.Since(start), 60*time.Millisecond, "the fourth call waits for three intervals")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l = NewLimiter(1)
+	require.NoError(t, l.Wait(ctx), "the first call does not wait")
+	assert.ErrorIs(t, l.Wait(ctx), context.Canceled)
+}
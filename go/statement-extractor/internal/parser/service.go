@@ -0,0 +1,450 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/statement-extractor/internal/cache"
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+const (
+	// APITimeoutSeconds bounds a single request to a PDF service
+	APITimeoutSeconds = 30
+	// APIMaxRetries is the number of retries after server errors
+	APIMaxRetries = 3
+	// APIRetryBackoffMS is the initial backoff, doubled after each retry
+	APIRetryBackoffMS = 1000
+	// APIMaxContinuations is the number of times a truncated response is
+	// continued before giving up
+	APIMaxContinuations = 4
+)
+
+// ErrProcessed is returned instead of sending a statement to a PDF service
+// when the processing ledger shows it was sent before
+var ErrProcessed = errors.New("statement was already sent to a PDF service")
+
+// Ledger records the statements sent to PDF services by their SHA-256
+// digest, so the same file is not paid for twice by accident
+type Ledger interface {
+	// Processed reports the service the statement was last sent to, and when
+	Processed(ctx context.Context, digest string) (provider string, at time.Time, ok bool, err error)
+
+	// RecordProcessed enters the statement as sent to provider now
+	RecordProcessed(ctx context.Context, digest, provider string) error
+}
+
+// Output selects what a PDF service returns for a document
+type Output string
+
+const (
+	OutputTransactions Output = "transactions" // the statement's rows, for method "pdf"
+	OutputText         Output = "text"         // the statement's text, for method "content"
+)
+
+// Service is a client for a configured PDF extraction service. Documents are
+// posted as JSON to {base_url}/extract:
+//
+//	{"model": "...", "output": "transactions", "document": "<base64 PDF>"}
+//
+// and the service answers with the extracted text or rows, and optionally
+// the usage it bills for:
+//
+//	{"text": "...", "transactions": [{"date": "2024-01-02", "description": "...", "amount": -12.5, "balance": 100, "page": 1}],
+//	 "usage": {"input_tokens": 1200, "output_tokens": 300, "cost": 0.004}}
+//
+// A transactions response may also describe the statement itself:
+//
+//	"statement": {"account_number": "06 2000 12345678", "period_start": "2024-01-01", "period_end": "2024-01-31",
+//	              "opening_balance": 1000, "closing_balance": 1250.5}
+//
+// With Prompt set the request also carries it in "prompt", as the
+// instructions for the service's model in place of its own.
+//
+// With Pages set the request also carries {"pages": {"first": 7, "last": 9}}:
+// the service extracts only those pages, numbering rows by their page in the
+// whole document, and returns text that starts at the first of them.
+//
+// Services configured with structured_output are also sent the JSON schema
+// of the transactions in "schema", for a gateway to constrain the model with.
+// Other services may answer with the model's unparsed output in "raw"
+// instead of "transactions"; it is repaired and parsed here.
+//
+// A service whose model ran out of output tokens answers with
+// "finish_reason": "length" and the model's output so far in "raw". The
+// request is then repeated with that output in "continue", and the pieces
+// are stitched together and decoded once the service finishes.
+type Service struct {
+	Name    string
+	BaseURL string
+	Model   string
+	APIKey  string // sent as a bearer token when set
+
+	Client  *http.Client
+	Backoff time.Duration // initial retry backoff
+
+	// Budget, when set, is checked before and charged after each call
+	Budget               *Budget
+	CostPerMillionTokens float64 // prices usage reported without a cost
+
+	// StructuredOutput sends the transactions schema for the service to
+	// enforce
+	StructuredOutput bool
+
+	// Repairs counts the responses whose raw output had to be repaired
+	Repairs int
+
+	// Info is what the last transactions response said about the statement
+	Info transaction.StatementInfo
+
+	// Pages, when set, limits extraction to part of the document
+	Pages *PageRange
+
+	// Prompt, when set, replaces the service's instructions to its model
+	Prompt string
+
+	// Limiter, when set, spaces out requests, retries included
+	Limiter *Limiter
+
+	// Cache, when set, answers requests made before without calling the
+	// service. It is only an optimisation: a cache that cannot be read or
+	// written is passed over.
+	Cache cache.Cache
+
+	// CacheHits counts the responses the cache answered
+	CacheHits int
+
+	// Ledger, when set, is checked before the document is sent, refusing a
+	// document sent before with ErrProcessed unless Reprocess is set, and
+	// records it once the service has answered
+	Ledger    Ledger
+	Reprocess bool
+}
+
+// NewService returns a client for the named service in cfg, reading its API
+// key from the configured environment variable. A nil client is built from
+// the service's [http] settings.
+func NewService(name string, cfg config.ServiceConfig, client *http.Client) (*Service, error) {
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("pdf service %q has no base_url", name)
+	}
+	s := &Service{
+		Name:    name,
+		BaseURL: strings.TrimSuffix(cfg.BaseURL, "/"),
+		Model:   cfg.Model,
+		Client:  client,
+		Backoff: APIRetryBackoffMS * time.Millisecond,
+
+		CostPerMillionTokens: cfg.CostPerMillionTokens,
+		StructuredOutput:     cfg.StructuredOutput,
+	}
+	if cfg.APIKeyEnv != "" {
+		if s.APIKey = os.Getenv(cfg.APIKeyEnv); s.APIKey == "" {
+			return nil, fmt.Errorf("pdf service %q: %s is not set", name, cfg.APIKeyEnv)
+		}
+	}
+	if s.Client == nil {
+		client, err := NewHTTPClient(cfg.HTTP)
+		if err != nil {
+			return nil, fmt.Errorf("pdf service %q: %w", name, err)
+		}
+		s.Client = client
+	}
+	return s, nil
+}
+
+type serviceRequest struct {
+	Model    string          `json:"model,omitempty"`
+	Output   Output          `json:"output"`
+	Document string          `json:"document"`
+	Continue string          `json:"continue,omitempty"` // the output so far of a truncated response
+	Schema   json.RawMessage `json:"schema,omitempty"`   // of the transactions output, with structured_output
+	Pages    *PageRange      `json:"pages,omitempty"`    // only extract these pages
+	Prompt   string          `json:"prompt,omitempty"`   // instructions for the model
+}
+
+type serviceResponse struct {
+	Text         string            `json:"text"`
+	Transactions []serviceRow      `json:"transactions"`
+	Statement    *serviceStatement `json:"statement"`
+	Usage        Usage             `json:"usage"`
+
+	FinishReason string `json:"finish_reason"` // "length" when the output was cut short
+	Raw          string `json:"raw"`           // the model's output, when continuing
+
+	Repaired bool `json:"-"` // Raw only parsed after repairJSON
+}
+
+// truncated reports whether the model stopped at its output token limit
+func (r *serviceResponse) truncated() bool {
+	return r.FinishReason == "length" || r.FinishReason == "max_tokens"
+}
+
+type serviceRow struct {
+	Date        string              `json:"date"` // YYYY-MM-DD
+	Description string              `json:"description"`
+	Amount      transaction.Amount  `json:"amount"`  // negative for money out
+	Balance     *transaction.Amount `json:"balance"` // null when not shown
+	Page        int                 `json:"page"`    // optional, 1-based
+}
+
+type serviceStatement struct {
+	AccountNumber  string              `json:"account_number"`
+	PeriodStart    string              `json:"period_start"` // YYYY-MM-DD
+	PeriodEnd      string              `json:"period_end"`
+	OpeningBalance *transaction.Amount `json:"opening_balance"`
+	ClosingBalance *transaction.Amount `json:"closing_balance"`
+}
+
+// info converts the statement to a StatementInfo, leaving out dates that do
+// not parse
+func (st *serviceStatement) info() transaction.StatementInfo {
+	if st == nil {
+		return transaction.StatementInfo{}
+	}
+	info := transaction.StatementInfo{OpeningBalance: st.OpeningBalance, ClosingBalance: st.ClosingBalance}
+	if st.AccountNumber != "" {
+		info.Account = transaction.MaskAccount(st.AccountNumber)
+	}
+	start, startErr := time.Parse("2006-01-02", st.PeriodStart)
+	end, endErr := time.Parse("2006-01-02", st.PeriodEnd)
+	if startErr == nil && endErr == nil {
+		info.PeriodStart, info.PeriodEnd = start, end
+	}
+	return info
+}
+
+// Text returns the text of the PDF document
+func (s *Service) Text(ctx context.Context, document []byte) (string, error) {
+	resp, err := s.extract(ctx, document, OutputText)
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// Transactions returns the rows of the PDF statement, keeping what the
+// service says about the statement in Info
+func (s *Service) Transactions(ctx context.Context, document []byte) ([]transaction.Transaction, error) {
+	resp, err := s.extract(ctx, document, OutputTransactions)
+	if err != nil {
+		return nil, err
+	}
+	s.Info = resp.Statement.info()
+
+	txns := make([]transaction.Transaction, 0, len(resp.Transactions))
+	for i, row := range resp.Transactions {
+		date, err := time.Parse("2006-01-02", row.Date)
+		if err != nil {
+			return nil, fmt.Errorf("pdf service %q: row %d: invalid date %q", s.Name, i+1, row.Date)
+		}
+		t := transaction.Transaction{
+			Date:        date,
+			Description: strings.TrimSpace(row.Description),
+			Amount:      row.Amount,
+			Balance:     row.Balance,
+		}
+		if row.Page > 0 {
+			t.Statement = &transaction.StatementRef{Page: row.Page}
+		}
+		txns = append(txns, t)
+	}
+	return txns, nil
+}
+
+// extract returns the service's response for the document, from the cache
+// when the same request was made before
+func (s *Service) extract(ctx context.Context, document []byte, output Output) (*serviceResponse, error) {
+	if s.Cache == nil {
+		return s.send(ctx, document, output)
+	}
+	var pages string
+	if s.Pages != nil {
+		pages = s.Pages.String()
+	}
+	parts := []string{Digest(document), s.BaseURL, s.Model, string(output), strconv.FormatBool(s.StructuredOutput), pages}
+	if s.Prompt != "" {
+		// Only then, so the responses cached before prompts are still found
+		parts = append(parts, s.Prompt)
+	}
+	key := cache.Key(parts...)
+	if value, ok, err := s.Cache.Get(ctx, key); err == nil && ok {
+		var cached serviceResponse
+		if json.Unmarshal(value, &cached) == nil {
+			s.CacheHits++
+			return &cached, nil
+		}
+	}
+
+	resp, err := s.send(ctx, document, output)
+	if err != nil {
+		return nil, err
+	}
+	if value, err := json.Marshal(resp); err == nil {
+		_ = s.Cache.Put(ctx, key, value)
+	}
+	return resp, nil
+}
+
+// send calls the service, after checking the ledger, and records the
+// document in the ledger once the service has answered. A ledger that cannot
+// be written then is passed over, as the response is paid for by then.
+func (s *Service) send(ctx context.Context, document []byte, output Output) (*serviceResponse, error) {
+	if s.Ledger == nil {
+		return s.fetch(ctx, document, output)
+	}
+	digest := Digest(document)
+	provider, at, ok, err := s.Ledger.Processed(ctx, digest)
+	if err != nil {
+		return nil, err
+	}
+	if ok && !s.Reprocess {
+		return nil, fmt.Errorf("%w: sent to %q on %s; pass --reprocess to send it again", ErrProcessed, provider, at.Local().Format(time.DateTime))
+	}
+	resp, err := s.fetch(ctx, document, output)
+	if err != nil {
+		return nil, err
+	}
+	_ = s.Ledger.RecordProcessed(ctx, digest, s.Name)
+	return resp, nil
+}
+
+// fetch calls the service, continuing a truncated response until the model
+// finishes
+func (s *Service) fetch(ctx context.Context, document []byte, output Output) (*serviceResponse, error) {
+	req := serviceRequest{
+		Model:    s.Model,
+		Output:   output,
+		Document: base64.StdEncoding.EncodeToString(document),
+	}
+	if s.StructuredOutput && output == OutputTransactions {
+		req.Schema = transactionSchema
+	}
+	req.Pages, req.Prompt = s.Pages, s.Prompt
+	result, err := s.call(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if !result.truncated() {
+		if result.Raw == "" || result.Text != "" || result.Transactions != nil {
+			return result, nil
+		}
+		parsed, err := decodeRaw(output, result.Raw)
+		if err != nil {
+			return nil, fmt.Errorf("pdf service %q: decoding raw output: %w", s.Name, err)
+		}
+		if parsed.Repaired {
+			s.Repairs++
+		}
+		return parsed, nil
+	}
+
+	var raw string
+	for n := 0; result.truncated(); n++ {
+		if result.Raw == "" {
+			return nil, fmt.Errorf("pdf service %q: %w and has no raw output to continue from", s.Name, ErrTruncated)
+		}
+		raw = stitch(raw, result.Raw)
+		if n == APIMaxContinuations {
+			return nil, fmt.Errorf("pdf service %q: %w after %d continuations", s.Name, ErrTruncated, n)
+		}
+		req.Continue = raw
+		if result, err = s.call(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+	raw = stitch(raw, result.Raw)
+
+	stitched, err := decodeRaw(output, raw)
+	if err != nil {
+		return nil, fmt.Errorf("pdf service %q: decoding continued response: %w", s.Name, err)
+	}
+	if stitched.Repaired {
+		s.Repairs++
+	}
+	return stitched, nil
+}
+
+// call makes one request to the service, charging its usage to the budget
+func (s *Service) call(ctx context.Context, req serviceRequest) (*serviceResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("encoding request: %w", err)
+	}
+	if err := s.Budget.Reserve(); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.post(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("pdf service %q: %s: %s", s.Name, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var result serviceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("pdf service %q: decoding response: %w", s.Name, err)
+	}
+	if result.Usage.Cost == 0 {
+		result.Usage.Cost = float64(result.Usage.Tokens()) * s.CostPerMillionTokens / 1e6
+	}
+	s.Budget.Add(result.Usage)
+	return &result, nil
+}
+
+// post sends the request, retrying network and server errors with
+// exponential backoff
+func (s *Service) post(ctx context.Context, body []byte) (*http.Response, error) {
+	var lastErr error
+	backoff := s.Backoff
+
+	for attempt := 0; attempt <= APIMaxRetries; attempt++ {
+		if err := s.Limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.BaseURL+"/extract", bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if s.APIKey != "" {
+			req.Header.Set("Authorization", "Bearer "+s.APIKey)
+		}
+
+		resp, err := s.Client.Do(req)
+		if err == nil {
+			if resp.StatusCode < 500 {
+				return resp, nil
+			}
+			resp.Body.Close()
+			lastErr = fmt.Errorf("server error: %s", resp.Status)
+		} else {
+			lastErr = err
+		}
+
+		if attempt < APIMaxRetries {
+			select {
+			case <-time.After(backoff):
+				backoff *= 2
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+	return nil, fmt.Errorf("pdf service %q: request failed after %d attempts: %w", s.Name, APIMaxRetries+1, lastErr)
+}
@@ -0,0 +1,247 @@
+package parser
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/cache"
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestServiceTransactions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/extract", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		var req serviceRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "model-1", req.Model)
+		assert.Equal(t, OutputTransactions, req.Output)
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("%PDF-1.4")), req.Document)
+
+		_, _ = w.Write([]byte(`{"transactions": [{"date": "2024-01-02", "description": " COLES ", "amount": -12.5, "balance": 100, "page": 2}],
+			"statement": {"account_number": "06 2000 12345678", "period_start": "2024-01-01", "period_end": "2024-01-31", "closing_balance": 100}}`))
+	}))
+	defer srv.Close()
+
+	t.Setenv("TEST_PDF_KEY", "secret")
+	s, err := NewService("test", config.ServiceConfig{APIKeyEnv: "TEST_PDF_KEY", BaseURL: srv.URL + "/", Model: "model-1"}, srv.Client())
+	require.NoError(t, err)
+
+	txns, err := s.Transactions(context.Background(), []byte("%PDF-1.4"))
+	require.NoError(t, err)
+	require.Len(t, txns, 1)
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), txns[0].Date)
+	assert.Equal(t, "COLES", txns[0].Description)
+	assert.Equal(t, transaction.Amount(-1250), txns[0].Amount)
+	assert.Equal(t, transaction.Amount(10000).Ptr(), txns[0].Balance)
+	assert.Equal(t, 2, txns[0].Statement.Page)
+
+	assert.Equal(t, "****5678", s.Info.Account)
+	assert.Equal(t, time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), s.Info.PeriodEnd)
+	assert.Nil(t, s.Info.OpeningBalance)
+	require.NotNil(t, s.Info.ClosingBalance)
+	assert.Equal(t, transaction.Amount(10000), *s.Info.ClosingBalance)
+}
+
+func TestServiceRetries(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"text": "statement text"}`))
+	}))
+	defer srv.Close()
+
+	s, err := NewService("test", config.ServiceConfig{BaseURL: srv.URL}, srv.Client())
+	require.NoError(t, err)
+	s.Backoff = time.Millisecond
+
+	text, err := s.Text(context.Background(), []byte("%PDF-1.4"))
+	require.NoError(t, err)
+	assert.Equal(t, "statement text", text)
+	assert.Equal(t, 3, calls)
+}
+
+func TestServiceCache(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(`{"transactions": [{"date": "2024-01-02", "description": "COLES", "amount": -12.5}],
+			"statement": {"closing_balance": 100}, "usage": {"input_tokens": 1000, "cost": 0.5}}`))
+	}))
+	defer srv.Close()
+
+	responses := cache.NewDir(t.TempDir())
+	extract := func(document string, pages *PageRange) ([]transaction.Transaction, *Service) {
+		s, err := NewService("test", config.ServiceConfig{BaseURL: srv.URL, Model: "model-1"}, srv.Client())
+		require.NoError(t, err)
+		s.Cache, s.Pages = responses, pages
+		s.Budget = &Budget{MaxCost: 0.75}
+		txns, err := s.Transactions(context.Background(), []byte(document))
+		require.NoError(t, err)
+		return txns, s
+	}
+
+	first, _ := extract("%PDF-1.4 jan", nil)
+	again, s := extract("%PDF-1.4 jan", nil)
+	assert.Equal(t, 1, calls, "the second run is answered from the cache")
+	assert.Equal(t, first, again)
+	assert.Equal(t, 1, s.CacheHits)
+	assert.Zero(t, s.Budget.Spent().Cost, "a cached response costs nothing")
+	require.NotNil(t, s.Info.ClosingBalance)
+
+	extract("%PDF-1.4 feb", nil)
+	extract("%PDF-1.4 jan", &PageRange{First: 2, Last: 2})
+	assert.Equal(t, 3, calls, "other statements and pages are not answered from the cache")
+}
+
+// mapLedger is a processing ledger in memory
+type mapLedger map[string]string
+
+func (l mapLedger) Processed(_ context.Context, digest string) (string, time.Time, bool, error) {
+	provider, ok := l[digest]
+	return provider, time.Date(2024, 7, 1, 9, 30, 0, 0, time.Local), ok, nil
+}
+
+func (l mapLedger) RecordProcessed(_ context.Context, digest, provider string) error {
+	l[digest] = provider
+	return nil
+}
+
+func TestServiceLedger(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(`{"text": "statement text"}`))
+	}))
+	defer srv.Close()
+
+	ledger := mapLedger{}
+	s, err := NewService("gateway", config.ServiceConfig{BaseURL: srv.URL}, srv.Client())
+	require.NoError(t, err)
+	s.Ledger = ledger
+
+	_, err = s.Text(context.Background(), []byte("%PDF-1.4 jan"))
+	require.NoError(t, err)
+	assert.Equal(t, mapLedger{Digest([]byte("%PDF-1.4 jan")): "gateway"}, ledger)
+
+	_, err = s.Text(context.Background(), []byte("%PDF-1.4 jan"))
+	assert.ErrorIs(t, err, ErrProcessed)
+	assert.ErrorContains(t, err, `sent to "gateway" on 2024-07-01 09:30:00; pass --reprocess`)
+	assert.Equal(t, 1, calls, "the statement is not sent again")
+	s.Reprocess = true
+	_, err = s.Text(context.Background(), []byte("%PDF-1.4 jan"))
+	require.NoError(t, err)
+	s.Reprocess = false
+
+	// A cached response is free, so it is not refused
+	s.Cache = cache.NewDir(t.TempDir())
+	ledger = mapLedger{}
+	s.Ledger = ledger
+	_, err = s.Text(context.Background(), []byte("%PDF-1.4 feb"))
+	require.NoError(t, err)
+	_, err = s.Text(context.Background(), []byte("%PDF-1.4 feb"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestServiceErrors(t *testing.T) {
+	_, err := NewService("test", config.ServiceConfig{}, nil)
+	assert.EqualError(t, err, `pdf service "test" has no base_url`)
+
+	_, err = NewService("test", config.ServiceConfig{BaseURL: "http://localhost", APIKeyEnv: "TEST_PDF_UNSET"}, nil)
+	assert.EqualError(t, err, `pdf service "test": TEST_PDF_UNSET is not set`)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad document", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	s, err := NewService("test", config.ServiceConfig{BaseURL: srv.URL}, srv.Client())
+	require.NoError(t, err)
+	_, err = s.Transactions(context.Background(), nil)
+	assert.EqualError(t, err, `pdf service "test": 400 Bad Request: bad document`)
+}
+
+func TestServiceContinuesTruncatedResponse(t *testing.T) {
+	pieces := []string{
+		`[{"date": "2024-01-02", "description": "COLES", "amount": -12.5}, {"date": "2024-01-0`,
+		`"amount": -12.5}, {"date": "2024-01-03", "description": "ALDI", "amount": -8}]`,
+	}
+	var continued []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req serviceRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		continued = append(continued, req.Continue)
+		resp := serviceResponse{Raw: pieces[len(continued)-1], Usage: Usage{OutputTokens: 100}}
+		if len(continued) < len(pieces) {
+			resp.FinishReason = "length"
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	s, err := NewService("test", config.ServiceConfig{BaseURL: srv.URL}, srv.Client())
+	require.NoError(t, err)
+	s.Budget = &Budget{}
+
+	txns, err := s.Transactions(context.Background(), []byte("%PDF-1.4"))
+	require.NoError(t, err)
+	require.Len(t, txns, 2)
+	assert.Equal(t, "ALDI", txns[1].Description)
+	assert.Equal(t, []string{"", pieces[0]}, continued)
+	assert.Equal(t, 200, s.Budget.Spent().Tokens(), "each continuation is charged")
+
+	continued = nil
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		continued = append(continued, "")
+		_, _ = w.Write([]byte(`{"finish_reason": "length", "raw": "..."}`))
+	})
+	_, err = s.Text(context.Background(), []byte("%PDF-1.4"))
+	assert.ErrorIs(t, err, ErrTruncated)
+	assert.Len(t, continued, APIMaxContinuations+1)
+
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"finish_reason": "max_tokens", "transactions": []}`))
+	})
+	_, err = s.Transactions(context.Background(), []byte("%PDF-1.4"))
+	assert.EqualError(t, err, `pdf service "test": response was truncated and has no raw output to continue from`)
+}
+
+func TestServiceStructuredOutput(t *testing.T) {
+	var schema json.RawMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req serviceRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		schema = req.Schema
+		_ = json.NewEncoder(w).Encode(serviceResponse{Raw: "```json\n[{\"date\": \"2024-01-02\", \"description\": \"COLES\", \"amount\": -12.5},]\n```"})
+	}))
+	defer srv.Close()
+
+	s, err := NewService("test", config.ServiceConfig{BaseURL: srv.URL, StructuredOutput: true}, srv.Client())
+	require.NoError(t, err)
+	txns, err := s.Transactions(context.Background(), []byte("%PDF-1.4"))
+	require.NoError(t, err)
+	require.Len(t, txns, 1, "raw output is repaired")
+	assert.Equal(t, "COLES", txns[0].Description)
+	assert.JSONEq(t, string(transactionSchema), string(schema))
+	assert.Equal(t, 1, s.Repairs)
+
+	s.StructuredOutput = false
+	_, err = s.Transactions(context.Background(), []byte("%PDF-1.4"))
+	require.NoError(t, err)
+	assert.Nil(t, schema)
+}
@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/example/statement-extractor/internal/importer"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+const (
+	datePattern   = `\d{1,2}\s+[A-Za-z]{3,9}\s+\d{4}|\d{1,2}/\d{1,2}/\d{4}|\d{4}-\d{2}-\d{2}`
+	amountPattern = `-?\$?[\d,]+\.\d{2}(?:[ \t]?(?:CR|DR))?`
+)
+
+// The statement summary labels, which read much the same across banks
+var (
+	accountLabel = regexp.MustCompile(`(?im)^[ \t]*account[ \t]+(?:number|no\.?)[ \t]*:?[ \t]*(\d[\d \t-]*\d)`)
+	periodLabel  = regexp.MustCompile(`(?i)period[ \t]*:?[ \t]*(` + datePattern + `)[ \t]*(?:-|–|to)[ \t]*(` + datePattern + `)`)
+	openingLabel = regexp.MustCompile(`(?i)opening[ \t]+balance[ \t]*:?[ \t]*(` + amountPattern + `)`)
+	closingLabel = regexp.MustCompile(`(?i)closing[ \t]+balance[ \t]*:?[ \t]*(` + amountPattern + `)`)
+)
+
+// statementDateLayouts are tried in turn on the period dates
+var statementDateLayouts = []string{"2 Jan 2006", "2 January 2006", "02/01/2006", "2006-01-02"}
+
+// ParseStatementInfo reads the account number, period and opening and
+// closing balances from the summary of a statement's text. Anything it
+// cannot find is left zero.
+func ParseStatementInfo(text string) transaction.StatementInfo {
+	var info transaction.StatementInfo
+	if m := accountLabel.FindStringSubmatch(text); m != nil {
+		info.Account = transaction.MaskAccount(strings.TrimSpace(m[1]))
+	}
+	if m := periodLabel.FindStringSubmatch(text); m != nil {
+		start, startOK := parseStatementDate(m[1])
+		end, endOK := parseStatementDate(m[2])
+		if startOK && endOK {
+			info.PeriodStart, info.PeriodEnd = start, end
+		}
+	}
+	info.OpeningBalance = findBalance(openingLabel, text)
+	info.ClosingBalance = findBalance(closingLabel, text)
+	return info
+}
+
+func parseStatementDate(s string) (time.Time, bool) {
+	s = strings.Join(strings.Fields(s), " ")
+	for _, layout := range statementDateLayouts {
+		if date, err := time.Parse(layout, s); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func findBalance(label *regexp.Regexp, text string) *transaction.Amount {
+	m := label.FindStringSubmatch(text)
+	if m == nil {
+		return nil
+	}
+	balance, err := importer.ParseAmount(m[1])
+	if err != nil {
+		return nil
+	}
+	return &balance
+}
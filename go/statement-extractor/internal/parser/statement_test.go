@@ -0,0 +1,32 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestParseStatementInfo(t *testing.T) {
+	info := ParseStatementInfo(cbaStatement + "Opening Balance $2,000.00 CR\nClosing Balance $4,454.80 CR\n")
+	assert.Equal(t, "****5678", info.Account)
+	assert.Equal(t, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), info.PeriodStart)
+	assert.Equal(t, time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), info.PeriodEnd)
+	require.NotNil(t, info.OpeningBalance)
+	assert.Equal(t, transaction.Amount(200000), *info.OpeningBalance)
+	require.NotNil(t, info.ClosingBalance)
+	assert.Equal(t, transaction.Amount(445480), *info.ClosingBalance)
+
+	info = ParseStatementInfo(anzStatement + "Period: 01/01/2024 to 31/01/2024\nOPENING BALANCE: $1,000.00DR\n")
+	assert.Equal(t, "****6789", info.Account)
+	assert.Equal(t, time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), info.PeriodEnd)
+	require.NotNil(t, info.OpeningBalance)
+	assert.Equal(t, transaction.Amount(-100000), *info.OpeningBalance)
+	assert.Nil(t, info.ClosingBalance)
+
+	assert.False(t, ParseStatementInfo("01 Dec OPENING BALANCE\n28 Dec PURCHASE 45.20").Found(),
+		"a balance label without an amount on its line")
+}
@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// transactionSchema is the JSON schema of the "transactions" output, sent to
+// services with structured_output so they can constrain the model to it
+// (OpenAI structured outputs, Anthropic tool input schemas)
+var transactionSchema = json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "transactions": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "date": {"type": "string", "description": "YYYY-MM-DD"},
+          "description": {"type": "string"},
+          "amount": {"type": "number", "description": "negative for money out"},
+          "balance": {"type": ["number", "null"], "description": "null when the row shows none"},
+          "page": {"type": "integer", "description": "1-based page of the statement"}
+        },
+        "required": ["date", "description", "amount", "balance", "page"],
+        "additionalProperties": false
+      }
+    }
+  },
+  "required": ["transactions"],
+  "additionalProperties": false
+}`)
+
+var (
+	// trailingComma matches a comma before a closing bracket or brace
+	trailingComma = regexp.MustCompile(`,(\s*[\]}])`)
+	// unquotedKey matches an object key written without quotes
+	unquotedKey = regexp.MustCompile(`([{,]\s*)([A-Za-z_][A-Za-z0-9_]*)(\s*:)`)
+)
+
+// decodeRaw decodes the model's output: plain text, or the transactions as
+// a JSON object or bare array. JSON that does not parse as is is repaired
+// and, if that works, the result is marked Repaired.
+func decodeRaw(output Output, raw string) (*serviceResponse, error) {
+	if output == OutputText {
+		return &serviceResponse{Text: raw}, nil
+	}
+
+	result, err := decodeRows(raw)
+	if err != nil {
+		if repaired, repairErr := decodeRows(repairJSON(raw)); repairErr == nil {
+			repaired.Repaired = true
+			return repaired, nil
+		}
+	}
+	return result, err
+}
+
+func decodeRows(raw string) (*serviceResponse, error) {
+	var result serviceResponse
+	if strings.HasPrefix(strings.TrimSpace(raw), "[") {
+		return &result, json.Unmarshal([]byte(raw), &result.Transactions)
+	}
+	return &result, json.Unmarshal([]byte(raw), &result)
+}
+
+// repairJSON cleans up the usual defects of JSON written by a model without
+// constrained output: a Markdown code fence, prose before or after the
+// value, unquoted keys and trailing commas
+func repairJSON(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "```") {
+		raw = strings.TrimPrefix(raw[strings.IndexByte(raw+"\n", '\n'):], "\n")
+		raw = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(raw), "```"))
+	}
+	if start := strings.IndexAny(raw, "[{"); start > 0 {
+		raw = raw[start:]
+	}
+	if end := strings.LastIndexAny(raw, "]}"); end >= 0 {
+		raw = raw[:end+1]
+	}
+	raw = unquotedKey.ReplaceAllString(raw, `$1"$2"$3`)
+	return trailingComma.ReplaceAllString(raw, "$1")
+}
@@ -0,0 +1,27 @@
+package parser
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionSchema(t *testing.T) {
+	var schema map[string]any
+	require.NoError(t, json.Unmarshal(transactionSchema, &schema))
+	assert.Equal(t, "object", schema["type"])
+}
+
+func TestRepairJSON(t *testing.T) {
+	for raw, want := range map[string]string{
+		"```json\n[{\"amount\": 1,}]\n```":                     `[{"amount": 1}]`,
+		"Here are the transactions:\n[{\"amount\": 1}]\nDone.": `[{"amount": 1}]`,
+		`{"transactions": [{"amount": 1}, {"amount": 2},  ],}`: `{"transactions": [{"amount": 1}, {"amount": 2}  ]}`,
+		`[{date: "2024-01-02", amount: -1, "page": 1}]`:        `[{"date": "2024-01-02", "amount": -1, "page": 1}]`,
+		`[]`: `[]`,
+	} {
+		assert.Equal(t, want, repairJSON(raw), raw)
+	}
+}
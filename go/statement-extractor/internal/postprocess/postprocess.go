@@ -0,0 +1,185 @@
+// Package postprocess applies configured per-parser rewrites to extracted
+// rows, so bank quirks such as a noisy description prefix or a section
+// with inverted signs can be fixed in config rather than code.
+package postprocess
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// DateLayout is the format of .Date in templates and of rewritten dates
+const DateLayout = "2006-01-02"
+
+// Row is the template data for one extracted row
+type Row struct {
+	Date        string
+	Description string
+	Amount      float64
+	Balance     float64
+	Category    string
+	Source      string
+}
+
+// funcs are available to templates in addition to the text/template builtins
+var funcs = template.FuncMap{
+	"trim":       strings.TrimSpace,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+	"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"matches": func(pattern, s string) (bool, error) {
+		return regexp.MatchString(pattern, s)
+	},
+	"regexReplace": func(pattern, repl, s string) (string, error) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", err
+		}
+		return re.ReplaceAllString(s, repl), nil
+	},
+	"neg": func(v float64) float64 { return -v },
+	"abs": math.Abs,
+}
+
+// step is a compiled PostProcessStep
+type step struct {
+	when     *template.Template
+	field    string
+	template *template.Template
+}
+
+// Pipeline is a compiled list of post-processing steps
+type Pipeline struct {
+	steps []step
+}
+
+// Compile parses the steps' templates
+func Compile(steps []config.PostProcessStep) (*Pipeline, error) {
+	p := &Pipeline{steps: make([]step, 0, len(steps))}
+	for i, s := range steps {
+		field := strings.ToLower(s.Field)
+		switch field {
+		case "description", "amount", "balance", "category", "date":
+		default:
+			return nil, fmt.Errorf("post_process step %d: unknown field %q (expected description, amount, balance, category or date)", i+1, s.Field)
+		}
+
+		compiled := step{field: field}
+		var err error
+		if compiled.template, err = parse(s.Template); err != nil {
+			return nil, fmt.Errorf("post_process step %d: %w", i+1, err)
+		}
+		if s.When != "" {
+			if compiled.when, err = parse(s.When); err != nil {
+				return nil, fmt.Errorf("post_process step %d when: %w", i+1, err)
+			}
+		}
+		p.steps = append(p.steps, compiled)
+	}
+	return p, nil
+}
+
+func parse(text string) (*template.Template, error) {
+	return template.New("").Funcs(funcs).Option("missingkey=error").Parse(text)
+}
+
+// Len returns the number of steps
+func (p *Pipeline) Len() int {
+	return len(p.steps)
+}
+
+// Apply runs every step against t in order; later steps see earlier rewrites
+func (p *Pipeline) Apply(t transaction.Transaction) (transaction.Transaction, error) {
+	for i, s := range p.steps {
+		var balance float64
+		if t.Balance != nil {
+			balance = t.Balance.Float()
+		}
+		row := Row{
+			Date:        t.Date.Format(DateLayout),
+			Description: t.Description,
+			Amount:      t.Amount.Float(),
+			Balance:     balance,
+			Category:    t.Category,
+			Source:      t.Source,
+		}
+
+		if s.when != nil {
+			ok, err := render(s.when, row)
+			if err != nil {
+				return t, fmt.Errorf("post_process step %d when: %w", i+1, err)
+			}
+			if strings.TrimSpace(ok) != "true" {
+				continue
+			}
+		}
+
+		value, err := render(s.template, row)
+		if err != nil {
+			return t, fmt.Errorf("post_process step %d: %w", i+1, err)
+		}
+		if err := set(&t, s.field, value); err != nil {
+			return t, fmt.Errorf("post_process step %d: %w", i+1, err)
+		}
+	}
+	return t, nil
+}
+
+// ApplyAll runs the pipeline over every row
+func (p *Pipeline) ApplyAll(txns []transaction.Transaction) error {
+	for i := range txns {
+		t, err := p.Apply(txns[i])
+		if err != nil {
+			return fmt.Errorf("row %d (%s): %w", i+1, txns[i].Description, err)
+		}
+		txns[i] = t
+	}
+	return nil
+}
+
+func render(tmpl *template.Template, row Row) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, row); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func set(t *transaction.Transaction, field, value string) error {
+	switch field {
+	case "description":
+		t.Description = strings.TrimSpace(value)
+	case "category":
+		t.Category = strings.TrimSpace(value)
+	case "amount", "balance":
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("%s template produced %q, not a number", field, value)
+		}
+		if field == "amount" {
+			t.Amount = transaction.FromFloat(v)
+		} else {
+			t.Balance = transaction.FromFloat(v).Ptr()
+		}
+	case "date":
+		d, err := time.Parse(DateLayout, strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("date template produced %q, expected YYYY-MM-DD", value)
+		}
+		t.Date = d
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package postprocess
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestApply(t *testing.T) {
+	p, err := Compile([]config.PostProcessStep{
+		{Field: "description", Template: `{{ trimPrefix "VISA PURCHASE " .Description }}`},
+		{Field: "description", Template: `{{ regexReplace " +[0-9]{4,}$" "" .Description }}`},
+		{When: `{{ hasPrefix "REFUND" .Description }}`, Field: "amount", Template: `{{ abs .Amount }}`},
+		{When: `{{ eq .Source "CBA credit" }}`, Field: "amount", Template: `{{ neg .Amount }}`},
+		{Field: "date", Template: `{{ if eq .Date "2024-02-30" }}2024-02-29{{ else }}{{ .Date }}{{ end }}`},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 5, p.Len())
+
+	txns := []transaction.Transaction{
+		{Date: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), Description: "VISA PURCHASE WOOLWORTHS 123456", Amount: -2000, Source: "CBA"},
+		{Date: time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC), Description: "REFUND ACME", Amount: -1500, Source: "CBA"},
+		{Date: time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC), Description: "INTEREST", Amount: 450, Source: "CBA credit"},
+	}
+	require.NoError(t, p.ApplyAll(txns))
+
+	assert.Equal(t, "WOOLWORTHS", txns[0].Description)
+	assert.Equal(t, transaction.Amount(-2000), txns[0].Amount, "unmatched when conditions leave the row alone")
+	assert.Equal(t, transaction.Amount(1500), txns[1].Amount)
+	assert.Equal(t, transaction.Amount(-450), txns[2].Amount)
+	assert.Equal(t, time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC), txns[2].Date)
+}
+
+func TestCompile_Errors(t *testing.T) {
+	_, err := Compile([]config.PostProcessStep{{Field: "memo", Template: "x"}})
+	assert.ErrorContains(t, err, `step 1: unknown field "memo"`)
+
+	_, err = Compile([]config.PostProcessStep{{Field: "description", Template: "{{ .Description"}})
+	assert.ErrorContains(t, err, "step 1:")
+
+	_, err = Compile([]config.PostProcessStep{{Field: "amount", Template: "1", When: "{{ nosuchfunc }}"}})
+	assert.ErrorContains(t, err, "step 1 when:")
+}
+
+func TestApply_Errors(t *testing.T) {
+	p, err := Compile([]config.PostProcessStep{{Field: "amount", Template: "{{ .Description }}"}})
+	require.NoError(t, err)
+	_, err = p.Apply(transaction.Transaction{Description: "ten"})
+	assert.EqualError(t, err, `post_process step 1: amount template produced "ten", not a number`)
+
+	p, err = Compile([]config.PostProcessStep{{Field: "date", Template: "31/01/2024"}})
+	require.NoError(t, err)
+	_, err = p.Apply(transaction.Transaction{})
+	assert.ErrorContains(t, err, "expected YYYY-MM-DD")
+
+	p, err = Compile([]config.PostProcessStep{{Field: "category", Template: `{{ .Memo }}`}})
+	require.NoError(t, err)
+	_, err = p.Apply(transaction.Transaction{})
+	assert.ErrorContains(t, err, "Memo")
+}
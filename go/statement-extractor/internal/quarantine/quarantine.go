@@ -0,0 +1,244 @@
+// Package quarantine sets aside statements that keep failing in batch runs,
+// with a sidecar report of why, so that they stop being retried on every run
+// and block nothing else. Quarantined statements can be put back once the
+// cause is fixed.
+package quarantine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/statement-extractor/internal/archive"
+	"github.com/example/statement-extractor/internal/clock"
+	"github.com/example/statement-extractor/internal/lockfile"
+)
+
+// FileName is the name of the failed attempts file in the state directory
+const FileName = "failures.json"
+
+// ReportSuffix is appended to a quarantined statement's name to name its
+// error report
+const ReportSuffix = ".error.json"
+
+// Attempts are the failed runs of one statement since it last succeeded
+type Attempts struct {
+	SHA256    string    `json:"sha256"` // a changed file starts again
+	Count     int       `json:"count"`
+	LastError string    `json:"last_error"`
+	LastAt    time.Time `json:"last_at"`
+}
+
+// Report is the sidecar written next to a quarantined statement
+type Report struct {
+	Source        string    `json:"source"`   // as in archive.File
+	Original      string    `json:"original"` // the path the statement was moved from
+	SHA256        string    `json:"sha256"`
+	Parser        string    `json:"parser,omitempty"`
+	Attempts      int       `json:"attempts"`
+	Error         string    `json:"error"` // of the last attempt
+	QuarantinedAt time.Time `json:"quarantined_at"`
+}
+
+// Quarantine tracks failed attempts in a state file and moves statements
+// into Dir once they reach MaxAttempts
+type Quarantine struct {
+	Dir         string
+	MaxAttempts int
+
+	path     string // of the attempts file
+	clock    clock.Clock
+	attempts map[string]Attempts // keyed by the statement's absolute path
+	changed  bool
+}
+
+// Load reads the attempts file at path; a missing file starts empty
+func Load(path, dir string, maxAttempts int, clk clock.Clock) (*Quarantine, error) {
+	q := &Quarantine{Dir: dir, MaxAttempts: maxAttempts, path: path, clock: clk, attempts: make(map[string]Attempts)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read failed attempts: %w", err)
+	}
+	if err := json.Unmarshal(data, &q.attempts); err != nil {
+		return nil, fmt.Errorf("failed to parse failed attempts in %s: %w", path, err)
+	}
+	return q, nil
+}
+
+// Fail records a failed attempt at the statement at path with the given
+// digest. Once the statement has failed MaxAttempts times it is moved into
+// Dir with a report, and the path it was moved to is returned.
+func (q *Quarantine) Fail(path, source, digest, parser string, failure error) (string, error) {
+	key, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	a := q.attempts[key]
+	if a.SHA256 != digest {
+		a = Attempts{SHA256: digest}
+	}
+	a.Count++
+	a.LastError = failure.Error()
+	a.LastAt = q.clock.Now().UTC()
+	q.attempts[key], q.changed = a, true
+	if a.Count < q.MaxAttempts {
+		return "", nil
+	}
+
+	report := Report{
+		Source:        source,
+		Original:      key,
+		SHA256:        digest,
+		Parser:        parser,
+		Attempts:      a.Count,
+		Error:         a.LastError,
+		QuarantinedAt: a.LastAt,
+	}
+	moved, err := q.put(path, report)
+	if err != nil {
+		return "", fmt.Errorf("failed to quarantine %s: %w", source, err)
+	}
+	delete(q.attempts, key)
+	return moved, nil
+}
+
+// Succeed forgets the failed attempts at the statement at path
+func (q *Quarantine) Succeed(path string) {
+	key, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	if _, ok := q.attempts[key]; ok {
+		delete(q.attempts, key)
+		q.changed = true
+	}
+}
+
+// Attempts returns the failed attempts recorded for the statement at path
+func (q *Quarantine) Attempts(path string) Attempts {
+	key, _ := filepath.Abs(path)
+	return q.attempts[key]
+}
+
+// put moves the statement into Dir under a free name and writes its report
+func (q *Quarantine) put(path string, report Report) (string, error) {
+	if err := os.MkdirAll(q.Dir, 0o755); err != nil {
+		return "", err
+	}
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	target := filepath.Join(q.Dir, base)
+	for n := 2; exists(target) || exists(target+ReportSuffix); n++ {
+		target = filepath.Join(q.Dir, fmt.Sprintf("%s-%d%s", strings.TrimSuffix(base, ext), n, ext))
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(target+ReportSuffix, append(data, '\n'), 0o644); err != nil {
+		return "", err
+	}
+	if err := archive.Move(path, target); err != nil {
+		os.Remove(target + ReportSuffix)
+		return "", err
+	}
+	return target, nil
+}
+
+// Save writes the attempts file when attempts were recorded or forgotten
+func (q *Quarantine) Save(ctx context.Context) error {
+	if !q.changed {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(q.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	lock, err := lockfile.Acquire(ctx, q.path, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	data, err := json.MarshalIndent(q.attempts, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode failed attempts: %w", err)
+	}
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write failed attempts: %w", err)
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		return fmt.Errorf("failed to write failed attempts: %w", err)
+	}
+	q.changed = false
+	return nil
+}
+
+// Entry is a quarantined statement
+type Entry struct {
+	Path string // of the statement in the quarantine directory
+	Report
+}
+
+// List returns the statements quarantined in dir, oldest first
+func List(dir string) ([]Entry, error) {
+	reports, err := filepath.Glob(filepath.Join(dir, "*"+ReportSuffix))
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, path := range reports {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read quarantine report: %w", err)
+		}
+		e := Entry{Path: strings.TrimSuffix(path, ReportSuffix)}
+		if err := json.Unmarshal(data, &e.Report); err != nil {
+			return nil, fmt.Errorf("failed to parse quarantine report %s: %w", path, err)
+		}
+		if !exists(e.Path) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].QuarantinedAt.Before(entries[j].QuarantinedAt) })
+	return entries, nil
+}
+
+// Restore moves a quarantined statement back to where it was taken from, or
+// into dir when given, and removes its report. The path it was restored to
+// is returned.
+func Restore(e Entry, dir string) (string, error) {
+	target := e.Original
+	if dir != "" {
+		target = filepath.Join(dir, filepath.Base(e.Original))
+	}
+	if exists(target) {
+		return "", fmt.Errorf("cannot restore %s: %s already exists", e.Source, target)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return "", fmt.Errorf("failed to restore %s: %w", e.Source, err)
+	}
+	if err := archive.Move(e.Path, target); err != nil {
+		return "", fmt.Errorf("failed to restore %s: %w", e.Source, err)
+	}
+	if err := os.Remove(e.Path + ReportSuffix); err != nil {
+		return "", fmt.Errorf("failed to remove quarantine report: %w", err)
+	}
+	return target, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
@@ -0,0 +1,81 @@
+package quarantine
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/clock"
+)
+
+func TestQuarantine(t *testing.T) {
+	dir := t.TempDir()
+	inbox := filepath.Join(dir, "inbox")
+	require.NoError(t, os.Mkdir(inbox, 0o755))
+	statement := filepath.Join(inbox, "jan.pdf")
+	require.NoError(t, os.WriteFile(statement, []byte("%PDF-jan"), 0o644))
+
+	state := filepath.Join(dir, "state", FileName)
+	quarantined := filepath.Join(dir, "quarantine")
+	at := time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)
+	q, err := Load(state, quarantined, 2, clock.Fixed(at))
+	require.NoError(t, err)
+
+	failure := errors.New("no parser detected")
+	moved, err := q.Fail(statement, "jan.pdf", "aaa", "cba", failure)
+	require.NoError(t, err)
+	assert.Empty(t, moved, "not yet at the limit")
+	require.NoError(t, q.Save(context.Background()))
+
+	q, err = Load(state, quarantined, 2, clock.Fixed(at))
+	require.NoError(t, err)
+	assert.Equal(t, 1, q.Attempts(statement).Count, "attempts carry over between runs")
+	_, err = q.Fail(statement, "jan.pdf", "bbb", "cba", failure)
+	require.NoError(t, err)
+	assert.Equal(t, 1, q.Attempts(statement).Count, "a changed file starts again")
+
+	moved, err = q.Fail(statement, "jan.pdf", "bbb", "cba", failure)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(quarantined, "jan.pdf"), moved)
+	assert.NoFileExists(t, statement)
+	assert.Zero(t, q.Attempts(statement).Count)
+
+	entries, err := List(quarantined)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, moved, entries[0].Path)
+	assert.Equal(t, Report{
+		Source: "jan.pdf", Original: statement, SHA256: "bbb", Parser: "cba",
+		Attempts: 2, Error: "no parser detected", QuarantinedAt: at,
+	}, entries[0].Report)
+
+	// A second statement of the same name does not overwrite the first
+	require.NoError(t, os.WriteFile(statement, []byte("%PDF-jan2"), 0o644))
+	q.MaxAttempts = 1
+	moved, err = q.Fail(statement, "jan.pdf", "ccc", "", failure)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(quarantined, "jan-2.pdf"), moved)
+
+	restored, err := Restore(entries[0], "")
+	require.NoError(t, err)
+	assert.Equal(t, statement, restored)
+	data, err := os.ReadFile(statement)
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-jan", string(data))
+	assert.NoFileExists(t, entries[0].Path+ReportSuffix)
+
+	entries, err = List(quarantined)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	_, err = Restore(entries[0], "")
+	assert.ErrorContains(t, err, "already exists")
+	restored, err = Restore(entries[0], filepath.Join(dir, "retry"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "retry", "jan.pdf"), restored)
+}
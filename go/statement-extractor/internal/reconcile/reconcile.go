@@ -0,0 +1,74 @@
+// Package reconcile matches the transactions of two sources for the same
+// account, such as the PDF statements and the CSV download of one month, to
+// find rows that either source is missing.
+package reconcile
+
+import (
+	"strings"
+
+	"github.com/example/statement-extractor/internal/dedup"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Result is the outcome of reconciling a left and a right source
+type Result struct {
+	Matched   []dedup.Duplicate // Existing indexes Left, Incoming indexes Right
+	LeftOnly  []int             // indexes into Left with no match on the right
+	RightOnly []int             // indexes into Right with no match on the left
+}
+
+// Near returns the number of matches made within the tolerance rather than
+// exactly
+func (r Result) Near() int {
+	n := 0
+	for _, m := range r.Matched {
+		if m.Near {
+			n++
+		}
+	}
+	return n
+}
+
+// Reconciled reports whether every transaction found a match
+func (r Result) Reconciled() bool {
+	return len(r.LeftOnly) == 0 && len(r.RightOnly) == 0
+}
+
+// Reconcile pairs each left transaction with at most one right transaction,
+// exactly or within tol, and lists those left over on each side in their
+// original order
+func Reconcile(left, right []transaction.Transaction, tol dedup.Tolerance) Result {
+	result := Result{Matched: dedup.FindNear(left, right, tol)}
+	matchedLeft := make(map[int]bool, len(result.Matched))
+	matchedRight := make(map[int]bool, len(result.Matched))
+	for _, m := range result.Matched {
+		matchedLeft[m.Existing] = true
+		matchedRight[m.Incoming] = true
+	}
+	for i := range left {
+		if !matchedLeft[i] {
+			result.LeftOnly = append(result.LeftOnly, i)
+		}
+	}
+	for i := range right {
+		if !matchedRight[i] {
+			result.RightOnly = append(result.RightOnly, i)
+		}
+	}
+	return result
+}
+
+// ForAccount keeps the transactions of the named account, matched
+// case-insensitively; an empty name keeps all
+func ForAccount(txns []transaction.Transaction, account string) []transaction.Transaction {
+	if account == "" {
+		return txns
+	}
+	var kept []transaction.Transaction
+	for _, t := range txns {
+		if strings.EqualFold(t.Account, account) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
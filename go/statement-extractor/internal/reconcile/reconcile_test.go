@@ -0,0 +1,44 @@
+package reconcile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/example/statement-extractor/internal/dedup"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func day(d int) time.Time {
+	return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestReconcile(t *testing.T) {
+	pdf := []transaction.Transaction{
+		{Date: day(3), Description: "WOOLWORTHS 1234 SYDNEY", Amount: -5410},
+		{Date: day(5), Description: "CAFE NERO", Amount: -450},
+		{Date: day(15), Description: "SALARY ACME", Amount: 250000},
+	}
+	csv := []transaction.Transaction{
+		{Date: day(5), Description: "CAFE NERO", Amount: -450},
+		{Date: day(2), Description: "WOOLWORTHS SYDNEY", Amount: -5410},
+		{Date: day(9), Description: "CAFE NERO", Amount: -450},
+		{Date: day(20), Description: "NETFLIX", Amount: -1699},
+	}
+
+	r := Reconcile(pdf, csv, dedup.DefaultTolerance)
+	assert.Len(t, r.Matched, 2)
+	assert.Equal(t, 1, r.Near())
+	assert.Equal(t, []int{2}, r.LeftOnly)
+	assert.Equal(t, []int{2, 3}, r.RightOnly)
+	assert.False(t, r.Reconciled())
+
+	assert.True(t, Reconcile(pdf[:2], csv[:2], dedup.DefaultTolerance).Reconciled())
+}
+
+func TestForAccount(t *testing.T) {
+	txns := []transaction.Transaction{{Account: "Everyday"}, {Account: "Credit"}, {}}
+	assert.Equal(t, txns[:1], ForAccount(txns, "everyday"))
+	assert.Equal(t, txns, ForAccount(txns, ""))
+}
@@ -0,0 +1,46 @@
+package report
+
+import (
+	"time"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Amortize replaces each transaction with AmortizeMonths > 1 by equal
+// monthly instalments starting in its own month, so a yearly renewal shows
+// up as a steady monthly cost. Instalments are rounded to cents with any
+// remainder on the last, so totals are unchanged.
+func Amortize(txns []transaction.Transaction) []transaction.Transaction {
+	var out []transaction.Transaction
+	for _, t := range txns {
+		n := t.AmortizeMonths
+		if n <= 1 {
+			out = append(out, t)
+			continue
+		}
+
+		share := t.Amount.Mul(1 / float64(n))
+		remaining := t.Amount
+		for i := range n {
+			part := t
+			part.Date = addMonths(t.Date, i)
+			part.AmortizeMonths = 0
+			if i == n-1 {
+				part.Amount = remaining
+			} else {
+				part.Amount = share
+				remaining -= share
+			}
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// addMonths moves d forward n months, clamping the day to the month's length
+// so 31 January plus one month is 29 February rather than 2 March
+func addMonths(d time.Time, n int) time.Time {
+	first := time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, d.Location()).AddDate(0, n, 0)
+	last := first.AddDate(0, 1, -1).Day()
+	return time.Date(first.Year(), first.Month(), min(d.Day(), last), d.Hour(), d.Minute(), d.Second(), d.Nanosecond(), d.Location())
+}
@@ -0,0 +1,37 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestAmortize(t *testing.T) {
+	txns := []transaction.Transaction{
+		{Date: date("2024-01-31"), Description: "CAR INSURANCE", Amount: -100000, Category: "Insurance", AmortizeMonths: 12},
+		{Date: date("2024-01-05"), Description: "COFFEE", Amount: -500, Category: "Food & dining"},
+	}
+
+	amortized := Amortize(txns)
+	require.Len(t, amortized, 13)
+	assert.Equal(t, transaction.Amount(-8333), amortized[0].Amount)
+	assert.Equal(t, date("2024-02-29"), amortized[1].Date, "day is clamped to the month")
+	assert.Equal(t, date("2024-12-31"), amortized[11].Date)
+	assert.Equal(t, transaction.Amount(-8337), amortized[11].Amount, "remainder goes on the last instalment")
+	assert.Zero(t, amortized[11].AmortizeMonths)
+	assert.Equal(t, txns[1], amortized[12])
+
+	var total transaction.Amount
+	for _, t := range amortized[:12] {
+		total += t.Amount
+	}
+	assert.Equal(t, transaction.Amount(-100000), total)
+
+	period, err := ParsePeriod("2024-01:2024-03")
+	require.NoError(t, err)
+	agg := Aggregate(amortized, period)
+	assert.Equal(t, transaction.Amount(-8333*3), agg.Total("Insurance"), "only instalments inside the period count")
+}
@@ -0,0 +1,65 @@
+package report
+
+import (
+	"github.com/example/statement-extractor/internal/alert"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Envelope is one month of a monthly_budget alert rule treated as a budget
+// envelope. Spent is the net spending on the transactions the rule matches,
+// positive when money went out; Carried is what the month before left over,
+// negative when it was overspent, and always 0 without rollover.
+type Envelope struct {
+	Name     string
+	Month    string // YYYY-MM
+	Rollover bool
+	Budget   transaction.Amount
+	Carried  transaction.Amount
+	Spent    transaction.Amount
+}
+
+// Available returns what is left of the envelope, negative when overspent
+func (e Envelope) Available() transaction.Amount {
+	return e.Carried + e.Budget - e.Spent
+}
+
+// Budget returns the envelope of every monthly_budget rule for each month
+// of the period, in rule then month order. Rules with rollover carry each
+// month's Available into the next, starting from opening[rule name], the
+// balance carried into the first month; rules without it start every month
+// afresh. Transactions are matched as the alert rules match them.
+func Budget(rules []*alert.Rule, txns []transaction.Transaction, period Period, opening map[string]transaction.Amount) []Envelope {
+	months := period.MonthLabels()
+	var envelopes []Envelope
+	for _, r := range rules {
+		if r.MonthlyBudget <= 0 {
+			continue
+		}
+		spent := make(map[string]transaction.Amount, len(months))
+		for _, t := range txns {
+			if period.Contains(t.Date) && r.Matches(t) {
+				spent[t.Date.Format(monthFormat)] -= t.Amount
+			}
+		}
+
+		var carried transaction.Amount
+		if r.Rollover {
+			carried = opening[r.Name]
+		}
+		for _, month := range months {
+			e := Envelope{
+				Name:     r.Name,
+				Month:    month,
+				Rollover: r.Rollover,
+				Budget:   transaction.FromFloat(r.MonthlyBudget),
+				Carried:  carried,
+				Spent:    spent[month],
+			}
+			envelopes = append(envelopes, e)
+			if r.Rollover {
+				carried = e.Available()
+			}
+		}
+	}
+	return envelopes
+}
@@ -0,0 +1,36 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/alert"
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestBudget(t *testing.T) {
+	period, err := ParsePeriod("2024-01:2024-03")
+	require.NoError(t, err)
+	rules, err := alert.Compile([]config.AlertRule{
+		{Name: "Groceries", Category: "groceries & household", MonthlyBudget: 150, Rollover: true},
+		{Name: "Fun", Category: "Entertainment", MonthlyBudget: 50},
+		{Name: "Big spend", MinAmount: 1000},
+	})
+	require.NoError(t, err)
+
+	envelopes := Budget(rules, sampleTransactions(), period, map[string]transaction.Amount{"Groceries": 1000, "Fun": 1000})
+
+	assert.Equal(t, []Envelope{
+		{Name: "Groceries", Month: "2024-01", Rollover: true, Budget: 15000, Carried: 1000, Spent: 20000},
+		{Name: "Groceries", Month: "2024-02", Rollover: true, Budget: 15000, Carried: -4000, Spent: 10000},
+		{Name: "Groceries", Month: "2024-03", Rollover: true, Budget: 15000, Carried: 1000, Spent: 6000},
+		{Name: "Fun", Month: "2024-01", Budget: 5000, Spent: 2000},
+		{Name: "Fun", Month: "2024-02", Budget: 5000, Spent: 6000},
+		{Name: "Fun", Month: "2024-03", Budget: 5000},
+	}, envelopes)
+	assert.Equal(t, transaction.Amount(10000), envelopes[2].Available())
+	assert.Equal(t, transaction.Amount(-1000), envelopes[4].Available())
+}
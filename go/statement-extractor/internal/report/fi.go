@@ -0,0 +1,113 @@
+package report
+
+import (
+	"strings"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// maxProjectionYears bounds the years-to-FI projection
+const maxProjectionYears = 100
+
+// FIReport summarizes savings rate and financial-independence projections
+type FIReport struct {
+	Period   Period
+	Income   float64 // total income over the period
+	Expenses float64 // total spending over the period, as a positive amount
+
+	WithdrawalRate float64
+	ReturnRate     float64
+	NetWorth       float64 // invested assets the projection starts from
+}
+
+// SavingsRate returns the share of income not spent, or 0 without income
+func (r *FIReport) SavingsRate() float64 {
+	if r.Income <= 0 {
+		return 0
+	}
+	return (r.Income - r.Expenses) / r.Income
+}
+
+// AnnualExpenses returns the spending run rate extrapolated to a year
+func (r *FIReport) AnnualExpenses() float64 {
+	months := r.Period.Months()
+	if months == 0 {
+		return 0
+	}
+	return r.Expenses / float64(months) * 12
+}
+
+// AnnualSavings returns the savings run rate extrapolated to a year
+func (r *FIReport) AnnualSavings() float64 {
+	months := r.Period.Months()
+	if months == 0 {
+		return 0
+	}
+	return (r.Income - r.Expenses) / float64(months) * 12
+}
+
+// FINumber returns the invested assets needed to cover annual expenses at
+// the configured withdrawal rate
+func (r *FIReport) FINumber() float64 {
+	if r.WithdrawalRate <= 0 {
+		return 0
+	}
+	return r.AnnualExpenses() / r.WithdrawalRate
+}
+
+// YearsToFI projects how many years of saving at the current rate, with
+// returns compounding annually, it takes to reach the FI number. It returns
+// false when the target is not reachable within maxProjectionYears.
+func (r *FIReport) YearsToFI() (int, bool) {
+	target := r.FINumber()
+	savings := r.AnnualSavings()
+	balance := r.NetWorth
+	for year := 0; year <= maxProjectionYears; year++ {
+		if balance >= target {
+			return year, true
+		}
+		balance = balance*(1+r.ReturnRate) + savings
+	}
+	return 0, false
+}
+
+// FI builds the FI report for transactions within period. Transactions in
+// income categories count as income, excluded categories are ignored, and
+// everything else nets into expenses so refunds offset spending.
+func FI(txns []transaction.Transaction, period Period, cfg config.FIConfig, netWorth float64) *FIReport {
+	income := lowerSet(cfg.IncomeCategories)
+	excluded := lowerSet(cfg.ExcludedCategories)
+
+	r := &FIReport{
+		Period:         period,
+		WithdrawalRate: cfg.WithdrawalRate,
+		ReturnRate:     cfg.ReturnRate,
+		NetWorth:       netWorth,
+	}
+	var incomeTotal, expenses transaction.Amount
+	for _, t := range txns {
+		if !period.Contains(t.Date) || t.ExcludeFromReports {
+			continue
+		}
+		category := strings.ToLower(t.Category)
+		switch {
+		case excluded[category]:
+		case income[category], len(income) == 0 && t.Amount > 0:
+			incomeTotal += t.Amount
+		default:
+			expenses -= t.Amount
+		}
+	}
+	r.Income = incomeTotal.Float()
+	r.Expenses = max(expenses, 0).Float()
+	return r
+}
+
+func lowerSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[strings.ToLower(v)] = true
+	}
+	return set
+}
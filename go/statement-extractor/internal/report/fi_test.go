@@ -0,0 +1,59 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestFI(t *testing.T) {
+	period, err := ParsePeriod("2024-01:2024-03")
+	require.NoError(t, err)
+
+	txns := append(sampleTransactions(),
+		transaction.Transaction{Date: date("2024-02-01"), Amount: -100000, Category: "Transfer"},
+		transaction.Transaction{Date: date("2024-04-01"), Amount: 999900, Category: "Income"},
+		transaction.Transaction{Date: date("2024-03-01"), Amount: -8000000, Category: "Housing", ExcludeFromReports: true},
+	)
+	cfg := config.FIConfig{
+		WithdrawalRate:     0.04,
+		IncomeCategories:   []string{"income"},
+		ExcludedCategories: []string{"Transfer"},
+	}
+
+	r := FI(txns, period, cfg, 0)
+
+	assert.Equal(t, 5000.0, r.Income)
+	assert.Equal(t, 440.0, r.Expenses)
+	assert.InDelta(t, 0.912, r.SavingsRate(), 1e-9)
+	assert.InDelta(t, 1760, r.AnnualExpenses(), 1e-9)
+	assert.InDelta(t, 44000, r.FINumber(), 1e-9)
+
+	years, ok := r.YearsToFI()
+	require.True(t, ok)
+	assert.Equal(t, 3, years)
+
+	r.NetWorth = 50000
+	years, ok = r.YearsToFI()
+	require.True(t, ok)
+	assert.Zero(t, years)
+}
+
+func TestFI_Unreachable(t *testing.T) {
+	period, err := ParsePeriod("2024-01")
+	require.NoError(t, err)
+
+	txns := []transaction.Transaction{
+		{Date: date("2024-01-02"), Amount: 100000, Category: "Income"},
+		{Date: date("2024-01-03"), Amount: -150000, Category: "Rent"},
+	}
+	r := FI(txns, period, config.FIConfig{WithdrawalRate: 0.04, IncomeCategories: []string{"Income"}}, 0)
+
+	assert.Equal(t, -0.5, r.SavingsRate())
+	_, ok := r.YearsToFI()
+	assert.False(t, ok)
+}
@@ -0,0 +1,72 @@
+package report
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Ungrouped is the group of categories not listed in any configured group
+const Ungrouped = "Ungrouped"
+
+// Unassigned is the owner of transactions no [[owners]] entry attributes
+const Unassigned = "Unassigned"
+
+// Groups maps categories to their configured reporting group
+type Groups struct {
+	of map[string]string // lower-cased category -> group name
+}
+
+// NewGroups indexes the configured groups. A category may belong to only
+// one group.
+func NewGroups(groups []config.CategoryGroup) (*Groups, error) {
+	g := &Groups{of: make(map[string]string)}
+	for _, group := range groups {
+		if group.Name == "" {
+			return nil, errors.New("category group without a name")
+		}
+		for _, category := range group.Categories {
+			key := strings.ToLower(category)
+			if existing, ok := g.of[key]; ok && existing != group.Name {
+				return nil, fmt.Errorf("category %q is in both groups %q and %q", category, existing, group.Name)
+			}
+			g.of[key] = group.Name
+		}
+	}
+	return g, nil
+}
+
+// Of returns the group of category, or Ungrouped
+func (g *Groups) Of(category string) string {
+	if group, ok := g.of[strings.ToLower(category)]; ok {
+		return group
+	}
+	return Ungrouped
+}
+
+// Relabel returns copies of txns with each category replaced by its group,
+// so the usual per-category reports aggregate by group instead
+func (g *Groups) Relabel(txns []transaction.Transaction) []transaction.Transaction {
+	relabeled := make([]transaction.Transaction, len(txns))
+	for i, t := range txns {
+		t.Category = g.Of(t.Category)
+		relabeled[i] = t
+	}
+	return relabeled
+}
+
+// ByOwner returns copies of txns with each category replaced by the owner,
+// or Unassigned, so the usual per-category reports aggregate by household
+// member instead
+func ByOwner(txns []transaction.Transaction) []transaction.Transaction {
+	relabeled := make([]transaction.Transaction, len(txns))
+	for i, t := range txns {
+		t.Category = cmp.Or(t.Owner, Unassigned)
+		relabeled[i] = t
+	}
+	return relabeled
+}
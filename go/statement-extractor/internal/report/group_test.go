@@ -0,0 +1,58 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestGroups(t *testing.T) {
+	groups, err := NewGroups([]config.CategoryGroup{
+		{Name: "Essentials", Categories: []string{"Groceries", "Utilities"}},
+		{Name: "Discretionary", Categories: []string{"Dining out"}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Essentials", groups.Of("groceries"))
+	assert.Equal(t, "Discretionary", groups.Of("Dining out"))
+	assert.Equal(t, Ungrouped, groups.Of("Travel"))
+
+	txns := []transaction.Transaction{
+		{Category: "Groceries", Amount: -5000},
+		{Category: "Utilities", Amount: -12000},
+		{Category: "Travel", Amount: -30000},
+	}
+	relabeled := groups.Relabel(txns)
+	assert.Equal(t, "Groceries", txns[0].Category, "input is not modified")
+
+	agg := Aggregate(relabeled, SpanOf(relabeled))
+	assert.Equal(t, []string{"Essentials", Ungrouped}, agg.Categories())
+	assert.Equal(t, transaction.Amount(-17000), agg.Total("Essentials"))
+}
+
+func TestByOwner(t *testing.T) {
+	txns := []transaction.Transaction{
+		{Date: date("2024-01-15"), Category: "Groceries", Owner: "Sam", Amount: -10000},
+		{Date: date("2024-01-15"), Category: "Dining", Owner: "Sam", Amount: -2000},
+		{Date: date("2024-01-15"), Category: "Dining", Amount: -5000},
+	}
+	agg := Aggregate(ByOwner(txns), SpanOf(txns))
+	assert.Equal(t, []string{"Sam", Unassigned}, agg.Categories())
+	assert.Equal(t, transaction.Amount(-12000), agg.Total("Sam"))
+	assert.Equal(t, "Groceries", txns[0].Category, "input is not modified")
+}
+
+func TestNewGroups_Errors(t *testing.T) {
+	_, err := NewGroups([]config.CategoryGroup{
+		{Name: "Essentials", Categories: []string{"Groceries"}},
+		{Name: "Discretionary", Categories: []string{"groceries"}},
+	})
+	assert.EqualError(t, err, `category "groceries" is in both groups "Essentials" and "Discretionary"`)
+
+	_, err = NewGroups([]config.CategoryGroup{{Categories: []string{"Groceries"}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,171 @@
+package report
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/example/statement-extractor/internal/training"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// RecurringTag is the tag MarkRecurring adds to recurring payments
+const RecurringTag = "recurring"
+
+// Frequency is a regular interval between payments
+type Frequency struct {
+	Name     string
+	Days     int // the nominal interval
+	min, max int // the range of gaps in days accepted as this interval
+	months   int // the interval in calendar months, when it is one
+}
+
+// Frequencies are the intervals FindRecurring recognizes
+var Frequencies = []Frequency{
+	{Name: "weekly", Days: 7, min: 6, max: 8},
+	{Name: "fortnightly", Days: 14, min: 12, max: 16},
+	{Name: "monthly", Days: 30, min: 26, max: 35, months: 1},
+	{Name: "quarterly", Days: 91, min: 84, max: 98, months: 3},
+	{Name: "yearly", Days: 365, min: 350, max: 380, months: 12},
+}
+
+// After returns the date one interval after d
+func (f Frequency) After(d time.Time) time.Time {
+	if f.months > 0 {
+		return addMonths(d, f.months)
+	}
+	return d.AddDate(0, 0, f.Days)
+}
+
+// RecurringOptions tune FindRecurring; the zero value uses the defaults
+type RecurringOptions struct {
+	MinCount  int     // payments needed to call a series recurring; default 3
+	Tolerance float64 // how far each amount may be from the typical one, as a fraction of it; default 0.2
+}
+
+// DefaultRecurringOptions are the options used for zero fields
+var DefaultRecurringOptions = RecurringOptions{MinCount: 3, Tolerance: 0.2}
+
+// Recurring is a series of payments to one merchant of a similar amount at
+// a regular interval, such as a subscription
+type Recurring struct {
+	Merchant  string
+	Frequency Frequency
+	Amount    transaction.Amount // the median payment, negative like the debits
+	Count     int
+	Last      time.Time
+	Next      time.Time // when the next payment is expected
+
+	// Indexes are the positions of the payments in the transactions given
+	// to FindRecurring, in date order
+	Indexes []int
+}
+
+// Monthly returns the series' cost spread over a month
+func (r Recurring) Monthly() transaction.Amount {
+	perMonth := 365.25 / 12 / float64(r.Frequency.Days)
+	if r.Frequency.months > 0 {
+		perMonth = 1 / float64(r.Frequency.months)
+	}
+	return r.Amount.Mul(perMonth)
+}
+
+// FindRecurring finds the debits that recur: at least MinCount payments to
+// the same merchant, each within Tolerance of their median amount, with
+// every gap between them matching one of the Frequencies. Merchants are
+// compared on the Merchant name, or else the description, as normalized by
+// training.Normalize. Transactions excluded from reports are ignored. The
+// series are returned by merchant.
+func FindRecurring(txns []transaction.Transaction, opts RecurringOptions) []Recurring {
+	opts.MinCount = cmp.Or(opts.MinCount, DefaultRecurringOptions.MinCount)
+	opts.Tolerance = cmp.Or(opts.Tolerance, DefaultRecurringOptions.Tolerance)
+
+	byMerchant := make(map[string][]int)
+	var merchants []string
+	for i, t := range txns {
+		if t.Amount >= 0 || t.ExcludeFromReports {
+			continue
+		}
+		key := training.Normalize(cmp.Or(t.Merchant, t.Description))
+		if key == "" {
+			continue
+		}
+		if _, ok := byMerchant[key]; !ok {
+			merchants = append(merchants, key)
+		}
+		byMerchant[key] = append(byMerchant[key], i)
+	}
+
+	var found []Recurring
+	for _, key := range merchants {
+		indexes := byMerchant[key]
+		if len(indexes) < opts.MinCount {
+			continue
+		}
+		slices.SortStableFunc(indexes, func(a, b int) int { return txns[a].Date.Compare(txns[b].Date) })
+		if r, ok := recurring(txns, indexes, opts.Tolerance); ok {
+			found = append(found, r)
+		}
+	}
+	slices.SortFunc(found, func(a, b Recurring) int { return cmp.Compare(a.Merchant, b.Merchant) })
+	return found
+}
+
+// recurring checks one merchant's payments, in date order, for a series
+func recurring(txns []transaction.Transaction, indexes []int, tolerance float64) (Recurring, bool) {
+	amounts := make([]transaction.Amount, len(indexes))
+	gaps := make([]int, 0, len(indexes)-1)
+	for i, j := range indexes {
+		amounts[i] = txns[j].Amount
+		if i > 0 {
+			gaps = append(gaps, int(txns[j].Date.Sub(txns[indexes[i-1]].Date).Hours()/24+0.5))
+		}
+	}
+
+	typical := median(amounts)
+	for _, a := range amounts {
+		if (a - typical).Abs() > typical.Abs().Mul(tolerance) {
+			return Recurring{}, false
+		}
+	}
+
+	freq := slices.IndexFunc(Frequencies, func(f Frequency) bool {
+		return !slices.ContainsFunc(gaps, func(gap int) bool { return gap < f.min || gap > f.max })
+	})
+	if freq < 0 {
+		return Recurring{}, false
+	}
+
+	last := txns[indexes[len(indexes)-1]]
+	return Recurring{
+		Merchant:  cmp.Or(last.Merchant, last.Description),
+		Frequency: Frequencies[freq],
+		Amount:    typical,
+		Count:     len(indexes),
+		Last:      last.Date,
+		Next:      Frequencies[freq].After(last.Date),
+		Indexes:   indexes,
+	}, true
+}
+
+// median returns the middle value, or the mean of the middle two
+func median(values []transaction.Amount) transaction.Amount {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]).Mul(0.5)
+}
+
+// MarkRecurring tags the payments of each series with RecurringTag and
+// records the series' frequency and next expected date on them
+func MarkRecurring(txns []transaction.Transaction, series []Recurring) {
+	for _, r := range series {
+		for _, i := range r.Indexes {
+			txns[i].AddTag(RecurringTag)
+			txns[i].Recurrence = &transaction.Recurrence{Frequency: r.Frequency.Name, Next: r.Next}
+		}
+	}
+}
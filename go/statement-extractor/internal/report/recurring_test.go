@@ -0,0 +1,67 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestFindRecurring(t *testing.T) {
+	txns := []transaction.Transaction{
+		{Date: date("2024-01-31"), Description: "NETFLIX.COM 1234 MELBOURNE", Amount: -2299},
+		{Date: date("2024-01-03"), Description: "GYM DIRECT DEBIT", Amount: -2500},
+		{Date: date("2024-02-29"), Description: "NETFLIX.COM 5678 MELBOURNE", Amount: -2299},
+		{Date: date("2024-01-17"), Description: "GYM DIRECT DEBIT", Amount: -2500},
+		{Date: date("2024-03-31"), Description: "NETFLIX.COM 9012 MELBOURNE", Amount: -2499},
+		{Date: date("2024-01-31"), Description: "GYM DIRECT DEBIT", Amount: -2500},
+		{Date: date("2024-01-10"), Description: "WOOLWORTHS", Amount: -8000},
+		{Date: date("2024-02-10"), Description: "WOOLWORTHS", Amount: -24000},
+		{Date: date("2024-03-10"), Description: "WOOLWORTHS", Amount: -9500},
+		{Date: date("2024-01-05"), Description: "CAFE", Amount: -500, Merchant: "Cafe"},
+		{Date: date("2024-01-06"), Description: "CAFE", Amount: -500, Merchant: "Cafe"},
+		{Date: date("2024-03-01"), Description: "CAFE", Amount: -500, Merchant: "Cafe"},
+		{Date: date("2024-01-15"), Description: "SALARY", Amount: 300000},
+		{Date: date("2024-02-15"), Description: "SALARY", Amount: 300000},
+		{Date: date("2024-03-15"), Description: "SALARY", Amount: 300000},
+	}
+
+	series := FindRecurring(txns, RecurringOptions{})
+	require.Len(t, series, 2, "irregular amounts, irregular gaps and credits are not recurring")
+
+	gym := series[0]
+	assert.Equal(t, "GYM DIRECT DEBIT", gym.Merchant)
+	assert.Equal(t, "fortnightly", gym.Frequency.Name)
+	assert.Equal(t, date("2024-02-14"), gym.Next)
+	assert.Equal(t, []int{1, 3, 5}, gym.Indexes, "payments are in date order")
+
+	netflix := series[1]
+	assert.Equal(t, "monthly", netflix.Frequency.Name)
+	assert.Equal(t, 3, netflix.Count)
+	assert.Equal(t, transaction.Amount(-2299), netflix.Amount, "the typical amount is the median")
+	assert.Equal(t, date("2024-04-30"), netflix.Next, "months are calendar months")
+	assert.Equal(t, transaction.Amount(-2299), netflix.Monthly())
+	assert.Equal(t, transaction.Amount(-5435), gym.Monthly())
+
+	assert.Empty(t, FindRecurring(txns, RecurringOptions{MinCount: 4}))
+	assert.Len(t, FindRecurring(txns, RecurringOptions{Tolerance: 0.01}), 1, "the price rise is outside the tolerance")
+}
+
+func TestMarkRecurring(t *testing.T) {
+	txns := []transaction.Transaction{
+		{Date: date("2024-01-01"), Description: "SPOTIFY", Amount: -1299, Tags: []string{"Recurring"}},
+		{Date: date("2024-02-01"), Description: "SPOTIFY", Amount: -1299},
+		{Date: date("2024-03-01"), Description: "SPOTIFY", Amount: -1299},
+		{Date: date("2024-03-02"), Description: "BUNNINGS", Amount: -4000},
+	}
+	MarkRecurring(txns, FindRecurring(txns, RecurringOptions{}))
+
+	assert.Equal(t, []string{"Recurring"}, txns[0].Tags, "the tag is not added twice")
+	assert.Equal(t, []string{RecurringTag}, txns[2].Tags)
+	require.NotNil(t, txns[1].Recurrence)
+	assert.Equal(t, transaction.Recurrence{Frequency: "monthly", Next: date("2024-04-01")}, *txns[1].Recurrence)
+	assert.Empty(t, txns[3].Tags)
+	assert.Nil(t, txns[3].Recurrence)
+}
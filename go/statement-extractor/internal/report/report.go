@@ -0,0 +1,210 @@
+// Package report aggregates categorized transactions into per-month,
+// per-category totals that reporting commands build on.
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+const (
+	monthFormat = "2006-01"
+	dateFormat  = "2006-01-02"
+)
+
+// Period is an inclusive range of whole calendar months
+type Period struct {
+	Start time.Time // first day of the first month
+	End   time.Time // first day of the month after the last month
+}
+
+// ParsePeriod accepts a year ("2024"), a month ("2024-06") or a month range
+// ("2024-01:2024-06")
+func ParsePeriod(s string) (Period, error) {
+	if from, to, ok := strings.Cut(s, ":"); ok {
+		start, err := time.Parse(monthFormat, from)
+		if err != nil {
+			return Period{}, fmt.Errorf("invalid period start %q: %w", from, err)
+		}
+		end, err := time.Parse(monthFormat, to)
+		if err != nil {
+			return Period{}, fmt.Errorf("invalid period end %q: %w", to, err)
+		}
+		if end.Before(start) {
+			return Period{}, fmt.Errorf("invalid period %q: end before start", s)
+		}
+		return Period{Start: start, End: end.AddDate(0, 1, 0)}, nil
+	}
+
+	if month, err := time.Parse(monthFormat, s); err == nil {
+		return Period{Start: month, End: month.AddDate(0, 1, 0)}, nil
+	}
+	if year, err := time.Parse("2006", s); err == nil {
+		return Period{Start: year, End: year.AddDate(1, 0, 0)}, nil
+	}
+	return Period{}, fmt.Errorf("invalid period %q: expected YYYY, YYYY-MM or YYYY-MM:YYYY-MM", s)
+}
+
+// Contains reports whether t falls within the period
+func (p Period) Contains(t time.Time) bool {
+	return !t.Before(p.Start) && t.Before(p.End)
+}
+
+// Months returns the number of calendar months covered by the period
+func (p Period) Months() int {
+	return (p.End.Year()-p.Start.Year())*12 + int(p.End.Month()-p.Start.Month())
+}
+
+// MonthLabels returns each month in the period as YYYY-MM, the keys used by
+// Aggregation.MonthTotal
+func (p Period) MonthLabels() []string {
+	labels := make([]string, 0, p.Months())
+	for m := p.Start; m.Before(p.End); m = m.AddDate(0, 1, 0) {
+		labels = append(labels, m.Format(monthFormat))
+	}
+	return labels
+}
+
+// String formats the period in the form accepted by ParsePeriod
+func (p Period) String() string {
+	last := p.End.AddDate(0, -1, 0)
+	if p.Months() == 1 {
+		return p.Start.Format(monthFormat)
+	}
+	return p.Start.Format(monthFormat) + ":" + last.Format(monthFormat)
+}
+
+// WeekStart returns the first day of the week containing t, for weeks
+// starting on first
+func WeekStart(t time.Time, first time.Weekday) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -((7 + int(day.Weekday()) - int(first)) % 7))
+}
+
+// Weeks returns the first day of each week starting on first that overlaps
+// the period
+func (p Period) Weeks(first time.Weekday) []time.Time {
+	var weeks []time.Time
+	for w := WeekStart(p.Start, first); w.Before(p.End); w = w.AddDate(0, 0, 7) {
+		weeks = append(weeks, w)
+	}
+	return weeks
+}
+
+// SpanOf returns the smallest period covering every transaction date
+func SpanOf(txns []transaction.Transaction) Period {
+	var first, last time.Time
+	for _, t := range txns {
+		if first.IsZero() || t.Date.Before(first) {
+			first = t.Date
+		}
+		if last.IsZero() || t.Date.After(last) {
+			last = t.Date
+		}
+	}
+	start := time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(last.Year(), last.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+	return Period{Start: start, End: end}
+}
+
+// Aggregation holds net amounts per category and month within a period.
+// Amounts keep the transaction sign: spending is negative, income positive.
+type Aggregation struct {
+	Period Period
+	totals map[string]map[string]transaction.Amount // category -> month, or week start date -> net amount
+	weeks  int                                      // weeks covered, when aggregated by week
+}
+
+// Aggregate totals the transactions falling within period, skipping those
+// excluded from reports
+func Aggregate(txns []transaction.Transaction, period Period) *Aggregation {
+	a := &Aggregation{
+		Period: period,
+		totals: make(map[string]map[string]transaction.Amount),
+	}
+	for _, t := range txns {
+		if !period.Contains(t.Date) || t.ExcludeFromReports {
+			continue
+		}
+		month := t.Date.Format(monthFormat)
+		if a.totals[t.Category] == nil {
+			a.totals[t.Category] = make(map[string]transaction.Amount)
+		}
+		a.totals[t.Category][month] += t.Amount
+	}
+	return a
+}
+
+// AggregateWeeks is Aggregate by week, for weeks starting on first. The
+// first and last weeks only count the days within the period.
+func AggregateWeeks(txns []transaction.Transaction, period Period, first time.Weekday) *Aggregation {
+	a := &Aggregation{
+		Period: period,
+		totals: make(map[string]map[string]transaction.Amount),
+		weeks:  len(period.Weeks(first)),
+	}
+	for _, t := range txns {
+		if !period.Contains(t.Date) || t.ExcludeFromReports {
+			continue
+		}
+		week := WeekStart(t.Date, first).Format(dateFormat)
+		if a.totals[t.Category] == nil {
+			a.totals[t.Category] = make(map[string]transaction.Amount)
+		}
+		a.totals[t.Category][week] += t.Amount
+	}
+	return a
+}
+
+// Categories returns the categories seen, sorted by name
+func (a *Aggregation) Categories() []string {
+	categories := make([]string, 0, len(a.totals))
+	for category := range a.totals {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// MonthTotal returns the net amount for category in month (YYYY-MM)
+func (a *Aggregation) MonthTotal(category, month string) transaction.Amount {
+	return a.totals[category][month]
+}
+
+// WeekTotal returns the net amount for category in the week starting on
+// week, from an aggregation by week
+func (a *Aggregation) WeekTotal(category string, week time.Time) transaction.Amount {
+	return a.totals[category][week.Format(dateFormat)]
+}
+
+// Total returns the net amount for category across the period
+func (a *Aggregation) Total(category string) transaction.Amount {
+	var total transaction.Amount
+	for _, amount := range a.totals[category] {
+		total += amount
+	}
+	return total
+}
+
+// MonthlyAverage returns the category total spread over every month of the
+// period, including months without transactions
+func (a *Aggregation) MonthlyAverage(category string) float64 {
+	months := a.Period.Months()
+	if months == 0 {
+		return 0
+	}
+	return a.Total(category).Float() / float64(months)
+}
+
+// WeeklyAverage returns the category total spread over every week of an
+// aggregation by week
+func (a *Aggregation) WeeklyAverage(category string) float64 {
+	if a.weeks == 0 {
+		return 0
+	}
+	return a.Total(category).Float() / float64(a.weeks)
+}
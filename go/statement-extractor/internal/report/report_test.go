@@ -0,0 +1,141 @@
+package report
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func date(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func sampleTransactions() []transaction.Transaction {
+	return []transaction.Transaction{
+		{Date: date("2024-01-05"), Description: "SALARY", Amount: 500000, Category: "Income"},
+		{Date: date("2024-01-10"), Description: "WOOLWORTHS", Amount: -20000, Category: "Groceries & household"},
+		{Date: date("2024-02-10"), Description: "WOOLWORTHS", Amount: -10000, Category: "Groceries & household"},
+		{Date: date("2024-01-12"), Description: "NETFLIX", Amount: -2000, Category: "Entertainment"},
+		{Date: date("2024-02-12"), Description: "NETFLIX", Amount: -2000, Category: "Entertainment"},
+		{Date: date("2024-02-14"), Description: "CINEMA", Amount: -4000, Category: "Entertainment"},
+		{Date: date("2024-03-01"), Description: "COLES", Amount: -6000, Category: "Groceries & household"},
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	tests := []struct {
+		input  string
+		start  string
+		months int
+		str    string
+	}{
+		{"2024", "2024-01-01", 12, "2024-01:2024-12"},
+		{"2024-06", "2024-06-01", 1, "2024-06"},
+		{"2024-11:2025-02", "2024-11-01", 4, "2024-11:2025-02"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p, err := ParsePeriod(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, date(tt.start), p.Start)
+			assert.Equal(t, tt.months, p.Months())
+			assert.Equal(t, tt.str, p.String())
+		})
+	}
+
+	for _, invalid := range []string{"", "24", "2024-13", "2024-06:2024-01"} {
+		_, err := ParsePeriod(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	period, err := ParsePeriod("2024-01:2024-02")
+	require.NoError(t, err)
+
+	txns := append(sampleTransactions(), transaction.Transaction{
+		Date: date("2024-01-20"), Amount: -5000000, Category: "Groceries & household", ExcludeFromReports: true,
+	})
+	agg := Aggregate(txns, period)
+
+	assert.Equal(t, []string{"Entertainment", "Groceries & household", "Income"}, agg.Categories())
+	assert.Equal(t, transaction.Amount(-30000), agg.Total("Groceries & household"))
+	assert.Equal(t, -150.0, agg.MonthlyAverage("Groceries & household"))
+	assert.Equal(t, transaction.Amount(-6000), agg.MonthTotal("Entertainment", "2024-02"))
+	assert.Zero(t, agg.Total("Unknown"))
+}
+
+func TestAggregateWeeks(t *testing.T) {
+	period, err := ParsePeriod("2024-02")
+	require.NoError(t, err)
+
+	// 1 February 2024 is a Thursday
+	assert.Equal(t, date("2024-01-29"), WeekStart(date("2024-02-01"), time.Monday))
+	assert.Equal(t, date("2024-01-28"), WeekStart(date("2024-02-01"), time.Sunday))
+	assert.Equal(t, date("2024-02-11"), WeekStart(date("2024-02-11"), time.Sunday))
+
+	weeks := period.Weeks(time.Sunday)
+	assert.Len(t, weeks, 5)
+	assert.Equal(t, date("2024-01-28"), weeks[0])
+
+	// NETFLIX and CINEMA fall on Monday 12 and Wednesday 14 February: the
+	// same week either way, but the CINEMA week begins on a different day
+	sunday := AggregateWeeks(sampleTransactions(), period, time.Sunday)
+	assert.Equal(t, transaction.Amount(-6000), sunday.WeekTotal("Entertainment", date("2024-02-11")))
+	assert.Equal(t, transaction.Amount(-10000), sunday.WeekTotal("Groceries & household", date("2024-02-04")))
+	assert.Equal(t, -20.0, sunday.WeeklyAverage("Groceries & household"))
+
+	monday := AggregateWeeks(sampleTransactions(), period, time.Monday)
+	assert.Equal(t, transaction.Amount(-10000), monday.WeekTotal("Groceries & household", date("2024-02-05")))
+	assert.Zero(t, monday.WeekTotal("Groceries & household", date("2024-02-04")))
+}
+
+func TestPeriodMonthLabels(t *testing.T) {
+	period, err := ParsePeriod("2023-11:2024-02")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2023-11", "2023-12", "2024-01", "2024-02"}, period.MonthLabels())
+}
+
+func TestSpanOf(t *testing.T) {
+	span := SpanOf(sampleTransactions())
+	assert.Equal(t, "2024-01:2024-03", span.String())
+}
+
+func TestParseCut(t *testing.T) {
+	cut, err := ParseCut("Food & dining=20%")
+	require.NoError(t, err)
+	assert.Equal(t, Cut{Category: "Food & dining", Fraction: 0.2}, cut)
+
+	cut, err = ParseCut("Travel=0.5")
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, cut.Fraction)
+
+	for _, invalid := range []string{"Travel", "=10%", "Travel=abc", "Travel=150%"} {
+		_, err := ParseCut(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	period, err := ParsePeriod("2024-01:2024-02")
+	require.NoError(t, err)
+
+	sim := Simulate(sampleTransactions(), period, Scenario{
+		Cuts:    []Cut{{Category: "groceries & household", Fraction: 0.5}},
+		Removed: []*regexp.Regexp{regexp.MustCompile("NETFLIX")},
+	})
+
+	require.Len(t, sim.Lines, 2, "income is not part of the simulation")
+	assert.Equal(t, SimulationLine{Category: "Entertainment", Actual: 40, Projected: 20}, sim.Lines[0])
+	assert.Equal(t, SimulationLine{Category: "Groceries & household", Actual: 150, Projected: 75}, sim.Lines[1])
+	assert.Equal(t, 95.0, sim.MonthlySavings())
+}
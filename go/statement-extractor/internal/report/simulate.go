@@ -0,0 +1,121 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Cut is a hypothetical reduction of spending in one category
+type Cut struct {
+	Category string
+	Fraction float64 // 0.2 removes 20% of the category's spending
+}
+
+// ParseCut parses "Category=20%" (or "Category=0.2")
+func ParseCut(s string) (Cut, error) {
+	category, value, ok := strings.Cut(s, "=")
+	category = strings.TrimSpace(category)
+	if !ok || category == "" {
+		return Cut{}, fmt.Errorf("invalid cut %q: expected Category=N%%", s)
+	}
+
+	value = strings.TrimSpace(value)
+	percent := strings.HasSuffix(value, "%")
+	fraction, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil {
+		return Cut{}, fmt.Errorf("invalid cut %q: %w", s, err)
+	}
+	if percent {
+		fraction /= 100
+	}
+	if fraction < 0 || fraction > 1 {
+		return Cut{}, fmt.Errorf("invalid cut %q: reduction must be between 0%% and 100%%", s)
+	}
+
+	return Cut{Category: category, Fraction: fraction}, nil
+}
+
+// Scenario describes the hypothetical changes to apply
+type Scenario struct {
+	Cuts    []Cut
+	Removed []*regexp.Regexp // descriptions dropped entirely, e.g. cancelled subscriptions
+}
+
+// SimulationLine compares actual and projected monthly spending for a category
+type SimulationLine struct {
+	Category  string
+	Actual    float64 // average monthly spending, as a positive amount
+	Projected float64
+}
+
+// Savings returns the projected monthly reduction in spending
+func (l SimulationLine) Savings() float64 {
+	return l.Actual - l.Projected
+}
+
+// Simulation is the result of applying a scenario to a period
+type Simulation struct {
+	Period Period
+	Lines  []SimulationLine
+}
+
+// MonthlySavings returns the projected reduction across all categories
+func (s *Simulation) MonthlySavings() float64 {
+	var total float64
+	for _, line := range s.Lines {
+		total += line.Savings()
+	}
+	return total
+}
+
+// Simulate re-aggregates spending over period with the scenario applied.
+// Only debits are affected; income and refunds are left as they are.
+func Simulate(txns []transaction.Transaction, period Period, scenario Scenario) *Simulation {
+	cuts := make(map[string]float64, len(scenario.Cuts))
+	for _, cut := range scenario.Cuts {
+		cuts[strings.ToLower(cut.Category)] = cut.Fraction
+	}
+
+	var spending, projected []transaction.Transaction
+	for _, t := range txns {
+		if t.Amount >= 0 {
+			continue
+		}
+		spending = append(spending, t)
+
+		if removed(t.Description, scenario.Removed) {
+			continue
+		}
+		if fraction, ok := cuts[strings.ToLower(t.Category)]; ok {
+			t.Amount = t.Amount.Mul(1 - fraction)
+		}
+		projected = append(projected, t)
+	}
+
+	actual := Aggregate(spending, period)
+	after := Aggregate(projected, period)
+
+	sim := &Simulation{Period: period}
+	for _, category := range actual.Categories() {
+		sim.Lines = append(sim.Lines, SimulationLine{
+			Category:  category,
+			Actual:    math.Abs(actual.MonthlyAverage(category)),
+			Projected: math.Abs(after.MonthlyAverage(category)),
+		})
+	}
+	return sim
+}
+
+func removed(description string, patterns []*regexp.Regexp) bool {
+	for _, re := range patterns {
+		if re.MatchString(description) {
+			return true
+		}
+	}
+	return false
+}
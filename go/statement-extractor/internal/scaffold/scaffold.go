@@ -0,0 +1,130 @@
+// Package scaffold generates the skeleton of a new built-in content parser:
+// the parser, its golden test and fixture, and the [parsers] config stanza,
+// so adding a bank starts from working code.
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/example/statement-extractor/internal/parser"
+)
+
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+// Parser describes the parser to generate
+type Parser struct {
+	Name string // the [parsers] key and Go type, e.g. "mybank"
+	Bank string // the bank's name as it appears on statements; default Name upper-cased
+}
+
+// File is a generated file, relative to the module root
+type File struct {
+	Path    string
+	Content []byte
+}
+
+// validName is a name usable as both a config key and a Go identifier
+var validName = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+
+// Files renders the parser's source, test, fixture and golden file
+func Files(p Parser) ([]File, error) {
+	if !validName.MatchString(p.Name) {
+		return nil, fmt.Errorf("invalid parser name %q: use lower-case letters and digits, starting with a letter", p.Name)
+	}
+	if _, ok := parser.Builtin(p.Name); ok {
+		return nil, fmt.Errorf("parser %q already exists", p.Name)
+	}
+	if strings.ContainsAny(p.Bank, "`\"\n") {
+		return nil, fmt.Errorf("invalid bank name %q", p.Bank)
+	}
+
+	dir := filepath.Join("internal", "parser")
+	testdata := filepath.Join(dir, "testdata", p.Name)
+	layout := []struct{ template, path string }{
+		{"parser.go.tmpl", filepath.Join(dir, p.Name+".go")},
+		{"parser_test.go.tmpl", filepath.Join(dir, p.Name+"_test.go")},
+		{"statement.txt.tmpl", filepath.Join(testdata, "statement.txt")},
+		{"transactions.golden.json.tmpl", filepath.Join(testdata, "transactions.golden.json")},
+	}
+
+	files := make([]File, 0, len(layout))
+	for _, l := range layout {
+		content, err := render(l.template, p)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(l.path, ".go") {
+			if content, err = format.Source(content); err != nil {
+				return nil, fmt.Errorf("formatting %s: %w", l.path, err)
+			}
+		}
+		files = append(files, File{Path: l.path, Content: content})
+	}
+	return files, nil
+}
+
+// Config renders the [parsers] stanza for the parser, with a prompt
+// describing the sample layout for sending its statements to a PDF service
+// until the content parser is written
+func Config(p Parser) (string, error) {
+	content, err := render("config.toml.tmpl", p)
+	return string(content), err
+}
+
+// Write writes the files under root, refusing to replace existing files
+// unless force is set
+func Write(root string, files []File, force bool) error {
+	if !force {
+		for _, f := range files {
+			if _, err := os.Stat(filepath.Join(root, f.Path)); err == nil {
+				return fmt.Errorf("%s already exists; use --force to replace it", f.Path)
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+	}
+	for _, f := range files {
+		path := filepath.Join(root, f.Path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := os.WriteFile(path, f.Content, 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.Path, err)
+		}
+	}
+	return nil
+}
+
+func render(name string, p Parser) ([]byte, error) {
+	tmpl, err := template.ParseFS(templates, "templates/"+name)
+	if err != nil {
+		return nil, err
+	}
+	bank := p.Bank
+	if bank == "" {
+		bank = strings.ToUpper(p.Name)
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]string{
+		"Name":   p.Name,
+		"Title":  strings.ToUpper(p.Name[:1]) + p.Name[1:],
+		"Source": strings.ToUpper(p.Name),
+		"Bank":   bank,
+		"Detect": regexp.QuoteMeta(bank),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rendering %s: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
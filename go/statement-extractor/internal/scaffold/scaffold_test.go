@@ -0,0 +1,65 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFiles(t *testing.T) {
+	files, err := Files(Parser{Name: "mybank", Bank: "My Bank (AU)"})
+	require.NoError(t, err)
+
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	assert.Equal(t, []string{
+		filepath.Join("internal", "parser", "mybank.go"),
+		filepath.Join("internal", "parser", "mybank_test.go"),
+		filepath.Join("internal", "parser", "testdata", "mybank", "statement.txt"),
+		filepath.Join("internal", "parser", "testdata", "mybank", "transactions.golden.json"),
+	}, paths)
+
+	source := string(files[0].Content)
+	assert.Contains(t, source, `builtin["mybank"] = newMybank()`)
+	assert.Contains(t, source, `regexp.MustCompile(`+"`(?i)My Bank \\(AU\\)`"+`)`, "the bank name is matched literally")
+	assert.Contains(t, source, `func (p *mybank) Name() string { return "MYBANK" }`)
+	assert.Contains(t, string(files[1].Content), "func TestMybankParse(t *testing.T)")
+	assert.Contains(t, string(files[2].Content), "My Bank (AU) EVERYDAY ACCOUNT STATEMENT")
+	assert.Contains(t, string(files[3].Content), `"source": "MYBANK"`)
+
+	stanza, err := Config(Parser{Name: "mybank"})
+	require.NoError(t, err)
+	assert.Contains(t, stanza, "[parsers.mybank]\n  method = \"content\"")
+	assert.Contains(t, stanza, "# This is a MYBANK account statement.", "the prompt names the bank")
+	assert.Contains(t, stanza, `{{ or .Currency "the account's currency" }}`, "the prompt's template actions are kept")
+}
+
+func TestFilesInvalid(t *testing.T) {
+	for _, name := range []string{"", "MyBank", "my-bank", "1bank"} {
+		_, err := Files(Parser{Name: name})
+		assert.ErrorContains(t, err, "invalid parser name", name)
+	}
+	_, err := Files(Parser{Name: "cba"})
+	assert.ErrorContains(t, err, `parser "cba" already exists`)
+	_, err = Files(Parser{Name: "mybank", Bank: "My `Bank`"})
+	assert.ErrorContains(t, err, "invalid bank name")
+}
+
+func TestWrite(t *testing.T) {
+	root := t.TempDir()
+	files := []File{{Path: filepath.Join("internal", "parser", "testdata", "mybank", "statement.txt"), Content: []byte("one")}}
+	require.NoError(t, Write(root, files, false))
+
+	files[0].Content = []byte("two")
+	assert.ErrorContains(t, Write(root, files, false), "already exists")
+	require.NoError(t, Write(root, files, true))
+
+	data, err := os.ReadFile(filepath.Join(root, files[0].Path))
+	require.NoError(t, err)
+	assert.Equal(t, "two", string(data))
+}
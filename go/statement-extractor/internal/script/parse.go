@@ -0,0 +1,344 @@
+package script
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// token is a lexical token; kind is one of the token kinds below, or the
+// operator itself
+type token struct {
+	kind string
+	text string
+	pos  int // 1-based column
+}
+
+const (
+	tokNumber = "number"
+	tokString = "string"
+	tokIdent  = "identifier"
+	tokEOF    = "end of script"
+)
+
+// operators are matched longest first
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/", "?", ":", "(", ")", ",", ";"}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case isDigit(src[i]) || src[i] == '.' && i+1 < len(src) && isDigit(src[i+1]):
+			start := i
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[start:i], pos: start + 1})
+		case isLetter(src[i]):
+			start := i
+			for i < len(src) && (isLetter(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[start:i], pos: start + 1})
+		case c == '\'' || c == '"':
+			start := i
+			var s strings.Builder
+			for i++; ; i++ {
+				if i >= len(src) {
+					return nil, fmt.Errorf("column %d: unterminated string", start+1)
+				}
+				if src[i] == byte(c) {
+					i++
+					break
+				}
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+				}
+				s.WriteByte(src[i])
+			}
+			tokens = append(tokens, token{kind: tokString, text: s.String(), pos: start + 1})
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("column %d: unexpected %q", i+1, c)
+			}
+			tokens = append(tokens, token{kind: op, text: op, pos: i + 1})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src) + 1}), nil
+}
+
+func isLetter(c byte) bool { return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// parser builds the syntax tree of a script by recursive descent:
+//
+//	script  = expr { ";" expr } [ ";" ]
+//	expr    = or [ "?" expr ":" expr ]
+//	or      = and { "||" and }
+//	and     = compare { "&&" compare }
+//	compare = sum [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" ) sum ]
+//	sum     = product { ( "+" | "-" ) product }
+//	product = unary { ( "*" | "/" ) unary }
+//	unary   = ( "!" | "-" ) unary | primary
+//	primary = number | string | "true" | "false" | name | name "(" [ expr { "," expr } ] ")" | "(" script ")"
+type parser struct {
+	tokens []token
+	next   int
+}
+
+func parse(src string) (node, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	n, err := p.script()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.unexpected(t)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token { return p.tokens[p.next] }
+
+func (p *parser) take() token {
+	t := p.tokens[p.next]
+	if t.kind != tokEOF {
+		p.next++
+	}
+	return t
+}
+
+// accept takes the next token if it is one of kinds
+func (p *parser) accept(kinds ...string) (token, bool) {
+	t := p.peek()
+	for _, k := range kinds {
+		if t.kind == k {
+			return p.take(), true
+		}
+	}
+	return t, false
+}
+
+func (p *parser) expect(kind string) error {
+	if t, ok := p.accept(kind); !ok {
+		return fmt.Errorf("column %d: expected %q, found %s", t.pos, kind, describe(t))
+	}
+	return nil
+}
+
+func (p *parser) unexpected(t token) error {
+	return fmt.Errorf("column %d: unexpected %s", t.pos, describe(t))
+}
+
+func describe(t token) string {
+	switch t.kind {
+	case tokEOF:
+		return t.kind
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+func (p *parser) script() (node, error) {
+	var steps []node
+	for {
+		n, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, n)
+		if _, ok := p.accept(";"); !ok {
+			break
+		}
+		if k := p.peek().kind; k == tokEOF || k == ")" {
+			break
+		}
+	}
+	if len(steps) == 1 {
+		return steps[0], nil
+	}
+	return sequence(steps), nil
+}
+
+func (p *parser) expr() (node, error) {
+	test, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.accept("?"); !ok {
+		return test, nil
+	}
+	yes, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	no, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	return conditional{test: test, yes: yes, no: no}, nil
+}
+
+func (p *parser) or() (node, error) {
+	return p.logical(p.and, "||")
+}
+
+func (p *parser) and() (node, error) {
+	return p.logical(p.compare, "&&")
+}
+
+func (p *parser) logical(operand func() (node, error), op string) (node, error) {
+	x, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept(op); !ok {
+			return x, nil
+		}
+		y, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		x = logical{op: op, x: x, y: y}
+	}
+}
+
+func (p *parser) compare() (node, error) {
+	x, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	t, ok := p.accept("==", "!=", "<", "<=", ">", ">=")
+	if !ok {
+		return x, nil
+	}
+	y, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	return binary{op: t.kind, pos: t.pos, x: x, y: y}, nil
+}
+
+func (p *parser) sum() (node, error) {
+	return p.arithmetic(p.product, "+", "-")
+}
+
+func (p *parser) product() (node, error) {
+	return p.arithmetic(p.unary, "*", "/")
+}
+
+func (p *parser) arithmetic(operand func() (node, error), ops ...string) (node, error) {
+	x, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.accept(ops...)
+		if !ok {
+			return x, nil
+		}
+		y, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		x = binary{op: t.kind, pos: t.pos, x: x, y: y}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	t, ok := p.accept("!", "-")
+	if !ok {
+		return p.primary()
+	}
+	x, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return unary{op: t.kind, pos: t.pos, x: x}, nil
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.take()
+	switch t.kind {
+	case tokNumber:
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("column %d: invalid number %q", t.pos, t.text)
+		}
+		return literal{v}, nil
+	case tokString:
+		return literal{t.text}, nil
+	case "(":
+		n, err := p.script()
+		if err != nil {
+			return nil, err
+		}
+		return n, p.expect(")")
+	case tokIdent:
+		if _, ok := p.accept("("); ok {
+			return p.call(t)
+		}
+		switch t.text {
+		case "true":
+			return literal{true}, nil
+		case "false":
+			return literal{false}, nil
+		}
+		if _, ok := variables[t.text]; !ok {
+			return nil, fmt.Errorf("column %d: unknown variable %q", t.pos, t.text)
+		}
+		return variable(t.text), nil
+	}
+	return nil, p.unexpected(t)
+}
+
+// call parses the arguments of a call to the function named by name, whose
+// opening parenthesis has been taken
+func (p *parser) call(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("column %d: unknown function %q", name.pos, name.text)
+	}
+	var args []node
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if _, ok := p.accept(","); !ok {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	if len(args) != len(fn.params) {
+		return nil, fmt.Errorf("column %d: %s takes %d arguments, got %d", name.pos, name.text, len(fn.params), len(args))
+	}
+	return call{name: name.text, pos: name.pos, fn: fn, args: args}, nil
+}
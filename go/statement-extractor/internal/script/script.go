@@ -0,0 +1,417 @@
+// Package script runs [[transforms]]: small expressions evaluated against
+// each transaction for rules the declarative schema cannot express, such as
+//
+//	amount < 0 && contains(desc, 'FEE') ? set_category('Bank fees') : skip()
+//
+// Scripts read the transaction's fields as variables and change it by
+// calling actions. Steps separated by ";" run in order, and the branches of
+// "?:", "&&" and "||" are only evaluated when they are taken.
+package script
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// CategorizedBy is the CategorizedBy value for a category set by the
+// transform named name
+func CategorizedBy(name string) string {
+	return "script:" + name
+}
+
+// Program is a compiled script
+type Program struct {
+	name string
+	root node
+}
+
+// Compile parses a script; name identifies it in errors and in the
+// CategorizedBy of the transactions it categorizes
+func Compile(name, src string) (*Program, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, errors.New("empty script")
+	}
+	root, err := parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{name: name, root: root}, nil
+}
+
+// Run evaluates the script against t, which its actions change
+func (p *Program) Run(t *transaction.Transaction) error {
+	_, err := p.root.eval(&env{t: t, name: p.name})
+	return err
+}
+
+// Transforms are the compiled [[transforms]], run in order
+type Transforms struct {
+	programs []*Program
+}
+
+// New compiles the transforms; unnamed ones are named by position
+func New(rules []config.TransformRule) (*Transforms, error) {
+	ts := &Transforms{}
+	for i, r := range rules {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("%d", i+1)
+		}
+		p, err := Compile(name, r.Transform)
+		if err != nil {
+			return nil, fmt.Errorf("transform %s: %w", name, err)
+		}
+		ts.programs = append(ts.programs, p)
+	}
+	return ts, nil
+}
+
+// Apply runs every transform against each transaction
+func (ts *Transforms) Apply(txns []transaction.Transaction) error {
+	for i := range txns {
+		for _, p := range ts.programs {
+			if err := p.Run(&txns[i]); err != nil {
+				return fmt.Errorf("transform %s on %q: %w", p.name, txns[i].Description, err)
+			}
+		}
+	}
+	return nil
+}
+
+// env is what a script runs against
+type env struct {
+	t    *transaction.Transaction
+	name string
+}
+
+// node is a node of the syntax tree. Values are float64, string, bool, or
+// nil for actions.
+type node interface {
+	eval(e *env) (any, error)
+}
+
+type literal struct{ v any }
+
+func (n literal) eval(*env) (any, error) { return n.v, nil }
+
+type variable string
+
+func (n variable) eval(e *env) (any, error) { return variables[string(n)](e.t), nil }
+
+// balance is a transaction's balance, 0 when the statement shows none
+func balance(t *transaction.Transaction) float64 {
+	if t.Balance == nil {
+		return 0
+	}
+	return t.Balance.Float()
+}
+
+// variables are the transaction fields scripts can read
+var variables = map[string]func(t *transaction.Transaction) any{
+	"amount":          func(t *transaction.Transaction) any { return t.Amount.Float() },
+	"balance":         func(t *transaction.Transaction) any { return balance(t) },
+	"desc":            func(t *transaction.Transaction) any { return t.Description },
+	"description":     func(t *transaction.Transaction) any { return t.Description },
+	"raw_description": func(t *transaction.Transaction) any { return t.RawDescription },
+	"merchant":        func(t *transaction.Transaction) any { return t.Merchant },
+	"category":        func(t *transaction.Transaction) any { return t.Category },
+	"source":          func(t *transaction.Transaction) any { return t.Source },
+	"account":         func(t *transaction.Transaction) any { return t.Account },
+	"owner":           func(t *transaction.Transaction) any { return t.Owner },
+	"card":            func(t *transaction.Transaction) any { return t.Card },
+	"currency":        func(t *transaction.Transaction) any { return t.Currency },
+	"date":            func(t *transaction.Transaction) any { return t.Date.Format("2006-01-02") },
+}
+
+type sequence []node
+
+func (n sequence) eval(e *env) (any, error) {
+	var v any
+	for _, step := range n {
+		var err error
+		if v, err = step.eval(e); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+type conditional struct{ test, yes, no node }
+
+func (n conditional) eval(e *env) (any, error) {
+	test, err := evalBool(e, n.test, "?:")
+	if err != nil {
+		return nil, err
+	}
+	if test {
+		return n.yes.eval(e)
+	}
+	return n.no.eval(e)
+}
+
+type logical struct {
+	op   string
+	x, y node
+}
+
+func (n logical) eval(e *env) (any, error) {
+	x, err := evalBool(e, n.x, n.op)
+	if err != nil {
+		return nil, err
+	}
+	if x == (n.op == "||") {
+		return x, nil
+	}
+	return evalBool(e, n.y, n.op)
+}
+
+// evalBool evaluates an operand that must be a boolean
+func evalBool(e *env, n node, op string) (bool, error) {
+	v, err := n.eval(e)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s needs a boolean, got %s", op, typeName(v))
+	}
+	return b, nil
+}
+
+type unary struct {
+	op  string
+	pos int
+	x   node
+}
+
+func (n unary) eval(e *env) (any, error) {
+	v, err := n.x.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	switch x := v.(type) {
+	case bool:
+		if n.op == "!" {
+			return !x, nil
+		}
+	case float64:
+		if n.op == "-" {
+			return -x, nil
+		}
+	}
+	return nil, fmt.Errorf("column %d: cannot apply %s to %s", n.pos, n.op, typeName(v))
+}
+
+type binary struct {
+	op   string
+	pos  int
+	x, y node
+}
+
+func (n binary) eval(e *env) (any, error) {
+	x, err := n.x.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	y, err := n.y.eval(e)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return x == y, nil
+	case "!=":
+		return x != y, nil
+	}
+	switch x := x.(type) {
+	case float64:
+		if y, ok := y.(float64); ok {
+			return arithmetic(n.op, x, y)
+		}
+	case string:
+		if y, ok := y.(string); ok {
+			switch n.op {
+			case "+":
+				return x + y, nil
+			case "<":
+				return x < y, nil
+			case "<=":
+				return x <= y, nil
+			case ">":
+				return x > y, nil
+			case ">=":
+				return x >= y, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("column %d: cannot apply %s to %s and %s", n.pos, n.op, typeName(x), typeName(y))
+}
+
+func arithmetic(op string, x, y float64) (any, error) {
+	switch op {
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/":
+		if y == 0 {
+			return nil, errors.New("division by zero")
+		}
+		return x / y, nil
+	case "<":
+		return x < y, nil
+	case "<=":
+		return x <= y, nil
+	case ">":
+		return x > y, nil
+	}
+	return x >= y, nil
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case float64:
+		return "a number"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	}
+	return "nothing"
+}
+
+// kind is the type of a function parameter
+type kind int
+
+const (
+	str kind = iota
+	num
+)
+
+// function is a builtin; actions change the transaction and return nothing
+type function struct {
+	params []kind
+	call   func(e *env, args []any) (any, error)
+}
+
+type call struct {
+	name string
+	pos  int
+	fn   function
+	args []node
+}
+
+func (n call) eval(e *env) (any, error) {
+	args := make([]any, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(e)
+		if err != nil {
+			return nil, err
+		}
+		want := "a string"
+		_, ok := v.(string)
+		if n.fn.params[i] == num {
+			want = "a number"
+			_, ok = v.(float64)
+		}
+		if !ok {
+			return nil, fmt.Errorf("column %d: argument %d of %s must be %s, got %s", n.pos, i+1, n.name, want, typeName(v))
+		}
+		args[i] = v
+	}
+	return n.fn.call(e, args)
+}
+
+// functions are the builtins. Names are snake_case, like the fields.
+var functions = map[string]function{
+	"contains":    stringTest(strings.Contains),
+	"starts_with": stringTest(strings.HasPrefix),
+	"ends_with":   stringTest(strings.HasSuffix),
+	"matches": {params: []kind{str, str}, call: func(_ *env, args []any) (any, error) {
+		re, err := compileRegexp(args[1].(string))
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString(args[0].(string)), nil
+	}},
+	"lower": stringFunc(strings.ToLower),
+	"upper": stringFunc(strings.ToUpper),
+	"trim":  stringFunc(strings.TrimSpace),
+	"replace": {params: []kind{str, str, str}, call: func(_ *env, args []any) (any, error) {
+		return strings.ReplaceAll(args[0].(string), args[1].(string), args[2].(string)), nil
+	}},
+	"abs": {params: []kind{num}, call: func(_ *env, args []any) (any, error) {
+		return math.Abs(args[0].(float64)), nil
+	}},
+	"round": {params: []kind{num}, call: func(_ *env, args []any) (any, error) {
+		return math.Round(args[0].(float64)*100) / 100, nil
+	}},
+	"has_tag": {params: []kind{str}, call: func(e *env, args []any) (any, error) {
+		return e.t.HasTag(args[0].(string)), nil
+	}},
+
+	"set_category": {params: []kind{str}, call: func(e *env, args []any) (any, error) {
+		e.t.Category = args[0].(string)
+		e.t.CategoryConfidence = 1
+		e.t.CategorizedBy = CategorizedBy(e.name)
+		return nil, nil
+	}},
+	"set_description": {params: []kind{str}, call: func(e *env, args []any) (any, error) {
+		e.t.Description = args[0].(string)
+		return nil, nil
+	}},
+	"set_merchant": {params: []kind{str}, call: func(e *env, args []any) (any, error) {
+		e.t.Merchant = args[0].(string)
+		return nil, nil
+	}},
+	"set_amount": {params: []kind{num}, call: func(e *env, args []any) (any, error) {
+		e.t.Amount = transaction.FromFloat(args[0].(float64))
+		return nil, nil
+	}},
+	"add_tag": {params: []kind{str}, call: func(e *env, args []any) (any, error) {
+		e.t.AddTag(args[0].(string))
+		return nil, nil
+	}},
+	"exclude": {call: func(e *env, _ []any) (any, error) {
+		e.t.ExcludeFromReports = true
+		return nil, nil
+	}},
+	"skip": {call: func(*env, []any) (any, error) { return nil, nil }},
+}
+
+func stringTest(f func(s, substr string) bool) function {
+	return function{params: []kind{str, str}, call: func(_ *env, args []any) (any, error) {
+		return f(args[0].(string), args[1].(string)), nil
+	}}
+}
+
+func stringFunc(f func(string) string) function {
+	return function{params: []kind{str}, call: func(_ *env, args []any) (any, error) {
+		return f(args[0].(string)), nil
+	}}
+}
+
+// regexps caches the patterns of matches, which are case-insensitive
+var regexps sync.Map
+
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexps.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, fmt.Errorf("matches: %w", err)
+	}
+	regexps.Store(pattern, re)
+	return re, nil
+}
@@ -0,0 +1,121 @@
+package script
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestTransforms(t *testing.T) {
+	ts, err := New([]config.TransformRule{
+		{Name: "fees", Transform: "amount < 0 && contains(desc, 'FEE') ? set_category('Bank fees') : skip()"},
+		{Transform: `category == "Transfer" && abs(amount) >= 10000 ? (add_tag('large'); exclude()) : skip();`},
+		{Name: "tidy", Transform: "starts_with(desc, 'VISA ') ? set_description(trim(replace(desc, 'VISA ', ''))) : skip()"},
+	})
+	require.NoError(t, err)
+
+	txns := []transaction.Transaction{
+		{Description: "ACCOUNT FEE", Amount: -500},
+		{Description: "ACCOUNT FEE REFUND", Amount: 500},
+		{Description: "HOUSE DEPOSIT", Amount: -5000000, Category: "Transfer"},
+		{Description: "VISA  NETFLIX", Amount: -2299},
+	}
+	require.NoError(t, ts.Apply(txns))
+
+	assert.Equal(t, "Bank fees", txns[0].Category)
+	assert.Equal(t, "script:fees", txns[0].CategorizedBy)
+	assert.Equal(t, 1.0, txns[0].CategoryConfidence)
+	assert.Empty(t, txns[1].Category, "credits are left alone")
+	assert.Equal(t, []string{"large"}, txns[2].Tags)
+	assert.True(t, txns[2].ExcludeFromReports)
+	assert.Equal(t, "NETFLIX", txns[3].Description)
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		script string
+		check  func(t *testing.T, tx transaction.Transaction)
+	}{
+		{"set_amount(amount * -1)", func(t *testing.T, tx transaction.Transaction) {
+			assert.Equal(t, transaction.Amount(1250), tx.Amount)
+		}},
+		{"matches(desc, '^uber\\\\s+trip') ? add_tag('ride') : skip()", func(t *testing.T, tx transaction.Transaction) {
+			assert.Equal(t, []string{"ride"}, tx.Tags, "matches is case-insensitive")
+		}},
+		{"date >= '2024-03-01' && !has_tag('work') ? add_tag('q1-late') : add_tag('other')", func(t *testing.T, tx transaction.Transaction) {
+			assert.Equal(t, []string{"q1-late"}, tx.Tags)
+		}},
+		{"set_merchant(lower(upper(merchant) + ' ' + card))", func(t *testing.T, tx transaction.Transaction) {
+			assert.Equal(t, "uber 4521", tx.Merchant)
+		}},
+		{"-amount > 10 + 2 * 1 || set_category('never')", func(t *testing.T, tx transaction.Transaction) {
+			assert.Empty(t, tx.Category, "|| stops at the first true operand")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.script, func(t *testing.T) {
+			p, err := Compile("test", tt.script)
+			require.NoError(t, err)
+			tx := transaction.Transaction{
+				Date: time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), Description: "UBER  TRIP",
+				Amount: -1250, Merchant: "Uber", Card: "4521",
+			}
+			require.NoError(t, p.Run(&tx))
+			tt.check(t, tx)
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := map[string]string{
+		"":                           "empty script",
+		"amount <":                   "column 9: unexpected end of script",
+		"amount < 0 ? skip()":        `expected ":"`,
+		"amout < 0":                  `unknown variable "amout"`,
+		"set_catgory('Fees')":        `unknown function "set_catgory"`,
+		"contains(desc)":             "contains takes 2 arguments, got 1",
+		"desc == 'FEE":               "unterminated string",
+		"amount # 2":                 `column 8: unexpected '#'`,
+		"skip() skip()":              `column 8: unexpected "skip"`,
+		"(set_category('Fees')":      `expected ")"`,
+		"set_category('Fees'); ;":    "unexpected \";\"",
+		"1.2.3 > amount":             `invalid number "1.2.3"`,
+		"contains(desc, 'A') ? : 1 ": `unexpected ":"`,
+	}
+	for src, want := range tests {
+		_, err := Compile("test", src)
+		assert.ErrorContains(t, err, want, src)
+	}
+
+	_, err := New([]config.TransformRule{{Transform: "skip()"}, {Transform: "skip("}})
+	assert.ErrorContains(t, err, "transform 2: ")
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := map[string]string{
+		"desc ? skip() : skip()": "?: needs a boolean, got a string",
+		"amount + desc":          "cannot apply + to a number and a string",
+		"!amount":                "cannot apply ! to a number",
+		"contains(amount, 'A')":  "argument 1 of contains must be a string, got a number",
+		"set_amount(desc)":       "argument 1 of set_amount must be a number",
+		"amount / 0 > 1":         "division by zero",
+		"matches(desc, '(')":     "matches: error parsing regexp",
+		"skip() && true":         "&& needs a boolean, got nothing",
+	}
+	for src, want := range tests {
+		p, err := Compile("test", src)
+		require.NoError(t, err, src)
+		tx := transaction.Transaction{Description: "FEE", Amount: -100}
+		assert.ErrorContains(t, p.Run(&tx), want, src)
+	}
+
+	ts, err := New([]config.TransformRule{{Name: "bad", Transform: "amount + desc"}})
+	require.NoError(t, err)
+	err = ts.Apply([]transaction.Transaction{{Description: "FEE"}})
+	assert.ErrorContains(t, err, `transform bad on "FEE": column 8`)
+}
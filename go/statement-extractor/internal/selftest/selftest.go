@@ -0,0 +1,225 @@
+// Package selftest runs the processing pipeline against an embedded sample
+// statement so users can check their installation and config before
+// trusting it with real statements.
+package selftest
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/example/statement-extractor/internal/alert"
+	"github.com/example/statement-extractor/internal/categorizer"
+	"github.com/example/statement-extractor/internal/clock"
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/export"
+	"github.com/example/statement-extractor/internal/importer"
+	"github.com/example/statement-extractor/internal/money"
+	"github.com/example/statement-extractor/internal/parser"
+	"github.com/example/statement-extractor/internal/postprocess"
+	"github.com/example/statement-extractor/internal/report"
+	"github.com/example/statement-extractor/internal/theme"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+//go:embed testdata/sample.csv
+var sample []byte
+
+// sampleTotal is the sum of the amounts in the sample statement
+const sampleTotal transaction.Amount = 566536
+
+// Env is shared by the stages of one run
+type Env struct {
+	Config       *config.Config
+	Dir          string // scratch directory, removed after the run
+	Transactions []transaction.Transaction
+}
+
+// Stage is one step of the pipeline. Run returns a short detail line on
+// success.
+type Stage struct {
+	Name string
+	Run  func(ctx context.Context, env *Env) (string, error)
+}
+
+// Result is the outcome of one stage. Stages after a failure are skipped.
+type Result struct {
+	Stage    string
+	Detail   string
+	Err      error
+	Skipped  bool
+	Duration time.Duration
+}
+
+// Passed reports whether every stage passed
+func Passed(results []Result) bool {
+	for _, r := range results {
+		if r.Err != nil || r.Skipped {
+			return false
+		}
+	}
+	return true
+}
+
+// Stages returns the pipeline stages in order
+func Stages() []Stage {
+	return []Stage{
+		{Name: "config", Run: checkConfig},
+		{Name: "parse", Run: parse},
+		{Name: "categorize", Run: categorize},
+		{Name: "report", Run: aggregate},
+		{Name: "output", Run: output},
+	}
+}
+
+// Run executes stages in a temporary directory
+func Run(ctx context.Context, cfg *config.Config, stages []Stage) ([]Result, error) {
+	dir, err := os.MkdirTemp("", "statement-extractor-selftest-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	env := &Env{Config: cfg, Dir: dir}
+	results := make([]Result, 0, len(stages))
+	failed := false
+	for _, stage := range stages {
+		if failed {
+			results = append(results, Result{Stage: stage.Name, Skipped: true})
+			continue
+		}
+		start := time.Now()
+		detail, err := stage.Run(ctx, env)
+		results = append(results, Result{Stage: stage.Name, Detail: detail, Err: err, Duration: time.Since(start)})
+		failed = err != nil
+	}
+	return results, nil
+}
+
+func checkConfig(_ context.Context, env *Env) (string, error) {
+	if _, err := categorizer.New(env.Config); err != nil {
+		return "", err
+	}
+	if _, err := alert.Compile(env.Config.Alerts); err != nil {
+		return "", err
+	}
+	if _, err := money.New(env.Config.Money); err != nil {
+		return "", err
+	}
+	if _, err := theme.New(env.Config.Theme, false); err != nil {
+		return "", err
+	}
+	for name, pc := range env.Config.Parsers {
+		if _, err := postprocess.Compile(pc.PostProcess); err != nil {
+			return "", fmt.Errorf("parser %s: %w", name, err)
+		}
+		if pc.Method == "csv" {
+			if _, err := parser.CSVMapping(pc.CSV); err != nil {
+				return "", fmt.Errorf("parser %s: %w", name, err)
+			}
+		}
+	}
+	for name, sc := range env.Config.PDFServices {
+		if _, err := parser.NewHTTPClient(sc.HTTP); err != nil {
+			return "", fmt.Errorf("pdf service %s: %w", name, err)
+		}
+	}
+	if _, err := export.NewCSVLayout(env.Config.Export.CSV); err != nil {
+		return "", err
+	}
+	if _, err := export.NewLedger(env.Config.Export.Ledger); err != nil {
+		return "", err
+	}
+	if _, err := export.NewBeancount(env.Config.Export.Beancount); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d category rules, %d alert rules", len(env.Config.Categories), len(env.Config.Alerts)), nil
+}
+
+func parse(_ context.Context, env *Env) (string, error) {
+	m := importer.Mapping{
+		Date:        []string{"Date"},
+		Description: []string{"Description"},
+		Amount:      []string{"Amount"},
+		Balance:     []string{"Balance"},
+		DateLayouts: []string{"2006-01-02"},
+	}
+	txns, err := m.Read(bytes.NewReader(sample), "selftest")
+	if err != nil {
+		return "", err
+	}
+	if len(txns) == 0 {
+		return "", errors.New("no transactions parsed from the sample statement")
+	}
+	env.Transactions = txns
+	return fmt.Sprintf("%d transactions", len(txns)), nil
+}
+
+func categorize(ctx context.Context, env *Env) (string, error) {
+	chain, err := categorizer.New(env.Config)
+	if err != nil {
+		return "", err
+	}
+	if err := chain.Apply(ctx, env.Transactions); err != nil {
+		return "", err
+	}
+
+	matched := 0
+	for _, t := range env.Transactions {
+		if t.Category == "" {
+			return "", fmt.Errorf("%q was left without a category", t.Description)
+		}
+		if t.Category != env.Config.DefaultCategory {
+			matched++
+		}
+	}
+	return fmt.Sprintf("%d of %d categorized", matched, len(env.Transactions)), nil
+}
+
+func aggregate(_ context.Context, env *Env) (string, error) {
+	period := report.SpanOf(env.Transactions)
+	agg := report.Aggregate(env.Transactions, period)
+
+	var total transaction.Amount
+	for _, category := range agg.Categories() {
+		total += agg.Total(category)
+	}
+	if total != sampleTotal {
+		return "", fmt.Errorf("category totals sum to %s, expected %s", total, sampleTotal)
+	}
+	return fmt.Sprintf("%d categories over %s", len(agg.Categories()), period), nil
+}
+
+func output(ctx context.Context, env *Env) (string, error) {
+	list := transaction.TransactionList{Source: "selftest", ProcessedAt: clock.From(ctx).Now().UTC()}
+	for _, t := range env.Transactions {
+		list.AddTransaction(t)
+	}
+	data, err := json.Marshal(list)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(env.Dir, "output.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", err
+	}
+
+	data, err = os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var back transaction.TransactionList
+	if err := json.Unmarshal(data, &back); err != nil {
+		return "", err
+	}
+	if back.Total != list.Total {
+		return "", fmt.Errorf("read back %d transactions, wrote %d", back.Total, list.Total)
+	}
+	return "TransactionList JSON round-trips", nil
+}
@@ -0,0 +1,50 @@
+package selftest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+)
+
+func TestRun(t *testing.T) {
+	cfg := config.Default()
+	cfg.Categories = []config.CategoryRule{{Pattern: "SALARY", Category: "Income"}}
+
+	results, err := Run(context.Background(), cfg, Stages())
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+	for _, r := range results {
+		assert.NoError(t, r.Err, r.Stage)
+	}
+	assert.True(t, Passed(results))
+	assert.Equal(t, "7 transactions", results[1].Detail)
+	assert.Equal(t, "6 of 7 categorized", results[2].Detail)
+}
+
+func TestRun_StopsAtFailure(t *testing.T) {
+	cfg := config.Default()
+	cfg.Categories = []config.CategoryRule{{Pattern: "(", Category: "Broken"}}
+
+	results, err := Run(context.Background(), cfg, Stages())
+	require.NoError(t, err)
+	assert.ErrorContains(t, results[0].Err, `invalid pattern for category "Broken"`)
+	for _, r := range results[1:] {
+		assert.True(t, r.Skipped, r.Stage)
+	}
+	assert.False(t, Passed(results))
+}
+
+func TestRun_StageError(t *testing.T) {
+	stages := []Stage{{Name: "boom", Run: func(context.Context, *Env) (string, error) {
+		return "", errors.New("boom")
+	}}}
+
+	results, err := Run(context.Background(), config.Default(), stages)
+	require.NoError(t, err)
+	assert.EqualError(t, results[0].Err, "boom")
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Changes recorded in the audit history
+const (
+	ChangeAdded      = "added"
+	ChangeRemoved    = "removed"
+	ChangeNote       = "note"
+	ChangeAttachment = "attachment"
+)
+
+// AuditEntry is one change to a transaction: added or removed by the write
+// Operation ("add", "replace" or "update"), or given a note or an
+// attachment, when Operation is the Change and Detail is the note's text or
+// the attachment's name
+type AuditEntry struct {
+	At          time.Time          `json:"at"`
+	Operation   string             `json:"operation"`
+	Change      string             `json:"change"`
+	Transaction string             `json:"transaction"` // the transaction's ID
+	Date        string             `json:"date"`        // YYYY-MM-DD
+	Description string             `json:"description"`
+	Amount      transaction.Amount `json:"amount"`
+	Detail      string             `json:"detail,omitempty"`
+}
+
+// auditTriggers returns the SQL of a write script, after its BEGIN, that
+// logs the transactions the script adds and removes to audit as operation
+// at now. changeCounts then counts them.
+func auditTriggers(operation string, now time.Time) string {
+	at, op := quote(now.UTC().Format(time.RFC3339)), quote(operation)
+	return fmt.Sprintf(`CREATE TEMP TABLE audit_start AS SELECT COALESCE(MAX(id), 0) AS id FROM audit;
+CREATE TEMP TRIGGER audit_added AFTER INSERT ON main.transactions BEGIN
+	INSERT INTO audit (at, operation, change, hash, txn_id, account, date, description, amount)
+	VALUES (%[1]s, %[2]s, '%[3]s', NEW.hash, NEW.txn_id, NEW.account, NEW.date, NEW.description, NEW.amount);
+END;
+CREATE TEMP TRIGGER audit_removed AFTER DELETE ON main.transactions BEGIN
+	INSERT INTO audit (at, operation, change, hash, txn_id, account, date, description, amount)
+	VALUES (%[1]s, %[2]s, '%[4]s', OLD.hash, OLD.txn_id, OLD.account, OLD.date, OLD.description, OLD.amount);
+END;
+`, at, op, ChangeAdded, ChangeRemoved)
+}
+
+// changeCounts selects the numbers of transactions a write script removed
+// and added, as logged since its auditTriggers
+const changeCounts = "SELECT COALESCE(SUM(change = '" + ChangeRemoved + "'), 0) AS removed, COALESCE(SUM(change = '" + ChangeAdded + "'), 0) AS added FROM audit WHERE id > (SELECT id FROM temp.audit_start);\n"
+
+// Audit returns the history of account's transactions, including those since
+// removed, oldest first
+func (s *Store) Audit(ctx context.Context, account string) ([]AuditEntry, error) {
+	var rows []struct {
+		At          string `json:"at"`
+		Operation   string `json:"operation"`
+		Change      string `json:"change"`
+		TxnID       string `json:"txn_id"`
+		Date        string `json:"date"`
+		Description string `json:"description"`
+		Amount      int64  `json:"amount"`
+		Detail      string `json:"detail"`
+	}
+	sql := "SELECT at, operation, change, txn_id, date, description, amount, detail FROM audit WHERE account = " +
+		quote(account) + " COLLATE NOCASE ORDER BY id;"
+	if err := s.query(ctx, sql, &rows); err != nil {
+		return nil, fmt.Errorf("failed to read the audit history: %w", err)
+	}
+	entries := make([]AuditEntry, 0, len(rows))
+	for _, r := range rows {
+		at, err := time.Parse(time.RFC3339, r.At)
+		if err != nil {
+			return nil, fmt.Errorf("invalid audit time %q in transaction database: %w", r.At, err)
+		}
+		entries = append(entries, AuditEntry{
+			At: at, Operation: r.Operation, Change: r.Change, Transaction: r.TxnID,
+			Date: r.Date, Description: r.Description, Amount: transaction.Amount(r.Amount), Detail: r.Detail,
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/statement-extractor/internal/clock"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Envelope is a budget envelope's month as the budget report saves it.
+// Balance is what the month left over, negative when overspent.
+type Envelope struct {
+	Name    string
+	Month   string // YYYY-MM
+	Budget  transaction.Amount
+	Carried transaction.Amount
+	Spent   transaction.Amount
+	Balance transaction.Amount
+}
+
+// SaveEnvelopes records the envelopes, replacing those saved before for the
+// same name and month
+func (s *Store) SaveEnvelopes(ctx context.Context, envelopes []Envelope) error {
+	if len(envelopes) == 0 {
+		return nil
+	}
+	lock, err := s.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	now := quote(clock.From(ctx).Now().UTC().Format(time.RFC3339))
+	var b strings.Builder
+	b.WriteString("BEGIN;\n")
+	for _, e := range envelopes {
+		fmt.Fprintf(&b, "INSERT OR REPLACE INTO envelopes (name, month, budget, carried, spent, balance, updated_at) VALUES (%s, %s, %d, %d, %d, %d, %s);\n",
+			quote(e.Name), quote(e.Month), e.Budget, e.Carried, e.Spent, e.Balance, now)
+	}
+	b.WriteString("COMMIT;")
+	if err := s.exec(ctx, b.String()); err != nil {
+		return fmt.Errorf("failed to save budget envelopes: %w", err)
+	}
+	return nil
+}
+
+// Envelopes returns the saved envelopes of month (YYYY-MM) by name
+func (s *Store) Envelopes(ctx context.Context, month string) (map[string]Envelope, error) {
+	var rows []struct {
+		Name    string `json:"name"`
+		Month   string `json:"month"`
+		Budget  int64  `json:"budget"`
+		Carried int64  `json:"carried"`
+		Spent   int64  `json:"spent"`
+		Balance int64  `json:"balance"`
+	}
+	sql := "SELECT name, month, budget, carried, spent, balance FROM envelopes WHERE month = " + quote(month) + ";"
+	if err := s.query(ctx, sql, &rows); err != nil {
+		return nil, fmt.Errorf("failed to read budget envelopes: %w", err)
+	}
+	envelopes := make(map[string]Envelope, len(rows))
+	for _, r := range rows {
+		envelopes[r.Name] = Envelope{
+			Name: r.Name, Month: r.Month, Budget: transaction.Amount(r.Budget), Carried: transaction.Amount(r.Carried),
+			Spent: transaction.Amount(r.Spent), Balance: transaction.Amount(r.Balance),
+		}
+	}
+	return envelopes, nil
+}
@@ -0,0 +1,34 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvelopes(t *testing.T) {
+	s := openStore(t)
+	ctx := context.Background()
+
+	require.NoError(t, s.SaveEnvelopes(ctx, []Envelope{
+		{Name: "Groceries", Month: "2024-01", Budget: 15000, Carried: 1000, Spent: 20000, Balance: -4000},
+		{Name: "Dining's", Month: "2024-01", Budget: 5000, Spent: 2000, Balance: 3000},
+		{Name: "Groceries", Month: "2024-02", Budget: 15000, Carried: -4000, Spent: 10000, Balance: 1000},
+	}))
+	require.NoError(t, s.SaveEnvelopes(ctx, []Envelope{
+		{Name: "Groceries", Month: "2024-01", Budget: 15000, Spent: 12000, Balance: 3000},
+	}))
+
+	jan, err := s.Envelopes(ctx, "2024-01")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Envelope{
+		"Groceries": {Name: "Groceries", Month: "2024-01", Budget: 15000, Spent: 12000, Balance: 3000},
+		"Dining's":  {Name: "Dining's", Month: "2024-01", Budget: 5000, Spent: 2000, Balance: 3000},
+	}, jan)
+
+	mar, err := s.Envelopes(ctx, "2024-03")
+	require.NoError(t, err)
+	assert.Empty(t, mar)
+}
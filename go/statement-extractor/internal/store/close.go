@@ -0,0 +1,270 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/statement-extractor/internal/clock"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// ErrClosed is returned when a change would touch a transaction in a closed
+// month
+var ErrClosed = errors.New("the change would alter a closed month")
+
+// closedMessage is raised by the closed month triggers
+const closedMessage = "transaction in a closed month"
+
+// closedError returns ErrClosed for a failure raised by the closed month
+// triggers, and err otherwise
+func closedError(err error) error {
+	if strings.Contains(err.Error(), closedMessage) {
+		return ErrClosed
+	}
+	return err
+}
+
+const monthFormat = "2006-01"
+
+// Closing is the record of a month closed with Close
+type Closing struct {
+	Month      string // YYYY-MM
+	ClosedAt   time.Time
+	ReopenedAt time.Time          // zero while the month is closed
+	Count      int                // transactions in the month when it was closed
+	Total      transaction.Amount // their net amount
+	Summary    []Total            // their totals by category
+}
+
+// Open reports whether the month has been reopened since it was closed
+func (c Closing) Open() bool {
+	return !c.ReopenedAt.IsZero()
+}
+
+// closingRow is a closed_months row as printed by the shell's JSON mode
+type closingRow struct {
+	Month      string `json:"month"`
+	ClosedAt   string `json:"closed_at"`
+	ReopenedAt string `json:"reopened_at"`
+	Count      int    `json:"count"`
+	Total      int64  `json:"total"` // in cents
+	Summary    string `json:"summary"`
+	Snapshot   string `json:"snapshot"`
+}
+
+func (r closingRow) closing() (Closing, error) {
+	c := Closing{Month: r.Month, Count: r.Count, Total: transaction.Amount(r.Total)}
+	var err error
+	if c.ClosedAt, err = time.Parse(time.RFC3339, r.ClosedAt); err != nil {
+		return Closing{}, fmt.Errorf("invalid closed_at %q in transaction database: %w", r.ClosedAt, err)
+	}
+	if r.ReopenedAt != "" {
+		if c.ReopenedAt, err = time.Parse(time.RFC3339, r.ReopenedAt); err != nil {
+			return Closing{}, fmt.Errorf("invalid reopened_at %q in transaction database: %w", r.ReopenedAt, err)
+		}
+	}
+	if err := json.Unmarshal([]byte(r.Summary), &c.Summary); err != nil {
+		return Closing{}, fmt.Errorf("invalid summary of %s in transaction database: %w", r.Month, err)
+	}
+	return c, nil
+}
+
+// monthFilter selects the transactions of month, the first day of which is
+// given
+func monthFilter(month time.Time) Filter {
+	return Filter{From: month, To: month.AddDate(0, 1, 0)}
+}
+
+// Close closes month, given by its first day: it records a snapshot of the
+// month's transactions and their totals, and locks them against changes
+// until Reopen. Closing a reopened month takes a new snapshot.
+func (s *Store) Close(ctx context.Context, month time.Time) (Closing, error) {
+	lock, err := s.lock(ctx)
+	if err != nil {
+		return Closing{}, err
+	}
+	defer lock.Release()
+
+	name := month.Format(monthFormat)
+	if c, ok, err := s.Closing(ctx, month); err != nil {
+		return Closing{}, err
+	} else if ok && !c.Open() {
+		return Closing{}, fmt.Errorf("%s is already closed", name)
+	}
+
+	f := monthFilter(month)
+	txns, err := s.List(ctx, f)
+	if err != nil {
+		return Closing{}, err
+	}
+	summary, err := s.Sum(ctx, f, "category")
+	if err != nil {
+		return Closing{}, err
+	}
+	c := Closing{Month: name, ClosedAt: clock.From(ctx).Now().UTC().Truncate(time.Second), Count: len(txns), Summary: summary}
+	for _, t := range txns {
+		c.Total += t.Amount
+	}
+	if c.Summary == nil {
+		c.Summary = []Total{}
+	}
+	summaryJSON, err := json.Marshal(c.Summary)
+	if err != nil {
+		return Closing{}, fmt.Errorf("failed to encode the summary of %s: %w", name, err)
+	}
+	snapshotJSON, err := json.Marshal(txns)
+	if err != nil {
+		return Closing{}, fmt.Errorf("failed to encode the snapshot of %s: %w", name, err)
+	}
+
+	script := fmt.Sprintf("INSERT OR REPLACE INTO closed_months (month, closed_at, reopened_at, count, total, summary, snapshot) VALUES (%s, %s, '', %d, %d, %s, %s);",
+		quote(name), quote(c.ClosedAt.Format(time.RFC3339)), c.Count, int64(c.Total), quote(string(summaryJSON)), quote(string(snapshotJSON)))
+	if err := s.exec(ctx, script); err != nil {
+		return Closing{}, fmt.Errorf("failed to close %s: %w", name, err)
+	}
+	return c, nil
+}
+
+// Reopen lifts the lock on a closed month, keeping its snapshot so the
+// changes made can be compared against it
+func (s *Store) Reopen(ctx context.Context, month time.Time) error {
+	lock, err := s.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	name := month.Format(monthFormat)
+	now := clock.From(ctx).Now().UTC().Format(time.RFC3339)
+	var rows []struct {
+		Changed int `json:"changed"`
+	}
+	script := fmt.Sprintf("UPDATE closed_months SET reopened_at = %s WHERE month = %s AND reopened_at = '';\nSELECT changes() AS changed;",
+		quote(now), quote(name))
+	if err := s.query(ctx, script, &rows); err != nil {
+		return fmt.Errorf("failed to reopen %s: %w", name, err)
+	}
+	if len(rows) == 0 || rows[0].Changed == 0 {
+		return fmt.Errorf("%s is not closed", name)
+	}
+	return nil
+}
+
+// Closing returns the record of month, given by its first day, and whether
+// it was ever closed
+func (s *Store) Closing(ctx context.Context, month time.Time) (Closing, bool, error) {
+	rows, err := s.closings(ctx, "WHERE month = "+quote(month.Format(monthFormat)), false)
+	if err != nil || len(rows) == 0 {
+		return Closing{}, false, err
+	}
+	c, err := rows[0].closing()
+	return c, err == nil, err
+}
+
+// Closings returns the records of every month closed, oldest month first
+func (s *Store) Closings(ctx context.Context) ([]Closing, error) {
+	rows, err := s.closings(ctx, "", false)
+	if err != nil {
+		return nil, err
+	}
+	closings := make([]Closing, len(rows))
+	for i, r := range rows {
+		if closings[i], err = r.closing(); err != nil {
+			return nil, err
+		}
+	}
+	return closings, nil
+}
+
+// Snapshot returns the transactions of month, given by its first day, as
+// they were when it was last closed
+func (s *Store) Snapshot(ctx context.Context, month time.Time) ([]transaction.Transaction, error) {
+	name := month.Format(monthFormat)
+	rows, err := s.closings(ctx, "WHERE month = "+quote(name), true)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s has never been closed", name)
+	}
+	var txns []transaction.Transaction
+	if err := json.Unmarshal([]byte(rows[0].Snapshot), &txns); err != nil {
+		return nil, fmt.Errorf("invalid snapshot of %s in transaction database: %w", name, err)
+	}
+	return txns, nil
+}
+
+// closings queries closed_months, with the snapshots when asked for
+func (s *Store) closings(ctx context.Context, where string, snapshot bool) ([]closingRow, error) {
+	columns := "month, closed_at, reopened_at, count, total, summary"
+	if snapshot {
+		columns += ", snapshot"
+	}
+	var rows []closingRow
+	if err := s.query(ctx, fmt.Sprintf("SELECT %s FROM closed_months %s ORDER BY month;", columns, where), &rows); err != nil {
+		return nil, fmt.Errorf("failed to query closed months: %w", err)
+	}
+	return rows, nil
+}
+
+// Change is a difference between a month's snapshot and its transactions
+// now: Before is nil for a transaction added since, After for one removed
+type Change struct {
+	Before, After *transaction.Transaction
+}
+
+// Diff compares a snapshot with the transactions now. Transactions are
+// matched by ID, or by date, amount and description when they have none, so
+// a changed amount shows as one transaction removed and another added, and
+// a changed category or note as a change.
+func Diff(before, after []transaction.Transaction) []Change {
+	keys := func(txns []transaction.Transaction) []string {
+		seen := make(map[string]int)
+		out := make([]string, len(txns))
+		for i, t := range txns {
+			key := t.ID
+			if key == "" {
+				key = fmt.Sprintf("%s|%d|%s", t.Date.Format(dateFormat), int64(t.Amount), t.Description)
+			}
+			seen[key]++
+			out[i] = fmt.Sprintf("%s|%d", key, seen[key])
+		}
+		return out
+	}
+
+	now := make(map[string]int)
+	for i, key := range keys(after) {
+		now[key] = i
+	}
+	var changes []Change
+	matched := make([]bool, len(after))
+	for i, key := range keys(before) {
+		j, ok := now[key]
+		if !ok {
+			changes = append(changes, Change{Before: &before[i]})
+			continue
+		}
+		matched[j] = true
+		if !sameTransaction(before[i], after[j]) {
+			changes = append(changes, Change{Before: &before[i], After: &after[j]})
+		}
+	}
+	for j := range after {
+		if !matched[j] {
+			changes = append(changes, Change{After: &after[j]})
+		}
+	}
+	return changes
+}
+
+// sameTransaction compares transactions as they are stored, so that a nil
+// and an empty list of tags are the same
+func sameTransaction(a, b transaction.Transaction) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
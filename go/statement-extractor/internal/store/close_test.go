@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestClose(t *testing.T) {
+	s := openStore(t)
+	ctx := context.Background()
+	_, err := s.Add(ctx, sample())
+	require.NoError(t, err)
+	jan := date("2024-01-01")
+
+	c, err := s.Close(ctx, jan)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01", c.Month)
+	assert.Equal(t, 3, c.Count)
+	assert.Equal(t, transaction.Amount(-9115), c.Total)
+	assert.Equal(t, []Total{{Group: "Dining", Count: 2, Amount: -900}, {Group: "Groceries", Count: 1, Amount: -8215}}, c.Summary)
+	assert.False(t, c.Open())
+
+	_, err = s.Close(ctx, jan)
+	assert.ErrorContains(t, err, "2024-01 is already closed")
+
+	added, err := s.Add(ctx, sample())
+	require.NoError(t, err, "re-importing a closed month's statement is fine")
+	assert.Zero(t, added)
+
+	late := transaction.Transaction{Date: date("2024-01-20"), Description: "NETFLIX", Amount: -2299, Account: "Everyday"}
+	_, err = s.Add(ctx, []transaction.Transaction{late})
+	assert.ErrorIs(t, err, ErrClosed)
+	_, _, err = s.Replace(ctx, Filter{Category: "Dining"}, nil)
+	assert.ErrorIs(t, err, ErrClosed)
+	_, _, err = s.Replace(ctx, Filter{From: date("2024-02-01")}, nil)
+	assert.NoError(t, err, "other months are not locked")
+
+	require.NoError(t, s.Reopen(ctx, jan))
+	assert.ErrorContains(t, s.Reopen(ctx, jan), "2024-01 is not closed")
+	_, err = s.Add(ctx, []transaction.Transaction{late})
+	require.NoError(t, err)
+
+	closing, ok, err := s.Closing(ctx, jan)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, closing.Open())
+	assert.Equal(t, c.Summary, closing.Summary)
+
+	snapshot, err := s.Snapshot(ctx, jan)
+	require.NoError(t, err)
+	assert.Equal(t, sample()[:3], snapshot)
+	now, err := s.List(ctx, monthFilter(jan))
+	require.NoError(t, err)
+	changes := Diff(snapshot, now)
+	require.Len(t, changes, 1)
+	assert.Nil(t, changes[0].Before)
+	assert.Equal(t, "NETFLIX", changes[0].After.Description)
+
+	c, err = s.Close(ctx, jan)
+	require.NoError(t, err, "a reopened month can be closed again")
+	assert.Equal(t, 4, c.Count)
+	closings, err := s.Closings(ctx)
+	require.NoError(t, err)
+	require.Len(t, closings, 1)
+	assert.False(t, closings[0].Open())
+
+	_, ok, err = s.Closing(ctx, date("2024-02-01"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+	_, err = s.Snapshot(ctx, date("2024-02-01"))
+	assert.ErrorContains(t, err, "2024-02 has never been closed")
+}
+
+func TestDiff(t *testing.T) {
+	before := sample()
+	after := sample()
+	after[0].Category = "Dining"
+	after = append(after[1:3], after[3:]...)
+	after = append(after, transaction.Transaction{Date: date("2024-01-09"), Description: "BP", Amount: -6000})
+	after[0].Category = "Coffee"
+
+	changes := Diff(before, after)
+	require.Len(t, changes, 3)
+	assert.Equal(t, "WOOLWORTHS 1234", changes[0].Before.Description)
+	assert.Nil(t, changes[0].After, "removed")
+	assert.Equal(t, "Dining", changes[1].Before.Category, "identical transactions are matched in order")
+	assert.Equal(t, "Coffee", changes[1].After.Category)
+	assert.Nil(t, changes[2].Before, "added")
+	assert.Equal(t, "BP", changes[2].After.Description)
+}
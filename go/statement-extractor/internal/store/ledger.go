@@ -0,0 +1,132 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/example/statement-extractor/internal/clock"
+)
+
+// Processed reports the PDF service the statement with the SHA-256 digest
+// was last sent to, and when
+func (s *Store) Processed(ctx context.Context, digest string) (provider string, at time.Time, ok bool, err error) {
+	var rows []struct {
+		Provider    string `json:"provider"`
+		ProcessedAt string `json:"processed_at"`
+	}
+	if err := s.query(ctx, "SELECT provider, processed_at FROM processed_statements WHERE sha256 = "+quote(digest)+";", &rows); err != nil {
+		return "", time.Time{}, false, fmt.Errorf("failed to read the processing ledger: %w", err)
+	}
+	if len(rows) == 0 {
+		return "", time.Time{}, false, nil
+	}
+	if at, err = time.Parse(time.RFC3339, rows[0].ProcessedAt); err != nil {
+		return "", time.Time{}, false, fmt.Errorf("invalid processed_at %q in transaction database: %w", rows[0].ProcessedAt, err)
+	}
+	return rows[0].Provider, at, true, nil
+}
+
+// RecordProcessed enters the statement with the SHA-256 digest in the
+// processing ledger as sent to provider now
+func (s *Store) RecordProcessed(ctx context.Context, digest, provider string) error {
+	lock, err := s.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	now := clock.From(ctx).Now().UTC().Format(time.RFC3339)
+	script := fmt.Sprintf("INSERT OR REPLACE INTO processed_statements (sha256, provider, processed_at) VALUES (%s, %s, %s);",
+		quote(digest), quote(provider), quote(now))
+	if err := s.exec(ctx, script); err != nil {
+		return fmt.Errorf("failed to record the statement in the processing ledger: %w", err)
+	}
+	return nil
+}
+
+// RecordExtraction logs a statement extracted now with parser, through the
+// PDF service provider when it is not empty
+func (s *Store) RecordExtraction(ctx context.Context, parser, provider string, cached bool, elapsed time.Duration) error {
+	lock, err := s.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	now := clock.From(ctx).Now().UTC().Format(time.RFC3339)
+	script := fmt.Sprintf("INSERT INTO extractions (extracted_at, parser, provider, cached, duration_ms) VALUES (%s, %s, %s, %d, %d);",
+		quote(now), quote(parser), quote(provider), boolInt(cached), elapsed.Milliseconds())
+	if err := s.exec(ctx, script); err != nil {
+		return fmt.Errorf("failed to record the extraction: %w", err)
+	}
+	return nil
+}
+
+// MonthUsage counts one month's statements: those first sent to a PDF
+// service, from the processing ledger, and those extracted, of which Cached
+// were answered by the response cache
+type MonthUsage struct {
+	Month     string // YYYY-MM
+	Sent      int
+	Extracted int
+	Cached    int
+}
+
+// UsageByMonth counts the statements of each month, in UTC, oldest first
+func (s *Store) UsageByMonth(ctx context.Context) ([]MonthUsage, error) {
+	var rows []struct {
+		Month     string `json:"month"`
+		Sent      int    `json:"sent"`
+		Extracted int    `json:"extracted"`
+		Cached    int    `json:"cached"`
+	}
+	sql := `SELECT month, sum(sent) AS sent, sum(extracted) AS extracted, sum(cached) AS cached FROM (
+		SELECT substr(processed_at, 1, 7) AS month, 1 AS sent, 0 AS extracted, 0 AS cached FROM processed_statements
+		UNION ALL
+		SELECT substr(extracted_at, 1, 7), 0, 1, cached FROM extractions
+	) GROUP BY month ORDER BY month;`
+	if err := s.query(ctx, sql, &rows); err != nil {
+		return nil, fmt.Errorf("failed to read the processing ledger: %w", err)
+	}
+	usage := make([]MonthUsage, len(rows))
+	for i, r := range rows {
+		usage[i] = MonthUsage(r)
+	}
+	return usage, nil
+}
+
+// ExtractionUsage summarizes the extractions of one parser and provider
+type ExtractionUsage struct {
+	Parser      string
+	Provider    string // empty for those not sent to a PDF service
+	Extractions int
+	Cached      int
+	Mean        time.Duration
+}
+
+// UsageByParser summarizes the extractions since the given time by parser
+// and provider, sorted by both
+func (s *Store) UsageByParser(ctx context.Context, since time.Time) ([]ExtractionUsage, error) {
+	var rows []struct {
+		Parser      string  `json:"parser"`
+		Provider    string  `json:"provider"`
+		Extractions int     `json:"extractions"`
+		Cached      int     `json:"cached"`
+		MeanMS      float64 `json:"mean_ms"`
+	}
+	sql := fmt.Sprintf(`SELECT parser, provider, count(*) AS extractions, sum(cached) AS cached, avg(duration_ms) AS mean_ms
+		FROM extractions WHERE extracted_at >= %s GROUP BY parser, provider ORDER BY parser, provider;`,
+		quote(since.UTC().Format(time.RFC3339)))
+	if err := s.query(ctx, sql, &rows); err != nil {
+		return nil, fmt.Errorf("failed to read the extraction log: %w", err)
+	}
+	usage := make([]ExtractionUsage, len(rows))
+	for i, r := range rows {
+		usage[i] = ExtractionUsage{
+			Parser: r.Parser, Provider: r.Provider, Extractions: r.Extractions, Cached: r.Cached,
+			Mean: time.Duration(r.MeanMS * float64(time.Millisecond)),
+		}
+	}
+	return usage, nil
+}
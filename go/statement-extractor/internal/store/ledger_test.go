@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/clock"
+)
+
+func TestProcessingLedger(t *testing.T) {
+	s := openStore(t)
+	at := time.Date(2024, 7, 1, 9, 30, 0, 0, time.UTC)
+	ctx := clock.With(context.Background(), clock.Fixed(at))
+
+	_, _, ok, err := s.Processed(ctx, "abc")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, s.RecordProcessed(ctx, "abc", "gateway"))
+	require.NoError(t, s.RecordProcessed(ctx, "abc", "backup"))
+	provider, when, ok, err := s.Processed(ctx, "abc")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "backup", provider)
+	assert.Equal(t, at, when)
+
+	_, _, ok, err = s.Processed(ctx, "def")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestExtractionLog(t *testing.T) {
+	s := openStore(t)
+	june := clock.With(context.Background(), clock.Fixed(time.Date(2024, 6, 30, 23, 0, 0, 0, time.UTC)))
+	july := clock.With(context.Background(), clock.Fixed(time.Date(2024, 7, 1, 9, 30, 0, 0, time.UTC)))
+
+	require.NoError(t, s.RecordProcessed(june, "abc", "gateway"))
+	require.NoError(t, s.RecordExtraction(june, "cba", "gateway", false, 3*time.Second))
+	require.NoError(t, s.RecordExtraction(july, "cba", "gateway", true, time.Second))
+	require.NoError(t, s.RecordExtraction(july, "anz", "", false, 200*time.Millisecond))
+
+	months, err := s.UsageByMonth(july)
+	require.NoError(t, err)
+	assert.Equal(t, []MonthUsage{
+		{Month: "2024-06", Sent: 1, Extracted: 1},
+		{Month: "2024-07", Extracted: 2, Cached: 1},
+	}, months)
+
+	usage, err := s.UsageByParser(july, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, []ExtractionUsage{
+		{Parser: "anz", Extractions: 1, Mean: 200 * time.Millisecond},
+		{Parser: "cba", Provider: "gateway", Extractions: 2, Cached: 1, Mean: 2 * time.Second},
+	}, usage)
+
+	usage, err = s.UsageByParser(july, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Len(t, usage, 2)
+	assert.Equal(t, 1, usage[1].Extractions, "earlier extractions are left out")
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/statement-extractor/internal/clock"
+)
+
+// Note is a note on a transaction
+type Note struct {
+	Transaction string    `json:"transaction"` // the transaction's ID
+	Text        string    `json:"text"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Attachment describes a file attached to a transaction, such as a receipt;
+// the file itself stays at Path
+type Attachment struct {
+	Transaction string    `json:"transaction"` // the transaction's ID
+	Name        string    `json:"name"`
+	Path        string    `json:"path"`
+	MediaType   string    `json:"media_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	AddedAt     time.Time `json:"added_at"`
+}
+
+// AddNote adds a note to the transaction with the ID id
+func (s *Store) AddNote(ctx context.Context, id, text string) error {
+	if strings.TrimSpace(text) == "" {
+		return fmt.Errorf("empty note for transaction %q", id)
+	}
+	return s.annotate(ctx, id, ChangeNote, text, func(hash, at string) string {
+		return fmt.Sprintf("INSERT INTO notes (hash, text, created_at) VALUES (%s, %s, %s);\n", hash, quote(text), at)
+	})
+}
+
+// Attach records a as attached to the transaction with the ID id
+func (s *Store) Attach(ctx context.Context, id string, a Attachment) error {
+	return s.annotate(ctx, id, ChangeAttachment, a.Name, func(hash, at string) string {
+		return fmt.Sprintf("INSERT INTO attachments (hash, name, path, media_type, size, sha256, added_at) VALUES (%s, %s, %s, %s, %d, %s, %s);\n",
+			hash, quote(a.Name), quote(a.Path), quote(a.MediaType), a.Size, quote(a.SHA256), at)
+	})
+}
+
+// annotate runs the insert of a note or attachment on the transaction with
+// the ID id, given its quoted hash and time, and logs it to audit as change
+// with detail
+func (s *Store) annotate(ctx context.Context, id, change, detail string, insert func(hash, at string) string) error {
+	if id == "" {
+		return errors.New("no transaction ID")
+	}
+	lock, err := s.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	var rows []struct {
+		Hash string `json:"hash"`
+	}
+	if err := s.query(ctx, "SELECT hash FROM transactions WHERE txn_id = "+quote(id)+";", &rows); err != nil {
+		return fmt.Errorf("failed to find transaction %q: %w", id, err)
+	}
+	switch len(rows) {
+	case 0:
+		return fmt.Errorf("no transaction %q in the transaction database", id)
+	case 1:
+	default:
+		return fmt.Errorf("%d transactions have the ID %q", len(rows), id)
+	}
+
+	hash := quote(rows[0].Hash)
+	at := quote(clock.From(ctx).Now().UTC().Format(time.RFC3339))
+	script := "BEGIN;\n" + insert(hash, at) + fmt.Sprintf(
+		"INSERT INTO audit (at, operation, change, hash, txn_id, account, date, description, amount, detail)\n"+
+			"SELECT %s, %s, %s, hash, txn_id, account, date, description, amount, %s FROM transactions WHERE hash = %s;\nCOMMIT;",
+		at, quote(change), quote(change), quote(detail), hash)
+	if err := s.exec(ctx, script); err != nil {
+		return fmt.Errorf("failed to add the %s to transaction %q: %w", change, id, err)
+	}
+	return nil
+}
+
+// Notes returns the notes on the transactions matching f, oldest first
+func (s *Store) Notes(ctx context.Context, f Filter) ([]Note, error) {
+	var rows []struct {
+		TxnID     string `json:"txn_id"`
+		Text      string `json:"text"`
+		CreatedAt string `json:"created_at"`
+	}
+	sql := "SELECT transactions.txn_id, notes.text, notes.created_at FROM notes JOIN transactions ON transactions.hash = notes.hash WHERE " +
+		f.where() + " ORDER BY notes.id;"
+	if err := s.query(ctx, sql, &rows); err != nil {
+		return nil, fmt.Errorf("failed to query notes: %w", err)
+	}
+	notes := make([]Note, 0, len(rows))
+	for _, r := range rows {
+		at, err := time.Parse(time.RFC3339, r.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid note time %q in transaction database: %w", r.CreatedAt, err)
+		}
+		notes = append(notes, Note{Transaction: r.TxnID, Text: r.Text, CreatedAt: at})
+	}
+	return notes, nil
+}
+
+// Attachments returns the attachments of the transactions matching f,
+// oldest first
+func (s *Store) Attachments(ctx context.Context, f Filter) ([]Attachment, error) {
+	var rows []struct {
+		TxnID     string `json:"txn_id"`
+		Name      string `json:"name"`
+		Path      string `json:"path"`
+		MediaType string `json:"media_type"`
+		Size      int64  `json:"size"`
+		SHA256    string `json:"sha256"`
+		AddedAt   string `json:"added_at"`
+	}
+	sql := "SELECT transactions.txn_id, attachments.name, attachments.path, attachments.media_type, attachments.size, attachments.sha256, attachments.added_at " +
+		"FROM attachments JOIN transactions ON transactions.hash = attachments.hash WHERE " + f.where() + " ORDER BY attachments.id;"
+	if err := s.query(ctx, sql, &rows); err != nil {
+		return nil, fmt.Errorf("failed to query attachments: %w", err)
+	}
+	attachments := make([]Attachment, 0, len(rows))
+	for _, r := range rows {
+		at, err := time.Parse(time.RFC3339, r.AddedAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid attachment time %q in transaction database: %w", r.AddedAt, err)
+		}
+		attachments = append(attachments, Attachment{
+			Transaction: r.TxnID, Name: r.Name, Path: r.Path, MediaType: r.MediaType, Size: r.Size, SHA256: r.SHA256, AddedAt: at,
+		})
+	}
+	return attachments, nil
+}
@@ -0,0 +1,68 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/clock"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestNotesAndAudit(t *testing.T) {
+	s := openStore(t)
+	at := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	ctx := clock.With(context.Background(), clock.Fixed(at))
+	txns := sample()
+	transaction.AssignIDs(txns)
+	_, err := s.Add(ctx, txns)
+	require.NoError(t, err)
+	stored, err := s.List(ctx, Filter{})
+	require.NoError(t, err)
+	id := stored[0].ID
+
+	require.NoError(t, s.AddNote(ctx, id, "Weekly shop, it's split with Sam"))
+	require.NoError(t, s.Attach(ctx, id, Attachment{Name: "receipt.pdf", Path: "/receipts/receipt.pdf", MediaType: "application/pdf", Size: 1024, SHA256: "abc"}))
+	assert.ErrorContains(t, s.AddNote(ctx, "missing", "text"), `no transaction "missing"`)
+	assert.Error(t, s.AddNote(ctx, id, " "), "empty notes are refused")
+	assert.Error(t, s.AddNote(ctx, "", "text"))
+
+	notes, err := s.Notes(ctx, Filter{Account: "everyday"})
+	require.NoError(t, err)
+	assert.Equal(t, []Note{{Transaction: id, Text: "Weekly shop, it's split with Sam", CreatedAt: at}}, notes)
+	attachments, err := s.Attachments(ctx, Filter{Account: "Everyday"})
+	require.NoError(t, err)
+	assert.Equal(t, []Attachment{{Transaction: id, Name: "receipt.pdf", Path: "/receipts/receipt.pdf", MediaType: "application/pdf", Size: 1024, SHA256: "abc", AddedAt: at}}, attachments)
+	notes, err = s.Notes(ctx, Filter{Account: "Other"})
+	require.NoError(t, err)
+	assert.Empty(t, notes)
+
+	fixed := stored[0]
+	fixed.Category = "Household"
+	removed, added, err := s.Update(ctx, stored[:1], []transaction.Transaction{fixed})
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, 1, added)
+	notes, err = s.Notes(ctx, Filter{})
+	require.NoError(t, err)
+	assert.Len(t, notes, 1, "notes stay with a rewritten transaction")
+
+	entries, err := s.Audit(ctx, "EVERYDAY")
+	require.NoError(t, err)
+	require.Len(t, entries, 8)
+	for _, e := range entries[:4] {
+		assert.Equal(t, AuditEntry{At: at, Operation: "add", Change: ChangeAdded, Transaction: e.Transaction, Date: e.Date, Description: e.Description, Amount: e.Amount}, e)
+	}
+	assert.Equal(t, AuditEntry{At: at, Operation: ChangeNote, Change: ChangeNote, Transaction: id, Date: "2024-01-03", Description: "WOOLWORTHS 1234", Amount: -8215, Detail: "Weekly shop, it's split with Sam"}, entries[4])
+	assert.Equal(t, ChangeAttachment, entries[5].Change)
+	assert.Equal(t, "receipt.pdf", entries[5].Detail)
+	assert.Equal(t, []string{ChangeRemoved, ChangeAdded}, []string{entries[6].Change, entries[7].Change})
+	assert.Equal(t, "update", entries[6].Operation)
+
+	entries, err = s.Audit(ctx, "Other")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Rows is the result of an SQL query: its columns, in order, and the
+// values of each row by column. Values are nil, string, int or float64.
+type Rows struct {
+	Columns []string
+	Values  [][]any
+}
+
+// SQL runs a user's SQL query over a read-only connection. The shell's
+// safe mode refuses ATTACH, extensions and the functions reading or writing
+// other files, and dot commands are refused here, so the query can only
+// read the database.
+func (s *Store) SQL(ctx context.Context, query string) (Rows, error) {
+	for line := range strings.Lines(query) {
+		if strings.HasPrefix(strings.TrimSpace(line), ".") {
+			return Rows{}, fmt.Errorf("invalid query: line %q is a sqlite3 dot command", strings.TrimSpace(line))
+		}
+	}
+	out, err := s.run(ctx, query, "-readonly", "-safe", "-json")
+	if err != nil {
+		return Rows{}, err
+	}
+
+	// The shell prints one array per statement that returns rows
+	var rows Rows
+	dec := json.NewDecoder(bytes.NewReader(out))
+	dec.UseNumber()
+	for {
+		columns, values, err := decodeRows(dec)
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return Rows{}, fmt.Errorf("failed to decode sqlite3 output: %w", err)
+		}
+		if rows.Columns != nil && !slices.Equal(rows.Columns, columns) {
+			return Rows{}, errors.New("invalid query: its statements return different columns")
+		}
+		rows.Columns = columns
+		rows.Values = append(rows.Values, values...)
+	}
+}
+
+// decodeRows decodes an array of row objects, keeping the order of their
+// keys, which a map would lose
+func decodeRows(dec *json.Decoder) (columns []string, values [][]any, err error) {
+	if err := expectDelim(dec, '['); err != nil {
+		return nil, nil, err
+	}
+	for dec.More() {
+		if err := expectDelim(dec, '{'); err != nil {
+			return nil, nil, err
+		}
+		var names []string
+		var row []any
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, nil, err
+			}
+			var v any
+			if err := dec.Decode(&v); err != nil {
+				return nil, nil, err
+			}
+			if n, ok := v.(json.Number); ok {
+				if i, err := n.Int64(); err == nil {
+					v = int(i)
+				} else {
+					v, _ = n.Float64()
+				}
+			}
+			names = append(names, key.(string))
+			row = append(row, v)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, nil, err
+		}
+		if columns == nil {
+			columns = names
+		}
+		values = append(values, row)
+	}
+	_, err = dec.Token()
+	return columns, values, err
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return fmt.Errorf("expected %v, got %v", delim, tok)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQL(t *testing.T) {
+	s := openStore(t)
+	ctx := context.Background()
+	_, err := s.Add(ctx, sample())
+	require.NoError(t, err)
+
+	rows, err := s.SQL(ctx, "SELECT description, amount, amount / 200.0 AS half, balance FROM transactions ORDER BY date, amount LIMIT 2;")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"description", "amount", "half", "balance"}, rows.Columns)
+	assert.Equal(t, [][]any{
+		{"WOOLWORTHS 1234", -8215, -41.075, nil},
+		{"CAFE O'BRIEN", -450, -2.25, nil},
+	}, rows.Values)
+
+	rows, err = s.SQL(ctx, "SELECT * FROM transactions WHERE amount > 1000000;")
+	require.NoError(t, err)
+	assert.Empty(t, rows.Values)
+
+	// The connection is read-only and cannot reach other files
+	_, err = s.SQL(ctx, "DELETE FROM transactions;")
+	assert.ErrorContains(t, err, "readonly")
+	_, err = s.SQL(ctx, "ATTACH 'other.db' AS other;")
+	assert.Error(t, err)
+	_, err = s.SQL(ctx, "SELECT 1;\n.shell touch pwned")
+	assert.ErrorContains(t, err, "dot command")
+	count, err := s.Count(ctx, Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, len(sample()), count)
+}
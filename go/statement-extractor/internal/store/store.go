@@ -0,0 +1,695 @@
+// Package store keeps transactions in a SQLite database so that statements
+// imported over the years can be listed, totalled and searched without
+// loading every TransactionList file. The database is driven through the
+// sqlite3 command-line shell.
+package store
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/statement-extractor/internal/clock"
+	"github.com/example/statement-extractor/internal/lockfile"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// sqlite3 is the SQLite command-line shell used to access the database
+var sqlite3 = "sqlite3"
+
+// busyTimeout is how long a statement waits for another process's write
+// to finish before failing with "database is locked"
+const busyTimeout = 5 * time.Second
+
+const dateFormat = "2006-01-02"
+
+// migrations are applied in order, each once; append new ones, never edit
+// an applied one
+var migrations = []string{
+	// Amounts and balances are stored in cents, as transaction.Amount holds
+	// them, so totals are summed exactly; a balance the statement does not
+	// give is NULL. Tags are stored lower-cased between commas,
+	// ",tax-deductible,work,", so one tag can be matched with
+	// LIKE '%,work,%', and splits as the JSON of Transaction.Splits, '' when
+	// unsplit.
+	`CREATE TABLE transactions (
+		id INTEGER PRIMARY KEY,
+		hash TEXT NOT NULL UNIQUE,
+		txn_id TEXT NOT NULL DEFAULT '',
+		date TEXT NOT NULL,
+		description TEXT NOT NULL,
+		amount INTEGER NOT NULL,
+		balance INTEGER,
+		category TEXT NOT NULL DEFAULT '',
+		source TEXT NOT NULL DEFAULT '',
+		currency TEXT NOT NULL DEFAULT '',
+		account TEXT NOT NULL DEFAULT '',
+		account_type TEXT NOT NULL DEFAULT '',
+		exclude_from_reports INTEGER NOT NULL DEFAULT 0,
+		amortize_months INTEGER NOT NULL DEFAULT 0,
+		statement_file TEXT NOT NULL DEFAULT '',
+		statement_sha256 TEXT NOT NULL DEFAULT '',
+		statement_page INTEGER NOT NULL DEFAULT 0,
+		imported_at TEXT NOT NULL,
+		tags TEXT NOT NULL DEFAULT '',
+		sequence INTEGER NOT NULL DEFAULT 0,
+		category_confidence REAL NOT NULL DEFAULT 0,
+		categorized_by TEXT NOT NULL DEFAULT '',
+		raw_description TEXT NOT NULL DEFAULT '',
+		merchant TEXT NOT NULL DEFAULT '',
+		owner TEXT NOT NULL DEFAULT '',
+		splits TEXT NOT NULL DEFAULT '',
+		card TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX transactions_date ON transactions (date);
+	CREATE INDEX transactions_category ON transactions (category);`,
+
+	// Closed months lock their transactions: the triggers refuse changes to
+	// them, other than re-adding one that is already stored, until the month
+	// is reopened
+	`CREATE TABLE closed_months (
+		month TEXT PRIMARY KEY,
+		closed_at TEXT NOT NULL,
+		reopened_at TEXT NOT NULL DEFAULT '',
+		count INTEGER NOT NULL,
+		total INTEGER NOT NULL,
+		summary TEXT NOT NULL,
+		snapshot TEXT NOT NULL
+	);
+	` + closedTriggers,
+
+	// The ledger of statements sent to PDF services, by content hash
+	`CREATE TABLE processed_statements (
+		sha256 TEXT PRIMARY KEY,
+		provider TEXT NOT NULL,
+		processed_at TEXT NOT NULL
+	);`,
+
+	// Every statement extracted, for stats: the parser, the PDF service
+	// that answered, if any, and whether from the response cache
+	`CREATE TABLE extractions (
+		id INTEGER PRIMARY KEY,
+		extracted_at TEXT NOT NULL,
+		parser TEXT NOT NULL,
+		provider TEXT NOT NULL DEFAULT '',
+		cached INTEGER NOT NULL DEFAULT 0,
+		duration_ms INTEGER NOT NULL
+	);`,
+	// One row per budget envelope and month, in cents; balance is what the
+	// month left over, carried into the next by envelopes with rollover
+	`CREATE TABLE envelopes (
+		name TEXT NOT NULL,
+		month TEXT NOT NULL,
+		budget INTEGER NOT NULL,
+		carried INTEGER NOT NULL,
+		spent INTEGER NOT NULL,
+		balance INTEGER NOT NULL,
+		updated_at TEXT NOT NULL,
+		PRIMARY KEY (name, month)
+	);`,
+
+	// Notes on transactions and the files attached to them, such as
+	// receipts, by the transaction's hash so that they outlive extracting
+	// its statement again; only attachments' metadata is kept, not the files
+	`CREATE TABLE notes (
+		id INTEGER PRIMARY KEY,
+		hash TEXT NOT NULL,
+		text TEXT NOT NULL,
+		created_at TEXT NOT NULL
+	);
+	CREATE INDEX notes_hash ON notes (hash);
+	CREATE TABLE attachments (
+		id INTEGER PRIMARY KEY,
+		hash TEXT NOT NULL,
+		name TEXT NOT NULL,
+		path TEXT NOT NULL,
+		media_type TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		sha256 TEXT NOT NULL,
+		added_at TEXT NOT NULL
+	);
+	CREATE INDEX attachments_hash ON attachments (hash);`,
+
+	// The history of each transaction: when it was added and removed, by
+	// which write, and the notes and attachments added to it. Rows of
+	// removed transactions are kept.
+	`CREATE TABLE audit (
+		id INTEGER PRIMARY KEY,
+		at TEXT NOT NULL,
+		operation TEXT NOT NULL,
+		change TEXT NOT NULL,
+		hash TEXT NOT NULL,
+		txn_id TEXT NOT NULL,
+		account TEXT NOT NULL,
+		date TEXT NOT NULL,
+		description TEXT NOT NULL,
+		amount INTEGER NOT NULL,
+		detail TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX audit_account ON audit (account);`,
+}
+
+// closedTriggers refuse changes to the transactions of a closed month, other
+// than re-adding one that is already stored
+const closedTriggers = `CREATE TRIGGER closed_months_insert BEFORE INSERT ON transactions
+	WHEN EXISTS (SELECT 1 FROM closed_months WHERE month = substr(NEW.date, 1, 7) AND reopened_at = '')
+		AND NOT EXISTS (SELECT 1 FROM transactions WHERE hash = NEW.hash)
+	BEGIN SELECT RAISE(ABORT, '` + closedMessage + `'); END;
+	CREATE TRIGGER closed_months_update BEFORE UPDATE ON transactions
+	WHEN EXISTS (SELECT 1 FROM closed_months WHERE month IN (substr(OLD.date, 1, 7), substr(NEW.date, 1, 7)) AND reopened_at = '')
+	BEGIN SELECT RAISE(ABORT, '` + closedMessage + `'); END;
+	CREATE TRIGGER closed_months_delete BEFORE DELETE ON transactions
+	WHEN EXISTS (SELECT 1 FROM closed_months WHERE month = substr(OLD.date, 1, 7) AND reopened_at = '')
+	BEGIN SELECT RAISE(ABORT, '` + closedMessage + `'); END;`
+
+// Store is a SQLite transaction database. Writes hold the database's
+// lockfile, so that watch and a command run by hand never write at once.
+type Store struct {
+	path string
+	wait time.Duration // how long a write waits for another process's lock
+}
+
+// Open opens the database at path, creating it if needed, and applies any
+// pending migrations. A write fails with lockfile.ErrBusy at once while
+// another process is writing; see OpenWait.
+func Open(ctx context.Context, path string) (*Store, error) {
+	return OpenWait(ctx, path, 0)
+}
+
+// OpenWait is Open but a write waits up to wait for another process to
+// finish writing before failing with lockfile.ErrBusy
+func OpenWait(ctx context.Context, path string, wait time.Duration) (*Store, error) {
+	if _, err := exec.LookPath(sqlite3); err != nil {
+		return nil, errors.New("the transaction database needs the sqlite3 command on PATH")
+	}
+	// A leading "-" would be read as an option by the shell
+	if strings.HasPrefix(path, "-") {
+		path = "./" + path
+	}
+	s := &Store{path: path, wait: wait}
+	if err := s.migrate(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Version returns the number of migrations applied to the database
+func (s *Store) Version(ctx context.Context) (int, error) {
+	var rows []struct {
+		Version int `json:"version"`
+	}
+	if err := s.query(ctx, "SELECT COALESCE(MAX(version), 0) AS version FROM schema_migrations;", &rows); err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	return rows[0].Version, nil
+}
+
+func (s *Store) migrate(ctx context.Context) error {
+	// Opening an up-to-date database only reads it
+	if version, err := s.Version(ctx); err == nil && version == len(migrations) {
+		return nil
+	}
+	lock, err := s.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	if err := s.exec(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, applied_at TEXT NOT NULL);"); err != nil {
+		return fmt.Errorf("failed to open transaction database: %w", err)
+	}
+	version, err := s.Version(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open transaction database: %w", err)
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("transaction database %s is at schema version %d; this build understands up to %d", s.path, version, len(migrations))
+	}
+
+	// Each migration commits together with its version row, so an
+	// interrupted upgrade resumes from the first unapplied migration
+	for i := version; i < len(migrations); i++ {
+		script := fmt.Sprintf("BEGIN;\n%s\nINSERT INTO schema_migrations VALUES (%d, %s);\nCOMMIT;",
+			migrations[i], i+1, quote(clock.From(ctx).Now().UTC().Format(time.RFC3339)))
+		if err := s.exec(ctx, script); err != nil {
+			return fmt.Errorf("failed to migrate transaction database to version %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Add inserts txns, skipping any whose hash is already stored, and returns
+// the number inserted
+func (s *Store) Add(ctx context.Context, txns []transaction.Transaction) (int, error) {
+	if len(txns) == 0 {
+		return 0, nil
+	}
+
+	lock, err := s.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer lock.Release()
+
+	now := clock.From(ctx).Now()
+	var b strings.Builder
+	b.WriteString("BEGIN;\n" + auditTriggers("add", now))
+	writeInserts(&b, txns, now)
+	b.WriteString(changeCounts + "COMMIT;")
+
+	var rows []struct {
+		Added int `json:"added"`
+	}
+	if err := s.query(ctx, b.String(), &rows); err != nil {
+		return 0, fmt.Errorf("failed to store transactions: %w", closedError(err))
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	return rows[0].Added, nil
+}
+
+// Replace deletes the transactions matching f and adds txns in their place
+// in one transaction, as when part of a statement is extracted again. It
+// returns the number removed and the number added.
+func (s *Store) Replace(ctx context.Context, f Filter, txns []transaction.Transaction) (removed, added int, err error) {
+	lock, err := s.lock(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer lock.Release()
+
+	now := clock.From(ctx).Now()
+	var b strings.Builder
+	b.WriteString("BEGIN;\n" + auditTriggers("replace", now))
+	fmt.Fprintf(&b, "DELETE FROM transactions WHERE %s;\n", f.where())
+	writeInserts(&b, txns, now)
+	b.WriteString(changeCounts + "COMMIT;")
+
+	var rows []struct {
+		Removed int `json:"removed"`
+		Added   int `json:"added"`
+	}
+	if err := s.query(ctx, b.String(), &rows); err != nil {
+		return 0, 0, fmt.Errorf("failed to replace transactions: %w", closedError(err))
+	}
+	if len(rows) == 0 {
+		return 0, 0, nil
+	}
+	return rows[0].Removed, rows[0].Added, nil
+}
+
+// Update removes each transaction in remove, as List returned it, and adds
+// add in one transaction, as when an import replaces the duplicates it
+// found. It returns the number removed and the number added.
+func (s *Store) Update(ctx context.Context, remove, add []transaction.Transaction) (removed, added int, err error) {
+	lock, err := s.lock(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer lock.Release()
+
+	now := clock.From(ctx).Now()
+	var b strings.Builder
+	b.WriteString("BEGIN;\n" + auditTriggers("update", now))
+	for _, t := range remove {
+		var ref transaction.StatementRef
+		if t.Statement != nil {
+			ref = *t.Statement
+		}
+		fmt.Fprintf(&b, "DELETE FROM transactions WHERE id = (SELECT id FROM transactions WHERE txn_id = %s AND date = %s AND description = %s AND amount = %d AND account = %s AND source = %s AND card = %s AND sequence = %d AND statement_sha256 = %s ORDER BY id LIMIT 1);\n",
+			quote(t.ID), quote(t.Date.Format(dateFormat)), quote(t.Description), int64(t.Amount), quote(t.Account), quote(t.Source), quote(t.Card), t.Sequence, quote(ref.SHA256))
+	}
+	writeInserts(&b, add, now)
+	b.WriteString(changeCounts + "COMMIT;")
+
+	var rows []struct {
+		Removed int `json:"removed"`
+		Added   int `json:"added"`
+	}
+	if err := s.query(ctx, b.String(), &rows); err != nil {
+		return 0, 0, fmt.Errorf("failed to update transactions: %w", closedError(err))
+	}
+	if len(rows) == 0 {
+		return 0, 0, nil
+	}
+	return rows[0].Removed, rows[0].Added, nil
+}
+
+// writeInserts writes an INSERT for each transaction, stamped as imported
+// at now, skipping any whose hash is already stored
+func writeInserts(b *strings.Builder, txns []transaction.Transaction, now time.Time) {
+	hashes := hashTransactions(txns)
+	importedAt := quote(now.UTC().Format(time.RFC3339))
+	for i, t := range txns {
+		var ref transaction.StatementRef
+		if t.Statement != nil {
+			ref = *t.Statement
+		}
+		fmt.Fprintf(b, "INSERT INTO transactions (hash, txn_id, date, description, amount, balance, category, source, currency, account, account_type, exclude_from_reports, amortize_months, statement_file, statement_sha256, statement_page, tags, sequence, category_confidence, categorized_by, raw_description, merchant, owner, splits, card, imported_at)\n"+
+			"VALUES (%s, %s, %s, %s, %d, %s, %s, %s, %s, %s, %s, %d, %d, %s, %s, %d, %s, %d, %s, %s, %s, %s, %s, %s, %s, %s) ON CONFLICT (hash) DO NOTHING;\n",
+			quote(hashes[i]), quote(t.ID), quote(t.Date.Format(dateFormat)), quote(t.Description),
+			int64(t.Amount), balance(t.Balance), quote(t.Category), quote(t.Source), quote(t.Currency),
+			quote(t.Account), quote(t.AccountType), boolInt(t.ExcludeFromReports), t.AmortizeMonths,
+			quote(ref.File), quote(ref.SHA256), ref.Page, quote(encodeTags(t.Tags)), t.Sequence,
+			number(t.CategoryConfidence), quote(t.CategorizedBy), quote(t.RawDescription), quote(t.Merchant), quote(t.Owner), quote(encodeSplits(t.Splits)), quote(t.Card), importedAt)
+	}
+}
+
+// hashTransactions returns the hash identifying each transaction in the
+// store: a digest of its transaction.Key, which dedup matches exactly too,
+// plus its position among transactions with the same key in the same import
+// so that two equal purchases on one day are both kept
+func hashTransactions(txns []transaction.Transaction) []string {
+	hashes := make([]string, len(txns))
+	seen := make(map[string]int)
+	for i, t := range txns {
+		key := transaction.Key(t)
+		seen[key]++
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d", key, seen[key])))
+		hashes[i] = hex.EncodeToString(sum[:])
+	}
+	return hashes
+}
+
+// Filter selects stored transactions; zero fields match everything
+type Filter struct {
+	From, To time.Time // dates from From up to but excluding To
+	Category string    // exact category, case-insensitive
+	Account  string    // exact account, case-insensitive
+	Text     string    // substring of the description, case-insensitive
+	Tag      string    // tag, case-insensitive
+	Card     string    // last digits of the card number
+
+	Statement           string // SHA-256 of the statement file
+	FirstPage, LastPage int    // statement pages, inclusive; zero is unbounded
+}
+
+func (f Filter) where() string {
+	clauses := []string{"1 = 1"}
+	if !f.From.IsZero() {
+		clauses = append(clauses, "date >= "+quote(f.From.Format(dateFormat)))
+	}
+	if !f.To.IsZero() {
+		clauses = append(clauses, "date < "+quote(f.To.Format(dateFormat)))
+	}
+	if f.Category != "" {
+		// A split transaction is in each category it is split into
+		clauses = append(clauses, fmt.Sprintf(`(CASE splits WHEN '' THEN category = %[1]s COLLATE NOCASE
+			ELSE EXISTS (SELECT 1 FROM json_each(splits) AS part WHERE COALESCE(json_extract(part.value, '$.category'), category) = %[1]s COLLATE NOCASE) END)`,
+			quote(f.Category)))
+	}
+	if f.Account != "" {
+		clauses = append(clauses, "account = "+quote(f.Account)+" COLLATE NOCASE")
+	}
+	if f.Text != "" {
+		escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(f.Text)
+		clauses = append(clauses, "description LIKE "+quote("%"+escaped+"%")+` ESCAPE '\'`)
+	}
+	if f.Statement != "" {
+		clauses = append(clauses, "statement_sha256 = "+quote(f.Statement))
+	}
+	if f.FirstPage > 0 {
+		clauses = append(clauses, fmt.Sprintf("statement_page >= %d", f.FirstPage))
+	}
+	if f.LastPage > 0 {
+		clauses = append(clauses, fmt.Sprintf("statement_page <= %d", f.LastPage))
+	}
+	if f.Tag != "" {
+		clauses = append(clauses, "instr(tags, "+quote(encodeTags([]string{f.Tag}))+") > 0")
+	}
+	if f.Card != "" {
+		clauses = append(clauses, "card <> '' AND substr(card, -length("+quote(f.Card)+")) = "+quote(f.Card))
+	}
+	return strings.Join(clauses, " AND ")
+}
+
+// row is a transactions row as printed by the shell's JSON mode
+type row struct {
+	TxnID              string  `json:"txn_id"`
+	Date               string  `json:"date"`
+	Description        string  `json:"description"`
+	Amount             int64   `json:"amount"`
+	Balance            *int64  `json:"balance"`
+	Category           string  `json:"category"`
+	Source             string  `json:"source"`
+	Currency           string  `json:"currency"`
+	Account            string  `json:"account"`
+	AccountType        string  `json:"account_type"`
+	ExcludeFromReports int     `json:"exclude_from_reports"`
+	AmortizeMonths     int     `json:"amortize_months"`
+	StatementFile      string  `json:"statement_file"`
+	StatementSHA256    string  `json:"statement_sha256"`
+	StatementPage      int     `json:"statement_page"`
+	Tags               string  `json:"tags"`
+	Sequence           int     `json:"sequence"`
+	CategoryConfidence float64 `json:"category_confidence"`
+	CategorizedBy      string  `json:"categorized_by"`
+	RawDescription     string  `json:"raw_description"`
+	Merchant           string  `json:"merchant"`
+	Owner              string  `json:"owner"`
+	Splits             string  `json:"splits"`
+	Card               string  `json:"card"`
+}
+
+// List returns the transactions matching f by date, then in import order
+func (s *Store) List(ctx context.Context, f Filter) ([]transaction.Transaction, error) {
+	var rows []row
+	sql := "SELECT txn_id, date, description, amount, balance, category, source, currency, account, account_type, exclude_from_reports, amortize_months, statement_file, statement_sha256, statement_page, tags, sequence, category_confidence, categorized_by, raw_description, merchant, owner, splits, card FROM transactions WHERE " +
+		f.where() + " ORDER BY date, sequence, id;"
+	if err := s.query(ctx, sql, &rows); err != nil {
+		return nil, fmt.Errorf("failed to query transactions: %w", err)
+	}
+
+	txns := make([]transaction.Transaction, 0, len(rows))
+	for _, r := range rows {
+		date, err := time.Parse(dateFormat, r.Date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q in transaction database: %w", r.Date, err)
+		}
+		t := transaction.Transaction{
+			ID:                 r.TxnID,
+			Date:               date,
+			Description:        r.Description,
+			Amount:             transaction.Amount(r.Amount),
+			Balance:            (*transaction.Amount)(r.Balance),
+			Category:           r.Category,
+			Source:             r.Source,
+			Currency:           r.Currency,
+			Account:            r.Account,
+			AccountType:        r.AccountType,
+			ExcludeFromReports: r.ExcludeFromReports != 0,
+			AmortizeMonths:     r.AmortizeMonths,
+			Tags:               decodeTags(r.Tags),
+			Sequence:           r.Sequence,
+			CategoryConfidence: r.CategoryConfidence,
+			CategorizedBy:      r.CategorizedBy,
+			RawDescription:     r.RawDescription,
+			Merchant:           r.Merchant,
+			Owner:              r.Owner,
+			Card:               r.Card,
+		}
+		if r.Splits != "" {
+			if err := json.Unmarshal([]byte(r.Splits), &t.Splits); err != nil {
+				return nil, fmt.Errorf("invalid splits %q in transaction database: %w", r.Splits, err)
+			}
+		}
+		if r.StatementFile != "" {
+			t.Statement = &transaction.StatementRef{File: r.StatementFile, SHA256: r.StatementSHA256, Page: r.StatementPage}
+		}
+		txns = append(txns, t)
+	}
+	return txns, nil
+}
+
+// Groupings are the ways Sum can break totals down
+var Groupings = []string{"category", "account", "owner", "card", "month", "source"}
+
+// Total is the sum of the transactions in one group
+type Total struct {
+	Group  string             `json:"grp"`
+	Count  int                `json:"count"`
+	Amount transaction.Amount `json:"amount"`
+}
+
+// allocations lists each transaction once per split, with the split's
+// category and amount, so category totals count each part in its own
+// category
+const allocations = `(SELECT transactions.*,
+		COALESCE(json_extract(split.value, '$.category'), category) AS allocated_category,
+		COALESCE(CAST(ROUND(json_extract(split.value, '$.amount') * 100) AS INTEGER), amount) AS allocated_amount
+	FROM transactions LEFT JOIN json_each(CASE splits WHEN '' THEN '[]' ELSE splits END) AS split)`
+
+// Sum totals the transactions matching f by one of Groupings, sorted by
+// group. Split transactions count in each category they are split into,
+// and with f.Category only their parts in that category are totalled.
+func (s *Store) Sum(ctx context.Context, f Filter, by string) ([]Total, error) {
+	var group string
+	from, amount, where := "transactions", "amount", f.where()
+	if f.Category != "" {
+		from, amount = allocations, "allocated_amount"
+		where += " AND allocated_category = " + quote(f.Category) + " COLLATE NOCASE"
+	}
+	switch by {
+	case "category":
+		group, from, amount = "allocated_category", allocations, "allocated_amount"
+	case "account", "owner", "card", "source":
+		group = by
+	case "month":
+		group = "substr(date, 1, 7)"
+	default:
+		return nil, fmt.Errorf("unknown grouping %q (expected %s)", by, strings.Join(Groupings, ", "))
+	}
+
+	// Amounts are summed in cents, which the shell prints as integers
+	var rows []struct {
+		Group  string `json:"grp"`
+		Count  int    `json:"count"`
+		Amount int64  `json:"amount"`
+	}
+	sql := fmt.Sprintf("SELECT %s AS grp, COUNT(DISTINCT id) AS count, SUM(%s) AS amount FROM %s WHERE %s GROUP BY grp ORDER BY grp;",
+		group, amount, from, where)
+	if err := s.query(ctx, sql, &rows); err != nil {
+		return nil, fmt.Errorf("failed to sum transactions: %w", err)
+	}
+	totals := make([]Total, len(rows))
+	for i, r := range rows {
+		totals[i] = Total{Group: r.Group, Count: r.Count, Amount: transaction.Amount(r.Amount)}
+	}
+	return totals, nil
+}
+
+// Count returns the number of transactions matching f, counting a split
+// transaction once
+func (s *Store) Count(ctx context.Context, f Filter) (int, error) {
+	var rows []struct {
+		Count int `json:"count"`
+	}
+	if err := s.query(ctx, "SELECT COUNT(*) AS count FROM transactions WHERE "+f.where()+";", &rows); err != nil {
+		return 0, fmt.Errorf("failed to count transactions: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	return rows[0].Count, nil
+}
+
+// encodeSplits returns the splits column of a transaction
+func encodeSplits(splits []transaction.Split) string {
+	if len(splits) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(splits)
+	return string(b)
+}
+
+// encodeTags joins tags for the tags column
+func encodeTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return "," + strings.ToLower(strings.Join(tags, ",")) + ","
+}
+
+func decodeTags(s string) []string {
+	if s = strings.Trim(s, ","); s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// lock takes the database's lockfile for a write
+func (s *Store) lock(ctx context.Context) (*lockfile.Lock, error) {
+	return lockfile.Acquire(ctx, s.path, s.wait)
+}
+
+// exec runs an SQL script
+func (s *Store) exec(ctx context.Context, script string) error {
+	_, err := s.run(ctx, script)
+	return err
+}
+
+// query runs an SQL script and decodes the rows its statements print into
+// dest
+func (s *Store) query(ctx context.Context, script string, dest any) error {
+	out, err := s.run(ctx, script, "-json")
+	if err != nil {
+		return err
+	}
+	// The shell prints nothing, not an empty array, for no rows; and one
+	// array per statement, of which a script here has only one that prints
+	out = bytes.TrimSpace(out)
+	if len(out) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(out, dest); err != nil {
+		return fmt.Errorf("failed to decode sqlite3 output: %w", err)
+	}
+	return nil
+}
+
+// run runs an SQL script through the sqlite3 shell, passing it the options
+func (s *Store) run(ctx context.Context, script string, options ...string) ([]byte, error) {
+	bin, err := exec.LookPath(sqlite3)
+	if err != nil {
+		return nil, errors.New("the transaction database needs the sqlite3 command on PATH")
+	}
+
+	// -init skips the user's ~/.sqliterc, whose settings could change the
+	// output format; -bail stops at the first error, rolling back an open
+	// transaction
+	args := append([]string{"-init", os.DevNull, "-batch", "-bail"}, options...)
+	args = append(args, s.path)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd.Stdin = strings.NewReader(fmt.Sprintf(".timeout %d\n%s\n", busyTimeout.Milliseconds(), script))
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("sqlite3 failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// quote returns s as an SQL string literal. Line breaks are spliced in
+// with char() so that no line of a script starts inside a literal, where
+// the shell could mistake it for a dot command.
+func quote(s string) string {
+	s = strings.ReplaceAll(s, "\x00", "")
+	s = strings.ReplaceAll(s, "'", "''")
+	s = strings.NewReplacer("\r", "' || char(13) || '", "\n", "' || char(10) || '").Replace(s)
+	return "'" + s + "'"
+}
+
+// balance returns the balance column of a transaction, NULL when the
+// statement gives none
+func balance(a *transaction.Amount) string {
+	if a == nil {
+		return "NULL"
+	}
+	return strconv.FormatInt(int64(*a), 10)
+}
+
+func number(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,265 @@
+package store
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/lockfile"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func openStore(t *testing.T) *Store {
+	t.Helper()
+	if _, err := exec.LookPath(sqlite3); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	s, err := Open(context.Background(), filepath.Join(t.TempDir(), "txns.db"))
+	require.NoError(t, err)
+	return s
+}
+
+func date(s string) time.Time {
+	d, _ := time.Parse("2006-01-02", s)
+	return d
+}
+
+func sample() []transaction.Transaction {
+	return []transaction.Transaction{
+		{Date: date("2024-01-03"), Description: "WOOLWORTHS 1234", RawDescription: "WOOLWORTHS 1234 SYDNEY NS AUS", Merchant: "WOOLWORTHS", Amount: -8215, Category: "Groceries", Source: "CBA", Account: "Everyday"},
+		{Date: date("2024-01-05"), Description: "CAFE O'BRIEN", Amount: -450, Category: "Dining", Source: "CBA", Account: "Everyday", Card: "4521", Tags: []string{"work"}},
+		{Date: date("2024-01-05"), Description: "CAFE O'BRIEN", Amount: -450, Category: "Dining", Source: "CBA", Account: "Everyday"},
+		{Date: date("2024-02-01"), Description: "SALARY\nACME", Amount: 300000, Category: "Income", CategoryConfidence: 0.75, CategorizedBy: "llm", Source: "CBA", Account: "Everyday", Owner: "Sam",
+			Tags: []string{"work", "tax-deductible"}, Statement: &transaction.StatementRef{File: "feb.pdf", SHA256: "abc", Page: 2}},
+	}
+}
+
+func TestOpenMigrates(t *testing.T) {
+	s := openStore(t)
+	ctx := context.Background()
+
+	version, err := s.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(migrations), version)
+
+	reopened, err := Open(ctx, s.path)
+	require.NoError(t, err)
+	version, err = reopened.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(migrations), version, "migrations are applied once")
+}
+
+func TestOpenNewerSchema(t *testing.T) {
+	s := openStore(t)
+	require.NoError(t, s.exec(context.Background(), "INSERT INTO schema_migrations VALUES (99, '');"))
+	_, err := Open(context.Background(), s.path)
+	assert.ErrorContains(t, err, "schema version 99")
+}
+
+func TestAddSkipsStored(t *testing.T) {
+	s := openStore(t)
+	ctx := context.Background()
+
+	added, err := s.Add(ctx, sample())
+	require.NoError(t, err)
+	assert.Equal(t, 4, added, "identical purchases on one day are both kept")
+
+	added, err = s.Add(ctx, sample())
+	require.NoError(t, err)
+	assert.Zero(t, added, "a repeated import adds nothing")
+
+	txns, err := s.List(ctx, Filter{})
+	require.NoError(t, err)
+	require.Len(t, txns, 4)
+	assert.Equal(t, sample()[3], txns[3], "fields round-trip, including quotes and line breaks")
+	assert.Equal(t, sample()[0], txns[0])
+}
+
+func TestListSequence(t *testing.T) {
+	s := openStore(t)
+	ctx := context.Background()
+
+	// Stored in the opposite order to the statement's
+	txns := sample()[1:3]
+	txns[0].Description, txns[0].Sequence = "SECOND", 2
+	txns[1].Description, txns[1].Sequence = "FIRST", 1
+	_, err := s.Add(ctx, txns)
+	require.NoError(t, err)
+
+	listed, err := s.List(ctx, Filter{})
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+	assert.Equal(t, "FIRST", listed[0].Description, "same-day transactions are listed in sequence")
+	assert.Equal(t, 2, listed[1].Sequence)
+}
+
+func TestListFilter(t *testing.T) {
+	s := openStore(t)
+	ctx := context.Background()
+	_, err := s.Add(ctx, sample())
+	require.NoError(t, err)
+
+	txns, err := s.List(ctx, Filter{From: date("2024-01-04"), To: date("2024-02-01")})
+	require.NoError(t, err)
+	assert.Len(t, txns, 2)
+
+	txns, err = s.List(ctx, Filter{Text: "o'brien", Category: "dining"})
+	require.NoError(t, err)
+	assert.Len(t, txns, 2)
+
+	txns, err = s.List(ctx, Filter{Tag: "Work"})
+	require.NoError(t, err)
+	assert.Len(t, txns, 2)
+	txns, err = s.List(ctx, Filter{Tag: "tax"})
+	require.NoError(t, err)
+	assert.Empty(t, txns, "tags match whole")
+
+	txns, err = s.List(ctx, Filter{Text: "%"})
+	require.NoError(t, err)
+	assert.Empty(t, txns, "LIKE wildcards are matched literally")
+}
+
+func TestSum(t *testing.T) {
+	s := openStore(t)
+	ctx := context.Background()
+	_, err := s.Add(ctx, sample())
+	require.NoError(t, err)
+
+	totals, err := s.Sum(ctx, Filter{}, "month")
+	require.NoError(t, err)
+	assert.Equal(t, []Total{
+		{Group: "2024-01", Count: 3, Amount: -9115},
+		{Group: "2024-02", Count: 1, Amount: 300000},
+	}, totals)
+
+	totals, err = s.Sum(ctx, Filter{Card: "21"}, "card")
+	require.NoError(t, err)
+	assert.Equal(t, []Total{{Group: "4521", Count: 1, Amount: -450}}, totals)
+
+	totals, err = s.Sum(ctx, Filter{}, "owner")
+	require.NoError(t, err)
+	assert.Equal(t, []Total{{Group: "", Count: 3, Amount: -9115}, {Group: "Sam", Count: 1, Amount: 300000}}, totals)
+
+	totals, err = s.Sum(ctx, Filter{Account: "savings"}, "category")
+	require.NoError(t, err)
+	assert.Empty(t, totals)
+
+	_, err = s.Sum(ctx, Filter{}, "payee")
+	assert.ErrorContains(t, err, "unknown grouping")
+
+	split := sample()[0]
+	split.Date = date("2024-03-01")
+	split.Splits = []transaction.Split{{Category: "Groceries", Amount: -6000}, {Category: "Household", Amount: -2215}}
+	_, err = s.Add(ctx, []transaction.Transaction{split})
+	require.NoError(t, err)
+	totals, err = s.Sum(ctx, Filter{From: date("2024-03-01")}, "category")
+	require.NoError(t, err)
+	assert.Equal(t, []Total{{Group: "Groceries", Count: 1, Amount: -6000}, {Group: "Household", Count: 1, Amount: -2215}}, totals, "splits count in their own categories")
+	count, err := s.Count(ctx, Filter{From: date("2024-03-01")})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count, "a split transaction is one transaction")
+	txns, err := s.List(ctx, Filter{From: date("2024-03-01")})
+	require.NoError(t, err)
+	assert.Equal(t, split, txns[0])
+
+	txns, err = s.List(ctx, Filter{Category: "household"})
+	require.NoError(t, err)
+	assert.Equal(t, []transaction.Transaction{split}, txns, "a split transaction is in each of its categories")
+	totals, err = s.Sum(ctx, Filter{Category: "household"}, "month")
+	require.NoError(t, err)
+	assert.Equal(t, []Total{{Group: "2024-03", Count: 1, Amount: -2215}}, totals, "only the part in the category counts")
+	totals, err = s.Sum(ctx, Filter{From: date("2024-03-01"), Category: "Groceries"}, "category")
+	require.NoError(t, err)
+	assert.Equal(t, []Total{{Group: "Groceries", Count: 1, Amount: -6000}}, totals)
+}
+
+func TestOpenWithoutSqlite(t *testing.T) {
+	old := sqlite3
+	sqlite3 = "sqlite3-not-installed"
+	defer func() { sqlite3 = old }()
+
+	path := filepath.Join(t.TempDir(), "txns.db")
+	_, err := Open(context.Background(), path)
+	assert.ErrorContains(t, err, "sqlite3 command")
+	assert.NoFileExists(t, path)
+}
+
+func TestBusy(t *testing.T) {
+	s := openStore(t)
+	ctx := context.Background()
+
+	lock, err := lockfile.Acquire(ctx, s.path, 0)
+	require.NoError(t, err)
+	_, err = s.Add(ctx, sample())
+	assert.ErrorIs(t, err, lockfile.ErrBusy)
+	txns, err := s.List(ctx, Filter{})
+	require.NoError(t, err, "reading does not need the lock")
+	assert.Empty(t, txns)
+
+	waiting, err := OpenWait(ctx, s.path, 2*time.Second)
+	require.NoError(t, err)
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		lock.Release()
+	}()
+	added, err := waiting.Add(ctx, sample())
+	require.NoError(t, err)
+	assert.Equal(t, len(sample()), added)
+}
+
+func TestReplace(t *testing.T) {
+	s := openStore(t)
+	ctx := context.Background()
+	_, err := s.Add(ctx, sample())
+	require.NoError(t, err)
+
+	fixed := sample()[3]
+	fixed.Description = "SALARY ACME PTY"
+	removed, added, err := s.Replace(ctx, Filter{Statement: "abc", FirstPage: 2, LastPage: 2}, []transaction.Transaction{fixed})
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, 1, added)
+
+	txns, err := s.List(ctx, Filter{})
+	require.NoError(t, err)
+	require.Len(t, txns, 4)
+	assert.Equal(t, "SALARY ACME PTY", txns[3].Description)
+
+	removed, added, err = s.Replace(ctx, Filter{Statement: "none"}, nil)
+	require.NoError(t, err)
+	assert.Zero(t, removed+added)
+}
+
+func TestUpdate(t *testing.T) {
+	s := openStore(t)
+	ctx := context.Background()
+	_, err := s.Add(ctx, sample())
+	require.NoError(t, err)
+	stored, err := s.List(ctx, Filter{Text: "cafe"})
+	require.NoError(t, err)
+	require.Len(t, stored, 2)
+
+	fixed := stored[0]
+	fixed.Category = "Coffee"
+	extra := sample()[0]
+	extra.Date = date("2024-03-01")
+	removed, added, err := s.Update(ctx, stored[:1], []transaction.Transaction{fixed, extra})
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed, "only one of two identical transactions is removed")
+	assert.Equal(t, 2, added)
+
+	txns, err := s.List(ctx, Filter{Text: "cafe"})
+	require.NoError(t, err)
+	require.Len(t, txns, 2)
+	assert.Equal(t, "Dining", txns[0].Category)
+	assert.Empty(t, txns[0].Card, "the transaction removed is the one listed")
+	assert.Equal(t, "Coffee", txns[1].Category)
+	count, err := s.Count(ctx, Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, 5, count)
+}
@@ -0,0 +1,100 @@
+// Package systemd integrates with systemd service management: readiness
+// and status notifications over $NOTIFY_SOCKET and socket activation via
+// $LISTEN_FDS. Both are no-ops outside systemd.
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenFDsStart is the first file descriptor passed by socket activation
+const listenFDsStart = 3
+
+// Notify sends a state string such as "READY=1" to the service manager. It
+// returns false without error when not running under systemd with
+// Type=notify.
+func Notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+	// Abstract sockets are written with a leading "@"
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("connecting to notify socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("sending notification: %w", err)
+	}
+	return true, nil
+}
+
+// Ready tells systemd that startup has finished
+func Ready() error {
+	_, err := Notify("READY=1")
+	return err
+}
+
+// Stopping tells systemd that shutdown has begun
+func Stopping() error {
+	_, err := Notify("STOPPING=1")
+	return err
+}
+
+// Status sets the free-form status shown by systemctl status
+func Status(format string, args ...any) error {
+	_, err := Notify("STATUS=" + fmt.Sprintf(format, args...))
+	return err
+}
+
+// Listeners returns the sockets passed by systemd socket activation, in the
+// order of the socket unit's Listen directives, or nil when the process was
+// not socket activated. The environment variables are cleared so child
+// processes do not inherit them.
+func Listeners() ([]net.Listener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	listeners := make([]net.Listener, 0, count)
+	for i := range count {
+		fd := listenFDsStart + i
+		name := "LISTEN_FD_" + strconv.Itoa(fd)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(fd), name)
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("socket %s: %w", name, err)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
@@ -0,0 +1,53 @@
+package systemd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", path)
+
+	require.NoError(t, Ready())
+	require.NoError(t, Status("processing %d statements", 3))
+
+	buf := make([]byte, 256)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "READY=1", string(buf[:n]))
+	n, err = conn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "STATUS=processing 3 statements", string(buf[:n]))
+}
+
+func TestNotify_NotSystemd(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	sent, err := Notify("READY=1")
+	assert.NoError(t, err)
+	assert.False(t, sent)
+
+	t.Setenv("NOTIFY_SOCKET", filepath.Join(t.TempDir(), "missing.sock"))
+	_, err = Notify("READY=1")
+	assert.ErrorContains(t, err, "connecting to notify socket")
+}
+
+func TestListeners_NotActivated(t *testing.T) {
+	t.Setenv("LISTEN_FDS", "1")
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+
+	listeners, err := Listeners()
+	assert.NoError(t, err)
+	assert.Nil(t, listeners, "sockets meant for another process are ignored")
+	_, set := os.LookupEnv("LISTEN_FDS")
+	assert.False(t, set, "environment is cleared")
+}
@@ -0,0 +1,291 @@
+// Package table renders aligned, sortable text tables for CLI output so that
+// every listing command supports the same column selection, sorting and
+// limiting behaviour.
+package table
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Kind determines how a column's values are formatted, aligned and sorted
+type Kind int
+
+const (
+	Text   Kind = iota // left aligned, sorted case-insensitively
+	Number             // right aligned integer or float
+	Money              // right aligned, two decimal places
+	Date               // YYYY-MM-DD
+)
+
+// Column describes one table column
+type Column struct {
+	Name   string // key used by --columns and --sort
+	Header string // defaults to the upper-cased Name
+	Kind   Kind
+}
+
+func (c Column) header() string {
+	if c.Header != "" {
+		return c.Header
+	}
+	return strings.ToUpper(c.Name)
+}
+
+// Styler decorates rendered text, e.g. with terminal colors. It receives the
+// unpadded text so that escape sequences do not affect alignment.
+type Styler interface {
+	Header(text string) string
+	Cell(column Column, value any, text string) string
+}
+
+// Options controls which rows and columns are rendered
+type Options struct {
+	Columns []string // column names to show, in order; empty shows all
+	Sort    []string // column names to sort by; a "-" prefix sorts descending
+	Limit   int      // maximum rows to show; zero shows all
+	Style   Styler   // optional
+
+	// FormatMoney formats Money cells; defaults to two decimal places
+	FormatMoney func(float64) string
+	// FormatDate formats dates; defaults to YYYY-MM-DD
+	FormatDate func(time.Time) string
+	// FormatNumber formats non-integral Number cells; defaults to %g
+	FormatNumber func(float64) string
+}
+
+// Table accumulates rows for rendering
+type Table struct {
+	Columns []Column
+	rows    [][]any
+}
+
+// New creates a table with the given columns
+func New(columns ...Column) *Table {
+	return &Table{Columns: columns}
+}
+
+// Append adds a row; values are matched to columns by position. Values with
+// a Float method, such as transaction amounts, are stored as float64 so they
+// format and sort as numbers.
+func (t *Table) Append(values ...any) {
+	for i, v := range values {
+		if f, ok := v.(interface{ Float() float64 }); ok {
+			values[i] = f.Float()
+		}
+	}
+	t.rows = append(t.rows, values)
+}
+
+// Len returns the number of rows
+func (t *Table) Len() int {
+	return len(t.rows)
+}
+
+// Render writes the table to w
+func (t *Table) Render(w io.Writer, opts Options) error {
+	columns, err := t.selectColumns(opts.Columns)
+	if err != nil {
+		return err
+	}
+
+	rows := slices.Clone(t.rows)
+	if err := t.sortRows(rows, opts.Sort); err != nil {
+		return err
+	}
+	if opts.Limit > 0 && len(rows) > opts.Limit {
+		rows = rows[:opts.Limit]
+	}
+
+	cells := make([][]string, 0, len(rows)+1)
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = t.Columns[c].header()
+	}
+	cells = append(cells, header)
+	for _, row := range rows {
+		line := make([]string, len(columns))
+		for i, c := range columns {
+			line[i] = format(t.Columns[c].Kind, value(row, c), opts)
+		}
+		cells = append(cells, line)
+	}
+
+	widths := make([]int, len(columns))
+	for _, line := range cells {
+		for i, cell := range line {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+
+	for r, line := range cells {
+		var b strings.Builder
+		for i, cell := range line {
+			if i > 0 {
+				b.WriteString("  ")
+			}
+			column := t.Columns[columns[i]]
+			pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			if i == len(line)-1 && !rightAligned(column.Kind) {
+				pad = ""
+			}
+
+			if opts.Style != nil {
+				if r == 0 {
+					cell = opts.Style.Header(cell)
+				} else {
+					cell = opts.Style.Cell(column, value(rows[r-1], columns[i]), cell)
+				}
+			}
+
+			if rightAligned(column.Kind) {
+				b.WriteString(pad + cell)
+			} else {
+				b.WriteString(cell + pad)
+			}
+		}
+		if _, err := fmt.Fprintln(w, strings.TrimRight(b.String(), " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selectColumns resolves column names to indexes
+func (t *Table) selectColumns(names []string) ([]int, error) {
+	if len(names) == 0 {
+		all := make([]int, len(t.Columns))
+		for i := range t.Columns {
+			all[i] = i
+		}
+		return all, nil
+	}
+
+	selected := make([]int, 0, len(names))
+	for _, name := range names {
+		i, err := t.column(name)
+		if err != nil {
+			return nil, err
+		}
+		selected = append(selected, i)
+	}
+	return selected, nil
+}
+
+func (t *Table) column(name string) (int, error) {
+	name = strings.TrimSpace(name)
+	for i, c := range t.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return i, nil
+		}
+	}
+
+	names := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		names[i] = c.Name
+	}
+	return 0, fmt.Errorf("unknown column %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+func (t *Table) sortRows(rows [][]any, keys []string) error {
+	type sortKey struct {
+		column int
+		desc   bool
+	}
+
+	var parsed []sortKey
+	for _, key := range keys {
+		name, desc := strings.CutPrefix(strings.TrimSpace(key), "-")
+		i, err := t.column(name)
+		if err != nil {
+			return err
+		}
+		parsed = append(parsed, sortKey{column: i, desc: desc})
+	}
+
+	slices.SortStableFunc(rows, func(a, b []any) int {
+		for _, key := range parsed {
+			c := compare(value(a, key.column), value(b, key.column))
+			if key.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+	return nil
+}
+
+func value(row []any, i int) any {
+	if i < len(row) {
+		return row[i]
+	}
+	return nil
+}
+
+func rightAligned(kind Kind) bool {
+	return kind == Number || kind == Money
+}
+
+func format(kind Kind, v any, opts Options) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		if opts.FormatDate != nil {
+			return opts.FormatDate(v)
+		}
+		return v.Format("2006-01-02")
+	case float64:
+		if kind == Money && opts.FormatMoney != nil {
+			return opts.FormatMoney(v)
+		}
+		if kind == Money {
+			return fmt.Sprintf("%.2f", v+0) // +0 normalizes negative zero
+		}
+		if opts.FormatNumber != nil {
+			return opts.FormatNumber(v)
+		}
+		return fmt.Sprintf("%g", v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// compare orders values of the same dynamic type, sorting nil first
+func compare(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	switch a := a.(type) {
+	case float64:
+		if b, ok := b.(float64); ok {
+			return cmp.Compare(a, b)
+		}
+	case int:
+		if b, ok := b.(int); ok {
+			return cmp.Compare(a, b)
+		}
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			return a.Compare(b)
+		}
+	}
+	return cmp.Compare(strings.ToLower(fmt.Sprint(a)), strings.ToLower(fmt.Sprint(b)))
+}
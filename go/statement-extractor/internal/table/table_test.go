@@ -0,0 +1,89 @@
+package table
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sample() *Table {
+	tbl := New(
+		Column{Name: "date", Kind: Date},
+		Column{Name: "description", Kind: Text},
+		Column{Name: "amount", Kind: Money},
+	)
+	tbl.Append(time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), "woolworths", -123.4)
+	tbl.Append(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), "Cafe", -4.5)
+	tbl.Append(time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC), "SALARY", 5000.0)
+	return tbl
+}
+
+func render(t *testing.T, tbl *Table, opts Options) string {
+	t.Helper()
+	var b strings.Builder
+	require.NoError(t, tbl.Render(&b, opts))
+	return b.String()
+}
+
+func TestRender_AlignsMoney(t *testing.T) {
+	want := "" +
+		"DATE        DESCRIPTION   AMOUNT\n" +
+		"2024-03-02  woolworths   -123.40\n" +
+		"2024-03-01  Cafe           -4.50\n" +
+		"2024-03-03  SALARY       5000.00\n"
+	assert.Equal(t, want, render(t, sample(), Options{}))
+}
+
+func TestRender_ColumnsSortLimit(t *testing.T) {
+	want := "" +
+		" AMOUNT  DESCRIPTION\n" +
+		"5000.00  SALARY\n" +
+		"  -4.50  Cafe\n"
+	got := render(t, sample(), Options{
+		Columns: []string{"amount", "Description"},
+		Sort:    []string{"-amount"},
+		Limit:   2,
+	})
+	assert.Equal(t, want, got)
+
+	got = render(t, sample(), Options{Columns: []string{"description"}, Sort: []string{"description"}})
+	assert.Equal(t, "DESCRIPTION\nCafe\nSALARY\nwoolworths\n", got)
+}
+
+func TestRender_UnknownColumn(t *testing.T) {
+	err := sample().Render(&strings.Builder{}, Options{Columns: []string{"payee"}})
+	assert.EqualError(t, err, `unknown column "payee" (available: date, description, amount)`)
+
+	err = sample().Render(&strings.Builder{}, Options{Sort: []string{"-payee"}})
+	assert.Error(t, err)
+}
+
+func TestRender_MissingAndNegativeZero(t *testing.T) {
+	tbl := New(Column{Name: "name"}, Column{Name: "total", Header: "Total", Kind: Money})
+	tbl.Append("a", -0.0)
+	tbl.Append("b")
+
+	assert.Equal(t, "NAME  Total\na      0.00\nb\n", render(t, tbl, Options{}))
+}
+
+func TestRender_FormatDate(t *testing.T) {
+	tbl := New(Column{Name: "date", Kind: Date}, Column{Name: "rate", Kind: Number})
+	tbl.Append(time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), 1.5)
+	got := render(t, tbl, Options{
+		FormatDate:   func(t time.Time) string { return t.Format("02.01.2006") },
+		FormatNumber: func(v float64) string { return strings.Replace(fmt.Sprint(v), ".", ",", 1) },
+	})
+	assert.Equal(t, "DATE        RATE\n02.03.2024   1,5\n", got)
+}
+
+func TestRender_FormatMoney(t *testing.T) {
+	got := render(t, sample(), Options{
+		Columns:     []string{"amount"},
+		FormatMoney: func(v float64) string { return fmt.Sprintf("$%.1f", v) },
+	})
+	assert.Equal(t, " AMOUNT\n$-123.4\n  $-4.5\n$5000.0\n", got)
+}
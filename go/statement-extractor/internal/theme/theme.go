@@ -0,0 +1,149 @@
+// Package theme colors terminal output: negative amounts, per-category
+// colors, headers and warnings. Color is disabled for non-terminals, when
+// NO_COLOR is set, or on request.
+package theme
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/table"
+)
+
+const reset = "\x1b[0m"
+
+// codes maps style names to SGR parameters
+var codes = map[string]string{
+	"bold":      "1",
+	"dim":       "2",
+	"italic":    "3",
+	"underline": "4",
+	"black":     "30",
+	"red":       "31",
+	"green":     "32",
+	"yellow":    "33",
+	"blue":      "34",
+	"magenta":   "35",
+	"cyan":      "36",
+	"white":     "37",
+	"gray":      "90",
+	"grey":      "90",
+}
+
+// Theme applies configured styles to output text
+type Theme struct {
+	enabled    bool
+	header     string
+	negative   string
+	positive   string
+	warning    string
+	categories map[string]string // lower-cased category -> escape sequence
+}
+
+// New builds a theme from config. When enabled is false every method
+// returns text unchanged.
+func New(cfg config.ThemeConfig, enabled bool) (*Theme, error) {
+	t := &Theme{enabled: enabled, categories: make(map[string]string)}
+
+	var err error
+	if t.header, err = sequence(cfg.Header); err != nil {
+		return nil, fmt.Errorf("theme header: %w", err)
+	}
+	if t.negative, err = sequence(cfg.Negative); err != nil {
+		return nil, fmt.Errorf("theme negative: %w", err)
+	}
+	if t.positive, err = sequence(cfg.Positive); err != nil {
+		return nil, fmt.Errorf("theme positive: %w", err)
+	}
+	if t.warning, err = sequence(cfg.Warning); err != nil {
+		return nil, fmt.Errorf("theme warning: %w", err)
+	}
+	for category, style := range cfg.Categories {
+		seq, err := sequence(style)
+		if err != nil {
+			return nil, fmt.Errorf("theme category %q: %w", category, err)
+		}
+		t.categories[strings.ToLower(category)] = seq
+	}
+	return t, nil
+}
+
+// Plain returns a theme that never colors output
+func Plain() *Theme {
+	return &Theme{}
+}
+
+// Enabled reports whether output should be colored given the --no-color
+// flag, the NO_COLOR convention and whether f is a terminal
+func Enabled(noColor bool, f *os.File) bool {
+	if noColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Header styles a table header cell
+func (t *Theme) Header(text string) string {
+	return t.paint(t.header, text)
+}
+
+// Warning highlights text needing attention, such as failures or alerts
+func (t *Theme) Warning(text string) string {
+	return t.paint(t.warning, text)
+}
+
+// Amount styles formatted text according to the sign of amount
+func (t *Theme) Amount(amount float64, text string) string {
+	switch {
+	case amount < 0:
+		return t.paint(t.negative, text)
+	case amount > 0:
+		return t.paint(t.positive, text)
+	}
+	return text
+}
+
+// Category styles a category name with its configured color
+func (t *Theme) Category(category, text string) string {
+	return t.paint(t.categories[strings.ToLower(category)], text)
+}
+
+// Cell implements table.Styler: money is colored by sign and columns named
+// "category" use the category colors
+func (t *Theme) Cell(column table.Column, value any, text string) string {
+	if amount, ok := value.(float64); ok && column.Kind == table.Money {
+		return t.Amount(amount, text)
+	}
+	if category, ok := value.(string); ok && strings.EqualFold(column.Name, "category") {
+		return t.Category(category, text)
+	}
+	return text
+}
+
+func (t *Theme) paint(seq, text string) string {
+	if t == nil || !t.enabled || seq == "" || text == "" {
+		return text
+	}
+	return seq + text + reset
+}
+
+// sequence converts a style such as "bold red" into an escape sequence
+func sequence(style string) (string, error) {
+	fields := strings.Fields(strings.ToLower(style))
+	if len(fields) == 0 {
+		return "", nil
+	}
+
+	params := make([]string, 0, len(fields))
+	for _, name := range fields {
+		code, ok := codes[name]
+		if !ok {
+			return "", fmt.Errorf("unknown style %q", name)
+		}
+		params = append(params, code)
+	}
+	return "\x1b[" + strings.Join(params, ";") + "m", nil
+}
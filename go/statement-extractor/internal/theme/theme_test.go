@@ -0,0 +1,53 @@
+package theme
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/config"
+	"github.com/example/statement-extractor/internal/table"
+)
+
+func TestTheme_Enabled(t *testing.T) {
+	th, err := New(config.ThemeConfig{
+		Header:     "bold",
+		Negative:   "red",
+		Categories: map[string]string{"food & dining": "Bold Yellow"},
+	}, true)
+	require.NoError(t, err)
+
+	assert.Equal(t, "\x1b[1mDATE\x1b[0m", th.Header("DATE"))
+	assert.Equal(t, "\x1b[31m-4.50\x1b[0m", th.Amount(-4.5, "-4.50"))
+	assert.Equal(t, "4.50", th.Amount(4.5, "4.50"), "positive has no style configured")
+	assert.Equal(t, "\x1b[1;33mFood & dining\x1b[0m", th.Category("Food & Dining", "Food & dining"))
+
+	money := table.Column{Name: "amount", Kind: table.Money}
+	assert.Equal(t, "\x1b[31m-1.00\x1b[0m", th.Cell(money, -1.0, "-1.00"))
+	category := table.Column{Name: "category"}
+	assert.Equal(t, "\x1b[1;33mFood & dining\x1b[0m", th.Cell(category, "Food & dining", "Food & dining"))
+	assert.Equal(t, "Other", th.Cell(category, "Other", "Other"))
+}
+
+func TestTheme_Disabled(t *testing.T) {
+	th, err := New(config.ThemeConfig{Negative: "red", Warning: "yellow"}, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, "-4.50", th.Amount(-4.5, "-4.50"))
+	assert.Equal(t, "failed", th.Warning("failed"))
+	assert.Equal(t, "x", Plain().Header("x"))
+}
+
+func TestTheme_UnknownStyle(t *testing.T) {
+	_, err := New(config.ThemeConfig{Negative: "crimson"}, true)
+	assert.EqualError(t, err, `theme negative: unknown style "crimson"`)
+}
+
+func TestEnabled_NoColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	assert.False(t, Enabled(false, nil))
+
+	t.Setenv("NO_COLOR", "")
+	assert.False(t, Enabled(true, nil))
+}
@@ -0,0 +1,109 @@
+package training
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// genericWords appear in the descriptions of many merchants, so they are
+// never the basis of a suggested rule
+var genericWords = map[string]bool{
+	"purchase": true, "payment": true, "pos": true, "eftpos": true, "visa": true,
+	"debit": true, "credit": true, "card": true, "direct": true, "transfer": true,
+	"to": true, "from": true, "the": true, "and": true, "pty": true, "ltd": true,
+	"au": true, "aus": true, "australia": true, "value": true, "date": true,
+	"transaction": true, "ref": true, "reference": true, "online": true,
+	"mobile": true, "bpay": true, "osko": true, "inc": true, "co": true,
+	"com": true, "www": true, "sq": true, "paypal": true,
+}
+
+// Suggestion is a candidate category rule generalized from corrections
+type Suggestion struct {
+	Pattern     string // case-insensitive regular expression on the description
+	Category    string
+	Corrections int // corrected transactions the pattern generalizes
+	Matches     int // transactions in the history the pattern matches
+	Agrees      int // of those, the ones already in Category
+}
+
+// Suggest proposes a rule for each merchant that corrections moved to the
+// same category in at least minCount transactions. Corrections are grouped
+// by category and the first word of their normalized description that is
+// not a generic banking word; a group's pattern is the words its
+// descriptions share from there. Each pattern is then run over history to
+// show how many transactions it would have matched. Suggestions are sorted
+// by corrections, then matches, most first.
+func Suggest(corrections, history []transaction.Transaction, minCount int) []Suggestion {
+	type key struct{ category, word string }
+	groups := make(map[key][][]string)
+	var order []key
+	for _, t := range corrections {
+		words := distinctive(Normalize(t.Description))
+		if t.Category == "" || len(words) == 0 {
+			continue
+		}
+		k := key{t.Category, words[0]}
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], words)
+	}
+
+	var suggestions []Suggestion
+	for _, k := range order {
+		group := groups[k]
+		if len(group) < max(minCount, 1) {
+			continue
+		}
+		words := group[0]
+		for _, other := range group[1:] {
+			words = commonPrefix(words, other)
+		}
+		for genericWords[words[len(words)-1]] {
+			words = words[:len(words)-1]
+		}
+		quoted := make([]string, len(words))
+		for i, word := range words {
+			quoted[i] = regexp.QuoteMeta(strings.ToUpper(word))
+		}
+		s := Suggestion{Pattern: strings.Join(quoted, ".*"), Category: k.category, Corrections: len(group)}
+		pattern := regexp.MustCompile("(?i)" + s.Pattern)
+		for _, t := range history {
+			if pattern.MatchString(t.Description) {
+				s.Matches++
+				if strings.EqualFold(t.Category, s.Category) {
+					s.Agrees++
+				}
+			}
+		}
+		suggestions = append(suggestions, s)
+	}
+	slices.SortStableFunc(suggestions, func(a, b Suggestion) int {
+		return cmp.Or(cmp.Compare(b.Corrections, a.Corrections), cmp.Compare(b.Matches, a.Matches))
+	})
+	return suggestions
+}
+
+// distinctive returns the words of a normalized description from the first
+// one that could identify a merchant
+func distinctive(normalized string) []string {
+	words := strings.Fields(normalized)
+	for i, word := range words {
+		if len(word) >= 3 && !genericWords[word] {
+			return words[i:]
+		}
+	}
+	return nil
+}
+
+func commonPrefix(a, b []string) []string {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return a[:n]
+}
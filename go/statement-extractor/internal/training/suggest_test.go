@@ -0,0 +1,33 @@
+package training
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestSuggest(t *testing.T) {
+	corrections := []transaction.Transaction{
+		{Description: "EFTPOS NETFLIX COM 1234 MELBOURNE", Category: "Subscriptions"},
+		{Description: "EFTPOS NETFLIX COM 5678 SYDNEY", Category: "Subscriptions"},
+		{Description: "VISA PURCHASE UBER *TRIP 0412", Category: "Transport"},
+		{Description: "UBER TRIP HELP.UBER.COM", Category: "Transport"},
+		{Description: "BUNNINGS 332", Category: "Household"},
+		{Description: "POS 99", Category: "Household"},
+	}
+	history := append([]transaction.Transaction{
+		{Description: "NETFLIX.COM MELBOURNE", Category: "Entertainment"},
+		{Description: "UBER EATS", Category: "Dining"},
+	}, corrections...)
+
+	assert.Equal(t, []Suggestion{
+		{Pattern: "NETFLIX", Category: "Subscriptions", Corrections: 2, Matches: 3, Agrees: 2},
+		{Pattern: "UBER.*TRIP", Category: "Transport", Corrections: 2, Matches: 2, Agrees: 2},
+	}, Suggest(corrections, history, 2))
+
+	suggestions := Suggest(corrections, history, 1)
+	assert.Len(t, suggestions, 3, "a single correction is enough with a minimum of 1")
+	assert.Equal(t, "BUNNINGS", suggestions[2].Pattern)
+}
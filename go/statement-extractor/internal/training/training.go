@@ -0,0 +1,124 @@
+// Package training turns categorized transactions into (description,
+// category) examples for experimenting with classifiers outside the tool.
+package training
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// Example is one labeled description
+type Example struct {
+	Description string `json:"description"`
+	Category    string `json:"category"`
+	Count       int    `json:"count"` // transactions sharing this pair
+}
+
+// Normalize reduces a bank description to the words that identify the
+// merchant: lower-cased, punctuation removed, and tokens containing digits
+// (card numbers, dates, references, store numbers) dropped.
+// "WOOLWORTHS 1234 SYDNEY 12/03" becomes "woolworths sydney".
+func Normalize(description string) string {
+	fields := strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '&' && r != '\''
+	})
+
+	words := fields[:0]
+	for _, field := range fields {
+		if strings.IndexFunc(field, unicode.IsDigit) >= 0 {
+			continue
+		}
+		if field = strings.Trim(field, "'"); field != "" {
+			words = append(words, field)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// Collect builds deduplicated examples from txns, skipping transactions
+// whose category is empty or listed in skip (case-insensitive). Examples are
+// sorted by description then category.
+func Collect(txns []transaction.Transaction, skip ...string) []Example {
+	type key struct{ description, category string }
+	counts := make(map[key]int)
+	for _, t := range txns {
+		if t.Category == "" || slices.ContainsFunc(skip, func(s string) bool { return strings.EqualFold(s, t.Category) }) {
+			continue
+		}
+		description := Normalize(t.Description)
+		if description == "" {
+			continue
+		}
+		counts[key{description, t.Category}]++
+	}
+
+	examples := make([]Example, 0, len(counts))
+	for k, n := range counts {
+		examples = append(examples, Example{Description: k.description, Category: k.category, Count: n})
+	}
+	slices.SortFunc(examples, func(a, b Example) int {
+		return cmp.Or(cmp.Compare(a.Description, b.Description), cmp.Compare(a.Category, b.Category))
+	})
+	return examples
+}
+
+// WriteCSV writes examples with a description,category,count header
+func WriteCSV(w io.Writer, examples []Example) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"description", "category", "count"})
+	for _, e := range examples {
+		_ = cw.Write([]string{e.Description, e.Category, strconv.Itoa(e.Count)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadCSV reads examples written by WriteCSV, or any CSV whose first two
+// columns are a description and a category. A header row naming the
+// description column is skipped, and a missing count is 1.
+func ReadCSV(r io.Reader) ([]Example, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > 0 && len(records[0]) > 0 && strings.EqualFold(strings.TrimSpace(records[0][0]), "description") {
+		records = records[1:]
+	}
+
+	examples := make([]Example, 0, len(records))
+	for i, record := range records {
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: expected a description and a category", i+1)
+		}
+		e := Example{Description: record[0], Category: strings.TrimSpace(record[1]), Count: 1}
+		if len(record) > 2 && strings.TrimSpace(record[2]) != "" {
+			if e.Count, err = strconv.Atoi(strings.TrimSpace(record[2])); err != nil || e.Count < 1 {
+				return nil, fmt.Errorf("line %d: invalid count %q", i+1, record[2])
+			}
+		}
+		examples = append(examples, e)
+	}
+	return examples, nil
+}
+
+// WriteJSONL writes one JSON object per example
+func WriteJSONL(w io.Writer, examples []Example) error {
+	enc := json.NewEncoder(w)
+	for _, e := range examples {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package training
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"WOOLWORTHS 1234 SYDNEY 12/03":  "woolworths sydney",
+		"  Netflix.com   Melbourne AU ": "netflix com melbourne au",
+		"McDonald's #0452":              "mcdonald's",
+		"EFTPOS M&S Ref:ABC123":         "eftpos m&s ref",
+		"Transfer to 062-000 12345678":  "transfer to",
+		"123456":                        "",
+	}
+	for input, want := range cases {
+		assert.Equal(t, want, Normalize(input), input)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	txns := []transaction.Transaction{
+		{Description: "WOOLWORTHS 1234 SYDNEY", Category: "Groceries"},
+		{Description: "WOOLWORTHS 5678 SYDNEY", Category: "Groceries"},
+		{Description: "WOOLWORTHS 5678 SYDNEY", Category: "Household"},
+		{Description: "ACME PTY LTD", Category: "Uncategorized"},
+		{Description: "UNKNOWN", Category: ""},
+		{Description: "9999", Category: "Groceries"},
+	}
+
+	examples := Collect(txns, "uncategorized")
+	assert.Equal(t, []Example{
+		{Description: "woolworths sydney", Category: "Groceries", Count: 2},
+		{Description: "woolworths sydney", Category: "Household", Count: 1},
+	}, examples)
+}
+
+func TestWrite(t *testing.T) {
+	examples := []Example{{Description: "coles, north", Category: "Groceries", Count: 3}}
+
+	var csvOut bytes.Buffer
+	require.NoError(t, WriteCSV(&csvOut, examples))
+	assert.Equal(t, "description,category,count\n\"coles, north\",Groceries,3\n", csvOut.String())
+
+	read, err := ReadCSV(&csvOut)
+	require.NoError(t, err)
+	assert.Equal(t, examples, read)
+
+	read, err = ReadCSV(bytes.NewBufferString("UBER *TRIP,Transport\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []Example{{Description: "UBER *TRIP", Category: "Transport", Count: 1}}, read)
+
+	_, err = ReadCSV(bytes.NewBufferString("UBER,Transport,lots\n"))
+	assert.Error(t, err)
+
+	var jsonOut bytes.Buffer
+	require.NoError(t, WriteJSONL(&jsonOut, examples))
+	assert.Equal(t, `{"description":"coles, north","category":"Groceries","count":3}`+"\n", jsonOut.String())
+}
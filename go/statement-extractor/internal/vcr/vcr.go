@@ -0,0 +1,281 @@
+// Package vcr records provider HTTP interactions to cassette files and
+// replays them, so provider integrations can be regression tested offline
+// against real responses. Credentials are removed before anything is
+// written to disk.
+package vcr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Mode selects whether a Recorder talks to the network
+type Mode int
+
+const (
+	Replay Mode = iota // serve from the cassette; unknown requests fail
+	Record             // forward to the network and save every interaction
+)
+
+// ErrNoInteraction is returned in Replay mode for requests the cassette
+// does not contain
+var ErrNoInteraction = errors.New("no recorded interaction")
+
+// Redacted replaces sensitive header and query values in cassettes
+const Redacted = "REDACTED"
+
+// sensitiveHeaders are always redacted, matched case-insensitively
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "Api-Key", "X-Goog-Api-Key"}
+
+// sensitiveParams are query parameters always redacted
+var sensitiveParams = []string{"key", "api_key", "apikey", "token", "access_token"}
+
+// Interaction is one recorded request and its response
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is the recorded part of an HTTP request
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response is the recorded part of an HTTP response
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Cassette is the on-disk form of a recording
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Options configures a Recorder
+type Options struct {
+	Mode      Mode
+	Transport http.RoundTripper // used in Record mode; defaults to http.DefaultTransport
+
+	// Redact lists extra secret strings, such as API keys read from the
+	// environment, to replace wherever they appear in a recording
+	Redact []string
+}
+
+// Recorder is an http.RoundTripper that records to or replays from a
+// cassette file. Replayed requests are matched on method, URL and body;
+// identical requests are served in recorded order.
+type Recorder struct {
+	path string
+	opts Options
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// New opens the cassette at path. In Replay mode the file must exist; in
+// Record mode any existing recording is replaced when Save is called.
+func New(path string, opts Options) (*Recorder, error) {
+	r := &Recorder{path: path, opts: opts}
+	if r.opts.Transport == nil {
+		r.opts.Transport = http.DefaultTransport
+	}
+	if opts.Mode == Record {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	if err := json.Unmarshal(data, &r.cassette); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// Client returns an HTTP client using the recorder as its transport
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded := r.sanitizeRequest(req, body)
+
+	if r.opts.Mode == Replay {
+		return r.replay(req, recorded)
+	}
+
+	resp, err := r.opts.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: recorded,
+		Response: Response{
+			Status: resp.StatusCode,
+			Header: r.sanitizeHeader(resp.Header),
+			Body:   r.redact(string(respBody)),
+		},
+	})
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// Save writes the recorded interactions to the cassette file. It is a no-op
+// in Replay mode.
+func (r *Recorder) Save() error {
+	if r.opts.Mode != Record {
+		return nil
+	}
+
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	if err := os.WriteFile(r.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+func (r *Recorder) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, in := range r.cassette.Interactions {
+		if r.used[i] || !matches(in.Request, recorded) {
+			continue
+		}
+		r.used[i] = true
+		header := in.Response.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.Status, http.StatusText(in.Response.Status)),
+			StatusCode:    in.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w for %s %s in %s", ErrNoInteraction, recorded.Method, recorded.URL, r.path)
+}
+
+func matches(a, b Request) bool {
+	return a.Method == b.Method && a.URL == b.URL && a.Body == b.Body
+}
+
+// readBody reads and restores the request body
+func readBody(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return "", nil
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return "", fmt.Errorf("reading request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return string(data), nil
+}
+
+func (r *Recorder) sanitizeRequest(req *http.Request, body string) Request {
+	u := *req.URL
+	query := u.Query()
+	for name := range query {
+		for _, sensitive := range sensitiveParams {
+			if strings.EqualFold(name, sensitive) {
+				query.Set(name, Redacted)
+			}
+		}
+	}
+	u.RawQuery = query.Encode()
+	u.User = nil
+
+	return Request{
+		Method: req.Method,
+		URL:    r.redact(unescape(u.String())),
+		Header: r.sanitizeHeader(req.Header),
+		Body:   r.redact(body),
+	}
+}
+
+func (r *Recorder) sanitizeHeader(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	clean := make(http.Header, len(h))
+	for name, values := range h {
+		redacted := false
+		for _, sensitive := range sensitiveHeaders {
+			if strings.EqualFold(name, sensitive) {
+				redacted = true
+			}
+		}
+		for _, value := range values {
+			if redacted {
+				value = Redacted
+			}
+			clean.Add(name, r.redact(value))
+		}
+	}
+	return clean
+}
+
+// redact replaces the configured secrets in s
+func (r *Recorder) redact(s string) string {
+	for _, secret := range r.opts.Redact {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, Redacted)
+		}
+	}
+	return s
+}
+
+// unescape keeps URLs readable in cassettes; the URL is only compared, never
+// requested, so an unescaped form is fine
+func unescape(s string) string {
+	if u, err := url.QueryUnescape(s); err == nil {
+		return u
+	}
+	return s
+}
@@ -0,0 +1,77 @@
+package vcr
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func post(t *testing.T, client *http.Client, url, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer sk-secret")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(data)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Set-Cookie", "session=abc")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"call":%d,"echo":%q}`, calls, r.URL.Query().Get("key"))
+	}))
+	path := filepath.Join(t.TempDir(), "cassettes", "provider.json")
+	url := server.URL + "/v1/extract?key=sk-secret&model=m1"
+
+	recorder, err := New(path, Options{Mode: Record, Redact: []string{"sk-secret"}})
+	require.NoError(t, err)
+	status, body := post(t, recorder.Client(), url, `{"prompt":"x"}`)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, `{"call":1,"echo":"sk-secret"}`, body, "the caller sees the real response")
+	_, body = post(t, recorder.Client(), url, `{"prompt":"x"}`)
+	assert.Equal(t, `{"call":2,"echo":"sk-secret"}`, body)
+	require.NoError(t, recorder.Save())
+	server.Close()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "sk-secret")
+	assert.NotContains(t, string(data), "session=abc")
+
+	replayer, err := New(path, Options{})
+	require.NoError(t, err)
+	status, body = post(t, replayer.Client(), url, `{"prompt":"x"}`)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, `{"call":1,"echo":"REDACTED"}`, body)
+	_, body = post(t, replayer.Client(), url, `{"prompt":"x"}`)
+	assert.Equal(t, `{"call":2,"echo":"REDACTED"}`, body, "identical requests replay in order")
+
+	_, err = replayer.Client().Post(url, "application/json", strings.NewReader(`{"prompt":"x"}`))
+	assert.True(t, errors.Is(err, ErrNoInteraction), "cassette is exhausted")
+
+	_, err = replayer.Client().Post(url, "application/json", strings.NewReader(`{"prompt":"y"}`))
+	assert.ErrorContains(t, err, "no recorded interaction for POST")
+}
+
+func TestReplay_MissingCassette(t *testing.T) {
+	_, err := New(filepath.Join(t.TempDir(), "missing.json"), Options{})
+	assert.ErrorContains(t, err, "failed to read cassette")
+}
@@ -0,0 +1,147 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrive(t *testing.T) {
+	parents := map[string]string{"f1": "inbox", "doc": "inbox"}
+	refreshed := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "refresh", r.PostForm.Get("refresh_token"))
+		assert.Equal(t, "client", r.PostForm.Get("client_id"))
+		refreshed++
+		fmt.Fprint(w, `{"access_token": "access", "expires_in": 3600}`)
+	})
+	authorized := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer access" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			h(w, r)
+		}
+	}
+	mux.HandleFunc("GET /drive/v3/files", authorized(func(w http.ResponseWriter, r *http.Request) {
+		switch q := r.URL.Query().Get("q"); q {
+		case "'inbox' in parents and trashed = false":
+			var files []map[string]any
+			for id, parent := range parents {
+				if parent != "inbox" {
+					continue
+				}
+				f := map[string]any{"id": id, "name": id + ".pdf", "mimeType": "application/pdf", "size": "8", "modifiedTime": "2024-06-01T08:00:00Z"}
+				if id == "doc" {
+					f = map[string]any{"id": id, "name": "Notes", "mimeType": "application/vnd.google-apps.document", "modifiedTime": "2024-06-01T08:00:00Z"}
+				}
+				files = append(files, f)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"files": files})
+		case "'inbox' in parents and name = 'processed' and mimeType = 'application/vnd.google-apps.folder' and trashed = false":
+			fmt.Fprint(w, `{"files": []}`)
+		default:
+			t.Errorf("unexpected query %q", q)
+		}
+	}))
+	mux.HandleFunc("POST /drive/v3/files", authorized(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": "done"}`)
+	}))
+	mux.HandleFunc("GET /drive/v3/files/{id}", authorized(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "media", r.URL.Query().Get("alt"))
+		fmt.Fprint(w, "%PDF-"+r.PathValue("id"))
+	}))
+	mux.HandleFunc("PATCH /drive/v3/files/{id}", authorized(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "inbox", r.URL.Query().Get("removeParents"))
+		parents[r.PathValue("id")] = r.URL.Query().Get("addParents")
+		fmt.Fprint(w, `{"id": "f1"}`)
+	}))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	source, err := Open("gdrive://inbox", Options{OAuth: &OAuth{ClientID: "client", RefreshToken: "refresh"}})
+	require.NoError(t, err)
+	drive := source.(*Drive)
+	drive.API, drive.auth.tokenURL = server.URL, server.URL+"/token"
+
+	dir := t.TempDir()
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	remote := New(drive, time.Hour)
+	_, err = Pull(ctx, remote, dir, now)
+	require.NoError(t, err)
+	pulled, err := Pull(ctx, remote, dir, now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "f1.pdf")}, pulled, "Google Docs are passed over")
+	content, err := os.ReadFile(pulled[0])
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-f1", string(content))
+	assert.Equal(t, "done", parents["f1"], "the file is moved into the processed folder")
+	assert.Equal(t, 1, refreshed, "the access token is reused until it expires")
+
+	_, err = Open("gdrive://inbox", Options{})
+	assert.ErrorContains(t, err, "no access token")
+}
+
+func TestDropbox(t *testing.T) {
+	files := map[string]string{"id:a": "/Inbox/jan.pdf"}
+	var moved map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /2/files/list_folder", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "/Inbox", body["path"])
+		fmt.Fprint(w, `{"entries": [{".tag": "folder", "id": "id:p", "name": "processed"}], "cursor": "c1", "has_more": true}`)
+	})
+	mux.HandleFunc("POST /2/files/list_folder/continue", func(w http.ResponseWriter, r *http.Request) {
+		var entries []map[string]any
+		for id, p := range files {
+			if path.Dir(p) == "/Inbox" {
+				entries = append(entries, map[string]any{".tag": "file", "id": id, "name": path.Base(p), "size": 8, "server_modified": "2024-06-01T08:00:00Z"})
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"entries": entries, "cursor": "c2", "has_more": false})
+	})
+	mux.HandleFunc("POST /2/files/download", func(w http.ResponseWriter, r *http.Request) {
+		assert.JSONEq(t, `{"path": "id:a"}`, r.Header.Get("Dropbox-API-Arg"))
+		fmt.Fprint(w, "%PDF-jan")
+	})
+	mux.HandleFunc("POST /2/files/move_v2", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&moved))
+		files["id:a"] = moved["to_path"].(string)
+		fmt.Fprint(w, `{"metadata": {}}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	source, err := Open("dropbox:///Inbox/", Options{Token: "token"})
+	require.NoError(t, err)
+	dropbox := source.(*Dropbox)
+	dropbox.API, dropbox.Content = server.URL, server.URL
+	assert.Equal(t, "dropbox:///Inbox", dropbox.String())
+
+	dir := t.TempDir()
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	remote := New(dropbox, time.Hour)
+	_, err = Pull(ctx, remote, dir, now)
+	require.NoError(t, err)
+	pulled, err := Pull(ctx, remote, dir, now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "jan.pdf")}, pulled)
+	assert.Equal(t, map[string]any{"from_path": "id:a", "to_path": "/Inbox/processed/jan.pdf", "autorename": true}, moved)
+}
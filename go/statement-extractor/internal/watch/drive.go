@@ -0,0 +1,183 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Google's API and token endpoints
+const (
+	driveAPI   = "https://www.googleapis.com"
+	driveToken = "https://oauth2.googleapis.com/token"
+)
+
+// driveFolder is the MIME type of Drive folders
+const driveFolder = "application/vnd.google-apps.folder"
+
+// Drive is a Google Drive folder, read with the Drive API v3. Google Docs
+// and other files without content of their own are passed over.
+type Drive struct {
+	Folder string // folder ID, or "root" for My Drive
+	API    string // default https://www.googleapis.com
+	Client *http.Client
+
+	auth      *bearer
+	processed string // ID of the processed subfolder, once found
+}
+
+// NewDrive returns the Drive folder with the ID folder
+func NewDrive(folder string, opts Options) (*Drive, error) {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	auth, err := newBearer(opts, driveToken)
+	if err != nil {
+		return nil, fmt.Errorf("gdrive://%s: %w", folder, err)
+	}
+	return &Drive{Folder: folder, API: driveAPI, Client: opts.Client, auth: auth}, nil
+}
+
+type driveFile struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	MimeType     string    `json:"mimeType"`
+	Size         string    `json:"size"` // int64 as a string; absent for Google Docs
+	ModifiedTime time.Time `json:"modifiedTime"`
+}
+
+// List returns the files in the folder
+func (d *Drive) List(ctx context.Context) ([]File, error) {
+	found, err := d.search(ctx, fmt.Sprintf("'%s' in parents and trashed = false", escapeQuery(d.Folder)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", d, err)
+	}
+	var files []File
+	for _, f := range found {
+		if f.Size == "" || strings.HasPrefix(f.MimeType, "application/vnd.google-apps.") {
+			continue
+		}
+		size, _ := strconv.ParseInt(f.Size, 10, 64)
+		files = append(files, File{Path: f.ID, Name: f.Name, Size: size, ModTime: f.ModifiedTime})
+	}
+	return files, nil
+}
+
+// search returns the files matching the Drive query q, from every page
+func (d *Drive) search(ctx context.Context, q string) ([]driveFile, error) {
+	var files []driveFile
+	page := ""
+	for {
+		query := url.Values{
+			"q":        {q},
+			"fields":   {"nextPageToken,files(id,name,mimeType,size,modifiedTime)"},
+			"pageSize": {"1000"},
+		}
+		if page != "" {
+			query.Set("pageToken", page)
+		}
+		var list struct {
+			NextPageToken string      `json:"nextPageToken"`
+			Files         []driveFile `json:"files"`
+		}
+		if err := d.call(ctx, http.MethodGet, "/drive/v3/files?"+query.Encode(), nil, &list); err != nil {
+			return nil, err
+		}
+		files = append(files, list.Files...)
+		if page = list.NextPageToken; page == "" {
+			return files, nil
+		}
+	}
+}
+
+// Fetch downloads the file's content to path
+func (d *Drive) Fetch(ctx context.Context, f File, path string) error {
+	resp, err := d.request(ctx, http.MethodGet, "/drive/v3/files/"+url.PathEscape(f.Path)+"?alt=media", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s", status(resp))
+	}
+	return writeFile(path, resp.Body)
+}
+
+// Archive moves the file into the processed subfolder, creating it the
+// first time. Drive allows names to repeat, so the name is kept.
+func (d *Drive) Archive(ctx context.Context, f File) error {
+	if d.processed == "" {
+		found, err := d.search(ctx, fmt.Sprintf("'%s' in parents and name = '%s' and mimeType = '%s' and trashed = false",
+			escapeQuery(d.Folder), Processed, driveFolder))
+		if err != nil {
+			return fmt.Errorf("finding %s: %w", Processed, err)
+		}
+		if len(found) > 0 {
+			d.processed = found[0].ID
+		} else {
+			var created driveFile
+			body := map[string]any{"name": Processed, "mimeType": driveFolder, "parents": []string{d.Folder}}
+			if err := d.call(ctx, http.MethodPost, "/drive/v3/files?fields=id", body, &created); err != nil {
+				return fmt.Errorf("creating %s: %w", Processed, err)
+			}
+			d.processed = created.ID
+		}
+	}
+	query := url.Values{"addParents": {d.processed}, "removeParents": {d.Folder}, "fields": {"id"}}
+	return d.call(ctx, http.MethodPatch, "/drive/v3/files/"+url.PathEscape(f.Path)+"?"+query.Encode(), map[string]any{}, nil)
+}
+
+func (d *Drive) String() string {
+	return "gdrive://" + d.Folder
+}
+
+// call sends a JSON request to the API and decodes the JSON response into
+// out, when not nil
+func (d *Drive) call(ctx context.Context, method, path string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	resp, err := d.request(ctx, method, path, r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s", status(resp))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (d *Drive) request(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, d.API+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if err := d.auth.authorize(req); err != nil {
+		return nil, err
+	}
+	return d.Client.Do(req)
+}
+
+// escapeQuery escapes a value quoted in a Drive query
+func escapeQuery(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
@@ -0,0 +1,145 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"time"
+)
+
+// Dropbox's API, content and token endpoints
+const (
+	dropboxAPI     = "https://api.dropboxapi.com"
+	dropboxContent = "https://content.dropboxapi.com"
+	dropboxToken   = "https://api.dropboxapi.com/oauth2/token"
+)
+
+// Dropbox is a Dropbox folder, read with the Dropbox API v2
+type Dropbox struct {
+	Folder  string // path such as "/Statements/Inbox", or "" for the root
+	API     string // default https://api.dropboxapi.com
+	Content string // default https://content.dropboxapi.com
+	Client  *http.Client
+
+	auth *bearer
+}
+
+// NewDropbox returns the Dropbox folder at path folder
+func NewDropbox(folder string, opts Options) (*Dropbox, error) {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	auth, err := newBearer(opts, dropboxToken)
+	if err != nil {
+		return nil, fmt.Errorf("dropbox://%s: %w", folder, err)
+	}
+	return &Dropbox{Folder: folder, API: dropboxAPI, Content: dropboxContent, Client: opts.Client, auth: auth}, nil
+}
+
+type dropboxEntry struct {
+	Tag            string    `json:".tag"`
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`
+	Size           int64     `json:"size"`
+	ServerModified time.Time `json:"server_modified"`
+}
+
+// List returns the files in the folder
+func (d *Dropbox) List(ctx context.Context) ([]File, error) {
+	var files []File
+	endpoint, body := "/2/files/list_folder", any(map[string]any{"path": d.Folder})
+	for {
+		var list struct {
+			Entries []dropboxEntry `json:"entries"`
+			Cursor  string         `json:"cursor"`
+			HasMore bool           `json:"has_more"`
+		}
+		if err := d.call(ctx, d.API+endpoint, body, &list); err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", d, err)
+		}
+		for _, e := range list.Entries {
+			if e.Tag == "file" {
+				files = append(files, File{Path: e.ID, Name: e.Name, Size: e.Size, ModTime: e.ServerModified})
+			}
+		}
+		if !list.HasMore {
+			return files, nil
+		}
+		endpoint, body = "/2/files/list_folder/continue", map[string]any{"cursor": list.Cursor}
+	}
+}
+
+// Fetch downloads the file's content to path
+func (d *Dropbox) Fetch(ctx context.Context, f File, local string) error {
+	arg, err := json.Marshal(map[string]string{"path": f.Path})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Content+"/2/files/download", nil)
+	if err != nil {
+		return err
+	}
+	// File IDs are ASCII, so the argument needs no escaping for a header
+	req.Header.Set("Dropbox-API-Arg", string(arg))
+	if err := d.auth.authorize(req); err != nil {
+		return err
+	}
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s", status(resp))
+	}
+	return writeFile(local, resp.Body)
+}
+
+// Archive moves the file into the processed subfolder, which Dropbox
+// creates as needed, renaming it when its name is taken there
+func (d *Dropbox) Archive(ctx context.Context, f File) error {
+	body := map[string]any{
+		"from_path":  f.Path,
+		"to_path":    path.Join("/", d.Folder, Processed, f.Name),
+		"autorename": true,
+	}
+	return d.call(ctx, d.API+"/2/files/move_v2", body, nil)
+}
+
+func (d *Dropbox) String() string {
+	return "dropbox://" + d.Folder
+}
+
+// call sends a JSON RPC request and decodes the JSON response into out,
+// when not nil
+func (d *Dropbox) call(ctx context.Context, endpoint string, body, out any) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := d.auth.authorize(req); err != nil {
+		return err
+	}
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s", status(resp))
+	}
+	if out == nil {
+		_, err := io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,98 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OAuth is a client's refresh token, exchanged for access tokens as they
+// expire
+type OAuth struct {
+	ClientID     string
+	ClientSecret string // may be empty for a PKCE client
+	RefreshToken string
+}
+
+// bearer supplies the access token of a cloud source: a fixed one, or one
+// obtained from a refresh token at tokenURL
+type bearer struct {
+	fixed    string
+	oauth    *OAuth
+	tokenURL string
+	client   *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// newBearer returns the tokens of opts for a service whose token endpoint
+// is tokenURL
+func newBearer(opts Options, tokenURL string) (*bearer, error) {
+	if opts.Token == "" && opts.OAuth == nil {
+		return nil, errors.New("no access token or refresh token")
+	}
+	return &bearer{fixed: opts.Token, oauth: opts.OAuth, tokenURL: tokenURL, client: opts.Client}, nil
+}
+
+// get returns a current access token, refreshing it a minute before it
+// expires
+func (b *bearer) get(ctx context.Context) (string, error) {
+	if b.oauth == nil {
+		return b.fixed, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.token != "" && time.Until(b.expires) > time.Minute {
+		return b.token, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {b.oauth.RefreshToken},
+		"client_id":     {b.oauth.ClientID},
+	}
+	if b.oauth.ClientSecret != "" {
+		form.Set("client_secret", b.oauth.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("refreshing the access token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("refreshing the access token: %s", status(resp))
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil || token.AccessToken == "" {
+		return "", errors.New("refreshing the access token: no access_token in the response")
+	}
+	b.token = token.AccessToken
+	b.expires = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return b.token, nil
+}
+
+// authorize sets the Authorization header of req
+func (b *bearer) authorize(req *http.Request) error {
+	token, err := b.get(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
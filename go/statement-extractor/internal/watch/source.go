@@ -0,0 +1,197 @@
+package watch
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/example/statement-extractor/internal/archive"
+)
+
+// Processed is the subfolder of a source that pulled files are moved into
+const Processed = "processed"
+
+// Source is a folder statements are listed in and fetched from
+type Source interface {
+	// List returns the files directly in the folder, without subfolders
+	List(ctx context.Context) ([]File, error)
+	// Fetch writes the content of f to the local file path
+	Fetch(ctx context.Context, f File, path string) error
+	// Archive moves f into the Processed subfolder, under a free name
+	Archive(ctx context.Context, f File) error
+	// String names the folder in messages, without credentials
+	String() string
+}
+
+// File is a file in a source
+type File struct {
+	Path    string // identifies the file in its source: a local path or a URL
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Options configure the sources Open returns
+type Options struct {
+	Client   *http.Client // for remote sources; default http.DefaultClient
+	Password string       // for the user named in a WebDAV URL
+
+	// Google Drive and Dropbox take an access Token, or OAuth to obtain
+	// them with a refresh token
+	Token string
+	OAuth *OAuth
+}
+
+// Open returns the source at location, chosen by its URL scheme: a WebDAV
+// folder for dav:// and http://, or davs:// and https:// over TLS, a Google
+// Drive folder for gdrive://<folder ID>, a Dropbox folder for
+// dropbox:///<path>, and a local directory for a path or file://. SMB shares
+// are not supported; mount the share and give its mount point.
+func Open(location string, opts Options) (Source, error) {
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme == "" || filepath.VolumeName(location) != "" {
+		// A plain path, including a Windows one such as C:\Statements
+		return Local(location), nil
+	}
+	switch u.Scheme {
+	case "file":
+		return Local(filepath.FromSlash(u.Path)), nil
+	case "dav", "http":
+		u.Scheme = "http"
+	case "davs", "https":
+		u.Scheme = "https"
+	case "gdrive":
+		return NewDrive(cmp.Or(u.Host, "root"), opts)
+	case "dropbox":
+		return NewDropbox(strings.TrimSuffix(u.Path, "/"), opts)
+	case "smb", "cifs":
+		return nil, fmt.Errorf("unsupported source %q: SMB shares are not supported; mount the share and watch the mount point", u.Redacted())
+	default:
+		return nil, fmt.Errorf("unsupported source %q: expected a directory, dav://, davs://, http://, https://, gdrive:// or dropbox://", u.Redacted())
+	}
+	return NewWebDAV(u, opts), nil
+}
+
+// Local is a local directory
+type Local string
+
+// List returns the regular files in the directory
+func (d Local) List(ctx context.Context) ([]File, error) {
+	entries, err := os.ReadDir(string(d))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", d, err)
+	}
+	files := make([]File, 0, len(entries))
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			// Removed since the directory was read
+			continue
+		}
+		files = append(files, File{
+			Path: filepath.Join(string(d), e.Name()), Name: e.Name(), Size: info.Size(), ModTime: info.ModTime(),
+		})
+	}
+	return files, nil
+}
+
+// Fetch copies the file to path
+func (d Local) Fetch(ctx context.Context, f File, path string) error {
+	in, err := os.Open(f.Path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeFile(path, in)
+}
+
+// Archive moves the file into the processed subdirectory
+func (d Local) Archive(ctx context.Context, f File) error {
+	dir := filepath.Join(string(d), Processed)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return archive.Move(f.Path, archive.FreeName(dir, f.Name))
+}
+
+func (d Local) String() string {
+	return string(d)
+}
+
+// Pull fetches the files of the remote folder that are ready into the local
+// directory dir, under a free name, and archives each one fetched in the
+// remote folder. It returns the paths written. A file that fails waits
+// RetryAfter in from, as a failed statement does, and the failures are
+// returned together after the rest are pulled.
+func Pull(ctx context.Context, from *Folder, dir string, now time.Time) ([]string, error) {
+	ready, err := from.Poll(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	var pulled []string
+	var errs []error
+	for _, path := range ready {
+		if ctx.Err() != nil {
+			break
+		}
+		f := from.files[path]
+		// Fetched under a hidden name, which the drop folder passes over
+		// until the file is complete
+		part := filepath.Join(dir, "."+f.Name+".part")
+		if err := from.Source.Fetch(ctx, f, part); err != nil {
+			os.Remove(part)
+			from.Fail(path, now)
+			errs = append(errs, fmt.Errorf("failed to fetch %s: %w", f.Name, err))
+			continue
+		}
+		local := archive.FreeName(dir, f.Name)
+		if err := archive.Move(part, local); err != nil {
+			os.Remove(part)
+			return pulled, errors.Join(append(errs, fmt.Errorf("failed to save %s: %w", f.Name, err))...)
+		}
+		pulled = append(pulled, local)
+		// Not archived, the file is pulled again at the next poll; the
+		// processing ledger keeps it from being sent to a service twice
+		if err := from.Source.Archive(ctx, f); err != nil {
+			from.Fail(path, now)
+			errs = append(errs, fmt.Errorf("failed to archive %s in %s: %w", f.Name, from.Source, err))
+			continue
+		}
+		from.Done(path)
+	}
+	return pulled, errors.Join(errs...)
+}
+
+// writeFile writes the content of r to a new file at path
+func writeFile(path string, r io.Reader) error {
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// numbered returns name with a -n suffix before its extension, as
+// archive.FreeName names a file when name is taken
+func numbered(name string, n int) string {
+	if n < 2 {
+		return name
+	}
+	ext := filepath.Ext(name)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(name, ext), n, ext)
+}
@@ -0,0 +1,179 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// davServer is a WebDAV server keeping one folder's files in memory
+type davServer struct {
+	mu        sync.Mutex
+	files     map[string]string // by path
+	modified  time.Time
+	processed bool
+}
+
+func (d *davServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if user, password, _ := r.BasicAuth(); user != "scanner" || password != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch r.Method {
+	case "PROPFIND":
+		var b strings.Builder
+		b.WriteString(`<?xml version="1.0"?><d:multistatus xmlns:d="DAV:">`)
+		b.WriteString(`<d:response><d:href>/inbox/</d:href><d:propstat><d:prop><d:resourcetype><d:collection/></d:resourcetype></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+		if d.processed {
+			b.WriteString(`<d:response><d:href>/inbox/processed/</d:href><d:propstat><d:prop><d:resourcetype><d:collection/></d:resourcetype></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+		}
+		for p, content := range d.files {
+			if path.Dir(p) != "/inbox" {
+				continue
+			}
+			fmt.Fprintf(&b, `<d:response><d:href>%s</d:href><d:propstat><d:prop><d:resourcetype/><d:getcontentlength>%d</d:getcontentlength><d:getlastmodified>%s</d:getlastmodified></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`,
+				(&url.URL{Path: p}).EscapedPath(), len(content), d.modified.Format(http.TimeFormat))
+		}
+		b.WriteString(`</d:multistatus>`)
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprint(w, b.String())
+	case http.MethodGet:
+		content, ok := d.files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, content)
+	case "MKCOL":
+		if d.processed {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		d.processed = true
+		w.WriteHeader(http.StatusCreated)
+	case "MOVE":
+		to, err := url.Parse(r.Header.Get("Destination"))
+		if err != nil || !d.processed {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		if _, ok := d.files[to.Path]; ok && r.Header.Get("Overwrite") == "F" {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		d.files[to.Path] = d.files[r.URL.Path]
+		delete(d.files, r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestPull(t *testing.T) {
+	dav := &davServer{
+		files: map[string]string{
+			"/inbox/jan statement.pdf":           "%PDF-jan",
+			"/inbox/notes.txt":                   "not a statement",
+			"/inbox/processed/jan statement.pdf": "%PDF-earlier",
+		},
+		modified:  time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC),
+		processed: true,
+	}
+	server := httptest.NewServer(dav)
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	source, err := Open("dav://scanner@"+u.Host+"/inbox", Options{Password: "secret"})
+	require.NoError(t, err)
+	assert.Equal(t, "http://"+u.Host+"/inbox/", source.String(), "credentials are left out")
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "jan statement.pdf"), []byte("%PDF-old"), 0o644))
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	remote := New(source, time.Hour)
+
+	pulled, err := Pull(ctx, remote, dir, now)
+	require.NoError(t, err)
+	assert.Empty(t, pulled, "files are pulled once they are seen unchanged")
+
+	pulled, err = Pull(ctx, remote, dir, now)
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(dir, "jan statement-2.pdf")}, pulled)
+	content, err := os.ReadFile(pulled[0])
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-jan", string(content))
+
+	assert.Equal(t, map[string]string{
+		"/inbox/notes.txt":                     "not a statement",
+		"/inbox/processed/jan statement.pdf":   "%PDF-earlier",
+		"/inbox/processed/jan statement-2.pdf": "%PDF-jan",
+	}, dav.files, "the pulled file is archived in the processed subfolder")
+
+	pulled, err = Pull(ctx, remote, dir, now)
+	require.NoError(t, err)
+	assert.Empty(t, pulled)
+
+	dav.files["/inbox/feb.pdf"] = "%PDF-feb"
+	dav.files["/inbox/mar.pdf"] = "%PDF-mar"
+	_, err = Pull(ctx, remote, dir, now)
+	require.NoError(t, err)
+	delete(dav.files, "/inbox/feb.pdf")
+	pulled, err = Pull(ctx, remote, dir, now)
+	require.NoError(t, err, "a file gone before it is fetched is not listed")
+	assert.Equal(t, []string{filepath.Join(dir, "mar.pdf")}, pulled)
+
+	wrong, err := Open("dav://scanner@"+u.Host+"/inbox", Options{Password: "wrong"})
+	require.NoError(t, err)
+	_, err = New(wrong, time.Hour).Poll(ctx, now)
+	assert.ErrorContains(t, err, "401 Unauthorized")
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	for _, location := range []string{dir, "file://" + filepath.ToSlash(dir)} {
+		source, err := Open(location, Options{})
+		require.NoError(t, err)
+		assert.Equal(t, Local(dir), source, location)
+	}
+
+	source, err := Open("davs://nas.local/scans", Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://nas.local/scans/", source.String())
+
+	_, err = Open("smb://nas.local/scans", Options{})
+	assert.ErrorContains(t, err, "mount the share")
+	_, err = Open("ftp://user:pw@nas.local/scans", Options{})
+	assert.ErrorContains(t, err, "unsupported source")
+	assert.NotContains(t, err.Error(), "pw")
+}
+
+func TestLocalArchive(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "jan.pdf"), []byte("%PDF-jan"), 0o644))
+	source := Local(dir)
+	ctx := context.Background()
+
+	files, err := source.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.NoError(t, source.Fetch(ctx, files[0], filepath.Join(t.TempDir(), "copy.pdf")))
+	require.NoError(t, source.Archive(ctx, files[0]))
+	assert.FileExists(t, filepath.Join(dir, Processed, "jan.pdf"))
+	assert.NoFileExists(t, filepath.Join(dir, "jan.pdf"))
+}
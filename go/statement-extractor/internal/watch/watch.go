@@ -0,0 +1,195 @@
+// Package watch finds the statements dropped into a folder that are ready
+// to be processed. File system events say when to look, where the folder's
+// file system has them; polling finds the files, so it works the same on
+// network shares and synced folders without events, and a file is only
+// handed out once it has stopped changing between polls, so statements still
+// being copied in are left alone. Folders are listed through a Source, so a
+// remote folder is polled the same way and its statements pulled into a
+// local one.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/example/statement-extractor/internal/archive"
+)
+
+// Settle is how long to wait for a new or changed file to stop changing
+// before polling again, when there are file system events
+const Settle = 2 * time.Second
+
+// stamp identifies one version of a file
+type stamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// failure is the version of a file that last failed and when
+type failure struct {
+	stamp
+	at time.Time
+}
+
+// Folder is a drop folder. Only files directly in it are considered, so
+// the processed and output folders may live inside it.
+type Folder struct {
+	Source Source
+
+	// RetryAfter is how long a statement that failed waits before it is
+	// tried again; a changed file is tried again straight away
+	RetryAfter time.Duration
+
+	seen     map[string]stamp // as of the previous poll
+	files    map[string]File
+	failed   map[string]failure
+	settling bool
+}
+
+// New returns the drop folder listed from source
+func New(source Source, retryAfter time.Duration) *Folder {
+	return &Folder{
+		Source:     source,
+		RetryAfter: retryAfter,
+		seen:       make(map[string]stamp),
+		files:      make(map[string]File),
+		failed:     make(map[string]failure),
+	}
+}
+
+// Poll returns the paths of the statements and archives in the folder that
+// are ready, in name order: unchanged since the previous poll, and not
+// failed in the same version less than RetryAfter before now. Files already
+// there when watching starts are ready from the second poll.
+func (f *Folder) Poll(ctx context.Context, now time.Time) ([]string, error) {
+	listed, err := f.Source.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(listed, func(a, b File) int { return strings.Compare(a.Name, b.Name) })
+
+	seen := make(map[string]stamp)
+	files := make(map[string]File)
+	var ready []string
+	settling := false
+	for _, file := range listed {
+		if strings.HasPrefix(file.Name, ".") || !statement(file.Name) {
+			continue
+		}
+		path := file.Path
+		s := stamp{file.Size, file.ModTime}
+		seen[path], files[path] = s, file
+
+		if prev, ok := f.seen[path]; !ok || prev != s {
+			settling = true
+			continue
+		}
+		if fail, ok := f.failed[path]; ok && fail.stamp == s && now.Sub(fail.at) < f.RetryAfter {
+			continue
+		}
+		ready = append(ready, path)
+	}
+
+	// Forget files that are gone
+	for path := range f.failed {
+		if _, ok := seen[path]; !ok {
+			delete(f.failed, path)
+		}
+	}
+	f.seen, f.files, f.settling = seen, files, settling
+	return ready, nil
+}
+
+// Settling reports whether the last poll found a statement new or changed
+// since the poll before, which the next poll may find ready
+func (f *Folder) Settling() bool {
+	return f.settling
+}
+
+// Notifier reports changes to the folder from file system events
+type Notifier struct {
+	// C receives when files in the folder are added, written, renamed or
+	// removed. Changes that come while one is waiting are merged into it.
+	C <-chan struct{}
+
+	watcher *fsnotify.Watcher
+}
+
+// Notify watches a local folder for file system events. It fails where the
+// file system has none to give, such as some network shares, and for remote
+// sources, leaving the folder to be polled.
+func (f *Folder) Notify() (*Notifier, error) {
+	dir, ok := f.Source.(Local)
+	if !ok {
+		return nil, fmt.Errorf("%s reports no changes", f.Source)
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch %s for changes: %w", dir, err)
+	}
+	if err := watcher.Add(string(dir)); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch %s for changes: %w", dir, err)
+	}
+
+	c := make(chan struct{}, 1)
+	changed := func() {
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// Permission changes alone say nothing about the content
+				if event.Op != fsnotify.Chmod {
+					changed()
+				}
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				// Events were lost, such as when the queue overflowed
+				changed()
+			}
+		}
+	}()
+	return &Notifier{C: c, watcher: watcher}, nil
+}
+
+// Close stops watching the folder
+func (n *Notifier) Close() error {
+	return n.watcher.Close()
+}
+
+// Fail records that the statement at path failed at now, so that it waits
+// RetryAfter before it is tried again
+func (f *Folder) Fail(path string, now time.Time) {
+	if s, ok := f.seen[path]; ok {
+		f.failed[path] = failure{s, now}
+	}
+}
+
+// Done forgets the statement at path once it has been moved out of the
+// folder
+func (f *Folder) Done(path string) {
+	delete(f.seen, path)
+	delete(f.files, path)
+	delete(f.failed, path)
+}
+
+// statement reports whether name is a statement or archive to process
+func statement(name string) bool {
+	return archive.IsArchive(name) || slices.Contains(archive.Extensions, strings.ToLower(filepath.Ext(name)))
+}
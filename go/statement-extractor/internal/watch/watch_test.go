@@ -0,0 +1,86 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoll(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+	jan := write("jan.pdf", "%PDF-jan")
+	write("notes.txt", "not a statement")
+	write(".partial.pdf", "hidden")
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "processed"), 0o755))
+
+	f := New(Local(dir), time.Hour)
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	ready, err := f.Poll(ctx, now)
+	require.NoError(t, err)
+	assert.Empty(t, ready, "files are ready once they are seen unchanged")
+	assert.True(t, f.Settling())
+
+	feb := write("feb.zip", "PK")
+	ready, err = f.Poll(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{jan}, ready)
+	assert.True(t, f.Settling(), "feb.zip is new")
+
+	// feb.zip is still being written
+	write("feb.zip", "PK more")
+	ready, err = f.Poll(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{jan}, ready)
+
+	f.Fail(jan, now)
+	ready, err = f.Poll(ctx, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []string{feb}, ready, "a failed statement waits")
+	assert.False(t, f.Settling())
+
+	ready, err = f.Poll(ctx, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []string{feb, jan}, ready, "and is retried after RetryAfter")
+
+	f.Fail(jan, now.Add(time.Hour))
+	require.NoError(t, os.Chtimes(jan, now, now))
+	_, err = f.Poll(ctx, now.Add(time.Hour))
+	require.NoError(t, err)
+	ready, err = f.Poll(ctx, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []string{feb, jan}, ready, "a changed statement is retried straight away")
+
+	require.NoError(t, os.Remove(jan))
+	f.Done(jan)
+	ready, err = f.Poll(ctx, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []string{feb}, ready)
+}
+
+func TestNotify(t *testing.T) {
+	dir := t.TempDir()
+	n, err := New(Local(dir), time.Hour).Notify()
+	require.NoError(t, err)
+	defer n.Close()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "jan.pdf"), []byte("%PDF-jan"), 0o644))
+	select {
+	case <-n.C:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no change reported for a new file")
+	}
+
+	_, err = New(Local(filepath.Join(dir, "missing")), time.Hour).Notify()
+	assert.Error(t, err)
+}
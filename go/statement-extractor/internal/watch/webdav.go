@@ -0,0 +1,191 @@
+package watch
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// maxNumbered bounds the names tried when archiving over existing ones
+const maxNumbered = 100
+
+// WebDAV is a folder on a WebDAV server, such as a scanner's or NAS's
+// share. It is listed with PROPFIND, fetched with GET and archived with
+// MKCOL and MOVE, with basic auth when there is a Username.
+type WebDAV struct {
+	URL      *url.URL // the folder, ending in "/"
+	Username string
+	Password string
+	Client   *http.Client
+}
+
+// NewWebDAV returns the WebDAV folder at u, an http or https URL. The user
+// in u is authenticated as, with the password in u or else opts.Password.
+func NewWebDAV(u *url.URL, opts Options) *WebDAV {
+	folder := *u
+	if !strings.HasSuffix(folder.Path, "/") {
+		folder.Path += "/"
+	}
+	folder.RawPath, folder.User = "", nil
+	w := &WebDAV{URL: &folder, Password: opts.Password, Client: opts.Client}
+	if u.User != nil {
+		w.Username = u.User.Username()
+		if password, ok := u.User.Password(); ok {
+			w.Password = password
+		}
+	}
+	if w.Client == nil {
+		w.Client = http.DefaultClient
+	}
+	return w
+}
+
+const propfind = `<?xml version="1.0" encoding="utf-8"?>
+<propfind xmlns="DAV:"><prop><resourcetype/><getcontentlength/><getlastmodified/></prop></propfind>`
+
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"DAV: href"`
+		Propstat []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"DAV: collection"`
+				} `xml:"DAV: resourcetype"`
+				ContentLength string `xml:"DAV: getcontentlength"`
+				LastModified  string `xml:"DAV: getlastmodified"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+// List returns the files in the folder, from a PROPFIND of depth 1
+func (w *WebDAV) List(ctx context.Context) ([]File, error) {
+	resp, err := w.do(ctx, "PROPFIND", w.URL, strings.NewReader(propfind), map[string]string{
+		"Depth": "1", "Content-Type": "application/xml; charset=utf-8",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", w, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, fmt.Errorf("failed to list %s: %s", w, status(resp))
+	}
+	var ms multistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("failed to list %s: decoding response: %w", w, err)
+	}
+
+	var files []File
+	for _, r := range ms.Responses {
+		href, err := w.URL.Parse(r.Href)
+		if err != nil || strings.HasSuffix(href.Path, "/") || path.Dir(href.Path) != path.Clean(w.URL.Path) {
+			// The folder itself, a subfolder, or beyond the folder
+			continue
+		}
+		f := File{Path: href.String(), Name: path.Base(href.Path)}
+		found := false
+		for _, ps := range r.Propstat {
+			if !strings.Contains(ps.Status, " 200 ") || ps.Prop.ResourceType.Collection != nil {
+				continue
+			}
+			found = true
+			f.Size, _ = strconv.ParseInt(strings.TrimSpace(ps.Prop.ContentLength), 10, 64)
+			f.ModTime, _ = http.ParseTime(strings.TrimSpace(ps.Prop.LastModified))
+		}
+		if found {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// Fetch downloads the file to path
+func (w *WebDAV) Fetch(ctx context.Context, f File, local string) error {
+	u, err := url.Parse(f.Path)
+	if err != nil {
+		return err
+	}
+	resp, err := w.do(ctx, http.MethodGet, u, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s", status(resp))
+	}
+	return writeFile(local, resp.Body)
+}
+
+// Archive moves the file into the processed subfolder, creating it first,
+// with a -2, -3... suffix when its name is taken there
+func (w *WebDAV) Archive(ctx context.Context, f File) error {
+	dir := w.URL.JoinPath(Processed + "/")
+	resp, err := w.do(ctx, "MKCOL", dir, nil, nil)
+	if err != nil {
+		return err
+	}
+	msg := status(resp)
+	resp.Body.Close()
+	// 405 Method Not Allowed: it already exists
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusMethodNotAllowed {
+		return fmt.Errorf("creating %s: %s", Processed, msg)
+	}
+
+	from, err := url.Parse(f.Path)
+	if err != nil {
+		return err
+	}
+	for n := 1; n <= maxNumbered; n++ {
+		to := dir.JoinPath(numbered(f.Name, n))
+		resp, err := w.do(ctx, "MOVE", from, nil, map[string]string{"Destination": to.String(), "Overwrite": "F"})
+		if err != nil {
+			return err
+		}
+		msg := status(resp)
+		resp.Body.Close()
+		switch resp.StatusCode {
+		case http.StatusCreated, http.StatusNoContent:
+			return nil
+		case http.StatusPreconditionFailed:
+			// Taken; try the next name
+		default:
+			return fmt.Errorf("moving to %s: %s", Processed, msg)
+		}
+	}
+	return fmt.Errorf("moving to %s: no free name for %s", Processed, f.Name)
+}
+
+func (w *WebDAV) String() string {
+	return w.URL.String()
+}
+
+// do sends a request with the folder's credentials
+func (w *WebDAV) do(ctx context.Context, method string, u *url.URL, body io.Reader, header map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	if w.Username != "" {
+		req.SetBasicAuth(w.Username, w.Password)
+	}
+	return w.Client.Do(req)
+}
+
+// status describes an unexpected response with the start of its body
+func status(resp *http.Response) string {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if text := strings.TrimSpace(string(msg)); text != "" && !strings.HasPrefix(text, "<") {
+		return resp.Status + ": " + text
+	}
+	return resp.Status
+}
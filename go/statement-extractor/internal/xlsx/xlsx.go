@@ -0,0 +1,284 @@
+// Package xlsx writes minimal Office Open XML spreadsheets: typed cells,
+// bold frozen header rows and currency and date number formats. It covers
+// what the exports need without a spreadsheet library dependency.
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Workbook is a set of sheets written together as one .xlsx file
+type Workbook struct {
+	Sheets []*Sheet
+
+	// CurrencyFormat is the Excel number format for money cells
+	CurrencyFormat string
+}
+
+// Sheet is a worksheet with a frozen header row. Cell values are written by
+// type: float64 as money, int as a plain number, time.Time as a date and
+// anything else as text.
+type Sheet struct {
+	Name   string
+	Header []string
+	Rows   [][]any
+}
+
+// DefaultCurrencyFormat shows two decimals with thousands separators
+const DefaultCurrencyFormat = `#,##0.00;-#,##0.00`
+
+// New returns an empty workbook
+func New(currencyFormat string) *Workbook {
+	if currencyFormat == "" {
+		currencyFormat = DefaultCurrencyFormat
+	}
+	return &Workbook{CurrencyFormat: currencyFormat}
+}
+
+// AddSheet appends a sheet. Names are made valid for Excel: at most 31
+// characters, without []:*?/\ and unique within the workbook.
+func (w *Workbook) AddSheet(name string, header ...string) *Sheet {
+	s := &Sheet{Name: w.uniqueName(name), Header: header}
+	w.Sheets = append(w.Sheets, s)
+	return s
+}
+
+// Append adds a row of values
+func (s *Sheet) Append(values ...any) {
+	s.Rows = append(s.Rows, values)
+}
+
+func (w *Workbook) uniqueName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '-'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" {
+		name = "Sheet"
+	}
+
+	candidate := truncate(name, 31)
+	for n := 2; w.hasSheet(candidate); n++ {
+		suffix := fmt.Sprintf(" (%d)", n)
+		candidate = truncate(name, 31-len(suffix)) + suffix
+	}
+	return candidate
+}
+
+func (w *Workbook) hasSheet(name string) bool {
+	for _, s := range w.Sheets {
+		if strings.EqualFold(s.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(s string, n int) string {
+	for utf8.RuneCountInString(s) > n {
+		_, size := utf8.DecodeLastRuneInString(s)
+		s = s[:len(s)-size]
+	}
+	return s
+}
+
+// Cell styles, indexes into cellXfs in styles.xml
+const (
+	stylePlain = iota
+	styleHeader
+	styleCurrency
+	styleDate
+)
+
+// Write encodes the workbook as .xlsx
+func (w *Workbook) Write(out io.Writer) error {
+	if len(w.Sheets) == 0 {
+		w.AddSheet("Sheet1")
+	}
+
+	z := zip.NewWriter(out)
+	file := func(name, content string) error {
+		f, err := z.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(f, xml.Header+content)
+		return err
+	}
+
+	var overrides, sheets, rels strings.Builder
+	for i, s := range w.Sheets {
+		n := i + 1
+		fmt.Fprintf(&overrides, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)
+		fmt.Fprintf(&sheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(s.Name), n, n)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)
+	}
+	fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(w.Sheets)+1)
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			overrides.String() + `</Types>`},
+		{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + sheets.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			rels.String() + `</Relationships>`},
+		{"xl/styles.xml", w.styles()},
+	}
+	for i, s := range w.Sheets {
+		parts = append(parts, struct{ name, content string }{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), s.xml()})
+	}
+
+	for _, part := range parts {
+		if err := file(part.name, part.content); err != nil {
+			return fmt.Errorf("writing %s: %w", part.name, err)
+		}
+	}
+	return z.Close()
+}
+
+func (w *Workbook) styles() string {
+	return `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<numFmts count="2">` +
+		`<numFmt numFmtId="164" formatCode="` + escape(w.CurrencyFormat) + `"/>` +
+		`<numFmt numFmtId="165" formatCode="yyyy-mm-dd"/>` +
+		`</numFmts>` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="4">` +
+		`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+		`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+		`<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+		`</cellXfs></styleSheet>`
+}
+
+func (s *Sheet) xml() string {
+	widths := make([]int, len(s.Header))
+	measure := func(col, width int) {
+		for len(widths) <= col {
+			widths = append(widths, 0)
+		}
+		widths[col] = max(widths[col], width)
+	}
+
+	var rows strings.Builder
+	if len(s.Header) > 0 {
+		rows.WriteString(`<row r="1">`)
+		for col, name := range s.Header {
+			writeCell(&rows, col, 1, name, styleHeader)
+			measure(col, utf8.RuneCountInString(name))
+		}
+		rows.WriteString(`</row>`)
+	}
+	offset := 1
+	if len(s.Header) == 0 {
+		offset = 0
+	}
+	for i, row := range s.Rows {
+		r := i + 1 + offset
+		fmt.Fprintf(&rows, `<row r="%d">`, r)
+		for col, value := range row {
+			measure(col, writeCell(&rows, col, r, value, -1))
+		}
+		rows.WriteString(`</row>`)
+	}
+
+	var b strings.Builder
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if len(s.Header) > 0 {
+		b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	}
+	if len(widths) > 0 {
+		b.WriteString(`<cols>`)
+		for col, width := range widths {
+			fmt.Fprintf(&b, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, col+1, col+1, min(max(width, 8), 60)+2)
+		}
+		b.WriteString(`</cols>`)
+	}
+	b.WriteString(`<sheetData>` + rows.String() + `</sheetData>`)
+	if len(s.Header) > 0 {
+		fmt.Fprintf(&b, `<autoFilter ref="A1:%s%d"/>`, column(len(s.Header)-1), len(s.Rows)+1)
+	}
+	b.WriteString(`</worksheet>`)
+	return b.String()
+}
+
+// writeCell writes one cell, styled by its value's type unless style is
+// given, and returns its approximate display width
+func writeCell(b *strings.Builder, col, row int, value any, style int) int {
+	ref := column(col) + strconv.Itoa(row)
+	attr := func(defaultStyle int) string {
+		if style < 0 {
+			style = defaultStyle
+		}
+		if style == stylePlain {
+			return fmt.Sprintf(`r="%s"`, ref)
+		}
+		return fmt.Sprintf(`r="%s" s="%d"`, ref, style)
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return writeCell(b, col, row, strconv.FormatFloat(v, 'f', -1, 64), style)
+		}
+		fmt.Fprintf(b, `<c %s><v>%s</v></c>`, attr(styleCurrency), strconv.FormatFloat(v, 'f', -1, 64))
+		return len(strconv.FormatFloat(v, 'f', 2, 64)) + 3
+	case int:
+		fmt.Fprintf(b, `<c %s><v>%d</v></c>`, attr(stylePlain), v)
+		return len(strconv.Itoa(v))
+	case time.Time:
+		fmt.Fprintf(b, `<c %s><v>%s</v></c>`, attr(styleDate), strconv.FormatFloat(serial(v), 'f', -1, 64))
+		return 10
+	default:
+		text := fmt.Sprint(v)
+		if text == "" {
+			return 0
+		}
+		fmt.Fprintf(b, `<c %s t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, attr(stylePlain), escape(text))
+		return utf8.RuneCountInString(text)
+	}
+}
+
+// serial converts a date to Excel's day count from 1899-12-30
+func serial(t time.Time) float64 {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Sub(epoch).Hours() / 24
+}
+
+// column converts a 0-based index to a column letter: 0 is A, 26 is AA
+func column(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,81 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parts unzips a workbook, checking every part is well-formed XML
+func parts(t *testing.T, data []byte) map[string]string {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	files := make(map[string]string)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+
+		dec := xml.NewDecoder(bytes.NewReader(content))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else {
+				require.NoError(t, err, f.Name)
+			}
+		}
+		files[f.Name] = string(content)
+	}
+	return files
+}
+
+func TestWrite(t *testing.T) {
+	w := New(`"$"#,##0.00`)
+	s := w.AddSheet("2024-01", "date", "description", "amount", "count")
+	s.Append(time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), "M&S <Food>", -54.1, 2)
+	s.Append(nil, "", 0.0)
+
+	var b bytes.Buffer
+	require.NoError(t, w.Write(&b))
+	files := parts(t, b.Bytes())
+
+	assert.Contains(t, files, "[Content_Types].xml")
+	assert.Contains(t, files["xl/workbook.xml"], `<sheet name="2024-01" sheetId="1" r:id="rId1"/>`)
+	assert.Contains(t, files["xl/styles.xml"], `formatCode="&#34;$&#34;#,##0.00"`)
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `state="frozen"`)
+	assert.Contains(t, sheet, `<c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">date</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="A2" s="3"><v>45294</v></c>`, "dates are Excel serial numbers")
+	assert.Contains(t, sheet, `<t xml:space="preserve">M&amp;S &lt;Food&gt;</t>`)
+	assert.Contains(t, sheet, `<c r="C2" s="2"><v>-54.1</v></c>`)
+	assert.Contains(t, sheet, `<c r="D2"><v>2</v></c>`)
+	assert.Contains(t, sheet, `<autoFilter ref="A1:D3"/>`)
+}
+
+func TestAddSheetNames(t *testing.T) {
+	w := New("")
+	assert.Equal(t, "Joint - Savings", w.AddSheet("Joint / Savings").Name)
+	assert.Equal(t, "joint - savings (2)", w.AddSheet("joint - savings").Name)
+	assert.Equal(t, strings.Repeat("x", 31), w.AddSheet(strings.Repeat("x", 40)).Name)
+	assert.Equal(t, strings.Repeat("x", 27)+" (2)", w.AddSheet(strings.Repeat("x", 35)).Name)
+	assert.Equal(t, "Sheet", w.AddSheet("  ").Name)
+}
+
+func TestColumn(t *testing.T) {
+	assert.Equal(t, "A", column(0))
+	assert.Equal(t, "Z", column(25))
+	assert.Equal(t, "AA", column(26))
+	assert.Equal(t, "AZ", column(51))
+	assert.Equal(t, "BA", column(52))
+}
@@ -0,0 +1,238 @@
+// Package client calls the HTTP API of "statement-extractor serve", as
+// described by the /openapi.json it serves, so that other tools can extract
+// and categorize statements without writing the HTTP calls themselves.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// DefaultPollInterval is how often Wait polls a job when the client has no
+// PollInterval
+const DefaultPollInterval = time.Second
+
+// Job statuses
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Client calls one server
+type Client struct {
+	BaseURL      string        // e.g. "http://127.0.0.1:8080"
+	Token        string        // bearer token, when the server has server.token_env set
+	HTTP         *http.Client  // nil is http.DefaultClient
+	PollInterval time.Duration // between polls of a job in Wait; default DefaultPollInterval
+}
+
+// New returns a client of the server at baseURL
+func New(baseURL, token string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), Token: token}
+}
+
+// Job is a statement queued for extraction
+type Job struct {
+	ID         string                       `json:"id"`
+	Status     string                       `json:"status"`
+	Error      string                       `json:"error,omitempty"`
+	Result     *transaction.TransactionList `json:"result,omitempty"` // once the job has succeeded
+	Position   int                          `json:"position,omitempty"`
+	CreatedAt  time.Time                    `json:"created_at"`
+	StartedAt  time.Time                    `json:"started_at,omitzero"`
+	FinishedAt time.Time                    `json:"finished_at,omitzero"`
+}
+
+// Finished reports whether the job has succeeded or failed
+func (j Job) Finished() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed
+}
+
+// Error is a request the server refused or failed
+type Error struct {
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration // for 429 Too Many Requests, when the queue is full
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("statement-extractor: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Extract queues the statement document, named name, and waits for its
+// transactions. An empty bank has the server detect the parser.
+func (c *Client) Extract(ctx context.Context, name string, document io.Reader, bank string) (transaction.TransactionList, error) {
+	job, err := c.Submit(ctx, name, document, bank)
+	if err != nil {
+		return transaction.TransactionList{}, err
+	}
+	return c.Wait(ctx, job.ID)
+}
+
+// Submit queues the statement document, named name, for extraction. When
+// the queue is full it fails with an *Error of status 429.
+func (c *Client) Submit(ctx context.Context, name string, document io.Reader, bank string) (Job, error) {
+	query := url.Values{}
+	if name != "" {
+		query.Set("name", name)
+	}
+	if bank != "" {
+		query.Set("bank", bank)
+	}
+	var job Job
+	err := c.do(ctx, http.MethodPost, "/extract?"+query.Encode(), "application/octet-stream", document, &job)
+	return job, err
+}
+
+// Job returns the job with the given ID
+func (c *Client) Job(ctx context.Context, id string) (Job, error) {
+	var job Job
+	err := c.do(ctx, http.MethodGet, "/jobs/"+url.PathEscape(id), "", nil, &job)
+	return job, err
+}
+
+// Wait polls the job with the given ID until it finishes, returning its
+// transactions, or its error when it failed
+func (c *Client) Wait(ctx context.Context, id string) (transaction.TransactionList, error) {
+	interval := c.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	for {
+		job, err := c.Job(ctx, id)
+		if err != nil {
+			return transaction.TransactionList{}, err
+		}
+		switch {
+		case job.Status == StatusFailed:
+			return transaction.TransactionList{}, fmt.Errorf("job %s failed: %s", id, job.Error)
+		case job.Status == StatusSucceeded && job.Result != nil:
+			return *job.Result, nil
+		case job.Status == StatusSucceeded:
+			return transaction.TransactionList{}, fmt.Errorf("job %s has no result", id)
+		}
+		select {
+		case <-ctx.Done():
+			return transaction.TransactionList{}, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Categories returns the categories transactions can be assigned
+func (c *Client) Categories(ctx context.Context) ([]string, error) {
+	var body struct {
+		Categories []string `json:"categories"`
+	}
+	err := c.do(ctx, http.MethodGet, "/categories", "", nil, &body)
+	return body.Categories, err
+}
+
+// Categorize returns txns categorized by the server's rules
+func (c *Client) Categorize(ctx context.Context, txns []transaction.Transaction) (transaction.TransactionList, error) {
+	body, err := json.Marshal(txns)
+	if err != nil {
+		return transaction.TransactionList{}, fmt.Errorf("failed to encode transactions: %w", err)
+	}
+	var list transaction.TransactionList
+	err = c.do(ctx, http.MethodPost, "/categorize", "application/json", bytes.NewReader(body), &list)
+	return list, err
+}
+
+// Lease is a statement a remote worker has taken to extract, from a server
+// with server.remote_workers set
+type Lease struct {
+	Job       string    `json:"job"`
+	Token     string    `json:"token"`
+	Expires   time.Time `json:"expires_at"` // when the job is queued again unless completed
+	Statement Statement `json:"payload"`
+}
+
+// Statement is an uploaded statement
+type Statement struct {
+	Name     string `json:"name"`
+	Bank     string `json:"bank,omitempty"` // empty to detect the parser
+	Document []byte `json:"document"`
+}
+
+// Lease takes the next statement queued for extraction, waiting up to wait
+// for one. It reports false when none was queued in time.
+func (c *Client) Lease(ctx context.Context, wait time.Duration) (Lease, bool, error) {
+	var lease Lease
+	err := c.do(ctx, http.MethodPost, "/worker/lease?wait="+url.QueryEscape(wait.String()), "", nil, &lease)
+	return lease, err == nil && lease.Job != "", err
+}
+
+// Complete reports the transactions extracted for lease, or failed, the
+// error extracting them, when not nil. A lease that lapsed fails with an
+// *Error of status 409.
+func (c *Client) Complete(ctx context.Context, lease Lease, list transaction.TransactionList, failed error) error {
+	completion := struct {
+		Token  string                       `json:"token"`
+		Result *transaction.TransactionList `json:"result,omitempty"`
+		Error  string                       `json:"error,omitempty"`
+	}{Token: lease.Token, Result: &list}
+	if failed != nil {
+		completion.Result, completion.Error = nil, failed.Error()
+	}
+	body, err := json.Marshal(completion)
+	if err != nil {
+		return fmt.Errorf("failed to encode transactions: %w", err)
+	}
+	return c.do(ctx, http.MethodPost, "/worker/jobs/"+url.PathEscape(lease.Job)+"/complete", "application/json", bytes.NewReader(body), nil)
+}
+
+// do sends a request and decodes the JSON response, if any, into out
+func (c *Client) do(ctx context.Context, method, path, contentType string, body io.Reader, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		apiErr := &Error{StatusCode: resp.StatusCode, Message: resp.Status}
+		var failed struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&failed) == nil && failed.Error != "" {
+			apiErr.Message = failed.Error
+		}
+		if seconds, err := time.ParseDuration(resp.Header.Get("Retry-After") + "s"); err == nil {
+			apiErr.RetryAfter = seconds
+		}
+		return apiErr
+	}
+	if resp.StatusCode == http.StatusNoContent || out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid response to %s %s: %w", method, path, err)
+	}
+	return nil
+}
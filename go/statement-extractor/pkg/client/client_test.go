@@ -0,0 +1,167 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/example/statement-extractor/internal/api"
+	"github.com/example/statement-extractor/internal/jobs"
+	"github.com/example/statement-extractor/pkg/transaction"
+)
+
+// fakeService extracts one transaction described by the document and
+// categorizes everything as Groceries
+type fakeService struct {
+	release chan struct{}
+}
+
+func (s *fakeService) Extract(_ context.Context, name string, document []byte, bank string) (transaction.TransactionList, error) {
+	if s.release != nil {
+		<-s.release
+	}
+	if string(document) == "broken" {
+		return transaction.TransactionList{}, errors.New("no parser recognized the statement")
+	}
+	list := transaction.TransactionList{Source: name + " " + bank}
+	list.AddTransaction(transaction.Transaction{Description: string(document), Amount: -450, Category: "Groceries"})
+	return list, nil
+}
+
+func (s *fakeService) Categorize(_ context.Context, txns []transaction.Transaction) error {
+	for i := range txns {
+		txns[i].Category = "Groceries"
+	}
+	return nil
+}
+
+func (s *fakeService) Categories() []string {
+	return []string{"Groceries", "Uncategorized"}
+}
+
+func newServer(t *testing.T, svc api.Service, capacity int) *Client {
+	q := jobs.NewQueue(jobs.Options{Workers: 1, Capacity: capacity})
+	srv := httptest.NewServer(api.RequireToken("s3cret", api.Handler(svc, q)))
+	t.Cleanup(func() {
+		srv.Close()
+		_ = q.Shutdown(context.Background())
+	})
+	c := New(srv.URL+"/", "s3cret")
+	c.PollInterval = 5 * time.Millisecond
+	return c
+}
+
+func TestExtract(t *testing.T) {
+	c := newServer(t, &fakeService{}, 4)
+	ctx := context.Background()
+
+	list, err := c.Extract(ctx, "jun.pdf", strings.NewReader("COLES"), "cba")
+	require.NoError(t, err)
+	assert.Equal(t, "jun.pdf cba", list.Source)
+	require.Len(t, list.Transactions, 1)
+	assert.Equal(t, transaction.Amount(-450), list.Transactions[0].Amount)
+
+	_, err = c.Extract(ctx, "jul.pdf", strings.NewReader("broken"), "")
+	assert.ErrorContains(t, err, "no parser recognized the statement")
+
+	_, err = c.Job(ctx, "missing")
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "job not found", apiErr.Message)
+
+	c.Token = "wrong"
+	_, err = c.Categories(ctx)
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+}
+
+func TestSubmitQueueFull(t *testing.T) {
+	svc := &fakeService{release: make(chan struct{})}
+	c := newServer(t, svc, 0)
+	ctx := context.Background()
+
+	job, err := c.Submit(ctx, "jun.pdf", strings.NewReader("COLES"), "")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		job, err = c.Job(ctx, job.ID)
+		return err == nil && job.Status == StatusRunning
+	}, 2*time.Second, 5*time.Millisecond)
+
+	_, err = c.Submit(ctx, "jul.pdf", strings.NewReader("ALDI"), "")
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusTooManyRequests, apiErr.StatusCode)
+	assert.Equal(t, 30*time.Second, apiErr.RetryAfter)
+
+	close(svc.release)
+	_, err = c.Wait(ctx, job.ID)
+	require.NoError(t, err)
+}
+
+func TestCategorize(t *testing.T) {
+	c := newServer(t, &fakeService{}, 1)
+	ctx := context.Background()
+
+	categories, err := c.Categories(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Groceries", "Uncategorized"}, categories)
+
+	list, err := c.Categorize(ctx, []transaction.Transaction{{Description: "COLES", Amount: -4550}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, list.Total)
+	assert.Equal(t, "Groceries", list.Transactions[0].Category)
+}
+
+func TestRemoteWorker(t *testing.T) {
+	q := jobs.NewQueue(jobs.Options{Capacity: 2, Remote: true})
+	mux := http.NewServeMux()
+	mux.Handle("/worker/", q.WorkerHandler())
+	mux.Handle("/", api.Handler(&fakeService{}, q))
+	srv := httptest.NewServer(api.RequireToken("s3cret", mux))
+	t.Cleanup(func() {
+		srv.Close()
+		_ = q.Shutdown(context.Background())
+	})
+	c := New(srv.URL, "s3cret")
+	c.PollInterval = 5 * time.Millisecond
+	ctx := context.Background()
+
+	_, ok, err := c.Lease(ctx, 0)
+	require.NoError(t, err)
+	assert.False(t, ok, "nothing is queued")
+
+	job, err := c.Submit(ctx, "jun.pdf", strings.NewReader("COLES"), "cba")
+	require.NoError(t, err)
+	lease, ok, err := c.Lease(ctx, time.Second)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, job.ID, lease.Job)
+	assert.Equal(t, Statement{Name: "jun.pdf", Bank: "cba", Document: []byte("COLES")}, lease.Statement)
+
+	list, err := (&fakeService{}).Extract(ctx, lease.Statement.Name, lease.Statement.Document, lease.Statement.Bank)
+	require.NoError(t, err)
+	require.NoError(t, c.Complete(ctx, lease, list, nil))
+	got, err := c.Wait(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "jun.pdf cba", got.Source)
+
+	var apiErr *Error
+	require.ErrorAs(t, c.Complete(ctx, lease, list, nil), &apiErr)
+	assert.Equal(t, http.StatusConflict, apiErr.StatusCode, "the lease was used")
+
+	job, err = c.Submit(ctx, "jul.pdf", strings.NewReader("broken"), "")
+	require.NoError(t, err)
+	lease, _, err = c.Lease(ctx, time.Second)
+	require.NoError(t, err)
+	require.NoError(t, c.Complete(ctx, lease, transaction.TransactionList{}, errors.New("no parser recognized the statement")))
+	_, err = c.Wait(ctx, job.ID)
+	assert.ErrorContains(t, err, "no parser recognized the statement")
+}
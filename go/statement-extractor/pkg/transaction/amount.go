@@ -0,0 +1,113 @@
+package transaction
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Amount is an exact sum of money in cents, so totals and running balances
+// add up without floating-point drift. In JSON it is a number with two
+// decimal places, e.g. -45.20.
+type Amount int64
+
+// FromFloat returns the amount nearest to f, rounding half-cents away from
+// zero
+func FromFloat(f float64) Amount {
+	return Amount(math.Round(f * 100))
+}
+
+// ParseAmount parses a decimal number such as "-1954.8" or "12" exactly.
+// Digits beyond cents are rounded half away from zero.
+func ParseAmount(s string) (Amount, error) {
+	text := strings.TrimSpace(s)
+	negative := false
+	switch {
+	case strings.HasPrefix(text, "-"):
+		negative, text = true, text[1:]
+	case strings.HasPrefix(text, "+"):
+		text = text[1:]
+	}
+	whole, fraction, _ := strings.Cut(text, ".")
+	if whole == "" && fraction == "" || strings.Trim(whole+fraction, "0123456789") != "" {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	fraction += "000"
+	cents, err := strconv.ParseInt(whole+fraction[:2], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if fraction[2] >= '5' {
+		cents++
+	}
+	if negative {
+		cents = -cents
+	}
+	return Amount(cents), nil
+}
+
+// Float returns the amount in units of the currency, for ratios and other
+// calculations that are not sums of money
+func (a Amount) Float() float64 {
+	return float64(a) / 100
+}
+
+// Abs returns the size of the amount
+func (a Amount) Abs() Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// Mul returns the amount multiplied by f, such as an exchange rate or a
+// percentage, rounded to cents
+func (a Amount) Mul(f float64) Amount {
+	return Amount(math.Round(float64(a) * f))
+}
+
+// String formats the amount with two decimal places, e.g. "-45.20"
+func (a Amount) String() string {
+	sign := ""
+	if a < 0 {
+		sign = "-"
+	}
+	cents := a.Abs()
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// Ptr returns a pointer to a copy of a, for amounts that may be missing,
+// such as a transaction's balance
+func (a Amount) Ptr() *Amount {
+	return &a
+}
+
+// MarshalJSON implements json.Marshaler
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting a number or a string
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	text := string(bytes.Trim(data, `"`))
+	if strings.ContainsAny(text, "eE") {
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return fmt.Errorf("invalid amount %s", data)
+		}
+		*a = FromFloat(f)
+		return nil
+	}
+	v, err := ParseAmount(text)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
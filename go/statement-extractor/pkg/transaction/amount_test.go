@@ -0,0 +1,86 @@
+package transaction
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAmount(t *testing.T) {
+	valid := map[string]Amount{
+		"12.50":   1250,
+		"-1954.8": -195480,
+		"+3":      300,
+		"0.1":     10,
+		".05":     5,
+		"7.":      700,
+		"1.005":   101,
+		"-1.004":  -100,
+		"0.999":   100,
+	}
+	for input, want := range valid {
+		got, err := ParseAmount(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	for _, input := range []string{"", "-", ".", "1.2.3", "1e5", "NaN", "$5", "1,000"} {
+		_, err := ParseAmount(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestAmountString(t *testing.T) {
+	assert.Equal(t, "-45.20", Amount(-4520).String())
+	assert.Equal(t, "-0.05", Amount(-5).String())
+	assert.Equal(t, "0.00", Amount(0).String())
+	assert.Equal(t, "1234.56", Amount(123456).String())
+}
+
+func TestAmountArithmetic(t *testing.T) {
+	var total Amount
+	for range 10 {
+		total += FromFloat(0.1)
+	}
+	assert.Equal(t, Amount(100), total, "ten cents ten times is exactly a dollar")
+
+	assert.Equal(t, Amount(-1502), Amount(-1001).Mul(1.5))
+	assert.Equal(t, Amount(4520), Amount(-4520).Abs())
+	assert.Equal(t, -45.2, Amount(-4520).Float())
+}
+
+func TestAmountJSON(t *testing.T) {
+	data, err := json.Marshal(Split{Category: "Books", Amount: -4520})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"category":"Books","amount":-45.20}`, string(data))
+
+	var s Split
+	require.NoError(t, json.Unmarshal(data, &s))
+	assert.Equal(t, Amount(-4520), s.Amount)
+
+	for input, want := range map[string]Amount{`0.1`: 10, `"12.34"`: 1234, `1e3`: 100000, `-2`: -200} {
+		var a Amount
+		require.NoError(t, json.Unmarshal([]byte(input), &a), input)
+		assert.Equal(t, want, a, input)
+	}
+	var a Amount
+	assert.Error(t, json.Unmarshal([]byte(`"abc"`), &a))
+}
+
+func FuzzParseAmount(f *testing.F) {
+	for _, seed := range []string{"12.50", "-1954.8", "+3", ".05", "7.", "1.005", "-0.995", "", "-", ".", "1.2.3", "92233720368547758.07", "00.0000009"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		amount, err := ParseAmount(s)
+		if err != nil {
+			return
+		}
+		again, err := ParseAmount(amount.String())
+		if err != nil || again != amount {
+			t.Fatalf("ParseAmount(%q) = %v does not round-trip: %v, %v", s, amount, again, err)
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package transaction
+
+import (
+	"slices"
+	"strings"
+)
+
+// Direction is the way a running balance moves with the amounts. Credit card
+// statements run their balances the opposite way to bank accounts, showing
+// the amount owed.
+type Direction int
+
+const (
+	Rising  Direction = 1  // the balance is the one before plus the amount
+	Falling Direction = -1 // the balance is the one before minus the amount
+)
+
+// Next returns the balance after amount, starting from before
+func (d Direction) Next(before, amount Amount) Amount {
+	return before + Amount(d)*amount
+}
+
+// BalanceDirection returns the direction in which balance follows from
+// before with amount, or 0 when it follows in neither, or in both as it
+// does for a zero amount
+func BalanceDirection(before, amount, balance Amount) Direction {
+	switch {
+	case amount == 0:
+		return 0
+	case Rising.Next(before, amount) == balance:
+		return Rising
+	case Falling.Next(before, amount) == balance:
+		return Falling
+	}
+	return 0
+}
+
+// BalanceFollows reports whether balance is before plus or minus amount,
+// for a single pair of balances whose direction is not known
+func BalanceFollows(before, amount, balance Amount) bool {
+	return before+amount == balance || before-amount == balance
+}
+
+// BalanceBreak is a transaction whose running balance does not follow from
+// the balance of the transaction before it, usually because the extractor
+// missed rows in between
+type BalanceBreak struct {
+	Index    int    // position of the transaction in the list
+	Previous int    // position of the transaction with the balance before it
+	Expected Amount // the previous balance plus the amount
+	Gap      Amount // the balance minus Expected: the net amount of any missing rows
+}
+
+// Reconciliation is the result of checking a list's running balances
+type Reconciliation struct {
+	Checked int // transactions whose balance was checked
+	Breaks  []BalanceBreak
+}
+
+// OK reports whether every checked balance follows
+func (r Reconciliation) OK() bool {
+	return len(r.Breaks) == 0
+}
+
+// Reconcile checks that each transaction's balance is the balance before it
+// plus its amount, or minus it on a statement whose balances run the other
+// way. Balances run separately per source and account, in date and
+// statement order, and each run goes one way throughout, the way most of its
+// balances follow (see runDirection), so a transaction whose amount has the
+// wrong sign breaks the run. Transactions without a balance are not checked and
+// do not break the run. Breaks are in list order.
+func (tl *TransactionList) Reconcile() Reconciliation {
+	runs := make(map[string][]int)
+	var keys []string
+	for i, t := range tl.Transactions {
+		key := strings.ToLower(t.Source) + "|" + strings.ToLower(t.Account)
+		if _, ok := runs[key]; !ok {
+			keys = append(keys, key)
+		}
+		runs[key] = append(runs[key], i)
+	}
+
+	var r Reconciliation
+	for _, key := range keys {
+		indexes := runs[key]
+		slices.SortStableFunc(indexes, func(a, b int) int { return Compare(tl.Transactions[a], tl.Transactions[b]) })
+		run := make([]Transaction, len(indexes))
+		for j, i := range indexes {
+			run[j] = tl.Transactions[i]
+		}
+		direction := runDirection(nil, run)
+		previous := -1
+		for _, i := range indexes {
+			t := tl.Transactions[i]
+			if t.Balance == nil {
+				continue
+			}
+			if previous >= 0 {
+				expected := direction.Next(*tl.Transactions[previous].Balance, t.Amount)
+				r.Checked++
+				if *t.Balance != expected {
+					r.Breaks = append(r.Breaks, BalanceBreak{Index: i, Previous: previous, Expected: expected, Gap: *t.Balance - expected})
+				}
+			}
+			previous = i
+		}
+	}
+	slices.SortFunc(r.Breaks, func(a, b BalanceBreak) int { return a.Index - b.Index })
+	return r
+}
+
+// runDirection returns the direction most of the running balances of txns,
+// in order, follow in, starting from before when it is not nil, so that a
+// few rows with the wrong sign do not turn the run around. When as many
+// follow either way, as when none have balances, credit accounts fall and
+// others rise.
+func runDirection(before *Amount, txns []Transaction) Direction {
+	var votes Direction
+	credit := false
+	for _, t := range txns {
+		credit = credit || strings.EqualFold(t.AccountType, "credit")
+		if t.Balance == nil {
+			continue
+		}
+		if before != nil {
+			votes += BalanceDirection(*before, t.Amount, *t.Balance)
+		}
+		before = t.Balance
+	}
+	switch {
+	case votes > 0:
+		return Rising
+	case votes < 0 || credit:
+		return Falling
+	}
+	return Rising
+}
@@ -0,0 +1,63 @@
+package transaction
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func withBalance(day int, description string, amount, balance float64) Transaction {
+	txn := statementTxn("jan", day, description, amount)
+	txn.Balance = FromFloat(balance).Ptr()
+	return txn
+}
+
+func TestReconcile(t *testing.T) {
+	list := TransactionList{Transactions: []Transaction{
+		withBalance(2, "SALARY", 2500, 3000),
+		withBalance(3, "RENT", -1200, 1800),
+		statementTxn("jan", 3, "NO BALANCE", -10),
+		withBalance(4, "WOOLWORTHS", -54.1, 1745.9),
+		withBalance(6, "CAFE", -4.5, 1700),
+		withBalance(7, "NETFLIX", -22.99, 1677.01),
+	}}
+	card := withBalance(5, "AMAZON", -30, 130)
+	card.Account = "Visa"
+	cardBefore := withBalance(1, "OPENING", -100, 100)
+	cardBefore.Account = "Visa"
+	list.Transactions = append(list.Transactions, card, cardBefore)
+
+	r := list.Reconcile()
+	assert.Equal(t, 5, r.Checked, "rows without a balance are skipped")
+	assert.False(t, r.OK())
+	assert.Equal(t, []BalanceBreak{{Index: 4, Previous: 3, Expected: 174140, Gap: -4140}}, r.Breaks,
+		"the card's balance runs the other way and is checked in date order")
+
+	empty := TransactionList{Transactions: []Transaction{statementTxn("jan", 2, "CAFE", -4.5)}}
+	assert.Equal(t, Reconciliation{}, empty.Reconcile())
+	assert.True(t, empty.Reconcile().OK())
+}
+
+func TestReconcileSignFlipped(t *testing.T) {
+	list := TransactionList{Transactions: []Transaction{
+		withBalance(2, "SALARY", 2500, 3000),
+		withBalance(3, "RENT", 1200, 1800), // a debit read as a credit
+		withBalance(4, "WOOLWORTHS", -54.1, 1745.9),
+		withBalance(6, "CAFE", -4.5, 1741.4),
+	}}
+	r := list.Reconcile()
+	assert.Equal(t, 3, r.Checked)
+	assert.Equal(t, []BalanceBreak{{Index: 1, Previous: 0, Expected: 420000, Gap: -240000}}, r.Breaks,
+		"the run's balances rise with the amounts, so a falling one is a break")
+}
+
+func TestReconcileZeroBalance(t *testing.T) {
+	list := TransactionList{Transactions: []Transaction{
+		withBalance(2, "TRANSFER IN", 100, 100),
+		withBalance(3, "TRANSFER OUT", -100, 0),
+		withBalance(4, "CAFE", -4.5, 4.5),
+	}}
+	r := list.Reconcile()
+	assert.Equal(t, 2, r.Checked, "a zero balance is a balance")
+	assert.Equal(t, []BalanceBreak{{Index: 2, Previous: 1, Expected: -450, Gap: 900}}, r.Breaks)
+}
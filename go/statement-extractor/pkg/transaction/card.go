@@ -0,0 +1,34 @@
+package transaction
+
+import (
+	"regexp"
+	"strings"
+)
+
+// cardNumber matches the visible digits of a card number in a description:
+// after the word CARD, as in "CARD 4521" or "Card xx4521", or after a mask,
+// as in "XX4521" or "4564 XXXX XXXX 4521"
+var cardNumber = regexp.MustCompile(`(?i)(?:\bcard(?:\s+(?:no\.?|number))?\s*[x*]*|[x*]{2,}[\s-]*)(\d{4,})\b`)
+
+// CardSuffix returns the last visible digits of the card number in a
+// description, or "" when it names none
+func CardSuffix(description string) string {
+	matches := cardNumber.FindAllStringSubmatch(description, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+	return matches[len(matches)-1][1]
+}
+
+// PaidWith reports whether t was paid with a card whose number ends in
+// suffix, from Card or else from a card number in the description
+func (t Transaction) PaidWith(suffix string) bool {
+	card := t.Card
+	if card == "" {
+		card = CardSuffix(t.Description)
+	}
+	if card == "" && t.RawDescription != "" {
+		card = CardSuffix(t.RawDescription)
+	}
+	return card != "" && suffix != "" && strings.HasSuffix(card, suffix)
+}
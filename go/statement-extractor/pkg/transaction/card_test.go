@@ -0,0 +1,32 @@
+package transaction
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCardSuffix(t *testing.T) {
+	tests := map[string]string{
+		"PURCHASE WOOLWORTHS 1234 Card xx4521":    "4521",
+		"COLES ****9912":                          "9912",
+		"QANTAS CARD 9912":                        "9912",
+		"Card number 4564 xxxx xxxx 1234":         "1234",
+		"SHELL XX0014521":                         "0014521",
+		"BP 4521 SYDNEY":                          "",
+		"TRANSFER TO XX SAVINGS":                  "",
+		"VISA PURCHASE CARD NO. 4521 VALUE 01/02": "4521",
+	}
+	for description, want := range tests {
+		assert.Equal(t, want, CardSuffix(description), description)
+	}
+}
+
+func TestPaidWith(t *testing.T) {
+	assert.True(t, Transaction{Card: "0014521"}.PaidWith("4521"))
+	assert.True(t, Transaction{Description: "UBER XX4521"}.PaidWith("4521"))
+	assert.True(t, Transaction{Description: "UBER", RawDescription: "SQ *UBER XX4521"}.PaidWith("4521"))
+	assert.False(t, Transaction{Card: "4521", Description: "UBER XX9912"}.PaidWith("9912"), "Card wins over the description")
+	assert.False(t, Transaction{Card: "4521"}.PaidWith("14521"))
+	assert.False(t, Transaction{Description: "UBER"}.PaidWith("4521"))
+}
@@ -0,0 +1,186 @@
+package transaction
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Tolerance bounds how far a fuzzy duplicate may drift from the transaction
+// it duplicates, as when two statements post the same purchase on different
+// days
+type Tolerance struct {
+	Days   int     // maximum difference in date
+	Amount float64 // maximum difference in amount
+}
+
+// FindDuplicates groups the transactions that are copies of one another, as
+// when overlapping statements both cover a few days. Two transactions are
+// copies when they have the same ID, or when they are Near within tol.
+// Transactions extracted from the same statement file are never copies
+// of each other, so two identical purchases on one statement are kept.
+//
+// Each group lists indexes into the list in order; transactions without a
+// copy are in no group.
+func (tl *TransactionList) FindDuplicates(tol Tolerance) [][]int {
+	merchants := make([]string, len(tl.Transactions))
+	for i, t := range tl.Transactions {
+		merchants[i] = Merchant(t.Description)
+	}
+
+	var groups [][]int
+	for i, t := range tl.Transactions {
+		joined := false
+		for g, group := range groups {
+			first := group[0]
+			if tl.inGroup(group, i) || !duplicates(tl.Transactions[first], t, merchants[first], merchants[i], tol) {
+				continue
+			}
+			groups[g] = append(group, i)
+			joined = true
+			break
+		}
+		if !joined {
+			groups = append(groups, []int{i})
+		}
+	}
+
+	var dups [][]int
+	for _, group := range groups {
+		if len(group) > 1 {
+			dups = append(dups, group)
+		}
+	}
+	return dups
+}
+
+// inGroup reports whether the group already has a transaction from the
+// statement file transaction i came from
+func (tl *TransactionList) inGroup(group []int, i int) bool {
+	ref := tl.Transactions[i].Statement
+	if ref == nil || ref.SHA256 == "" {
+		return false
+	}
+	for _, j := range group {
+		if other := tl.Transactions[j].Statement; other != nil && other.SHA256 == ref.SHA256 {
+			return true
+		}
+	}
+	return false
+}
+
+// duplicates reports whether b is a copy of a
+func duplicates(a, b Transaction, merchantA, merchantB string, tol Tolerance) bool {
+	if a.ID != "" && a.ID == b.ID {
+		return true
+	}
+	_, _, ok := tol.Near(a, b, merchantA, merchantB)
+	return ok
+}
+
+// Key identifies a transaction for exact duplicate detection: its ID when
+// it has one, otherwise FieldKey
+func Key(t Transaction) string {
+	if t.ID != "" {
+		return "id:" + t.ID
+	}
+	return FieldKey(t)
+}
+
+// FieldKey joins the fields two copies of a transaction share exactly, for
+// matching transactions without IDs: the account, date, amount in cents and
+// description, folded as for IDs
+func FieldKey(t Transaction) string {
+	return fmt.Sprintf("%s|%s|%d|%s",
+		strings.ToLower(strings.TrimSpace(t.Account)),
+		t.Date.Format("2006-01-02"),
+		int64(t.Amount),
+		normalizeDescription(t.Description))
+}
+
+// Near reports whether b may be a copy of a posted differently, and how many
+// days and how much money apart they are, for choosing the closest of
+// several: their merchants must match, their accounts too where both are
+// set, and their dates and amounts must be within tol. merchantA and
+// merchantB are the transactions' descriptions reduced by Merchant.
+func (tol Tolerance) Near(a, b Transaction, merchantA, merchantB string) (days int, amount Amount, ok bool) {
+	if !SameMerchant(merchantA, merchantB) {
+		return 0, 0, false
+	}
+	if a.Account != "" && b.Account != "" && !strings.EqualFold(a.Account, b.Account) {
+		return 0, 0, false
+	}
+	days = int(math.Abs(b.Date.Sub(a.Date).Hours()/24) + 0.5)
+	amount = (a.Amount - b.Amount).Abs()
+	return days, amount, days <= tol.Days && amount <= FromFloat(tol.Amount)
+}
+
+// Merchant reduces a description to its words without digits, dropping
+// card numbers, dates and references that differ between statements
+func Merchant(description string) string {
+	var words []string
+	for _, word := range strings.Fields(normalizeDescription(description)) {
+		if strings.IndexFunc(word, unicode.IsDigit) < 0 {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// SameMerchant reports whether two descriptions reduced by Merchant name the
+// same merchant, allowing for a prefix or suffix one source adds
+func SameMerchant(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	return strings.Contains(" "+a+" ", " "+b+" ") || strings.Contains(" "+b+" ", " "+a+" ")
+}
+
+// KeepFirst keeps the earliest transaction of a duplicate group
+func KeepFirst(group []Transaction) (int, error) { return 0, nil }
+
+// KeepLatest keeps the last transaction of a duplicate group, which in a
+// list built from statements in order comes from the most recent one
+func KeepLatest(group []Transaction) (int, error) { return len(group) - 1, nil }
+
+// Deduplicate removes the copies found by FindDuplicates. For each group
+// keep returns the index within the group of the transaction to keep, or -1
+// to keep them all. It returns the removed transactions.
+func (tl *TransactionList) Deduplicate(tol Tolerance, keep func(group []Transaction) (int, error)) ([]Transaction, error) {
+	remove := make(map[int]bool)
+	for _, group := range tl.FindDuplicates(tol) {
+		txns := make([]Transaction, len(group))
+		for i, j := range group {
+			txns[i] = tl.Transactions[j]
+		}
+		kept, err := keep(txns)
+		if err != nil {
+			return nil, err
+		}
+		if kept < 0 {
+			continue
+		}
+		if kept >= len(group) {
+			return nil, fmt.Errorf("invalid choice %d for a group of %d duplicates", kept+1, len(group))
+		}
+		for i, j := range group {
+			if i != kept {
+				remove[j] = true
+			}
+		}
+	}
+
+	kept := make([]Transaction, 0, len(tl.Transactions)-len(remove))
+	var removed []Transaction
+	for i, t := range tl.Transactions {
+		if remove[i] {
+			removed = append(removed, t)
+		} else {
+			kept = append(kept, t)
+		}
+	}
+	tl.Transactions = kept
+	tl.Total = len(kept)
+	return removed, nil
+}
@@ -0,0 +1,96 @@
+package transaction
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func statementTxn(statement string, day int, description string, amount float64) Transaction {
+	return Transaction{
+		Date:        time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC),
+		Description: description,
+		Amount:      FromFloat(amount),
+		Statement:   &StatementRef{File: statement + ".pdf", SHA256: statement},
+	}
+}
+
+// overlapping covers 30 Jan to 1 Feb twice: once on the January statement
+// and once, posted a day later, on the February one
+func overlapping() *TransactionList {
+	return &TransactionList{Transactions: []Transaction{
+		statementTxn("jan", 29, "RENT", -500),
+		statementTxn("jan", 30, "CAFE NERO 1234", -4.5),
+		statementTxn("jan", 30, "CAFE NERO 1234", -4.5),
+		statementTxn("feb", 31, "CAFE NERO 5678", -4.5),
+		statementTxn("feb", 31, "CAFE NERO 5678", -4.5),
+		statementTxn("feb", 32, "WOOLWORTHS", -60),
+	}}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	tl := overlapping()
+	tol := Tolerance{Days: 2, Amount: 5}
+	assert.Equal(t, [][]int{{1, 3}, {2, 4}}, tl.FindDuplicates(tol), "same-statement purchases are not copies of each other")
+
+	assert.Empty(t, tl.FindDuplicates(Tolerance{}), "a day apart is outside a zero tolerance")
+
+	tl.Transactions[3].Account, tl.Transactions[1].Account = "Everyday", "Savings"
+	assert.Equal(t, [][]int{{1, 4}, {2, 3}}, tl.FindDuplicates(tol), "accounts must agree when both are set")
+}
+
+func TestFindDuplicatesByID(t *testing.T) {
+	a := Transaction{ID: "FIT1", Description: "SALARY", Amount: 250000}
+	b := Transaction{ID: "FIT1", Description: "SALARY ACME PTY", Amount: 250000, Date: time.Now()}
+	tl := &TransactionList{Transactions: []Transaction{a, b}}
+	assert.Equal(t, [][]int{{0, 1}}, tl.FindDuplicates(Tolerance{}))
+}
+
+func TestKey(t *testing.T) {
+	a := Transaction{Date: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), Description: "CAFE  NERO", Amount: -450, Account: "Everyday"}
+	b := a
+	b.Description = "cafe nero."
+	assert.Equal(t, Key(a), Key(b), "case, spacing and punctuation are folded")
+	assert.Equal(t, ComputeID(a, 1), ComputeID(b, 1), "as they are for IDs")
+
+	b.Amount = -451
+	assert.NotEqual(t, Key(a), Key(b))
+
+	a.ID, b.ID = "FIT1", "FIT1"
+	assert.Equal(t, Key(a), Key(b), "IDs take precedence over the other fields")
+}
+
+func TestDeduplicate(t *testing.T) {
+	tol := Tolerance{Days: 2, Amount: 5}
+
+	tl := overlapping()
+	removed, err := tl.Deduplicate(tol, KeepFirst)
+	require.NoError(t, err)
+	assert.Len(t, removed, 2)
+	assert.Equal(t, 4, tl.Total)
+	for _, txn := range tl.Transactions[1:3] {
+		assert.Equal(t, "jan", txn.Statement.SHA256)
+	}
+
+	tl = overlapping()
+	_, err = tl.Deduplicate(tol, KeepLatest)
+	require.NoError(t, err)
+	require.Len(t, tl.Transactions, 4)
+	for _, txn := range tl.Transactions[1:3] {
+		assert.Equal(t, "feb", txn.Statement.SHA256)
+	}
+
+	tl = overlapping()
+	removed, err = tl.Deduplicate(tol, func([]Transaction) (int, error) { return -1, nil })
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+	assert.Len(t, tl.Transactions, 6)
+
+	tl = overlapping()
+	_, err = tl.Deduplicate(tol, func([]Transaction) (int, error) { return 0, errors.New("no answer") })
+	assert.EqualError(t, err, "no answer")
+	assert.Len(t, tl.Transactions, 6, "the list is unchanged on error")
+}
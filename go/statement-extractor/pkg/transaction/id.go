@@ -0,0 +1,52 @@
+package transaction
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ComputeID returns the canonical ID of t: a hash of its source, date,
+// amount in cents and normalized description, and of seq, its 1-based
+// position among the transactions of the same statement that share all of
+// those. Re-processing a statement therefore yields the same IDs, and two
+// identical purchases on one day still get different ones.
+func ComputeID(t Transaction, seq int) string {
+	sum := sha256.Sum256([]byte(idKey(t) + fmt.Sprintf("|%d", seq)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// AssignIDs sets the canonical ID of each transaction in txns that has
+// none, numbering identical transactions in the order given. IDs from the
+// source, such as an OFX FITID, are kept.
+func AssignIDs(txns []Transaction) {
+	seen := make(map[string]int)
+	for i := range txns {
+		key := idKey(txns[i])
+		seen[key]++
+		if txns[i].ID == "" {
+			txns[i].ID = ComputeID(txns[i], seen[key])
+		}
+	}
+}
+
+// idKey joins the fields that identify a transaction within its statement
+func idKey(t Transaction) string {
+	return fmt.Sprintf("%s|%s|%d|%s",
+		strings.ToLower(strings.TrimSpace(t.Source)),
+		t.Date.Format("2006-01-02"),
+		int64(t.Amount),
+		normalizeDescription(t.Description))
+}
+
+// normalizeDescription lowercases s and reduces everything but letters and
+// digits to single spaces, so that spacing and punctuation differences
+// between parser versions do not change the ID, nor FieldKey and Merchant
+func normalizeDescription(s string) string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
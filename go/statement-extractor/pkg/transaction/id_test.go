@@ -0,0 +1,49 @@
+package transaction
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeID(t *testing.T) {
+	tx := Transaction{
+		Date:        time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+		Description: "CAFE  O'BRIEN, SYDNEY",
+		Amount:      -450,
+		Source:      "CBA",
+	}
+	id := ComputeID(tx, 1)
+	assert.Len(t, id, 16)
+	assert.Equal(t, id, ComputeID(tx, 1), "IDs are stable")
+
+	reformatted := tx
+	reformatted.Description = "cafe o brien sydney"
+	reformatted.Source = "cba"
+	reformatted.Amount = FromFloat(-4.499999)
+	reformatted.Category = "Dining"
+	assert.Equal(t, id, ComputeID(reformatted, 1), "case, punctuation, rounding and category do not matter")
+
+	assert.NotEqual(t, id, ComputeID(tx, 2))
+	other := tx
+	other.Date = other.Date.AddDate(0, 0, 1)
+	assert.NotEqual(t, id, ComputeID(other, 1))
+}
+
+func TestAssignIDs(t *testing.T) {
+	coffee := Transaction{Date: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), Description: "CAFE", Amount: -450, Source: "CBA"}
+	fitid := coffee
+	fitid.ID = "FITID-1"
+	txns := []Transaction{coffee, coffee, fitid, coffee}
+
+	AssignIDs(txns)
+	assert.Equal(t, ComputeID(coffee, 1), txns[0].ID)
+	assert.Equal(t, ComputeID(coffee, 2), txns[1].ID)
+	assert.Equal(t, "FITID-1", txns[2].ID, "source IDs are kept")
+	assert.Equal(t, ComputeID(coffee, 4), txns[3].ID)
+
+	again := []Transaction{coffee, coffee, fitid, coffee}
+	AssignIDs(again)
+	assert.Equal(t, txns, again, "re-processing yields the same IDs")
+}
@@ -0,0 +1,40 @@
+package transaction
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// AssignSequences numbers the transactions of each day 1, 2, ... in the
+// order given, which for an extracted statement is the order it lists them.
+// Days are numbered separately for each statement and account. A sequence
+// already set is kept and the numbering carries on after it.
+func AssignSequences(txns []Transaction) {
+	last := make(map[string]int)
+	for i := range txns {
+		t := &txns[i]
+		var statement string
+		if t.Statement != nil {
+			statement = cmp.Or(t.Statement.SHA256, t.Statement.File)
+		}
+		key := statement + "|" + strings.ToLower(t.Account) + "|" + t.Date.Format("2006-01-02")
+		if t.Sequence > 0 {
+			last[key] = max(last[key], t.Sequence)
+			continue
+		}
+		last[key]++
+		t.Sequence = last[key]
+	}
+}
+
+// Compare orders transactions by date, then by sequence within the day
+func Compare(a, b Transaction) int {
+	return cmp.Or(a.Date.Compare(b.Date), cmp.Compare(a.Sequence, b.Sequence))
+}
+
+// SortByDate sorts txns oldest first, keeping the statement order of each
+// day where sequences are known and the given order otherwise
+func SortByDate(txns []Transaction) {
+	slices.SortStableFunc(txns, Compare)
+}
@@ -0,0 +1,43 @@
+package transaction
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssignSequences(t *testing.T) {
+	txns := []Transaction{
+		statementTxn("jan", 2, "RENT", -500),
+		statementTxn("jan", 2, "CAFE", -4.5),
+		statementTxn("jan", 3, "CAFE", -4.5),
+		statementTxn("feb", 2, "CAFE", -4.5),
+		statementTxn("jan", 2, "REFUND", 20),
+	}
+	txns[4].Account = "Savings"
+	AssignSequences(txns)
+
+	var got []int
+	for _, txn := range txns {
+		got = append(got, txn.Sequence)
+	}
+	assert.Equal(t, []int{1, 2, 1, 1, 1}, got, "days are numbered per statement and account")
+
+	more := []Transaction{statementTxn("jan", 2, "A", 1), statementTxn("jan", 2, "B", 1)}
+	more[0].Sequence = 5
+	AssignSequences(more)
+	assert.Equal(t, 6, more[1].Sequence, "numbering carries on after a set sequence")
+}
+
+func TestSortByDate(t *testing.T) {
+	txns := []Transaction{
+		statementTxn("jan", 3, "LATER", -1),
+		statementTxn("jan", 2, "SECOND", -1),
+		statementTxn("jan", 2, "FIRST", -1),
+	}
+	txns[0].Sequence, txns[1].Sequence, txns[2].Sequence = 1, 2, 1
+	SortByDate(txns)
+	assert.Equal(t, "FIRST", txns[0].Description)
+	assert.Equal(t, "SECOND", txns[1].Description)
+	assert.Equal(t, "LATER", txns[2].Description)
+}
@@ -0,0 +1,73 @@
+package transaction
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// StatementInfo is what a statement says about itself, apart from its
+// transactions. Fields the statement does not show are left zero.
+type StatementInfo struct {
+	File   string `json:"file"`   // as in StatementRef
+	SHA256 string `json:"sha256"` // as in StatementRef
+
+	// Account is the account number with all but its last four digits
+	// masked, e.g. "****5678"
+	Account string `json:"account,omitempty"`
+
+	PeriodStart time.Time `json:"period_start,omitzero"`
+	PeriodEnd   time.Time `json:"period_end,omitzero"`
+
+	// The balances are pointers because a zero balance is common
+	OpeningBalance *Amount `json:"opening_balance,omitempty"`
+	ClosingBalance *Amount `json:"closing_balance,omitempty"`
+}
+
+// Found reports whether any of the statement's own details were found
+func (s StatementInfo) Found() bool {
+	return s.Account != "" || !s.PeriodStart.IsZero() || !s.PeriodEnd.IsZero() ||
+		s.OpeningBalance != nil || s.ClosingBalance != nil
+}
+
+// MaskAccount masks all but the last four digits of an account number,
+// ignoring spaces and dashes, e.g. "06 2000 12345678" becomes "****5678"
+func MaskAccount(number string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, number)
+	if len(digits) <= 4 {
+		return digits
+	}
+	return "****" + digits[len(digits)-4:]
+}
+
+// Verify checks that the transactions of the statement, those whose
+// Statement has its SHA256, take the opening balance to the closing balance.
+// Like a run of balances in Reconcile, the statement's balances go the way
+// its running balances mostly follow, or fall for a credit account, so
+// amounts with the wrong sign are not accepted. It returns nil when the
+// statement does not show both balances.
+func (s StatementInfo) Verify(txns []Transaction) error {
+	if s.OpeningBalance == nil || s.ClosingBalance == nil {
+		return nil
+	}
+	var own []Transaction
+	var total Amount
+	for _, t := range txns {
+		if t.Statement != nil && t.Statement.SHA256 == s.SHA256 {
+			own = append(own, t)
+			total += t.Amount
+		}
+	}
+	slices.SortStableFunc(own, Compare)
+	if runDirection(s.OpeningBalance, own).Next(*s.OpeningBalance, total) == *s.ClosingBalance {
+		return nil
+	}
+	return fmt.Errorf("%s: transactions total %s, but the balance goes from %s to %s, a difference of %s",
+		s.File, total, *s.OpeningBalance, *s.ClosingBalance, *s.ClosingBalance-*s.OpeningBalance)
+}
@@ -0,0 +1,53 @@
+package transaction
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskAccount(t *testing.T) {
+	assert.Equal(t, "****5678", MaskAccount("06 2000 12345678"))
+	assert.Equal(t, "****6789", MaskAccount("1234-56789"))
+	assert.Equal(t, "1234", MaskAccount("1234"), "too short to mask")
+}
+
+func TestStatementVerify(t *testing.T) {
+	opening, closing := Amount(100000), Amount(344590)
+	info := StatementInfo{File: "jan.pdf", SHA256: "jan", OpeningBalance: &opening, ClosingBalance: &closing}
+	txns := []Transaction{
+		statementTxn("jan", 2, "SALARY", 2500),
+		statementTxn("jan", 4, "WOOLWORTHS", -54.1),
+		statementTxn("feb", 1, "RENT", -1200),
+	}
+	assert.NoError(t, info.Verify(txns), "only the statement's own transactions count")
+
+	card := info
+	card.OpeningBalance, card.ClosingBalance = &closing, &opening
+	assert.Error(t, card.Verify(txns))
+	cardTxns := slices.Clone(txns)
+	for i := range cardTxns {
+		cardTxns[i].AccountType = "credit"
+	}
+	assert.NoError(t, card.Verify(cardTxns), "a card's balances run the other way")
+	withBalances := slices.Clone(txns)
+	withBalances[0].Balance = Amount(94590).Ptr()
+	assert.NoError(t, card.Verify(withBalances), "as running balances show")
+
+	flipped := slices.Clone(txns)
+	for i := range flipped {
+		flipped[i].Amount = -flipped[i].Amount
+	}
+	assert.EqualError(t, info.Verify(flipped),
+		"jan.pdf: transactions total -2445.90, but the balance goes from 1000.00 to 3445.90, a difference of 2445.90",
+		"amounts with the wrong sign do not reach the closing balance")
+
+	txns = txns[1:]
+	assert.EqualError(t, info.Verify(txns),
+		"jan.pdf: transactions total -54.10, but the balance goes from 1000.00 to 3445.90, a difference of 2445.90")
+
+	assert.NoError(t, StatementInfo{SHA256: "jan", ClosingBalance: &closing}.Verify(txns), "nothing to check without both balances")
+	assert.False(t, StatementInfo{File: "jan.pdf", SHA256: "jan"}.Found())
+	assert.True(t, info.Found())
+}
@@ -1,6 +1,9 @@
 package transaction
 
 import (
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -9,10 +12,149 @@ type Transaction struct {
 	ID          string    `json:"id"`
 	Date        time.Time `json:"date"`
 	Description string    `json:"description"`
-	Amount      float64   `json:"amount"`
-	Balance     float64   `json:"balance,omitempty"`
+	Amount      Amount    `json:"amount"`
+	Balance     *Amount   `json:"balance,omitempty"` // nil when the statement shows none
 	Category    string    `json:"category"`
-	Source      string    `json:"source"` // e.g., "CBA", "ANZ"
+	Source      string    `json:"source"`                 // e.g., "CBA", "ANZ"
+	Currency    string    `json:"currency,omitempty"`     // ISO 4217 code, e.g. "AUD"
+	Account     string    `json:"account,omitempty"`      // e.g. "Everyday"
+	AccountType string    `json:"account_type,omitempty"` // e.g. "transaction", "savings", "credit"
+	Owner       string    `json:"owner,omitempty"`        // household member, e.g. "Sam"
+	Card        string    `json:"card,omitempty"`         // last digits of the card used, e.g. "4521"
+
+	// RawDescription is the description as extracted, before rewrites, and
+	// Merchant the merchant name cleaned from it
+	RawDescription string `json:"raw_description,omitempty"`
+	Merchant       string `json:"merchant,omitempty"`
+
+	// Sequence is the 1-based position of the transaction among those on
+	// the same day of its statement, so same-day transactions keep their
+	// statement order; 0 when unknown
+	Sequence int `json:"sequence,omitempty"`
+
+	// CategoryConfidence is how sure the categorizer was of Category, from
+	// 0 to 1; rules are sure, and 0 is unknown
+	CategoryConfidence float64 `json:"category_confidence,omitempty"`
+
+	// CategorizedBy records what assigned Category: "rule:<id>" for a
+	// category rule, "script:<name>" for a transform, "llm", "manual" for a
+	// category the statement or an earlier edit carried, "default" when
+	// nothing matched, or the name of another categorizer stage
+	CategorizedBy string `json:"categorized_by,omitempty"`
+
+	// Splits allocate the amount between categories, e.g. a supermarket
+	// purchase 70/30 between Groceries and Household; the split amounts add
+	// up to Amount. Category stays the category of the whole transaction.
+	Splits []Split `json:"splits,omitempty"`
+
+	// Tags label transactions independently of the single Category, e.g.
+	// "tax-deductible" or "work"
+	Tags []string `json:"tags,omitempty"`
+
+	// ExcludeFromReports keeps one-off outliers, such as a house deposit
+	// transfer, out of report totals and averages. The transaction is still
+	// stored and exported.
+	ExcludeFromReports bool `json:"exclude_from_reports,omitempty"`
+
+	// AmortizeMonths spreads an annual or otherwise lumpy expense, such as
+	// insurance, evenly over this many months in reports run with --amortize
+	AmortizeMonths int `json:"amortize_months,omitempty"`
+
+	// Recurrence describes the recurring series the transaction belongs to,
+	// as detected by report recurring --output
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+
+	// Statement links the transaction to the statement it was extracted from
+	Statement *StatementRef `json:"statement,omitempty"`
+}
+
+// Split is the part of a transaction's amount allocated to one category
+type Split struct {
+	Category string `json:"category"`
+	Amount   Amount `json:"amount"`
+}
+
+// Recurrence is the interval of a recurring payment and when the next one
+// is expected
+type Recurrence struct {
+	Frequency string    `json:"frequency"` // e.g. "monthly"
+	Next      time.Time `json:"next"`
+}
+
+// StatementRef identifies a source statement so exported figures can be
+// traced back to the original document
+type StatementRef struct {
+	File   string `json:"file"`           // e.g. "2024.zip/jan.pdf"
+	SHA256 string `json:"sha256"`         // hex digest of the statement file
+	Page   int    `json:"page,omitempty"` // 1-based, when the parser knows it
+}
+
+// String formats the reference for export memo fields, e.g.
+// "jan.pdf p.2 sha256:9f86d081884c"
+func (r StatementRef) String() string {
+	s := r.File
+	if r.Page > 0 {
+		s += fmt.Sprintf(" p.%d", r.Page)
+	}
+	if len(r.SHA256) >= 12 {
+		s += " sha256:" + r.SHA256[:12]
+	}
+	return s
+}
+
+// Values of CategorizedBy other than rules and stages
+const (
+	CategorizedByLLM       = "llm"
+	CategorizedByEmbedding = "embedding"
+	CategorizedByManual    = "manual"
+	CategorizedByDefault   = "default"
+)
+
+// CategorizedByRule is the CategorizedBy value for the category rule id
+func CategorizedByRule(id string) string {
+	return "rule:" + id
+}
+
+// Allocations returns a copy of t per split, with the split's category and
+// amount, or t itself when it is not split
+func (t Transaction) Allocations() []Transaction {
+	if len(t.Splits) == 0 {
+		return []Transaction{t}
+	}
+	parts := make([]Transaction, len(t.Splits))
+	for i, s := range t.Splits {
+		part := t
+		part.Category, part.Amount, part.Splits = s.Category, s.Amount, nil
+		parts[i] = part
+	}
+	return parts
+}
+
+// ExpandSplits replaces each split transaction with its allocations, so
+// per-category totals count each part in its own category
+func ExpandSplits(txns []Transaction) []Transaction {
+	expanded := make([]Transaction, 0, len(txns))
+	for _, t := range txns {
+		expanded = append(expanded, t.Allocations()...)
+	}
+	return expanded
+}
+
+// HasTag reports whether the transaction has the tag, ignoring case
+func (t Transaction) HasTag(tag string) bool {
+	for _, have := range t.Tags {
+		if strings.EqualFold(have, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddTag adds the tag unless the transaction already has it
+func (t *Transaction) AddTag(tag string) {
+	if !t.HasTag(tag) {
+		t.Tags = append(t.Tags, tag)
+	}
 }
 
 // TransactionList holds a collection of transactions
@@ -21,6 +163,10 @@ type TransactionList struct {
 	Total        int           `json:"total"`
 	Source       string        `json:"source"`
 	ProcessedAt  time.Time     `json:"processed_at"`
+
+	// Statements describe the statements the transactions were extracted
+	// from, when the parser could read their headers
+	Statements []StatementInfo `json:"statements,omitempty"`
 }
 
 // AddTransaction appends a transaction to the list
@@ -38,4 +184,26 @@ func (tl *TransactionList) GetByCategory(category string) []Transaction {
 		}
 	}
 	return filtered
-}
\ No newline at end of file
+}
+
+// Replace removes the transactions for which match returns true and puts
+// with in their place, where the first of them was, or at the end when none
+// matched. It returns the number removed.
+func (tl *TransactionList) Replace(match func(Transaction) bool, with []Transaction) int {
+	at := -1
+	kept := make([]Transaction, 0, len(tl.Transactions)+len(with))
+	for _, t := range tl.Transactions {
+		if !match(t) {
+			kept = append(kept, t)
+		} else if at < 0 {
+			at = len(kept)
+		}
+	}
+	removed := len(tl.Transactions) - len(kept)
+	if at < 0 {
+		at = len(kept)
+	}
+	tl.Transactions = slices.Insert(kept, at, with...)
+	tl.Total = len(tl.Transactions)
+	return removed
+}
@@ -14,7 +14,7 @@ func TestTransactionList_AddTransaction(t *testing.T) {
 		ID:          "test-1",
 		Date:        time.Now(),
 		Description: "Test transaction",
-		Amount:      -50.00,
+		Amount:      -5000,
 		Category:    "Food & dining",
 		Source:      "CBA",
 	}
@@ -32,17 +32,17 @@ func TestTransactionList_GetByCategory(t *testing.T) {
 	tx1 := Transaction{
 		ID:       "tx-1",
 		Category: "Food & dining",
-		Amount:   -25.50,
+		Amount:   -2550,
 	}
 	tx2 := Transaction{
 		ID:       "tx-2", 
 		Category: "Groceries & household",
-		Amount:   -75.00,
+		Amount:   -7500,
 	}
 	tx3 := Transaction{
 		ID:       "tx-3",
 		Category: "Food & dining", 
-		Amount:   -15.25,
+		Amount:   -1525,
 	}
 	
 	tl.AddTransaction(tx1)
@@ -57,4 +57,48 @@ func TestTransactionList_GetByCategory(t *testing.T) {
 	groceryTransactions := tl.GetByCategory("Groceries & household")
 	assert.Equal(t, 1, len(groceryTransactions))
 	assert.Equal(t, "tx-2", groceryTransactions[0].ID)
-}
\ No newline at end of file
+}
+
+func TestStatementRef_String(t *testing.T) {
+	ref := StatementRef{File: "2024.zip/jan.pdf", SHA256: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", Page: 2}
+	assert.Equal(t, "2024.zip/jan.pdf p.2 sha256:9f86d081884c", ref.String())
+	assert.Equal(t, "jan.pdf", StatementRef{File: "jan.pdf"}.String())
+}
+
+func TestTransaction_Tags(t *testing.T) {
+	tx := Transaction{Tags: []string{"Work"}}
+	assert.True(t, tx.HasTag("work"))
+	assert.False(t, tx.HasTag("subscription"))
+
+	tx.AddTag("WORK")
+	tx.AddTag("subscription")
+	assert.Equal(t, []string{"Work", "subscription"}, tx.Tags)
+}
+
+func TestTransactionList_Replace(t *testing.T) {
+	tl := &TransactionList{Transactions: []Transaction{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}}}
+	stale := func(tx Transaction) bool { return tx.ID == "b" || tx.ID == "c" }
+
+	removed := tl.Replace(stale, []Transaction{{ID: "b2"}})
+	assert.Equal(t, 2, removed)
+	ids := make([]string, len(tl.Transactions))
+	for i, tx := range tl.Transactions {
+		ids[i] = tx.ID
+	}
+	assert.Equal(t, []string{"a", "b2", "d"}, ids)
+	assert.Equal(t, 3, tl.Total)
+
+	assert.Zero(t, tl.Replace(stale, []Transaction{{ID: "e"}}))
+	assert.Equal(t, "e", tl.Transactions[3].ID, "appended when nothing matched")
+}
+
+func TestExpandSplits(t *testing.T) {
+	split := Transaction{ID: "a", Category: "Groceries", Amount: -10000,
+		Splits: []Split{{Category: "Groceries", Amount: -7000}, {Category: "Household", Amount: -3000}}}
+	expanded := ExpandSplits([]Transaction{split, {ID: "b", Category: "Dining", Amount: -2000}})
+
+	assert.Len(t, expanded, 3)
+	assert.Equal(t, Transaction{ID: "a", Category: "Household", Amount: -3000}, expanded[1])
+	assert.Equal(t, "b", expanded[2].ID)
+	assert.Len(t, split.Splits, 2, "input is not modified")
+}